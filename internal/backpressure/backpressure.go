@@ -0,0 +1,115 @@
+// Package backpressure lets the server signal overload to clients so they can reduce their flow rate.
+package backpressure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+)
+
+// Path is the control endpoint on the health server that exposes the backpressure status
+const Path = "/backpressure"
+
+// Thresholds defines when the server considers itself overloaded. A zero value disables the check.
+type Thresholds struct {
+	MaxActiveConnections int
+	MaxPPS               float64
+}
+
+// Status is the backpressure state reported to clients
+type Status struct {
+	Overloaded        bool    `json:"overloaded"`
+	Reason            string  `json:"reason,omitempty"`
+	ActiveConnections int64   `json:"active_connections"`
+	PPS               float64 `json:"pps"`
+}
+
+// Monitor periodically samples the server load and derives the backpressure status
+type Monitor struct {
+	mc          *metrics.MetricsCollector
+	thresholds  Thresholds
+	mu          sync.RWMutex
+	status      Status
+	lastPackets uint64
+	lastSample  time.Time
+}
+
+// NewMonitor creates a new backpressure monitor
+func NewMonitor(mc *metrics.MetricsCollector, thresholds Thresholds) *Monitor {
+	return &Monitor{
+		mc:          mc,
+		thresholds:  thresholds,
+		lastPackets: mc.TotalUDPReceived(),
+		lastSample:  time.Now(),
+	}
+}
+
+// Run samples the server load at the given interval until the context is cancelled
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			m.sample(now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sample updates the status from the current load and records overload transitions
+func (m *Monitor) sample(now time.Time) {
+	packets := m.mc.TotalUDPReceived()
+	active := m.mc.ActiveTCPConnectionCount()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var pps float64
+	if elapsed := now.Sub(m.lastSample).Seconds(); elapsed > 0 {
+		pps = float64(packets-m.lastPackets) / elapsed
+	}
+	m.lastPackets = packets
+	m.lastSample = now
+
+	status := Status{ActiveConnections: active, PPS: pps}
+	switch {
+	case m.thresholds.MaxActiveConnections > 0 && active >= int64(m.thresholds.MaxActiveConnections):
+		status.Overloaded = true
+		status.Reason = fmt.Sprintf("active connections %d reached threshold %d", active, m.thresholds.MaxActiveConnections)
+	case m.thresholds.MaxPPS > 0 && pps >= m.thresholds.MaxPPS:
+		status.Overloaded = true
+		status.Reason = fmt.Sprintf("packet rate %.1f pps reached threshold %.1f", pps, m.thresholds.MaxPPS)
+	}
+
+	if status.Overloaded && !m.status.Overloaded {
+		logging.Logger.Warnf("Signaling backpressure to clients: %s", status.Reason)
+		m.mc.IncBackpressureSignals()
+	} else if !status.Overloaded && m.status.Overloaded {
+		logging.Logger.Info("Server load back below thresholds, clearing backpressure signal")
+	}
+	m.mc.SetBackpressureActive(status.Overloaded)
+	m.status = status
+}
+
+// Status returns the most recently sampled backpressure status
+func (m *Monitor) Status() Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+// ServeHTTP reports the current backpressure status as JSON
+func (m *Monitor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(m.Status()); err != nil {
+		logging.Logger.Debugf("Failed to write backpressure status: %v", err)
+	}
+}
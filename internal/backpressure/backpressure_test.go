@@ -0,0 +1,86 @@
+package backpressure
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	// Initialize logger for tests
+	logging.InitLogger("json", "error")
+}
+
+func TestMonitorActiveConnections(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	m := NewMonitor(mc, Thresholds{MaxActiveConnections: 2})
+	signals := testutil.ToFloat64(mc.BackpressureSignals)
+
+	mc.IncActiveTCPConnections()
+	m.sample(time.Now())
+	assert.False(t, m.Status().Overloaded)
+
+	mc.IncActiveTCPConnections()
+	m.sample(time.Now())
+	status := m.Status()
+	assert.True(t, status.Overloaded)
+	assert.Equal(t, int64(2), status.ActiveConnections)
+	assert.Contains(t, status.Reason, "active connections")
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.BackpressureActive))
+
+	// Staying overloaded does not raise a new signal
+	m.sample(time.Now())
+	assert.Equal(t, signals+1, testutil.ToFloat64(mc.BackpressureSignals))
+
+	mc.DecActiveTCPConnections()
+	mc.DecActiveTCPConnections()
+	m.sample(time.Now())
+	assert.False(t, m.Status().Overloaded)
+	assert.Equal(t, float64(0), testutil.ToFloat64(mc.BackpressureActive))
+}
+
+func TestMonitorPPS(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	m := NewMonitor(mc, Thresholds{MaxPPS: 100})
+	start := m.lastSample
+
+	for i := 0; i < 50; i++ {
+		mc.IncRequestsReceived("udp", "9000")
+	}
+	m.sample(start.Add(1 * time.Second))
+	assert.False(t, m.Status().Overloaded)
+	assert.InDelta(t, 50, m.Status().PPS, 0.01)
+
+	for i := 0; i < 150; i++ {
+		mc.IncRequestsReceived("udp", "9000")
+	}
+	m.sample(start.Add(2 * time.Second))
+	assert.True(t, m.Status().Overloaded)
+	assert.Contains(t, m.Status().Reason, "packet rate")
+}
+
+func TestMonitorServeHTTP(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	m := NewMonitor(mc, Thresholds{MaxActiveConnections: 1})
+	mc.IncActiveTCPConnections()
+	defer mc.DecActiveTCPConnections()
+	m.sample(time.Now())
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var status Status
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.True(t, status.Overloaded)
+	assert.Equal(t, int64(1), status.ActiveConnections)
+}
@@ -0,0 +1,253 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"runtime"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/handlers"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+)
+
+// payloadSize is the size of the requests echoed by the handler benchmarks, the size of the handler read buffer
+const payloadSize = 1024
+
+// Benchmark is a named micro-benchmark of the tool itself. F returns an error if the benchmark cannot
+// run, e.g. because no loopback socket could be opened.
+type Benchmark struct {
+	Name string
+	F    func(b *testing.B) error
+}
+
+// Result is the outcome of a benchmark
+type Result struct {
+	Name        string
+	Iterations  int
+	NsPerOp     float64
+	MBPerSec    float64
+	BytesPerOp  int64
+	AllocsPerOp int64
+	// Err is set if the benchmark could not run
+	Err string
+}
+
+// Standard returns the benchmarks of the metrics hot path, the TCP and UDP handler echo loops and the TCP
+// connection lifecycle
+func Standard() []Benchmark {
+	return []Benchmark{
+		{Name: "metrics/request", F: benchmarkMetricsRequest},
+		{Name: "metrics/request-parallel", F: benchmarkMetricsRequestParallel},
+		{Name: "handler/tcp-echo", F: benchmarkTCPEcho},
+		{Name: "handler/tcp-connection", F: benchmarkTCPConnection},
+		{Name: "handler/udp-echo", F: benchmarkUDPEcho},
+	}
+}
+
+// Run runs the benchmarks whose name matches the regular expression filter, all of them if filter is empty
+func Run(benchmarks []Benchmark, filter string) ([]Result, error) {
+	re, err := regexp.Compile(filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	// The handlers log through the global logger, only errors matter while benchmarking
+	if logging.Logger == nil {
+		logging.InitLogger("human", "error")
+	}
+	var results []Result
+	for _, bm := range benchmarks {
+		if re.MatchString(bm.Name) {
+			results = append(results, run(bm))
+		}
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no benchmark matches %q", filter)
+	}
+	return results, nil
+}
+
+// run runs a single benchmark. Errors are reported in the result, as testing.Benchmark does not return them.
+func run(bm Benchmark) Result {
+	var failure error
+	r := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		if err := bm.F(b); err != nil && failure == nil {
+			failure = err
+		}
+	})
+	if failure != nil {
+		return Result{Name: bm.Name, Err: failure.Error()}
+	}
+	result := Result{
+		Name:        bm.Name,
+		Iterations:  r.N,
+		BytesPerOp:  r.AllocedBytesPerOp(),
+		AllocsPerOp: r.AllocsPerOp(),
+	}
+	if r.N > 0 {
+		result.NsPerOp = float64(r.T.Nanoseconds()) / float64(r.N)
+	}
+	if r.Bytes > 0 && r.T > 0 {
+		result.MBPerSec = float64(r.Bytes) * float64(r.N) / 1e6 / r.T.Seconds()
+	}
+	return result
+}
+
+// Print writes the results as a table, after a line describing the host they were measured on
+func Print(w io.Writer, results []Result) error {
+	if _, err := fmt.Fprintf(w, "%s/%s, %d CPUs, %s\n\n", runtime.GOOS, runtime.GOARCH, runtime.NumCPU(), runtime.Version()); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "BENCHMARK\tITERATIONS\tNS/OP\tMB/S\tB/OP\tALLOCS/OP\t")
+	for _, r := range results {
+		if r.Err != "" {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t\t\t\t\t\n", r.Name, r.Err)
+			continue
+		}
+		mbps := "-"
+		if r.MBPerSec > 0 {
+			mbps = fmt.Sprintf("%.2f", r.MBPerSec)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%.1f\t%s\t%d\t%d\t\n", r.Name, r.Iterations, r.NsPerOp, mbps, r.BytesPerOp, r.AllocsPerOp)
+	}
+	return tw.Flush()
+}
+
+// recordRequest is what the client records for a request echoed by the server
+func recordRequest(mc *metrics.MetricsCollector) {
+	mc.IncRequestsSent("tcp", "8080")
+	mc.AddBytesSent("tcp", "8080", payloadSize)
+	mc.AddBytesReceived("tcp", "8080", payloadSize)
+	mc.ObserveLatency("tcp", "8080", 250*time.Microsecond)
+}
+
+// benchmarkMetricsRequest measures the metrics recorded per request
+func benchmarkMetricsRequest(b *testing.B) error {
+	mc := metrics.NewMetricsCollector()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		recordRequest(mc)
+	}
+	return nil
+}
+
+// benchmarkMetricsRequestParallel measures the metrics recorded per request by concurrent flows
+func benchmarkMetricsRequestParallel(b *testing.B) error {
+	mc := metrics.NewMetricsCollector()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			recordRequest(mc)
+		}
+	})
+	return nil
+}
+
+// benchmarkTCPEcho measures request round trips through the TCP echo handler over loopback
+func benchmarkTCPEcho(b *testing.B) error {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = ln.Close() }()
+	handler := handlers.NewTCPHandler(metrics.NewMetricsCollector())
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		handler.Handle(conn)
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+	return echoLoop(b, conn)
+}
+
+// benchmarkTCPConnection measures TCP connections echoing a single request through the TCP echo handler over
+// loopback, from dialing to closing, which shows what every new flow costs the server
+func benchmarkTCPConnection(b *testing.B) error {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = ln.Close() }()
+	handler := handlers.NewTCPHandler(metrics.NewMetricsCollector())
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go handler.Handle(conn)
+		}
+	}()
+
+	request := make([]byte, payloadSize)
+	reply := make([]byte, payloadSize)
+	b.SetBytes(2 * payloadSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			return err
+		}
+		if _, err := conn.Write(request); err != nil {
+			_ = conn.Close()
+			return err
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = io.ReadFull(conn, reply)
+		_ = conn.Close()
+		if err != nil {
+			return fmt.Errorf("no echo received: %w", err)
+		}
+	}
+	return nil
+}
+
+// benchmarkUDPEcho measures request round trips through the UDP echo handler over loopback
+func benchmarkUDPEcho(b *testing.B) error {
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = server.Close() }()
+	go handlers.NewUDPHandler(metrics.NewMetricsCollector()).Handle(server)
+
+	conn, err := net.Dial("udp", server.LocalAddr().String())
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+	return echoLoop(b, conn)
+}
+
+// echoLoop sends a request and reads its full echo per iteration
+func echoLoop(b *testing.B, conn net.Conn) error {
+	request := make([]byte, payloadSize)
+	reply := make([]byte, payloadSize)
+	b.SetBytes(2 * payloadSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := conn.Write(request); err != nil {
+			return err
+		}
+		if err := conn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return fmt.Errorf("no echo received: %w", err)
+		}
+	}
+	return nil
+}
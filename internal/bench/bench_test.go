@@ -0,0 +1,57 @@
+package bench
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	noop := func(b *testing.B) error { return nil }
+	benchmarks := []Benchmark{
+		{Name: "group/noop", F: noop},
+		{Name: "group/failing", F: func(b *testing.B) error { return errors.New("no socket") }},
+		{Name: "other/noop", F: noop},
+	}
+
+	results, err := Run(benchmarks, "^group/")
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "group/noop", results[0].Name)
+	assert.Positive(t, results[0].Iterations)
+	assert.Empty(t, results[0].Err)
+	assert.Equal(t, Result{Name: "group/failing", Err: "no socket"}, results[1])
+
+	_, err = Run(benchmarks, "missing")
+	assert.EqualError(t, err, `no benchmark matches "missing"`)
+	_, err = Run(benchmarks, "(")
+	assert.ErrorContains(t, err, "invalid filter")
+}
+
+func TestStandardBenchmarks(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs each benchmark for a second")
+	}
+	results, err := Run(Standard(), "")
+	require.NoError(t, err)
+	require.Len(t, results, len(Standard()))
+	for _, r := range results {
+		assert.Empty(t, r.Err, r.Name)
+		assert.Positive(t, r.NsPerOp, r.Name)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Print(&buf, []Result{
+		{Name: "handler/tcp-echo", Iterations: 1000, NsPerOp: 12345.6, MBPerSec: 165.9, BytesPerOp: 16, AllocsPerOp: 1},
+		{Name: "handler/udp-echo", Err: "no echo received"},
+	}))
+	out := buf.String()
+	assert.Contains(t, out, "CPUs")
+	assert.Regexp(t, `handler/tcp-echo\s+1000\s+12345.6\s+165.90\s+16\s+1`, out)
+	assert.Regexp(t, `handler/udp-echo\s+no echo received`, out)
+}
@@ -0,0 +1,53 @@
+// Package bufpool shares read buffers between the connections of the server and the flows of the client, so
+// high flow rates do not allocate and collect a buffer for every connection or request.
+package bufpool
+
+import (
+	"math/bits"
+	"sync"
+)
+
+const (
+	// minShift and maxShift are the sizes of the smallest and largest pooled buffers as powers of two
+	minShift = 10
+	maxShift = 16
+	// MaxSize is the size of the largest pooled buffers, 64 KiB, larger buffers are allocated on every Get
+	MaxSize = 1 << maxShift
+)
+
+// pools hold the buffers of the size classes from 1 KiB to MaxSize in powers of two
+var pools [maxShift - minShift + 1]sync.Pool
+
+// class returns the index of the smallest size class holding n bytes
+func class(n int) int {
+	if n <= 1<<minShift {
+		return 0
+	}
+	return bits.Len(uint(n-1)) - minShift
+}
+
+// Get returns a buffer of n bytes with undefined content. It is passed to Put once it is no longer used.
+// Buffers are handed around as pointers, so putting them back does not allocate.
+func Get(n int) *[]byte {
+	if n > MaxSize {
+		b := make([]byte, n)
+		return &b
+	}
+	i := class(n)
+	if b, ok := pools[i].Get().(*[]byte); ok {
+		*b = (*b)[:n]
+		return b
+	}
+	b := make([]byte, n, 1<<(i+minShift))
+	return &b
+}
+
+// Put returns a buffer from Get to its pool. Buffers larger than MaxSize are left to the garbage collector.
+func Put(b *[]byte) {
+	c := cap(*b)
+	if c > MaxSize || c < 1<<minShift || c&(c-1) != 0 {
+		return
+	}
+	*b = (*b)[:c]
+	pools[class(c)].Put(b)
+}
@@ -0,0 +1,77 @@
+package bufpool
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClass(t *testing.T) {
+	tests := []struct {
+		n    int
+		want int
+	}{
+		{1, 0},
+		{1024, 0},
+		{1025, 1},
+		{2048, 1},
+		{65507, 6},
+		{MaxSize, 6},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.n), func(t *testing.T) {
+			assert.Equal(t, tt.want, class(tt.n))
+		})
+	}
+}
+
+func TestGetPut(t *testing.T) {
+	for _, n := range []int{1, 1024, 1500, 65507, MaxSize, MaxSize + 1} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			b := Get(n)
+			assert.Len(t, *b, n)
+			if n <= MaxSize {
+				assert.Equal(t, 1<<(class(n)+minShift), cap(*b))
+			}
+			Put(b)
+		})
+	}
+
+	// Buffers not from Get are not pooled
+	b := make([]byte, 1000)
+	Put(&b)
+	assert.Len(t, *Get(1000), 1000)
+}
+
+// sink keeps the buffers of the benchmarks escaping to the heap like those of connections do
+var sink []byte
+
+func BenchmarkBuffers(b *testing.B) {
+	for _, n := range []int{1024, 64 * 1024} {
+		b.Run(fmt.Sprintf("make-%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				sink = make([]byte, n)
+			}
+		})
+		b.Run(fmt.Sprintf("pool-%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buf := Get(n)
+				sink = *buf
+				Put(buf)
+			}
+		})
+		b.Run(fmt.Sprintf("pool-parallel-%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					buf := Get(n)
+					(*buf)[0] = 1
+					Put(buf)
+				}
+			})
+		})
+	}
+}
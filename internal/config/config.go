@@ -1,9 +1,25 @@
 package config
 
 import (
+	"crypto/tls"
 	"fmt"
+	"io"
+	"math"
+	"net/netip"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
 
+	"github.com/PhilipSchmid/flow-generator-app/internal/sink"
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/service"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
@@ -11,36 +27,294 @@ import (
 const (
 	// EnvPrefix is the prefix for all environment variables
 	EnvPrefix = "FLOW_GENERATOR"
+
+	// MaxTCPReadBuffer is the largest size of the reads of TCP connections, 16 MiB
+	MaxTCPReadBuffer = 16 << 20
+	// MaxUDPReadBuffer is the largest size of the reads of UDP sockets, 64 KiB, which takes any datagram whole
+	MaxUDPReadBuffer = 64 << 10
+	// PayloadSizeLimit is the largest payload a flow sends, 64 MiB
+	PayloadSizeLimit = 64 << 20
 )
 
 // CommonConfig holds configuration fields shared between client and server.
 type CommonConfig struct {
-	LogLevel       string
-	LogFormat      string
+	LogLevel  string
+	LogFormat string
+	// LogTheme selects the look of the human log format: auto, color, plain or classic, empty is auto
+	LogTheme       string
 	MetricsPort    string
 	TracingEnabled bool
 	JaegerEndpoint string
+
+	// OTLPMetricsEnabled pushes all metrics to the OTLP endpoint used for tracing
+	OTLPMetricsEnabled  bool
+	OTLPMetricsInterval float64
+
+	StatsdAddress    string
+	StatsdSampleRate float64
+
+	// Profile selects named presets from ProfileDir (comma-separated, applied left to right), which the
+	// config file, environment and flags override
+	ProfileDir string
+	Profile    string
+
+	// TCPNoDelay sends TCP segments without waiting to coalesce small writes (TCP_NODELAY)
+	TCPNoDelay bool
+	// SocketSendBuffer and SocketReceiveBuffer are the SO_SNDBUF and SO_RCVBUF sizes of the sockets in
+	// bytes, 0 keeps the kernel default
+	SocketSendBuffer    int
+	SocketReceiveBuffer int
+	// TCPKeepAlive enables keepalive probes on TCP connections. TCPKeepAliveIdle is the idle time in seconds
+	// before the first probe, TCPKeepAliveInterval the seconds between unanswered probes and TCPKeepAliveCount
+	// the unanswered probes after which a connection is dropped, 0 keeps the Go default (15s, 15s and 9).
+	TCPKeepAlive         bool
+	TCPKeepAliveIdle     float64
+	TCPKeepAliveInterval float64
+	TCPKeepAliveCount    int
 }
 
 // ClientConfig holds client-specific configuration, embedding CommonConfig.
 type ClientConfig struct {
 	CommonConfig
-	Server         string
-	Rate           float64
-	MaxConcurrent  int
-	Protocol       string
-	MinDuration    float64
-	MaxDuration    float64
-	ConstantFlows  bool
-	TCPPorts       string
-	UDPPorts       string
-	PayloadSize    int
-	MinPayloadSize int
-	MaxPayloadSize int
+	Server string
+	// TargetCIDR spreads flows across the addresses of a prefix instead of sending them to server
+	TargetCIDR string
+	// TargetProbe limits the addresses of target_cidr to those answering a probe before the run
+	TargetProbe bool
+	// BackupServer takes over the flows of server once the failure rate of server within FailoverWindow
+	// seconds reaches FailoverThreshold percent
+	BackupServer      string
+	FailoverThreshold float64
+	FailoverWindow    float64
+	Rate              float64
+	MaxConcurrent     int
+	Protocol          string
+	MinDuration       float64
+	MaxDuration       float64
+	ConstantFlows     bool
+	TCPPorts          string
+	UDPPorts          string
+	PayloadSize       int
+	MinPayloadSize    int
+	MaxPayloadSize    int
+	// PayloadPattern is the content of payloads: cached, random, zeros, ascii-text or compressible
+	PayloadPattern string
+	// PayloadTemplate is a Go template rendered at the start of every message, e.g. "flow={{.FlowID}}"
+	PayloadTemplate string
+	// MimicPayloads replaces the payloads of flows to well-known ports (53, 123, 443, 3306, ...) with a plausible
+	// message of the port's protocol, such as a DNS query or a TLS ClientHello, padded toward the payload size
+	MimicPayloads bool
+	// MimicPorts maps further ports to the protocol their payloads mimic as comma-separated port=protocol
+	// pairs (e.g. "8053=dns,9443=tls"), overriding the well-known ports
+	MimicPorts string
+	// PayloadDistribution picks payload sizes: "uniform" between min and max_payload_size, "bimodal" either
+	// of them, "empirical" from the payload_sizes table
+	PayloadDistribution string
+	// PayloadLargeFraction is the fraction of max_payload_size payloads of the bimodal distribution
+	PayloadLargeFraction float64
+	// PayloadSizes is the size:weight table of the empirical distribution, or "imix"
+	PayloadSizes   string
 	MTU            int
 	MSS            int
+	WireL2Overhead int
 	FlowTimeout    float64
 	FlowCount      int
+	// StopCondition combines flow_count and flow_timeout: "any" stops at whichever is reached first, "all"
+	// generates flows until both are reached
+	StopCondition string
+	// ShutdownTimeout is how long in seconds active flows may take to end once flow generation stopped, after
+	// which the run is reported without them. 0 waits for all of them.
+	ShutdownTimeout float64
+	// PauseWindows are daily quiet windows in local time during which no new flows are started, given as
+	// comma-separated HH:MM-HH:MM ranges (e.g. "02:00-02:15"). A window ending before it starts spans midnight.
+	PauseWindows string
+	// Warmup is the time in seconds at the start of the run whose traffic is left out of the metrics, the
+	// summary and the SLA assertions
+	Warmup float64
+	// Seed seeds the random choice of ports, durations and payload sizes of flows, 0 picks a random seed
+	Seed uint64
+	// FlowHeader prefixes payloads with a flow header identifying the run, flow and request, so the server can
+	// detect duplicate flows and replayed datagrams
+	FlowHeader bool
+
+	DebugSampleFlows    int
+	DebugSampleInterval int
+	DebugHexDump        bool
+	// FlowVerbosity selects what is logged about individual flows regardless of the log level: 0 nothing,
+	// 1 failures, 2 the start and end of every flow as well, 3 every flow in full
+	FlowVerbosity int
+
+	ConnectionReuse bool
+	PoolSize        int
+
+	BurstSize        int
+	BurstInterval    float64
+	PortStartOffsets bool
+
+	// RateTransition is the time in seconds over which rate changes at runtime are ramped in, 0 applies them at once
+	RateTransition float64
+	// Steps is a stepped load profile replacing rate, semicolon-separated rate:duration[:name] entries
+	// (e.g. "100:2m;500:2m;1000:2m"). Flow generation stops after the last step.
+	Steps string
+
+	UDPInterval float64
+	UDPJitter   float64
+	// UDPBitrate sends the datagrams of UDP flows at a constant bitrate in bits per second with an optional
+	// k, M or G suffix (e.g. "10M"), replacing udp_interval and udp_jitter. Empty sends a datagram per
+	// udp_interval.
+	UDPBitrate string
+
+	BackpressureURL          string
+	BackpressurePollInterval float64
+	BackpressureFactor       float64
+
+	RelayChain string
+
+	StatusPort string
+	// ControlAPI lets the run be paused, resumed and its rate changed through the status server
+	ControlAPI bool
+	Scenario   string
+	// Meta holds comma-separated key=value pairs describing the experiment (owner, ticket, ...), carried into
+	// the run status, reports, the run_info metric and the flow log
+	Meta string
+
+	OutputFile   string
+	OutputFormat string
+	// LatencyHeatmapSlice is the length in seconds of the time slices of the latency heatmap in the JSON
+	// results, 0 disables the heatmap
+	LatencyHeatmapSlice float64
+
+	// FlowLogFile receives one JSON line per finished flow, "-" writes to stdout
+	FlowLogFile string
+
+	// ArtifactBundle is the .tgz, .tar.gz or .zip archive the config, report, flow log and logs of the run are
+	// packed into when it ends
+	ArtifactBundle string
+
+	// OutputSinks lists comma-separated destinations the results, flow log and artifact bundle are shipped to
+	// when the run ends: "-" for stdout, a directory, an http(s):// URL, s3://bucket/prefix or gs://bucket/prefix.
+	// A failed delivery is retried OutputSinkRetries times. Artifacts uploaded to S3 or GCS are logged with a
+	// presigned URL valid for OutputSinkURLExpiry seconds, 0 logs none.
+	OutputSinks         string
+	OutputSinkRetries   int
+	OutputSinkURLExpiry float64
+
+	// Output selects what is written to stdout: "text" for the metric tables, "ndjson" to stream stats and flows
+	Output        string
+	StatsInterval float64
+	// TUI redraws a live dashboard of the run on the terminal, showing the log entries within it
+	TUI bool
+	// ReportInterval is the interval in seconds between the reports of the flows, traffic and errors of the
+	// last interval written to the log while the run goes on, 0 disables them
+	ReportInterval float64
+	// ProgressEvents is where the lifecycle events of the run are written as JSON lines for orchestrators: "-" for
+	// stdout, "fd:N" for a file descriptor inherited from the parent process or a file path such as a named pipe.
+	// Empty disables them.
+	ProgressEvents string
+
+	// SLA assertions checked at the end of the run: the percentage of failed flows, the p99 round-trip latency
+	// in milliseconds per protocol and the echoed throughput in Mbit/s. 100 and 0 disable them.
+	MaxErrorRate  float64
+	MaxP99Latency float64
+	MinThroughput float64
+	// Phases splits the run into time-boxed phases with their own SLA assertions, semicolon-separated
+	// name:seconds[:assertion=value,...] entries (e.g. "steady:60:max_error_rate=0.1;chaos:120:max_error_rate=5")
+	Phases string
+	// WarnThresholds are soft limits checked over the last WarnWindow seconds while the run is in progress,
+	// comma-separated assertion=value pairs like those of phases (e.g. "max_error_rate=1,max_p99_latency=100").
+	// Crossing one logs a warning, posts it to WarnWebhook if set and marks it in the report without stopping
+	// the run.
+	WarnThresholds string
+	WarnWindow     float64
+	WarnWebhook    string
+
+	// TransportPorts maps ports to custom flow transports registered with the client (e.g. "9000=rpc")
+	TransportPorts string
+
+	// ExpectServices declares what is expected to answer on ports of the server (e.g. "8080=echo,443=tls"):
+	// echo, http, tls or none. The client fingerprints every declared port before the run and reports
+	// mismatches.
+	ExpectServices string
+	// ResponseSizes declares the ports whose responses the server sizes with response_sizes, in the same format,
+	// so the responses are checked against the size expected instead of the size sent
+	ResponseSizes string
+	// TLSServerNames are comma-separated SNI names the flows of the tls transport send in turn, without any they
+	// send the server address
+	TLSServerNames string
+	// TCPReadBuffer is the size in bytes of the reads of stream responses, 0 sizes them to the response from
+	// 1 KiB to 64 KiB
+	TCPReadBuffer int
+
+	// PriorityPorts maps ports to flow priority classes (e.g. "53=high"), unlisted ports are low priority
+	PriorityPorts string
+
+	// SourceCIDR is the range flows take their source addresses from (e.g. "10.1.0.0/16"), SourceAddresses
+	// lists the source addresses or network interfaces instead. SourceStrategy selects the source of each flow.
+	SourceCIDR      string
+	SourceAddresses string
+	SourceStrategy  string
+
+	// Netns lists the network namespaces flows are sent from, as comma-separated name[=share] entries where the
+	// name may also be a path (e.g. "client-a=2,/proc/1234/ns/net"). Names are looked up in /run/netns like ip
+	// netns does, and every namespace gets its share of the flows, 1 unless given.
+	Netns string
+
+	// SourcePortRange is the range of source ports flows are bound to in turn (e.g. "20000-59999"), so every
+	// flow gets its own source port until the range wraps. Empty leaves the source port to the kernel.
+	SourcePortRange string
+	// TrackTuples counts the flows whose 5-tuple was not used by a recent flow before
+	TrackTuples bool
+	// ConntrackBackoff backs off to ConntrackBackoffFactor of the flow rate once ConntrackBackoffThreshold percent
+	// of the flows within ConntrackBackoffWindow seconds time out or are refused after the flows had been
+	// completing, the signature of a full conntrack table on the path, and restores the rate once it recovered
+	ConntrackBackoff          bool
+	ConntrackBackoffThreshold float64
+	ConntrackBackoffWindow    float64
+	ConntrackBackoffFactor    float64
+
+	// LocalAddress is the local address all client connections are bound to, Interface the network interface
+	// they are bound to (SO_BINDTODEVICE), to force the traffic out a chosen NIC on multi-homed hosts
+	LocalAddress string
+	Interface    string
+
+	// AddressFamily selects the address family flows to a dual-stack server use: "any" leaves the choice to the
+	// resolver order, "ipv4" and "ipv6" force one family, "prefer-ipv4" and "prefer-ipv6" try one family first.
+	// HappyEyeballsDelay is the time in seconds after which the other family is raced, 0 waits for a failure.
+	AddressFamily      string
+	HappyEyeballsDelay float64
+
+	// UDPTimeoutMin and UDPTimeoutMax bound the response timeout of UDP requests in seconds, which is derived
+	// from the round trips measured per target. A UDPTimeoutMax of 0 waits a fixed second instead.
+	UDPTimeoutMin float64
+	UDPTimeoutMax float64
+	// ConnectTimeout bounds every connection attempt in seconds (0 for no limit below the flow duration).
+	// Failed attempts are retried ConnectRetries times, waiting ConnectBackoff seconds doubled per retry.
+	ConnectTimeout float64
+	ConnectRetries int
+	ConnectBackoff float64
+
+	// DSCP is the DSCP class (e.g. "ef", "af41" or 0-63) flows are marked with, DSCPPorts overrides it per port
+	DSCP      string
+	DSCPPorts string
+
+	// TTL is the IPv4 TTL and IPv6 hop limit of flows (0 for the system default), TTLPorts overrides it per port
+	TTL      int
+	TTLPorts string
+
+	// FlowLabel is the IPv6 flow label of flows: a fixed value (1-0xfffff), "random" for a random label per flow
+	// or empty to leave it to the kernel
+	FlowLabel string
+
+	// Role is "client" to only generate flows or "agent" to also serve the listeners of a server
+	Role string
+
+	// Peers lists the hosts the agent probes for the peer latency matrix, host names expand to all their
+	// addresses. Probes are UDP echo requests to PeerProbePort, PeerName identifies this agent in the matrix.
+	Peers             string
+	PeerProbePort     int
+	PeerProbeInterval float64
+	PeerProbeCount    int
+	PeerName          string
 }
 
 // ServerConfig holds server-specific configuration, embedding CommonConfig.
@@ -49,6 +323,72 @@ type ServerConfig struct {
 	TCPPortsServer string
 	UDPPortsServer string
 	HealthPort     string
+	ServiceModes   string
+	// ResponseDelays delays the echo responses of ports by a fixed or random amount, as comma-separated
+	// port=delay[±jitter] pairs of Go durations (e.g. "8081=50ms±10ms,8082=20ms")
+	ResponseDelays string
+	// ResponseDrops drops a percentage of the echo responses of ports instead of sending them, as comma-separated
+	// port=percent pairs (e.g. "8081=5")
+	ResponseDrops string
+	// ResponseSizes sizes the echo responses of ports instead of returning the request as is, as comma-separated
+	// port=mode:value pairs (e.g. "8081=truncate:64,8082=amplify:4,8083=fixed:1400")
+	ResponseSizes string
+	// GeneratorRates paces the streams of ports in generator mode, as comma-separated port=bitrate pairs with an
+	// optional k, M or G suffix (e.g. "8090=100M"). Ports without a rate stream as fast as the client reads.
+	GeneratorRates string
+	// HTTPEchoHeaders are the comma-separated request headers ports in http mode reflect in their responses,
+	// prefixed with X-Echo-
+	HTTPEchoHeaders string
+
+	RelayPortsServer string
+
+	// TLSPortsServer lists TCP ports or port ranges served over TLS, which follow their service mode like TCP ports
+	TLSPortsServer string
+	// TLSCertificates are the certificates of the TLS ports as comma-separated cert:key pairs of PEM files. A client
+	// gets the certificate matching its SNI, or the first one if none does. Without any, a self-signed
+	// certificate is generated for every SNI.
+	TLSCertificates string
+
+	// HandlerPorts maps ports to custom services registered with the server (e.g. "9092=kafka-mock")
+	HandlerPorts string
+
+	UDPConnectedPeers  bool
+	UDPPeerIdleTimeout float64
+	// UDPWorkers is the number of sockets bound to every UDP port with SO_REUSEPORT, each read by its own
+	// goroutine, so a port scales beyond one core
+	UDPWorkers int
+	// TCPReadBuffer and UDPReadBuffer are the sizes in bytes of the reads of TCP connections and UDP sockets,
+	// 0 reads 1 KiB from TCP connections and 64 KiB from UDP sockets
+	TCPReadBuffer int
+	UDPReadBuffer int
+	// ZeroCopyEcho echoes TCP connections with splice on Linux, so echoed data is not copied to user space
+	ZeroCopyEcho bool
+
+	// DuplicateWindow is how long in seconds flow headers are remembered to detect duplicate flows and
+	// replayed datagrams, 0 disables the detection
+	DuplicateWindow float64
+
+	BackpressureMaxConnections int
+	BackpressureMaxPPS         float64
+	BackpressureInterval       float64
+
+	// MaxConnections caps the simultaneous TCP connections of all listeners and MaxConnectionsPerListener those
+	// of each listener, 0 does not limit them
+	MaxConnections            int
+	MaxConnectionsPerListener int
+	// ConnectionLimitMode is what happens to connections over a limit, see ConnectionLimitReject and
+	// ConnectionLimitQueue
+	ConnectionLimitMode string
+	// AcceptRate is the number of TCP connections each listener accepts per second at most, 0 does not limit it
+	AcceptRate float64
+	// TCPListenSockets is the number of sockets bound to every TCP port with SO_REUSEPORT, each with its own
+	// accept loop, so accepting scales beyond one core
+	TCPListenSockets int
+
+	UpstreamServers  string
+	UpstreamFraction float64
+	UpstreamDepth    int
+	UpstreamTimeout  float64
 }
 
 // Validate validates the common configuration
@@ -63,9 +403,45 @@ func (c *CommonConfig) Validate() error {
 		return fmt.Errorf("invalid log format: %s, must be one of: %v", c.LogFormat, validLogFormats)
 	}
 
+	validLogThemes := []string{"auto", "color", "plain", "classic"}
+	if c.LogTheme != "" && !contains(validLogThemes, c.LogTheme) {
+		return fmt.Errorf("invalid log theme: %s, must be one of: %v", c.LogTheme, validLogThemes)
+	}
+
+	if c.OTLPMetricsInterval < 0 {
+		return fmt.Errorf("otlp_metrics_interval cannot be negative")
+	}
+
+	if c.StatsdAddress != "" && (c.StatsdSampleRate <= 0 || c.StatsdSampleRate > 1) {
+		return fmt.Errorf("statsd_sample_rate must be greater than 0 and at most 1")
+	}
+
+	if c.SocketSendBuffer < 0 {
+		return fmt.Errorf("socket_sndbuf cannot be negative")
+	}
+	if c.SocketReceiveBuffer < 0 {
+		return fmt.Errorf("socket_rcvbuf cannot be negative")
+	}
+	if c.TCPKeepAliveIdle < 0 || c.TCPKeepAliveInterval < 0 || c.TCPKeepAliveCount < 0 {
+		return fmt.Errorf("tcp_keepalive_idle, tcp_keepalive_interval and tcp_keepalive_count cannot be negative")
+	}
+	if !c.TCPKeepAlive && (c.TCPKeepAliveIdle > 0 || c.TCPKeepAliveInterval > 0 || c.TCPKeepAliveCount > 0) {
+		return fmt.Errorf("tcp_keepalive_idle, tcp_keepalive_interval and tcp_keepalive_count require tcp_keepalive")
+	}
+
 	return nil
 }
 
+// KeepAlive returns the TCP keepalive settings of the configuration
+func (c *CommonConfig) KeepAlive() sockopt.KeepAlive {
+	return sockopt.KeepAlive{
+		Disabled: !c.TCPKeepAlive,
+		Idle:     time.Duration(c.TCPKeepAliveIdle * float64(time.Second)),
+		Interval: time.Duration(c.TCPKeepAliveInterval * float64(time.Second)),
+		Count:    c.TCPKeepAliveCount,
+	}
+}
+
 // Validate validates the client configuration
 func (c *ClientConfig) Validate() error {
 	if err := c.CommonConfig.Validate(); err != nil {
@@ -75,6 +451,27 @@ func (c *ClientConfig) Validate() error {
 	if c.Server == "" {
 		return fmt.Errorf("server address cannot be empty")
 	}
+	if c.TargetCIDR != "" {
+		if _, err := ParseTargetCIDR(c.TargetCIDR); err != nil {
+			return err
+		}
+	} else if c.TargetProbe {
+		return fmt.Errorf("target_probe requires target_cidr")
+	}
+	if c.BackupServer != "" {
+		if c.TargetCIDR != "" {
+			return fmt.Errorf("backup_server cannot be combined with target_cidr")
+		}
+		if c.BackupServer == c.Server {
+			return fmt.Errorf("backup_server must differ from server")
+		}
+		if c.FailoverThreshold <= 0 || c.FailoverThreshold > 100 {
+			return fmt.Errorf("failover_threshold must be greater than 0 and at most 100")
+		}
+		if c.FailoverWindow <= 0 {
+			return fmt.Errorf("failover_window must be positive")
+		}
+	}
 
 	if c.Rate <= 0 {
 		return fmt.Errorf("rate must be positive")
@@ -97,167 +494,1936 @@ func (c *ClientConfig) Validate() error {
 		return fmt.Errorf("min_duration cannot be greater than max_duration")
 	}
 
-	if c.TCPPorts == "" && c.UDPPorts == "" {
+	if c.StopCondition != "" {
+		validStopConditions := []string{"any", "all"}
+		if !contains(validStopConditions, c.StopCondition) {
+			return fmt.Errorf("invalid stop_condition: %s, must be one of: %v", c.StopCondition, validStopConditions)
+		}
+	}
+	if c.ShutdownTimeout < 0 {
+		return fmt.Errorf("shutdown_timeout cannot be negative")
+	}
+	if c.PauseWindows != "" {
+		if _, err := ParsePauseWindows(c.PauseWindows); err != nil {
+			return fmt.Errorf("invalid pause_windows: %w", err)
+		}
+	}
+
+	if c.PayloadPattern != "" {
+		validPayloadPatterns := []string{"cached", "random", "zeros", "ascii-text", "compressible"}
+		if !contains(validPayloadPatterns, c.PayloadPattern) {
+			return fmt.Errorf("invalid payload_pattern: %s, must be one of: %v", c.PayloadPattern, validPayloadPatterns)
+		}
+	}
+	if c.PayloadTemplate != "" {
+		if _, err := ParsePayloadTemplate(c.PayloadTemplate); err != nil {
+			return err
+		}
+	}
+	if err := c.validatePayloadDistribution(); err != nil {
+		return err
+	}
+	mimicPorts, err := ParsePortMap(c.MimicPorts)
+	if err != nil {
+		return fmt.Errorf("invalid mimic_ports: %w", err)
+	}
+	for port, protocol := range mimicPorts {
+		if !contains(ValidMimicProtocols, protocol) {
+			return fmt.Errorf("invalid mimic_ports: %q for port %d, must be one of: %v", protocol, port, ValidMimicProtocols)
+		}
+	}
+	if c.MimicPayloads || c.MimicPorts != "" {
+		// These put their own content at the start of payloads, where the mimicked message goes
+		switch {
+		case c.FlowHeader:
+			return fmt.Errorf("payload mimicry cannot be combined with flow_header")
+		case c.PayloadTemplate != "":
+			return fmt.Errorf("payload mimicry cannot be combined with payload_template")
+		case c.UDPBitrate != "":
+			return fmt.Errorf("payload mimicry cannot be combined with udp_bitrate")
+		}
+	}
+
+	if c.Warmup < 0 {
+		return fmt.Errorf("warmup cannot be negative")
+	}
+	if c.FlowTimeout > 0 && c.Warmup >= c.FlowTimeout {
+		return fmt.Errorf("warmup must be shorter than flow_timeout")
+	}
+
+	if c.TCPPorts == "" && c.UDPPorts == "" && c.TransportPorts == "" {
 		return fmt.Errorf("at least one port (TCP or UDP) must be specified")
 	}
 
+	transportPorts, err := ParsePortMap(c.TransportPorts)
+	if err != nil {
+		return fmt.Errorf("invalid transport_ports: %w", err)
+	}
+	for port, transport := range transportPorts {
+		if transport == "" {
+			return fmt.Errorf("invalid transport_ports: no transport given for port %d", port)
+		}
+	}
+
+	expectServices, err := ParsePortMap(c.ExpectServices)
+	if err != nil {
+		return fmt.Errorf("invalid expect_service: %w", err)
+	}
+	for port, service := range expectServices {
+		if !contains(ValidServices, service) {
+			return fmt.Errorf("invalid expect_service: %q for port %d, must be one of: %v", service, port, ValidServices)
+		}
+	}
+	if _, err := ParseResponseSizes(c.ResponseSizes); err != nil {
+		return fmt.Errorf("invalid response_sizes: %w", err)
+	}
+	if c.TCPReadBuffer < 0 || c.TCPReadBuffer > MaxTCPReadBuffer {
+		return fmt.Errorf("tcp_read_buffer must be between 0 and %d", MaxTCPReadBuffer)
+	}
+
+	priorityPorts, err := ParsePortMap(c.PriorityPorts)
+	if err != nil {
+		return fmt.Errorf("invalid priority_ports: %w", err)
+	}
+	validPriorities := []string{"high", "low"}
+	for port, priority := range priorityPorts {
+		if !contains(validPriorities, priority) {
+			return fmt.Errorf("invalid priority_ports: priority %q of port %d must be one of: %v", priority, port, validPriorities)
+		}
+	}
+
+	if c.DSCP != "" {
+		if _, err := ParseDSCP(c.DSCP); err != nil {
+			return fmt.Errorf("invalid dscp: %w", err)
+		}
+	}
+	dscpPorts, err := ParsePortMap(c.DSCPPorts)
+	if err != nil {
+		return fmt.Errorf("invalid dscp_ports: %w", err)
+	}
+	for port, class := range dscpPorts {
+		if _, err := ParseDSCP(class); err != nil {
+			return fmt.Errorf("invalid dscp_ports: port %d: %w", port, err)
+		}
+	}
+
+	if c.TTL < 0 || c.TTL > 255 {
+		return fmt.Errorf("ttl must be between 0 and 255")
+	}
+	ttlPorts, err := ParsePortMap(c.TTLPorts)
+	if err != nil {
+		return fmt.Errorf("invalid ttl_ports: %w", err)
+	}
+	for port, value := range ttlPorts {
+		if ttl, err := strconv.Atoi(value); err != nil || ttl < 1 || ttl > 255 {
+			return fmt.Errorf("invalid ttl_ports: TTL %q of port %d must be between 1 and 255", value, port)
+		}
+	}
+
+	if c.FlowLabel != "" {
+		if _, _, err := ParseFlowLabel(c.FlowLabel); err != nil {
+			return fmt.Errorf("invalid flow_label: %w", err)
+		}
+		// The label is set while connecting, which leaves no room for binding a local address or taking over
+		// connections that are already established
+		if c.ConnectionReuse || c.RelayChain != "" || c.SourceCIDR != "" || c.SourceAddresses != "" || c.LocalAddress != "" {
+			return fmt.Errorf("flow_label cannot be combined with connection_reuse, relay_chain, source_cidr, source_addresses or local_address")
+		}
+	}
+
 	if c.MTU <= 0 || c.MSS <= 0 {
 		return fmt.Errorf("MTU and MSS must be positive")
 	}
 
+	if c.WireL2Overhead < 0 {
+		return fmt.Errorf("wire_l2_overhead cannot be negative")
+	}
+
 	if c.MSS >= c.MTU {
 		return fmt.Errorf("MSS must be less than MTU")
 	}
 
-	return nil
-}
+	if c.DebugSampleFlows < 0 || c.DebugSampleInterval < 0 {
+		return fmt.Errorf("debug_sample_flows and debug_sample_interval cannot be negative")
+	}
 
-// Validate validates the server configuration
-func (c *ServerConfig) Validate() error {
-	if err := c.CommonConfig.Validate(); err != nil {
-		return err
+	if c.FlowVerbosity < 0 || c.FlowVerbosity > 3 {
+		return fmt.Errorf("invalid flow_verbosity: %d, must be between 0 and 3", c.FlowVerbosity)
 	}
 
-	if c.TCPPortsServer == "" && c.UDPPortsServer == "" {
-		return fmt.Errorf("at least one port (TCP or UDP) must be specified")
+	if c.ConnectionReuse && c.PoolSize <= 0 {
+		return fmt.Errorf("pool_size must be positive when connection_reuse is enabled")
 	}
 
-	return nil
-}
+	if c.BurstSize < 0 {
+		return fmt.Errorf("burst_size cannot be negative")
+	}
 
-// LoadClientConfig loads and returns the client configuration.
-func LoadClientConfig() (*ClientConfig, error) {
-	initViper()
-	setClientDefaults()
+	if c.BurstSize > 0 && c.BurstInterval <= 0 {
+		return fmt.Errorf("burst_interval must be positive when burst_size is set")
+	}
 
-	// Bind command-line flags
-	if err := viper.BindPFlags(pflag.CommandLine); err != nil {
-		return nil, fmt.Errorf("failed to bind command-line flags: %w", err)
+	if c.RateTransition < 0 {
+		return fmt.Errorf("rate_transition cannot be negative")
 	}
 
-	// Populate ClientConfig
-	config := &ClientConfig{
-		CommonConfig: CommonConfig{
-			LogLevel:       viper.GetString("log_level"),
-			LogFormat:      viper.GetString("log_format"),
-			MetricsPort:    viper.GetString("metrics_port"),
-			TracingEnabled: viper.GetBool("tracing_enabled"),
-			JaegerEndpoint: viper.GetString("jaeger_endpoint"),
-		},
-		Server:         viper.GetString("server"),
-		Rate:           viper.GetFloat64("rate"),
-		MaxConcurrent:  viper.GetInt("max_concurrent"),
-		Protocol:       viper.GetString("protocol"),
-		MinDuration:    viper.GetFloat64("min_duration"),
-		MaxDuration:    viper.GetFloat64("max_duration"),
-		ConstantFlows:  viper.GetBool("constant_flows"),
-		TCPPorts:       viper.GetString("tcp_ports"),
-		UDPPorts:       viper.GetString("udp_ports"),
-		PayloadSize:    viper.GetInt("payload_size"),
-		MinPayloadSize: viper.GetInt("min_payload_size"),
-		MaxPayloadSize: viper.GetInt("max_payload_size"),
-		MTU:            viper.GetInt("mtu"),
-		MSS:            viper.GetInt("mss"),
-		FlowTimeout:    viper.GetFloat64("flow_timeout"),
-		FlowCount:      viper.GetInt("flow_count"),
+	if c.Steps != "" {
+		if _, err := ParseSteps(c.Steps); err != nil {
+			return fmt.Errorf("invalid step: %w", err)
+		}
+		if c.BurstSize > 0 {
+			return fmt.Errorf("step cannot be combined with burst mode")
+		}
 	}
 
-	// Validate configuration
-	if err := config.Validate(); err != nil {
-		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	if c.UDPInterval < 0 || c.UDPJitter < 0 {
+		return fmt.Errorf("udp_interval and udp_jitter cannot be negative")
+	}
+	if c.UDPBitrate != "" {
+		if _, err := ParseBitrate(c.UDPBitrate); err != nil {
+			return fmt.Errorf("invalid udp_bitrate: %w", err)
+		}
 	}
 
-	return config, nil
-}
+	if c.BackpressureURL != "" {
+		if c.BackpressurePollInterval <= 0 {
+			return fmt.Errorf("backpressure_poll_interval must be positive when backpressure_url is set")
+		}
+		if c.BackpressureFactor <= 0 || c.BackpressureFactor > 1 {
+			return fmt.Errorf("backpressure_factor must be greater than 0 and at most 1")
+		}
+	}
 
-// LoadServerConfig loads and returns the server configuration.
-func LoadServerConfig() (*ServerConfig, error) {
-	initViper()
-	setServerDefaults()
+	if c.RelayChain != "" {
+		if c.Protocol != "tcp" {
+			return fmt.Errorf("relay_chain requires protocol tcp")
+		}
+		if c.ConnectionReuse {
+			return fmt.Errorf("relay_chain cannot be combined with connection_reuse")
+		}
+	}
 
-	// Bind command-line flags
-	if err := viper.BindPFlags(pflag.CommandLine); err != nil {
-		return nil, fmt.Errorf("failed to bind command-line flags: %w", err)
+	if c.SourceCIDR != "" {
+		if _, err := netip.ParsePrefix(c.SourceCIDR); err != nil {
+			return fmt.Errorf("invalid source_cidr: %w", err)
+		}
+		if c.ConnectionReuse || c.RelayChain != "" {
+			return fmt.Errorf("source_cidr cannot be combined with connection_reuse or relay_chain")
+		}
 	}
 
-	// Populate ServerConfig
-	config := &ServerConfig{
-		CommonConfig: CommonConfig{
-			LogLevel:       viper.GetString("log_level"),
-			LogFormat:      viper.GetString("log_format"),
-			MetricsPort:    viper.GetString("metrics_port"),
-			TracingEnabled: viper.GetBool("tracing_enabled"),
-			JaegerEndpoint: viper.GetString("jaeger_endpoint"),
-		},
-		TCPPortsServer: viper.GetString("tcp_ports_server"),
-		UDPPortsServer: viper.GetString("udp_ports_server"),
-		HealthPort:     viper.GetString("health_port"),
+	if c.SourceAddresses != "" {
+		if _, err := ParseSources(c.SourceAddresses); err != nil {
+			return fmt.Errorf("invalid source_addresses: %w", err)
+		}
+		if c.SourceCIDR != "" || c.LocalAddress != "" || c.Interface != "" {
+			return fmt.Errorf("source_addresses cannot be combined with source_cidr, local_address or interface")
+		}
+		if c.ConnectionReuse || c.RelayChain != "" {
+			return fmt.Errorf("source_addresses cannot be combined with connection_reuse or relay_chain")
+		}
 	}
 
-	// Validate configuration
-	if err := config.Validate(); err != nil {
-		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	if c.Netns != "" {
+		if _, err := ParseNetns(c.Netns); err != nil {
+			return fmt.Errorf("invalid netns: %w", err)
+		}
+		// Pooled connections were dialed from any namespace, and interfaces are looked up in the client's own
+		if c.ConnectionReuse || c.SourceAddresses != "" || c.Interface != "" {
+			return fmt.Errorf("netns cannot be combined with connection_reuse, source_addresses or interface")
+		}
+		// Preferring an address family races the other one from another thread, which stays in the client's
+		// namespace
+		if c.AddressFamily == "prefer-ipv4" || c.AddressFamily == "prefer-ipv6" {
+			return fmt.Errorf("netns cannot be combined with address_family prefer-ipv4 or prefer-ipv6")
+		}
 	}
 
-	return config, nil
-}
+	if c.SourcePortRange != "" {
+		if _, _, err := ParsePortRange(c.SourcePortRange); err != nil {
+			return fmt.Errorf("invalid source_port_range: %w", err)
+		}
+		if c.ConnectionReuse || c.RelayChain != "" {
+			return fmt.Errorf("source_port_range cannot be combined with connection_reuse or relay_chain")
+		}
+	}
+	if c.ConntrackBackoff {
+		if c.ConntrackBackoffThreshold <= 0 || c.ConntrackBackoffThreshold > 100 {
+			return fmt.Errorf("conntrack_backoff_threshold must be greater than 0 and at most 100")
+		}
+		if c.ConntrackBackoffWindow <= 0 {
+			return fmt.Errorf("conntrack_backoff_window must be positive")
+		}
+		if c.ConntrackBackoffFactor <= 0 || c.ConntrackBackoffFactor >= 1 {
+			return fmt.Errorf("conntrack_backoff_factor must be greater than 0 and less than 1")
+		}
+	}
 
-// initViper initializes viper with common settings
-func initViper() {
-	viper.SetEnvPrefix(EnvPrefix)
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-	viper.AutomaticEnv()
+	if c.AddressFamily != "" {
+		validFamilies := []string{"any", "ipv4", "ipv6", "prefer-ipv4", "prefer-ipv6"}
+		if !contains(validFamilies, c.AddressFamily) {
+			return fmt.Errorf("invalid address_family: %s, must be one of: %v", c.AddressFamily, validFamilies)
+		}
+	}
+	if c.HappyEyeballsDelay < 0 {
+		return fmt.Errorf("happy_eyeballs_delay cannot be negative")
+	}
 
-	// Load config file (if present)
-	viper.SetConfigName("config")
-	viper.AddConfigPath(".")
-	viper.AddConfigPath("/etc/flow-generator")
-	viper.AddConfigPath("$HOME/.flow-generator")
+	if c.UDPTimeoutMin < 0 {
+		return fmt.Errorf("udp_timeout_min cannot be negative")
+	}
+	if c.UDPTimeoutMax < 0 {
+		return fmt.Errorf("udp_timeout_max cannot be negative")
+	}
+	if c.UDPTimeoutMax > 0 && c.UDPTimeoutMax < c.UDPTimeoutMin {
+		return fmt.Errorf("udp_timeout_max must be at least udp_timeout_min")
+	}
+	if c.ConnectTimeout < 0 {
+		return fmt.Errorf("connect_timeout cannot be negative")
+	}
+	if c.ConnectRetries < 0 {
+		return fmt.Errorf("connect_retries cannot be negative")
+	}
+	if c.ConnectBackoff < 0 {
+		return fmt.Errorf("connect_backoff cannot be negative")
+	}
 
-	// Ignore if config file is not found
-	_ = viper.ReadInConfig()
-}
+	if c.SourceStrategy != "" {
+		validStrategies := []string{"round-robin", "random", "hash"}
+		if !contains(validStrategies, c.SourceStrategy) {
+			return fmt.Errorf("invalid source_strategy: %s, must be one of: %v", c.SourceStrategy, validStrategies)
+		}
+	}
 
-// setCommonDefaults sets default values for common configuration
-func setCommonDefaults() {
-	viper.SetDefault("log_level", "info")
-	viper.SetDefault("log_format", "human")
-	viper.SetDefault("metrics_port", "9090")
-	viper.SetDefault("tracing_enabled", false)
-	viper.SetDefault("jaeger_endpoint", "http://localhost:14268/api/traces")
-}
+	if c.LocalAddress != "" {
+		if _, err := netip.ParseAddr(c.LocalAddress); err != nil {
+			return fmt.Errorf("invalid local_address: %w", err)
+		}
+		if c.SourceCIDR != "" {
+			return fmt.Errorf("local_address cannot be combined with source_cidr")
+		}
+	}
 
-// setClientDefaults sets default values for client configuration
-func setClientDefaults() {
-	setCommonDefaults()
+	// Interface names are limited to IFNAMSIZ (16) bytes including the terminating NUL
+	if len(c.Interface) > 15 {
+		return fmt.Errorf("invalid interface: %q is longer than 15 characters", c.Interface)
+	}
 
-	// Client-specific defaults
-	viper.SetDefault("server", "localhost")
-	viper.SetDefault("rate", 10.0)
-	viper.SetDefault("max_concurrent", 100)
-	viper.SetDefault("protocol", "both")
-	viper.SetDefault("min_duration", 1.0)
-	viper.SetDefault("max_duration", 10.0)
-	viper.SetDefault("constant_flows", false)
-	viper.SetDefault("tcp_ports", "8080")
-	viper.SetDefault("udp_ports", "")
-	viper.SetDefault("payload_size", 0)
-	viper.SetDefault("min_payload_size", 0)
-	viper.SetDefault("max_payload_size", 0)
-	viper.SetDefault("mtu", 1500)
-	viper.SetDefault("mss", 1460)
-	viper.SetDefault("flow_timeout", 0.0)
-	viper.SetDefault("flow_count", 0)
-}
+	if c.Role != "" {
+		validRoles := []string{"client", "agent"}
+		if !contains(validRoles, c.Role) {
+			return fmt.Errorf("invalid role: %s, must be one of: %v", c.Role, validRoles)
+		}
+	}
 
-// setServerDefaults sets default values for server configuration
+	if c.Peers != "" {
+		if c.Role != "agent" {
+			return fmt.Errorf("peers requires role agent")
+		}
+		if c.PeerProbePort <= 0 || c.PeerProbePort > 65535 {
+			return fmt.Errorf("peer_probe_port must be between 1 and 65535 when peers is set")
+		}
+		if c.PeerProbeInterval <= 0 || c.PeerProbeCount <= 0 {
+			return fmt.Errorf("peer_probe_interval and peer_probe_count must be positive when peers is set")
+		}
+	}
+
+	validOutputFormats := []string{"json", "csv", "junit", "html"}
+	if (c.OutputFile != "" || c.OutputSinks != "") && !contains(validOutputFormats, c.OutputFormat) {
+		return fmt.Errorf("invalid output format: %s, must be one of: %v", c.OutputFormat, validOutputFormats)
+	}
+	if c.LatencyHeatmapSlice < 0 {
+		return fmt.Errorf("latency_heatmap_slice cannot be negative")
+	}
+
+	if c.Output != "" {
+		validOutputs := []string{"text", "ndjson"}
+		if !contains(validOutputs, c.Output) {
+			return fmt.Errorf("invalid output: %s, must be one of: %v", c.Output, validOutputs)
+		}
+	}
+
+	if c.Output == "ndjson" {
+		if c.StatsInterval <= 0 {
+			return fmt.Errorf("stats_interval must be positive when output is ndjson")
+		}
+		if c.FlowLogFile == "-" {
+			return fmt.Errorf("flow_log_file cannot write to stdout when output is ndjson, flows are already streamed")
+		}
+	}
+
+	if c.ReportInterval < 0 {
+		return fmt.Errorf("report_interval cannot be negative")
+	}
+
+	if c.TUI {
+		if c.Output == "ndjson" {
+			return fmt.Errorf("tui cannot be combined with output ndjson, both write to stdout")
+		}
+		if c.FlowLogFile == "-" || c.ProgressEvents == "-" {
+			return fmt.Errorf("tui cannot be combined with flow_log_file or progress_events writing to stdout")
+		}
+	}
+
+	if fd, ok := strings.CutPrefix(c.ProgressEvents, "fd:"); ok {
+		if n, err := strconv.Atoi(fd); err != nil || n < 3 {
+			return fmt.Errorf("progress_events file descriptor must be 3 or higher, use - for stdout")
+		}
+	}
+	if c.ProgressEvents == "-" {
+		if c.Output == "ndjson" {
+			return fmt.Errorf("progress_events cannot write to stdout when output is ndjson")
+		}
+		if c.FlowLogFile == "-" {
+			return fmt.Errorf("progress_events cannot write to stdout when flow_log_file does")
+		}
+	}
+
+	if c.MaxErrorRate < 0 || c.MaxErrorRate > 100 {
+		return fmt.Errorf("max_error_rate must be between 0 and 100 percent")
+	}
+	if c.MaxP99Latency < 0 {
+		return fmt.Errorf("max_p99_latency cannot be negative")
+	}
+	if c.MinThroughput < 0 {
+		return fmt.Errorf("min_throughput cannot be negative")
+	}
+	if c.Phases != "" {
+		if _, err := ParsePhases(c.Phases); err != nil {
+			return fmt.Errorf("invalid phase: %w", err)
+		}
+	}
+	if c.WarnThresholds != "" {
+		if _, err := ParseWarnThresholds(c.WarnThresholds); err != nil {
+			return fmt.Errorf("invalid warn_thresholds: %w", err)
+		}
+		if c.WarnWindow <= 0 {
+			return fmt.Errorf("warn_window must be positive when warn_thresholds is set")
+		}
+	}
+	if c.WarnWebhook != "" {
+		if c.WarnThresholds == "" {
+			return fmt.Errorf("warn_webhook requires warn_thresholds")
+		}
+		if u, err := url.Parse(c.WarnWebhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("warn_webhook must be an http or https URL")
+		}
+	}
+
+	if c.ArtifactBundle != "" && ArchiveFormat(c.ArtifactBundle) == "" {
+		return fmt.Errorf("artifact_bundle must end in .tgz, .tar.gz or .zip")
+	}
+
+	for _, destination := range strings.Split(c.OutputSinks, ",") {
+		destination = strings.TrimSpace(destination)
+		if destination == "" {
+			continue
+		}
+		if _, err := sink.New(destination); err != nil {
+			return fmt.Errorf("invalid output_sink: %w", err)
+		}
+		if destination == "-" && c.Output == "ndjson" {
+			return fmt.Errorf("output_sink cannot write to stdout when output is ndjson")
+		}
+		if destination == "-" && c.ProgressEvents == "-" {
+			return fmt.Errorf("output_sink cannot write to stdout when progress_events does")
+		}
+	}
+	if c.OutputSinkRetries < 0 {
+		return fmt.Errorf("output_sink_retries cannot be negative")
+	}
+	if c.OutputSinkURLExpiry < 0 || c.OutputSinkURLExpiry > sink.MaxURLExpiry.Seconds() {
+		return fmt.Errorf("output_sink_url_expiry must be between 0 and %.0f seconds", sink.MaxURLExpiry.Seconds())
+	}
+
+	if c.ControlAPI && c.StatusPort == "" {
+		return fmt.Errorf("control_api requires status_port")
+	}
+
+	if _, err := ParseMetadata(c.Meta); err != nil {
+		return fmt.Errorf("invalid meta: %w", err)
+	}
+
+	return nil
+}
+
+// Validate validates the server configuration
+func (c *ServerConfig) Validate() error {
+	if err := c.CommonConfig.Validate(); err != nil {
+		return err
+	}
+
+	if c.TCPPortsServer == "" && c.UDPPortsServer == "" && c.RelayPortsServer == "" && c.TLSPortsServer == "" && c.HandlerPorts == "" {
+		return fmt.Errorf("at least one port (TCP or UDP) must be specified")
+	}
+
+	modes, err := ParsePortMap(c.ServiceModes)
+	if err != nil {
+		return fmt.Errorf("invalid service_modes: %w", err)
+	}
+	// Relay is not selectable per port, relay ports are configured with relay_ports_server
+	validModes := []string{service.ModeEcho, service.ModeDiscard, service.ModeSink, service.ModeChargen, service.ModeGenerator, service.ModeHTTP}
+	for port, mode := range modes {
+		if !contains(validModes, mode) {
+			return fmt.Errorf("invalid service mode %q for port %d, must be one of: %v", mode, port, validModes)
+		}
+	}
+
+	if _, err := ParseResponseDelays(c.ResponseDelays); err != nil {
+		return fmt.Errorf("invalid response_delays: %w", err)
+	}
+	if _, err := ParseResponseDrops(c.ResponseDrops); err != nil {
+		return fmt.Errorf("invalid response_drops: %w", err)
+	}
+	if _, err := ParseResponseSizes(c.ResponseSizes); err != nil {
+		return fmt.Errorf("invalid response_sizes: %w", err)
+	}
+	if _, err := ParseGeneratorRates(c.GeneratorRates); err != nil {
+		return fmt.Errorf("invalid generator_rates: %w", err)
+	}
+	if _, err := ParseHeaderNames(c.HTTPEchoHeaders); err != nil {
+		return fmt.Errorf("invalid http_echo_headers: %w", err)
+	}
+
+	if _, err := LoadTLSCertificates(c.TLSCertificates); err != nil {
+		return fmt.Errorf("invalid tls_certificates: %w", err)
+	}
+
+	handlerPorts, err := ParsePortMap(c.HandlerPorts)
+	if err != nil {
+		return fmt.Errorf("invalid handler_ports: %w", err)
+	}
+	for port, service := range handlerPorts {
+		if service == "" {
+			return fmt.Errorf("invalid handler_ports: no service given for port %d", port)
+		}
+	}
+
+	if c.UDPConnectedPeers && c.UDPPeerIdleTimeout <= 0 {
+		return fmt.Errorf("udp_peer_idle_timeout must be positive when udp_connected_peers is enabled")
+	}
+	if c.UDPWorkers < 0 {
+		return fmt.Errorf("udp_workers cannot be negative")
+	}
+	if c.TCPReadBuffer < 0 || c.TCPReadBuffer > MaxTCPReadBuffer {
+		return fmt.Errorf("tcp_read_buffer must be between 0 and %d", MaxTCPReadBuffer)
+	}
+	if c.UDPReadBuffer < 0 || c.UDPReadBuffer > MaxUDPReadBuffer {
+		return fmt.Errorf("udp_read_buffer must be between 0 and %d", MaxUDPReadBuffer)
+	}
+
+	if c.DuplicateWindow < 0 {
+		return fmt.Errorf("duplicate_window cannot be negative")
+	}
+
+	if c.BackpressureMaxConnections < 0 || c.BackpressureMaxPPS < 0 {
+		return fmt.Errorf("backpressure_max_connections and backpressure_max_pps cannot be negative")
+	}
+
+	if (c.BackpressureMaxConnections > 0 || c.BackpressureMaxPPS > 0) && c.BackpressureInterval <= 0 {
+		return fmt.Errorf("backpressure_interval must be positive when a backpressure threshold is set")
+	}
+
+	if c.MaxConnections < 0 || c.MaxConnectionsPerListener < 0 {
+		return fmt.Errorf("max_connections and max_connections_per_listener cannot be negative")
+	}
+	validLimitModes := []string{ConnectionLimitReject, ConnectionLimitQueue}
+	if (c.MaxConnections > 0 || c.MaxConnectionsPerListener > 0) && !contains(validLimitModes, c.ConnectionLimitMode) {
+		return fmt.Errorf("invalid connection_limit_mode %q, must be one of: %v", c.ConnectionLimitMode, validLimitModes)
+	}
+
+	if c.AcceptRate < 0 {
+		return fmt.Errorf("accept_rate cannot be negative")
+	}
+	if c.TCPListenSockets < 0 {
+		return fmt.Errorf("tcp_listen_sockets cannot be negative")
+	}
+
+	if c.UpstreamFraction < 0 || c.UpstreamFraction > 1 {
+		return fmt.Errorf("upstream_fraction must be between 0 and 1")
+	}
+
+	if c.UpstreamServers != "" {
+		if c.UpstreamDepth <= 0 {
+			return fmt.Errorf("upstream_depth must be positive when upstream_servers is set")
+		}
+		if c.UpstreamTimeout <= 0 {
+			return fmt.Errorf("upstream_timeout must be positive when upstream_servers is set")
+		}
+	}
+
+	return nil
+}
+
+// LoadClientConfig loads and returns the client configuration.
+func LoadClientConfig() (*ClientConfig, error) {
+	initViper()
+	setClientDefaults()
+
+	// Bind command-line flags
+	if err := viper.BindPFlags(pflag.CommandLine); err != nil {
+		return nil, fmt.Errorf("failed to bind command-line flags: %w", err)
+	}
+	if err := applyProfiles(); err != nil {
+		return nil, fmt.Errorf("failed to apply profiles: %w", err)
+	}
+
+	// Populate ClientConfig
+	config := &ClientConfig{
+		CommonConfig: CommonConfig{
+			LogLevel:       viper.GetString("log_level"),
+			LogFormat:      viper.GetString("log_format"),
+			LogTheme:       viper.GetString("log_theme"),
+			MetricsPort:    viper.GetString("metrics_port"),
+			TracingEnabled: viper.GetBool("tracing_enabled"),
+			JaegerEndpoint: viper.GetString("jaeger_endpoint"),
+
+			OTLPMetricsEnabled:  viper.GetBool("otlp_metrics_enabled"),
+			OTLPMetricsInterval: viper.GetFloat64("otlp_metrics_interval"),
+
+			StatsdAddress:    viper.GetString("statsd_address"),
+			StatsdSampleRate: viper.GetFloat64("statsd_sample_rate"),
+
+			ProfileDir: viper.GetString("profile_dir"),
+			Profile:    viper.GetString("profile"),
+
+			TCPNoDelay:          viper.GetBool("tcp_nodelay"),
+			SocketSendBuffer:    viper.GetInt("socket_sndbuf"),
+			SocketReceiveBuffer: viper.GetInt("socket_rcvbuf"),
+
+			TCPKeepAlive:         viper.GetBool("tcp_keepalive"),
+			TCPKeepAliveIdle:     viper.GetFloat64("tcp_keepalive_idle"),
+			TCPKeepAliveInterval: viper.GetFloat64("tcp_keepalive_interval"),
+			TCPKeepAliveCount:    viper.GetInt("tcp_keepalive_count"),
+		},
+		Server:            viper.GetString("server"),
+		TargetCIDR:        viper.GetString("target_cidr"),
+		TargetProbe:       viper.GetBool("target_probe"),
+		BackupServer:      viper.GetString("backup_server"),
+		FailoverThreshold: viper.GetFloat64("failover_threshold"),
+		FailoverWindow:    viper.GetFloat64("failover_window"),
+		Rate:              viper.GetFloat64("rate"),
+		MaxConcurrent:     viper.GetInt("max_concurrent"),
+		Protocol:          viper.GetString("protocol"),
+		MinDuration:       viper.GetFloat64("min_duration"),
+		MaxDuration:       viper.GetFloat64("max_duration"),
+		ConstantFlows:     viper.GetBool("constant_flows"),
+		TCPPorts:          viper.GetString("tcp_ports"),
+		UDPPorts:          viper.GetString("udp_ports"),
+		PayloadSize:       viper.GetInt("payload_size"),
+		MinPayloadSize:    viper.GetInt("min_payload_size"),
+		MaxPayloadSize:    viper.GetInt("max_payload_size"),
+
+		PayloadPattern:       viper.GetString("payload_pattern"),
+		PayloadTemplate:      viper.GetString("payload_template"),
+		MimicPayloads:        viper.GetBool("mimic_payloads"),
+		MimicPorts:           viper.GetString("mimic_ports"),
+		PayloadDistribution:  viper.GetString("payload_distribution"),
+		PayloadLargeFraction: viper.GetFloat64("payload_large_fraction"),
+		PayloadSizes:         viper.GetString("payload_sizes"),
+		FlowHeader:           viper.GetBool("flow_header"),
+		MTU:                  viper.GetInt("mtu"),
+		MSS:                  viper.GetInt("mss"),
+		WireL2Overhead:       viper.GetInt("wire_l2_overhead"),
+		FlowTimeout:          viper.GetFloat64("flow_timeout"),
+		FlowCount:            viper.GetInt("flow_count"),
+		StopCondition:        viper.GetString("stop_condition"),
+		ShutdownTimeout:      viper.GetFloat64("shutdown_timeout"),
+		PauseWindows:         strings.Join(viper.GetStringSlice("pause_windows"), ","),
+		Warmup:               viper.GetFloat64("warmup"),
+		Seed:                 viper.GetUint64("seed"),
+
+		DebugSampleFlows:    viper.GetInt("debug_sample_flows"),
+		DebugSampleInterval: viper.GetInt("debug_sample_interval"),
+		DebugHexDump:        viper.GetBool("debug_hex_dump"),
+		FlowVerbosity:       viper.GetInt("flow_verbosity"),
+
+		ConnectionReuse: viper.GetBool("connection_reuse"),
+		PoolSize:        viper.GetInt("pool_size"),
+
+		BurstSize:        viper.GetInt("burst_size"),
+		BurstInterval:    viper.GetFloat64("burst_interval"),
+		PortStartOffsets: viper.GetBool("port_start_offsets"),
+
+		RateTransition: viper.GetFloat64("rate_transition"),
+		Steps:          strings.Join(viper.GetStringSlice("step"), ";"),
+
+		UDPInterval: viper.GetFloat64("udp_interval"),
+		UDPJitter:   viper.GetFloat64("udp_jitter"),
+		UDPBitrate:  viper.GetString("udp_bitrate"),
+
+		BackpressureURL:          viper.GetString("backpressure_url"),
+		BackpressurePollInterval: viper.GetFloat64("backpressure_poll_interval"),
+		BackpressureFactor:       viper.GetFloat64("backpressure_factor"),
+
+		RelayChain: viper.GetString("relay_chain"),
+
+		StatusPort: viper.GetString("status_port"),
+		ControlAPI: viper.GetBool("control_api"),
+		Scenario:   viper.GetString("scenario"),
+		Meta:       strings.Join(viper.GetStringSlice("meta"), ","),
+
+		OutputFile:   viper.GetString("output_file"),
+		OutputFormat: viper.GetString("output_format"),
+
+		LatencyHeatmapSlice: viper.GetFloat64("latency_heatmap_slice"),
+
+		FlowLogFile: viper.GetString("flow_log_file"),
+
+		ArtifactBundle: viper.GetString("artifact_bundle"),
+
+		OutputSinks:         strings.Join(viper.GetStringSlice("output_sink"), ","),
+		OutputSinkRetries:   viper.GetInt("output_sink_retries"),
+		OutputSinkURLExpiry: viper.GetFloat64("output_sink_url_expiry"),
+
+		Output:         viper.GetString("output"),
+		StatsInterval:  viper.GetFloat64("stats_interval"),
+		TUI:            viper.GetBool("tui"),
+		ReportInterval: viper.GetFloat64("report_interval"),
+		ProgressEvents: viper.GetString("progress_events"),
+
+		MaxErrorRate:  viper.GetFloat64("max_error_rate"),
+		MaxP99Latency: viper.GetFloat64("max_p99_latency"),
+		MinThroughput: viper.GetFloat64("min_throughput"),
+		Phases:        strings.Join(viper.GetStringSlice("phase"), ";"),
+
+		WarnThresholds: viper.GetString("warn_thresholds"),
+		WarnWindow:     viper.GetFloat64("warn_window"),
+		WarnWebhook:    viper.GetString("warn_webhook"),
+
+		TransportPorts: viper.GetString("transport_ports"),
+		ExpectServices: viper.GetString("expect_service"),
+		ResponseSizes:  viper.GetString("response_sizes"),
+		TLSServerNames: viper.GetString("tls_server_names"),
+		TCPReadBuffer:  viper.GetInt("tcp_read_buffer"),
+
+		PriorityPorts: viper.GetString("priority_ports"),
+
+		SourceCIDR:      viper.GetString("source_cidr"),
+		SourceAddresses: viper.GetString("source_addresses"),
+		SourceStrategy:  viper.GetString("source_strategy"),
+		Netns:           viper.GetString("netns"),
+		SourcePortRange: viper.GetString("source_port_range"),
+		TrackTuples:     viper.GetBool("track_tuples"),
+
+		ConntrackBackoff:          viper.GetBool("conntrack_backoff"),
+		ConntrackBackoffThreshold: viper.GetFloat64("conntrack_backoff_threshold"),
+		ConntrackBackoffWindow:    viper.GetFloat64("conntrack_backoff_window"),
+		ConntrackBackoffFactor:    viper.GetFloat64("conntrack_backoff_factor"),
+
+		LocalAddress: viper.GetString("local_address"),
+		Interface:    viper.GetString("interface"),
+
+		AddressFamily:      viper.GetString("address_family"),
+		HappyEyeballsDelay: viper.GetFloat64("happy_eyeballs_delay"),
+
+		ConnectTimeout: viper.GetFloat64("connect_timeout"),
+		UDPTimeoutMin:  viper.GetFloat64("udp_timeout_min"),
+		UDPTimeoutMax:  viper.GetFloat64("udp_timeout_max"),
+		ConnectRetries: viper.GetInt("connect_retries"),
+		ConnectBackoff: viper.GetFloat64("connect_backoff"),
+
+		DSCP:      viper.GetString("dscp"),
+		DSCPPorts: viper.GetString("dscp_ports"),
+
+		TTL:      viper.GetInt("ttl"),
+		TTLPorts: viper.GetString("ttl_ports"),
+
+		FlowLabel: viper.GetString("flow_label"),
+
+		Role: viper.GetString("role"),
+
+		Peers:             viper.GetString("peers"),
+		PeerProbePort:     viper.GetInt("peer_probe_port"),
+		PeerProbeInterval: viper.GetFloat64("peer_probe_interval"),
+		PeerProbeCount:    viper.GetInt("peer_probe_count"),
+		PeerName:          viper.GetString("peer_name"),
+	}
+
+	// Validate configuration
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+
+	return config, nil
+}
+
+// LoadServerConfig loads and returns the server configuration.
+func LoadServerConfig() (*ServerConfig, error) {
+	initViper()
+	setServerDefaults()
+	return loadServerConfig(nil)
+}
+
+// LoadAgentServerConfig loads the server configuration of the agent role, in which the client also serves
+// the listeners of a server. It must be called after LoadClientConfig, the log, metrics and tracing settings
+// are shared with the client configuration.
+func LoadAgentServerConfig(client *ClientConfig) (*ServerConfig, error) {
+	setServerSpecificDefaults()
+	return loadServerConfig(&client.CommonConfig)
+}
+
+// loadServerConfig populates and validates the server configuration from flags, environment and config file.
+// If common is set, the common settings are taken from it instead.
+func loadServerConfig(common *CommonConfig) (*ServerConfig, error) {
+	// Bind command-line flags
+	if err := viper.BindPFlags(pflag.CommandLine); err != nil {
+		return nil, fmt.Errorf("failed to bind command-line flags: %w", err)
+	}
+	if err := applyProfiles(); err != nil {
+		return nil, fmt.Errorf("failed to apply profiles: %w", err)
+	}
+
+	// Populate ServerConfig
+	config := &ServerConfig{
+		CommonConfig: CommonConfig{
+			LogLevel:       viper.GetString("log_level"),
+			LogFormat:      viper.GetString("log_format"),
+			LogTheme:       viper.GetString("log_theme"),
+			MetricsPort:    viper.GetString("metrics_port"),
+			TracingEnabled: viper.GetBool("tracing_enabled"),
+			JaegerEndpoint: viper.GetString("jaeger_endpoint"),
+
+			OTLPMetricsEnabled:  viper.GetBool("otlp_metrics_enabled"),
+			OTLPMetricsInterval: viper.GetFloat64("otlp_metrics_interval"),
+
+			StatsdAddress:    viper.GetString("statsd_address"),
+			StatsdSampleRate: viper.GetFloat64("statsd_sample_rate"),
+
+			ProfileDir: viper.GetString("profile_dir"),
+			Profile:    viper.GetString("profile"),
+
+			TCPNoDelay:          viper.GetBool("tcp_nodelay"),
+			SocketSendBuffer:    viper.GetInt("socket_sndbuf"),
+			SocketReceiveBuffer: viper.GetInt("socket_rcvbuf"),
+
+			TCPKeepAlive:         viper.GetBool("tcp_keepalive"),
+			TCPKeepAliveIdle:     viper.GetFloat64("tcp_keepalive_idle"),
+			TCPKeepAliveInterval: viper.GetFloat64("tcp_keepalive_interval"),
+			TCPKeepAliveCount:    viper.GetInt("tcp_keepalive_count"),
+		},
+		TCPPortsServer: viper.GetString("tcp_ports_server"),
+		UDPPortsServer: viper.GetString("udp_ports_server"),
+		HealthPort:     viper.GetString("health_port"),
+		ServiceModes:   viper.GetString("service_modes"),
+		ResponseDelays: viper.GetString("response_delays"),
+		ResponseDrops:  viper.GetString("response_drops"),
+		ResponseSizes:  viper.GetString("response_sizes"),
+		GeneratorRates: viper.GetString("generator_rates"),
+
+		HTTPEchoHeaders: viper.GetString("http_echo_headers"),
+
+		RelayPortsServer: viper.GetString("relay_ports_server"),
+
+		TLSPortsServer:  viper.GetString("tls_ports_server"),
+		TLSCertificates: viper.GetString("tls_certificates"),
+
+		HandlerPorts: viper.GetString("handler_ports"),
+
+		UDPConnectedPeers:  viper.GetBool("udp_connected_peers"),
+		UDPPeerIdleTimeout: viper.GetFloat64("udp_peer_idle_timeout"),
+		UDPWorkers:         viper.GetInt("udp_workers"),
+		TCPReadBuffer:      viper.GetInt("tcp_read_buffer"),
+		UDPReadBuffer:      viper.GetInt("udp_read_buffer"),
+		ZeroCopyEcho:       viper.GetBool("zero_copy_echo"),
+
+		DuplicateWindow: viper.GetFloat64("duplicate_window"),
+
+		BackpressureMaxConnections: viper.GetInt("backpressure_max_connections"),
+		BackpressureMaxPPS:         viper.GetFloat64("backpressure_max_pps"),
+		BackpressureInterval:       viper.GetFloat64("backpressure_interval"),
+
+		MaxConnections:            viper.GetInt("max_connections"),
+		MaxConnectionsPerListener: viper.GetInt("max_connections_per_listener"),
+		ConnectionLimitMode:       viper.GetString("connection_limit_mode"),
+		AcceptRate:                viper.GetFloat64("accept_rate"),
+		TCPListenSockets:          viper.GetInt("tcp_listen_sockets"),
+
+		UpstreamServers:  viper.GetString("upstream_servers"),
+		UpstreamFraction: viper.GetFloat64("upstream_fraction"),
+		UpstreamDepth:    viper.GetInt("upstream_depth"),
+		UpstreamTimeout:  viper.GetFloat64("upstream_timeout"),
+	}
+	if common != nil {
+		config.CommonConfig = *common
+	}
+
+	// Validate configuration
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+
+	return config, nil
+}
+
+// initViper initializes viper with common settings
+func initViper() {
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+
+	// Load config file (if present)
+	viper.SetConfigName("config")
+	viper.AddConfigPath(".")
+	viper.AddConfigPath("/etc/flow-generator")
+	viper.AddConfigPath("$HOME/.flow-generator")
+
+	// Ignore if config file is not found
+	_ = viper.ReadInConfig()
+}
+
+// setCommonDefaults sets default values for common configuration
+func setCommonDefaults() {
+	viper.SetDefault("log_level", "info")
+	viper.SetDefault("log_format", "human")
+	viper.SetDefault("log_theme", "auto")
+	viper.SetDefault("metrics_port", "9090")
+	viper.SetDefault("tracing_enabled", false)
+	viper.SetDefault("jaeger_endpoint", "http://localhost:14268/api/traces")
+	viper.SetDefault("otlp_metrics_enabled", false)
+	viper.SetDefault("otlp_metrics_interval", 10.0)
+	viper.SetDefault("statsd_address", "")
+	viper.SetDefault("statsd_sample_rate", 1.0)
+	viper.SetDefault("profile_dir", "")
+	viper.SetDefault("profile", "")
+	viper.SetDefault("tcp_nodelay", true)
+	viper.SetDefault("socket_sndbuf", 0)
+	viper.SetDefault("socket_rcvbuf", 0)
+	viper.SetDefault("tcp_keepalive", true)
+	viper.SetDefault("tcp_keepalive_idle", 0.0)
+	viper.SetDefault("tcp_keepalive_interval", 0.0)
+	viper.SetDefault("tcp_keepalive_count", 0)
+}
+
+// setClientDefaults sets default values for client configuration
+func setClientDefaults() {
+	setCommonDefaults()
+
+	// Client-specific defaults
+	// The client serves metrics on a different port than the server so both can run on one host
+	viper.SetDefault("metrics_port", "9091")
+	viper.SetDefault("server", "localhost")
+	viper.SetDefault("target_cidr", "")
+	viper.SetDefault("target_probe", false)
+	viper.SetDefault("backup_server", "")
+	viper.SetDefault("failover_threshold", 50.0)
+	viper.SetDefault("failover_window", 10.0)
+	viper.SetDefault("rate", 10.0)
+	viper.SetDefault("max_concurrent", 100)
+	viper.SetDefault("protocol", "both")
+	viper.SetDefault("min_duration", 1.0)
+	viper.SetDefault("max_duration", 10.0)
+	viper.SetDefault("constant_flows", false)
+	viper.SetDefault("tcp_ports", "8080")
+	viper.SetDefault("udp_ports", "")
+	viper.SetDefault("payload_size", 0)
+	viper.SetDefault("min_payload_size", 0)
+	viper.SetDefault("max_payload_size", 0)
+	viper.SetDefault("payload_pattern", "cached")
+	viper.SetDefault("payload_template", "")
+	viper.SetDefault("mimic_payloads", false)
+	viper.SetDefault("mimic_ports", "")
+	viper.SetDefault("payload_distribution", "uniform")
+	viper.SetDefault("payload_large_fraction", 0.5)
+	viper.SetDefault("payload_sizes", "")
+	viper.SetDefault("flow_header", false)
+	viper.SetDefault("mtu", 1500)
+	viper.SetDefault("mss", 1460)
+	viper.SetDefault("wire_l2_overhead", 14)
+	viper.SetDefault("flow_timeout", 0.0)
+	viper.SetDefault("warmup", 0.0)
+	viper.SetDefault("seed", 0)
+	viper.SetDefault("flow_count", 0)
+	viper.SetDefault("stop_condition", "any")
+	viper.SetDefault("shutdown_timeout", 10.0)
+	viper.SetDefault("pause_windows", "")
+	viper.SetDefault("debug_sample_flows", 0)
+	viper.SetDefault("debug_sample_interval", 0)
+	viper.SetDefault("debug_hex_dump", false)
+	viper.SetDefault("flow_verbosity", 1)
+	viper.SetDefault("connection_reuse", false)
+	viper.SetDefault("pool_size", 10)
+	viper.SetDefault("burst_size", 0)
+	viper.SetDefault("burst_interval", 1.0)
+	viper.SetDefault("port_start_offsets", false)
+	viper.SetDefault("rate_transition", 0.0)
+	viper.SetDefault("udp_interval", 0.1)
+	viper.SetDefault("udp_jitter", 0.0)
+	viper.SetDefault("udp_bitrate", "")
+	viper.SetDefault("backpressure_url", "")
+	viper.SetDefault("backpressure_poll_interval", 1.0)
+	viper.SetDefault("backpressure_factor", 0.5)
+	viper.SetDefault("relay_chain", "")
+	viper.SetDefault("status_port", "")
+	viper.SetDefault("control_api", false)
+	viper.SetDefault("scenario", "default")
+	viper.SetDefault("meta", "")
+	viper.SetDefault("output_file", "")
+	viper.SetDefault("flow_log_file", "")
+	viper.SetDefault("artifact_bundle", "")
+	viper.SetDefault("output_sink", "")
+	viper.SetDefault("output_sink_retries", 3)
+	viper.SetDefault("output_sink_url_expiry", 86400.0)
+	viper.SetDefault("output", "text")
+	viper.SetDefault("progress_events", "")
+	viper.SetDefault("stats_interval", 10.0)
+	viper.SetDefault("tui", false)
+	viper.SetDefault("report_interval", 0.0)
+	viper.SetDefault("max_error_rate", 100.0)
+	viper.SetDefault("phase", "")
+	viper.SetDefault("step", "")
+	viper.SetDefault("max_p99_latency", 0.0)
+	viper.SetDefault("min_throughput", 0.0)
+	viper.SetDefault("warn_thresholds", "")
+	viper.SetDefault("warn_window", 30.0)
+	viper.SetDefault("warn_webhook", "")
+	viper.SetDefault("output_format", "json")
+	viper.SetDefault("latency_heatmap_slice", 0.0)
+	viper.SetDefault("transport_ports", "")
+	viper.SetDefault("expect_service", "")
+	viper.SetDefault("tls_server_names", "")
+	viper.SetDefault("tcp_read_buffer", 0)
+	viper.SetDefault("response_sizes", "")
+	viper.SetDefault("priority_ports", "")
+	viper.SetDefault("source_cidr", "")
+	viper.SetDefault("source_addresses", "")
+	viper.SetDefault("source_strategy", "round-robin")
+	viper.SetDefault("netns", "")
+	viper.SetDefault("source_port_range", "")
+	viper.SetDefault("track_tuples", false)
+	viper.SetDefault("conntrack_backoff", false)
+	viper.SetDefault("conntrack_backoff_threshold", 50.0)
+	viper.SetDefault("conntrack_backoff_window", 5.0)
+	viper.SetDefault("conntrack_backoff_factor", 0.1)
+	viper.SetDefault("local_address", "")
+	viper.SetDefault("interface", "")
+	viper.SetDefault("address_family", "any")
+	viper.SetDefault("happy_eyeballs_delay", 0.3)
+	viper.SetDefault("connect_timeout", 5.0)
+	viper.SetDefault("udp_timeout_min", 0.05)
+	viper.SetDefault("udp_timeout_max", 3.0)
+	viper.SetDefault("connect_retries", 2)
+	viper.SetDefault("connect_backoff", 0.1)
+	viper.SetDefault("dscp", "")
+	viper.SetDefault("dscp_ports", "")
+	viper.SetDefault("ttl", 0)
+	viper.SetDefault("ttl_ports", "")
+	viper.SetDefault("flow_label", "")
+	viper.SetDefault("role", "client")
+	viper.SetDefault("peers", "")
+	viper.SetDefault("peer_probe_port", 0)
+	viper.SetDefault("peer_probe_interval", 5.0)
+	viper.SetDefault("peer_probe_count", 3)
+	viper.SetDefault("peer_name", "")
+}
+
+// setServerDefaults sets default values for server configuration
 func setServerDefaults() {
 	setCommonDefaults()
+	setServerSpecificDefaults()
+}
 
-	// Server-specific defaults
+// setServerSpecificDefaults sets default values for the settings only the server has
+func setServerSpecificDefaults() {
 	viper.SetDefault("tcp_ports_server", "8080")
 	viper.SetDefault("udp_ports_server", "")
 	viper.SetDefault("health_port", "8082")
+	viper.SetDefault("service_modes", "")
+	viper.SetDefault("response_delays", "")
+	viper.SetDefault("response_drops", "")
+	viper.SetDefault("response_sizes", "")
+	viper.SetDefault("generator_rates", "")
+	viper.SetDefault("http_echo_headers", "User-Agent,X-Request-Id,X-Forwarded-For")
+	viper.SetDefault("handler_ports", "")
+	viper.SetDefault("relay_ports_server", "")
+	viper.SetDefault("tls_ports_server", "")
+	viper.SetDefault("tls_certificates", "")
+	viper.SetDefault("udp_connected_peers", false)
+	viper.SetDefault("udp_peer_idle_timeout", 30.0)
+	viper.SetDefault("udp_workers", 1)
+	viper.SetDefault("tcp_read_buffer", 1024)
+	viper.SetDefault("udp_read_buffer", MaxUDPReadBuffer)
+	viper.SetDefault("zero_copy_echo", false)
+	viper.SetDefault("duplicate_window", 300.0)
+	viper.SetDefault("backpressure_max_connections", 0)
+	viper.SetDefault("backpressure_max_pps", 0.0)
+	viper.SetDefault("backpressure_interval", 1.0)
+	viper.SetDefault("max_connections", 0)
+	viper.SetDefault("max_connections_per_listener", 0)
+	viper.SetDefault("connection_limit_mode", ConnectionLimitReject)
+	viper.SetDefault("accept_rate", 0.0)
+	viper.SetDefault("tcp_listen_sockets", 1)
+	viper.SetDefault("upstream_servers", "")
+	viper.SetDefault("upstream_fraction", 0.0)
+	viper.SetDefault("upstream_depth", 1)
+	viper.SetDefault("upstream_timeout", 2.0)
+}
+
+// Sources of an effective configuration value, in order of precedence
+const (
+	SourceFlag    = "flag"
+	SourceEnv     = "env"
+	SourceFile    = "file"
+	SourceProfile = "profile"
+	SourceDefault = "default"
+)
+
+// Setting is the effective value of a configuration key and the source it was taken from
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// EffectiveSettings returns the resolved value and source of every known configuration key, sorted by key.
+// It must be called after LoadClientConfig or LoadServerConfig.
+func EffectiveSettings() []Setting {
+	keys := viper.AllKeys()
+	sort.Strings(keys)
+
+	settings := make([]Setting, 0, len(keys))
+	for _, key := range keys {
+		settings = append(settings, Setting{
+			Key:    key,
+			Value:  fmt.Sprint(viper.Get(key)),
+			Source: settingSource(key),
+		})
+	}
+	return settings
+}
+
+// WriteEffectiveSettings writes the effective configuration as an aligned table
+func WriteEffectiveSettings(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, s := range EffectiveSettings() {
+		_, _ = fmt.Fprintf(tw, "%s\t%q\t%s\n", s.Key, s.Value, s.Source)
+	}
+	return tw.Flush()
+}
+
+// settingSource returns where the effective value of a configuration key comes from, following viper's precedence
+func settingSource(key string) string {
+	if flag := pflag.CommandLine.Lookup(key); flag != nil && flag.Changed {
+		return SourceFlag
+	}
+	if _, ok := os.LookupEnv(strings.ToUpper(EnvPrefix + "_" + key)); ok {
+		return SourceEnv
+	}
+	if viper.InConfig(key) {
+		return SourceFile
+	}
+	if _, ok := profileSettings[key]; ok {
+		return SourceProfile
+	}
+	return SourceDefault
+}
+
+// Archive formats of the artifact bundle
+const (
+	ArchiveTarGz = "tgz"
+	ArchiveZip   = "zip"
+)
+
+// ArchiveFormat returns the archive format of a path by its extension, or an empty string if it is not supported
+func ArchiveFormat(path string) string {
+	switch lower := strings.ToLower(path); {
+	case strings.HasSuffix(lower, ".tgz"), strings.HasSuffix(lower, ".tar.gz"):
+		return ArchiveTarGz
+	case strings.HasSuffix(lower, ".zip"):
+		return ArchiveZip
+	}
+	return ""
+}
+
+// validatePayloadDistribution checks the settings of the payload size distribution
+func (c *ClientConfig) validatePayloadDistribution() error {
+	if c.LargestPayloadSize() > PayloadSizeLimit {
+		return fmt.Errorf("payload sizes cannot exceed %d bytes", PayloadSizeLimit)
+	}
+	if c.PayloadLargeFraction < 0 || c.PayloadLargeFraction > 1 {
+		return fmt.Errorf("payload_large_fraction must be between 0 and 1")
+	}
+	switch c.PayloadDistribution {
+	case "", "uniform":
+	case "bimodal":
+		if c.PayloadSize > 0 {
+			return fmt.Errorf("payload_size cannot be combined with payload_distribution bimodal")
+		}
+		if c.MinPayloadSize <= 0 || c.MaxPayloadSize <= c.MinPayloadSize {
+			return fmt.Errorf("payload_distribution bimodal requires 0 < min_payload_size < max_payload_size")
+		}
+	case "empirical":
+		if c.PayloadSize > 0 {
+			return fmt.Errorf("payload_size cannot be combined with payload_distribution empirical")
+		}
+		if _, err := ParsePayloadSizes(c.PayloadSizes); err != nil {
+			return fmt.Errorf("invalid payload_sizes: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid payload_distribution: %s, must be one of: [uniform bimodal empirical]", c.PayloadDistribution)
+	}
+	if c.PayloadSizes != "" {
+		return fmt.Errorf("payload_sizes requires payload_distribution empirical")
+	}
+	return nil
+}
+
+// LargestPayloadSize returns the largest payload size of the configuration, 0 if no size is configured
+func (c *ClientConfig) LargestPayloadSize() int {
+	largest := max(c.PayloadSize, c.MaxPayloadSize)
+	if c.PayloadDistribution == "empirical" {
+		sizes, _ := ParsePayloadSizes(c.PayloadSizes)
+		for _, s := range sizes {
+			largest = max(largest, s.Size)
+		}
+	}
+	return largest
+}
+
+// PayloadSizeWeight is an entry of the payload_sizes table, a payload size in bytes and its relative weight
+type PayloadSizeWeight struct {
+	Size   int
+	Weight int
+}
+
+// imixPayloadSizes are the UDP payload sizes over IPv4 whose Ethernet frames make up the simple IMIX of 64,
+// 594 and 1518 byte frames in a 7:4:1 ratio
+const imixPayloadSizes = "18:7,548:4,1472:1"
+
+// ParsePayloadSizes parses a comma-separated size:weight table of payload sizes (e.g. "64:7,576:4,1500:1"),
+// or "imix" for the simple IMIX
+func ParsePayloadSizes(s string) ([]PayloadSizeWeight, error) {
+	if strings.EqualFold(strings.TrimSpace(s), "imix") {
+		s = imixPayloadSizes
+	}
+	var sizes []PayloadSizeWeight
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		size, weight, found := strings.Cut(entry, ":")
+		if !found {
+			return nil, fmt.Errorf("entry %q is not in size:weight format", entry)
+		}
+		e := PayloadSizeWeight{}
+		var err error
+		if e.Size, err = strconv.Atoi(strings.TrimSpace(size)); err != nil || e.Size <= 0 {
+			return nil, fmt.Errorf("invalid payload size %q", size)
+		}
+		if e.Weight, err = strconv.Atoi(strings.TrimSpace(weight)); err != nil || e.Weight <= 0 {
+			return nil, fmt.Errorf("invalid weight %q", weight)
+		}
+		sizes = append(sizes, e)
+	}
+	if len(sizes) == 0 {
+		return nil, fmt.Errorf("no payload sizes given")
+	}
+	return sizes, nil
+}
+
+// PauseWindow is a daily quiet window, given by the time of day it starts and ends at
+type PauseWindow struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// String formats the window as HH:MM-HH:MM
+func (w PauseWindow) String() string {
+	clock := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return clock(w.Start) + "-" + clock(w.End)
+}
+
+// ParsePauseWindows parses comma-separated daily quiet windows given as HH:MM-HH:MM (e.g. "02:00-02:15")
+func ParsePauseWindows(s string) ([]PauseWindow, error) {
+	var windows []PauseWindow
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		start, end, ok := strings.Cut(entry, "-")
+		if !ok {
+			return nil, fmt.Errorf("pause window %q is not in HH:MM-HH:MM format", entry)
+		}
+		var w PauseWindow
+		var err error
+		if w.Start, err = parseTimeOfDay(start); err != nil {
+			return nil, fmt.Errorf("pause window %q: %w", entry, err)
+		}
+		if w.End, err = parseTimeOfDay(end); err != nil {
+			return nil, fmt.Errorf("pause window %q: %w", entry, err)
+		}
+		if w.Start == w.End {
+			return nil, fmt.Errorf("pause window %q is empty", entry)
+		}
+		windows = append(windows, w)
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("no pause windows given")
+	}
+	return windows, nil
+}
+
+// parseTimeOfDay parses a time of day given as HH:MM into the time since midnight
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, must be HH:MM", strings.TrimSpace(s))
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Step is a stage of a stepped load profile, generating flows at a fixed rate for a duration in seconds
+type Step struct {
+	Name     string
+	Rate     float64
+	Duration float64
+}
+
+// ParseSteps parses semicolon-separated load steps given as rate:duration[:name], where the duration is a
+// number of seconds or a duration such as "2m". Unnamed steps are named after their position (step1, step2).
+func ParseSteps(s string) ([]Step, error) {
+	var steps []Step
+	names := make(map[string]bool)
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("step %q is not in rate:duration[:name] format", entry)
+		}
+		st := Step{Name: fmt.Sprintf("step%d", len(steps)+1)}
+		if len(parts) == 3 {
+			st.Name = strings.TrimSpace(parts[2])
+		}
+		if !metadataKey.MatchString(st.Name) {
+			return nil, fmt.Errorf("step name %q must consist of letters, digits and underscores", st.Name)
+		}
+		if names[st.Name] {
+			return nil, fmt.Errorf("duplicate step %s", st.Name)
+		}
+		names[st.Name] = true
+		var err error
+		if st.Rate, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64); err != nil || st.Rate <= 0 {
+			return nil, fmt.Errorf("rate %q of step %s must be a positive number of flows per second", parts[0], st.Name)
+		}
+		if st.Duration, err = parseSeconds(parts[1]); err != nil || st.Duration <= 0 {
+			return nil, fmt.Errorf("duration %q of step %s must be a positive number of seconds or a duration such as 2m", parts[1], st.Name)
+		}
+		steps = append(steps, st)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("no steps given")
+	}
+	return steps, nil
+}
+
+// parseSeconds parses a number of seconds or a duration such as "90s" or "2m"
+func parseSeconds(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return v, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	return d.Seconds(), nil
+}
+
+// Phase is a time-boxed section of a run with its own SLA assertions, which are disabled by 100 and 0 like
+// the assertions of the whole run
+type Phase struct {
+	Name          string
+	Duration      float64
+	MaxErrorRate  float64
+	MaxP99Latency float64
+	MinThroughput float64
+}
+
+// ParsePhases parses semicolon-separated phases given as name:seconds[:assertion=value,...], where the
+// assertions are max_error_rate, max_p99_latency and min_throughput
+// (e.g. "steady:60:max_error_rate=0.1;chaos:120:max_error_rate=5,max_p99_latency=250")
+func ParsePhases(s string) ([]Phase, error) {
+	var phases []Phase
+	names := make(map[string]bool)
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("phase %q is not in name:seconds[:assertion=value,...] format", entry)
+		}
+		p := Phase{Name: strings.TrimSpace(parts[0]), MaxErrorRate: 100}
+		if !metadataKey.MatchString(p.Name) {
+			return nil, fmt.Errorf("phase name %q must consist of letters, digits and underscores", p.Name)
+		}
+		if names[p.Name] {
+			return nil, fmt.Errorf("duplicate phase %s", p.Name)
+		}
+		names[p.Name] = true
+		var err error
+		if p.Duration, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err != nil || p.Duration <= 0 {
+			return nil, fmt.Errorf("duration %q of phase %s must be a positive number of seconds", parts[1], p.Name)
+		}
+		if len(parts) == 3 {
+			if err := parseAssertions(parts[2], &p.MaxErrorRate, &p.MaxP99Latency, &p.MinThroughput); err != nil {
+				return nil, fmt.Errorf("phase %s: %w", p.Name, err)
+			}
+		}
+		phases = append(phases, p)
+	}
+	if len(phases) == 0 {
+		return nil, fmt.Errorf("no phases given")
+	}
+	return phases, nil
+}
+
+// WarnThresholds are the soft limits of warn_thresholds, which are disabled by 100 and 0 like the SLA
+// assertions
+type WarnThresholds struct {
+	MaxErrorRate  float64
+	MaxP99Latency float64
+	MinThroughput float64
+}
+
+// ParseWarnThresholds parses the comma-separated assertion=value pairs of warn_thresholds, where the
+// assertions are those of phases (e.g. "max_error_rate=1,max_p99_latency=100")
+func ParseWarnThresholds(s string) (WarnThresholds, error) {
+	t := WarnThresholds{MaxErrorRate: 100}
+	if err := parseAssertions(s, &t.MaxErrorRate, &t.MaxP99Latency, &t.MinThroughput); err != nil {
+		return WarnThresholds{}, err
+	}
+	if t.MaxErrorRate == 100 && t.MaxP99Latency == 0 && t.MinThroughput == 0 {
+		return WarnThresholds{}, fmt.Errorf("no thresholds given")
+	}
+	return t, nil
+}
+
+// parseAssertions parses comma-separated assertion=value pairs into the limits they set
+func parseAssertions(s string, maxErrorRate, maxP99Latency, minThroughput *float64) error {
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("assertion %q is not in assertion=value format", pair)
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return fmt.Errorf("invalid value %q of %s", value, key)
+		}
+		switch strings.TrimSpace(key) {
+		case "max_error_rate":
+			if v < 0 || v > 100 {
+				return fmt.Errorf("max_error_rate must be between 0 and 100 percent")
+			}
+			*maxErrorRate = v
+		case "max_p99_latency":
+			if v < 0 {
+				return fmt.Errorf("max_p99_latency cannot be negative")
+			}
+			*maxP99Latency = v
+		case "min_throughput":
+			if v < 0 {
+				return fmt.Errorf("min_throughput cannot be negative")
+			}
+			*minThroughput = v
+		default:
+			return fmt.Errorf("unknown assertion %q, must be one of max_error_rate, max_p99_latency, min_throughput", key)
+		}
+	}
+	return nil
+}
+
+// ParsePayloadTemplate parses the Go template of payload_template
+func ParsePayloadTemplate(s string) (*template.Template, error) {
+	tmpl, err := template.New("payload").Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload_template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// MaxTargetAddresses bounds the number of addresses of target_cidr, a /16 IPv4 or /112 IPv6 prefix
+const MaxTargetAddresses = 1 << 16
+
+// ParseTargetCIDR parses the prefix of target_cidr (e.g. "10.2.0.0/24"), which may cover at most
+// MaxTargetAddresses addresses
+func ParseTargetCIDR(s string) (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(strings.TrimSpace(s))
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid target_cidr %q: %w", s, err)
+	}
+	if prefix.Addr().BitLen()-prefix.Bits() > 16 {
+		return netip.Prefix{}, fmt.Errorf("target_cidr %s covers more than %d addresses", prefix, MaxTargetAddresses)
+	}
+	return prefix.Masked(), nil
+}
+
+// ParsePortRange parses a port or an inclusive range of ports (e.g. "8080" or "20000-29999") and returns its
+// first and last port
+func ParsePortRange(s string) (int, int, error) {
+	lo, hi, isRange := strings.Cut(strings.TrimSpace(s), "-")
+	first, err := strconv.Atoi(strings.TrimSpace(lo))
+	if err != nil || first <= 0 || first > 65535 {
+		return 0, 0, fmt.Errorf("invalid port %q", lo)
+	}
+	if !isRange {
+		return first, first, nil
+	}
+	last, err := strconv.Atoi(strings.TrimSpace(hi))
+	if err != nil || last <= 0 || last > 65535 {
+		return 0, 0, fmt.Errorf("invalid port %q", hi)
+	}
+	if last < first {
+		return 0, 0, fmt.Errorf("port range %q ends before it starts", s)
+	}
+	return first, last, nil
+}
+
+// ResponseDelay is the delay of the responses of a port, picked uniformly from Base-Jitter to Base+Jitter
+// and never negative
+type ResponseDelay struct {
+	Base   time.Duration
+	Jitter time.Duration
+}
+
+// String formats the delay as it is configured, e.g. "50ms±10ms"
+func (d ResponseDelay) String() string {
+	if d.Jitter == 0 {
+		return d.Base.String()
+	}
+	return d.Base.String() + "±" + d.Jitter.String()
+}
+
+// ParseResponseDelays parses the comma-separated port=delay[±jitter] pairs of response_delays, where delay and
+// jitter are Go durations and the jitter may also be given after "+-" (e.g. "8081=50ms±10ms,8082=20ms")
+func ParseResponseDelays(s string) (map[int]ResponseDelay, error) {
+	ports, err := ParsePortMap(s)
+	if err != nil {
+		return nil, err
+	}
+	delays := make(map[int]ResponseDelay, len(ports))
+	for port, value := range ports {
+		base, jitter, found := strings.Cut(value, "±")
+		if !found {
+			base, jitter, found = strings.Cut(value, "+-")
+		}
+		var d ResponseDelay
+		if d.Base, err = time.ParseDuration(strings.TrimSpace(base)); err != nil || d.Base < 0 {
+			return nil, fmt.Errorf("delay %q of port %d must be a non-negative duration such as 50ms", base, port)
+		}
+		if found {
+			if d.Jitter, err = time.ParseDuration(strings.TrimSpace(jitter)); err != nil || d.Jitter < 0 {
+				return nil, fmt.Errorf("jitter %q of port %d must be a non-negative duration such as 10ms", jitter, port)
+			}
+		}
+		delays[port] = d
+	}
+	return delays, nil
+}
+
+// ParseResponseDrops parses the comma-separated port=percent pairs of response_drops, where the percentage of
+// responses dropped is above 0 and at most 100 and may end in "%" (e.g. "8081=5,8082=0.5%")
+func ParseResponseDrops(s string) (map[int]float64, error) {
+	ports, err := ParsePortMap(s)
+	if err != nil {
+		return nil, err
+	}
+	drops := make(map[int]float64, len(ports))
+	for port, value := range ports {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value, "%")), 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return nil, fmt.Errorf("drop percentage %q of port %d must be above 0 and at most 100", value, port)
+		}
+		drops[port] = percent
+	}
+	return drops, nil
+}
+
+// Modes of response_sizes
+const (
+	// ResponseSizeTruncate cuts responses to at most the given number of bytes
+	ResponseSizeTruncate = "truncate"
+	// ResponseSizeAmplify repeats the request the given number of times
+	ResponseSizeAmplify = "amplify"
+	// ResponseSizeFixed responds with exactly the given number of bytes, repeating or cutting the request
+	ResponseSizeFixed = "fixed"
+)
+
+// Limits of response_sizes
+const (
+	// maxResponseSize is the largest truncated or fixed response, the largest UDP payload
+	maxResponseSize = 65507
+	// maxResponseAmplification is the largest amplification factor
+	maxResponseAmplification = 100
+)
+
+// ResponseSize is how the responses of a port are sized instead of echoing the request as is
+type ResponseSize struct {
+	Mode  string
+	Value int
+}
+
+// Of returns the size of the response to a request of n bytes
+func (s ResponseSize) Of(n int) int {
+	switch s.Mode {
+	case ResponseSizeTruncate:
+		return min(n, s.Value)
+	case ResponseSizeAmplify:
+		return n * s.Value
+	case ResponseSizeFixed:
+		return s.Value
+	default:
+		return n
+	}
+}
+
+// String formats the size as it is configured, e.g. "amplify:4"
+func (s ResponseSize) String() string {
+	return s.Mode + ":" + strconv.Itoa(s.Value)
+}
+
+// ParseResponseSizes parses the comma-separated port=mode:value pairs of response_sizes (e.g.
+// "8081=truncate:64,8082=amplify:4"). Truncated and fixed sizes are 1 to 65507 bytes, amplification factors
+// 2 to 100.
+func ParseResponseSizes(s string) (map[int]ResponseSize, error) {
+	ports, err := ParsePortMap(s)
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[int]ResponseSize, len(ports))
+	for port, value := range ports {
+		mode, n, found := strings.Cut(value, ":")
+		if !found {
+			return nil, fmt.Errorf("response size %q of port %d is not in mode:value format", value, port)
+		}
+		size := ResponseSize{Mode: strings.ToLower(strings.TrimSpace(mode))}
+		if size.Value, err = strconv.Atoi(strings.TrimSpace(n)); err != nil {
+			return nil, fmt.Errorf("invalid value %q of port %d", n, port)
+		}
+		switch size.Mode {
+		case ResponseSizeTruncate, ResponseSizeFixed:
+			if size.Value < 1 || size.Value > maxResponseSize {
+				return nil, fmt.Errorf("%s size of port %d must be between 1 and %d bytes", size.Mode, port, maxResponseSize)
+			}
+		case ResponseSizeAmplify:
+			if size.Value < 2 || size.Value > maxResponseAmplification {
+				return nil, fmt.Errorf("amplification of port %d must be between 2 and %d", port, maxResponseAmplification)
+			}
+		default:
+			return nil, fmt.Errorf("invalid response size mode %q of port %d, must be one of: %v", mode, port,
+				[]string{ResponseSizeTruncate, ResponseSizeAmplify, ResponseSizeFixed})
+		}
+		sizes[port] = size
+	}
+	return sizes, nil
+}
+
+// Modes of connection_limit_mode
+const (
+	// ConnectionLimitReject closes connections over a limit right after accepting them
+	ConnectionLimitReject = "reject"
+	// ConnectionLimitQueue stops accepting connections while a limit is reached, so further connections wait in
+	// the listen backlog of the kernel until a connection closes
+	ConnectionLimitQueue = "queue"
+)
+
+// ParseGeneratorRates parses the comma-separated port=bitrate pairs of generator_rates, in bits per second
+// with an optional k, M or G suffix (e.g. "8090=100M,8091=500k")
+func ParseGeneratorRates(s string) (map[int]float64, error) {
+	ports, err := ParsePortMap(s)
+	if err != nil {
+		return nil, err
+	}
+	rates := make(map[int]float64, len(ports))
+	for port, value := range ports {
+		if rates[port], err = ParseBitrate(value); err != nil {
+			return nil, fmt.Errorf("port %d: %w", port, err)
+		}
+	}
+	return rates, nil
+}
+
+// ParseHeaderNames parses comma-separated HTTP header names (e.g. "User-Agent,X-Request-Id")
+func ParseHeaderNames(s string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		for _, r := range name {
+			if !isHeaderTokenRune(r) {
+				return nil, fmt.Errorf("%q is not a valid header name", name)
+			}
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// isHeaderTokenRune reports whether r may appear in an HTTP header name (RFC 9110 token)
+func isHeaderTokenRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("!#$%&'*+-.^_`|~", r)
+}
+
+// LoadTLSCertificates loads the comma-separated cert:key pairs of PEM files of tls_certificates (e.g.
+// "a.pem:a-key.pem,b.pem:b-key.pem")
+func LoadTLSCertificates(s string) ([]tls.Certificate, error) {
+	var certs []tls.Certificate
+	for _, entry := range strings.Split(s, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		certFile, keyFile, found := strings.Cut(entry, ":")
+		if !found || certFile == "" || keyFile == "" {
+			return nil, fmt.Errorf("entry %q is not in cert:key format", entry)
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load certificate %s: %w", certFile, err)
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// ParseBitrate parses a bitrate in bits per second with an optional decimal k, M or G suffix (e.g. "500k",
+// "10M" or "1.5G")
+func ParseBitrate(s string) (float64, error) {
+	number := strings.TrimSpace(s)
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(number, "k"), strings.HasSuffix(number, "K"):
+		multiplier = 1e3
+	case strings.HasSuffix(number, "M"):
+		multiplier = 1e6
+	case strings.HasSuffix(number, "G"):
+		multiplier = 1e9
+	}
+	if multiplier > 1 {
+		number = number[:len(number)-1]
+	}
+	v, err := strconv.ParseFloat(number, 64)
+	if err != nil || v <= 0 || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("bitrate %q must be a positive number of bits per second with an optional k, M or G suffix", s)
+	}
+	return v * multiplier, nil
+}
+
+// FormatBitrate formats a bitrate in bits per second with a decimal unit, such as 10 Mbit/s
+func FormatBitrate(bps float64) string {
+	switch {
+	case bps >= 1e9:
+		return strconv.FormatFloat(bps/1e9, 'g', 4, 64) + " Gbit/s"
+	case bps >= 1e6:
+		return strconv.FormatFloat(bps/1e6, 'g', 4, 64) + " Mbit/s"
+	case bps >= 1e3:
+		return strconv.FormatFloat(bps/1e3, 'g', 4, 64) + " kbit/s"
+	}
+	return strconv.FormatFloat(bps, 'g', 4, 64) + " bit/s"
+}
+
+// ValidMimicProtocols are the protocols whose messages payloads can mimic
+var ValidMimicProtocols = []string{"dns", "http", "mysql", "ntp", "postgres", "smtp", "ssh", "tls"}
+
+// ValidServices are the services that can be expected on a port, none if nothing should accept connections
+var ValidServices = []string{"echo", "http", "tls", "none"}
+
+// ParsePortMap parses a comma-separated list of port=value pairs (e.g. "7=echo,9=discard")
+func ParsePortMap(s string) (map[int]string, error) {
+	result := make(map[int]string)
+	if strings.TrimSpace(s) == "" {
+		return result, nil
+	}
+	for _, entry := range strings.Split(s, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found {
+			return nil, fmt.Errorf("entry %q is not in port=value format", entry)
+		}
+		port, err := strconv.Atoi(strings.TrimSpace(key))
+		if err != nil || port <= 0 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %q", key)
+		}
+		result[port] = strings.TrimSpace(value)
+	}
+	return result, nil
+}
+
+// dscpClasses maps the DSCP class names of RFC 2474, RFC 2597, RFC 3246, RFC 5865 and RFC 8622 to their values
+var dscpClasses = map[string]int{
+	"default": 0, "le": 1,
+	"cs0": 0, "cs1": 8, "cs2": 16, "cs3": 24, "cs4": 32, "cs5": 40, "cs6": 48, "cs7": 56,
+	"af11": 10, "af12": 12, "af13": 14,
+	"af21": 18, "af22": 20, "af23": 22,
+	"af31": 26, "af32": 28, "af33": 30,
+	"af41": 34, "af42": 36, "af43": 38,
+	"va": 44, "ef": 46,
+}
+
+// ParseDSCP parses a DSCP class name (e.g. "ef" or "AF41") or a numeric value between 0 and 63
+func ParseDSCP(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if v, ok := dscpClasses[s]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 || v > 63 {
+		return 0, fmt.Errorf("DSCP %q must be a class name (e.g. ef, af41, cs1) or a value between 0 and 63", s)
+	}
+	return v, nil
+}
+
+// netnsDir is where named network namespaces are mounted, as by ip netns
+const netnsDir = "/run/netns"
+
+// NetnsGroup is an entry of the netns list, a network namespace flows are sent from and its share of the flows
+type NetnsGroup struct {
+	// Name labels the metrics of the namespace, the name given or the last element of its path
+	Name  string
+	Path  string
+	Share float64
+}
+
+// ParseNetns parses the comma-separated name[=share] entries of the netns list, where the name may also be
+// the path of a network namespace (e.g. "client-a=2,client-b,/proc/1234/ns/net=0.5")
+func ParseNetns(s string) ([]NetnsGroup, error) {
+	var groups []NetnsGroup
+	names := make(map[string]bool)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, share, hasShare := strings.Cut(entry, "=")
+		g := NetnsGroup{Name: strings.TrimSpace(name), Share: 1}
+		if hasShare {
+			v, err := strconv.ParseFloat(strings.TrimSpace(share), 64)
+			if err != nil || v <= 0 || math.IsInf(v, 0) {
+				return nil, fmt.Errorf("share %q of network namespace %s must be a positive number", share, g.Name)
+			}
+			g.Share = v
+		}
+		switch {
+		case g.Name == "":
+			return nil, fmt.Errorf("network namespace %q has no name", entry)
+		case strings.Contains(g.Name, "/"):
+			g.Path = g.Name
+			g.Name = path.Base(g.Path)
+			// The namespace of a process (/proc/<pid>/ns/net) is named after the process
+			if dir := path.Dir(path.Clean(g.Path)); g.Name == "net" && path.Base(dir) == "ns" {
+				g.Name = path.Base(path.Dir(dir))
+			}
+		default:
+			g.Path = path.Join(netnsDir, g.Name)
+		}
+		if names[g.Name] {
+			return nil, fmt.Errorf("network namespace %s is listed twice", g.Name)
+		}
+		names[g.Name] = true
+		groups = append(groups, g)
+	}
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("no network namespaces given")
+	}
+	return groups, nil
+}
+
+// Source is an entry of the source_addresses list, a source address or a network interface flows are bound to
+type Source struct {
+	Addr      netip.Addr
+	Interface string
+}
+
+// ParseSources parses a comma-separated list of source addresses and network interface names
+// (e.g. "10.0.0.5,10.0.0.6" or "eth1,eth2")
+func ParseSources(s string) ([]Source, error) {
+	var sources []Source
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			sources = append(sources, Source{Addr: addr})
+			continue
+		}
+		// Interface names are limited to IFNAMSIZ (16) bytes including the terminating NUL
+		if len(entry) > 15 || strings.ContainsAny(entry, " /:") {
+			return nil, fmt.Errorf("source %q is neither an IP address nor an interface name", entry)
+		}
+		sources = append(sources, Source{Interface: entry})
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no sources given")
+	}
+	return sources, nil
+}
+
+// ParseFlowLabel parses an IPv6 flow label given as a decimal or 0x-prefixed hexadecimal value between 1 and
+// 0xfffff, or "random" for a random label per flow
+func ParseFlowLabel(s string) (label uint32, random bool, err error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "random" {
+		return 0, true, nil
+	}
+	v, err := strconv.ParseUint(s, 0, 32)
+	if err != nil || v < 1 || v > 0xfffff {
+		return 0, false, fmt.Errorf("flow label %q must be random or a value between 1 and 0xfffff", s)
+	}
+	return uint32(v), false, nil
+}
+
+// Limits of the experiment metadata, which ends up as labels of the run_info metric
+const (
+	maxMetadataEntries = 16
+	maxMetadataValue   = 128
+)
+
+// metadataKey matches metadata keys, which have to be valid Prometheus label names
+var metadataKey = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ParseMetadata parses experiment metadata given as comma-separated key=value pairs
+// (e.g. "owner=netops,ticket=NET-1234")
+func ParseMetadata(s string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || !metadataKey.MatchString(key) {
+			return nil, fmt.Errorf("metadata %q must be key=value with a key of letters, digits and underscores", pair)
+		}
+		if key == "scenario" {
+			return nil, fmt.Errorf("metadata key scenario is reserved, use the scenario setting")
+		}
+		if _, dup := metadata[key]; dup {
+			return nil, fmt.Errorf("duplicate metadata key %s", key)
+		}
+		if len(value) > maxMetadataValue {
+			return nil, fmt.Errorf("metadata %s exceeds %d characters", key, maxMetadataValue)
+		}
+		metadata[key] = value
+	}
+	if len(metadata) > maxMetadataEntries {
+		return nil, fmt.Errorf("at most %d metadata entries are allowed, got %d", maxMetadataEntries, len(metadata))
+	}
+	return metadata, nil
 }
 
 // contains checks if a string slice contains a specific value
@@ -1,8 +1,13 @@
 package config
 
 import (
+	"bytes"
+	"fmt"
+	"net/netip"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -43,6 +48,111 @@ func TestCommonConfigValidate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "invalid log format",
 		},
+		{
+			name: "log theme",
+			config: CommonConfig{
+				LogLevel:  "info",
+				LogFormat: "human",
+				LogTheme:  "plain",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid log theme",
+			config: CommonConfig{
+				LogLevel:  "info",
+				LogFormat: "human",
+				LogTheme:  "neon",
+			},
+			wantErr: true,
+			errMsg:  "invalid log theme",
+		},
+		{
+			name: "negative otlp metrics interval",
+			config: CommonConfig{
+				LogLevel:            "info",
+				LogFormat:           "json",
+				OTLPMetricsEnabled:  true,
+				OTLPMetricsInterval: -1,
+			},
+			wantErr: true,
+			errMsg:  "otlp_metrics_interval cannot be negative",
+		},
+		{
+			name: "invalid statsd sample rate",
+			config: CommonConfig{
+				LogLevel:         "info",
+				LogFormat:        "json",
+				StatsdAddress:    "localhost:8125",
+				StatsdSampleRate: 1.5,
+			},
+			wantErr: true,
+			errMsg:  "statsd_sample_rate must be greater than 0 and at most 1",
+		},
+		{
+			name: "socket buffers",
+			config: CommonConfig{
+				LogLevel:            "info",
+				LogFormat:           "json",
+				TCPNoDelay:          true,
+				SocketSendBuffer:    4 << 20,
+				SocketReceiveBuffer: 4 << 20,
+			},
+			wantErr: false,
+		},
+		{
+			name: "tcp keepalive tuning",
+			config: CommonConfig{
+				LogLevel:             "info",
+				LogFormat:            "json",
+				TCPKeepAlive:         true,
+				TCPKeepAliveIdle:     600,
+				TCPKeepAliveInterval: 30,
+				TCPKeepAliveCount:    4,
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative tcp keepalive idle",
+			config: CommonConfig{
+				LogLevel:         "info",
+				LogFormat:        "json",
+				TCPKeepAlive:     true,
+				TCPKeepAliveIdle: -1,
+			},
+			wantErr: true,
+			errMsg:  "cannot be negative",
+		},
+		{
+			name: "tcp keepalive tuning without keepalive",
+			config: CommonConfig{
+				LogLevel:          "info",
+				LogFormat:         "json",
+				TCPKeepAliveCount: 4,
+			},
+			wantErr: true,
+			errMsg:  "require tcp_keepalive",
+		},
+		{
+			name: "negative socket send buffer",
+			config: CommonConfig{
+				LogLevel:         "info",
+				LogFormat:        "json",
+				SocketSendBuffer: -1,
+			},
+			wantErr: true,
+			errMsg:  "socket_sndbuf cannot be negative",
+		},
+		{
+			name: "negative socket receive buffer",
+			config: CommonConfig{
+				LogLevel:            "info",
+				LogFormat:           "json",
+				SocketReceiveBuffer: -1,
+			},
+			wantErr: true,
+			errMsg:  "socket_rcvbuf cannot be negative",
+		},
 	}
 
 	for _, tt := range tests {
@@ -185,141 +295,4382 @@ func TestClientConfigValidate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "MSS must be less than MTU",
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := tt.config.Validate()
-			if tt.wantErr {
-				assert.Error(t, err)
-				assert.Contains(t, err.Error(), tt.errMsg)
-			} else {
-				assert.NoError(t, err)
-			}
-		})
-	}
-}
-
-func TestServerConfigValidate(t *testing.T) {
-	tests := []struct {
-		name    string
-		config  ServerConfig
-		wantErr bool
-		errMsg  string
-	}{
 		{
-			name: "valid config with TCP ports",
-			config: ServerConfig{
+			name: "negative debug sample flows",
+			config: ClientConfig{
 				CommonConfig: CommonConfig{
 					LogLevel:  "info",
 					LogFormat: "json",
 				},
-				TCPPortsServer: "8080",
+				Server:           "localhost",
+				Rate:             10.0,
+				MaxConcurrent:    100,
+				Protocol:         "tcp",
+				MinDuration:      1.0,
+				MaxDuration:      10.0,
+				TCPPorts:         "8080",
+				MTU:              1500,
+				MSS:              1460,
+				DebugSampleFlows: -1,
 			},
-			wantErr: false,
+			wantErr: true,
+			errMsg:  "debug_sample_flows and debug_sample_interval cannot be negative",
 		},
 		{
-			name: "valid config with UDP ports",
-			config: ServerConfig{
+			name: "connection reuse without pool size",
+			config: ClientConfig{
 				CommonConfig: CommonConfig{
 					LogLevel:  "info",
 					LogFormat: "json",
 				},
-				UDPPortsServer: "9000",
+				Server:          "localhost",
+				Rate:            10.0,
+				MaxConcurrent:   100,
+				Protocol:        "tcp",
+				MinDuration:     1.0,
+				MaxDuration:     10.0,
+				TCPPorts:        "8080",
+				MTU:             1500,
+				MSS:             1460,
+				ConnectionReuse: true,
 			},
-			wantErr: false,
+			wantErr: true,
+			errMsg:  "pool_size must be positive",
 		},
 		{
-			name: "no ports specified",
-			config: ServerConfig{
+			name: "burst size without interval",
+			config: ClientConfig{
 				CommonConfig: CommonConfig{
 					LogLevel:  "info",
 					LogFormat: "json",
 				},
-				TCPPortsServer: "",
-				UDPPortsServer: "",
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				BurstSize:     50,
 			},
 			wantErr: true,
-			errMsg:  "at least one port",
+			errMsg:  "burst_interval must be positive",
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := tt.config.Validate()
-			if tt.wantErr {
-				assert.Error(t, err)
-				assert.Contains(t, err.Error(), tt.errMsg)
-			} else {
-				assert.NoError(t, err)
-			}
-		})
-	}
-}
-
-func TestLoadClientConfig(t *testing.T) {
-	// Reset viper and pflags for clean test
-	viper.Reset()
-	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
-
-	// Set environment variable with prefix
-	_ = os.Setenv("FLOW_GENERATOR_LOG_LEVEL", "debug")
-	defer func() { _ = os.Unsetenv("FLOW_GENERATOR_LOG_LEVEL") }()
-
-	config, err := LoadClientConfig()
-	require.NoError(t, err)
-	assert.NotNil(t, config)
-	assert.Equal(t, "debug", config.LogLevel)
-	assert.Equal(t, "localhost", config.Server) // default value
-}
-
-func TestLoadServerConfig(t *testing.T) {
-	// Reset viper and pflags for clean test
-	viper.Reset()
-	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
-
-	// Set environment variable with prefix
-	_ = os.Setenv("FLOW_GENERATOR_METRICS_PORT", "9999")
-	defer func() { _ = os.Unsetenv("FLOW_GENERATOR_METRICS_PORT") }()
-
-	config, err := LoadServerConfig()
-	require.NoError(t, err)
-	assert.NotNil(t, config)
-	assert.Equal(t, "9999", config.MetricsPort)
-	assert.Equal(t, "8080", config.TCPPortsServer) // default value
-}
-
-func TestContains(t *testing.T) {
-	tests := []struct {
-		name  string
-		slice []string
-		val   string
-		want  bool
-	}{
 		{
-			name:  "value exists",
-			slice: []string{"a", "b", "c"},
-			val:   "b",
-			want:  true,
+			name: "negative rate transition",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				RateTransition: -1,
+			},
+			wantErr: true,
+			errMsg:  "rate_transition cannot be negative",
 		},
 		{
-			name:  "value doesn't exist",
-			slice: []string{"a", "b", "c"},
-			val:   "d",
-			want:  false,
+			name: "negative UDP jitter",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "udp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				UDPPorts:      "9000",
+				MTU:           1500,
+				MSS:           1460,
+				UDPInterval:   0.1,
+				UDPJitter:     -0.05,
+			},
+			wantErr: true,
+			errMsg:  "udp_interval and udp_jitter cannot be negative",
 		},
 		{
-			name:  "empty slice",
-			slice: []string{},
-			val:   "a",
-			want:  false,
+			name: "backpressure factor out of range",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:                   "localhost",
+				Rate:                     10.0,
+				MaxConcurrent:            100,
+				Protocol:                 "tcp",
+				MinDuration:              1.0,
+				MaxDuration:              10.0,
+				TCPPorts:                 "8080",
+				MTU:                      1500,
+				MSS:                      1460,
+				BackpressureURL:          "http://localhost:8082/backpressure",
+				BackpressurePollInterval: 1.0,
+				BackpressureFactor:       1.5,
+			},
+			wantErr: true,
+			errMsg:  "backpressure_factor must be greater than 0 and at most 1",
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := contains(tt.slice, tt.val)
-			assert.Equal(t, tt.want, got)
-		})
+		{
+			name: "relay chain with UDP",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "both",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				UDPPorts:      "9000",
+				MTU:           1500,
+				MSS:           1460,
+				RelayChain:    "relay-a:9999",
+			},
+			wantErr: true,
+			errMsg:  "relay_chain requires protocol tcp",
+		},
+		{
+			name: "invalid output format",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				OutputFile:    "results.xml",
+				OutputFormat:  "xml",
+			},
+			wantErr: true,
+			errMsg:  "invalid output format",
+		},
+		{
+			name: "transport ports only",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "both",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				MTU:            1500,
+				MSS:            1460,
+				TransportPorts: "9000=rpc",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid transport ports",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				TransportPorts: "9000=",
+			},
+			wantErr: true,
+			errMsg:  "no transport given for port 9000",
+		},
+		{
+			name: "response sizes",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				ResponseSizes: "8080=amplify:4",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid response sizes",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				ResponseSizes: "8080=amplify:1",
+			},
+			wantErr: true,
+			errMsg:  "invalid response_sizes",
+		},
+		{
+			name: "negative TCP read buffer",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				TCPReadBuffer: -1,
+			},
+			wantErr: true,
+			errMsg:  "tcp_read_buffer must be between 0 and 16777216",
+		},
+		{
+			name: "conntrack backoff",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:                    "localhost",
+				Rate:                      10.0,
+				MaxConcurrent:             100,
+				Protocol:                  "tcp",
+				MinDuration:               1.0,
+				MaxDuration:               10.0,
+				TCPPorts:                  "8080",
+				MTU:                       1500,
+				MSS:                       1460,
+				ConntrackBackoff:          true,
+				ConntrackBackoffWindow:    5,
+				ConntrackBackoffThreshold: 50,
+				ConntrackBackoffFactor:    0.1,
+			},
+			wantErr: false,
+		},
+		{
+			name: "conntrack backoff threshold above 100",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:                    "localhost",
+				Rate:                      10.0,
+				MaxConcurrent:             100,
+				Protocol:                  "tcp",
+				MinDuration:               1.0,
+				MaxDuration:               10.0,
+				TCPPorts:                  "8080",
+				MTU:                       1500,
+				MSS:                       1460,
+				ConntrackBackoff:          true,
+				ConntrackBackoffWindow:    5,
+				ConntrackBackoffThreshold: 150,
+				ConntrackBackoffFactor:    0.1,
+			},
+			wantErr: true,
+			errMsg:  "conntrack_backoff_threshold must be greater than 0 and at most 100",
+		},
+		{
+			name: "conntrack backoff without window",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:                    "localhost",
+				Rate:                      10.0,
+				MaxConcurrent:             100,
+				Protocol:                  "tcp",
+				MinDuration:               1.0,
+				MaxDuration:               10.0,
+				TCPPorts:                  "8080",
+				MTU:                       1500,
+				MSS:                       1460,
+				ConntrackBackoff:          true,
+				ConntrackBackoffThreshold: 50,
+				ConntrackBackoffFactor:    0.1,
+			},
+			wantErr: true,
+			errMsg:  "conntrack_backoff_window must be positive",
+		},
+		{
+			name: "conntrack backoff factor of 1",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:                    "localhost",
+				Rate:                      10.0,
+				MaxConcurrent:             100,
+				Protocol:                  "tcp",
+				MinDuration:               1.0,
+				MaxDuration:               10.0,
+				TCPPorts:                  "8080",
+				MTU:                       1500,
+				MSS:                       1460,
+				ConntrackBackoff:          true,
+				ConntrackBackoffWindow:    5,
+				ConntrackBackoffThreshold: 50,
+				ConntrackBackoffFactor:    1,
+			},
+			wantErr: true,
+			errMsg:  "conntrack_backoff_factor must be greater than 0 and less than 1",
+		},
+		{
+			name: "valid network namespaces",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				Netns:         "client-a=2,client-b",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid network namespace share",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				Netns:         "client-a=0",
+			},
+			wantErr: true,
+			errMsg:  "invalid netns",
+		},
+		{
+			name: "network namespaces with connection reuse",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:          "localhost",
+				Rate:            10.0,
+				MaxConcurrent:   100,
+				Protocol:        "tcp",
+				MinDuration:     1.0,
+				MaxDuration:     10.0,
+				TCPPorts:        "8080",
+				MTU:             1500,
+				MSS:             1460,
+				Netns:           "client-a",
+				ConnectionReuse: true,
+				PoolSize:        10,
+			},
+			wantErr: true,
+			errMsg:  "netns cannot be combined with connection_reuse",
+		},
+		{
+			name: "network namespaces with happy eyeballs",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				Netns:         "client-a",
+				AddressFamily: "prefer-ipv6",
+			},
+			wantErr: true,
+			errMsg:  "netns cannot be combined with address_family",
+		},
+		{
+			name: "progress events on a file descriptor",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				ProgressEvents: "fd:3",
+			},
+			wantErr: false,
+		},
+		{
+			name: "progress events on a low file descriptor",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				ProgressEvents: "fd:1",
+			},
+			wantErr: true,
+			errMsg:  "progress_events file descriptor must be 3 or higher",
+		},
+		{
+			name: "progress events and ndjson output on stdout",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				ProgressEvents: "-",
+				Output:         "ndjson",
+				StatsInterval:  10,
+			},
+			wantErr: true,
+			errMsg:  "progress_events cannot write to stdout when output is ndjson",
+		},
+		{
+			name: "progress events and flow log on stdout",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				ProgressEvents: "-",
+				FlowLogFile:    "-",
+			},
+			wantErr: true,
+			errMsg:  "progress_events cannot write to stdout when flow_log_file does",
+		},
+		{
+			name: "progress events and output sink on stdout",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				ProgressEvents: "-",
+				OutputFormat:   "json",
+				OutputSinks:    "-",
+			},
+			wantErr: true,
+			errMsg:  "output_sink cannot write to stdout when progress_events does",
+		},
+		{
+			name: "valid warn thresholds",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				WarnThresholds: "max_error_rate=1,max_p99_latency=100",
+				WarnWindow:     30,
+				WarnWebhook:    "https://hooks.example.com/services/x",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid warn thresholds",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				WarnThresholds: "max_jitter=5",
+				WarnWindow:     30,
+			},
+			wantErr: true,
+			errMsg:  "invalid warn_thresholds",
+		},
+		{
+			name: "warn window not positive",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				WarnThresholds: "max_error_rate=1",
+			},
+			wantErr: true,
+			errMsg:  "warn_window must be positive when warn_thresholds is set",
+		},
+		{
+			name: "warn webhook without thresholds",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				WarnWebhook:   "https://hooks.example.com",
+			},
+			wantErr: true,
+			errMsg:  "warn_webhook requires warn_thresholds",
+		},
+		{
+			name: "warn webhook not a URL",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				WarnThresholds: "max_error_rate=1",
+				WarnWindow:     30,
+				WarnWebhook:    "hooks.example.com",
+			},
+			wantErr: true,
+			errMsg:  "warn_webhook must be an http or https URL",
+		},
+		{
+			name: "valid payload mimicry",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				MimicPayloads: true,
+				MimicPorts:    "8053=dns,9443=tls",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid mimic protocol",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				MimicPorts:    "8053=ldap",
+			},
+			wantErr: true,
+			errMsg:  "invalid mimic_ports",
+		},
+		{
+			name: "invalid mimic port",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				MimicPorts:    "70000=dns",
+			},
+			wantErr: true,
+			errMsg:  "invalid mimic_ports",
+		},
+		{
+			name: "payload mimicry with flow header",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				MimicPayloads: true,
+				FlowHeader:    true,
+			},
+			wantErr: true,
+			errMsg:  "payload mimicry cannot be combined with flow_header",
+		},
+		{
+			name: "payload mimicry with bitrate",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				MimicPorts:    "5001=dns",
+				UDPBitrate:    "1M",
+			},
+			wantErr: true,
+			errMsg:  "payload mimicry cannot be combined with udp_bitrate",
+		},
+		{
+			name: "valid pause windows",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				PauseWindows:  "02:00-02:15,23:50-00:10",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid pause windows",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				PauseWindows:  "02:00-02:00",
+			},
+			wantErr: true,
+			errMsg:  "invalid pause_windows",
+		},
+		{
+			name: "valid udp bitrate",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				UDPBitrate:    "10M",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid udp bitrate",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				UDPBitrate:    "10 Mbps",
+			},
+			wantErr: true,
+			errMsg:  "invalid udp_bitrate",
+		},
+		{
+			name: "valid steps",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				Steps:         "100:2m;500:2m:peak",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid step",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				Steps:         "100:2m;0:2m",
+			},
+			wantErr: true,
+			errMsg:  "invalid step",
+		},
+		{
+			name: "steps with burst mode",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				Steps:         "100:2m",
+				BurstSize:     10,
+				BurstInterval: 1,
+			},
+			wantErr: true,
+			errMsg:  "step cannot be combined with burst mode",
+		},
+		{
+			name: "flow verbosity detail",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				FlowVerbosity: 3,
+			},
+			wantErr: false,
+		},
+		{
+			name: "flow verbosity out of range",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				FlowVerbosity: 4,
+			},
+			wantErr: true,
+			errMsg:  "invalid flow_verbosity",
+		},
+		{
+			name: "negative flow verbosity",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				FlowVerbosity: -1,
+			},
+			wantErr: true,
+			errMsg:  "invalid flow_verbosity",
+		},
+		{
+			name: "valid source port range",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:          "localhost",
+				Rate:            10.0,
+				MaxConcurrent:   100,
+				Protocol:        "tcp",
+				MinDuration:     1.0,
+				MaxDuration:     10.0,
+				TCPPorts:        "8080",
+				MTU:             1500,
+				MSS:             1460,
+				SourcePortRange: "20000-29999",
+				TrackTuples:     true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid source port range",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:          "localhost",
+				Rate:            10.0,
+				MaxConcurrent:   100,
+				Protocol:        "tcp",
+				MinDuration:     1.0,
+				MaxDuration:     10.0,
+				TCPPorts:        "8080",
+				MTU:             1500,
+				MSS:             1460,
+				SourcePortRange: "30000-20000",
+			},
+			wantErr: true,
+			errMsg:  "invalid source_port_range",
+		},
+		{
+			name: "source port range with connection reuse",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:          "localhost",
+				Rate:            10.0,
+				MaxConcurrent:   100,
+				Protocol:        "tcp",
+				MinDuration:     1.0,
+				MaxDuration:     10.0,
+				TCPPorts:        "8080",
+				MTU:             1500,
+				MSS:             1460,
+				SourcePortRange: "20000-29999",
+				ConnectionReuse: true,
+				PoolSize:        10,
+			},
+			wantErr: true,
+			errMsg:  "source_port_range cannot be combined",
+		},
+		{
+			name: "expected services",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				ExpectServices: "8080=echo, 443=tls,80=http,9=none",
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown expected service",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				ExpectServices: "8080=ssh",
+			},
+			wantErr: true,
+			errMsg:  `invalid expect_service: "ssh" for port 8080`,
+		},
+		{
+			name: "invalid expected service port",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				ExpectServices: "http=8080",
+			},
+			wantErr: true,
+			errMsg:  "invalid expect_service: invalid port",
+		},
+		{
+			name: "negative output sink URL expiry",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:              "localhost",
+				Rate:                10.0,
+				MaxConcurrent:       100,
+				Protocol:            "tcp",
+				MinDuration:         1.0,
+				MaxDuration:         10.0,
+				TCPPorts:            "8080",
+				MTU:                 1500,
+				MSS:                 1460,
+				OutputSinkURLExpiry: -1,
+			},
+			wantErr: true,
+			errMsg:  "output_sink_url_expiry must be between 0 and 604800 seconds",
+		},
+		{
+			name: "output sink URL expiry above 7 days",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:              "localhost",
+				Rate:                10.0,
+				MaxConcurrent:       100,
+				Protocol:            "tcp",
+				MinDuration:         1.0,
+				MaxDuration:         10.0,
+				TCPPorts:            "8080",
+				MTU:                 1500,
+				MSS:                 1460,
+				OutputSinkURLExpiry: 604801,
+			},
+			wantErr: true,
+			errMsg:  "output_sink_url_expiry must be between 0 and 604800 seconds",
+		},
+		{
+			name: "output sink URLs disabled",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:              "localhost",
+				Rate:                10.0,
+				MaxConcurrent:       100,
+				Protocol:            "tcp",
+				MinDuration:         1.0,
+				MaxDuration:         10.0,
+				TCPPorts:            "8080",
+				MTU:                 1500,
+				MSS:                 1460,
+				OutputSinks:         "s3://bucket",
+				OutputFormat:        "json",
+				OutputSinkURLExpiry: 0,
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid output sinks",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				OutputFormat:  "json",
+				OutputSinks:   "-,s3://bucket/runs,https://collector/upload",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid output sink",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				OutputFormat:  "json",
+				OutputSinks:   "ftp://host/dir",
+			},
+			wantErr: true,
+			errMsg:  "invalid output_sink: unsupported sink scheme",
+		},
+		{
+			name: "output sink needs output format",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				OutputSinks:   "/tmp/results",
+			},
+			wantErr: true,
+			errMsg:  "invalid output format",
+		},
+		{
+			name: "output sink to stdout with ndjson",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				OutputFormat:  "json",
+				Output:        "ndjson",
+				StatsInterval: 10,
+				OutputSinks:   "-",
+			},
+			wantErr: true,
+			errMsg:  "output_sink cannot write to stdout when output is ndjson",
+		},
+		{
+			name: "negative output sink retries",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:            "localhost",
+				Rate:              10.0,
+				MaxConcurrent:     100,
+				Protocol:          "tcp",
+				MinDuration:       1.0,
+				MaxDuration:       10.0,
+				TCPPorts:          "8080",
+				MTU:               1500,
+				MSS:               1460,
+				OutputFormat:      "json",
+				OutputSinks:       "/tmp/results",
+				OutputSinkRetries: -1,
+			},
+			wantErr: true,
+			errMsg:  "output_sink_retries cannot be negative",
+		},
+		{
+			name: "valid phases",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				MaxErrorRate:  100,
+				Phases:        "steady:60:max_error_rate=0.1;chaos:120:max_error_rate=5",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid phase",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				MaxErrorRate:  100,
+				Phases:        "steady:60;steady:120",
+			},
+			wantErr: true,
+			errMsg:  "invalid phase: duplicate phase steady",
+		},
+		{
+			name: "valid failover",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:            "localhost",
+				Rate:              10.0,
+				MaxConcurrent:     100,
+				Protocol:          "tcp",
+				MinDuration:       1.0,
+				MaxDuration:       10.0,
+				TCPPorts:          "8080",
+				MTU:               1500,
+				MSS:               1460,
+				BackupServer:      "backup",
+				FailoverThreshold: 50,
+				FailoverWindow:    10,
+			},
+			wantErr: false,
+		},
+		{
+			name: "backup server with target cidr",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:            "localhost",
+				Rate:              10.0,
+				MaxConcurrent:     100,
+				Protocol:          "tcp",
+				MinDuration:       1.0,
+				MaxDuration:       10.0,
+				TCPPorts:          "8080",
+				MTU:               1500,
+				MSS:               1460,
+				BackupServer:      "backup",
+				FailoverThreshold: 50,
+				FailoverWindow:    10,
+				TargetCIDR:        "10.2.0.0/24",
+			},
+			wantErr: true,
+			errMsg:  "backup_server cannot be combined with target_cidr",
+		},
+		{
+			name: "backup server same as server",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:            "localhost",
+				Rate:              10.0,
+				MaxConcurrent:     100,
+				Protocol:          "tcp",
+				MinDuration:       1.0,
+				MaxDuration:       10.0,
+				TCPPorts:          "8080",
+				MTU:               1500,
+				MSS:               1460,
+				BackupServer:      "localhost",
+				FailoverThreshold: 50,
+				FailoverWindow:    10,
+			},
+			wantErr: true,
+			errMsg:  "backup_server must differ from server",
+		},
+		{
+			name: "invalid failover threshold",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:            "localhost",
+				Rate:              10.0,
+				MaxConcurrent:     100,
+				Protocol:          "tcp",
+				MinDuration:       1.0,
+				MaxDuration:       10.0,
+				TCPPorts:          "8080",
+				MTU:               1500,
+				MSS:               1460,
+				BackupServer:      "backup",
+				FailoverThreshold: 150,
+				FailoverWindow:    10,
+			},
+			wantErr: true,
+			errMsg:  "failover_threshold must be greater than 0 and at most 100",
+		},
+		{
+			name: "invalid failover window",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:            "localhost",
+				Rate:              10.0,
+				MaxConcurrent:     100,
+				Protocol:          "tcp",
+				MinDuration:       1.0,
+				MaxDuration:       10.0,
+				TCPPorts:          "8080",
+				MTU:               1500,
+				MSS:               1460,
+				BackupServer:      "backup",
+				FailoverThreshold: 50,
+			},
+			wantErr: true,
+			errMsg:  "failover_window must be positive",
+		},
+		{
+			name: "valid payload template",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:          "localhost",
+				Rate:            10.0,
+				MaxConcurrent:   100,
+				Protocol:        "tcp",
+				MinDuration:     1.0,
+				MaxDuration:     10.0,
+				TCPPorts:        "8080",
+				MTU:             1500,
+				MSS:             1460,
+				PayloadTemplate: "flow={{.FlowID}}",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid payload template",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:          "localhost",
+				Rate:            10.0,
+				MaxConcurrent:   100,
+				Protocol:        "tcp",
+				MinDuration:     1.0,
+				MaxDuration:     10.0,
+				TCPPorts:        "8080",
+				MTU:             1500,
+				MSS:             1460,
+				PayloadTemplate: "flow={{.FlowID",
+			},
+			wantErr: true,
+			errMsg:  "invalid payload_template",
+		},
+		{
+			name: "valid udp timeouts",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				UDPTimeoutMin: 0.05,
+				UDPTimeoutMax: 3,
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative udp timeout min",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				UDPTimeoutMin: -1,
+				UDPTimeoutMax: 3,
+			},
+			wantErr: true,
+			errMsg:  "udp_timeout_min cannot be negative",
+		},
+		{
+			name: "negative udp timeout max",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				UDPTimeoutMax: -1,
+			},
+			wantErr: true,
+			errMsg:  "udp_timeout_max cannot be negative",
+		},
+		{
+			name: "udp timeout max below min",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				UDPTimeoutMin: 2,
+				UDPTimeoutMax: 1,
+			},
+			wantErr: true,
+			errMsg:  "udp_timeout_max must be at least udp_timeout_min",
+		},
+		{
+			name: "valid target cidr with probe",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				TargetCIDR:    "10.2.0.0/24",
+				TargetProbe:   true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "target cidr too large",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				TargetCIDR:    "10.0.0.0/8",
+			},
+			wantErr: true,
+			errMsg:  "target_cidr 10.0.0.0/8 covers more than 65536 addresses",
+		},
+		{
+			name: "target probe without target cidr",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				TargetProbe:   true,
+			},
+			wantErr: true,
+			errMsg:  "target_probe requires target_cidr",
+		},
+		{
+			name: "compressible payload pattern",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				PayloadPattern: "compressible",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid payload pattern",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				PayloadPattern: "ones",
+			},
+			wantErr: true,
+			errMsg:  "invalid payload_pattern: ones, must be one of: [cached random zeros ascii-text compressible]",
+		},
+		{
+			name: "bimodal payload sizes",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:               "localhost",
+				Rate:                 10.0,
+				MaxConcurrent:        100,
+				Protocol:             "tcp",
+				MinDuration:          1.0,
+				MaxDuration:          10.0,
+				TCPPorts:             "8080",
+				MTU:                  1500,
+				MSS:                  1460,
+				MinPayloadSize:       64,
+				MaxPayloadSize:       1400,
+				PayloadDistribution:  "bimodal",
+				PayloadLargeFraction: 0.3,
+			},
+			wantErr: false,
+		},
+		{
+			name: "bimodal payload without sizes",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:              "localhost",
+				Rate:                10.0,
+				MaxConcurrent:       100,
+				Protocol:            "tcp",
+				MinDuration:         1.0,
+				MaxDuration:         10.0,
+				TCPPorts:            "8080",
+				MTU:                 1500,
+				MSS:                 1460,
+				PayloadDistribution: "bimodal",
+			},
+			wantErr: true,
+			errMsg:  "payload_distribution bimodal requires 0 < min_payload_size < max_payload_size",
+		},
+		{
+			name: "bimodal payload with fixed size",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:              "localhost",
+				Rate:                10.0,
+				MaxConcurrent:       100,
+				Protocol:            "tcp",
+				MinDuration:         1.0,
+				MaxDuration:         10.0,
+				TCPPorts:            "8080",
+				MTU:                 1500,
+				MSS:                 1460,
+				PayloadSize:         100,
+				PayloadDistribution: "bimodal",
+			},
+			wantErr: true,
+			errMsg:  "payload_size cannot be combined with payload_distribution bimodal",
+		},
+		{
+			name: "payload over the size limit",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				MinPayloadSize: 1024,
+				MaxPayloadSize: PayloadSizeLimit + 1,
+			},
+			wantErr: true,
+			errMsg:  "payload sizes cannot exceed 67108864 bytes",
+		},
+		{
+			name: "payload large fraction out of range",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:               "localhost",
+				Rate:                 10.0,
+				MaxConcurrent:        100,
+				Protocol:             "tcp",
+				MinDuration:          1.0,
+				MaxDuration:          10.0,
+				TCPPorts:             "8080",
+				MTU:                  1500,
+				MSS:                  1460,
+				PayloadLargeFraction: 1.5,
+			},
+			wantErr: true,
+			errMsg:  "payload_large_fraction must be between 0 and 1",
+		},
+		{
+			name: "empirical payload sizes",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:              "localhost",
+				Rate:                10.0,
+				MaxConcurrent:       100,
+				Protocol:            "tcp",
+				MinDuration:         1.0,
+				MaxDuration:         10.0,
+				TCPPorts:            "8080",
+				MTU:                 1500,
+				MSS:                 1460,
+				PayloadDistribution: "empirical",
+				PayloadSizes:        "64:7,576:4,1500:1",
+			},
+			wantErr: false,
+		},
+		{
+			name: "imix payload sizes",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:              "localhost",
+				Rate:                10.0,
+				MaxConcurrent:       100,
+				Protocol:            "tcp",
+				MinDuration:         1.0,
+				MaxDuration:         10.0,
+				TCPPorts:            "8080",
+				MTU:                 1500,
+				MSS:                 1460,
+				PayloadDistribution: "empirical",
+				PayloadSizes:        "IMIX",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid empirical payload sizes",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:              "localhost",
+				Rate:                10.0,
+				MaxConcurrent:       100,
+				Protocol:            "tcp",
+				MinDuration:         1.0,
+				MaxDuration:         10.0,
+				TCPPorts:            "8080",
+				MTU:                 1500,
+				MSS:                 1460,
+				PayloadDistribution: "empirical",
+				PayloadSizes:        "64:0",
+			},
+			wantErr: true,
+			errMsg:  `invalid payload_sizes: invalid weight "0"`,
+		},
+		{
+			name: "payload sizes without empirical distribution",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				PayloadSizes:  "64:1",
+			},
+			wantErr: true,
+			errMsg:  "payload_sizes requires payload_distribution empirical",
+		},
+		{
+			name: "invalid payload distribution",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:              "localhost",
+				Rate:                10.0,
+				MaxConcurrent:       100,
+				Protocol:            "tcp",
+				MinDuration:         1.0,
+				MaxDuration:         10.0,
+				TCPPorts:            "8080",
+				MTU:                 1500,
+				MSS:                 1460,
+				PayloadDistribution: "normal",
+			},
+			wantErr: true,
+			errMsg:  "invalid payload_distribution: normal, must be one of: [uniform bimodal empirical]",
+		},
+		{
+			name: "stop condition all",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				FlowCount:     100,
+				FlowTimeout:   60,
+				StopCondition: "all",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid stop condition",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				StopCondition: "first",
+			},
+			wantErr: true,
+			errMsg:  "invalid stop_condition: first, must be one of: [any all]",
+		},
+		{
+			name: "negative shutdown timeout",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:          "localhost",
+				Rate:            10.0,
+				MaxConcurrent:   100,
+				Protocol:        "tcp",
+				MinDuration:     1.0,
+				MaxDuration:     10.0,
+				TCPPorts:        "8080",
+				MTU:             1500,
+				MSS:             1460,
+				ShutdownTimeout: -1,
+			},
+			wantErr: true,
+			errMsg:  "shutdown_timeout cannot be negative",
+		},
+		{
+			name: "latency heatmap slice",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:              "localhost",
+				Rate:                10.0,
+				MaxConcurrent:       100,
+				Protocol:            "tcp",
+				MinDuration:         1.0,
+				MaxDuration:         10.0,
+				TCPPorts:            "8080",
+				MTU:                 1500,
+				MSS:                 1460,
+				LatencyHeatmapSlice: 5,
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative latency heatmap slice",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:              "localhost",
+				Rate:                10.0,
+				MaxConcurrent:       100,
+				Protocol:            "tcp",
+				MinDuration:         1.0,
+				MaxDuration:         10.0,
+				TCPPorts:            "8080",
+				MTU:                 1500,
+				MSS:                 1460,
+				LatencyHeatmapSlice: -1,
+			},
+			wantErr: true,
+			errMsg:  "latency_heatmap_slice cannot be negative",
+		},
+		{
+			name: "warmup within flow timeout",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				FlowTimeout:   60,
+				Warmup:        10,
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative warmup",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				Warmup:        -1,
+			},
+			wantErr: true,
+			errMsg:  "warmup cannot be negative",
+		},
+		{
+			name: "warmup as long as flow timeout",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				FlowTimeout:   10,
+				Warmup:        10,
+			},
+			wantErr: true,
+			errMsg:  "warmup must be shorter than flow_timeout",
+		},
+		{
+			name: "sla assertions",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				MaxErrorRate:  1.5,
+				MaxP99Latency: 20,
+				MinThroughput: 0.5,
+			},
+			wantErr: false,
+		},
+		{
+			name: "max error rate above 100",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				MaxErrorRate:  101,
+			},
+			wantErr: true,
+			errMsg:  "max_error_rate must be between 0 and 100 percent",
+		},
+		{
+			name: "negative max p99 latency",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				MaxErrorRate:  100,
+				MaxP99Latency: -1,
+			},
+			wantErr: true,
+			errMsg:  "max_p99_latency cannot be negative",
+		},
+		{
+			name: "negative min throughput",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				MaxErrorRate:  100,
+				MinThroughput: -1,
+			},
+			wantErr: true,
+			errMsg:  "min_throughput cannot be negative",
+		},
+		{
+			name: "artifact bundle tgz",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				ArtifactBundle: "run-42.tgz",
+			},
+			wantErr: false,
+		},
+		{
+			name: "artifact bundle zip",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				ArtifactBundle: "out/RUN.ZIP",
+			},
+			wantErr: false,
+		},
+		{
+			name: "artifact bundle unknown format",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				ArtifactBundle: "run.tar",
+			},
+			wantErr: true,
+			errMsg:  "artifact_bundle must end in .tgz, .tar.gz or .zip",
+		},
+		{
+			name: "control API with status port",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				StatusPort:    "8083",
+				ControlAPI:    true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "control API without status port",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				ControlAPI:    true,
+			},
+			wantErr: true,
+			errMsg:  "control_api requires status_port",
+		},
+		{
+			name: "valid connect retries",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				ConnectTimeout: 2,
+				ConnectRetries: 3,
+				ConnectBackoff: 0.5,
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative connect timeout",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				ConnectTimeout: -1,
+			},
+			wantErr: true,
+			errMsg:  "connect_timeout cannot be negative",
+		},
+		{
+			name: "negative connect retries",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				ConnectRetries: -1,
+			},
+			wantErr: true,
+			errMsg:  "connect_retries cannot be negative",
+		},
+		{
+			name: "negative connect backoff",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				ConnectBackoff: -0.1,
+			},
+			wantErr: true,
+			errMsg:  "connect_backoff cannot be negative",
+		},
+		{
+			name: "valid metadata",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				Meta:          "owner=netops,ticket=NET-1234,purpose=soak test",
+			},
+			wantErr: false,
+		},
+		{
+			name: "metadata without value separator",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				Meta:          "owner",
+			},
+			wantErr: true,
+			errMsg:  "invalid meta",
+		},
+		{
+			name: "metadata with invalid key",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				Meta:          "team-name=netops",
+			},
+			wantErr: true,
+			errMsg:  "invalid meta",
+		},
+		{
+			name: "metadata with reserved key",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				Meta:          "scenario=soak",
+			},
+			wantErr: true,
+			errMsg:  "scenario is reserved",
+		},
+		{
+			name: "duplicate metadata key",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				Meta:          "owner=a,owner=b",
+			},
+			wantErr: true,
+			errMsg:  "duplicate metadata key owner",
+		},
+		{
+			name: "prefer IPv6",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:             "localhost",
+				Rate:               10.0,
+				MaxConcurrent:      100,
+				Protocol:           "tcp",
+				MinDuration:        1.0,
+				MaxDuration:        10.0,
+				TCPPorts:           "8080",
+				MTU:                1500,
+				MSS:                1460,
+				AddressFamily:      "prefer-ipv6",
+				HappyEyeballsDelay: 0.25,
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid address family",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				AddressFamily: "ipv5",
+			},
+			wantErr: true,
+			errMsg:  "invalid address_family",
+		},
+		{
+			name: "negative happy eyeballs delay",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:             "localhost",
+				Rate:               10.0,
+				MaxConcurrent:      100,
+				Protocol:           "tcp",
+				MinDuration:        1.0,
+				MaxDuration:        10.0,
+				TCPPorts:           "8080",
+				MTU:                1500,
+				MSS:                1460,
+				HappyEyeballsDelay: -1,
+			},
+			wantErr: true,
+			errMsg:  "happy_eyeballs_delay cannot be negative",
+		},
+		{
+			name: "source addresses with hash strategy",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:          "localhost",
+				Rate:            10.0,
+				MaxConcurrent:   100,
+				Protocol:        "tcp",
+				MinDuration:     1.0,
+				MaxDuration:     10.0,
+				TCPPorts:        "8080",
+				MTU:             1500,
+				MSS:             1460,
+				SourceAddresses: "10.0.0.5, 10.0.0.6, eth1",
+				SourceStrategy:  "hash",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid source addresses",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:          "localhost",
+				Rate:            10.0,
+				MaxConcurrent:   100,
+				Protocol:        "tcp",
+				MinDuration:     1.0,
+				MaxDuration:     10.0,
+				TCPPorts:        "8080",
+				MTU:             1500,
+				MSS:             1460,
+				SourceAddresses: "10.0.0.0/24",
+			},
+			wantErr: true,
+			errMsg:  "invalid source_addresses",
+		},
+		{
+			name: "source addresses with source CIDR",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:          "localhost",
+				Rate:            10.0,
+				MaxConcurrent:   100,
+				Protocol:        "tcp",
+				MinDuration:     1.0,
+				MaxDuration:     10.0,
+				TCPPorts:        "8080",
+				MTU:             1500,
+				MSS:             1460,
+				SourceAddresses: "10.0.0.5",
+				SourceCIDR:      "10.1.0.0/16",
+			},
+			wantErr: true,
+			errMsg:  "source_addresses cannot be combined with source_cidr",
+		},
+		{
+			name: "source addresses with connection reuse",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:          "localhost",
+				Rate:            10.0,
+				MaxConcurrent:   100,
+				Protocol:        "tcp",
+				MinDuration:     1.0,
+				MaxDuration:     10.0,
+				TCPPorts:        "8080",
+				MTU:             1500,
+				MSS:             1460,
+				SourceAddresses: "10.0.0.5",
+				ConnectionReuse: true,
+				PoolSize:        10,
+			},
+			wantErr: true,
+			errMsg:  "source_addresses cannot be combined with connection_reuse",
+		},
+		{
+			name: "invalid source strategy",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				SourceCIDR:     "10.1.0.0/16",
+				SourceStrategy: "least-used",
+			},
+			wantErr: true,
+			errMsg:  "invalid source_strategy",
+		},
+		{
+			name: "fixed flow label",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				FlowLabel:     "0x12345",
+			},
+			wantErr: false,
+		},
+		{
+			name: "random flow label",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				FlowLabel:     "random",
+			},
+			wantErr: false,
+		},
+		{
+			name: "flow label out of range",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				FlowLabel:     "0x100000",
+			},
+			wantErr: true,
+			errMsg:  "invalid flow_label",
+		},
+		{
+			name: "flow label with connection reuse",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:          "localhost",
+				Rate:            10.0,
+				MaxConcurrent:   100,
+				Protocol:        "tcp",
+				MinDuration:     1.0,
+				MaxDuration:     10.0,
+				TCPPorts:        "8080",
+				MTU:             1500,
+				MSS:             1460,
+				FlowLabel:       "random",
+				ConnectionReuse: true,
+				PoolSize:        10,
+			},
+			wantErr: true,
+			errMsg:  "flow_label cannot be combined",
+		},
+		{
+			name: "flow label with local address",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				FlowLabel:     "7",
+				LocalAddress:  "2001:db8::1",
+			},
+			wantErr: true,
+			errMsg:  "flow_label cannot be combined",
+		},
+		{
+			name: "local address and interface",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				LocalAddress:  "192.0.2.10",
+				Interface:     "eth1",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid local address",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				LocalAddress:  "192.0.2",
+			},
+			wantErr: true,
+			errMsg:  "invalid local_address",
+		},
+		{
+			name: "local address with source CIDR",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				LocalAddress:  "192.0.2.10",
+				SourceCIDR:    "10.1.0.0/16",
+			},
+			wantErr: true,
+			errMsg:  "local_address cannot be combined with source_cidr",
+		},
+		{
+			name: "interface name too long",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				Interface:     "interface-name-too-long",
+			},
+			wantErr: true,
+			errMsg:  "longer than 15 characters",
+		},
+		{
+			name: "peer matrix",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:            "localhost",
+				Rate:              10.0,
+				MaxConcurrent:     100,
+				Protocol:          "tcp",
+				MinDuration:       1.0,
+				MaxDuration:       10.0,
+				TCPPorts:          "8080",
+				MTU:               1500,
+				MSS:               1460,
+				Role:              "agent",
+				Peers:             "flow-agent",
+				PeerProbePort:     53,
+				PeerProbeInterval: 5,
+				PeerProbeCount:    3,
+			},
+			wantErr: false,
+		},
+		{
+			name: "peers without agent role",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:            "localhost",
+				Rate:              10.0,
+				MaxConcurrent:     100,
+				Protocol:          "tcp",
+				MinDuration:       1.0,
+				MaxDuration:       10.0,
+				TCPPorts:          "8080",
+				MTU:               1500,
+				MSS:               1460,
+				Peers:             "flow-agent",
+				PeerProbePort:     53,
+				PeerProbeInterval: 5,
+				PeerProbeCount:    3,
+			},
+			wantErr: true,
+			errMsg:  "peers requires role agent",
+		},
+		{
+			name: "peers without probe port",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:            "localhost",
+				Rate:              10.0,
+				MaxConcurrent:     100,
+				Protocol:          "tcp",
+				MinDuration:       1.0,
+				MaxDuration:       10.0,
+				TCPPorts:          "8080",
+				MTU:               1500,
+				MSS:               1460,
+				Role:              "agent",
+				Peers:             "flow-agent",
+				PeerProbePort:     0,
+				PeerProbeInterval: 5,
+				PeerProbeCount:    3,
+			},
+			wantErr: true,
+			errMsg:  "peer_probe_port must be between 1 and 65535",
+		},
+		{
+			name: "peers without probe count",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:            "localhost",
+				Rate:              10.0,
+				MaxConcurrent:     100,
+				Protocol:          "tcp",
+				MinDuration:       1.0,
+				MaxDuration:       10.0,
+				TCPPorts:          "8080",
+				MTU:               1500,
+				MSS:               1460,
+				Role:              "agent",
+				Peers:             "flow-agent",
+				PeerProbePort:     53,
+				PeerProbeInterval: 5,
+				PeerProbeCount:    0,
+			},
+			wantErr: true,
+			errMsg:  "peer_probe_interval and peer_probe_count must be positive",
+		},
+		{
+			name: "TTL limits",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				TTL:           64,
+				TTLPorts:      "8080=2",
+			},
+			wantErr: false,
+		},
+		{
+			name: "TTL out of range",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				TTL:           256,
+			},
+			wantErr: true,
+			errMsg:  "ttl must be between 0 and 255",
+		},
+		{
+			name: "invalid TTL port value",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				TTLPorts:      "8080=0",
+			},
+			wantErr: true,
+			errMsg:  `TTL "0" of port 8080 must be between 1 and 255`,
+		},
+		{
+			name: "agent role",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				Role:          "agent",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid role",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				Role:          "server",
+			},
+			wantErr: true,
+			errMsg:  "invalid role: server",
+		},
+		{
+			name: "DSCP classes",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				DSCP:          "af41",
+				DSCPPorts:     "53=ef, 8080=10",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid DSCP",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				DSCP:          "gold",
+			},
+			wantErr: true,
+			errMsg:  "invalid dscp",
+		},
+		{
+			name: "invalid DSCP port class",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				DSCPPorts:     "53=64",
+			},
+			wantErr: true,
+			errMsg:  "invalid dscp_ports: port 53",
+		},
+		{
+			name: "source CIDR",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				SourceCIDR:    "10.1.0.0/16",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid source CIDR",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				SourceCIDR:    "10.1.0.0",
+			},
+			wantErr: true,
+			errMsg:  "invalid source_cidr",
+		},
+		{
+			name: "source CIDR with connection reuse",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:          "localhost",
+				Rate:            10.0,
+				MaxConcurrent:   100,
+				Protocol:        "tcp",
+				MinDuration:     1.0,
+				MaxDuration:     10.0,
+				TCPPorts:        "8080",
+				MTU:             1500,
+				MSS:             1460,
+				SourceCIDR:      "10.1.0.0/16",
+				ConnectionReuse: true,
+				PoolSize:        10,
+			},
+			wantErr: true,
+			errMsg:  "source_cidr cannot be combined",
+		},
+		{
+			name: "ndjson output",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				Output:        "ndjson",
+				StatsInterval: 5,
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid output",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				Output:        "xml",
+			},
+			wantErr: true,
+			errMsg:  "invalid output: xml",
+		},
+		{
+			name: "ndjson output without stats interval",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				Output:        "ndjson",
+			},
+			wantErr: true,
+			errMsg:  "stats_interval must be positive",
+		},
+		{
+			name: "ndjson output with flow log on stdout",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				Output:        "ndjson",
+				StatsInterval: 5,
+				FlowLogFile:   "-",
+			},
+			wantErr: true,
+			errMsg:  "flow_log_file cannot write to stdout",
+		},
+		{
+			name: "negative report interval",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				ReportInterval: -1,
+			},
+			wantErr: true,
+			errMsg:  "report_interval cannot be negative",
+		},
+		{
+			name: "tui with ndjson output",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "tcp",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				MTU:           1500,
+				MSS:           1460,
+				Output:        "ndjson",
+				StatsInterval: 5,
+				TUI:           true,
+			},
+			wantErr: true,
+			errMsg:  "tui cannot be combined with output ndjson",
+		},
+		{
+			name: "tui with progress events on stdout",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				ProgressEvents: "-",
+				TUI:            true,
+			},
+			wantErr: true,
+			errMsg:  "tui cannot be combined with flow_log_file or progress_events",
+		},
+		{
+			name: "priority ports",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "both",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				UDPPorts:      "53",
+				MTU:           1500,
+				MSS:           1460,
+				PriorityPorts: "53=high,8080=low",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid priority class",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "both",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				UDPPorts:      "53",
+				MTU:           1500,
+				MSS:           1460,
+				PriorityPorts: "53=urgent",
+			},
+			wantErr: true,
+			errMsg:  "priority \"urgent\" of port 53 must be one of",
+		},
+		{
+			name: "invalid priority ports",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:        "localhost",
+				Rate:          10.0,
+				MaxConcurrent: 100,
+				Protocol:      "both",
+				MinDuration:   1.0,
+				MaxDuration:   10.0,
+				TCPPorts:      "8080",
+				UDPPorts:      "53",
+				MTU:           1500,
+				MSS:           1460,
+				PriorityPorts: "53",
+			},
+			wantErr: true,
+			errMsg:  "invalid priority_ports",
+		},
+		{
+			name: "negative wire overhead",
+			config: ClientConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				Server:         "localhost",
+				Rate:           10.0,
+				MaxConcurrent:  100,
+				Protocol:       "tcp",
+				MinDuration:    1.0,
+				MaxDuration:    10.0,
+				TCPPorts:       "8080",
+				MTU:            1500,
+				MSS:            1460,
+				WireL2Overhead: -1,
+			},
+			wantErr: true,
+			errMsg:  "wire_l2_overhead cannot be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestServerConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  ServerConfig
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "valid config with TCP ports",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TCPPortsServer: "8080",
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative duplicate window",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TCPPortsServer:  "8080",
+				DuplicateWindow: -1,
+			},
+			wantErr: true,
+			errMsg:  "duplicate_window cannot be negative",
+		},
+		{
+			name: "valid config with UDP ports",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				UDPPortsServer: "9000",
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid config with custom service ports only",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				HandlerPorts: "9092=mock-kafka",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid handler ports",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TCPPortsServer: "8080",
+				HandlerPorts:   "kafka",
+			},
+			wantErr: true,
+			errMsg:  "invalid handler_ports",
+		},
+		{
+			name: "no ports specified",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TCPPortsServer: "",
+				UDPPortsServer: "",
+			},
+			wantErr: true,
+			errMsg:  "at least one port",
+		},
+		{
+			name: "valid service modes",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TCPPortsServer: "7,9,19",
+				ServiceModes:   "7=echo,9=discard,5001=sink,19=chargen,8090=generator",
+				GeneratorRates: "8090=100M",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid service mode",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TCPPortsServer: "13",
+				ServiceModes:   "13=daytime",
+			},
+			wantErr: true,
+			errMsg:  "invalid service mode",
+		},
+		{
+			name: "invalid response delay",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TCPPortsServer: "8081",
+				ResponseDelays: "8081=50",
+			},
+			wantErr: true,
+			errMsg:  "invalid response_delays",
+		},
+		{
+			name: "invalid response drop",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TCPPortsServer: "8081",
+				ResponseDrops:  "8081=120",
+			},
+			wantErr: true,
+			errMsg:  "invalid response_drops",
+		},
+		{
+			name: "invalid response size",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TCPPortsServer: "8081",
+				ResponseSizes:  "8081=double:2",
+			},
+			wantErr: true,
+			errMsg:  "invalid response_sizes",
+		},
+		{
+			name: "invalid generator rate",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TCPPortsServer: "8090",
+				ServiceModes:   "8090=generator",
+				GeneratorRates: "8090=fast",
+			},
+			wantErr: true,
+			errMsg:  "invalid generator_rates",
+		},
+		{
+			name: "valid connection limits",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TCPPortsServer:            "8080",
+				MaxConnections:            1000,
+				MaxConnectionsPerListener: 200,
+				ConnectionLimitMode:       "queue",
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative connection limit",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TCPPortsServer: "8080",
+				MaxConnections: -1,
+			},
+			wantErr: true,
+			errMsg:  "cannot be negative",
+		},
+		{
+			name: "invalid connection limit mode",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TCPPortsServer:            "8080",
+				MaxConnectionsPerListener: 200,
+				ConnectionLimitMode:       "drop",
+			},
+			wantErr: true,
+			errMsg:  "invalid connection_limit_mode",
+		},
+		{
+			name: "negative accept rate",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TCPPortsServer: "8080",
+				AcceptRate:     -5,
+			},
+			wantErr: true,
+			errMsg:  "accept_rate cannot be negative",
+		},
+		{
+			name: "negative TCP listen sockets",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TCPPortsServer:   "8080",
+				TCPListenSockets: -1,
+			},
+			wantErr: true,
+			errMsg:  "tcp_listen_sockets cannot be negative",
+		},
+		{
+			name: "negative UDP workers",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				UDPPortsServer: "9000",
+				UDPWorkers:     -1,
+			},
+			wantErr: true,
+			errMsg:  "udp_workers cannot be negative",
+		},
+		{
+			name: "TCP read buffer too large",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TCPPortsServer: "8080",
+				TCPReadBuffer:  MaxTCPReadBuffer + 1,
+			},
+			wantErr: true,
+			errMsg:  "tcp_read_buffer must be between 0 and 16777216",
+		},
+		{
+			name: "negative UDP read buffer",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				UDPPortsServer: "9000",
+				UDPReadBuffer:  -1,
+			},
+			wantErr: true,
+			errMsg:  "udp_read_buffer must be between 0 and 65536",
+		},
+		{
+			name: "valid HTTP mode with echo headers",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TCPPortsServer:  "8080",
+				ServiceModes:    "8080=http",
+				HTTPEchoHeaders: "User-Agent, X-Request-Id",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid HTTP echo header",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TCPPortsServer:  "8080",
+				HTTPEchoHeaders: "X Request Id",
+			},
+			wantErr: true,
+			errMsg:  "invalid http_echo_headers: \"X Request Id\" is not a valid header name",
+		},
+		{
+			name: "valid TLS ports with self-signed certificates",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TLSPortsServer: "8443",
+			},
+			wantErr: false,
+		},
+		{
+			name: "TLS certificate not in cert:key format",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TLSPortsServer:  "8443",
+				TLSCertificates: "server.pem",
+			},
+			wantErr: true,
+			errMsg:  "invalid tls_certificates: entry \"server.pem\" is not in cert:key format",
+		},
+		{
+			name: "missing TLS certificate",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TLSPortsServer:  "8443",
+				TLSCertificates: "/nonexistent/server.pem:/nonexistent/server.key",
+			},
+			wantErr: true,
+			errMsg:  "failed to load certificate /nonexistent/server.pem",
+		},
+		{
+			name: "connected UDP peers without idle timeout",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				UDPPortsServer:    "9000",
+				UDPConnectedPeers: true,
+			},
+			wantErr: true,
+			errMsg:  "udp_peer_idle_timeout must be positive",
+		},
+		{
+			name: "backpressure threshold without interval",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TCPPortsServer:             "8080",
+				BackpressureMaxConnections: 100,
+			},
+			wantErr: true,
+			errMsg:  "backpressure_interval must be positive",
+		},
+		{
+			name: "upstream fraction out of range",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TCPPortsServer:   "8080",
+				UpstreamServers:  "backend",
+				UpstreamFraction: 1.5,
+				UpstreamDepth:    1,
+				UpstreamTimeout:  2.0,
+			},
+			wantErr: true,
+			errMsg:  "upstream_fraction must be between 0 and 1",
+		},
+		{
+			name: "upstream servers without depth",
+			config: ServerConfig{
+				CommonConfig: CommonConfig{
+					LogLevel:  "info",
+					LogFormat: "json",
+				},
+				TCPPortsServer:   "8080",
+				UpstreamServers:  "backend",
+				UpstreamFraction: 0.5,
+				UpstreamTimeout:  2.0,
+			},
+			wantErr: true,
+			errMsg:  "upstream_depth must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestParsePortRange(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		first, last int
+		wantErr     bool
+	}{
+		{"single port", "8080", 8080, 8080, false},
+		{"range", "20000-29999", 20000, 29999, false},
+		{"range with spaces", " 20000 - 20001 ", 20000, 20001, false},
+		{"single port range", "9000-9000", 9000, 9000, false},
+		{"reversed range", "29999-20000", 0, 0, true},
+		{"open range", "20000-", 0, 0, true},
+		{"out of range port", "60000-70000", 0, 0, true},
+		{"invalid port", "abc", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, last, err := ParsePortRange(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.first, first)
+				assert.Equal(t, tt.last, last)
+			}
+		})
+	}
+}
+
+func TestParsePortMap(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[int]string
+		wantErr  bool
+	}{
+		{"empty string", "", map[int]string{}, false},
+		{"single entry", "9=discard", map[int]string{9: "discard"}, false},
+		{"multiple entries with spaces", " 7 = echo , 19=chargen ", map[int]string{7: "echo", 19: "chargen"}, false},
+		{"missing separator", "7echo", nil, true},
+		{"invalid port", "abc=echo", nil, true},
+		{"out of range port", "70000=echo", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParsePortMap(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseResponseDelays(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[int]ResponseDelay
+		wantErr  bool
+	}{
+		{"empty string", "", map[int]ResponseDelay{}, false},
+		{"fixed delay", "8081=50ms", map[int]ResponseDelay{8081: {Base: 50 * time.Millisecond}}, false},
+		{"delay with jitter", "8081=50ms±10ms, 8082 = 1s +- 200ms", map[int]ResponseDelay{
+			8081: {Base: 50 * time.Millisecond, Jitter: 10 * time.Millisecond},
+			8082: {Base: time.Second, Jitter: 200 * time.Millisecond},
+		}, false},
+		{"missing unit", "8081=50", nil, true},
+		{"negative delay", "8081=-5ms", nil, true},
+		{"invalid jitter", "8081=50ms±x", nil, true},
+		{"invalid port", "abc=50ms", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseResponseDelays(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+		})
+	}
+
+	assert.Equal(t, "50ms±10ms", ResponseDelay{Base: 50 * time.Millisecond, Jitter: 10 * time.Millisecond}.String())
+	assert.Equal(t, "20ms", ResponseDelay{Base: 20 * time.Millisecond}.String())
+}
+
+func TestParseResponseDrops(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[int]float64
+		wantErr  bool
+	}{
+		{"empty string", "", map[int]float64{}, false},
+		{"percentages", "8081=5, 8082 = 0.5%,8083=100", map[int]float64{8081: 5, 8082: 0.5, 8083: 100}, false},
+		{"zero percent", "8081=0", nil, true},
+		{"above 100 percent", "8081=101", nil, true},
+		{"not a number", "8081=some", nil, true},
+		{"invalid port", "abc=5", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseResponseDrops(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseResponseSizes(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[int]ResponseSize
+		wantErr  bool
+	}{
+		{"empty string", "", map[int]ResponseSize{}, false},
+		{"all modes", "8081=truncate:64, 8082 = Amplify:4,8083=fixed:1400", map[int]ResponseSize{
+			8081: {Mode: ResponseSizeTruncate, Value: 64},
+			8082: {Mode: ResponseSizeAmplify, Value: 4},
+			8083: {Mode: ResponseSizeFixed, Value: 1400},
+		}, false},
+		{"missing value", "8081=truncate", nil, true},
+		{"unknown mode", "8081=double:2", nil, true},
+		{"zero truncate", "8081=truncate:0", nil, true},
+		{"fixed above the largest datagram", "8081=fixed:70000", nil, true},
+		{"amplify by 1", "8081=amplify:1", nil, true},
+		{"amplify above 100", "8081=amplify:101", nil, true},
+		{"not a number", "8081=fixed:big", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseResponseSizes(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+		})
+	}
+
+	assert.Equal(t, 64, ResponseSize{Mode: ResponseSizeTruncate, Value: 64}.Of(100))
+	assert.Equal(t, 10, ResponseSize{Mode: ResponseSizeTruncate, Value: 64}.Of(10))
+	assert.Equal(t, 400, ResponseSize{Mode: ResponseSizeAmplify, Value: 4}.Of(100))
+	assert.Equal(t, 1400, ResponseSize{Mode: ResponseSizeFixed, Value: 1400}.Of(100))
+	assert.Equal(t, 100, ResponseSize{}.Of(100))
+	assert.Equal(t, "amplify:4", ResponseSize{Mode: ResponseSizeAmplify, Value: 4}.String())
+}
+
+func TestParseNetns(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []NetnsGroup
+		wantErr  bool
+	}{
+		{"names with shares", "client-a=2, client-b", []NetnsGroup{
+			{Name: "client-a", Path: "/run/netns/client-a", Share: 2},
+			{Name: "client-b", Path: "/run/netns/client-b", Share: 1},
+		}, false},
+		{"paths", "/var/run/netns/blue=0.5,/proc/1234/ns/net", []NetnsGroup{
+			{Name: "blue", Path: "/var/run/netns/blue", Share: 0.5},
+			{Name: "1234", Path: "/proc/1234/ns/net", Share: 1},
+		}, false},
+		{"empty", " , ", nil, true},
+		{"duplicate name", "blue,/var/run/netns/blue", nil, true},
+		{"zero share", "blue=0", nil, true},
+		{"invalid share", "blue=x", nil, true},
+		{"missing name", "=2", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseNetns(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseDSCP(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+		wantErr  bool
+	}{
+		{"ef", 46, false},
+		{" AF41 ", 34, false},
+		{"cs1", 8, false},
+		{"le", 1, false},
+		{"0", 0, false},
+		{"63", 63, false},
+		{"64", 0, true},
+		{"-1", 0, true},
+		{"gold", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ParseDSCP(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseSources(t *testing.T) {
+	sources, err := ParseSources(" 10.0.0.5, fd00::5 ,eth1,")
+	require.NoError(t, err)
+	assert.Equal(t, []Source{
+		{Addr: netip.MustParseAddr("10.0.0.5")},
+		{Addr: netip.MustParseAddr("fd00::5")},
+		{Interface: "eth1"},
+	}, sources)
+
+	for _, invalid := range []string{"", " , ", "10.0.0.0/24", "interface-name-too-long", "bond 0"} {
+		_, err := ParseSources(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestParsePayloadSizes(t *testing.T) {
+	sizes, err := ParsePayloadSizes(" 64:7, 576:4 ,1500:1,")
+	require.NoError(t, err)
+	assert.Equal(t, []PayloadSizeWeight{{64, 7}, {576, 4}, {1500, 1}}, sizes)
+
+	sizes, err = ParsePayloadSizes("imix")
+	require.NoError(t, err)
+	assert.Equal(t, []PayloadSizeWeight{{18, 7}, {548, 4}, {1472, 1}}, sizes)
+
+	for _, invalid := range []string{"", " , ", "64", "64:", "0:1", "-5:1", "64:0", "64:x"} {
+		_, err := ParsePayloadSizes(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestLargestPayloadSize(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ClientConfig
+		want   int
+	}{
+		{"default", ClientConfig{}, 0},
+		{"fixed", ClientConfig{PayloadSize: 4 << 20}, 4 << 20},
+		{"uniform", ClientConfig{MinPayloadSize: 64, MaxPayloadSize: 8 << 20}, 8 << 20},
+		{"empirical", ClientConfig{PayloadDistribution: "empirical", PayloadSizes: "64:7,2000000:1,576:4"}, 2000000},
+		{"imix", ClientConfig{PayloadDistribution: "empirical", PayloadSizes: "imix"}, 1472},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.config.LargestPayloadSize())
+		})
+	}
+}
+
+func TestParseTargetCIDR(t *testing.T) {
+	prefix, err := ParseTargetCIDR(" 10.2.0.7/24 ")
+	require.NoError(t, err)
+	assert.Equal(t, netip.MustParsePrefix("10.2.0.0/24"), prefix)
+
+	for _, valid := range []string{"10.0.0.0/16", "10.0.0.1/32", "fd00::/112"} {
+		_, err := ParseTargetCIDR(valid)
+		assert.NoError(t, err, valid)
+	}
+	for _, invalid := range []string{"", "10.2.0.0", "10.2.0.0/33", "10.0.0.0/15", "fd00::/64"} {
+		_, err := ParseTargetCIDR(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestParsePhases(t *testing.T) {
+	phases, err := ParsePhases("steady:60:max_error_rate=0.1; chaos:120.5:max_error_rate=5, max_p99_latency=250,min_throughput=1;recovery:30")
+	require.NoError(t, err)
+	assert.Equal(t, []Phase{
+		{Name: "steady", Duration: 60, MaxErrorRate: 0.1},
+		{Name: "chaos", Duration: 120.5, MaxErrorRate: 5, MaxP99Latency: 250, MinThroughput: 1},
+		{Name: "recovery", Duration: 30, MaxErrorRate: 100},
+	}, phases)
+
+	for _, invalid := range []string{"", ";", "steady", "steady:0", "steady:x", "a b:10", "steady:10;steady:20",
+		"steady:10:max_error_rate", "steady:10:max_error_rate=101", "steady:10:max_p99_latency=-1", "steady:10:max_jitter=5"} {
+		_, err := ParsePhases(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestParseWarnThresholds(t *testing.T) {
+	thresholds, err := ParseWarnThresholds("max_error_rate=1, max_p99_latency=100")
+	require.NoError(t, err)
+	assert.Equal(t, WarnThresholds{MaxErrorRate: 1, MaxP99Latency: 100}, thresholds)
+
+	thresholds, err = ParseWarnThresholds("min_throughput=2.5")
+	require.NoError(t, err)
+	assert.Equal(t, WarnThresholds{MaxErrorRate: 100, MinThroughput: 2.5}, thresholds)
+
+	for _, invalid := range []string{"", ",", "max_error_rate", "max_error_rate=101", "max_p99_latency=-1", "max_jitter=5", "max_error_rate=100"} {
+		_, err := ParseWarnThresholds(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestParseBitrate(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+		wantErr  bool
+	}{
+		{"800", 800, false},
+		{"500k", 500e3, false},
+		{"64K", 64e3, false},
+		{" 10M ", 10e6, false},
+		{"1.5G", 1.5e9, false},
+		{"", 0, true},
+		{"0", 0, true},
+		{"-1M", 0, true},
+		{"10 Mbps", 0, true},
+		{"M", 0, true},
+		{"Inf", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			bitrate, err := ParseBitrate(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, bitrate)
+			}
+		})
+	}
+}
+
+func TestFormatBitrate(t *testing.T) {
+	assert.Equal(t, "1.5 Gbit/s", FormatBitrate(1.5e9))
+	assert.Equal(t, "10 Mbit/s", FormatBitrate(10e6))
+	assert.Equal(t, "64 kbit/s", FormatBitrate(64e3))
+	assert.Equal(t, "800 bit/s", FormatBitrate(800))
+}
+
+func TestParseGeneratorRates(t *testing.T) {
+	rates, err := ParseGeneratorRates("8090=100M, 8091=500k")
+	require.NoError(t, err)
+	assert.Equal(t, map[int]float64{8090: 100e6, 8091: 500e3}, rates)
+
+	rates, err = ParseGeneratorRates("")
+	require.NoError(t, err)
+	assert.Empty(t, rates)
+
+	for _, input := range []string{"8090", "8090=0", "8090=fast", "0=1M"} {
+		_, err := ParseGeneratorRates(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestParsePauseWindows(t *testing.T) {
+	windows, err := ParsePauseWindows("02:00-02:15, 23:50-0:10")
+	require.NoError(t, err)
+	assert.Equal(t, []PauseWindow{
+		{Start: 2 * time.Hour, End: 2*time.Hour + 15*time.Minute},
+		{Start: 23*time.Hour + 50*time.Minute, End: 10 * time.Minute},
+	}, windows)
+	assert.Equal(t, "23:50-00:10", windows[1].String())
+
+	for _, invalid := range []string{"", ",", "02:00", "02:00-02:00", "24:00-01:00", "02:00-02:60", "2am-3am"} {
+		_, err := ParsePauseWindows(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestParseSteps(t *testing.T) {
+	steps, err := ParseSteps("100:2m; 500:90.5:peak;1000:30s")
+	require.NoError(t, err)
+	assert.Equal(t, []Step{
+		{Name: "step1", Rate: 100, Duration: 120},
+		{Name: "peak", Rate: 500, Duration: 90.5},
+		{Name: "step3", Rate: 1000, Duration: 30},
+	}, steps)
+
+	for _, invalid := range []string{"", ";", "100", "0:60", "-1:60", "x:60", "100:0", "100:x", "100:60:a b",
+		"100:60:peak;200:60:peak", "100:60;200:60:step1"} {
+		_, err := ParseSteps(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestParseFlowLabel(t *testing.T) {
+	tests := []struct {
+		input      string
+		expected   uint32
+		wantRandom bool
+		wantErr    bool
+	}{
+		{"1", 1, false, false},
+		{"0x12345", 0x12345, false, false},
+		{"0xFFFFF", 0xfffff, false, false},
+		{" Random ", 0, true, false},
+		{"0", 0, false, true},
+		{"0x100000", 0, false, true},
+		{"-1", 0, false, true},
+		{"ecmp", 0, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			label, random, err := ParseFlowLabel(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, label)
+				assert.Equal(t, tt.wantRandom, random)
+			}
+		})
+	}
+}
+
+func TestLoadClientConfig(t *testing.T) {
+	// Reset viper and pflags for clean test
+	viper.Reset()
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+
+	// Set environment variable with prefix
+	_ = os.Setenv("FLOW_GENERATOR_LOG_LEVEL", "debug")
+	defer func() { _ = os.Unsetenv("FLOW_GENERATOR_LOG_LEVEL") }()
+
+	config, err := LoadClientConfig()
+	require.NoError(t, err)
+	assert.NotNil(t, config)
+	assert.Equal(t, "debug", config.LogLevel)
+	assert.Equal(t, "localhost", config.Server) // default value
+	assert.Equal(t, "default", config.Scenario) // default value
+}
+
+func TestLoadServerConfig(t *testing.T) {
+	// Reset viper and pflags for clean test
+	viper.Reset()
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+
+	// Set environment variable with prefix
+	_ = os.Setenv("FLOW_GENERATOR_METRICS_PORT", "9999")
+	defer func() { _ = os.Unsetenv("FLOW_GENERATOR_METRICS_PORT") }()
+
+	config, err := LoadServerConfig()
+	require.NoError(t, err)
+	assert.NotNil(t, config)
+	assert.Equal(t, "9999", config.MetricsPort)
+	assert.Equal(t, "8080", config.TCPPortsServer) // default value
+	assert.True(t, config.TCPNoDelay)              // default value
+}
+
+func TestLoadAgentServerConfig(t *testing.T) {
+	viper.Reset()
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+
+	_ = os.Setenv("FLOW_GENERATOR_ROLE", "agent")
+	_ = os.Setenv("FLOW_GENERATOR_UDP_PORTS_SERVER", "5353")
+	defer func() {
+		_ = os.Unsetenv("FLOW_GENERATOR_ROLE")
+		_ = os.Unsetenv("FLOW_GENERATOR_UDP_PORTS_SERVER")
+	}()
+
+	client, err := LoadClientConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "agent", client.Role)
+
+	server, err := LoadAgentServerConfig(client)
+	require.NoError(t, err)
+	assert.Equal(t, "5353", server.UDPPortsServer)
+	assert.Equal(t, "8082", server.HealthPort) // default value
+	// The agent serves the metrics of both roles on the client's metrics port
+	assert.Equal(t, "9091", server.MetricsPort)
+	assert.Equal(t, client.CommonConfig, server.CommonConfig)
+}
+
+func TestEffectiveSettings(t *testing.T) {
+	viper.Reset()
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	pflag.CommandLine.Float64("rate", 0, "")
+	pflag.CommandLine.String("server", "", "")
+	require.NoError(t, pflag.CommandLine.Parse([]string{"--rate", "42"}))
+
+	_ = os.Setenv("FLOW_GENERATOR_PROTOCOL", "udp")
+	defer func() { _ = os.Unsetenv("FLOW_GENERATOR_PROTOCOL") }()
+
+	_, err := LoadClientConfig()
+	require.NoError(t, err)
+
+	settings := make(map[string]Setting)
+	for _, s := range EffectiveSettings() {
+		settings[s.Key] = s
+	}
+	assert.Equal(t, Setting{Key: "rate", Value: "42", Source: SourceFlag}, settings["rate"])
+	assert.Equal(t, Setting{Key: "protocol", Value: "udp", Source: SourceEnv}, settings["protocol"])
+	assert.Equal(t, Setting{Key: "server", Value: "localhost", Source: SourceDefault}, settings["server"])
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteEffectiveSettings(&buf))
+	assert.Contains(t, buf.String(), "KEY")
+	assert.Regexp(t, `rate\s+"42"\s+flag`, buf.String())
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		name  string
+		slice []string
+		val   string
+		want  bool
+	}{
+		{
+			name:  "value exists",
+			slice: []string{"a", "b", "c"},
+			val:   "b",
+			want:  true,
+		},
+		{
+			name:  "value doesn't exist",
+			slice: []string{"a", "b", "c"},
+			val:   "d",
+			want:  false,
+		},
+		{
+			name:  "empty slice",
+			slice: []string{},
+			val:   "a",
+			want:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := contains(tt.slice, tt.val)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseMetadata(t *testing.T) {
+	metadata, err := ParseMetadata("owner=netops, ticket = NET-1234 ,empty=,note=a=b")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"owner": "netops", "ticket": "NET-1234", "empty": "", "note": "a=b"}, metadata)
+
+	metadata, err = ParseMetadata("")
+	require.NoError(t, err)
+	assert.Empty(t, metadata)
+
+	_, err = ParseMetadata("owner=" + strings.Repeat("x", 129))
+	assert.ErrorContains(t, err, "exceeds 128 characters")
+
+	var many []string
+	for i := range 17 {
+		many = append(many, fmt.Sprintf("key%d=v", i))
+	}
+	_, err = ParseMetadata(strings.Join(many, ","))
+	assert.ErrorContains(t, err, "at most 16 metadata entries")
+}
+
+func TestArchiveFormat(t *testing.T) {
+	tests := map[string]string{
+		"run.tgz":          ArchiveTarGz,
+		"out/run.tar.gz":   ArchiveTarGz,
+		"RUN.ZIP":          ArchiveZip,
+		"run.tar":          "",
+		"run.gz":           "",
+		"":                 "",
+		"bundle.zip.extra": "",
+	}
+	for path, want := range tests {
+		assert.Equal(t, want, ArchiveFormat(path), path)
 	}
 }
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// profileExtensions are the file extensions of profiles, in lookup order
+var profileExtensions = []string{".yaml", ".yml"}
+
+// profileKeys are the keys a profile cannot set, as they select the profiles themselves
+var profileKeys = []string{"extends", "profile", "profile_dir"}
+
+// profileSettings maps the configuration keys set by the applied profiles to the profile that set them
+var profileSettings map[string]string
+
+// builtinProfiles are the presets that are available without a profile directory. A profile of the same
+// name in the profile directory takes precedence.
+var builtinProfiles = map[string]Profile{
+	// conntrack maximizes the distinct conntrack entries per second: every flow leaves from its own source
+	// port to a random port of a large range and ends after a fraction of a second
+	"conntrack": {Name: "conntrack", Settings: map[string]any{
+		"protocol":          "both",
+		"tcp_ports":         "10000-10999",
+		"udp_ports":         "10000-10999",
+		"source_port_range": "20000-59999",
+		"min_duration":      0.05,
+		"max_duration":      0.2,
+		"rate":              1000.0,
+		"max_concurrent":    1000,
+		"track_tuples":      true,
+	}},
+}
+
+// Profile is a named preset of configuration settings loaded from the profile directory or built in
+type Profile struct {
+	Name string
+	// Extends lists the profiles this one builds on, their settings are applied first
+	Extends  []string
+	Settings map[string]any
+}
+
+// ResolveProfiles loads the named profiles from dir together with all profiles they extend, and returns them
+// in the order their settings are applied: every profile after the profiles it extends, and the named
+// profiles from left to right. A profile extended several times is only applied once, where it first appears.
+func ResolveProfiles(dir string, names []string) ([]Profile, error) {
+	r := &profileResolver{dir: dir, resolved: make(map[string]bool)}
+	for _, name := range names {
+		if err := r.resolve(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return r.chain, nil
+}
+
+// profileResolver walks the extends graph of the profiles depth-first
+type profileResolver struct {
+	dir      string
+	resolved map[string]bool
+	chain    []Profile
+}
+
+// resolve appends the profile and the profiles it extends to the chain. path holds the profiles currently
+// being resolved, to detect cycles.
+func (r *profileResolver) resolve(name string, path []string) error {
+	if r.resolved[name] {
+		return nil
+	}
+	if slices.Contains(path, name) {
+		return fmt.Errorf("profile %s extends itself: %s", name, strings.Join(append(path, name), " -> "))
+	}
+	p, err := loadProfile(r.dir, name)
+	if err != nil {
+		return err
+	}
+	path = append(path, name)
+	for _, parent := range p.Extends {
+		if err := r.resolve(parent, path); err != nil {
+			return err
+		}
+	}
+	r.resolved[name] = true
+	r.chain = append(r.chain, p)
+	return nil
+}
+
+// loadProfile reads a single profile from dir, falling back to the built-in profiles
+func loadProfile(dir, name string) (Profile, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return Profile{}, fmt.Errorf("invalid profile name %q", name)
+	}
+	for _, ext := range profileExtensions {
+		if dir == "" {
+			break
+		}
+		path := filepath.Join(dir, name+ext)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		v := viper.New()
+		v.SetConfigFile(path)
+		if err := v.ReadInConfig(); err != nil {
+			return Profile{}, fmt.Errorf("failed to read profile %s: %w", name, err)
+		}
+		p := Profile{Name: name, Extends: v.GetStringSlice("extends"), Settings: make(map[string]any)}
+		for _, key := range v.AllKeys() {
+			if key == "extends" {
+				continue
+			}
+			if slices.Contains(profileKeys, key) {
+				return Profile{}, fmt.Errorf("profile %s cannot set %s", name, key)
+			}
+			p.Settings[key] = v.Get(key)
+		}
+		return p, nil
+	}
+	if p, ok := builtinProfiles[name]; ok {
+		return p, nil
+	}
+	return Profile{}, fmt.Errorf("profile %s not found in %s", name, dir)
+}
+
+// applyProfiles applies the settings of the profiles selected with profile and profile_dir. They take
+// precedence over the defaults only, so the config file, environment and flags still override them.
+func applyProfiles() error {
+	profileSettings = nil
+	var names []string
+	for _, name := range strings.Split(viper.GetString("profile"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	dir := viper.GetString("profile_dir")
+	for _, name := range names {
+		if _, ok := builtinProfiles[name]; !ok && dir == "" {
+			return fmt.Errorf("profile requires profile_dir")
+		}
+	}
+
+	chain, err := ResolveProfiles(dir, names)
+	if err != nil {
+		return err
+	}
+	settings := make(map[string]string)
+	for _, p := range chain {
+		for key, value := range p.Settings {
+			viper.SetDefault(key, value)
+			settings[key] = p.Name
+		}
+	}
+	profileSettings = settings
+	return nil
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeProfiles writes each profile to a file named after it in a new directory
+func writeProfiles(t *testing.T, profiles map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range profiles {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	return dir
+}
+
+// profileNames returns the names of the profiles in order
+func profileNames(chain []Profile) []string {
+	names := make([]string, 0, len(chain))
+	for _, p := range chain {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func TestResolveProfiles(t *testing.T) {
+	dir := writeProfiles(t, map[string]string{
+		"base.yaml":    "rate: 5\nprotocol: tcp\n",
+		"staging.yaml": "extends: base\nserver: staging.local\n",
+		"tls.yml":      "extends: base\ntcp_ports: \"443\"\n",
+		"smoke.yaml":   "extends: [staging, tls]\nflow_count: 10\n",
+		"loop-a.yaml":  "extends: loop-b\n",
+		"loop-b.yaml":  "extends: loop-a\n",
+		"nested.yaml":  "extends: profile\nprofile: base\n",
+	})
+
+	tests := []struct {
+		name    string
+		names   []string
+		want    []string
+		wantErr string
+	}{
+		{name: "single profile", names: []string{"base"}, want: []string{"base"}},
+		{name: "inheritance", names: []string{"staging"}, want: []string{"base", "staging"}},
+		{name: "shared parent applied once", names: []string{"smoke"}, want: []string{"base", "staging", "tls", "smoke"}},
+		{name: "several profiles", names: []string{"tls", "staging"}, want: []string{"base", "tls", "staging"}},
+		{name: "missing profile", names: []string{"prod"}, wantErr: "profile prod not found"},
+		{name: "cycle", names: []string{"loop-a"}, wantErr: "loop-a -> loop-b -> loop-a"},
+		{name: "invalid name", names: []string{"../base"}, wantErr: "invalid profile name"},
+		{name: "profile selecting profiles", names: []string{"nested"}, wantErr: "profile nested cannot set profile"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain, err := ResolveProfiles(dir, tt.names)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, profileNames(chain))
+		})
+	}
+}
+
+func TestLoadClientConfigProfiles(t *testing.T) {
+	viper.Reset()
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	pflag.CommandLine.String("server", "", "")
+	require.NoError(t, pflag.CommandLine.Parse([]string{"--server", "cli.local"}))
+
+	dir := writeProfiles(t, map[string]string{
+		"base.yaml":    "rate: 5\nprotocol: tcp\nmax_concurrent: 50\n",
+		"staging.yaml": "extends: base\nserver: staging.local\nrate: 20\n",
+	})
+	t.Setenv("FLOW_GENERATOR_PROFILE_DIR", dir)
+	t.Setenv("FLOW_GENERATOR_PROFILE", "staging")
+	t.Setenv("FLOW_GENERATOR_MAX_CONCURRENT", "75")
+
+	config, err := LoadClientConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "staging", config.Profile)
+	assert.Equal(t, 20.0, config.Rate)          // staging overrides base
+	assert.Equal(t, "tcp", config.Protocol)     // inherited from base
+	assert.Equal(t, 75, config.MaxConcurrent)   // the environment overrides profiles
+	assert.Equal(t, "cli.local", config.Server) // flags override profiles
+	assert.Equal(t, "8080", config.TCPPorts)    // default value
+	assert.Equal(t, "default", config.Scenario) // default value
+
+	settings := make(map[string]Setting)
+	for _, s := range EffectiveSettings() {
+		settings[s.Key] = s
+	}
+	assert.Equal(t, SourceProfile, settings["rate"].Source)
+	assert.Equal(t, SourceEnv, settings["max_concurrent"].Source)
+	assert.Equal(t, SourceFlag, settings["server"].Source)
+	assert.Equal(t, SourceDefault, settings["tcp_ports"].Source)
+}
+
+func TestLoadServerConfigProfiles(t *testing.T) {
+	viper.Reset()
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+
+	dir := writeProfiles(t, map[string]string{"lab.yaml": "udp_ports_server: \"5353\"\n"})
+	t.Setenv("FLOW_GENERATOR_PROFILE_DIR", dir)
+	t.Setenv("FLOW_GENERATOR_PROFILE", "lab")
+
+	config, err := LoadServerConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "5353", config.UDPPortsServer)
+}
+
+func TestLoadClientConfigProfileErrors(t *testing.T) {
+	viper.Reset()
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	t.Setenv("FLOW_GENERATOR_PROFILE", "staging")
+
+	_, err := LoadClientConfig()
+	assert.ErrorContains(t, err, "profile requires profile_dir")
+
+	t.Setenv("FLOW_GENERATOR_PROFILE_DIR", t.TempDir())
+	_, err = LoadClientConfig()
+	assert.ErrorContains(t, err, "profile staging not found")
+}
+
+func TestLoadClientConfigBuiltinProfile(t *testing.T) {
+	viper.Reset()
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	t.Setenv("FLOW_GENERATOR_PROFILE", "conntrack")
+	t.Setenv("FLOW_GENERATOR_RATE", "200")
+
+	// The built-in profile needs no profile directory
+	config, err := LoadClientConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "both", config.Protocol)
+	assert.Equal(t, "10000-10999", config.TCPPorts)
+	assert.Equal(t, "20000-59999", config.SourcePortRange)
+	assert.Equal(t, 0.2, config.MaxDuration)
+	assert.True(t, config.TrackTuples)
+	assert.Equal(t, 200.0, config.Rate) // the environment overrides profiles
+
+	// A profile of the same name in the profile directory takes precedence over the built-in one
+	viper.Reset()
+	dir := writeProfiles(t, map[string]string{
+		"conntrack.yaml": "rate: 50\n",
+		"soak.yaml":      "extends: conntrack\nmax_duration: 1\n",
+	})
+	t.Setenv("FLOW_GENERATOR_PROFILE_DIR", dir)
+	t.Setenv("FLOW_GENERATOR_PROFILE", "soak")
+	t.Setenv("FLOW_GENERATOR_RATE", "")
+	config, err = LoadClientConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, config.Rate)
+	assert.Equal(t, "8080", config.TCPPorts)
+	assert.Empty(t, config.SourcePortRange)
+}
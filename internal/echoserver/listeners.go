@@ -0,0 +1,152 @@
+package echoserver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/handlers"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/server"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/service"
+)
+
+// ListenerKey identifies a listener by server type ("TCP", "TLS" or "UDP") and port
+type ListenerKey struct {
+	ServerType string
+	Port       int
+}
+
+// ListenerBuilder creates the server for a listener following the given service mode
+type ListenerBuilder func(key ListenerKey, mode handlers.ServiceMode) server.Server
+
+// ParsePorts parses a comma-separated string of ports and port ranges (e.g. "9000-9099") into a slice of integers
+func ParsePorts(portsStr string) []int {
+	if portsStr == "" {
+		return []int{}
+	}
+	var ports []int
+	for _, p := range strings.Split(portsStr, ",") {
+		p = strings.TrimSpace(p)
+		first, last, err := config.ParsePortRange(p)
+		if err != nil {
+			logging.Logger.Warnf("Invalid port '%s' ignored", p)
+			continue
+		}
+		for port := first; port <= last; port++ {
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
+// ListenerModes returns the listeners in the configuration and the service mode each one follows
+func ListenerModes(cfg *config.ServerConfig) map[ListenerKey]handlers.ServiceMode {
+	// The format has already been validated as part of the configuration
+	serviceModes, _ := config.ParsePortMap(cfg.ServiceModes)
+	modeFor := func(port int) handlers.ServiceMode {
+		if mode, ok := serviceModes[port]; ok {
+			return handlers.ServiceMode(mode)
+		}
+		return handlers.ModeEcho
+	}
+
+	listeners := make(map[ListenerKey]handlers.ServiceMode)
+	for _, port := range ParsePorts(cfg.TCPPortsServer) {
+		listeners[ListenerKey{"TCP", port}] = modeFor(port)
+	}
+	for _, port := range ParsePorts(cfg.RelayPortsServer) {
+		listeners[ListenerKey{"TCP", port}] = handlers.ModeRelay
+	}
+	for _, port := range ParsePorts(cfg.TLSPortsServer) {
+		listeners[ListenerKey{"TLS", port}] = modeFor(port)
+	}
+	for _, port := range ParsePorts(cfg.UDPPortsServer) {
+		mode := modeFor(port)
+		if mode == handlers.ModeHTTP {
+			// HTTP is only served over TCP, UDP ports of the same number echo
+			mode = handlers.ModeEcho
+		}
+		listeners[ListenerKey{"UDP", port}] = mode
+	}
+	// Custom services take over their ports for every protocol they support
+	handlerPorts, _ := config.ParsePortMap(cfg.HandlerPorts)
+	for port, name := range handlerPorts {
+		svc, ok := service.Lookup(name)
+		if !ok {
+			logging.Logger.Warnf("Port %d ignored, no service registered for %q (available: %v)", port, name, service.Registered())
+			continue
+		}
+		if svc.TCP != nil {
+			listeners[ListenerKey{"TCP", port}] = handlers.ServiceMode(name)
+		}
+		if svc.UDP != nil {
+			listeners[ListenerKey{"UDP", port}] = handlers.ServiceMode(name)
+		}
+	}
+	return listeners
+}
+
+// CheckHandlerPorts reports the ports mapped with handler_ports to a service that is not registered. The
+// configuration cannot check them when it is loaded, as services are registered by the program.
+func CheckHandlerPorts(cfg *config.ServerConfig) error {
+	handlerPorts, _ := config.ParsePortMap(cfg.HandlerPorts)
+	var unknown []string
+	for port, name := range handlerPorts {
+		if _, ok := service.Lookup(name); !ok {
+			unknown = append(unknown, fmt.Sprintf("%d=%s", port, name))
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("no service registered for handler_ports %s (available: %v)", strings.Join(unknown, ","), service.Registered())
+	}
+	return nil
+}
+
+// ReconcileListeners stops listeners that are gone or changed mode and starts new or changed ones.
+// It returns the listeners that are in place afterwards.
+func ReconcileListeners(manager *server.Manager, current, desired map[ListenerKey]handlers.ServiceMode, build ListenerBuilder) map[ListenerKey]handlers.ServiceMode {
+	result := make(map[ListenerKey]handlers.ServiceMode, len(desired))
+
+	for _, key := range SortedListenerKeys(current) {
+		if mode, ok := desired[key]; ok && mode == current[key] {
+			result[key] = mode
+			continue
+		}
+		if err := manager.StopServer(key.ServerType, key.Port); err != nil {
+			logging.Logger.Errorf("Failed to remove listener: %v", err)
+			result[key] = current[key]
+			continue
+		}
+		logging.Logger.Infof("Removed %s listener on port %d", key.ServerType, key.Port)
+	}
+
+	for _, key := range SortedListenerKeys(desired) {
+		if _, ok := result[key]; ok {
+			continue
+		}
+		if err := manager.StartServer(build(key, desired[key])); err != nil {
+			logging.Logger.Errorf("Failed to add listener: %v", err)
+			continue
+		}
+		result[key] = desired[key]
+	}
+	return result
+}
+
+// SortedListenerKeys returns the keys of a listener map ordered by server type and port
+func SortedListenerKeys(listeners map[ListenerKey]handlers.ServiceMode) []ListenerKey {
+	keys := make([]ListenerKey, 0, len(listeners))
+	for key := range listeners {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].ServerType != keys[j].ServerType {
+			return keys[i].ServerType < keys[j].ServerType
+		}
+		return keys[i].Port < keys[j].Port
+	})
+	return keys
+}
@@ -0,0 +1,138 @@
+package echoserver
+
+import (
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/handlers"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/internal/server"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePorts(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []int
+	}{
+		{"single port", "8080", []int{8080}},
+		{"multiple ports", "8080,8081,8082", []int{8080, 8081, 8082}},
+		{"with spaces", " 8080 , 8081 , 8082 ", []int{8080, 8081, 8082}},
+		{"empty string", "", []int{}},
+		{"invalid port", "8080,invalid,8081", []int{8080, 8081}},
+		{"out of range port", "8080,70000,8081", []int{8080, 8081}},
+		{"negative port", "8080,-1,8081", []int{8080, 8081}},
+		{"duplicate ports", "8080,8080,8081", []int{8080, 8080, 8081}},
+		{"port range", "8080,9000-9002", []int{8080, 9000, 9001, 9002}},
+		{"reversed range", "9002-9000,8080", []int{8080}},
+		{"range out of range", "65534-65536,8081", []int{8081}},
+	}
+
+	// Capture log output
+	oldLogger := logging.Logger
+	logging.InitLogger("json", "error")
+	defer func() { logging.Logger = oldLogger }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ParsePorts(tt.input)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+// fakeServer records whether it was started and stopped
+type fakeServer struct {
+	key     ListenerKey
+	started bool
+	stopped bool
+}
+
+func (f *fakeServer) Start() error { f.started = true; return nil }
+func (f *fakeServer) Stop() error  { f.stopped = true; return nil }
+func (f *fakeServer) Port() int    { return f.key.Port }
+func (f *fakeServer) Type() string { return f.key.ServerType }
+
+func TestListenerModes(t *testing.T) {
+	logging.InitLogger("json", "error")
+	cfg := &config.ServerConfig{
+		TCPPortsServer:   "7,8080,8000",
+		UDPPortsServer:   "7,8000",
+		RelayPortsServer: "9999",
+		ServiceModes:     "7=discard,8000=http",
+	}
+
+	// UDP ports of HTTP mode echo
+	assert.Equal(t, map[ListenerKey]handlers.ServiceMode{
+		{"TCP", 7}:    handlers.ModeDiscard,
+		{"TCP", 8000}: handlers.ModeHTTP,
+		{"TCP", 8080}: handlers.ModeEcho,
+		{"TCP", 9999}: handlers.ModeRelay,
+		{"UDP", 7}:    handlers.ModeDiscard,
+		{"UDP", 8000}: handlers.ModeEcho,
+	}, ListenerModes(cfg))
+}
+
+func TestListenerModesCustomServices(t *testing.T) {
+	logging.InitLogger("json", "error")
+	service.Register("mock-kafka", service.Service{
+		TCP: func(m service.Metrics) service.ConnHandler {
+			return handlers.NewTCPHandler(m.(*metrics.MetricsCollector))
+		},
+	})
+
+	cfg := &config.ServerConfig{
+		TCPPortsServer: "8080,9092",
+		HandlerPorts:   "9092=mock-kafka,9093=unknown",
+	}
+	assert.Equal(t, map[ListenerKey]handlers.ServiceMode{
+		{"TCP", 8080}: handlers.ModeEcho,
+		{"TCP", 9092}: "mock-kafka",
+	}, ListenerModes(cfg))
+
+	assert.EqualError(t, CheckHandlerPorts(cfg), `no service registered for handler_ports 9093=unknown (available: [mock-kafka])`)
+	assert.NoError(t, CheckHandlerPorts(&config.ServerConfig{HandlerPorts: "9092=mock-kafka"}))
+}
+
+func TestReconcileListeners(t *testing.T) {
+	logging.InitLogger("json", "error")
+	manager := server.NewManager()
+	built := make(map[ListenerKey]*fakeServer)
+	build := func(key ListenerKey, mode handlers.ServiceMode) server.Server {
+		built[key] = &fakeServer{key: key}
+		return built[key]
+	}
+
+	listeners := ReconcileListeners(manager, nil, map[ListenerKey]handlers.ServiceMode{
+		{"TCP", 8080}: handlers.ModeEcho,
+		{"UDP", 9000}: handlers.ModeEcho,
+		{"TCP", 19}:   handlers.ModeChargen,
+	}, build)
+	require.NoError(t, manager.Start())
+	defer func() { _ = manager.Stop() }()
+	assert.Len(t, listeners, 3)
+	assert.Equal(t, 3, manager.ServerCount())
+
+	// Drop UDP 9000, keep TCP 8080, switch TCP 19 to discard and add TCP 8081
+	original := built
+	built = make(map[ListenerKey]*fakeServer)
+	listeners = ReconcileListeners(manager, listeners, map[ListenerKey]handlers.ServiceMode{
+		{"TCP", 8080}: handlers.ModeEcho,
+		{"TCP", 8081}: handlers.ModeEcho,
+		{"TCP", 19}:   handlers.ModeDiscard,
+	}, build)
+
+	assert.Len(t, listeners, 3)
+	assert.Equal(t, 3, manager.ServerCount())
+	assert.True(t, original[ListenerKey{"UDP", 9000}].stopped)
+	assert.True(t, original[ListenerKey{"TCP", 19}].stopped)
+	assert.False(t, original[ListenerKey{"TCP", 8080}].stopped)
+	assert.True(t, built[ListenerKey{"TCP", 8081}].started)
+	assert.True(t, built[ListenerKey{"TCP", 19}].started)
+	assert.NotContains(t, built, ListenerKey{"TCP", 8080})
+	assert.Equal(t, handlers.ModeDiscard, listeners[ListenerKey{"TCP", 19}])
+}
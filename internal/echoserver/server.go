@@ -0,0 +1,312 @@
+// Package echoserver runs the listeners of a server configuration. It is shared by the echo server and
+// the agent role of the flow generator, which serves peers from the same process that generates flows.
+package echoserver
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/backpressure"
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/handlers"
+	"github.com/PhilipSchmid/flow-generator-app/internal/health"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/internal/server"
+	"github.com/PhilipSchmid/flow-generator-app/internal/webui"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/service"
+)
+
+// Server serves the listeners of a server configuration and keeps them in line with it on reload
+type Server struct {
+	cfg       *config.ServerConfig
+	mc        *metrics.MetricsCollector
+	manager   *server.Manager
+	listeners map[ListenerKey]handlers.ServiceMode
+
+	tcpHandler *handlers.TCPHandler
+	udpHandler *handlers.UDPHandler
+	upstream   *handlers.Upstream
+	duplicates *handlers.DuplicateDetector
+	delays     map[int]config.ResponseDelay
+	drops      map[int]float64
+	sizes      map[int]config.ResponseSize
+	rates      map[int]float64
+	// httpHeaders are the request headers ports in http mode reflect
+	httpHeaders []string
+	// connLimit caps the connections of all TCP listeners, nil if they are not limited
+	connLimit *server.ConnLimit
+	// tls terminates TLS on the TLS listeners
+	tls *server.TLS
+}
+
+// New creates the listeners of the configuration, recording their traffic in mc. They are opened by Start.
+func New(cfg *config.ServerConfig, mc *metrics.MetricsCollector) *Server {
+	s := &Server{
+		cfg:     cfg,
+		mc:      mc,
+		manager: server.NewManager(),
+	}
+	s.tcpHandler = s.newTCPHandler(handlers.ModeEcho)
+	s.udpHandler = s.newUDPHandler(handlers.ModeEcho)
+
+	// Echo requests may be relayed to upstream servers to simulate multi-service topologies
+	if cfg.UpstreamServers != "" {
+		var servers []string
+		for _, srv := range strings.Split(cfg.UpstreamServers, ",") {
+			if srv = strings.TrimSpace(srv); srv != "" {
+				servers = append(servers, srv)
+			}
+		}
+		s.upstream = handlers.NewUpstream(servers, cfg.UpstreamFraction, cfg.UpstreamDepth, time.Duration(cfg.UpstreamTimeout*float64(time.Second)))
+		s.tcpHandler.SetUpstream(s.upstream)
+		s.udpHandler.SetUpstream(s.upstream)
+		logging.Logger.Infof("Relaying %.0f%% of echo requests through %d upstream call(s) to %v", cfg.UpstreamFraction*100, cfg.UpstreamDepth, servers)
+	}
+
+	// Flows carrying a flow header are checked for duplicates on the echo ports
+	if cfg.DuplicateWindow > 0 {
+		s.duplicates = handlers.NewDuplicateDetector(time.Duration(cfg.DuplicateWindow * float64(time.Second)))
+		s.tcpHandler.SetDuplicateDetector(s.duplicates)
+		s.udpHandler.SetDuplicateDetector(s.duplicates)
+	}
+
+	// Responses of single ports may be held back to emulate slow services, dropped to emulate loss or sized
+	// for asymmetric traffic, validated with the configuration
+	s.delays, _ = config.ParseResponseDelays(cfg.ResponseDelays)
+	s.drops, _ = config.ParseResponseDrops(cfg.ResponseDrops)
+	s.sizes, _ = config.ParseResponseSizes(cfg.ResponseSizes)
+	s.rates, _ = config.ParseGeneratorRates(cfg.GeneratorRates)
+	s.httpHeaders, _ = config.ParseHeaderNames(cfg.HTTPEchoHeaders)
+
+	// An aggressive client cannot exhaust the file descriptors of the server with connection limits
+	s.connLimit = server.NewConnLimit(mc, "global", cfg.MaxConnections)
+	if cfg.MaxConnections > 0 || cfg.MaxConnectionsPerListener > 0 {
+		logging.Logger.Infof("Limiting TCP connections to %s", DescribeConnLimits(cfg))
+	}
+	// The certificates have been loaded when the configuration was validated
+	certs, _ := config.LoadTLSCertificates(cfg.TLSCertificates)
+	s.tls = server.NewTLS(mc, certs)
+	if cfg.TLSPortsServer != "" {
+		logging.Logger.Infof("TLS ports serve %s", DescribeTLS(cfg))
+	}
+	if cfg.AcceptRate > 0 {
+		logging.Logger.Infof("Accepting at most %g TCP connections per second on each listener", cfg.AcceptRate)
+	}
+	if cfg.ZeroCopyEcho {
+		if runtime.GOOS == "linux" {
+			logging.Logger.Infof("Echoing TCP connections with splice where responses are sent back unchanged")
+		} else {
+			logging.Logger.Warnf("Zero-copy echo requires Linux, TCP connections are echoed by copying")
+		}
+	}
+
+	s.listeners = ReconcileListeners(s.manager, nil, ListenerModes(cfg), s.build)
+	return s
+}
+
+// build creates the server of a listener. Ports with an explicit service mode, a response delay, drops or a
+// response size get a dedicated handler, all others share the echo handlers.
+func (s *Server) build(key ListenerKey, mode handlers.ServiceMode) server.Server {
+	if svc, ok := service.Lookup(string(mode)); ok {
+		logging.Logger.Infof("%s port %d serves custom service %s", key.ServerType, key.Port, mode)
+		if key.ServerType == "UDP" {
+			return s.udpServer(key.Port, svc.UDP(s.mc))
+		}
+		return s.tcpServer(key, svc.TCP(s.mc))
+	}
+	// Only echo responses are delayed, dropped or sized, the other service modes keep their own behavior
+	delay, delayed := s.delays[key.Port]
+	drop := s.drops[key.Port]
+	size, sized := s.sizes[key.Port]
+	custom := delayed || drop > 0 || sized
+	if mode == handlers.ModeEcho && delayed {
+		logging.Logger.Infof("%s port %d delays responses by %s", key.ServerType, key.Port, delay)
+	}
+	if mode == handlers.ModeEcho && drop > 0 {
+		logging.Logger.Infof("%s port %d drops %g%% of responses", key.ServerType, key.Port, drop)
+	}
+	if mode == handlers.ModeEcho && sized {
+		logging.Logger.Infof("%s port %d sizes responses as %s", key.ServerType, key.Port, size)
+	}
+	if key.ServerType == "UDP" {
+		handler := s.udpHandler
+		switch {
+		case mode != handlers.ModeEcho:
+			handler = s.newUDPHandler(mode)
+			handler.SetUpstream(s.upstream)
+			logging.Logger.Infof("UDP port %d uses %s service mode", key.Port, mode)
+		case custom:
+			handler = s.newUDPHandler(mode)
+			handler.SetUpstream(s.upstream)
+			handler.SetDuplicateDetector(s.duplicates)
+			handler.SetResponseDelay(delay.Base, delay.Jitter)
+			handler.SetResponseDrop(drop)
+			if sized {
+				handler.SetResponseSize(size.Of)
+			}
+		}
+		if s.cfg.UDPConnectedPeers {
+			handler.EnableConnectedPeers(time.Duration(s.cfg.UDPPeerIdleTimeout * float64(time.Second)))
+		}
+		return s.udpServer(key.Port, handler)
+	}
+
+	handler := s.tcpHandler
+	switch mode {
+	case handlers.ModeEcho:
+		if custom {
+			handler = s.newTCPHandler(mode)
+			handler.SetUpstream(s.upstream)
+			handler.SetDuplicateDetector(s.duplicates)
+			handler.SetResponseDelay(delay.Base, delay.Jitter)
+			handler.SetResponseDrop(drop)
+			if sized {
+				handler.SetResponseSize(size.Of)
+			}
+		}
+	case handlers.ModeRelay:
+		handler = s.newTCPHandler(mode)
+		logging.Logger.Infof("TCP port %d relays flows to their next hop", key.Port)
+	case handlers.ModeHTTP:
+		handler = s.newTCPHandler(mode)
+		handler.SetEchoHeaders(s.httpHeaders)
+		logging.Logger.Infof("%s port %d echoes HTTP requests", key.ServerType, key.Port)
+	case handlers.ModeGenerator:
+		handler = s.newTCPHandler(mode)
+		if rate, ok := s.rates[key.Port]; ok {
+			handler.SetStreamRate(rate)
+			logging.Logger.Infof("TCP port %d streams to every client at %s", key.Port, config.FormatBitrate(rate))
+		} else {
+			logging.Logger.Infof("TCP port %d streams to every client as fast as it reads", key.Port)
+		}
+	default:
+		handler = s.newTCPHandler(mode)
+		handler.SetUpstream(s.upstream)
+		logging.Logger.Infof("TCP port %d uses %s service mode", key.Port, mode)
+	}
+	return s.tcpServer(key, handler)
+}
+
+// newTCPHandler creates a TCP handler of a service mode with the read buffer size and zero-copy echo of the
+// configuration
+func (s *Server) newTCPHandler(mode handlers.ServiceMode) *handlers.TCPHandler {
+	handler := handlers.NewTCPServiceHandler(s.mc, mode)
+	handler.SetReadBufferSize(s.cfg.TCPReadBuffer)
+	handler.SetZeroCopy(s.cfg.ZeroCopyEcho)
+	return handler
+}
+
+// newUDPHandler creates a UDP handler of a service mode with the read buffer size of the configuration
+func (s *Server) newUDPHandler(mode handlers.ServiceMode) *handlers.UDPHandler {
+	handler := handlers.NewUDPServiceHandler(s.mc, mode)
+	handler.SetReadBufferSize(s.cfg.UDPReadBuffer)
+	return handler
+}
+
+// DescribeTLS describes the certificates TLS listeners of the configuration serve
+func DescribeTLS(cfg *config.ServerConfig) string {
+	if cfg.TLSCertificates == "" {
+		return "self-signed certificates generated for the SNI of every client"
+	}
+	return fmt.Sprintf("%d certificate(s) chosen by the SNI of the client", len(strings.Split(cfg.TLSCertificates, ",")))
+}
+
+// DescribeConnLimits describes the connection limits of the configuration and what happens to connections
+// over them, e.g. "1000 in total and 200 per listener, rejecting connections over a limit"
+func DescribeConnLimits(cfg *config.ServerConfig) string {
+	var parts []string
+	if cfg.MaxConnections > 0 {
+		parts = append(parts, fmt.Sprintf("%d in total", cfg.MaxConnections))
+	}
+	if cfg.MaxConnectionsPerListener > 0 {
+		parts = append(parts, fmt.Sprintf("%d per listener", cfg.MaxConnectionsPerListener))
+	}
+	action := "rejecting connections over a limit"
+	if cfg.ConnectionLimitMode == config.ConnectionLimitQueue {
+		action = "queueing connections over a limit in the listen backlog"
+	}
+	return strings.Join(parts, " and ") + ", " + action
+}
+
+// socketOptions returns the socket tuning of the configuration
+func (s *Server) socketOptions() server.SocketOptions {
+	return server.SocketOptions{
+		NoDelay:       s.cfg.TCPNoDelay,
+		SendBuffer:    s.cfg.SocketSendBuffer,
+		ReceiveBuffer: s.cfg.SocketReceiveBuffer,
+		KeepAlive:     s.cfg.KeepAlive(),
+	}
+}
+
+// tcpServer creates a TCP listener with the socket tuning and connection limits of the configuration, which
+// terminates TLS if it is a TLS listener
+func (s *Server) tcpServer(key ListenerKey, handler service.ConnHandler) server.Server {
+	port := key.Port
+	srv := server.NewTCPServer(port, handler)
+	if key.ServerType == "TLS" {
+		srv.SetTLS(s.tls)
+	}
+	srv.SetSocketOptions(s.socketOptions())
+	srv.SetConnLimits(server.ConnLimits{
+		Listener: server.NewConnLimit(s.mc, strconv.Itoa(port), s.cfg.MaxConnectionsPerListener),
+		Global:   s.connLimit,
+		Queue:    s.cfg.ConnectionLimitMode == config.ConnectionLimitQueue,
+	})
+	srv.SetAcceptLimiter(server.NewAcceptLimiter(s.cfg.AcceptRate))
+	srv.SetListenSockets(s.cfg.TCPListenSockets)
+	return srv
+}
+
+// udpServer creates a UDP listener with the socket tuning and workers of the configuration
+func (s *Server) udpServer(port int, handler service.PacketHandler) server.Server {
+	srv := server.NewUDPServer(port, handler)
+	srv.SetSocketOptions(s.socketOptions())
+	srv.SetWorkers(s.cfg.UDPWorkers)
+	return srv
+}
+
+// Start opens all listeners
+func (s *Server) Start() error {
+	return s.manager.Start()
+}
+
+// Reload adds, removes and changes listeners to match the listeners of cfg. Other settings are only
+// applied on restart.
+func (s *Server) Reload(cfg *config.ServerConfig) {
+	s.listeners = ReconcileListeners(s.manager, s.listeners, ListenerModes(cfg), s.build)
+}
+
+// Listeners returns the listeners in place and the service mode each one follows
+func (s *Server) Listeners() map[ListenerKey]handlers.ServiceMode {
+	return s.listeners
+}
+
+// Stop closes all listeners
+func (s *Server) Stop() error {
+	return s.manager.Stop()
+}
+
+// NewHealthChecker creates the health check server for the configured health port, which also serves the
+// live stats endpoint and the web UI. If backpressure thresholds are configured, it samples the server load
+// until ctx is done and also serves the backpressure status to clients. More endpoints can be added before
+// it is started.
+func NewHealthChecker(ctx context.Context, cfg *config.ServerConfig, mc *metrics.MetricsCollector) *health.Checker {
+	healthChecker := health.NewChecker()
+	healthChecker.Handle(StatsPath, &statsHandler{mc: mc, start: time.Now()})
+	healthChecker.Handle(WebUIPath, webui.Handler(webUIPage))
+	if cfg.BackpressureMaxConnections > 0 || cfg.BackpressureMaxPPS > 0 {
+		monitor := backpressure.NewMonitor(mc, backpressure.Thresholds{
+			MaxActiveConnections: cfg.BackpressureMaxConnections,
+			MaxPPS:               cfg.BackpressureMaxPPS,
+		})
+		go monitor.Run(ctx, time.Duration(cfg.BackpressureInterval*float64(time.Second)))
+		healthChecker.Handle(backpressure.Path, monitor)
+		logging.Logger.Infof("Backpressure signaling enabled on health port %s%s", cfg.HealthPort, backpressure.Path)
+	}
+	return healthChecker
+}
@@ -0,0 +1,113 @@
+package echoserver
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/backpressure"
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/handlers"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/service"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	logging.InitLogger("json", "error")
+	mc := metrics.NewMetricsCollector()
+	srv := New(&config.ServerConfig{TCPPortsServer: "18190"}, mc)
+	require.NoError(t, srv.Start())
+	defer func() { _ = srv.Stop() }()
+
+	// The listener echoes like the echo server does
+	var conn net.Conn
+	require.Eventually(t, func() bool {
+		var err error
+		conn, err = net.Dial("tcp", "127.0.0.1:18190")
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	_, err := conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+	_ = conn.Close()
+
+	srv.Reload(&config.ServerConfig{TCPPortsServer: "18190", UDPPortsServer: "18191", ServiceModes: "18191=discard"})
+	assert.Equal(t, map[ListenerKey]handlers.ServiceMode{
+		{"TCP", 18190}: handlers.ModeEcho,
+		{"UDP", 18191}: handlers.ModeDiscard,
+	}, srv.Listeners())
+}
+
+// greetService is a custom service that writes a fixed greeting and records it in the metrics
+type greetService struct {
+	m service.Metrics
+}
+
+func (g greetService) Handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	port := strconv.Itoa(conn.LocalAddr().(*net.TCPAddr).Port)
+	g.m.IncRequestsReceived("tcp", port)
+	n, _ := conn.Write([]byte("hello"))
+	g.m.AddBytesSent("tcp", port, n)
+}
+
+func TestServerCustomService(t *testing.T) {
+	logging.InitLogger("json", "error")
+	service.Register("greet", service.Service{TCP: func(m service.Metrics) service.ConnHandler { return greetService{m} }})
+	mc := metrics.NewMetricsCollector()
+	srv := New(&config.ServerConfig{HandlerPorts: "18192=greet"}, mc)
+	require.NoError(t, srv.Start())
+	defer func() { _ = srv.Stop() }()
+	assert.Equal(t, map[ListenerKey]handlers.ServiceMode{{"TCP", 18192}: "greet"}, srv.Listeners())
+
+	var conn net.Conn
+	require.Eventually(t, func() bool {
+		var err error
+		conn, err = net.Dial("tcp", "127.0.0.1:18192")
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	defer func() { _ = conn.Close() }()
+	greeting, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(greeting))
+	assert.Equal(t, float64(5), testutil.ToFloat64(mc.BytesSent.WithLabelValues("tcp", "18192")))
+}
+
+func TestNewHealthChecker(t *testing.T) {
+	logging.InitLogger("json", "error")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := &config.ServerConfig{HealthPort: "18192", BackpressureMaxPPS: 100, BackpressureInterval: 1}
+	checker := NewHealthChecker(ctx, cfg, metrics.NewMetricsCollector())
+	require.NoError(t, checker.Start(cfg.HealthPort))
+	defer func() { _ = checker.Stop() }()
+
+	var resp *http.Response
+	require.Eventually(t, func() bool {
+		var err error
+		resp, err = http.Get("http://127.0.0.1:18192" + backpressure.Path)
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	defer func() { _ = resp.Body.Close() }()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// The stats endpoint and the web UI are always served
+	for _, path := range []string{StatsPath, WebUIPath} {
+		r, err := http.Get("http://127.0.0.1:18192" + path)
+		require.NoError(t, err)
+		_ = r.Body.Close()
+		assert.Equal(t, http.StatusOK, r.StatusCode, path)
+	}
+}
@@ -0,0 +1,101 @@
+package echoserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/internal/webui"
+)
+
+// Paths of the live stats endpoint and the web UI on the health port
+const (
+	StatsPath = "/stats"
+	WebUIPath = "/ui"
+)
+
+// Stats is the snapshot of the server served on the stats endpoint
+type Stats struct {
+	Time          string  `json:"time"`
+	UptimeSeconds float64 `json:"uptime_seconds"`
+	// ActiveTCPConnections is the number of TCP connections open at the time of the snapshot
+	ActiveTCPConnections int64 `json:"active_tcp_connections"`
+	// ConnectionsClosed counts the TCP connections closed so far by reason, see handlers.CloseReason
+	ConnectionsClosed map[string]uint64 `json:"connections_closed"`
+	Metrics           metrics.Summary   `json:"metrics"`
+}
+
+// statsHandler serves snapshots of the server, polled by the web UI
+type statsHandler struct {
+	mc    *metrics.MetricsCollector
+	start time.Time
+}
+
+// ServeHTTP reports the connections and metrics of the server as JSON
+func (h *statsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	stats := Stats{
+		Time:                 now.UTC().Format(time.RFC3339Nano),
+		UptimeSeconds:        now.Sub(h.start).Seconds(),
+		ActiveTCPConnections: h.mc.ActiveTCPConnectionCount(),
+		ConnectionsClosed:    h.mc.ConnectionsClosedCounts(),
+		Metrics:              h.mc.Summary(),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		logging.Logger.Debugf("Failed to write stats: %v", err)
+	}
+}
+
+// webUIPage polls the stats endpoint every second and charts the last minutes of the server. Connections
+// closed by a reason other than fin are charted as errors.
+var webUIPage = webui.Page("Flow Generator Server", "", webui.Charts(
+	webui.Chart("requestChart", "Requests (requests/s)"),
+	webui.Chart("throughputChart", "Throughput (Mbit/s)"),
+	webui.Chart("errorChart", "Connections closed per second by reason"),
+	webui.Chart("activeChart", "Active TCP connections"),
+), `var last = null;
+
+function update(stats) {
+  var m = stats.metrics;
+  var now = {
+    time: Date.parse(stats.time) / 1000,
+    requests: m.total_requests_received,
+    bytes: total(m.bytes_sent) + total(m.bytes_received),
+    closed: stats.connections_closed || {}
+  };
+  if (last && now.time > last.time) {
+    var dt = now.time - last.time;
+    push("requestChart", "received", (now.requests - last.requests) / dt);
+    push("throughputChart", "sent + received", (now.bytes - last.bytes) * 8 / dt / 1e6);
+    for (var reason in now.closed) {
+      push("errorChart", reason, (now.closed[reason] - (last.closed[reason] || 0)) / dt);
+    }
+    push("activeChart", "active", stats.active_tcp_connections);
+    ["requestChart", "throughputChart", "errorChart", "activeChart"].forEach(draw);
+  }
+  last = now;
+
+  var errors = 0;
+  for (var reason in now.closed) {
+    if (reason !== "fin") {
+      errors += now.closed[reason];
+    }
+  }
+  document.getElementById("status").innerHTML = "<span>Uptime: " + stats.uptime_seconds.toFixed(0) + "s</span>" +
+    "<span>Requests received: " + m.total_requests_received + "</span>" +
+    "<span>Active TCP connections: " + stats.active_tcp_connections + "</span>" +
+    "<span>Connections closed abnormally: " + errors + "</span>";
+}
+
+function poll() {
+  fetch("stats").then(function(r) { return r.json(); }).then(update).catch(function() {
+    document.getElementById("status").textContent = "disconnected, the server may have stopped";
+  });
+}
+
+poll();
+setInterval(poll, 1000);
+`)
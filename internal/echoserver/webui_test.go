@@ -0,0 +1,45 @@
+package echoserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/internal/webui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsHandler(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	mc.IncActiveTCPConnections()
+	mc.IncActiveTCPConnections()
+	mc.IncConnectionsClosed("reset")
+	mc.IncRequestsReceived("tcp", "8080")
+	mc.AddBytesReceived("tcp", "8080", 64)
+
+	rec := httptest.NewRecorder()
+	(&statsHandler{mc: mc, start: time.Now().Add(-time.Minute)}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, StatsPath, nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var stats Stats
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	assert.GreaterOrEqual(t, stats.UptimeSeconds, 60.0)
+	assert.Equal(t, int64(2), stats.ActiveTCPConnections)
+	assert.Equal(t, map[string]uint64{"reset": 1}, stats.ConnectionsClosed)
+	assert.Equal(t, uint64(1), stats.Metrics.TotalRequestsReceived)
+	assert.Equal(t, uint64(64), stats.Metrics.BytesReceived["tcp"]["8080"])
+}
+
+func TestWebUIPage(t *testing.T) {
+	rec := httptest.NewRecorder()
+	webui.Handler(webUIPage).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, WebUIPath, nil))
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "<title>Flow Generator Server</title>")
+	assert.Contains(t, rec.Body.String(), `fetch("stats")`)
+	assert.Contains(t, rec.Body.String(), `<svg id="activeChart">`)
+	assert.Contains(t, rec.Body.String(), "function draw(chart)")
+}
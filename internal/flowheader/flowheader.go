@@ -0,0 +1,56 @@
+// Package flowheader implements the header clients put at the start of flow payloads to identify flows
+// on the server.
+//
+// The header carries the ID of the client run, the ID of the flow within the run and the sequence number
+// of the request within the flow. The echo server returns payloads unchanged, so the header costs the
+// client nothing but its size, while the server can tell flows apart independently of their 5-tuples,
+// e.g. to detect duplicated flows and replayed datagrams.
+package flowheader
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Size is the length of an encoded header
+const Size = len("FGFH") + 1 + 8 + 8 + 4
+
+// version is the header format written by Put
+const version = 1
+
+var magic = []byte("FGFH")
+
+// Header identifies a request of a flow
+type Header struct {
+	// RunID identifies the client run, so flow IDs of different clients and runs do not collide
+	RunID  uint64
+	FlowID uint64
+	// Seq counts the requests of the flow, starting at 0
+	Seq uint32
+}
+
+// Put encodes the header into the first Size bytes of b, which must be at least Size bytes long
+func (h Header) Put(b []byte) {
+	copy(b, magic)
+	b[len(magic)] = version
+	binary.BigEndian.PutUint64(b[len(magic)+1:], h.RunID)
+	binary.BigEndian.PutUint64(b[len(magic)+9:], h.FlowID)
+	SetSeq(b, h.Seq)
+}
+
+// SetSeq updates the sequence number of the header encoded at the start of b
+func SetSeq(b []byte, seq uint32) {
+	binary.BigEndian.PutUint32(b[len(magic)+17:], seq)
+}
+
+// Parse decodes the header at the start of b. It reports false if b does not start with a header.
+func Parse(b []byte) (Header, bool) {
+	if len(b) < Size || !bytes.Equal(b[:len(magic)], magic) || b[len(magic)] != version {
+		return Header{}, false
+	}
+	return Header{
+		RunID:  binary.BigEndian.Uint64(b[len(magic)+1:]),
+		FlowID: binary.BigEndian.Uint64(b[len(magic)+9:]),
+		Seq:    binary.BigEndian.Uint32(b[len(magic)+17:]),
+	}, true
+}
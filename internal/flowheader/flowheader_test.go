@@ -0,0 +1,46 @@
+package flowheader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPutParse(t *testing.T) {
+	b := make([]byte, Size+10)
+	want := Header{RunID: 0x0102030405060708, FlowID: 42, Seq: 7}
+	want.Put(b)
+
+	got, ok := Parse(b)
+	assert.True(t, ok)
+	assert.Equal(t, want, got)
+
+	SetSeq(b, 8)
+	got, ok = Parse(b)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(8), got.Seq)
+}
+
+func TestParseInvalid(t *testing.T) {
+	valid := make([]byte, Size)
+	Header{RunID: 1, FlowID: 1}.Put(valid)
+
+	wrongVersion := append([]byte(nil), valid...)
+	wrongVersion[4] = 2
+
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{"empty", nil},
+		{"truncated", valid[:Size-1]},
+		{"random payload", []byte("0123456789012345678901234567890")},
+		{"unknown version", wrongVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := Parse(tt.b)
+			assert.False(t, ok)
+		})
+	}
+}
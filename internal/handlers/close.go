@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// Reasons a TCP connection ended, used as the reason label of connections_closed_total
+const (
+	// CloseFIN is a clean close by the client
+	CloseFIN = "fin"
+	// CloseReset is a connection reset by the client or a middlebox
+	CloseReset = "reset"
+	// CloseTimeout is a connection that timed out
+	CloseTimeout = "timeout"
+	// CloseServer is a connection closed by the server itself, e.g. on shutdown or a failed relay
+	CloseServer = "server"
+	// CloseError is any other error
+	CloseError = "error"
+)
+
+// ErrClosedByServer is returned by connection handlers that gave up on a connection themselves
+var ErrClosedByServer = errors.New("connection closed by server")
+
+// CloseReason classifies the error that ended a TCP connection. A nil error or EOF is a clean FIN.
+// Write errors after a reset (EPIPE) count as resets, since the peer is gone either way.
+func CloseReason(err error) string {
+	var netErr net.Error
+	switch {
+	case err == nil || errors.Is(err, io.EOF):
+		return CloseFIN
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE), errors.Is(err, syscall.ECONNABORTED):
+		return CloseReset
+	case errors.Is(err, syscall.ETIMEDOUT), errors.As(err, &netErr) && netErr.Timeout():
+		return CloseTimeout
+	case errors.Is(err, ErrClosedByServer), errors.Is(err, net.ErrClosed):
+		return CloseServer
+	default:
+		return CloseError
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// timeoutError is a net.Error that timed out
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestCloseReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, CloseFIN},
+		{"EOF", io.EOF, CloseFIN},
+		{"reset", &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, CloseReset},
+		{"broken pipe", &net.OpError{Op: "write", Err: os.NewSyscallError("write", syscall.EPIPE)}, CloseReset},
+		{"deadline", &net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}, CloseTimeout},
+		{"net timeout", timeoutError{}, CloseTimeout},
+		{"keepalive timeout", &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ETIMEDOUT)}, CloseTimeout},
+		{"closed by server", fmt.Errorf("relay: %w", ErrClosedByServer), CloseServer},
+		{"closed listener", net.ErrClosed, CloseServer},
+		{"other", errors.New("boom"), CloseError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CloseReason(tt.err))
+		})
+	}
+}
+
+func TestTCPHandlerCloseReasons(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	handler := NewTCPHandler(mc)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+
+	// serve handles a single connection and waits for the handler to return
+	serve := func(client func(c *net.TCPConn)) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			conn, err := ln.Accept()
+			if err == nil {
+				handler.Handle(conn)
+			}
+		}()
+		c, err := net.Dial("tcp", ln.Addr().String())
+		require.NoError(t, err)
+		client(c.(*net.TCPConn))
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("Handler did not finish in time")
+		}
+	}
+
+	fin := testutil.ToFloat64(mc.ConnectionsClosed.WithLabelValues(CloseFIN))
+	reset := testutil.ToFloat64(mc.ConnectionsClosed.WithLabelValues(CloseReset))
+
+	serve(func(c *net.TCPConn) { _ = c.Close() })
+	// A zero linger time makes the close send an RST instead of a FIN
+	serve(func(c *net.TCPConn) {
+		require.NoError(t, c.SetLinger(0))
+		_ = c.Close()
+	})
+
+	assert.Equal(t, fin+1, testutil.ToFloat64(mc.ConnectionsClosed.WithLabelValues(CloseFIN)))
+	assert.Equal(t, reset+1, testutil.ToFloat64(mc.ConnectionsClosed.WithLabelValues(CloseReset)))
+}
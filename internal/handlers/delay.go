@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"io"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/bufpool"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+)
+
+// delayedChunks is the number of read chunks of a TCP connection waiting for their response delay before
+// reading blocks
+const delayedChunks = 64
+
+// responseDelay holds back responses by a fixed or random amount, to emulate slow services without netem
+type responseDelay struct {
+	base   time.Duration
+	jitter time.Duration
+}
+
+// enabled reports whether responses are delayed
+func (d responseDelay) enabled() bool {
+	return d.base > 0 || d.jitter > 0
+}
+
+// pick returns the delay of a response, uniformly from base-jitter to base+jitter and never negative
+func (d responseDelay) pick() time.Duration {
+	if d.jitter == 0 {
+		return d.base
+	}
+	// #nosec G404 - math/rand is sufficient for response delays
+	return max(d.base-d.jitter+rand.N(2*d.jitter+1), 0)
+}
+
+// SetResponseDelay makes the handler delay every echo response by base, varied by up to jitter either way
+func (h *TCPHandler) SetResponseDelay(base, jitter time.Duration) {
+	h.delay = responseDelay{base: base, jitter: jitter}
+}
+
+// SetResponseDelay makes the handler delay every reply by base, varied by up to jitter either way
+func (h *UDPHandler) SetResponseDelay(base, jitter time.Duration) {
+	h.delay = responseDelay{base: base, jitter: jitter}
+}
+
+// delayedEcho echoes like echo, but writes every chunk its response delay after it was read. Reading goes
+// on meanwhile, so a request read in several chunks is delayed once, not once per chunk. Chunks are written
+// in order, a chunk picking a shorter delay than the one before it waits for that one.
+func (h *TCPHandler) delayedEcho(conn net.Conn, protocol, portStr string) error {
+	type chunk struct {
+		// buf is the pooled read buffer holding the data, returned once the chunk was written
+		buf  *[]byte
+		data []byte
+		read time.Time
+		due  time.Time
+	}
+	chunks := make(chan chunk, delayedChunks)
+	writeErr := make(chan error, 1)
+	go func() {
+		var err error
+		for c := range chunks {
+			if err != nil {
+				bufpool.Put(c.buf)
+				continue
+			}
+			time.Sleep(time.Until(c.due))
+			var n int
+			n, err = conn.Write(resize(h.size, h.upstream.respond(h.metricsCollector, protocol, portStr, c.data)))
+			bufpool.Put(c.buf)
+			if err != nil {
+				logging.Logger.Debugf("Failed to write to TCP connection from %s: %v", conn.RemoteAddr().String(), err)
+				// Unblock the reader, the client does not get its responses anymore
+				_ = conn.Close()
+				continue
+			}
+			h.metricsCollector.ObserveEchoDelay(protocol, portStr, time.Since(c.read))
+			h.metricsCollector.AddBytesSent(protocol, portStr, n)
+		}
+		writeErr <- err
+	}()
+
+	var last time.Time
+	for first := true; ; first = false {
+		bp := bufpool.Get(h.readBufferSize())
+		buf := *bp
+		n, err := conn.Read(buf)
+		if err != nil {
+			bufpool.Put(bp)
+			// Responses still waiting for their delay are written before the connection is closed
+			close(chunks)
+			if werr := <-writeErr; werr != nil {
+				return werr
+			}
+			if err != io.EOF {
+				logging.Logger.Debugf("TCP connection from %s closed: %v", conn.RemoteAddr().String(), err)
+			}
+			return err
+		}
+		readDone := time.Now()
+		h.metricsCollector.AddBytesReceived(protocol, portStr, n)
+		if first && h.duplicates != nil {
+			h.checkDuplicate(conn, buf[:n], protocol, portStr, readDone)
+		}
+		if h.drop.hit() {
+			bufpool.Put(bp)
+			h.metricsCollector.IncResponsesDropped(protocol, portStr)
+			continue
+		}
+		due := readDone.Add(h.delay.pick())
+		if due.Before(last) {
+			due = last
+		}
+		last = due
+		chunks <- chunk{buf: bp, data: buf[:n], read: readDone, due: due}
+	}
+}
+
+// sendDelayed writes a reply with write once its response delay after the request was read at readDone
+// passed, in the background so the next datagrams are read meanwhile
+func (h *UDPHandler) sendDelayed(portStr, peer string, reply []byte, readDone time.Time, write func([]byte) (int, error)) {
+	// The reply may share the read buffer, which is reused for the next datagram
+	reply = slices.Clone(reply)
+	time.AfterFunc(time.Until(readDone.Add(h.delay.pick())), func() {
+		n, err := write(reply)
+		if err != nil {
+			logging.Logger.Debugf("Failed to write UDP packet to %s: %v", peer, err)
+			return
+		}
+		h.metricsCollector.ObserveEchoDelay("udp", portStr, time.Since(readDone))
+		h.metricsCollector.AddBytesSent("udp", portStr, n)
+	})
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseDelayPick(t *testing.T) {
+	assert.False(t, responseDelay{}.enabled())
+	assert.Equal(t, 50*time.Millisecond, responseDelay{base: 50 * time.Millisecond}.pick())
+
+	d := responseDelay{base: 50 * time.Millisecond, jitter: 10 * time.Millisecond}
+	for i := 0; i < 100; i++ {
+		delay := d.pick()
+		assert.GreaterOrEqual(t, delay, 40*time.Millisecond)
+		assert.LessOrEqual(t, delay, 60*time.Millisecond)
+	}
+
+	// Jitter larger than the base never yields a negative delay
+	for i := 0; i < 100; i++ {
+		assert.GreaterOrEqual(t, responseDelay{base: time.Millisecond, jitter: 10 * time.Millisecond}.pick(), time.Duration(0))
+	}
+}
+
+func TestTCPHandlerResponseDelay(t *testing.T) {
+	handler := NewTCPHandler(metrics.NewMetricsCollector())
+	handler.SetResponseDelay(50*time.Millisecond, 0)
+
+	client, server := net.Pipe()
+	defer func() { _ = client.Close() }()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.Handle(&pipeConn{Conn: server})
+	}()
+
+	_ = client.SetDeadline(time.Now().Add(5 * time.Second))
+	for _, msg := range []string{"first", "second"} {
+		start := time.Now()
+		_, err := client.Write([]byte(msg))
+		require.NoError(t, err)
+		buf := make([]byte, 16)
+		n, err := client.Read(buf)
+		require.NoError(t, err)
+		assert.Equal(t, msg, string(buf[:n]))
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	}
+
+	_ = client.Close()
+	<-done
+}
+
+func TestUDPHandlerResponseDelay(t *testing.T) {
+	handler := NewUDPHandler(metrics.NewMetricsCollector())
+	handler.SetResponseDelay(50*time.Millisecond, 0)
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	go handler.Handle(conn)
+
+	clientConn, err := net.Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer func() { _ = clientConn.Close() }()
+
+	start := time.Now()
+	_, err = clientConn.Write([]byte("ping"))
+	require.NoError(t, err)
+	_ = clientConn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 16)
+	n, err := clientConn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf[:n]))
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import "math/rand/v2"
+
+// responseDrop is the percentage of echo responses dropped instead of sent, to emulate lossy services
+// without netem
+type responseDrop float64
+
+// hit reports whether the next response is dropped
+func (d responseDrop) hit() bool {
+	// #nosec G404 - math/rand is sufficient for response drops
+	return d > 0 && rand.Float64()*100 < float64(d)
+}
+
+// SetResponseDrop makes the handler drop the given percentage of echo responses. The data is still read, the
+// client just never gets its echo back.
+func (h *TCPHandler) SetResponseDrop(percent float64) {
+	h.drop = responseDrop(percent)
+}
+
+// SetResponseDrop makes the handler drop the given percentage of replies
+func (h *UDPHandler) SetResponseDrop(percent float64) {
+	h.drop = responseDrop(percent)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseDropHit(t *testing.T) {
+	assert.False(t, responseDrop(0).hit())
+	assert.True(t, responseDrop(100).hit())
+
+	hits := 0
+	for i := 0; i < 10000; i++ {
+		if responseDrop(25).hit() {
+			hits++
+		}
+	}
+	assert.InDelta(t, 2500, hits, 300)
+}
+
+func TestTCPHandlerResponseDrop(t *testing.T) {
+	for _, delay := range []time.Duration{0, 10 * time.Millisecond} {
+		t.Run("delay "+delay.String(), func(t *testing.T) {
+			mc := metrics.NewMetricsCollector()
+			handler := NewTCPHandler(mc)
+			handler.SetResponseDelay(delay, 0)
+			handler.SetResponseDrop(100)
+			dropped := testutil.ToFloat64(mc.ResponsesDropped.WithLabelValues("tcp", "19"))
+
+			client, server := net.Pipe()
+			defer func() { _ = client.Close() }()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				handler.Handle(&pipeConn{Conn: server})
+			}()
+
+			_ = client.SetWriteDeadline(time.Now().Add(5 * time.Second))
+			_, err := client.Write([]byte("ping"))
+			require.NoError(t, err)
+			_ = client.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+			_, err = client.Read(make([]byte, 16))
+			assert.ErrorIs(t, err, os.ErrDeadlineExceeded, "no response expected")
+
+			_ = client.Close()
+			<-done
+			assert.Equal(t, dropped+1, testutil.ToFloat64(mc.ResponsesDropped.WithLabelValues("tcp", "19")))
+		})
+	}
+}
+
+func TestUDPHandlerResponseDrop(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	handler := NewUDPHandler(mc)
+	handler.SetResponseDrop(100)
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	port := strconv.Itoa(conn.LocalAddr().(*net.UDPAddr).Port)
+	go handler.Handle(conn)
+
+	clientConn, err := net.Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer func() { _ = clientConn.Close() }()
+
+	_, err = clientConn.Write([]byte("ping"))
+	require.NoError(t, err)
+	_ = clientConn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	_, err = clientConn.Read(make([]byte, 16))
+	assert.Error(t, err, "no reply expected")
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.ResponsesDropped.WithLabelValues("udp", port)))
+}
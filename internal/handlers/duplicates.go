@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/flowheader"
+)
+
+// replayWindow is the number of sequence numbers below the highest one seen for which replayed
+// datagrams are detected, like the anti-replay window of IPsec
+const replayWindow = 64
+
+// flowKey identifies a flow across clients by the IDs in its flow header
+type flowKey struct {
+	runID  uint64
+	flowID uint64
+}
+
+// flowSighting is what is known about a flow seen on the server
+type flowSighting struct {
+	// peers are the connections (TCP) or peer addresses (UDP) the flow arrived from, the first one is
+	// the original flow
+	peers    []string
+	lastSeen time.Time
+	// highestSeq and seen track the sequence numbers of the original flow within the replay window,
+	// bit i of seen is set if highestSeq-i arrived
+	highestSeq uint32
+	seen       uint64
+}
+
+// DuplicateDetector detects flows whose flow header was seen before, e.g. because a client retried them or
+// the datapath duplicated them. Flows are remembered until they have been idle for the window.
+type DuplicateDetector struct {
+	mu        sync.Mutex
+	window    time.Duration
+	flows     map[flowKey]*flowSighting
+	lastSweep time.Time
+}
+
+// NewDuplicateDetector creates a detector remembering flows for the given window
+func NewDuplicateDetector(window time.Duration) *DuplicateDetector {
+	return &DuplicateDetector{window: window, flows: make(map[flowKey]*flowSighting), lastSweep: time.Now()}
+}
+
+// Observe records a request of a flow arriving from peer at now. It reports whether the flow is a duplicate,
+// i.e. the flow was seen from another peer before and this is the first request from this peer, and whether
+// the request is a replay of a request of the flow from the same peer.
+func (d *DuplicateDetector) Observe(h flowheader.Header, peer string, now time.Time) (duplicate, replay bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sweep(now)
+
+	key := flowKey{runID: h.RunID, flowID: h.FlowID}
+	s, ok := d.flows[key]
+	if !ok {
+		d.flows[key] = &flowSighting{peers: []string{peer}, lastSeen: now, highestSeq: h.Seq, seen: 1}
+		return false, false
+	}
+	s.lastSeen = now
+	if s.peers[0] != peer {
+		for _, p := range s.peers[1:] {
+			if p == peer {
+				return false, false
+			}
+		}
+		s.peers = append(s.peers, peer)
+		return true, false
+	}
+	return false, s.replayed(h.Seq)
+}
+
+// replayed records a sequence number of the original flow and reports whether it arrived before
+func (s *flowSighting) replayed(seq uint32) bool {
+	switch {
+	case seq > s.highestSeq:
+		shift := seq - s.highestSeq
+		if shift >= replayWindow {
+			s.seen = 0
+		} else {
+			s.seen <<= shift
+		}
+		s.seen |= 1
+		s.highestSeq = seq
+		return false
+	case s.highestSeq-seq >= replayWindow:
+		// Too old to tell, treated as a replay like IPsec does
+		return true
+	default:
+		bit := uint64(1) << (s.highestSeq - seq)
+		if s.seen&bit != 0 {
+			return true
+		}
+		s.seen |= bit
+		return false
+	}
+}
+
+// sweep forgets flows idle for longer than the window. It runs at most every half window, so looking up
+// flows stays cheap. The caller must hold mu.
+func (d *DuplicateDetector) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < d.window/2 {
+		return
+	}
+	d.lastSweep = now
+	for key, s := range d.flows {
+		if now.Sub(s.lastSeen) > d.window {
+			delete(d.flows, key)
+		}
+	}
+}
+
+// Len returns the number of flows currently remembered
+func (d *DuplicateDetector) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.flows)
+}
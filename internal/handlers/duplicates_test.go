@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/flowheader"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// headerPayload returns a payload starting with the flow header of the given flow and sequence number
+func headerPayload(flowID uint64, seq uint32) []byte {
+	b := make([]byte, flowheader.Size+8)
+	flowheader.Header{RunID: 0xfeed, FlowID: flowID, Seq: seq}.Put(b)
+	return b
+}
+
+func TestDuplicateDetectorObserve(t *testing.T) {
+	d := NewDuplicateDetector(time.Minute)
+	now := time.Now()
+	flow := flowheader.Header{RunID: 1, FlowID: 7}
+
+	observe := func(seq uint32, peer string) (bool, bool) {
+		h := flow
+		h.Seq = seq
+		return d.Observe(h, peer, now)
+	}
+	steps := []struct {
+		name          string
+		seq           uint32
+		peer          string
+		wantDuplicate bool
+		wantReplay    bool
+	}{
+		{"first request", 0, "10.0.0.1:4000", false, false},
+		{"next request", 1, "10.0.0.1:4000", false, false},
+		{"replayed request", 1, "10.0.0.1:4000", false, true},
+		{"reordered request", 5, "10.0.0.1:4000", false, false},
+		{"late request within window", 3, "10.0.0.1:4000", false, false},
+		{"late request replayed", 3, "10.0.0.1:4000", false, true},
+		{"retried from another port", 0, "10.0.0.1:4001", true, false},
+		{"retry continues", 1, "10.0.0.1:4001", false, false},
+		{"jump beyond window", 200, "10.0.0.1:4000", false, false},
+		{"older than window", 100, "10.0.0.1:4000", false, true},
+	}
+	for _, step := range steps {
+		duplicate, replay := observe(step.seq, step.peer)
+		assert.Equal(t, step.wantDuplicate, duplicate, step.name)
+		assert.Equal(t, step.wantReplay, replay, step.name)
+	}
+
+	// Other runs may use the same flow ID
+	duplicate, replay := d.Observe(flowheader.Header{RunID: 2, FlowID: 7}, "10.0.0.2:4000", now)
+	assert.False(t, duplicate)
+	assert.False(t, replay)
+	assert.Equal(t, 2, d.Len())
+}
+
+func TestDuplicateDetectorExpiry(t *testing.T) {
+	d := NewDuplicateDetector(time.Minute)
+	start := time.Now()
+	d.Observe(flowheader.Header{RunID: 1, FlowID: 1}, "a", start)
+	d.Observe(flowheader.Header{RunID: 1, FlowID: 2}, "a", start.Add(50*time.Second))
+
+	// Flows idle for longer than the window are forgotten, so a late retry is not a duplicate anymore
+	duplicate, _ := d.Observe(flowheader.Header{RunID: 1, FlowID: 1}, "b", start.Add(90*time.Second))
+	assert.False(t, duplicate)
+	assert.Equal(t, 2, d.Len())
+}
+
+func TestTCPHandlerDuplicateFlows(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	handler := NewTCPHandler(mc)
+	handler.SetDuplicateDetector(NewDuplicateDetector(time.Minute))
+
+	// A port no other test uses, so the counter starts at zero
+	serve := func(clientPort int, payload []byte) []byte {
+		conn := newMockConn()
+		conn.localAddr = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 18444}
+		conn.remoteAddr = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: clientPort}
+		conn.writeToReadBuf(payload)
+		handler.Handle(conn)
+		return conn.getWrittenData()
+	}
+	payload := headerPayload(1, 0)
+	assert.Equal(t, payload, serve(40000, payload))
+	assert.Equal(t, payload, serve(40001, payload))
+	serve(40002, headerPayload(2, 0))
+	serve(40003, []byte("no flow header"))
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.DuplicateFlows.WithLabelValues("tcp", "18444")))
+}
+
+func TestUDPHandlerReplayedDatagrams(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	handler := NewUDPHandler(mc)
+	handler.SetDuplicateDetector(NewDuplicateDetector(time.Minute))
+
+	first := headerPayload(3, 0)
+	assert.Equal(t, first, handler.reply("18445", "10.0.0.1:5000", first))
+	assert.NotNil(t, handler.reply("18445", "10.0.0.1:5000", headerPayload(3, 1)))
+	// The replayed datagram is not echoed
+	assert.Nil(t, handler.reply("18445", "10.0.0.1:5000", headerPayload(3, 1)))
+	// The same flow from another peer is a duplicate flow
+	assert.NotNil(t, handler.reply("18445", "10.0.0.2:5000", first))
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.ReplayedDatagrams.WithLabelValues("18445")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.DuplicateFlows.WithLabelValues("udp", "18445")))
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+)
+
+const (
+	// generatorChunkSize is the size of the writes of generator mode
+	generatorChunkSize = 16 * 1024
+	// generatorWritesPerSecond is how often a paced stream writes at most, smaller writes keep low rates smooth
+	generatorWritesPerSecond = 100
+)
+
+// SetStreamRate paces the stream of generator mode to the given bits per second, 0 streams as fast as the
+// client reads
+func (h *TCPHandler) SetStreamRate(bitsPerSecond float64) {
+	h.rate = bitsPerSecond
+}
+
+// generatorChunk returns the data written by every write of a stream at the given rate
+func generatorChunk(bitsPerSecond float64) []byte {
+	if bitsPerSecond <= 0 {
+		return chargenData(generatorChunkSize)
+	}
+	return chargenData(min(max(int(bitsPerSecond/8/generatorWritesPerSecond), 1), generatorChunkSize))
+}
+
+// generate streams the chargen pattern until the client closes the connection, and returns the error that
+// ended it. A stream with a rate writes every chunk once the bytes before it are due, so it keeps the rate on
+// average however long writes block. Any data sent by the client is read and discarded.
+func (h *TCPHandler) generate(conn net.Conn, protocol, portStr string) error {
+	readErr := h.drainInput(conn, protocol, portStr)
+	chunk := generatorChunk(h.rate)
+	start := time.Now()
+	var sent int
+	for {
+		if h.rate > 0 {
+			time.Sleep(time.Until(start.Add(time.Duration(float64(sent) * 8 / h.rate * float64(time.Second)))))
+		}
+		n, err := conn.Write(chunk)
+		if err != nil {
+			logging.Logger.Debugf("Generator stream to %s ended after %d bytes: %v", conn.RemoteAddr().String(), sent, err)
+			return streamEnd(err, readErr)
+		}
+		sent += n
+		h.metricsCollector.AddBytesSent(protocol, portStr, n)
+	}
+}
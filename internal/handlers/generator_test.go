@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratorChunk(t *testing.T) {
+	assert.Len(t, generatorChunk(0), generatorChunkSize)
+	// 8 Mbit/s are written in chunks of 10 kB
+	assert.Len(t, generatorChunk(8e6), 10000)
+	assert.Len(t, generatorChunk(1e9), generatorChunkSize)
+	assert.Len(t, generatorChunk(8), 1)
+	assert.Equal(t, chargenLine(0), generatorChunk(0)[:chargenLineLength+2])
+}
+
+func TestTCPHandlerGeneratorMode(t *testing.T) {
+	tests := []struct {
+		name string
+		rate float64
+	}{
+		{"unpaced", 0},
+		{"paced", 800e3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mc := metrics.NewMetricsCollector()
+			handler := NewTCPServiceHandler(mc, ModeGenerator)
+			handler.SetStreamRate(tt.rate)
+
+			serverConn, clientConn := net.Pipe()
+			done := make(chan bool)
+			go func() {
+				handler.Handle(&pipeConn{Conn: serverConn})
+				done <- true
+			}()
+
+			// 30 kB take 300ms at 800 kbit/s
+			start := time.Now()
+			buf := make([]byte, 30000)
+			_ = clientConn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			_, err := io.ReadFull(clientConn, buf)
+			require.NoError(t, err)
+			if tt.rate > 0 {
+				assert.GreaterOrEqual(t, time.Since(start), 250*time.Millisecond)
+			}
+
+			_ = clientConn.Close()
+
+			select {
+			case <-done:
+			case <-time.After(1 * time.Second):
+				t.Fatal("Generator handler did not stop after client disconnect")
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+)
+
+const (
+	// httpMaxBodySize is the largest request body HTTP mode echoes, larger requests are answered with 413
+	httpMaxBodySize = 16 << 20
+	// httpMaxPaths is the number of distinct paths of a port that get their own metrics label, further paths
+	// share httpOtherLabel so clients cannot grow the metrics without bound
+	httpMaxPaths = 100
+	// httpOtherLabel is the metrics label of paths over httpMaxPaths and of non-standard methods
+	httpOtherLabel = "other"
+	// httpEchoPrefix prefixes the response headers reflecting the request
+	httpEchoPrefix = "X-Echo-"
+)
+
+// httpMethods are the methods that get their own metrics label
+var httpMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true, http.MethodPatch: true,
+	http.MethodDelete: true, http.MethodConnect: true, http.MethodOptions: true, http.MethodTrace: true,
+}
+
+// httpPaths hands out the metrics labels of the paths requested on a port
+type httpPaths struct {
+	mu    sync.Mutex
+	known map[string]bool
+}
+
+// label returns the metrics label of a path, the path itself until httpMaxPaths paths were seen
+func (p *httpPaths) label(path string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.known[path] {
+		return path
+	}
+	if len(p.known) >= httpMaxPaths {
+		return httpOtherLabel
+	}
+	if p.known == nil {
+		p.known = make(map[string]bool)
+	}
+	p.known[path] = true
+	return path
+}
+
+// httpMethodLabel returns the metrics label of a request method
+func httpMethodLabel(method string) string {
+	if httpMethods[method] {
+		return method
+	}
+	return httpOtherLabel
+}
+
+// SetEchoHeaders sets the request headers HTTP mode reflects in its responses, prefixed with X-Echo-
+func (h *TCPHandler) SetEchoHeaders(names []string) {
+	h.echoHeaders = make([]string, len(names))
+	for i, name := range names {
+		h.echoHeaders[i] = http.CanonicalHeaderKey(name)
+	}
+}
+
+// countingReader reads from a connection and counts the bytes received
+type countingReader struct {
+	conn net.Conn
+	add  func(n int)
+}
+
+// Read reads from the connection
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.conn.Read(p)
+	r.add(n)
+	return n, err
+}
+
+// countingWriter writes to a connection and counts the bytes sent
+type countingWriter struct {
+	conn net.Conn
+	add  func(n int)
+}
+
+// Write writes to the connection
+func (w countingWriter) Write(p []byte) (int, error) {
+	n, err := w.conn.Write(p)
+	w.add(n)
+	return n, err
+}
+
+// httpEcho answers every HTTP/1.x request with its body, reflecting the method, path and the selected request
+// headers in the response headers, until the client closes the connection or asks to. It returns the error
+// that ended the connection, or ErrClosedByServer if the client sent a malformed request.
+func (h *TCPHandler) httpEcho(conn net.Conn, protocol, portStr string) error {
+	r := bufio.NewReader(countingReader{conn: conn, add: func(n int) { h.metricsCollector.AddBytesReceived(protocol, portStr, n) }})
+	w := bufio.NewWriter(countingWriter{conn: conn, add: func(n int) { h.metricsCollector.AddBytesSent(protocol, portStr, n) }})
+	for {
+		req, err := http.ReadRequest(r)
+		if err != nil {
+			var netErr net.Error
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr) {
+				return err
+			}
+			logging.Logger.Debugf("Malformed HTTP request from %s: %v", conn.RemoteAddr().String(), err)
+			resp := &http.Response{StatusCode: http.StatusBadRequest, ProtoMajor: 1, ProtoMinor: 1, Close: true}
+			if err := writeHTTPResponse(w, resp); err != nil {
+				return err
+			}
+			return ErrClosedByServer
+		}
+		body, err := io.ReadAll(io.LimitReader(req.Body, httpMaxBodySize+1))
+		if err != nil {
+			logging.Logger.Debugf("Failed to read HTTP request body from %s: %v", conn.RemoteAddr().String(), err)
+			return err
+		}
+		readDone := time.Now()
+
+		resp := h.httpResponse(req, body)
+		if err := writeHTTPResponse(w, resp); err != nil {
+			logging.Logger.Debugf("Failed to write to TCP connection from %s: %v", conn.RemoteAddr().String(), err)
+			return err
+		}
+		h.metricsCollector.ObserveHTTPRequest(portStr, httpMethodLabel(req.Method), h.paths.label(req.URL.Path), resp.StatusCode, time.Since(readDone))
+		if resp.Close {
+			if resp.StatusCode != http.StatusOK {
+				return ErrClosedByServer
+			}
+			// The client asked to close the connection after the response
+			return nil
+		}
+	}
+}
+
+// httpResponse returns the response of HTTP mode to a request with the given body
+func (h *TCPHandler) httpResponse(req *http.Request, body []byte) *http.Response {
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Request:    req,
+		Header:     make(http.Header),
+		Close:      req.Close,
+	}
+	resp.Header.Set(httpEchoPrefix+"Method", req.Method)
+	resp.Header.Set(httpEchoPrefix+"Path", req.URL.RequestURI())
+	for _, name := range h.echoHeaders {
+		for _, value := range req.Header.Values(name) {
+			resp.Header.Add(httpEchoPrefix+name, value)
+		}
+	}
+	if len(body) > httpMaxBodySize {
+		// The rest of the body was not read, so the connection cannot carry further requests
+		resp.StatusCode, resp.Close = http.StatusRequestEntityTooLarge, true
+		return resp
+	}
+	contentType := req.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	resp.Header.Set("Content-Type", contentType)
+	resp.ContentLength = int64(len(body))
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp
+}
+
+// writeHTTPResponse writes a response in as few writes as possible
+func writeHTTPResponse(w *bufio.Writer, resp *http.Response) error {
+	if err := resp.Write(w); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPPathsLabel(t *testing.T) {
+	var paths httpPaths
+	for i := range httpMaxPaths {
+		assert.Equal(t, fmt.Sprintf("/%d", i), paths.label(fmt.Sprintf("/%d", i)))
+	}
+	// Paths seen before keep their label, new ones share the other label
+	assert.Equal(t, "/0", paths.label("/0"))
+	assert.Equal(t, httpOtherLabel, paths.label("/new"))
+}
+
+func TestHTTPMethodLabel(t *testing.T) {
+	assert.Equal(t, http.MethodGet, httpMethodLabel(http.MethodGet))
+	assert.Equal(t, httpOtherLabel, httpMethodLabel("BREW"))
+}
+
+func TestTCPHandlerHTTPMode(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	handler := NewTCPServiceHandler(mc, ModeHTTP)
+	handler.SetEchoHeaders([]string{"x-request-id"})
+
+	serverConn, clientConn := net.Pipe()
+	done := make(chan bool)
+	go func() {
+		handler.Handle(&pipeConn{Conn: serverConn})
+		done <- true
+	}()
+	_ = clientConn.SetDeadline(time.Now().Add(2 * time.Second))
+	r := bufio.NewReader(clientConn)
+
+	// Several requests are answered on the same connection
+	for _, body := range []string{"hello", "world"} {
+		req, err := http.NewRequest(http.MethodPost, "http://echo/api?q=1", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("X-Request-Id", "42")
+		req.Header.Set("Content-Type", "text/plain")
+		req.Header.Set("Authorization", "secret")
+		require.NoError(t, req.Write(clientConn))
+
+		resp, err := http.ReadResponse(r, req)
+		require.NoError(t, err)
+		got, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, body, string(got))
+		assert.Equal(t, "text/plain", resp.Header.Get("Content-Type"))
+		assert.Equal(t, http.MethodPost, resp.Header.Get("X-Echo-Method"))
+		assert.Equal(t, "/api?q=1", resp.Header.Get("X-Echo-Path"))
+		assert.Equal(t, "42", resp.Header.Get("X-Echo-X-Request-Id"))
+		// Only the selected headers are reflected
+		assert.Empty(t, resp.Header.Get("X-Echo-Authorization"))
+	}
+
+	// The server closes the connection once the client asks to
+	req, err := http.NewRequest(http.MethodGet, "http://echo/", nil)
+	require.NoError(t, err)
+	req.Close = true
+	require.NoError(t, req.Write(clientConn))
+	resp, err := http.ReadResponse(r, req)
+	require.NoError(t, err)
+	assert.True(t, resp.Close)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("HTTP handler did not close the connection the client asked to close")
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(mc.HTTPRequests.WithLabelValues("19", "POST", "/api", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(mc.HTTPRequests.WithLabelValues("19", "GET", "/", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(mc.ConnectionsClosed.WithLabelValues(CloseFIN)))
+}
+
+func TestTCPHandlerHTTPModeMalformedRequest(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	handler := NewTCPServiceHandler(mc, ModeHTTP)
+
+	serverConn, clientConn := net.Pipe()
+	done := make(chan bool)
+	go func() {
+		handler.Handle(&pipeConn{Conn: serverConn})
+		done <- true
+	}()
+	_ = clientConn.SetDeadline(time.Now().Add(2 * time.Second))
+	go func() { _, _ = clientConn.Write([]byte("not http\r\n\r\n")) }()
+
+	resp, err := http.ReadResponse(bufio.NewReader(clientConn), nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("HTTP handler did not close the connection after a malformed request")
+	}
+	assert.Equal(t, 1.0, testutil.ToFloat64(mc.ConnectionsClosed.WithLabelValues(CloseServer)))
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"io"
+	"net"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/relay"
+)
+
+const (
+	// relayHeaderTimeout bounds how long a relay waits for the flow header
+	relayHeaderTimeout = 10 * time.Second
+	// relayDialTimeout bounds how long a relay waits to connect to its next hop
+	relayDialTimeout = 5 * time.Second
+)
+
+// relay reads the flow header, connects to the next hop and pipes data in both directions. It returns the
+// error that ended the client connection, or ErrClosedByServer if the relay gave up on the flow.
+func (h *TCPHandler) relay(conn net.Conn, protocol, portStr string) error {
+	_ = conn.SetReadDeadline(time.Now().Add(relayHeaderTimeout))
+	hops, err := relay.ReadHeader(conn)
+	if err != nil {
+		logging.Logger.Debugf("Rejecting relay flow from %s: %v", conn.RemoteAddr().String(), err)
+		return err
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	next, remaining := hops[0], hops[1:]
+	upstream, err := net.DialTimeout("tcp", next, relayDialTimeout)
+	if err != nil {
+		logging.Logger.Warnf("Failed to relay flow from %s to %s: %v", conn.RemoteAddr().String(), next, err)
+		return ErrClosedByServer
+	}
+	defer func() { _ = upstream.Close() }()
+
+	if len(remaining) > 0 {
+		if err := relay.WriteHeader(upstream, remaining); err != nil {
+			logging.Logger.Debugf("Failed to forward relay header to %s: %v", next, err)
+			return ErrClosedByServer
+		}
+	}
+	if err := relay.WriteAck(conn, time.Now()); err != nil {
+		logging.Logger.Debugf("Failed to acknowledge relay flow from %s: %v", conn.RemoteAddr().String(), err)
+		return err
+	}
+	h.metricsCollector.IncRelayedConnections()
+	logging.Logger.Debugf("Relaying flow from %s to %s (%d hops remaining)", conn.RemoteAddr().String(), next, len(remaining))
+
+	done := make(chan struct{})
+	var clientErr error
+	go func() {
+		defer close(done)
+		n, err := io.Copy(upstream, conn)
+		clientErr = err
+		h.metricsCollector.AddBytesReceived(protocol, portStr, int(n))
+		// Propagate the client's close to the next hop
+		if tcpConn, ok := upstream.(*net.TCPConn); ok {
+			_ = tcpConn.CloseWrite()
+		}
+	}()
+
+	n, err := io.Copy(conn, upstream)
+	h.metricsCollector.AddBytesSent(protocol, portStr, int(n))
+	_ = conn.Close()
+	<-done
+	if clientErr != nil {
+		return clientErr
+	}
+	return err
+}
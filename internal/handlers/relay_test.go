@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/internal/relay"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveTCP accepts connections on a loopback listener and passes them to the handler
+func serveTCP(t *testing.T, handler *TCPHandler) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go handler.Handle(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestTCPHandlerRelayChain(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	target := serveTCP(t, NewTCPHandler(mc))
+	relayA := serveTCP(t, NewTCPServiceHandler(mc, ModeRelay))
+	relayB := serveTCP(t, NewTCPServiceHandler(mc, ModeRelay))
+	relayed := testutil.ToFloat64(mc.RelayedConnections)
+
+	conn, err := net.Dial("tcp", relayA)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	require.NoError(t, relay.WriteHeader(conn, []string{relayB, target}))
+	for hop := 0; hop < 2; hop++ {
+		_, err := relay.ReadAck(conn)
+		require.NoError(t, err, "missing acknowledgement for hop %d", hop+1)
+	}
+
+	_, err = conn.Write([]byte("through the chain"))
+	require.NoError(t, err)
+	buf := make([]byte, len("through the chain"))
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "through the chain", string(buf))
+	assert.Equal(t, relayed+2, testutil.ToFloat64(mc.RelayedConnections))
+}
+
+func TestTCPHandlerRelayRejectsMissingHeader(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	relayAddr := serveTCP(t, NewTCPServiceHandler(mc, ModeRelay))
+
+	conn, err := net.Dial("tcp", relayAddr)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	_, err = conn.Write([]byte("no header here"))
+	require.NoError(t, err)
+	_, err = conn.Read(make([]byte, 16))
+	// The relay closes the connection without acknowledging
+	require.Error(t, err)
+	var netErr net.Error
+	assert.False(t, errors.As(err, &netErr) && netErr.Timeout())
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import "github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/service"
+
+// ServiceMode selects the classic inetd-style service semantics a handler follows
+type ServiceMode string
+
+const (
+	// ModeEcho sends back any data received (RFC 862)
+	ModeEcho ServiceMode = service.ModeEcho
+	// ModeDiscard throws away any data received (RFC 863)
+	ModeDiscard ServiceMode = service.ModeDiscard
+	// ModeSink reads and throws away any data received like discard, with reads sized for throughput tests
+	ModeSink ServiceMode = service.ModeSink
+	// ModeChargen sends generated characters regardless of input (RFC 864)
+	ModeChargen ServiceMode = service.ModeChargen
+	// ModeGenerator streams the chargen pattern in large writes at a configurable rate, for server-to-client
+	// throughput tests
+	ModeGenerator ServiceMode = service.ModeGenerator
+	// ModeHTTP answers HTTP/1.x requests with their body, reflecting their method, path and selected headers,
+	// on TCP ports. UDP ports echo.
+	ModeHTTP ServiceMode = service.ModeHTTP
+	// ModeRelay forwards TCP flows to the next hop named in their relay header
+	ModeRelay ServiceMode = service.ModeRelay
+)
+
+const (
+	// chargenLineLength is the number of printable characters per chargen line
+	chargenLineLength = 72
+	// chargenMaxDatagram is the maximum number of characters in a UDP chargen reply
+	chargenMaxDatagram = 512
+	// defaultTCPReadBufferSize is the size of the reads of TCP connections unless configured otherwise
+	defaultTCPReadBufferSize = 1024
+	// defaultUDPReadBufferSize is the size of the reads of UDP sockets unless configured otherwise, which takes
+	// any datagram whole
+	defaultUDPReadBufferSize = 64 * 1024
+	// sinkReadBufferSize is the smallest size of the reads of TCP connections in sink mode, which drains
+	// streams in few reads
+	sinkReadBufferSize = 64 * 1024
+)
+
+// SetReadBufferSize sets the size of the reads of the connections, 0 reads 1 KiB. Sink mode reads at least
+// 64 KiB.
+func (h *TCPHandler) SetReadBufferSize(n int) {
+	h.readSize = n
+}
+
+// readBufferSize returns the size of the reads of the connections
+func (h *TCPHandler) readBufferSize() int {
+	size := h.readSize
+	if size <= 0 {
+		size = defaultTCPReadBufferSize
+	}
+	if h.mode == ModeSink {
+		return max(size, sinkReadBufferSize)
+	}
+	return size
+}
+
+// SetReadBufferSize sets the size of the reads of the socket, 0 reads 64 KiB. Larger datagrams are truncated
+// to it.
+func (h *UDPHandler) SetReadBufferSize(n int) {
+	h.readSize = n
+}
+
+// readBufferSize returns the size of the reads of the socket
+func (h *UDPHandler) readBufferSize() int {
+	if h.readSize <= 0 {
+		return defaultUDPReadBufferSize
+	}
+	return h.readSize
+}
+
+// chargenPattern holds the 95 printable ASCII characters rotated through by chargen
+var chargenPattern = func() []byte {
+	pattern := make([]byte, 0, 95)
+	for c := byte(' '); c <= '~'; c++ {
+		pattern = append(pattern, c)
+	}
+	return pattern
+}()
+
+// chargenLine returns the n-th line of the RFC 864 rotating character pattern, terminated by CRLF
+func chargenLine(n int) []byte {
+	line := make([]byte, 0, chargenLineLength+2)
+	start := n % len(chargenPattern)
+	for i := 0; i < chargenLineLength; i++ {
+		line = append(line, chargenPattern[(start+i)%len(chargenPattern)])
+	}
+	return append(line, '\r', '\n')
+}
+
+// chargenData returns size bytes of the chargen line pattern
+func chargenData(size int) []byte {
+	data := make([]byte, 0, size+chargenLineLength+2)
+	for n := 0; len(data) < size; n++ {
+		data = append(data, chargenLine(n)...)
+	}
+	return data[:size]
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlersImplementServiceInterfaces(t *testing.T) {
+	var _ service.ConnHandler = NewTCPHandler(nil)
+	var _ service.PacketHandler = NewUDPHandler(nil)
+	var _ service.Metrics = metrics.NewMetricsCollector()
+}
+
+func TestChargenLine(t *testing.T) {
+	line := chargenLine(0)
+	assert.Len(t, line, chargenLineLength+2)
+	assert.Equal(t, byte(' '), line[0])
+	assert.Equal(t, "\r\n", string(line[chargenLineLength:]))
+
+	// Each line starts one character further into the pattern
+	assert.Equal(t, byte('!'), chargenLine(1)[0])
+	assert.Equal(t, chargenLine(0), chargenLine(len(chargenPattern)))
+}
+
+func TestChargenData(t *testing.T) {
+	assert.Empty(t, chargenData(0))
+	data := chargenData(200)
+	assert.Len(t, data, 200)
+	assert.Equal(t, chargenLine(0), data[:chargenLineLength+2])
+}
+
+func TestTCPHandlerDiscardMode(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	handler := NewTCPServiceHandler(mc, ModeDiscard)
+	assert.Equal(t, ModeDiscard, handler.Mode())
+
+	conn := newMockConn()
+	conn.writeToReadBuf([]byte("throw this away"))
+
+	handler.Handle(conn)
+
+	assert.Empty(t, conn.getWrittenData())
+	assert.True(t, conn.isClosed())
+}
+
+func TestTCPHandlerSinkMode(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	handler := NewTCPServiceHandler(mc, ModeSink)
+
+	serverConn, clientConn := net.Pipe()
+	done := make(chan bool)
+	go func() {
+		handler.Handle(&pipeConn{Conn: serverConn})
+		done <- true
+	}()
+
+	// A pipe write only returns once the handler read all of it
+	data := make([]byte, sinkReadBufferSize)
+	n, err := clientConn.Write(data)
+	require.NoError(t, err)
+	assert.Equal(t, sinkReadBufferSize, n)
+	_ = clientConn.Close()
+
+	select {
+	case <-done:
+	case <-time.After(1 * time.Second):
+		t.Fatal("Sink handler did not stop after client disconnect")
+	}
+}
+
+func TestReadBufferSize(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	tests := []struct {
+		name    string
+		mode    ServiceMode
+		size    int
+		wantTCP int
+		wantUDP int
+	}{
+		{"echo default", ModeEcho, 0, defaultTCPReadBufferSize, defaultUDPReadBufferSize},
+		{"discard default", ModeDiscard, 0, defaultTCPReadBufferSize, defaultUDPReadBufferSize},
+		{"sink default", ModeSink, 0, sinkReadBufferSize, defaultUDPReadBufferSize},
+		{"echo configured", ModeEcho, 16384, 16384, 16384},
+		// Sink mode reads at least 64 KiB
+		{"sink configured smaller", ModeSink, 4096, sinkReadBufferSize, 4096},
+		{"sink configured larger", ModeSink, 1 << 20, 1 << 20, 1 << 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tcp := NewTCPServiceHandler(mc, tt.mode)
+			tcp.SetReadBufferSize(tt.size)
+			assert.Equal(t, tt.wantTCP, tcp.readBufferSize())
+			udp := NewUDPServiceHandler(mc, tt.mode)
+			udp.SetReadBufferSize(tt.size)
+			assert.Equal(t, tt.wantUDP, udp.readBufferSize())
+		})
+	}
+}
+
+func TestTCPHandlerReadBufferSize(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	handler := NewTCPHandler(mc)
+	handler.SetReadBufferSize(8192)
+
+	serverConn, clientConn := net.Pipe()
+	go handler.Handle(&pipeConn{Conn: serverConn})
+	defer func() { _ = clientConn.Close() }()
+
+	// A pipe write returns once the handler read all of it, the echo comes back in a single write
+	_ = clientConn.SetDeadline(time.Now().Add(time.Second))
+	go func() { _, _ = clientConn.Write(make([]byte, 8192)) }()
+	n, err := clientConn.Read(make([]byte, 16384))
+	require.NoError(t, err)
+	assert.Equal(t, 8192, n)
+}
+
+func TestTCPHandlerChargenMode(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	handler := NewTCPServiceHandler(mc, ModeChargen)
+
+	serverConn, clientConn := net.Pipe()
+	done := make(chan bool)
+	go func() {
+		handler.Handle(&pipeConn{Conn: serverConn})
+		done <- true
+	}()
+
+	buf := make([]byte, chargenLineLength+2)
+	_ = clientConn.SetReadDeadline(time.Now().Add(1 * time.Second))
+	_, err := io.ReadFull(clientConn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, chargenLine(0), buf)
+
+	_ = clientConn.Close()
+
+	select {
+	case <-done:
+	case <-time.After(1 * time.Second):
+		t.Fatal("Chargen handler did not stop after client disconnect")
+	}
+}
+
+func TestUDPHandlerServiceModes(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      ServiceMode
+		wantReply bool
+	}{
+		{"discard", ModeDiscard, false},
+		{"sink", ModeSink, false},
+		{"chargen", ModeChargen, true},
+		{"generator", ModeGenerator, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mc := metrics.NewMetricsCollector()
+			handler := NewUDPServiceHandler(mc, tt.mode)
+
+			conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			require.NoError(t, err)
+			defer func() { _ = conn.Close() }()
+			go handler.Handle(conn)
+
+			clientConn, err := net.Dial("udp", conn.LocalAddr().String())
+			require.NoError(t, err)
+			defer func() { _ = clientConn.Close() }()
+
+			_, err = clientConn.Write([]byte("ping"))
+			require.NoError(t, err)
+
+			buf := make([]byte, 1024)
+			_ = clientConn.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+			n, err := clientConn.Read(buf)
+			if tt.wantReply {
+				require.NoError(t, err)
+				assert.LessOrEqual(t, n, chargenMaxDatagram)
+				assert.Equal(t, chargenData(n), buf[:n])
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+// pipeConn wraps a net.Pipe end with TCP addresses so handlers can resolve the local port
+type pipeConn struct {
+	net.Conn
+}
+
+func (p *pipeConn) LocalAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 19}
+}
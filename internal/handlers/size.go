@@ -0,0 +1,30 @@
+package handlers
+
+// SetResponseSize makes the handler size every echo response with size, which returns the length of the
+// response to a request of n bytes. Longer responses repeat the request, shorter ones cut it. A request read
+// in several chunks is sized chunk by chunk.
+func (h *TCPHandler) SetResponseSize(size func(n int) int) {
+	h.size = size
+}
+
+// SetResponseSize makes the handler size every reply with size, which returns the length of the reply to a
+// datagram of n bytes. Longer replies repeat the datagram, shorter ones cut it.
+func (h *UDPHandler) SetResponseSize(size func(n int) int) {
+	h.size = size
+}
+
+// resize returns the response sized by size, or data as is without a size
+func resize(size func(n int) int, data []byte) []byte {
+	if size == nil || len(data) == 0 {
+		return data
+	}
+	n := size(len(data))
+	if n <= len(data) {
+		return data[:n]
+	}
+	resized := make([]byte, n)
+	for i := 0; i < n; i += len(data) {
+		copy(resized[i:], data)
+	}
+	return resized
+}
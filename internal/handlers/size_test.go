@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResize(t *testing.T) {
+	data := []byte("abc")
+	assert.Equal(t, data, resize(nil, data))
+	assert.Equal(t, []byte("ab"), resize(func(int) int { return 2 }, data))
+	assert.Equal(t, []byte("abcabcabc"), resize(func(n int) int { return 3 * n }, data))
+	assert.Equal(t, []byte("abcabcab"), resize(func(int) int { return 8 }, data))
+	assert.Empty(t, resize(func(int) int { return 8 }, nil))
+}
+
+func TestTCPHandlerResponseSize(t *testing.T) {
+	handler := NewTCPHandler(metrics.NewMetricsCollector())
+	handler.SetResponseSize(func(n int) int { return 2 * n })
+
+	client, server := net.Pipe()
+	defer func() { _ = client.Close() }()
+	go handler.Handle(&pipeConn{Conn: server})
+
+	_ = client.SetDeadline(time.Now().Add(5 * time.Second))
+	_, err := client.Write([]byte("ping"))
+	require.NoError(t, err)
+	buf := make([]byte, 8)
+	_, err = io.ReadFull(client, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "pingping", string(buf))
+}
+
+func TestUDPHandlerResponseSize(t *testing.T) {
+	handler := NewUDPHandler(metrics.NewMetricsCollector())
+	handler.SetResponseSize(func(int) int { return 2 })
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	go handler.Handle(conn)
+
+	clientConn, err := net.Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer func() { _ = clientConn.Close() }()
+
+	_, err = clientConn.Write([]byte("ping"))
+	require.NoError(t, err)
+	_ = clientConn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 16)
+	n, err := clientConn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "pi", string(buf[:n]))
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
+)
+
+// SetZeroCopy makes the handler echo connections with splice(2) on Linux, so the data is not copied to user
+// space. Only echo responses that are sent back unchanged are spliced, other platforms, TLS connections and
+// ports whose responses are relayed, delayed, dropped or sized keep the copy loop.
+func (h *TCPHandler) SetZeroCopy(enabled bool) {
+	h.zeroCopy = enabled
+}
+
+// splicer returns the splicer echoing a connection in the kernel, or nil if it is echoed by the copy loop
+func (h *TCPHandler) splicer(conn net.Conn) *sockopt.Splicer {
+	if !h.zeroCopy || h.upstream != nil || h.drop > 0 || h.size != nil {
+		return nil
+	}
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return nil
+	}
+	s, err := sockopt.NewSplicer(sc)
+	if err != nil {
+		logging.Logger.Debugf("Echoing TCP connection from %s without zero-copy: %v", conn.RemoteAddr().String(), err)
+		return nil
+	}
+	return s
+}
+
+// splicedEcho echoes the connection with the splicer until the client closes it, and returns the error that
+// ended it
+func (h *TCPHandler) splicedEcho(conn net.Conn, s *sockopt.Splicer, protocol, portStr string) error {
+	size := h.readBufferSize()
+	for {
+		n, err := s.Receive(size)
+		if err != nil {
+			if err != io.EOF {
+				logging.Logger.Debugf("TCP connection from %s closed: %v", conn.RemoteAddr().String(), err)
+			}
+			return err
+		}
+		readDone := time.Now()
+		h.metricsCollector.AddBytesReceived(protocol, portStr, n)
+
+		n, err = s.Send()
+		h.metricsCollector.AddBytesSent(protocol, portStr, n)
+		if err != nil {
+			logging.Logger.Debugf("Failed to write to TCP connection from %s: %v", conn.RemoteAddr().String(), err)
+			return err
+		}
+		h.metricsCollector.ObserveEchoDelay(protocol, portStr, time.Since(readDone))
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// acceptedConn returns the server side of a loopback TCP connection
+func acceptedConn(t *testing.T) net.Conn {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+	client, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestTCPHandlerSplicer(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("zero-copy echo requires Linux")
+	}
+	mc := metrics.NewMetricsCollector()
+	conn := acceptedConn(t)
+
+	h := NewTCPHandler(mc)
+	h.SetZeroCopy(true)
+	s := h.splicer(conn)
+	require.NotNil(t, s)
+	require.NoError(t, s.Close())
+
+	tests := []struct {
+		name  string
+		setup func(h *TCPHandler)
+	}{
+		{"zero-copy disabled", func(h *TCPHandler) { h.SetZeroCopy(false) }},
+		{"dropped responses", func(h *TCPHandler) { h.SetResponseDrop(5) }},
+		{"sized responses", func(h *TCPHandler) { h.SetResponseSize(func(n int) int { return n * 2 }) }},
+		{"relayed responses", func(h *TCPHandler) { h.SetUpstream(NewUpstream([]string{"127.0.0.1:1"}, 1, 1, time.Second)) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewTCPHandler(mc)
+			h.SetZeroCopy(true)
+			tt.setup(h)
+			assert.Nil(t, h.splicer(conn))
+		})
+	}
+
+	// Connections without a socket, like TLS connections, keep the copy loop
+	assert.Nil(t, h.splicer(newMockConn()))
+}
+
+func TestTCPHandlerZeroCopy(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("zero-copy echo requires Linux")
+	}
+	mc := metrics.NewMetricsCollector()
+	handler := NewTCPHandler(mc)
+	handler.SetZeroCopy(true)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		handler.Handle(conn)
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+	payload := bytes.Repeat([]byte("0123456789"), 20000)
+	go func() { _, _ = conn.Write(payload) }()
+	got := make([]byte, len(payload))
+	_, err = io.ReadFull(conn, got)
+	require.NoError(t, err)
+	assert.Equal(t, payload, got)
+
+	require.NoError(t, conn.(*net.TCPConn).CloseWrite())
+	<-done
+	port := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+	assert.Equal(t, float64(len(payload)), testutil.ToFloat64(mc.BytesReceived.WithLabelValues("tcp", port)))
+	assert.Equal(t, float64(len(payload)), testutil.ToFloat64(mc.BytesSent.WithLabelValues("tcp", port)))
+}
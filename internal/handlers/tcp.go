@@ -4,7 +4,10 @@ import (
 	"io"
 	"net"
 	"strconv"
+	"time"
 
+	"github.com/PhilipSchmid/flow-generator-app/internal/bufpool"
+	"github.com/PhilipSchmid/flow-generator-app/internal/flowheader"
 	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
 	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
 )
@@ -12,21 +15,58 @@ import (
 // TCPHandler handles TCP connections
 type TCPHandler struct {
 	metricsCollector *metrics.MetricsCollector
+	mode             ServiceMode
+	upstream         *Upstream
+	duplicates       *DuplicateDetector
+	delay            responseDelay
+	drop             responseDrop
+	size             func(n int) int
+	// rate is the bits per second generator mode streams at, 0 streams as fast as the client reads
+	rate float64
+	// readSize is the size of the reads of the connections, 0 for the default
+	readSize int
+	// zeroCopy echoes connections with splice where possible
+	zeroCopy bool
+	// echoHeaders are the request headers HTTP mode reflects in its responses
+	echoHeaders []string
+	paths       httpPaths
 }
 
-// NewTCPHandler creates a new TCP handler
+// NewTCPHandler creates a new TCP echo handler
 func NewTCPHandler(mc *metrics.MetricsCollector) *TCPHandler {
+	return NewTCPServiceHandler(mc, ModeEcho)
+}
+
+// NewTCPServiceHandler creates a new TCP handler following the given service semantics
+func NewTCPServiceHandler(mc *metrics.MetricsCollector, mode ServiceMode) *TCPHandler {
 	return &TCPHandler{
 		metricsCollector: mc,
+		mode:             mode,
 	}
 }
 
+// Mode returns the service mode of the handler
+func (h *TCPHandler) Mode() ServiceMode {
+	return h.mode
+}
+
+// SetUpstream makes the handler relay a fraction of echo requests to upstream servers
+func (h *TCPHandler) SetUpstream(u *Upstream) {
+	h.upstream = u
+}
+
+// SetDuplicateDetector makes the handler check the flow header at the start of echoed connections for
+// duplicate flows
+func (h *TCPHandler) SetDuplicateDetector(d *DuplicateDetector) {
+	h.duplicates = d
+}
+
 // Handle processes a TCP connection
 func (h *TCPHandler) Handle(conn net.Conn) {
 	defer func() { _ = conn.Close() }()
 
-	h.metricsCollector.ActiveTCPConnections.Inc()
-	defer h.metricsCollector.ActiveTCPConnections.Dec()
+	h.metricsCollector.IncActiveTCPConnections()
+	defer h.metricsCollector.DecActiveTCPConnections()
 
 	port := conn.LocalAddr().(*net.TCPAddr).Port
 	portStr := strconv.Itoa(port)
@@ -34,25 +74,146 @@ func (h *TCPHandler) Handle(conn net.Conn) {
 
 	h.metricsCollector.IncRequestsReceived(protocol, portStr)
 	h.metricsCollector.TCPConnectionsOpenedPerSecond.Inc()
+	h.metricsCollector.ObserveFlowStarted()
 
 	logging.Logger.Debugf("Accepted TCP connection on %s from %s", conn.LocalAddr().String(), conn.RemoteAddr().String())
 
-	buf := make([]byte, 1024)
-	for {
+	var err error
+	switch h.mode {
+	case ModeDiscard, ModeSink:
+		err = h.discard(conn, protocol, portStr)
+	case ModeChargen:
+		err = h.chargen(conn, protocol, portStr)
+	case ModeGenerator:
+		err = h.generate(conn, protocol, portStr)
+	case ModeRelay:
+		err = h.relay(conn, protocol, portStr)
+	case ModeHTTP:
+		err = h.httpEcho(conn, protocol, portStr)
+	default:
+		if h.delay.enabled() {
+			err = h.delayedEcho(conn, protocol, portStr)
+		} else {
+			err = h.echo(conn, protocol, portStr)
+		}
+	}
+	h.metricsCollector.IncConnectionsClosed(CloseReason(err))
+}
+
+// echo sends back any data received until the client closes the connection, and returns the error that ended it.
+// With zero-copy, only the first read, which may carry the flow header, is copied.
+func (h *TCPHandler) echo(conn net.Conn, protocol, portStr string) error {
+	splicer := h.splicer(conn)
+	if splicer != nil {
+		defer func() { _ = splicer.Close() }()
+	}
+	bp := bufpool.Get(h.readBufferSize())
+	defer bufpool.Put(bp)
+	buf := *bp
+	for first := true; ; first = false {
 		n, err := conn.Read(buf)
 		if err != nil {
 			if err != io.EOF {
 				logging.Logger.Debugf("TCP connection from %s closed: %v", conn.RemoteAddr().String(), err)
 			}
-			return
+			return err
 		}
+		readDone := time.Now()
 		h.metricsCollector.AddBytesReceived(protocol, portStr, n)
+		if first && h.duplicates != nil {
+			h.checkDuplicate(conn, buf[:n], protocol, portStr, readDone)
+		}
+		if h.drop.hit() {
+			h.metricsCollector.IncResponsesDropped(protocol, portStr)
+			continue
+		}
 
-		n, err = conn.Write(buf[:n])
+		n, err = conn.Write(resize(h.size, h.upstream.respond(h.metricsCollector, protocol, portStr, buf[:n])))
 		if err != nil {
 			logging.Logger.Debugf("Failed to write to TCP connection from %s: %v", conn.RemoteAddr().String(), err)
-			return
+			return err
 		}
+		h.metricsCollector.ObserveEchoDelay(protocol, portStr, time.Since(readDone))
 		h.metricsCollector.AddBytesSent(protocol, portStr, n)
+		if splicer != nil {
+			return h.splicedEcho(conn, splicer, protocol, portStr)
+		}
+	}
+}
+
+// discard reads and throws away any data received until the client closes the connection, and returns
+// the error that ended it
+func (h *TCPHandler) discard(conn net.Conn, protocol, portStr string) error {
+	bp := bufpool.Get(h.readBufferSize())
+	defer bufpool.Put(bp)
+	buf := *bp
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			if err != io.EOF {
+				logging.Logger.Debugf("TCP connection from %s closed: %v", conn.RemoteAddr().String(), err)
+			}
+			return err
+		}
+		h.metricsCollector.AddBytesReceived(protocol, portStr, n)
+	}
+}
+
+// chargen streams the rotating character pattern until the client closes the connection, and returns
+// the error that ended it. Any data sent by the client is read and discarded.
+func (h *TCPHandler) chargen(conn net.Conn, protocol, portStr string) error {
+	readErr := h.drainInput(conn, protocol, portStr)
+	for line := 0; ; line++ {
+		n, err := conn.Write(chargenLine(line))
+		if err != nil {
+			logging.Logger.Debugf("Chargen stream to %s ended: %v", conn.RemoteAddr().String(), err)
+			return streamEnd(err, readErr)
+		}
+		h.metricsCollector.AddBytesSent(protocol, portStr, n)
+	}
+}
+
+// drainInput reads and discards any data sent by the client of a stream in the background. It closes the
+// connection once the client goes away, which unblocks the writer, and sends the error that ended reading.
+func (h *TCPHandler) drainInput(conn net.Conn, protocol, portStr string) <-chan error {
+	readErr := make(chan error, 1)
+	go func() {
+		bp := bufpool.Get(h.readBufferSize())
+		defer bufpool.Put(bp)
+		buf := *bp
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				readErr <- err
+				_ = conn.Close()
+				return
+			}
+			h.metricsCollector.AddBytesReceived(protocol, portStr, n)
+		}
+	}()
+	return readErr
+}
+
+// streamEnd returns the error that ended a stream whose write failed with err. How the client went away is
+// only known if the reader saw it first.
+func streamEnd(err error, readErr <-chan error) error {
+	select {
+	case err = <-readErr:
+	default:
+	}
+	return err
+}
+
+// checkDuplicate counts the connection as a duplicate flow if it starts with the flow header of a flow seen
+// on another connection. Connections are told apart by the client address, which differs for every
+// connection of a client.
+func (h *TCPHandler) checkDuplicate(conn net.Conn, data []byte, protocol, portStr string, now time.Time) {
+	header, ok := flowheader.Parse(data)
+	if !ok {
+		return
+	}
+	if duplicate, _ := h.duplicates.Observe(header, conn.RemoteAddr().String(), now); duplicate {
+		h.metricsCollector.IncDuplicateFlows(protocol, portStr)
+		logging.Logger.Debugf("Duplicate flow %d of run %x from %s", header.FlowID, header.RunID, conn.RemoteAddr().String())
 	}
 }
@@ -9,6 +9,7 @@ import (
 
 	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
 	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -165,6 +166,21 @@ func TestTCPHandlerMetrics(t *testing.T) {
 	assert.True(t, conn.isClosed())
 }
 
+func TestTCPHandlerEchoDelay(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	handler := NewTCPHandler(mc)
+
+	// A port no other test uses, so the echo delays form a new series
+	conn := newMockConn()
+	conn.localAddr = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 18443}
+	conn.writeToReadBuf([]byte("test data"))
+	series := testutil.CollectAndCount(mc.EchoDelay)
+
+	handler.Handle(conn)
+
+	assert.Equal(t, series+1, testutil.CollectAndCount(mc.EchoDelay))
+}
+
 func BenchmarkTCPHandler(b *testing.B) {
 	mc := metrics.NewMetricsCollector()
 	handler := NewTCPHandler(mc)
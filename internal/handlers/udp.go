@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"math/rand/v2"
 	"net"
 	"strconv"
+	"time"
 
+	"github.com/PhilipSchmid/flow-generator-app/internal/bufpool"
+	"github.com/PhilipSchmid/flow-generator-app/internal/flowheader"
 	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
 	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
 )
@@ -11,18 +15,68 @@ import (
 // UDPHandler handles UDP packets
 type UDPHandler struct {
 	metricsCollector *metrics.MetricsCollector
+	mode             ServiceMode
+	peerIdleTimeout  time.Duration
+	upstream         *Upstream
+	duplicates       *DuplicateDetector
+	delay            responseDelay
+	drop             responseDrop
+	size             func(n int) int
+	// readSize is the size of the reads of the socket, 0 for the default
+	readSize int
 }
 
-// NewUDPHandler creates a new UDP handler
+// NewUDPHandler creates a new UDP echo handler
 func NewUDPHandler(mc *metrics.MetricsCollector) *UDPHandler {
+	return NewUDPServiceHandler(mc, ModeEcho)
+}
+
+// NewUDPServiceHandler creates a new UDP handler following the given service semantics
+func NewUDPServiceHandler(mc *metrics.MetricsCollector, mode ServiceMode) *UDPHandler {
 	return &UDPHandler{
 		metricsCollector: mc,
+		mode:             mode,
 	}
 }
 
+// Mode returns the service mode of the handler
+func (h *UDPHandler) Mode() ServiceMode {
+	return h.mode
+}
+
+// SetUpstream makes the handler relay a fraction of echo requests to upstream servers
+func (h *UDPHandler) SetUpstream(u *Upstream) {
+	h.upstream = u
+}
+
+// SetDuplicateDetector makes the handler check the flow header of echoed datagrams for duplicate flows and
+// replayed datagrams, which are not echoed
+func (h *UDPHandler) SetDuplicateDetector(d *DuplicateDetector) {
+	h.duplicates = d
+}
+
+// EnableConnectedPeers makes the handler serve each peer over a dedicated connected socket,
+// which is closed after the peer has been idle for the given timeout
+func (h *UDPHandler) EnableConnectedPeers(idleTimeout time.Duration) {
+	h.peerIdleTimeout = idleTimeout
+}
+
+// ConnectedPeers reports whether peers are served over dedicated connected sockets.
+// The listening socket must then allow port reuse.
+func (h *UDPHandler) ConnectedPeers() bool {
+	return h.peerIdleTimeout > 0
+}
+
 // Handle processes UDP packets on the given connection
 func (h *UDPHandler) Handle(conn *net.UDPConn) {
-	buf := make([]byte, 1024)
+	if h.ConnectedPeers() {
+		h.handleConnectedPeers(conn)
+		return
+	}
+
+	bp := bufpool.Get(h.readBufferSize())
+	defer bufpool.Put(bp)
+	buf := *bp
 	for {
 		n, addr, err := conn.ReadFromUDP(buf)
 		if err != nil {
@@ -30,21 +84,72 @@ func (h *UDPHandler) Handle(conn *net.UDPConn) {
 			return
 		}
 
+		readDone := time.Now()
 		port := conn.LocalAddr().(*net.UDPAddr).Port
 		portStr := strconv.Itoa(port)
-		protocol := "udp"
-
-		h.metricsCollector.IncRequestsReceived(protocol, portStr)
-		h.metricsCollector.UDPPacketsReceived.Inc()
-		h.metricsCollector.AddBytesReceived(protocol, portStr, n)
 
 		logging.Logger.Debugf("Received UDP packet from %s", addr.String())
 
-		n, err = conn.WriteToUDP(buf[:n], addr)
+		reply := h.reply(portStr, addr.String(), buf[:n])
+		if reply == nil {
+			continue
+		}
+		if h.delay.enabled() {
+			h.sendDelayed(portStr, addr.String(), reply, readDone, func(b []byte) (int, error) { return conn.WriteToUDP(b, addr) })
+			continue
+		}
+
+		n, err = conn.WriteToUDP(reply, addr)
 		if err != nil {
 			logging.Logger.Debugf("Failed to write UDP packet to %s: %v", addr.String(), err)
 			continue
 		}
-		h.metricsCollector.AddBytesSent(protocol, portStr, n)
+		h.metricsCollector.ObserveEchoDelay("udp", portStr, time.Since(readDone))
+		h.metricsCollector.AddBytesSent("udp", portStr, n)
+	}
+}
+
+// reply records metrics for a received packet from peer and returns the response to send, or nil if none
+func (h *UDPHandler) reply(portStr, peer string, data []byte) []byte {
+	protocol := "udp"
+	h.metricsCollector.IncRequestsReceived(protocol, portStr)
+	h.metricsCollector.UDPPacketsReceived.Inc()
+	h.metricsCollector.AddBytesReceived(protocol, portStr, len(data))
+
+	switch h.mode {
+	case ModeDiscard, ModeSink:
+		return nil
+	case ModeChargen, ModeGenerator:
+		// Without a connection to stream over, generator mode replies to every datagram like chargen
+		// #nosec G404 - math/rand is sufficient for chargen reply sizes
+		return chargenData(rand.IntN(chargenMaxDatagram + 1))
+	default:
+		if h.duplicates != nil && h.replayed(portStr, peer, data) {
+			return nil
+		}
+		if h.drop.hit() {
+			h.metricsCollector.IncResponsesDropped(protocol, portStr)
+			return nil
+		}
+		return resize(h.size, h.upstream.respond(h.metricsCollector, protocol, portStr, data))
+	}
+}
+
+// replayed checks the flow header of a datagram from peer. It counts datagrams of flows seen from another
+// peer before as a duplicate flow, and reports whether the datagram is a replay of one already received.
+func (h *UDPHandler) replayed(portStr, peer string, data []byte) bool {
+	header, ok := flowheader.Parse(data)
+	if !ok {
+		return false
+	}
+	duplicate, replay := h.duplicates.Observe(header, peer, time.Now())
+	if duplicate {
+		h.metricsCollector.IncDuplicateFlows("udp", portStr)
+		logging.Logger.Debugf("Duplicate flow %d of run %x from %s", header.FlowID, header.RunID, peer)
+	}
+	if replay {
+		h.metricsCollector.IncReplayedDatagrams(portStr)
+		logging.Logger.Debugf("Replayed datagram %d of flow %d from %s not echoed", header.Seq, header.FlowID, peer)
 	}
+	return replay
 }
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/bufpool"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
+)
+
+// handleConnectedPeers reads first packets from the listening socket and hands each new peer
+// over to a dedicated socket connected to that peer. The kernel then delivers ICMP errors
+// for the peer and routes its subsequent packets to the more specific connected socket.
+func (h *UDPHandler) handleConnectedPeers(conn *net.UDPConn) {
+	localAddr := conn.LocalAddr().(*net.UDPAddr)
+	portStr := strconv.Itoa(localAddr.Port)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	peers := make(map[string]*net.UDPConn)
+
+	defer func() {
+		mu.Lock()
+		for _, peer := range peers {
+			_ = peer.Close()
+		}
+		mu.Unlock()
+		wg.Wait()
+	}()
+
+	bp := bufpool.Get(h.readBufferSize())
+	defer bufpool.Put(bp)
+	buf := *bp
+	for {
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			logging.Logger.Infof("UDP connection closed: %v", err)
+			return
+		}
+		readDone := time.Now()
+
+		key := addr.String()
+		mu.Lock()
+		peer, known := peers[key]
+		if !known {
+			peer, err = dialPeer(localAddr, addr)
+			if err != nil {
+				logging.Logger.Warnf("Failed to create connected UDP socket for %s, replying unconnected: %v", key, err)
+			} else {
+				peers[key] = peer
+				h.metricsCollector.UDPConnectedPeers.Inc()
+				logging.Logger.Debugf("Serving UDP peer %s over a connected socket", key)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					h.servePeer(peer, portStr)
+					mu.Lock()
+					delete(peers, key)
+					mu.Unlock()
+					h.metricsCollector.UDPConnectedPeers.Dec()
+				}()
+			}
+		}
+		mu.Unlock()
+
+		// Packets that raced the peer socket setup still arrive on the listening socket
+		reply := h.reply(portStr, key, buf[:n])
+		if reply == nil {
+			continue
+		}
+		if h.delay.enabled() {
+			write := func(b []byte) (int, error) { return conn.WriteToUDP(b, addr) }
+			if peer != nil {
+				write = peer.Write
+			}
+			h.sendDelayed(portStr, key, reply, readDone, write)
+			continue
+		}
+		if peer != nil {
+			n, err = peer.Write(reply)
+		} else {
+			n, err = conn.WriteToUDP(reply, addr)
+		}
+		if err != nil {
+			logging.Logger.Debugf("Failed to write UDP packet to %s: %v", key, err)
+			continue
+		}
+		h.metricsCollector.ObserveEchoDelay("udp", portStr, time.Since(readDone))
+		h.metricsCollector.AddBytesSent("udp", portStr, n)
+	}
+}
+
+// servePeer handles packets of a single peer on its connected socket until it goes idle or is closed
+func (h *UDPHandler) servePeer(peer *net.UDPConn, portStr string) {
+	defer func() { _ = peer.Close() }()
+
+	bp := bufpool.Get(h.readBufferSize())
+	defer bufpool.Put(bp)
+	buf := *bp
+	for {
+		_ = peer.SetReadDeadline(time.Now().Add(h.peerIdleTimeout))
+		n, err := peer.Read(buf)
+		if err != nil {
+			var netErr net.Error
+			switch {
+			case errors.Is(err, syscall.ECONNREFUSED):
+				// ICMP port unreachable for an earlier reply, the peer socket stays usable
+				h.metricsCollector.UDPPeerICMPErrors.Inc()
+				logging.Logger.Debugf("ICMP error from UDP peer %s: %v", peer.RemoteAddr(), err)
+				continue
+			case errors.As(err, &netErr) && netErr.Timeout():
+				logging.Logger.Debugf("UDP peer %s idle, closing connected socket", peer.RemoteAddr())
+			default:
+				logging.Logger.Debugf("Connected UDP socket for %s closed: %v", peer.RemoteAddr(), err)
+			}
+			return
+		}
+		readDone := time.Now()
+
+		reply := h.reply(portStr, peer.RemoteAddr().String(), buf[:n])
+		if reply == nil {
+			continue
+		}
+		if h.delay.enabled() {
+			h.sendDelayed(portStr, peer.RemoteAddr().String(), reply, readDone, peer.Write)
+			continue
+		}
+		n, err = peer.Write(reply)
+		if err != nil {
+			if errors.Is(err, syscall.ECONNREFUSED) {
+				h.metricsCollector.UDPPeerICMPErrors.Inc()
+			}
+			logging.Logger.Debugf("Failed to write UDP packet to %s: %v", peer.RemoteAddr(), err)
+			continue
+		}
+		h.metricsCollector.ObserveEchoDelay("udp", portStr, time.Since(readDone))
+		h.metricsCollector.AddBytesSent("udp", portStr, n)
+	}
+}
+
+// dialPeer creates a UDP socket bound to the listening port and connected to the given peer
+func dialPeer(localAddr, peerAddr *net.UDPAddr) (*net.UDPConn, error) {
+	dialer := net.Dialer{
+		LocalAddr: &net.UDPAddr{IP: localAddr.IP, Port: localAddr.Port},
+		Control:   sockopt.ReusePort,
+	}
+	conn, err := dialer.Dial("udp", peerAddr.String())
+	if err != nil {
+		return nil, err
+	}
+	return conn.(*net.UDPConn), nil
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUDPHandlerConnectedPeers(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("connected UDP peer sockets are only tested on Linux")
+	}
+
+	mc := metrics.NewMetricsCollector()
+	handler := NewUDPHandler(mc)
+	assert.False(t, handler.ConnectedPeers())
+	handler.EnableConnectedPeers(200 * time.Millisecond)
+	assert.True(t, handler.ConnectedPeers())
+
+	lc := net.ListenConfig{Control: sockopt.ReusePort}
+	pc, err := lc.ListenPacket(context.Background(), "udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	conn := pc.(*net.UDPConn)
+
+	done := make(chan bool)
+	go func() {
+		handler.Handle(conn)
+		done <- true
+	}()
+
+	clientConn, err := net.Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer func() { _ = clientConn.Close() }()
+
+	// Both the first packet (listening socket) and later ones (connected socket) are echoed
+	for _, msg := range []string{"first", "second", "third"} {
+		_, err = clientConn.Write([]byte(msg))
+		require.NoError(t, err)
+
+		buf := make([]byte, 1024)
+		_ = clientConn.SetReadDeadline(time.Now().Add(1 * time.Second))
+		n, err := clientConn.Read(buf)
+		require.NoError(t, err)
+		assert.Equal(t, msg, string(buf[:n]))
+	}
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.UDPConnectedPeers))
+
+	_ = conn.Close()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Handler did not stop after listener close")
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
 	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -150,3 +151,29 @@ func BenchmarkUDPHandler(b *testing.B) {
 		}
 	}
 }
+
+func TestUDPHandlerEchoDelay(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	handler := NewUDPHandler(mc)
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	go handler.Handle(conn)
+
+	// The listener has its own port, so its echo delays form a new series
+	series := testutil.CollectAndCount(mc.EchoDelay)
+
+	clientConn, err := net.Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer func() { _ = clientConn.Close() }()
+	_, err = clientConn.Write([]byte("ping"))
+	require.NoError(t, err)
+	_ = clientConn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = clientConn.Read(make([]byte, 16))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return testutil.CollectAndCount(mc.EchoDelay) == series+1
+	}, time.Second, 10*time.Millisecond)
+}
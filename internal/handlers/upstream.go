@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+)
+
+// Upstream relays a fraction of echo requests to other echo servers before the handler
+// responds, simulating a service that calls further services to answer a request
+type Upstream struct {
+	servers  []string
+	fraction float64
+	depth    int
+	timeout  time.Duration
+	next     atomic.Uint64
+}
+
+// NewUpstream creates an upstream relay. Each relayed request passes through depth upstream
+// calls in sequence, picked round-robin from servers on the same protocol and port.
+func NewUpstream(servers []string, fraction float64, depth int, timeout time.Duration) *Upstream {
+	return &Upstream{
+		servers:  servers,
+		fraction: fraction,
+		depth:    depth,
+		timeout:  timeout,
+	}
+}
+
+// shouldRelay reports whether the next request is relayed upstream
+func (u *Upstream) shouldRelay() bool {
+	if u == nil || len(u.servers) == 0 {
+		return false
+	}
+	// #nosec G404 - math/rand is sufficient for request sampling
+	return rand.Float64() < u.fraction
+}
+
+// respond returns the response for an echo request, relaying it upstream if sampled.
+// If the upstream calls fail, the request is echoed locally.
+func (u *Upstream) respond(mc *metrics.MetricsCollector, protocol, port string, data []byte) []byte {
+	if !u.shouldRelay() {
+		return data
+	}
+	reply, err := u.relay(protocol, port, data)
+	if err != nil {
+		logging.Logger.Debugf("Falling back to local echo: %v", err)
+		mc.IncUpstreamErrors()
+		return data
+	}
+	mc.IncUpstreamRequests(protocol, port)
+	return reply
+}
+
+// relay sends data through the upstream calls and returns the final response
+func (u *Upstream) relay(protocol, port string, data []byte) ([]byte, error) {
+	start := u.next.Add(uint64(u.depth)) - uint64(u.depth)
+	for i := 0; i < u.depth; i++ {
+		server := u.servers[(start+uint64(i))%uint64(len(u.servers))]
+		reply, err := u.call(protocol, net.JoinHostPort(server, port), data)
+		if err != nil {
+			return nil, fmt.Errorf("upstream call to %s failed: %w", server, err)
+		}
+		data = reply
+	}
+	return data, nil
+}
+
+// call performs a single request/response exchange with an upstream echo server
+func (u *Upstream) call(protocol, addr string, data []byte) ([]byte, error) {
+	conn, err := net.DialTimeout(protocol, addr, u.timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if err := conn.SetDeadline(time.Now().Add(u.timeout)); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(data); err != nil {
+		return nil, err
+	}
+
+	reply := make([]byte, len(data))
+	if protocol == "udp" {
+		n, err := conn.Read(reply)
+		if err != nil {
+			return nil, err
+		}
+		return reply[:n], nil
+	}
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
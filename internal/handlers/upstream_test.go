@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startUpstreamTCP starts a TCP server that replies with the received data upper-cased
+func startUpstreamTCP(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(c net.Conn) {
+				defer func() { _ = c.Close() }()
+				buf := make([]byte, 1024)
+				n, err := c.Read(buf)
+				if err != nil {
+					return
+				}
+				for i := range buf[:n] {
+					if buf[i] >= 'a' && buf[i] <= 'z' {
+						buf[i] -= 'a' - 'A'
+					}
+				}
+				_, _ = c.Write(buf[:n])
+			}(conn)
+		}
+	}()
+	return strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+}
+
+func TestUpstreamShouldRelay(t *testing.T) {
+	var nilUpstream *Upstream
+	assert.False(t, nilUpstream.shouldRelay())
+	assert.False(t, NewUpstream(nil, 1, 1, time.Second).shouldRelay())
+	assert.False(t, NewUpstream([]string{"127.0.0.1"}, 0, 1, time.Second).shouldRelay())
+	assert.True(t, NewUpstream([]string{"127.0.0.1"}, 1, 1, time.Second).shouldRelay())
+}
+
+func TestUpstreamRespondTCP(t *testing.T) {
+	port := startUpstreamTCP(t)
+	mc := metrics.NewMetricsCollector()
+	u := NewUpstream([]string{"127.0.0.1"}, 1, 2, time.Second)
+
+	reply := u.respond(mc, "tcp", port, []byte("hello"))
+	assert.Equal(t, "HELLO", string(reply))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.UpstreamRequests.WithLabelValues("tcp", port)))
+}
+
+func TestUpstreamRespondUDP(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	go NewUDPHandler(metrics.NewMetricsCollector()).Handle(conn)
+
+	port := strconv.Itoa(conn.LocalAddr().(*net.UDPAddr).Port)
+	u := NewUpstream([]string{"127.0.0.1"}, 1, 1, time.Second)
+	reply := u.respond(metrics.NewMetricsCollector(), "udp", port, []byte("ping"))
+	assert.Equal(t, "ping", string(reply))
+}
+
+func TestUpstreamRespondFallback(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+	_ = listener.Close()
+
+	mc := metrics.NewMetricsCollector()
+	failures := testutil.ToFloat64(mc.UpstreamErrors)
+	u := NewUpstream([]string{"127.0.0.1"}, 1, 1, 200*time.Millisecond)
+
+	reply := u.respond(mc, "tcp", port, []byte("hello"))
+	assert.Equal(t, "hello", string(reply))
+	assert.Equal(t, failures+1, testutil.ToFloat64(mc.UpstreamErrors))
+}
+
+func TestTCPHandlerUpstream(t *testing.T) {
+	port := startUpstreamTCP(t)
+	mc := metrics.NewMetricsCollector()
+	handler := NewTCPHandler(mc)
+	handler.SetUpstream(NewUpstream([]string{"127.0.0.1"}, 1, 1, time.Second))
+
+	serverConn, clientConn := net.Pipe()
+	portNum, _ := strconv.Atoi(port)
+	go handler.Handle(&portPipeConn{Conn: serverConn, port: portNum})
+	defer func() { _ = clientConn.Close() }()
+
+	_ = clientConn.SetDeadline(time.Now().Add(1 * time.Second))
+	_, err := clientConn.Write([]byte("relay me"))
+	require.NoError(t, err)
+
+	buf := make([]byte, len("relay me"))
+	_, err = io.ReadFull(clientConn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "RELAY ME", string(buf))
+}
+
+// portPipeConn wraps a net.Pipe end with a TCP local address on the given port
+type portPipeConn struct {
+	net.Conn
+	port int
+}
+
+func (p *portPipeConn) LocalAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: p.port}
+}
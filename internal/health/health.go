@@ -11,9 +11,10 @@ import (
 )
 
 type Checker struct {
-	ready   atomic.Bool
-	healthy atomic.Bool
-	server  *http.Server
+	ready    atomic.Bool
+	healthy  atomic.Bool
+	server   *http.Server
+	handlers map[string]http.Handler
 }
 
 // NewChecker creates a new health checker
@@ -29,6 +30,15 @@ func (c *Checker) SetHealthy(healthy bool) {
 	c.healthy.Store(healthy)
 }
 
+// Handle registers an additional endpoint served alongside the health checks.
+// It must be called before Start.
+func (c *Checker) Handle(pattern string, handler http.Handler) {
+	if c.handlers == nil {
+		c.handlers = make(map[string]http.Handler)
+	}
+	c.handlers[pattern] = handler
+}
+
 // Start starts the health check server on the specified port
 func (c *Checker) Start(port string) error {
 	mux := http.NewServeMux()
@@ -55,6 +65,10 @@ func (c *Checker) Start(port string) error {
 		}
 	})
 
+	for pattern, handler := range c.handlers {
+		mux.Handle(pattern, handler)
+	}
+
 	c.server = &http.Server{
 		Addr:              ":" + port,
 		Handler:           mux,
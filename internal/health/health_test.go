@@ -95,6 +95,27 @@ func TestHealthServerStop(t *testing.T) {
 	assert.False(t, checker.healthy.Load())
 }
 
+func TestHealthServerExtraHandler(t *testing.T) {
+	checker := NewChecker()
+	port := "8084"
+
+	checker.Handle("/backpressure", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("{}"))
+	}))
+	err := checker.Start(port)
+	require.NoError(t, err)
+	defer func() { _ = checker.Stop() }()
+
+	time.Sleep(100 * time.Millisecond)
+
+	resp, err := http.Get("http://localhost:" + port + "/backpressure")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, "{}", string(body))
+}
+
 func TestStopWithoutStart(t *testing.T) {
 	checker := NewChecker()
 
@@ -0,0 +1,204 @@
+package logging
+
+import (
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// Themes of the human log format. ThemeAuto colors the output if it goes to a terminal and NO_COLOR is
+// unset, ThemeClassic is the zap development output.
+const (
+	ThemeAuto    = "auto"
+	ThemeColor   = "color"
+	ThemePlain   = "plain"
+	ThemeClassic = "classic"
+)
+
+// FlowKey and ProtocolKey are the fields identifying the flow an entry is about, which the human format
+// shows in fixed-width columns instead of with the other fields
+const (
+	FlowKey     = "flow"
+	ProtocolKey = "protocol"
+)
+
+// ANSI escape sequences of the colored theme
+const (
+	ansiReset   = "\x1b[0m"
+	ansiDim     = "\x1b[2m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiBlue    = "\x1b[34m"
+	ansiMagenta = "\x1b[35m"
+	ansiCyan    = "\x1b[36m"
+)
+
+// protocolPalette colors the protocols other than tcp and udp, picked by a hash of the protocol name so a
+// protocol keeps its color across runs
+var protocolPalette = []string{"\x1b[94m", "\x1b[95m", "\x1b[96m", "\x1b[92m", "\x1b[93m", "\x1b[91m"}
+
+var consolePool = buffer.NewPool()
+
+// consoleEncoder writes entries as aligned lines: time, level, protocol and flow ID in fixed-width columns,
+// followed by the message and the remaining fields as key=value pairs. Fields added with With are kept in
+// the embedded map encoder.
+type consoleEncoder struct {
+	*zapcore.MapObjectEncoder
+	color bool
+}
+
+// newConsoleEncoder returns a console encoder, coloring the columns if color is set
+func newConsoleEncoder(color bool) *consoleEncoder {
+	return &consoleEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder(), color: color}
+}
+
+// themeColors reports whether the theme colors the output written to f
+func themeColors(theme string, f *os.File) bool {
+	switch theme {
+	case ThemeColor:
+		return true
+	case ThemeAuto:
+		if _, ok := os.LookupEnv("NO_COLOR"); ok {
+			return false
+		}
+		info, err := f.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0
+	}
+	return false
+}
+
+// Clone copies the encoder together with the fields added to it
+func (e *consoleEncoder) Clone() zapcore.Encoder {
+	clone := newConsoleEncoder(e.color)
+	maps.Copy(clone.Fields, e.Fields)
+	return clone
+}
+
+// EncodeEntry writes a single line for the entry, followed by its stack trace if any
+func (e *consoleEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	values := zapcore.NewMapObjectEncoder()
+	maps.Copy(values.Fields, e.Fields)
+	for _, f := range fields {
+		f.AddTo(values)
+	}
+	protocol, _ := values.Fields[ProtocolKey].(string)
+	flow, isFlow := values.Fields[FlowKey]
+	delete(values.Fields, ProtocolKey)
+	delete(values.Fields, FlowKey)
+
+	buf := consolePool.Get()
+	buf.AppendString(ent.Time.Format("01-02 15:04:05.000"))
+	buf.AppendByte(' ')
+	e.appendColored(buf, levelColor(ent.Level), fmt.Sprintf("%-5s", ent.Level.CapitalString()))
+	buf.AppendByte(' ')
+	e.appendColored(buf, protocolColor(protocol), fmt.Sprintf("%-8s", protocol))
+	buf.AppendByte(' ')
+	if isFlow {
+		buf.AppendString(fmt.Sprintf("#%-8v", flow))
+	} else {
+		buf.AppendString(strings.Repeat(" ", 9))
+	}
+	buf.AppendByte(' ')
+	buf.AppendString(ent.Message)
+	for _, key := range slices.Sorted(maps.Keys(values.Fields)) {
+		buf.AppendByte(' ')
+		e.appendColored(buf, ansiDim, key+"="+formatValue(values.Fields[key]))
+	}
+	if ent.Stack != "" {
+		buf.AppendByte('\n')
+		buf.AppendString(ent.Stack)
+	}
+	buf.AppendByte('\n')
+	return buf, nil
+}
+
+// appendColored appends s in color, or plain if the encoder does not color or color is empty
+func (e *consoleEncoder) appendColored(buf *buffer.Buffer, color, s string) {
+	if !e.color || color == "" {
+		buf.AppendString(s)
+		return
+	}
+	buf.AppendString(color)
+	buf.AppendString(s)
+	buf.AppendString(ansiReset)
+}
+
+// levelColor returns the color of a level
+func levelColor(l zapcore.Level) string {
+	switch {
+	case l < zapcore.InfoLevel:
+		return ansiMagenta
+	case l == zapcore.InfoLevel:
+		return ansiBlue
+	case l == zapcore.WarnLevel:
+		return ansiYellow
+	}
+	return ansiRed
+}
+
+// protocolColor returns the color of a protocol, none if the entry is not about a protocol
+func protocolColor(protocol string) string {
+	switch protocol {
+	case "":
+		return ""
+	case "tcp":
+		return ansiCyan
+	case "udp":
+		return ansiGreen
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(protocol))
+	return protocolPalette[h.Sum32()%uint32(len(protocolPalette))]
+}
+
+// formatValue formats a field value, durations compactly and strings quoted if they contain spaces
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case time.Duration:
+		return CompactDuration(v)
+	case string:
+		if strings.ContainsAny(v, " \t\n\"=") {
+			return strconv.Quote(v)
+		}
+		return v
+	}
+	return fmt.Sprint(v)
+}
+
+// CompactDuration formats a duration with up to three significant digits in the largest fitting unit, such
+// as 1.23s, 45.6ms or 789µs, as the human format shows duration fields. Durations of a minute or more are
+// rounded to the second.
+func CompactDuration(d time.Duration) string {
+	var value float64
+	var unit string
+	switch abs := d.Abs(); {
+	case abs >= time.Minute:
+		return d.Round(time.Second).String()
+	case abs >= time.Second:
+		value, unit = d.Seconds(), "s"
+	case abs >= time.Millisecond:
+		value, unit = float64(d)/float64(time.Millisecond), "ms"
+	case abs >= time.Microsecond:
+		value, unit = float64(d)/float64(time.Microsecond), "µs"
+	default:
+		return d.String()
+	}
+	decimals := 0
+	switch v := math.Abs(value); {
+	case v < 10:
+		decimals = 2
+	case v < 100:
+		decimals = 1
+	}
+	return strconv.FormatFloat(value, 'f', decimals, 64) + unit
+}
@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestConsoleEncoder(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 30, 45, 123_000_000, time.UTC)
+	tests := []struct {
+		name   string
+		color  bool
+		level  zapcore.Level
+		fields []zapcore.Field
+		want   string
+	}{
+		{
+			name:   "flow entry",
+			level:  zapcore.WarnLevel,
+			fields: []zapcore.Field{zap.Uint64(FlowKey, 42), zap.String(ProtocolKey, "tcp")},
+			want:   "05-01 12:30:45.123 WARN  tcp      #42       message\n",
+		},
+		{
+			name:   "other entry with fields",
+			level:  zapcore.InfoLevel,
+			fields: []zapcore.Field{zap.Duration("took", 1234567*time.Microsecond), zap.String("peer", "a b"), zap.Error(errors.New("refused"))},
+			want:   "05-01 12:30:45.123 INFO                     message error=refused peer=\"a b\" took=1.23s\n",
+		},
+		{
+			name:   "colored",
+			color:  true,
+			level:  zapcore.ErrorLevel,
+			fields: []zapcore.Field{zap.Uint64(FlowKey, 7), zap.String(ProtocolKey, "udp"), zap.Int("port", 53)},
+			want:   "05-01 12:30:45.123 \x1b[31mERROR\x1b[0m \x1b[32mudp     \x1b[0m #7        message \x1b[2mport=53\x1b[0m\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := newConsoleEncoder(tt.color).EncodeEntry(zapcore.Entry{Level: tt.level, Time: at, Message: "message"}, tt.fields)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestConsoleEncoderWith(t *testing.T) {
+	enc := newConsoleEncoder(false)
+	zap.String(ProtocolKey, "tcp_churn").AddTo(enc)
+	clone := enc.Clone()
+	zap.Uint64(FlowKey, 3).AddTo(clone)
+
+	buf, err := clone.EncodeEntry(zapcore.Entry{Message: "message"}, nil)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "tcp_churn #3        message\n")
+	// Fields added to the clone do not leak into the original
+	buf, err = enc.EncodeEntry(zapcore.Entry{Message: "message"}, nil)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "tcp_churn           message\n")
+}
+
+func TestCompactDuration(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{1234567 * time.Microsecond, "1.23s"},
+		{12345 * time.Millisecond, "12.3s"},
+		{45600 * time.Microsecond, "45.6ms"},
+		{789 * time.Microsecond, "789µs"},
+		{-2 * time.Millisecond, "-2.00ms"},
+		{500, "500ns"},
+		{90500 * time.Millisecond, "1m31s"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, CompactDuration(tt.in))
+	}
+}
+
+func TestThemeColors(t *testing.T) {
+	assert.True(t, themeColors(ThemeColor, nil))
+	assert.False(t, themeColors(ThemePlain, nil))
+	// Test output is not a terminal
+	file, err := os.Create(filepath.Join(t.TempDir(), "log"))
+	require.NoError(t, err)
+	defer func() { _ = file.Close() }()
+	assert.False(t, themeColors(ThemeAuto, file))
+}
@@ -1,8 +1,12 @@
 package logging
 
 import (
+	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"strings"
+	"sync"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -10,6 +14,70 @@ import (
 
 var Logger *zap.SugaredLogger
 
+// Flows logs the details of individual flows. Its entries are written regardless of the log level, as the
+// client selects the flows and details worth logging with its own flow verbosity.
+var Flows *zap.SugaredLogger
+
+// level is shared with the active logger so it can be changed at runtime
+var level = zap.NewAtomicLevel()
+
+// newEncoder creates the encoder of the active logger, so additional outputs use the same format
+var newEncoder func() zapcore.Encoder
+
+// consoleScheme is the zap sink scheme of the console, used in place of stderr so entries can be diverted
+const consoleScheme = "flowgen-console"
+
+// consoleWriter writes the log entries meant for the terminal, to stderr unless they are diverted
+type consoleWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Write writes a log entry to the current destination of the console
+func (c *consoleWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.w.Write(p)
+}
+
+// Sync flushes the destination of the console if it is a file
+func (c *consoleWriter) Sync() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if f, ok := c.w.(*os.File); ok {
+		return f.Sync()
+	}
+	return nil
+}
+
+// Close leaves the destination open, it is shared by all loggers
+func (c *consoleWriter) Close() error {
+	return nil
+}
+
+// console is the destination of all loggers writing to the terminal
+var console = &consoleWriter{w: os.Stderr}
+
+func init() {
+	if err := zap.RegisterSink(consoleScheme, func(*url.URL) (zap.Sink, error) { return console, nil }); err != nil {
+		panic("Failed to register console sink: " + err.Error())
+	}
+}
+
+// Divert writes the log entries meant for stderr to w instead, until the returned function restores stderr.
+// Outputs added with Tee are not affected.
+func Divert(w io.Writer) (restore func()) {
+	console.mu.Lock()
+	defer console.mu.Unlock()
+	previous := console.w
+	console.w = w
+	return func() {
+		console.mu.Lock()
+		defer console.mu.Unlock()
+		console.w = previous
+	}
+}
+
 // getLogLevel converts a string level to a zapcore.Level
 func getLogLevel(level string) zapcore.Level {
 	switch level {
@@ -26,10 +94,29 @@ func getLogLevel(level string) zapcore.Level {
 	}
 }
 
-// InitLogger initializes the logger based on logformat and loglevel
+// InitLogger initializes the logger based on logformat and loglevel, with the auto theme for the human format
 func InitLogger(logFormat string, logLevel string) {
-	var cfg zap.Config
+	InitLoggerWithTheme(logFormat, logLevel, ThemeAuto)
+}
 
+// InitLoggerWithTheme initializes the logger based on logformat and loglevel. The theme selects the look of
+// the human format, see the Theme constants, an empty theme is ThemeAuto.
+func InitLoggerWithTheme(logFormat string, logLevel string, theme string) {
+	if theme == "" {
+		theme = ThemeAuto
+	}
+	level.SetLevel(getLogLevel(logLevel))
+	if logFormat != "json" && theme != ThemeClassic {
+		color := themeColors(theme, os.Stderr)
+		out := zapcore.AddSync(console)
+		Logger = zap.New(zapcore.NewCore(newConsoleEncoder(color), out, level), zap.AddStacktrace(zap.DPanicLevel)).Sugar()
+		Flows = zap.New(zapcore.NewCore(newConsoleEncoder(color), out, zap.DebugLevel)).Sugar()
+		// Files never get colors
+		newEncoder = func() zapcore.Encoder { return newConsoleEncoder(false) }
+		return
+	}
+
+	var cfg zap.Config
 	switch logFormat {
 	case "human":
 		cfg = zap.NewDevelopmentConfig()
@@ -39,8 +126,14 @@ func InitLogger(logFormat string, logLevel string) {
 		// Default to human-readable if an invalid format is provided
 		cfg = zap.NewDevelopmentConfig()
 	}
-
-	cfg.Level = zap.NewAtomicLevelAt(getLogLevel(logLevel))
+	cfg.Level = level
+	cfg.OutputPaths = []string{fmt.Sprintf("%s:", consoleScheme)}
+	newEncoder = func() zapcore.Encoder {
+		if cfg.Encoding == "json" {
+			return zapcore.NewJSONEncoder(cfg.EncoderConfig)
+		}
+		return zapcore.NewConsoleEncoder(cfg.EncoderConfig)
+	}
 
 	// Build the logger
 	logger, err := cfg.Build()
@@ -50,6 +143,32 @@ func InitLogger(logFormat string, logLevel string) {
 
 	// Assign the sugared logger
 	Logger = logger.Sugar()
+
+	flowCfg := cfg
+	flowCfg.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
+	flows, err := flowCfg.Build()
+	if err != nil {
+		panic("Failed to initialize flow logger: " + err.Error())
+	}
+	Flows = flows.Sugar()
+}
+
+// Tee additionally writes all log entries from now on to w, in the format and at the level of the active logger,
+// and all flow log entries
+func Tee(w io.Writer) {
+	out := zapcore.NewCore(newEncoder(), zapcore.AddSync(w), level)
+	Logger = Logger.Desugar().WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(c, out)
+	})).Sugar()
+	flowOut := zapcore.NewCore(newEncoder(), zapcore.AddSync(w), zap.DebugLevel)
+	Flows = Flows.Desugar().WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(c, flowOut)
+	})).Sugar()
+}
+
+// SetLevel changes the log level of the active logger
+func SetLevel(logLevel string) {
+	level.SetLevel(getLogLevel(logLevel))
 }
 
 // SyncLogger safely syncs the logger, handling CI environment issues
@@ -1,6 +1,8 @@
 package logging
 
 import (
+	"bytes"
+	"encoding/json"
 	"os"
 	"testing"
 
@@ -57,6 +59,86 @@ func TestInitLogger(t *testing.T) {
 	}
 }
 
+func TestSetLevel(t *testing.T) {
+	InitLogger("json", "info")
+	defer SetLevel("info")
+
+	assert.False(t, Logger.Desugar().Core().Enabled(zap.DebugLevel))
+	SetLevel("debug")
+	assert.True(t, Logger.Desugar().Core().Enabled(zap.DebugLevel))
+	SetLevel("error")
+	assert.False(t, Logger.Desugar().Core().Enabled(zap.WarnLevel))
+}
+
+func TestTee(t *testing.T) {
+	InitLogger("json", "info")
+	defer InitLogger("human", "info")
+
+	var buf bytes.Buffer
+	Tee(&buf)
+	Logger.Debug("not captured")
+	Logger.Infow("captured", "flow", 7)
+
+	var entry map[string]any
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "captured", entry["msg"])
+	assert.Equal(t, float64(7), entry["flow"])
+
+	InitLogger("human", "info")
+	buf.Reset()
+	Tee(&buf)
+	Logger.Warn("console entry")
+	assert.Contains(t, buf.String(), "WARN")
+	assert.Contains(t, buf.String(), "console entry")
+}
+
+func TestDivert(t *testing.T) {
+	defer InitLogger("human", "info")
+	tests := []struct {
+		format string
+		theme  string
+	}{
+		{"json", ThemeClassic},
+		{"human", ThemeClassic},
+		{"human", ThemeAuto},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format+" "+tt.theme, func(t *testing.T) {
+			InitLoggerWithTheme(tt.format, "info", tt.theme)
+			var diverted, teed bytes.Buffer
+			Tee(&teed)
+			restore := Divert(&diverted)
+			Logger.Info("diverted entry")
+			Flows.Info("diverted flow")
+			restore()
+
+			assert.Contains(t, diverted.String(), "diverted entry")
+			assert.Contains(t, diverted.String(), "diverted flow")
+			// Tee outputs keep getting every entry
+			assert.Contains(t, teed.String(), "diverted entry")
+
+			diverted.Reset()
+			Logger.Info("restored entry")
+			assert.Empty(t, diverted.String())
+		})
+	}
+}
+
+func TestFlowsIgnoreLogLevel(t *testing.T) {
+	InitLogger("json", "error")
+	defer InitLogger("human", "info")
+
+	var buf bytes.Buffer
+	Tee(&buf)
+	Logger.Info("not captured")
+	Flows.Debug("flow entry")
+
+	var entry map[string]any
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "flow entry", entry["msg"])
+	assert.Equal(t, "debug", entry["level"])
+}
+
 func TestLoggerOutput(t *testing.T) {
 	core, recorded := observer.New(zapcore.InfoLevel)
 
@@ -3,11 +3,13 @@ package metrics
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
 	"github.com/olekukonko/tablewriter"
@@ -21,9 +23,71 @@ type MetricsCollector struct {
 	RequestsSent                  *prometheus.CounterVec
 	BytesReceived                 *prometheus.CounterVec
 	BytesSent                     *prometheus.CounterVec
+	WireBytesReceived             *prometheus.CounterVec
+	WireBytesSent                 *prometheus.CounterVec
 	TCPConnectionsOpenedPerSecond prometheus.Counter
 	UDPPacketsReceived            prometheus.Counter
 	ActiveTCPConnections          prometheus.Gauge
+	TCPConnectionsReused          prometheus.Counter
+	UDPConnectedPeers             prometheus.Gauge
+	UDPPeerICMPErrors             prometheus.Counter
+	BackpressureActive            prometheus.Gauge
+	BackpressureSignals           prometheus.Counter
+	EffectiveFlowRate             prometheus.Gauge
+	UpstreamRequests              *prometheus.CounterVec
+	UpstreamErrors                prometheus.Counter
+	RelayedConnections            prometheus.Counter
+	RelayHopSetup                 *prometheus.HistogramVec
+	RequestLatency                *prometheus.HistogramVec
+	FlowStartGapRatio             prometheus.Histogram
+	FlowStartBurstiness           prometheus.Gauge
+	FlowsSkipped                  *prometheus.CounterVec
+	FlowsPreempted                prometheus.Counter
+	EchoDelay                     *prometheus.HistogramVec
+	ConnectionsClosed             *prometheus.CounterVec
+	FlowsMarked                   *prometheus.CounterVec
+	SourceFlows                   *prometheus.CounterVec
+	SourceActiveFlows             *prometheus.GaugeVec
+	HalfOpenFlows                 *prometheus.CounterVec
+	HalfOpenActiveFlows           *prometheus.GaugeVec
+	ConnectionsChurned            *prometheus.CounterVec
+	UniqueTuples                  *prometheus.CounterVec
+	LoadStageRate                 *prometheus.GaugeVec
+	StageFlows                    *prometheus.CounterVec
+	StageFlowLatency              *prometheus.HistogramVec
+	CBRDatagramsLost              *prometheus.CounterVec
+	CBRDatagramsOutOfOrder        *prometheus.CounterVec
+	CBRJitter                     *prometheus.HistogramVec
+	FlowsMimicked                 *prometheus.CounterVec
+	SoftLimitWarnings             *prometheus.CounterVec
+	NetnsFlows                    *prometheus.CounterVec
+	NetnsActiveFlows              *prometheus.GaugeVec
+	ConntrackBackoffs             prometheus.Counter
+	ConntrackBackoffActive        prometheus.Gauge
+	FlowsByFamily                 *prometheus.CounterVec
+	ConnectRetries                *prometheus.CounterVec
+	FlowErrors                    *prometheus.CounterVec
+	ICMPErrors                    *prometheus.CounterVec
+	DuplicateFlows                *prometheus.CounterVec
+	ReplayedDatagrams             *prometheus.CounterVec
+	ResponsesDropped              *prometheus.CounterVec
+	ConnectionsRejected           *prometheus.CounterVec
+	ConnectionLimitSaturation     *prometheus.GaugeVec
+	TLSHandshakes                 *prometheus.CounterVec
+	TLSHandshakeFailures          *prometheus.CounterVec
+	HTTPRequests                  *prometheus.CounterVec
+	HTTPRequestDuration           *prometheus.HistogramVec
+	ResponseReadThroughput        *prometheus.HistogramVec
+	PeerRTT                       *prometheus.GaugeVec
+	PeerLoss                      *prometheus.GaugeVec
+	BytesSentRate                 prometheus.GaugeFunc
+	BytesReceivedRate             prometheus.GaugeFunc
+	FlowsStartedRate              prometheus.GaugeFunc
+
+	// Sliding windows the rate gauges are computed over
+	sentRate     *rateWindow
+	receivedRate *rateWindow
+	flowRate     *rateWindow
 
 	// Local counters for termination output
 	totalRequestsReceived uint64
@@ -32,14 +96,56 @@ type MetricsCollector struct {
 	requestsSent          sync.Map
 	bytesReceived         sync.Map
 	bytesSent             sync.Map
+	wireBytesReceived     sync.Map
+	wireBytesSent         sync.Map
 	totalTCPSent          uint64
 	totalTCPReceived      uint64
 	totalUDPReceived      uint64
 	totalUDPSent          uint64
+	activeTCPConnections  int64
+	connectionsClosed     sync.Map
+	latency               sync.Map
+
+	// Latency heatmaps per protocol, recorded once EnableLatencyHeatmap set the slice length
+	heatmaps     sync.Map
+	heatmapStart time.Time
+	heatmapSlice time.Duration
+
+	// warmup is set while the warmup of a run lasts, see SetWarmup
+	warmup atomic.Bool
+
+	// Optional StatsD sink mirroring the per-flow counters and latency timings
+	statsd *StatsdSink
+
+	// Where LogMetrics prints its tables, stdout if nil
+	tableOut io.Writer
+}
+
+// Summary holds the metrics collected during a run in machine-readable form.
+type Summary struct {
+	TotalRequestsReceived uint64                       `json:"total_requests_received"`
+	TotalRequestsSent     uint64                       `json:"total_requests_sent"`
+	TotalTCPReceived      uint64                       `json:"total_tcp_received"`
+	TotalTCPSent          uint64                       `json:"total_tcp_sent"`
+	TotalUDPReceived      uint64                       `json:"total_udp_received"`
+	TotalUDPSent          uint64                       `json:"total_udp_sent"`
+	RequestsReceived      map[string]map[string]uint64 `json:"requests_received"`
+	RequestsSent          map[string]map[string]uint64 `json:"requests_sent"`
+	BytesReceived         map[string]map[string]uint64 `json:"bytes_received"`
+	BytesSent             map[string]map[string]uint64 `json:"bytes_sent"`
+	WireBytesReceived     map[string]map[string]uint64 `json:"wire_bytes_received"`
+	WireBytesSent         map[string]map[string]uint64 `json:"wire_bytes_sent"`
+	Latency               map[string]LatencySummary    `json:"latency"`
 }
 
 var metricsRegistered = false
 
+// EchoDelayBuckets cover server processing delays from 10µs to 1s, which are far below typical round-trip times.
+var EchoDelayBuckets = []float64{0.00001, 0.000025, 0.00005, 0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.05, 0.1, 0.5, 1}
+
+// ReadThroughputBuckets cover the read throughput of responses from 100 KB/s to 26 GB/s in factors of 4.
+var ReadThroughputBuckets = prometheus.ExponentialBuckets(1e5, 4, 10)
+
 // NewMetricsCollector initializes the collector and registers Prometheus metrics.
 func NewMetricsCollector() *MetricsCollector {
 	mc := &MetricsCollector{
@@ -59,6 +165,14 @@ func NewMetricsCollector() *MetricsCollector {
 			prometheus.CounterOpts{Name: "bytes_sent_total", Help: "Total bytes sent"},
 			[]string{"protocol", "port"},
 		),
+		WireBytesReceived: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "wire_bytes_received_total", Help: "Estimated on-wire bytes received, including IP, transport and link-layer headers"},
+			[]string{"protocol", "port"},
+		),
+		WireBytesSent: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "wire_bytes_sent_total", Help: "Estimated on-wire bytes sent, including IP, transport and link-layer headers"},
+			[]string{"protocol", "port"},
+		),
 		TCPConnectionsOpenedPerSecond: prometheus.NewCounter(
 			prometheus.CounterOpts{Name: "tcp_connections_opened_total", Help: "Total TCP connections opened"},
 		),
@@ -68,7 +182,203 @@ func NewMetricsCollector() *MetricsCollector {
 		ActiveTCPConnections: prometheus.NewGauge(
 			prometheus.GaugeOpts{Name: "active_tcp_connections", Help: "Current active TCP connections"},
 		),
+		TCPConnectionsReused: prometheus.NewCounter(
+			prometheus.CounterOpts{Name: "tcp_connections_reused_total", Help: "Total flows served over a pooled TCP connection"},
+		),
+		UDPConnectedPeers: prometheus.NewGauge(
+			prometheus.GaugeOpts{Name: "udp_connected_peers", Help: "Current UDP peers served over a dedicated connected socket"},
+		),
+		UDPPeerICMPErrors: prometheus.NewCounter(
+			prometheus.CounterOpts{Name: "udp_peer_icmp_errors_total", Help: "Total ICMP errors reported on connected UDP peer sockets"},
+		),
+		BackpressureActive: prometheus.NewGauge(
+			prometheus.GaugeOpts{Name: "backpressure_active", Help: "Whether backpressure is currently signaled by the server or applied by the client (1 = active)"},
+		),
+		BackpressureSignals: prometheus.NewCounter(
+			prometheus.CounterOpts{Name: "backpressure_signals_total", Help: "Total backpressure signals raised by the server or received by the client"},
+		),
+		EffectiveFlowRate: prometheus.NewGauge(
+			prometheus.GaugeOpts{Name: "flow_rate_effective", Help: "Current flow generation rate in flows per second after backpressure adjustments"},
+		),
+		UpstreamRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "upstream_requests_total", Help: "Total requests relayed to upstream servers"},
+			[]string{"protocol", "port"},
+		),
+		UpstreamErrors: prometheus.NewCounter(
+			prometheus.CounterOpts{Name: "upstream_errors_total", Help: "Total relayed requests that fell back to a local echo after an upstream failure"},
+		),
+		RelayedConnections: prometheus.NewCounter(
+			prometheus.CounterOpts{Name: "relayed_connections_total", Help: "Total TCP flows forwarded to their next hop in relay mode"},
+		),
+		RelayHopSetup: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{Name: "relay_hop_setup_seconds", Help: "Time for each relay hop to connect to its next hop, as observed by the client", Buckets: prometheus.DefBuckets},
+			[]string{"hop"},
+		),
+		RequestLatency: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{Name: "request_latency_seconds", Help: "Round-trip time between sending a request and receiving its full echo", Buckets: prometheus.DefBuckets},
+			[]string{"protocol", "port"},
+		),
+		FlowStartGapRatio: prometheus.NewHistogram(
+			prometheus.HistogramOpts{Name: "flow_start_gap_ratio", Help: "Observed gap between consecutive flow starts divided by the gap intended at the effective flow rate", Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 0.75, 1, 1.5, 2, 5, 10}},
+		),
+		FlowStartBurstiness: prometheus.NewGauge(
+			prometheus.GaugeOpts{Name: "flow_start_burstiness", Help: "Smoothed deviation of flow start gaps from the intended gap (0 = evenly spaced, 1 = back-to-back bursts)"},
+		),
+		FlowsSkipped: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "flows_skipped_total", Help: "Total flows not started because max_concurrent flows were running, per priority class"},
+			[]string{"priority"},
+		),
+		FlowsPreempted: prometheus.NewCounter(
+			prometheus.CounterOpts{Name: "flows_preempted_total", Help: "Total low-priority flows ended early to make room for a high-priority flow"},
+		),
+		EchoDelay: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{Name: "echo_delay_seconds", Help: "Time the server took from completing a read to completing the write of its response", Buckets: EchoDelayBuckets},
+			[]string{"protocol", "port"},
+		),
+		ConnectionsClosed: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "connections_closed_total", Help: "Total TCP connections closed on the server by reason (fin, reset, timeout, server, error)"},
+			[]string{"reason"},
+		),
+		FlowsMarked: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "flows_marked_total", Help: "Total flows started with a DSCP marking per protocol, port and DSCP class"},
+			[]string{"protocol", "port", "dscp"},
+		),
+		SourceFlows: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "source_flows_total", Help: "Total flows started per source address or interface of the source pool and protocol"},
+			[]string{"source", "protocol"},
+		),
+		SourceActiveFlows: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "source_active_flows", Help: "Flows currently active per source address or interface of the source pool"},
+			[]string{"source"},
+		),
+		HalfOpenFlows: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "half_open_flows_total", Help: "Total half-open flows started on the client, which hold a TCP connection without reading from it, per protocol and port"},
+			[]string{"protocol", "port"},
+		),
+		HalfOpenActiveFlows: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "half_open_active_flows", Help: "Half-open flows currently holding a connection per protocol"},
+			[]string{"protocol"},
+		),
+		ConnectionsChurned: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "churned_connections_total", Help: "Total TCP connections the client opened and closed right away without sending a payload per protocol and port"},
+			[]string{"protocol", "port"},
+		),
+		UniqueTuples: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "unique_tuples_total", Help: "Total flows per protocol whose 5-tuple was not used by a recent flow, with track_tuples enabled"},
+			[]string{"protocol"},
+		),
+		LoadStageRate: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "load_stage_rate", Help: "Flow rate of the load step currently running per stage, 0 for the other stages"},
+			[]string{"stage"},
+		),
+		StageFlows: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "stage_flows_total", Help: "Total flows finished within each load step per stage and result (completed, failed)"},
+			[]string{"stage", "result"},
+		),
+		StageFlowLatency: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{Name: "stage_flow_latency_seconds", Help: "Mean round-trip time of the flows completed within each load step per stage", Buckets: prometheus.DefBuckets},
+			[]string{"stage"},
+		),
+		CBRDatagramsLost: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "cbr_datagrams_lost_total", Help: "Datagrams of constant bitrate flows whose echo never arrived per protocol and port"},
+			[]string{"protocol", "port"},
+		),
+		CBRDatagramsOutOfOrder: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "cbr_datagrams_out_of_order_total", Help: "Echoed datagrams of constant bitrate flows arriving after a later datagram per protocol and port"},
+			[]string{"protocol", "port"},
+		),
+		CBRJitter: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{Name: "cbr_jitter_seconds", Help: "Interarrival jitter of the echoes of constant bitrate flows at their end as in RFC 3550 per protocol", Buckets: EchoDelayBuckets},
+			[]string{"protocol"},
+		),
+		FlowsMimicked: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "mimicked_flows_total", Help: "Total flows whose payload mimics an application protocol per protocol, port and mimicked protocol"},
+			[]string{"protocol", "port", "mimic"},
+		),
+		SoftLimitWarnings: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "soft_limit_warnings_total", Help: "Times a soft limit of warn_thresholds was crossed during the run per limit"},
+			[]string{"limit"},
+		),
+		NetnsFlows: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "netns_flows_total", Help: "Total flows finished per network namespace they were sent from, protocol and result (completed, failed)"},
+			[]string{"netns", "protocol", "result"},
+		),
+		NetnsActiveFlows: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "netns_active_flows", Help: "Flows currently active per network namespace they are sent from"},
+			[]string{"netns"},
+		),
+		ConntrackBackoffs: prometheus.NewCounter(
+			prometheus.CounterOpts{Name: "conntrack_backoffs_total", Help: "Total times the client backed off because timeouts and refusals suggested a full conntrack table on the path"},
+		),
+		ConntrackBackoffActive: prometheus.NewGauge(
+			prometheus.GaugeOpts{Name: "conntrack_backoff_active", Help: "Whether the client is currently backed off waiting for a full conntrack table to recover (1 = active)"},
+		),
+		FlowsByFamily: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "flows_by_family_total", Help: "Total flows connected per protocol and address family (ipv4, ipv6) of the server address"},
+			[]string{"protocol", "family"},
+		),
+		ConnectRetries: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "connect_retries_total", Help: "Total connection attempts retried after a failure or timeout per protocol"},
+			[]string{"protocol"},
+		),
+		FlowErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "flow_errors_total", Help: "Total flow errors on the client per protocol, port and reason (refused, timeout, reset, closed, dns, unreachable, prohibited, mismatch, dial, write, read)"},
+			[]string{"protocol", "port", "reason"},
+		),
+		ICMPErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "icmp_errors_total", Help: "Total ICMP errors received on client sockets per protocol, port and type (port_unreachable, admin_prohibited, host_unreachable, net_unreachable, frag_needed, ttl_exceeded, other)"},
+			[]string{"protocol", "port", "type"},
+		),
+		DuplicateFlows: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "duplicate_flows_total", Help: "Total flows received on the server whose flow header was seen on another connection or from another peer before"},
+			[]string{"protocol", "port"},
+		),
+		ReplayedDatagrams: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "replayed_datagrams_total", Help: "Total UDP datagrams received on the server whose flow header sequence number was seen before, they are not echoed"},
+			[]string{"port"},
+		),
+		ResponsesDropped: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "responses_dropped_total", Help: "Total echo responses the server dropped on purpose per protocol and port, as configured with response_drops"},
+			[]string{"protocol", "port"},
+		),
+		ConnectionsRejected: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "connections_rejected_total", Help: "Total TCP connections the server closed right after accepting them because a connection limit was reached, per limit (a listener port or global)"},
+			[]string{"limit"},
+		),
+		ConnectionLimitSaturation: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "connection_limit_saturation", Help: "Fraction of the TCP connections allowed by a connection limit of the server that are in use, per limit (a listener port or global)"},
+			[]string{"limit"},
+		),
+		TLSHandshakes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "tls_handshakes_total", Help: "Total TLS handshakes completed per port, SNI (none if the client sent none), TLS version and cipher suite, accepted by the server or dialed by the client"},
+			[]string{"port", "sni", "version", "cipher_suite"},
+		),
+		TLSHandshakeFailures: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "tls_handshake_failures_total", Help: "Total TLS handshakes that failed per port, accepted by the server or dialed by the client"},
+			[]string{"port"},
+		),
+		HTTPRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "http_requests_total", Help: "Total HTTP requests answered by the server per port, method, path and status code"},
+			[]string{"port", "method", "path", "code"},
+		),
+		HTTPRequestDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{Name: "http_request_duration_seconds", Help: "Time the server took from completing the read of an HTTP request to completing the write of its response per port and path", Buckets: EchoDelayBuckets},
+			[]string{"port", "path"},
+		),
+		ResponseReadThroughput: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{Name: "response_read_throughput_bytes_per_second", Help: "Bytes per second the client read stream responses taking more than one read at, from their first to their last byte, per protocol and port", Buckets: ReadThroughputBuckets},
+			[]string{"protocol", "port"},
+		),
+		PeerRTT: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "peer_rtt_seconds", Help: "Mean round-trip time of the last probe round from this agent (src) to a peer (dst)"},
+			[]string{"src", "dst"},
+		),
+		PeerLoss: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "peer_loss_ratio", Help: "Fraction of the probes of the last probe round from this agent (src) to a peer (dst) that were lost"},
+			[]string{"src", "dst"},
+		),
 	}
+	mc.initRates(time.Now(), "")
 
 	// Register Prometheus metrics only once
 	if !metricsRegistered {
@@ -77,9 +387,66 @@ func NewMetricsCollector() *MetricsCollector {
 			mc.RequestsSent,
 			mc.BytesReceived,
 			mc.BytesSent,
+			mc.WireBytesReceived,
+			mc.WireBytesSent,
 			mc.TCPConnectionsOpenedPerSecond,
 			mc.UDPPacketsReceived,
 			mc.ActiveTCPConnections,
+			mc.TCPConnectionsReused,
+			mc.UDPConnectedPeers,
+			mc.UDPPeerICMPErrors,
+			mc.BackpressureActive,
+			mc.BackpressureSignals,
+			mc.EffectiveFlowRate,
+			mc.UpstreamRequests,
+			mc.UpstreamErrors,
+			mc.RelayedConnections,
+			mc.RelayHopSetup,
+			mc.RequestLatency,
+			mc.FlowStartGapRatio,
+			mc.FlowStartBurstiness,
+			mc.FlowsSkipped,
+			mc.FlowsPreempted,
+			mc.EchoDelay,
+			mc.ConnectionsClosed,
+			mc.FlowsMarked,
+			mc.SourceFlows,
+			mc.SourceActiveFlows,
+			mc.HalfOpenFlows,
+			mc.HalfOpenActiveFlows,
+			mc.ConnectionsChurned,
+			mc.UniqueTuples,
+			mc.LoadStageRate,
+			mc.StageFlows,
+			mc.StageFlowLatency,
+			mc.CBRDatagramsLost,
+			mc.CBRDatagramsOutOfOrder,
+			mc.CBRJitter,
+			mc.FlowsMimicked,
+			mc.SoftLimitWarnings,
+			mc.NetnsFlows,
+			mc.NetnsActiveFlows,
+			mc.ConntrackBackoffs,
+			mc.ConntrackBackoffActive,
+			mc.FlowsByFamily,
+			mc.ConnectRetries,
+			mc.FlowErrors,
+			mc.ICMPErrors,
+			mc.DuplicateFlows,
+			mc.ReplayedDatagrams,
+			mc.ResponsesDropped,
+			mc.ConnectionsRejected,
+			mc.ConnectionLimitSaturation,
+			mc.TLSHandshakes,
+			mc.TLSHandshakeFailures,
+			mc.HTTPRequests,
+			mc.HTTPRequestDuration,
+			mc.ResponseReadThroughput,
+			mc.PeerRTT,
+			mc.PeerLoss,
+			mc.BytesSentRate,
+			mc.BytesReceivedRate,
+			mc.FlowsStartedRate,
 		)
 		metricsRegistered = true
 	}
@@ -87,8 +454,45 @@ func NewMetricsCollector() *MetricsCollector {
 	return mc
 }
 
+// initRates creates the sliding windows of the rate gauges starting at the given time and the gauges
+// reading them, their names prefixed with prefix
+func (mc *MetricsCollector) initRates(now time.Time, prefix string) {
+	mc.sentRate = newRateWindow(RateWindow, now)
+	mc.receivedRate = newRateWindow(RateWindow, now)
+	mc.flowRate = newRateWindow(RateWindow, now)
+	gauge := func(name, help string, w *rateWindow) prometheus.GaugeFunc {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: prefix + name, Help: help}, func() float64 {
+			return w.rate(time.Now())
+		})
+	}
+	window := rateWindowHelp()
+	mc.BytesSentRate = gauge("bytes_sent_per_second", "Bytes sent per second "+window, mc.sentRate)
+	mc.BytesReceivedRate = gauge("bytes_received_per_second", "Bytes received per second "+window, mc.receivedRate)
+	mc.FlowsStartedRate = gauge("flows_started_per_second", "Flows started per second "+window+", TCP connections accepted on the server", mc.flowRate)
+}
+
+// SetStatsdSink mirrors the per-flow counters and latency timings to a StatsD sink. It must be called
+// before any metrics are recorded.
+func (mc *MetricsCollector) SetStatsdSink(sink *StatsdSink) {
+	mc.statsd = sink
+}
+
+// SetWarmup starts or ends the warmup of a run. While it lasts, requests, bytes, latencies and flow errors
+// are not recorded, so cold-start effects stay out of the metrics and the summary.
+func (mc *MetricsCollector) SetWarmup(active bool) {
+	mc.warmup.Store(active)
+}
+
+// WarmingUp reports whether the warmup of a run is in progress.
+func (mc *MetricsCollector) WarmingUp() bool {
+	return mc.warmup.Load()
+}
+
 // IncRequestsReceived increments requests received counters.
 func (mc *MetricsCollector) IncRequestsReceived(protocol, port string) {
+	if mc.warmup.Load() {
+		return
+	}
 	mc.RequestsReceived.WithLabelValues(protocol, port).Inc()
 	atomic.AddUint64(&mc.totalRequestsReceived, 1)
 	switch protocol {
@@ -98,10 +502,16 @@ func (mc *MetricsCollector) IncRequestsReceived(protocol, port string) {
 		atomic.AddUint64(&mc.totalUDPReceived, 1)
 	}
 	mc.updateSyncMap(&mc.requestsReceived, protocol, port, 1)
+	if mc.statsd != nil {
+		mc.statsd.Count("requests_received", protocol, port, 1)
+	}
 }
 
 // IncRequestsSent increments requests sent counters.
 func (mc *MetricsCollector) IncRequestsSent(protocol, port string) {
+	if mc.warmup.Load() {
+		return
+	}
 	mc.RequestsSent.WithLabelValues(protocol, port).Inc()
 	atomic.AddUint64(&mc.totalRequestsSent, 1)
 	switch protocol {
@@ -111,24 +521,65 @@ func (mc *MetricsCollector) IncRequestsSent(protocol, port string) {
 		atomic.AddUint64(&mc.totalUDPSent, 1)
 	}
 	mc.updateSyncMap(&mc.requestsSent, protocol, port, 1)
+	if mc.statsd != nil {
+		mc.statsd.Count("requests_sent", protocol, port, 1)
+	}
 }
 
 // AddBytesReceived adds bytes to received counters.
 func (mc *MetricsCollector) AddBytesReceived(protocol, port string, n int) {
+	if mc.warmup.Load() {
+		return
+	}
 	if n < 0 {
 		return
 	}
 	mc.BytesReceived.WithLabelValues(protocol, port).Add(float64(n))
 	mc.updateSyncMap(&mc.bytesReceived, protocol, port, uint64(n))
+	mc.receivedRate.add(time.Now(), uint64(n))
+	if mc.statsd != nil {
+		mc.statsd.Count("bytes_received", protocol, port, int64(n))
+	}
 }
 
 // AddBytesSent adds bytes to sent counters.
 func (mc *MetricsCollector) AddBytesSent(protocol, port string, n int) {
+	if mc.warmup.Load() {
+		return
+	}
 	if n < 0 {
 		return
 	}
 	mc.BytesSent.WithLabelValues(protocol, port).Add(float64(n))
 	mc.updateSyncMap(&mc.bytesSent, protocol, port, uint64(n))
+	mc.sentRate.add(time.Now(), uint64(n))
+	if mc.statsd != nil {
+		mc.statsd.Count("bytes_sent", protocol, port, int64(n))
+	}
+}
+
+// AddWireBytesReceived adds estimated on-wire bytes to received counters.
+func (mc *MetricsCollector) AddWireBytesReceived(protocol, port string, n int) {
+	if mc.warmup.Load() {
+		return
+	}
+	if n < 0 {
+		return
+	}
+	mc.WireBytesReceived.WithLabelValues(protocol, port).Add(float64(n))
+	mc.updateSyncMap(&mc.wireBytesReceived, protocol, port, uint64(n))
+}
+
+// AddWireBytesSent adds estimated on-wire bytes to sent counters.
+func (mc *MetricsCollector) AddWireBytesSent(protocol, port string, n int) {
+	if mc.warmup.Load() {
+		return
+	}
+	if n < 0 {
+		return
+	}
+	mc.WireBytesSent.WithLabelValues(protocol, port).Add(float64(n))
+	mc.updateSyncMap(&mc.wireBytesSent, protocol, port, uint64(n))
 }
 
 // IncTCPConnectionsOpened increments the TCP connections opened counter.
@@ -136,6 +587,11 @@ func (mc *MetricsCollector) IncTCPConnectionsOpened() {
 	mc.TCPConnectionsOpenedPerSecond.Inc()
 }
 
+// IncTCPConnectionsReused increments the pooled TCP connection reuse counter.
+func (mc *MetricsCollector) IncTCPConnectionsReused() {
+	mc.TCPConnectionsReused.Inc()
+}
+
 // IncUDPPacketsReceived increments the UDP packets received counter.
 func (mc *MetricsCollector) IncUDPPacketsReceived() {
 	mc.UDPPacketsReceived.Inc()
@@ -143,6 +599,369 @@ func (mc *MetricsCollector) IncUDPPacketsReceived() {
 
 func (mc *MetricsCollector) SetActiveTCPConnections(n int) {
 	mc.ActiveTCPConnections.Set(float64(n))
+	atomic.StoreInt64(&mc.activeTCPConnections, int64(n))
+}
+
+// IncActiveTCPConnections increments the active TCP connections gauge.
+func (mc *MetricsCollector) IncActiveTCPConnections() {
+	mc.ActiveTCPConnections.Inc()
+	atomic.AddInt64(&mc.activeTCPConnections, 1)
+}
+
+// DecActiveTCPConnections decrements the active TCP connections gauge.
+func (mc *MetricsCollector) DecActiveTCPConnections() {
+	mc.ActiveTCPConnections.Dec()
+	atomic.AddInt64(&mc.activeTCPConnections, -1)
+}
+
+// ActiveTCPConnectionCount returns the current number of active TCP connections.
+func (mc *MetricsCollector) ActiveTCPConnectionCount() int64 {
+	return atomic.LoadInt64(&mc.activeTCPConnections)
+}
+
+// TotalUDPReceived returns the total number of UDP packets received.
+func (mc *MetricsCollector) TotalUDPReceived() uint64 {
+	return atomic.LoadUint64(&mc.totalUDPReceived)
+}
+
+// IncBackpressureSignals increments the backpressure signals counter.
+func (mc *MetricsCollector) IncBackpressureSignals() {
+	mc.BackpressureSignals.Inc()
+}
+
+// SetBackpressureActive records whether backpressure is currently in effect.
+func (mc *MetricsCollector) SetBackpressureActive(active bool) {
+	if active {
+		mc.BackpressureActive.Set(1)
+	} else {
+		mc.BackpressureActive.Set(0)
+	}
+}
+
+// SetEffectiveFlowRate records the current flow generation rate.
+func (mc *MetricsCollector) SetEffectiveFlowRate(rate float64) {
+	mc.EffectiveFlowRate.Set(rate)
+}
+
+// IncUpstreamRequests increments the relayed upstream requests counter.
+func (mc *MetricsCollector) IncUpstreamRequests(protocol, port string) {
+	mc.UpstreamRequests.WithLabelValues(protocol, port).Inc()
+}
+
+// IncUpstreamErrors increments the failed upstream relay counter.
+func (mc *MetricsCollector) IncUpstreamErrors() {
+	mc.UpstreamErrors.Inc()
+}
+
+// IncRelayedConnections increments the relayed connections counter.
+func (mc *MetricsCollector) IncRelayedConnections() {
+	mc.RelayedConnections.Inc()
+}
+
+// ObserveRelayHopSetup records the setup time of the given 1-based relay hop.
+func (mc *MetricsCollector) ObserveRelayHopSetup(hop int, d time.Duration) {
+	mc.RelayHopSetup.WithLabelValues(strconv.Itoa(hop)).Observe(d.Seconds())
+}
+
+// ObserveLatency records the round-trip time of a request.
+func (mc *MetricsCollector) ObserveLatency(protocol, port string, d time.Duration) {
+	if mc.warmup.Load() {
+		return
+	}
+	mc.RequestLatency.WithLabelValues(protocol, port).Observe(d.Seconds())
+	recorder, _ := mc.latency.LoadOrStore(protocol, newLatencyRecorder())
+	recorder.(*latencyRecorder).observe(d)
+	if mc.heatmapSlice > 0 {
+		heatmap, _ := mc.heatmaps.LoadOrStore(protocol, newLatencyHeatmap(mc.heatmapStart, mc.heatmapSlice))
+		heatmap.(*latencyHeatmap).observe(time.Now(), d)
+	}
+	if mc.statsd != nil {
+		mc.statsd.Timing("request_latency", protocol, port, d)
+	}
+}
+
+// ObserveFlowStartGap records the gap between two flow starts relative to the intended gap, and the resulting burstiness.
+func (mc *MetricsCollector) ObserveFlowStartGap(ratio, burstiness float64) {
+	mc.FlowStartGapRatio.Observe(ratio)
+	mc.FlowStartBurstiness.Set(burstiness)
+}
+
+// ObserveEchoDelay records the time the server took to respond to a request after reading it.
+func (mc *MetricsCollector) ObserveEchoDelay(protocol, port string, d time.Duration) {
+	mc.EchoDelay.WithLabelValues(protocol, port).Observe(d.Seconds())
+}
+
+// ObserveFlowStarted counts a flow started on the client or a TCP connection accepted on the server for the
+// flows per second gauge.
+func (mc *MetricsCollector) ObserveFlowStarted() {
+	if mc.warmup.Load() {
+		return
+	}
+	mc.flowRate.add(time.Now(), 1)
+}
+
+// IncConnectionsClosed increments the closed TCP connections counter for the given reason.
+func (mc *MetricsCollector) IncConnectionsClosed(reason string) {
+	mc.ConnectionsClosed.WithLabelValues(reason).Inc()
+	counter, _ := mc.connectionsClosed.LoadOrStore(reason, &atomic.Uint64{})
+	counter.(*atomic.Uint64).Add(1)
+}
+
+// ConnectionsClosedCounts returns the number of TCP connections closed on the server so far by reason.
+func (mc *MetricsCollector) ConnectionsClosedCounts() map[string]uint64 {
+	result := make(map[string]uint64)
+	mc.connectionsClosed.Range(func(reason, counter any) bool {
+		result[reason.(string)] = counter.(*atomic.Uint64).Load()
+		return true
+	})
+	return result
+}
+
+// IncFlowsMarked increments the marked flows counter of a DSCP class.
+func (mc *MetricsCollector) IncFlowsMarked(protocol, port, dscp string) {
+	mc.FlowsMarked.WithLabelValues(protocol, port, dscp).Inc()
+}
+
+// SourceFlowStarted counts a flow started from a source of the source pool as started and active.
+func (mc *MetricsCollector) SourceFlowStarted(source, protocol string) {
+	mc.SourceFlows.WithLabelValues(source, protocol).Inc()
+	mc.SourceActiveFlows.WithLabelValues(source).Inc()
+}
+
+// SourceFlowEnded removes a flow of a source of the source pool from the active flows.
+func (mc *MetricsCollector) SourceFlowEnded(source string) {
+	mc.SourceActiveFlows.WithLabelValues(source).Dec()
+}
+
+// HalfOpenFlowStarted counts a half-open flow and adds it to the active half-open flows.
+func (mc *MetricsCollector) HalfOpenFlowStarted(protocol, port string) {
+	mc.HalfOpenFlows.WithLabelValues(protocol, port).Inc()
+	mc.HalfOpenActiveFlows.WithLabelValues(protocol).Inc()
+}
+
+// HalfOpenFlowEnded removes a half-open flow from the active half-open flows.
+func (mc *MetricsCollector) HalfOpenFlowEnded(protocol string) {
+	mc.HalfOpenActiveFlows.WithLabelValues(protocol).Dec()
+}
+
+// IncConnectionsChurned increments the churned connections counter.
+func (mc *MetricsCollector) IncConnectionsChurned(protocol, port string) {
+	mc.ConnectionsChurned.WithLabelValues(protocol, port).Inc()
+}
+
+// IncUniqueTuples increments the unique 5-tuples counter.
+func (mc *MetricsCollector) IncUniqueTuples(protocol string) {
+	mc.UniqueTuples.WithLabelValues(protocol).Inc()
+}
+
+// ObserveCBRFlow records the loss, reordering and jitter of a constant bitrate flow once it ended
+func (mc *MetricsCollector) ObserveCBRFlow(protocol, port string, lost, outOfOrder uint64, jitter time.Duration) {
+	mc.CBRDatagramsLost.WithLabelValues(protocol, port).Add(float64(lost))
+	mc.CBRDatagramsOutOfOrder.WithLabelValues(protocol, port).Add(float64(outOfOrder))
+	mc.CBRJitter.WithLabelValues(protocol).Observe(jitter.Seconds())
+}
+
+// SetLoadStageRate sets the flow rate of a load step, 0 once the step is over.
+func (mc *MetricsCollector) SetLoadStageRate(stage string, rate float64) {
+	mc.LoadStageRate.WithLabelValues(stage).Set(rate)
+}
+
+// ObserveStageFlow counts a flow finished within a load step, and records the mean round-trip time of
+// completed flows that got answers.
+func (mc *MetricsCollector) ObserveStageFlow(stage, result string, latency time.Duration) {
+	mc.StageFlows.WithLabelValues(stage, result).Inc()
+	if latency > 0 {
+		mc.StageFlowLatency.WithLabelValues(stage).Observe(latency.Seconds())
+	}
+}
+
+// IncFlowsMimicked increments the mimicked flows counter of an application protocol.
+func (mc *MetricsCollector) IncFlowsMimicked(protocol, port, mimic string) {
+	mc.FlowsMimicked.WithLabelValues(protocol, port, mimic).Inc()
+}
+
+// IncSoftLimitWarnings increments the crossings counter of a soft limit.
+func (mc *MetricsCollector) IncSoftLimitWarnings(limit string) {
+	mc.SoftLimitWarnings.WithLabelValues(limit).Inc()
+}
+
+// NetnsFlowStarted adds a flow sent from a network namespace to its active flows.
+func (mc *MetricsCollector) NetnsFlowStarted(netns string) {
+	mc.NetnsActiveFlows.WithLabelValues(netns).Inc()
+}
+
+// NetnsFlowEnded counts a flow sent from a network namespace as finished and removes it from the active flows.
+func (mc *MetricsCollector) NetnsFlowEnded(netns, protocol string, failed bool) {
+	result := "completed"
+	if failed {
+		result = "failed"
+	}
+	mc.NetnsFlows.WithLabelValues(netns, protocol, result).Inc()
+	mc.NetnsActiveFlows.WithLabelValues(netns).Dec()
+}
+
+// ConntrackBackoffStarted counts a conntrack backoff and marks it active.
+func (mc *MetricsCollector) ConntrackBackoffStarted() {
+	mc.ConntrackBackoffs.Inc()
+	mc.ConntrackBackoffActive.Set(1)
+}
+
+// ConntrackBackoffEnded marks the conntrack backoff as no longer active.
+func (mc *MetricsCollector) ConntrackBackoffEnded() {
+	mc.ConntrackBackoffActive.Set(0)
+}
+
+// IncFlowsByFamily increments the connected flows counter of an address family.
+func (mc *MetricsCollector) IncFlowsByFamily(protocol, family string) {
+	mc.FlowsByFamily.WithLabelValues(protocol, family).Inc()
+}
+
+// IncConnectRetries increments the retried connection attempts counter of a protocol.
+func (mc *MetricsCollector) IncConnectRetries(protocol string) {
+	mc.ConnectRetries.WithLabelValues(protocol).Inc()
+}
+
+// IncFlowErrors increments the flow errors counter of a protocol/port for the given reason.
+func (mc *MetricsCollector) IncFlowErrors(protocol, port, reason string) {
+	if mc.warmup.Load() {
+		return
+	}
+	mc.FlowErrors.WithLabelValues(protocol, port, reason).Inc()
+}
+
+// IncICMPErrors increments the ICMP errors counter of a protocol/port for the given ICMP error type.
+func (mc *MetricsCollector) IncICMPErrors(protocol, port, icmpType string) {
+	if mc.warmup.Load() {
+		return
+	}
+	mc.ICMPErrors.WithLabelValues(protocol, port, icmpType).Inc()
+}
+
+// IncDuplicateFlows increments the duplicate flows counter of a protocol/port.
+func (mc *MetricsCollector) IncDuplicateFlows(protocol, port string) {
+	mc.DuplicateFlows.WithLabelValues(protocol, port).Inc()
+}
+
+// IncReplayedDatagrams increments the replayed datagrams counter of a UDP port.
+func (mc *MetricsCollector) IncReplayedDatagrams(port string) {
+	mc.ReplayedDatagrams.WithLabelValues(port).Inc()
+}
+
+// IncResponsesDropped increments the dropped responses counter of a protocol/port.
+func (mc *MetricsCollector) IncResponsesDropped(protocol, port string) {
+	mc.ResponsesDropped.WithLabelValues(protocol, port).Inc()
+}
+
+// IncConnectionsRejected increments the rejected connections counter of a connection limit.
+func (mc *MetricsCollector) IncConnectionsRejected(limit string) {
+	mc.ConnectionsRejected.WithLabelValues(limit).Inc()
+}
+
+// IncTLSHandshakes increments the completed TLS handshakes counter of a port, SNI, TLS version and cipher suite.
+func (mc *MetricsCollector) IncTLSHandshakes(port, sni, version, cipherSuite string) {
+	mc.TLSHandshakes.WithLabelValues(port, sni, version, cipherSuite).Inc()
+}
+
+// IncTLSHandshakeFailures increments the failed TLS handshakes counter of a port.
+func (mc *MetricsCollector) IncTLSHandshakeFailures(port string) {
+	mc.TLSHandshakeFailures.WithLabelValues(port).Inc()
+}
+
+// ObserveHTTPRequest records an HTTP request answered on a port with the given status code and the time the
+// server took to respond to it.
+func (mc *MetricsCollector) ObserveHTTPRequest(port, method, path string, code int, d time.Duration) {
+	mc.HTTPRequests.WithLabelValues(port, method, path, strconv.Itoa(code)).Inc()
+	mc.HTTPRequestDuration.WithLabelValues(port, path).Observe(d.Seconds())
+}
+
+// ObserveResponseRead records a stream response of n bytes the client read from its first to its last byte
+// within d. Responses read at once carry no throughput and are not recorded.
+func (mc *MetricsCollector) ObserveResponseRead(protocol, port string, n int, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	mc.ResponseReadThroughput.WithLabelValues(protocol, port).Observe(float64(n) / d.Seconds())
+}
+
+// SetConnectionLimitSaturation sets the fraction of the connections of a connection limit that are in use.
+func (mc *MetricsCollector) SetConnectionLimitSaturation(limit string, saturation float64) {
+	mc.ConnectionLimitSaturation.WithLabelValues(limit).Set(saturation)
+}
+
+// SetPeerProbe records the result of a probe round to a peer. Without a single response the round-trip
+// time of the peer is removed, since there is nothing to report.
+func (mc *MetricsCollector) SetPeerProbe(src, dst string, rtt time.Duration, loss float64, answered bool) {
+	if answered {
+		mc.PeerRTT.WithLabelValues(src, dst).Set(rtt.Seconds())
+	} else {
+		mc.PeerRTT.DeleteLabelValues(src, dst)
+	}
+	mc.PeerLoss.WithLabelValues(src, dst).Set(loss)
+}
+
+// DeletePeer removes the probe results of a peer that is gone.
+func (mc *MetricsCollector) DeletePeer(src, dst string) {
+	mc.PeerRTT.DeleteLabelValues(src, dst)
+	mc.PeerLoss.DeleteLabelValues(src, dst)
+}
+
+// IncFlowsSkipped increments the skipped flows counter of a priority class.
+func (mc *MetricsCollector) IncFlowsSkipped(priority string) {
+	mc.FlowsSkipped.WithLabelValues(priority).Inc()
+}
+
+// IncFlowsPreempted increments the preempted flows counter.
+func (mc *MetricsCollector) IncFlowsPreempted() {
+	mc.FlowsPreempted.Inc()
+}
+
+// LatencySummaries returns the latency statistics observed so far per protocol.
+func (mc *MetricsCollector) LatencySummaries() map[string]LatencySummary {
+	result := make(map[string]LatencySummary)
+	mc.latency.Range(func(key, value interface{}) bool {
+		result[key.(string)] = value.(*latencyRecorder).summary()
+		return true
+	})
+	return result
+}
+
+// EnableLatencyHeatmap records latencies by time slice of the given length since start and latency range. It
+// must be called before latencies are observed.
+func (mc *MetricsCollector) EnableLatencyHeatmap(start time.Time, slice time.Duration) {
+	mc.heatmapStart = start
+	mc.heatmapSlice = slice
+}
+
+// LatencyHeatmaps returns the latency heatmaps observed so far per protocol, nil if none were recorded.
+func (mc *MetricsCollector) LatencyHeatmaps() map[string]LatencyHeatmap {
+	var result map[string]LatencyHeatmap
+	mc.heatmaps.Range(func(key, value interface{}) bool {
+		if result == nil {
+			result = make(map[string]LatencyHeatmap)
+		}
+		result[key.(string)] = value.(*latencyHeatmap).heatmap()
+		return true
+	})
+	return result
+}
+
+// Summary returns the totals, per-protocol/port counters and latency statistics collected so far.
+func (mc *MetricsCollector) Summary() Summary {
+	return Summary{
+		TotalRequestsReceived: atomic.LoadUint64(&mc.totalRequestsReceived),
+		TotalRequestsSent:     atomic.LoadUint64(&mc.totalRequestsSent),
+		TotalTCPReceived:      atomic.LoadUint64(&mc.totalTCPReceived),
+		TotalTCPSent:          atomic.LoadUint64(&mc.totalTCPSent),
+		TotalUDPReceived:      atomic.LoadUint64(&mc.totalUDPReceived),
+		TotalUDPSent:          atomic.LoadUint64(&mc.totalUDPSent),
+		RequestsReceived:      mc.getSyncMapData(&mc.requestsReceived),
+		RequestsSent:          mc.getSyncMapData(&mc.requestsSent),
+		BytesReceived:         mc.getSyncMapData(&mc.bytesReceived),
+		BytesSent:             mc.getSyncMapData(&mc.bytesSent),
+		WireBytesReceived:     mc.getSyncMapData(&mc.wireBytesReceived),
+		WireBytesSent:         mc.getSyncMapData(&mc.wireBytesSent),
+		Latency:               mc.LatencySummaries(),
+	}
 }
 
 // updateSyncMap updates a sync.Map with protocol/port counts using pointers.
@@ -164,11 +983,25 @@ func (mc *MetricsCollector) updateSyncMap(m *sync.Map, protocol, port string, de
 	counter.Add(delta)
 }
 
+// SetTableOutput redirects the metric tables printed by LogMetrics, which go to stdout by default.
+func (mc *MetricsCollector) SetTableOutput(w io.Writer) {
+	mc.tableOut = w
+}
+
+// tableOutput returns where the metric tables are printed.
+func (mc *MetricsCollector) tableOutput() io.Writer {
+	if mc.tableOut != nil {
+		return mc.tableOut
+	}
+	return os.Stdout
+}
+
 // LogMetrics prints all metrics in the specified format upon termination.
 func (mc *MetricsCollector) LogMetrics(logFormat string) {
 	if logFormat == "human" {
+		w := mc.tableOutput()
 		// Total Metrics Table
-		table := tablewriter.NewWriter(os.Stdout)
+		table := tablewriter.NewWriter(w)
 		table.Header("Metric", "Value")
 		_ = table.Append("Total Requests Received", fmt.Sprintf("%d", atomic.LoadUint64(&mc.totalRequestsReceived)))
 		_ = table.Append("Total Requests Sent", fmt.Sprintf("%d", atomic.LoadUint64(&mc.totalRequestsSent)))
@@ -176,51 +1009,54 @@ func (mc *MetricsCollector) LogMetrics(logFormat string) {
 		_ = table.Append("Total TCP Requests Sent", fmt.Sprintf("%d", atomic.LoadUint64(&mc.totalTCPSent)))
 		_ = table.Append("Total UDP Requests Received", fmt.Sprintf("%d", atomic.LoadUint64(&mc.totalUDPReceived)))
 		_ = table.Append("Total UDP Requests Sent", fmt.Sprintf("%d", atomic.LoadUint64(&mc.totalUDPSent)))
-		fmt.Println("Total Metrics:")
+		_, _ = fmt.Fprintln(w, "Total Metrics:")
 		_ = table.Render()
 
 		// Per-Protocol/Port Metrics
 		requestsReceived := mc.getSyncMapData(&mc.requestsReceived)
 		if len(requestsReceived) > 0 {
-			printTable("Requests Received Per-protocol/port:", []string{"Protocol", "Port", "Requests Received"}, requestsReceived, false)
+			printTable(w, "Requests Received Per-protocol/port:", []string{"Protocol", "Port", "Requests Received"}, requestsReceived, false)
 		}
 
 		requestsSent := mc.getSyncMapData(&mc.requestsSent)
 		if len(requestsSent) > 0 {
-			printTable("Requests Sent Per-protocol/port:", []string{"Protocol", "Port", "Requests Sent"}, requestsSent, false)
+			printTable(w, "Requests Sent Per-protocol/port:", []string{"Protocol", "Port", "Requests Sent"}, requestsSent, false)
 		}
 
 		bytesReceived := mc.getSyncMapData(&mc.bytesReceived)
 		if len(bytesReceived) > 0 {
-			printTable("Bytes Received Per-protocol/port:", []string{"Protocol", "Port", "Bytes Received"}, bytesReceived, false)
+			printTable(w, "Bytes Received Per-protocol/port:", []string{"Protocol", "Port", "Bytes Received"}, bytesReceived, false)
 		}
 
 		bytesSent := mc.getSyncMapData(&mc.bytesSent)
 		if len(bytesSent) > 0 {
-			printTable("Bytes Sent Per-protocol/port:", []string{"Protocol", "Port", "Bytes Sent"}, bytesSent, false)
+			printTable(w, "Bytes Sent Per-protocol/port:", []string{"Protocol", "Port", "Bytes Sent"}, bytesSent, false)
+		}
+
+		wireBytesReceived := mc.getSyncMapData(&mc.wireBytesReceived)
+		if len(wireBytesReceived) > 0 {
+			printTable(w, "Estimated On-wire Bytes Received Per-protocol/port:", []string{"Protocol", "Port", "Wire Bytes Received"}, wireBytesReceived, false)
+		}
+
+		wireBytesSent := mc.getSyncMapData(&mc.wireBytesSent)
+		if len(wireBytesSent) > 0 {
+			printTable(w, "Estimated On-wire Bytes Sent Per-protocol/port:", []string{"Protocol", "Port", "Wire Bytes Sent"}, wireBytesSent, false)
+		}
+
+		if latency := mc.LatencySummaries(); len(latency) > 0 {
+			printLatencyTable(w, latency)
 		}
 	} else {
 		// JSON output for non-human formats
-		metricsData := map[string]interface{}{
-			"total_requests_received": atomic.LoadUint64(&mc.totalRequestsReceived),
-			"total_requests_sent":     atomic.LoadUint64(&mc.totalRequestsSent),
-			"total_tcp_received":      atomic.LoadUint64(&mc.totalTCPReceived),
-			"total_tcp_sent":          atomic.LoadUint64(&mc.totalTCPSent),
-			"total_udp_received":      atomic.LoadUint64(&mc.totalUDPReceived),
-			"total_udp_sent":          atomic.LoadUint64(&mc.totalUDPSent),
-			"requests_received":       mc.getSyncMapData(&mc.requestsReceived),
-			"requests_sent":           mc.getSyncMapData(&mc.requestsSent),
-			"bytes_received":          mc.getSyncMapData(&mc.bytesReceived),
-			"bytes_sent":              mc.getSyncMapData(&mc.bytesSent),
-		}
+		metricsData := mc.Summary()
 		jsonData, _ := json.MarshalIndent(metricsData, "", "  ")
 		logging.Logger.Infof("Application terminated. Metrics:\n%s", string(jsonData))
 	}
 }
 
 // printTable prints a sorted table for a given metrics category
-func printTable(title string, headers []string, data map[string]map[string]uint64, supportsColor bool) {
-	table := tablewriter.NewWriter(os.Stdout)
+func printTable(w io.Writer, title string, headers []string, data map[string]map[string]uint64, supportsColor bool) {
+	table := tablewriter.NewWriter(w)
 	table.Header(headers[0], headers[1], headers[2])
 	// Sort protocols alphabetically
 	var protocols []string
@@ -245,7 +1081,26 @@ func printTable(title string, headers []string, data map[string]map[string]uint6
 			_ = table.Append(protocol, port, fmt.Sprintf("%d", count))
 		}
 	}
-	fmt.Println(title)
+	_, _ = fmt.Fprintln(w, title)
+	_ = table.Render()
+}
+
+// printLatencyTable prints the latency statistics per protocol
+func printLatencyTable(w io.Writer, latency map[string]LatencySummary) {
+	table := tablewriter.NewWriter(w)
+	table.Header("Protocol", "Count", "Min (ms)", "Mean (ms)", "P50 (ms)", "P90 (ms)", "P99 (ms)", "Max (ms)")
+	protocols := make([]string, 0, len(latency))
+	for protocol := range latency {
+		protocols = append(protocols, protocol)
+	}
+	sort.Strings(protocols)
+	for _, protocol := range protocols {
+		l := latency[protocol]
+		_ = table.Append(protocol, strconv.FormatUint(l.Count, 10),
+			fmt.Sprintf("%.3f", l.MinMs), fmt.Sprintf("%.3f", l.MeanMs), fmt.Sprintf("%.3f", l.P50Ms),
+			fmt.Sprintf("%.3f", l.P90Ms), fmt.Sprintf("%.3f", l.P99Ms), fmt.Sprintf("%.3f", l.MaxMs))
+	}
+	_, _ = fmt.Fprintln(w, "Request Latency Per-protocol:")
 	_ = table.Render()
 }
 
@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
 	"os"
@@ -9,16 +10,18 @@ import (
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 // testMetricsCollector creates a MetricsCollector without registering metrics
 func testMetricsCollector() *MetricsCollector {
-	return &MetricsCollector{
+	mc := &MetricsCollector{
 		RequestsReceived: prometheus.NewCounterVec(
 			prometheus.CounterOpts{Name: "test_requests_received_total", Help: "Test"},
 			[]string{"protocol", "port"},
@@ -35,6 +38,14 @@ func testMetricsCollector() *MetricsCollector {
 			prometheus.CounterOpts{Name: "test_bytes_sent_total", Help: "Test"},
 			[]string{"protocol", "port"},
 		),
+		WireBytesReceived: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_wire_bytes_received_total", Help: "Test"},
+			[]string{"protocol", "port"},
+		),
+		WireBytesSent: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_wire_bytes_sent_total", Help: "Test"},
+			[]string{"protocol", "port"},
+		),
 		TCPConnectionsOpenedPerSecond: prometheus.NewCounter(
 			prometheus.CounterOpts{Name: "test_tcp_connections_opened_total", Help: "Test"},
 		),
@@ -44,11 +55,208 @@ func testMetricsCollector() *MetricsCollector {
 		ActiveTCPConnections: prometheus.NewGauge(
 			prometheus.GaugeOpts{Name: "test_active_tcp_connections", Help: "Test"},
 		),
+		TCPConnectionsReused: prometheus.NewCounter(
+			prometheus.CounterOpts{Name: "test_tcp_connections_reused_total", Help: "Test"},
+		),
+		UDPConnectedPeers: prometheus.NewGauge(
+			prometheus.GaugeOpts{Name: "test_udp_connected_peers", Help: "Test"},
+		),
+		UDPPeerICMPErrors: prometheus.NewCounter(
+			prometheus.CounterOpts{Name: "test_udp_peer_icmp_errors_total", Help: "Test"},
+		),
+		BackpressureActive: prometheus.NewGauge(
+			prometheus.GaugeOpts{Name: "test_backpressure_active", Help: "Test"},
+		),
+		BackpressureSignals: prometheus.NewCounter(
+			prometheus.CounterOpts{Name: "test_backpressure_signals_total", Help: "Test"},
+		),
+		EffectiveFlowRate: prometheus.NewGauge(
+			prometheus.GaugeOpts{Name: "test_flow_rate_effective", Help: "Test"},
+		),
+		UpstreamRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_upstream_requests_total", Help: "Test"},
+			[]string{"protocol", "port"},
+		),
+		UpstreamErrors: prometheus.NewCounter(
+			prometheus.CounterOpts{Name: "test_upstream_errors_total", Help: "Test"},
+		),
+		RelayedConnections: prometheus.NewCounter(
+			prometheus.CounterOpts{Name: "test_relayed_connections_total", Help: "Test"},
+		),
+		RelayHopSetup: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{Name: "test_relay_hop_setup_seconds", Help: "Test"},
+			[]string{"hop"},
+		),
+		RequestLatency: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{Name: "test_request_latency_seconds", Help: "Test"},
+			[]string{"protocol", "port"},
+		),
+		FlowStartGapRatio: prometheus.NewHistogram(
+			prometheus.HistogramOpts{Name: "test_flow_start_gap_ratio", Help: "Test"},
+		),
+		FlowStartBurstiness: prometheus.NewGauge(
+			prometheus.GaugeOpts{Name: "test_flow_start_burstiness", Help: "Test"},
+		),
+		FlowsSkipped: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_flows_skipped_total", Help: "Test"},
+			[]string{"priority"},
+		),
+		FlowsPreempted: prometheus.NewCounter(
+			prometheus.CounterOpts{Name: "test_flows_preempted_total", Help: "Test"},
+		),
+		EchoDelay: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{Name: "test_echo_delay_seconds", Help: "Test", Buckets: EchoDelayBuckets},
+			[]string{"protocol", "port"},
+		),
+		ConnectionsClosed: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_connections_closed_total", Help: "Test"},
+			[]string{"reason"},
+		),
+		FlowsMarked: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_flows_marked_total", Help: "Test"},
+			[]string{"protocol", "port", "dscp"},
+		),
+		SourceFlows: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_source_flows_total", Help: "Test"},
+			[]string{"source", "protocol"},
+		),
+		SourceActiveFlows: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_source_active_flows", Help: "Test"},
+			[]string{"source"},
+		),
+		HalfOpenFlows: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_half_open_flows_total", Help: "Test"},
+			[]string{"protocol", "port"},
+		),
+		HalfOpenActiveFlows: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_half_open_active_flows", Help: "Test"},
+			[]string{"protocol"},
+		),
+		ConnectionsChurned: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_churned_connections_total", Help: "Test"},
+			[]string{"protocol", "port"},
+		),
+		UniqueTuples: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_unique_tuples_total", Help: "Test"},
+			[]string{"protocol"},
+		),
+		LoadStageRate: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_load_stage_rate", Help: "Test"},
+			[]string{"stage"},
+		),
+		StageFlows: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_stage_flows_total", Help: "Test"},
+			[]string{"stage", "result"},
+		),
+		StageFlowLatency: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{Name: "test_stage_flow_latency_seconds", Help: "Test", Buckets: prometheus.DefBuckets},
+			[]string{"stage"},
+		),
+		CBRDatagramsLost: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_cbr_datagrams_lost_total", Help: "Test"},
+			[]string{"protocol", "port"},
+		),
+		CBRDatagramsOutOfOrder: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_cbr_datagrams_out_of_order_total", Help: "Test"},
+			[]string{"protocol", "port"},
+		),
+		CBRJitter: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{Name: "test_cbr_jitter_seconds", Help: "Test", Buckets: EchoDelayBuckets},
+			[]string{"protocol"},
+		),
+		FlowsMimicked: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_mimicked_flows_total", Help: "Test"},
+			[]string{"protocol", "port", "mimic"},
+		),
+		SoftLimitWarnings: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_soft_limit_warnings_total", Help: "Test"},
+			[]string{"limit"},
+		),
+		NetnsFlows: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_netns_flows_total", Help: "Test"},
+			[]string{"netns", "protocol", "result"},
+		),
+		NetnsActiveFlows: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_netns_active_flows", Help: "Test"},
+			[]string{"netns"},
+		),
+		ConntrackBackoffs: prometheus.NewCounter(
+			prometheus.CounterOpts{Name: "test_conntrack_backoffs_total", Help: "Test"},
+		),
+		ConntrackBackoffActive: prometheus.NewGauge(
+			prometheus.GaugeOpts{Name: "test_conntrack_backoff_active", Help: "Test"},
+		),
+		FlowsByFamily: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_flows_by_family_total", Help: "Test"},
+			[]string{"protocol", "family"},
+		),
+		ConnectRetries: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_connect_retries_total", Help: "Test"},
+			[]string{"protocol"},
+		),
+		FlowErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_flow_errors_total", Help: "Test"},
+			[]string{"protocol", "port", "reason"},
+		),
+		ICMPErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_icmp_errors_total", Help: "Test"},
+			[]string{"protocol", "port", "type"},
+		),
+		DuplicateFlows: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_duplicate_flows_total", Help: "Test"},
+			[]string{"protocol", "port"},
+		),
+		ReplayedDatagrams: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_replayed_datagrams_total", Help: "Test"},
+			[]string{"port"},
+		),
+		ResponsesDropped: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_responses_dropped_total", Help: "Test"},
+			[]string{"protocol", "port"},
+		),
+		ConnectionsRejected: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_connections_rejected_total", Help: "Test"},
+			[]string{"limit"},
+		),
+		ConnectionLimitSaturation: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_connection_limit_saturation", Help: "Test"},
+			[]string{"limit"},
+		),
+		TLSHandshakes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_tls_handshakes_total", Help: "Test"},
+			[]string{"port", "sni", "version", "cipher_suite"},
+		),
+		TLSHandshakeFailures: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_tls_handshake_failures_total", Help: "Test"},
+			[]string{"port"},
+		),
+		HTTPRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_http_requests_total", Help: "Test"},
+			[]string{"port", "method", "path", "code"},
+		),
+		HTTPRequestDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{Name: "test_http_request_duration_seconds", Help: "Test", Buckets: EchoDelayBuckets},
+			[]string{"port", "path"},
+		),
+		ResponseReadThroughput: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{Name: "test_response_read_throughput_bytes_per_second", Help: "Test", Buckets: ReadThroughputBuckets},
+			[]string{"protocol", "port"},
+		),
+		PeerRTT: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_peer_rtt_seconds", Help: "Test"},
+			[]string{"src", "dst"},
+		),
+		PeerLoss: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_peer_loss_ratio", Help: "Test"},
+			[]string{"src", "dst"},
+		),
 		requestsReceived: sync.Map{},
 		requestsSent:     sync.Map{},
 		bytesReceived:    sync.Map{},
 		bytesSent:        sync.Map{},
 	}
+	mc.initRates(time.Now(), "test_")
+	return mc
 }
 
 func TestNewMetricsCollector(t *testing.T) {
@@ -63,9 +271,24 @@ func TestNewMetricsCollector(t *testing.T) {
 	assert.NotNil(t, mc.RequestsSent)
 	assert.NotNil(t, mc.BytesReceived)
 	assert.NotNil(t, mc.BytesSent)
+	assert.NotNil(t, mc.WireBytesReceived)
+	assert.NotNil(t, mc.WireBytesSent)
 	assert.NotNil(t, mc.TCPConnectionsOpenedPerSecond)
 	assert.NotNil(t, mc.UDPPacketsReceived)
 	assert.NotNil(t, mc.ActiveTCPConnections)
+	assert.NotNil(t, mc.TCPConnectionsReused)
+	assert.NotNil(t, mc.UDPConnectedPeers)
+	assert.NotNil(t, mc.UDPPeerICMPErrors)
+	assert.NotNil(t, mc.BackpressureActive)
+	assert.NotNil(t, mc.BackpressureSignals)
+	assert.NotNil(t, mc.EffectiveFlowRate)
+	assert.NotNil(t, mc.UpstreamRequests)
+	assert.NotNil(t, mc.UpstreamErrors)
+	assert.NotNil(t, mc.RelayedConnections)
+	assert.NotNil(t, mc.RelayHopSetup)
+	assert.NotNil(t, mc.RequestLatency)
+	assert.NotNil(t, mc.FlowStartGapRatio)
+	assert.NotNil(t, mc.FlowStartBurstiness)
 
 	assert.True(t, metricsRegistered)
 }
@@ -138,6 +361,20 @@ func TestAddBytesSent(t *testing.T) {
 	assert.Equal(t, uint64(512), data["udp"]["9000"])
 }
 
+func TestAddWireBytes(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.AddWireBytesSent("tcp", "8080", 154)
+	mc.AddWireBytesReceived("tcp", "8080", 154)
+	mc.AddWireBytesSent("tcp", "8080", -1)
+
+	assert.Equal(t, float64(154), testutil.ToFloat64(mc.WireBytesSent.WithLabelValues("tcp", "8080")))
+	assert.Equal(t, float64(154), testutil.ToFloat64(mc.WireBytesReceived.WithLabelValues("tcp", "8080")))
+	s := mc.Summary()
+	assert.Equal(t, uint64(154), s.WireBytesSent["tcp"]["8080"])
+	assert.Equal(t, uint64(154), s.WireBytesReceived["tcp"]["8080"])
+}
+
 func TestIncTCPConnectionsOpened(t *testing.T) {
 	mc := testMetricsCollector()
 
@@ -148,6 +385,442 @@ func TestIncTCPConnectionsOpened(t *testing.T) {
 	})
 }
 
+func TestIncTCPConnectionsReused(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.IncTCPConnectionsReused()
+	mc.IncTCPConnectionsReused()
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.TCPConnectionsReused))
+}
+
+func TestUpstreamMetrics(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.IncUpstreamRequests("tcp", "8080")
+	mc.IncUpstreamRequests("tcp", "8080")
+	mc.IncUpstreamErrors()
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.UpstreamRequests.WithLabelValues("tcp", "8080")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.UpstreamErrors))
+}
+
+func TestRelayMetrics(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.IncRelayedConnections()
+	mc.ObserveRelayHopSetup(1, 5*time.Millisecond)
+	mc.ObserveRelayHopSetup(2, 7*time.Millisecond)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.RelayedConnections))
+	assert.Equal(t, 2, testutil.CollectAndCount(mc.RelayHopSetup))
+}
+
+func TestObserveLatency(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.ObserveLatency("tcp", "8080", 2*time.Millisecond)
+	mc.ObserveLatency("tcp", "8081", 4*time.Millisecond)
+	mc.ObserveLatency("udp", "9000", time.Millisecond)
+
+	assert.Equal(t, 3, testutil.CollectAndCount(mc.RequestLatency))
+	latency := mc.LatencySummaries()
+	require.Len(t, latency, 2)
+	assert.Equal(t, uint64(2), latency["tcp"].Count)
+	assert.Equal(t, 3.0, latency["tcp"].MeanMs)
+	assert.Equal(t, 1.0, latency["udp"].MaxMs)
+}
+
+func TestObserveFlowStartGap(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.ObserveFlowStartGap(0.5, 0.05)
+	mc.ObserveFlowStartGap(1.5, 0.1)
+
+	assert.Equal(t, 1, testutil.CollectAndCount(mc.FlowStartGapRatio))
+	assert.Equal(t, 0.1, testutil.ToFloat64(mc.FlowStartBurstiness))
+}
+
+func TestObserveEchoDelay(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.ObserveEchoDelay("tcp", "8080", 20*time.Microsecond)
+	mc.ObserveEchoDelay("tcp", "8080", 2*time.Millisecond)
+	mc.ObserveEchoDelay("udp", "9000", time.Millisecond)
+
+	// One series per protocol/port
+	assert.Equal(t, 2, testutil.CollectAndCount(mc.EchoDelay))
+}
+
+func TestIncConnectionsClosed(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.IncConnectionsClosed("fin")
+	mc.IncConnectionsClosed("reset")
+	mc.IncConnectionsClosed("reset")
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.ConnectionsClosed.WithLabelValues("fin")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.ConnectionsClosed.WithLabelValues("reset")))
+}
+
+func TestIncFlowsMarked(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.IncFlowsMarked("udp", "53", "ef")
+	mc.IncFlowsMarked("udp", "53", "ef")
+	mc.IncFlowsMarked("tcp", "8080", "af11")
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.FlowsMarked.WithLabelValues("udp", "53", "ef")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.FlowsMarked.WithLabelValues("tcp", "8080", "af11")))
+}
+
+func TestSourceFlows(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.SourceFlowStarted("10.0.0.1", "tcp")
+	mc.SourceFlowStarted("10.0.0.1", "udp")
+	mc.SourceFlowStarted("eth1", "tcp")
+	mc.SourceFlowEnded("10.0.0.1")
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.SourceFlows.WithLabelValues("10.0.0.1", "tcp")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.SourceFlows.WithLabelValues("10.0.0.1", "udp")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.SourceActiveFlows.WithLabelValues("10.0.0.1")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.SourceActiveFlows.WithLabelValues("eth1")))
+}
+
+func TestHalfOpenFlows(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.HalfOpenFlowStarted("tcp_handshake", "8080")
+	mc.HalfOpenFlowStarted("tcp_handshake", "8080")
+	mc.HalfOpenFlowStarted("tcp_noread", "8081")
+	mc.HalfOpenFlowEnded("tcp_handshake")
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.HalfOpenFlows.WithLabelValues("tcp_handshake", "8080")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.HalfOpenFlows.WithLabelValues("tcp_noread", "8081")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.HalfOpenActiveFlows.WithLabelValues("tcp_handshake")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.HalfOpenActiveFlows.WithLabelValues("tcp_noread")))
+}
+
+func TestIncConnectionsChurned(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.IncConnectionsChurned("tcp_churn", "8080")
+	mc.IncConnectionsChurned("tcp_churn", "8080")
+	mc.IncConnectionsChurned("tcp_churn_rst", "8080")
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.ConnectionsChurned.WithLabelValues("tcp_churn", "8080")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.ConnectionsChurned.WithLabelValues("tcp_churn_rst", "8080")))
+}
+
+func TestIncUniqueTuples(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.IncUniqueTuples("tcp")
+	mc.IncUniqueTuples("tcp")
+	mc.IncUniqueTuples("udp")
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.UniqueTuples.WithLabelValues("tcp")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.UniqueTuples.WithLabelValues("udp")))
+}
+
+func TestObserveCBRFlow(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.ObserveCBRFlow("udp", "5001", 3, 1, 200*time.Microsecond)
+	mc.ObserveCBRFlow("udp", "5001", 2, 0, 0)
+
+	assert.Equal(t, float64(5), testutil.ToFloat64(mc.CBRDatagramsLost.WithLabelValues("udp", "5001")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.CBRDatagramsOutOfOrder.WithLabelValues("udp", "5001")))
+	assert.Equal(t, 1, testutil.CollectAndCount(mc.CBRJitter))
+}
+
+func TestLoadStageMetrics(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.SetLoadStageRate("step1", 100)
+	mc.SetLoadStageRate("step1", 0)
+	mc.SetLoadStageRate("step2", 500)
+	mc.ObserveStageFlow("step2", "completed", 2*time.Millisecond)
+	mc.ObserveStageFlow("step2", "failed", 0)
+
+	assert.Equal(t, float64(0), testutil.ToFloat64(mc.LoadStageRate.WithLabelValues("step1")))
+	assert.Equal(t, float64(500), testutil.ToFloat64(mc.LoadStageRate.WithLabelValues("step2")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.StageFlows.WithLabelValues("step2", "completed")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.StageFlows.WithLabelValues("step2", "failed")))
+	assert.Equal(t, 1, testutil.CollectAndCount(mc.StageFlowLatency))
+}
+
+func TestIncFlowsMimicked(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.IncFlowsMimicked("tcp", "443", "tls")
+	mc.IncFlowsMimicked("tcp", "443", "tls")
+	mc.IncFlowsMimicked("udp", "53", "dns")
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.FlowsMimicked.WithLabelValues("tcp", "443", "tls")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.FlowsMimicked.WithLabelValues("udp", "53", "dns")))
+}
+
+func TestIncSoftLimitWarnings(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.IncSoftLimitWarnings("max_error_rate")
+	mc.IncSoftLimitWarnings("max_error_rate")
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.SoftLimitWarnings.WithLabelValues("max_error_rate")))
+}
+
+func TestNetnsFlows(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.NetnsFlowStarted("client-a")
+	mc.NetnsFlowStarted("client-a")
+	mc.NetnsFlowStarted("client-b")
+	mc.NetnsFlowEnded("client-a", "tcp", false)
+	mc.NetnsFlowEnded("client-b", "udp", true)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.NetnsFlows.WithLabelValues("client-a", "tcp", "completed")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.NetnsFlows.WithLabelValues("client-b", "udp", "failed")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.NetnsActiveFlows.WithLabelValues("client-a")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(mc.NetnsActiveFlows.WithLabelValues("client-b")))
+}
+
+func TestConntrackBackoff(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.ConntrackBackoffStarted()
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.ConntrackBackoffActive))
+	mc.ConntrackBackoffEnded()
+	mc.ConntrackBackoffStarted()
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.ConntrackBackoffs))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.ConntrackBackoffActive))
+	mc.ConntrackBackoffEnded()
+	assert.Equal(t, float64(0), testutil.ToFloat64(mc.ConntrackBackoffActive))
+}
+
+func TestIncFlowsByFamily(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.IncFlowsByFamily("tcp", "ipv6")
+	mc.IncFlowsByFamily("tcp", "ipv6")
+	mc.IncFlowsByFamily("udp", "ipv4")
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.FlowsByFamily.WithLabelValues("tcp", "ipv6")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.FlowsByFamily.WithLabelValues("udp", "ipv4")))
+}
+
+func TestIncConnectRetries(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.IncConnectRetries("tcp")
+	mc.IncConnectRetries("tcp")
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.ConnectRetries.WithLabelValues("tcp")))
+}
+
+func TestIncFlowErrors(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.IncFlowErrors("tcp", "8080", "refused")
+	mc.IncFlowErrors("tcp", "8080", "refused")
+	mc.IncFlowErrors("udp", "53", "timeout")
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.FlowErrors.WithLabelValues("tcp", "8080", "refused")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.FlowErrors.WithLabelValues("udp", "53", "timeout")))
+}
+
+func TestWarmup(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.SetWarmup(true)
+	assert.True(t, mc.WarmingUp())
+	mc.IncRequestsSent("tcp", "8080")
+	mc.AddBytesSent("tcp", "8080", 100)
+	mc.AddBytesReceived("tcp", "8080", 100)
+	mc.AddWireBytesSent("tcp", "8080", 154)
+	mc.ObserveLatency("tcp", "8080", 5*time.Millisecond)
+	mc.IncFlowErrors("tcp", "8080", "timeout")
+
+	assert.Equal(t, float64(0), testutil.ToFloat64(mc.RequestsSent.WithLabelValues("tcp", "8080")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(mc.FlowErrors.WithLabelValues("tcp", "8080", "timeout")))
+	summary := mc.Summary()
+	assert.Zero(t, summary.TotalRequestsSent)
+	assert.Empty(t, summary.BytesSent)
+	assert.Empty(t, summary.Latency)
+
+	mc.SetWarmup(false)
+	assert.False(t, mc.WarmingUp())
+	mc.IncRequestsSent("tcp", "8080")
+	mc.ObserveLatency("tcp", "8080", 2*time.Millisecond)
+
+	summary = mc.Summary()
+	assert.Equal(t, uint64(1), summary.TotalRequestsSent)
+	assert.Equal(t, uint64(1), summary.Latency["tcp"].Count)
+}
+
+func TestIncICMPErrors(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.IncICMPErrors("udp", "53", "admin_prohibited")
+	mc.IncICMPErrors("udp", "53", "admin_prohibited")
+	mc.IncICMPErrors("udp", "53", "port_unreachable")
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.ICMPErrors.WithLabelValues("udp", "53", "admin_prohibited")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.ICMPErrors.WithLabelValues("udp", "53", "port_unreachable")))
+}
+
+func TestIncDuplicateFlows(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.IncDuplicateFlows("tcp", "8080")
+	mc.IncReplayedDatagrams("53")
+	mc.IncReplayedDatagrams("53")
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.DuplicateFlows.WithLabelValues("tcp", "8080")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.ReplayedDatagrams.WithLabelValues("53")))
+}
+
+func TestIncResponsesDropped(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.IncResponsesDropped("udp", "8081")
+	mc.IncResponsesDropped("udp", "8081")
+	mc.IncResponsesDropped("tcp", "8080")
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.ResponsesDropped.WithLabelValues("udp", "8081")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.ResponsesDropped.WithLabelValues("tcp", "8080")))
+}
+
+func TestTLSHandshakeMetrics(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.IncTLSHandshakes("8443", "a.example.com", "TLS 1.3", "TLS_AES_128_GCM_SHA256")
+	mc.IncTLSHandshakes("8443", "a.example.com", "TLS 1.3", "TLS_AES_128_GCM_SHA256")
+	mc.IncTLSHandshakeFailures("8443")
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.TLSHandshakes.WithLabelValues("8443", "a.example.com", "TLS 1.3", "TLS_AES_128_GCM_SHA256")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.TLSHandshakeFailures.WithLabelValues("8443")))
+}
+
+func TestHTTPRequestMetrics(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.ObserveHTTPRequest("8080", "GET", "/", 200, time.Millisecond)
+	mc.ObserveHTTPRequest("8080", "POST", "/api", 200, time.Millisecond)
+	mc.ObserveHTTPRequest("8080", "POST", "/api", 413, time.Millisecond)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.HTTPRequests.WithLabelValues("8080", "GET", "/", "200")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.HTTPRequests.WithLabelValues("8080", "POST", "/api", "413")))
+	assert.Equal(t, 2, testutil.CollectAndCount(mc.HTTPRequestDuration, "test_http_request_duration_seconds"))
+}
+
+func TestObserveResponseRead(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.ObserveResponseRead("tcp", "8080", 1<<20, 10*time.Millisecond)
+	mc.ObserveResponseRead("tcp", "9090", 1024, 0)
+
+	// A response read at once is not recorded
+	assert.Equal(t, 1, testutil.CollectAndCount(mc.ResponseReadThroughput))
+}
+
+func TestConnectionLimitMetrics(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.IncConnectionsRejected("8080")
+	mc.IncConnectionsRejected("8080")
+	mc.IncConnectionsRejected("global")
+	mc.SetConnectionLimitSaturation("8080", 1)
+	mc.SetConnectionLimitSaturation("global", 0.25)
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.ConnectionsRejected.WithLabelValues("8080")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.ConnectionsRejected.WithLabelValues("global")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.ConnectionLimitSaturation.WithLabelValues("8080")))
+	assert.Equal(t, 0.25, testutil.ToFloat64(mc.ConnectionLimitSaturation.WithLabelValues("global")))
+}
+
+func TestSetPeerProbe(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.SetPeerProbe("node-a", "10.0.0.2", 2*time.Millisecond, 0.25, true)
+	assert.Equal(t, 0.002, testutil.ToFloat64(mc.PeerRTT.WithLabelValues("node-a", "10.0.0.2")))
+	assert.Equal(t, 0.25, testutil.ToFloat64(mc.PeerLoss.WithLabelValues("node-a", "10.0.0.2")))
+
+	// A peer without responses keeps its loss but has no round-trip time
+	mc.SetPeerProbe("node-a", "10.0.0.2", 0, 1, false)
+	assert.Equal(t, 0, testutil.CollectAndCount(mc.PeerRTT))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.PeerLoss.WithLabelValues("node-a", "10.0.0.2")))
+
+	mc.DeletePeer("node-a", "10.0.0.2")
+	assert.Equal(t, 0, testutil.CollectAndCount(mc.PeerLoss))
+}
+
+func TestFlowsSkippedAndPreempted(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.IncFlowsSkipped("low")
+	mc.IncFlowsSkipped("low")
+	mc.IncFlowsSkipped("high")
+	mc.IncFlowsPreempted()
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.FlowsSkipped.WithLabelValues("low")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.FlowsSkipped.WithLabelValues("high")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.FlowsPreempted))
+}
+
+func TestSummary(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.IncRequestsSent("tcp", "8080")
+	mc.IncRequestsReceived("udp", "9000")
+	mc.AddBytesSent("tcp", "8080", 100)
+	mc.ObserveLatency("tcp", "8080", time.Millisecond)
+
+	s := mc.Summary()
+	assert.Equal(t, uint64(1), s.TotalRequestsSent)
+	assert.Equal(t, uint64(1), s.TotalTCPSent)
+	assert.Equal(t, uint64(1), s.TotalUDPReceived)
+	assert.Equal(t, uint64(1), s.RequestsSent["tcp"]["8080"])
+	assert.Equal(t, uint64(100), s.BytesSent["tcp"]["8080"])
+	assert.Equal(t, uint64(1), s.Latency["tcp"].Count)
+}
+
+func TestRateGauges(t *testing.T) {
+	mc := testMetricsCollector()
+	// Rates are over the time since the collector was created while the window fills up
+	mc.initRates(time.Now().Add(-RateWindow), "test_")
+
+	mc.AddBytesSent("tcp", "8080", 1000)
+	mc.AddBytesReceived("tcp", "8080", 500)
+	mc.ObserveFlowStarted()
+	mc.ObserveFlowStarted()
+	assert.Greater(t, testutil.ToFloat64(mc.BytesSentRate), 0.0)
+	assert.InDelta(t, 2*testutil.ToFloat64(mc.BytesReceivedRate), testutil.ToFloat64(mc.BytesSentRate), 1)
+	assert.InDelta(t, testutil.ToFloat64(mc.BytesReceivedRate)/250, testutil.ToFloat64(mc.FlowsStartedRate), 0.01)
+	assert.LessOrEqual(t, testutil.ToFloat64(mc.BytesSentRate), 1000/(RateWindow-RateWindow/rateWindowBuckets).Seconds())
+	assert.Contains(t, mc.BytesSentRate.Desc().String(), "over a sliding window of 10s: the last 9 complete slots of 1s and the elapsed part of the current one")
+
+	// Nothing is counted during warmup
+	warm := testMetricsCollector()
+	warm.SetWarmup(true)
+	warm.AddBytesSent("tcp", "8080", 1000)
+	warm.ObserveFlowStarted()
+	assert.Equal(t, 0.0, testutil.ToFloat64(warm.BytesSentRate))
+	assert.Equal(t, 0.0, testutil.ToFloat64(warm.FlowsStartedRate))
+}
+
+func TestConnectionsClosedCounts(t *testing.T) {
+	mc := testMetricsCollector()
+	assert.Empty(t, mc.ConnectionsClosedCounts())
+
+	mc.IncConnectionsClosed("fin")
+	mc.IncConnectionsClosed("fin")
+	mc.IncConnectionsClosed("reset")
+	assert.Equal(t, map[string]uint64{"fin": 2, "reset": 1}, mc.ConnectionsClosedCounts())
+}
+
 func TestIncUDPPacketsReceived(t *testing.T) {
 	mc := testMetricsCollector()
 
@@ -165,6 +838,27 @@ func TestSetActiveTCPConnections(t *testing.T) {
 		mc.SetActiveTCPConnections(10)
 		mc.SetActiveTCPConnections(3)
 	})
+	assert.Equal(t, int64(3), mc.ActiveTCPConnectionCount())
+
+	mc.IncActiveTCPConnections()
+	mc.DecActiveTCPConnections()
+	mc.DecActiveTCPConnections()
+	assert.Equal(t, int64(2), mc.ActiveTCPConnectionCount())
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.ActiveTCPConnections))
+}
+
+func TestBackpressureMetrics(t *testing.T) {
+	mc := testMetricsCollector()
+
+	mc.IncBackpressureSignals()
+	mc.SetBackpressureActive(true)
+	mc.SetEffectiveFlowRate(5)
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.BackpressureSignals))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.BackpressureActive))
+	assert.Equal(t, float64(5), testutil.ToFloat64(mc.EffectiveFlowRate))
+
+	mc.SetBackpressureActive(false)
+	assert.Equal(t, float64(0), testutil.ToFloat64(mc.BackpressureActive))
 }
 
 func TestUpdateSyncMap(t *testing.T) {
@@ -223,6 +917,22 @@ func TestLogMetricsHuman(t *testing.T) {
 	assert.Contains(t, outputStr, "Bytes Sent Per-protocol/port:")
 }
 
+func TestLogMetricsTableOutput(t *testing.T) {
+	logging.InitLogger("json", "error")
+
+	mc := testMetricsCollector()
+	mc.updateSyncMap(&mc.requestsSent, "tcp", "8080", 60)
+	mc.ObserveLatency("tcp", "8080", time.Millisecond)
+
+	var buf bytes.Buffer
+	mc.SetTableOutput(&buf)
+	mc.LogMetrics("human")
+
+	assert.Contains(t, buf.String(), "Total Metrics:")
+	assert.Contains(t, buf.String(), "Requests Sent Per-protocol/port:")
+	assert.Contains(t, buf.String(), "Request Latency Per-protocol:")
+}
+
 func TestLogMetricsJSON(t *testing.T) {
 	logging.InitLogger("json", "error")
 
@@ -282,7 +992,7 @@ func TestPrintTable(t *testing.T) {
 		},
 	}
 
-	printTable("Test Table:", []string{"Protocol", "Port", "Count"}, data, false)
+	printTable(os.Stdout, "Test Table:", []string{"Protocol", "Port", "Count"}, data, false)
 
 	_ = w.Close()
 	os.Stdout = oldStdout
@@ -440,7 +1150,7 @@ func TestPrintTableSorting(t *testing.T) {
 		},
 	}
 
-	printTable("Port Sorting Test:", []string{"Protocol", "Port", "Count"}, data, false)
+	printTable(os.Stdout, "Port Sorting Test:", []string{"Protocol", "Port", "Count"}, data, false)
 
 	_ = w.Close()
 	os.Stdout = oldStdout
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// HeatmapBucketsMs are the upper bounds in milliseconds of the latency ranges of the heatmap, from round trips
+// within a host to stalled requests. Latencies above the last bound are counted in an overflow bucket.
+var HeatmapBucketsMs = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// LatencyHeatmap holds the number of latencies observed per time slice of the run and latency range.
+type LatencyHeatmap struct {
+	SliceSeconds float64   `json:"slice_seconds"`
+	BucketsMs    []float64 `json:"buckets_ms"`
+	// Counts has a row per time slice since the start of the run, with a count per bucket followed by the
+	// count of the overflow bucket.
+	Counts [][]uint64 `json:"counts"`
+}
+
+// latencyHeatmap counts the latencies observed by time slice and latency bucket.
+type latencyHeatmap struct {
+	mu    sync.Mutex
+	start time.Time
+	slice time.Duration
+	rows  [][]uint64
+}
+
+// newLatencyHeatmap creates an empty heatmap with time slices of the given length starting at start.
+func newLatencyHeatmap(start time.Time, slice time.Duration) *latencyHeatmap {
+	return &latencyHeatmap{start: start, slice: slice}
+}
+
+// observe records a latency observed at the given time.
+func (h *latencyHeatmap) observe(at time.Time, d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	row := max(int(at.Sub(h.start)/h.slice), 0)
+	for len(h.rows) <= row {
+		h.rows = append(h.rows, make([]uint64, len(HeatmapBucketsMs)+1))
+	}
+	h.rows[row][sort.SearchFloat64s(HeatmapBucketsMs, milliseconds(d))]++
+}
+
+// heatmap returns a copy of the counts observed so far.
+func (h *latencyHeatmap) heatmap() LatencyHeatmap {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	counts := make([][]uint64, len(h.rows))
+	for i, row := range h.rows {
+		counts[i] = append([]uint64(nil), row...)
+	}
+	return LatencyHeatmap{
+		SliceSeconds: h.slice.Seconds(),
+		BucketsMs:    HeatmapBucketsMs,
+		Counts:       counts,
+	}
+}
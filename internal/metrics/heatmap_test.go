@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyHeatmap(t *testing.T) {
+	start := time.Unix(1000, 0)
+	h := newLatencyHeatmap(start, 10*time.Second)
+	assert.Empty(t, h.heatmap().Counts)
+
+	h.observe(start.Add(time.Second), 300*time.Microsecond)
+	h.observe(start.Add(2*time.Second), time.Millisecond)
+	h.observe(start.Add(25*time.Second), 40*time.Millisecond)
+	h.observe(start.Add(26*time.Second), time.Minute)
+	// Observed before the start, counted in the first slice
+	h.observe(start.Add(-time.Second), 300*time.Microsecond)
+
+	m := h.heatmap()
+	assert.Equal(t, 10.0, m.SliceSeconds)
+	assert.Equal(t, HeatmapBucketsMs, m.BucketsMs)
+	require.Len(t, m.Counts, 3)
+	for _, row := range m.Counts {
+		assert.Len(t, row, len(HeatmapBucketsMs)+1)
+	}
+	// 0.3ms falls into the 0.5ms bucket, 1ms into the 1ms bucket
+	assert.Equal(t, uint64(2), m.Counts[0][2])
+	assert.Equal(t, uint64(1), m.Counts[0][3])
+	assert.Equal(t, make([]uint64, len(HeatmapBucketsMs)+1), m.Counts[1])
+	assert.Equal(t, uint64(1), m.Counts[2][8])
+	assert.Equal(t, uint64(1), m.Counts[2][len(HeatmapBucketsMs)])
+
+	// The copy is not changed by later observations
+	h.observe(start, time.Millisecond)
+	assert.Equal(t, uint64(1), m.Counts[0][3])
+}
+
+func TestCollectorLatencyHeatmaps(t *testing.T) {
+	mc := testMetricsCollector()
+	mc.ObserveLatency("tcp", "8080", time.Millisecond)
+	assert.Nil(t, mc.LatencyHeatmaps(), "heatmaps are only recorded once enabled")
+
+	mc.EnableLatencyHeatmap(time.Now(), time.Minute)
+	mc.ObserveLatency("tcp", "8080", time.Millisecond)
+	mc.ObserveLatency("udp", "53", 20*time.Millisecond)
+
+	heatmaps := mc.LatencyHeatmaps()
+	require.Len(t, heatmaps, 2)
+	assert.Equal(t, [][]uint64{{0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}}, heatmaps["tcp"].Counts)
+	assert.Equal(t, uint64(1), heatmaps["udp"].Counts[0][7])
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxLatencySamples bounds the memory used to compute latency percentiles over long runs
+const maxLatencySamples = 10000
+
+// LatencySummary holds latency statistics in milliseconds.
+type LatencySummary struct {
+	Count  uint64  `json:"count"`
+	MinMs  float64 `json:"min_ms"`
+	MeanMs float64 `json:"mean_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P90Ms  float64 `json:"p90_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+// latencyRecorder keeps exact count, mean, min and max of observed latencies and a uniform
+// reservoir sample of them for percentiles.
+type latencyRecorder struct {
+	mu      sync.Mutex
+	count   uint64
+	sum     time.Duration
+	min     time.Duration
+	max     time.Duration
+	samples *Reservoir
+}
+
+// newLatencyRecorder creates an empty latency recorder.
+func newLatencyRecorder() *latencyRecorder {
+	return &latencyRecorder{samples: NewReservoir(maxLatencySamples)}
+}
+
+// observe records a single latency.
+func (r *latencyRecorder) observe(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.count++
+	r.sum += d
+	if r.count == 1 || d < r.min {
+		r.min = d
+	}
+	if d > r.max {
+		r.max = d
+	}
+	r.samples.Observe(d)
+}
+
+// summary returns the statistics of all latencies observed so far.
+func (r *latencyRecorder) summary() LatencySummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.count == 0 {
+		return LatencySummary{}
+	}
+	sorted := append([]time.Duration(nil), r.samples.Samples()...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return LatencySummary{
+		Count:  r.count,
+		MinMs:  milliseconds(r.min),
+		MeanMs: milliseconds(r.sum) / float64(r.count),
+		P50Ms:  milliseconds(Percentile(sorted, 0.50)),
+		P90Ms:  milliseconds(Percentile(sorted, 0.90)),
+		P99Ms:  milliseconds(Percentile(sorted, 0.99)),
+		MaxMs:  milliseconds(r.max),
+	}
+}
+
+// Percentile returns the nearest-rank percentile p (0-1] of sorted latencies, which must not be empty.
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
+
+// milliseconds converts a duration to fractional milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyRecorderSummary(t *testing.T) {
+	r := newLatencyRecorder()
+	assert.Equal(t, LatencySummary{}, r.summary())
+
+	for i := 1; i <= 100; i++ {
+		r.observe(time.Duration(i) * time.Millisecond)
+	}
+
+	s := r.summary()
+	assert.Equal(t, uint64(100), s.Count)
+	assert.Equal(t, 1.0, s.MinMs)
+	assert.Equal(t, 50.5, s.MeanMs)
+	assert.Equal(t, 50.0, s.P50Ms)
+	assert.Equal(t, 90.0, s.P90Ms)
+	assert.Equal(t, 99.0, s.P99Ms)
+	assert.Equal(t, 100.0, s.MaxMs)
+}
+
+func TestLatencyRecorderBoundedSamples(t *testing.T) {
+	r := newLatencyRecorder()
+	for i := 0; i < 3*maxLatencySamples; i++ {
+		r.observe(time.Millisecond)
+	}
+	r.observe(time.Second)
+
+	assert.Len(t, r.samples.Samples(), maxLatencySamples)
+	s := r.summary()
+	assert.Equal(t, uint64(3*maxLatencySamples+1), s.Count)
+	assert.Equal(t, 1000.0, s.MaxMs)
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond, 4 * time.Millisecond}
+	assert.Equal(t, time.Millisecond, Percentile(sorted, 0.25))
+	assert.Equal(t, 2*time.Millisecond, Percentile(sorted, 0.5))
+	assert.Equal(t, 4*time.Millisecond, Percentile(sorted, 0.99))
+	assert.Equal(t, time.Millisecond, Percentile(sorted[:1], 0.99))
+}
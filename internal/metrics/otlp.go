@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	otelprom "go.opentelemetry.io/contrib/bridges/prometheus"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+)
+
+// DefaultOTLPInterval is the interval at which metrics are pushed when none is configured.
+const DefaultOTLPInterval = 10 * time.Second
+
+// InitOTLPExporter periodically pushes every registered collector metric to an OpenTelemetry
+// collector via OTLP gRPC, using the same endpoint as the trace exporter. The metrics stay
+// registered with Prometheus, so the scrape endpoint keeps working alongside the exporter.
+// The returned function pushes a final export and stops the exporter; it is never nil.
+func InitOTLPExporter(serviceName, endpoint string, interval time.Duration) func(context.Context) error {
+	if interval <= 0 {
+		interval = DefaultOTLPInterval
+	}
+
+	exporter, err := otlpmetricgrpc.New(context.Background(), otlpmetricgrpc.WithEndpoint(endpoint), otlpmetricgrpc.WithInsecure())
+	if err != nil {
+		logging.Logger.Warnf("Failed to initialize OTLP metrics exporter: %v", err)
+		return func(context.Context) error { return nil }
+	}
+
+	return newOTLPMeterProvider(serviceName, exporter, prometheus.DefaultGatherer, interval).Shutdown
+}
+
+// newOTLPMeterProvider creates a meter provider that periodically reads the metrics of the
+// gatherer and hands the result to the exporter.
+func newOTLPMeterProvider(serviceName string, exporter sdkmetric.Exporter, gatherer prometheus.Gatherer, interval time.Duration) *sdkmetric.MeterProvider {
+	reader := sdkmetric.NewPeriodicReader(exporter,
+		sdkmetric.WithInterval(interval),
+		sdkmetric.WithProducer(otelprom.NewMetricProducer(otelprom.WithGatherer(gatherer))),
+	)
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(reader),
+		sdkmetric.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(serviceName),
+		)),
+	)
+
+	logging.Logger.Debugf("OTLP metrics exporter initialized for service %s with interval %s", serviceName, interval)
+	return mp
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// captureExporter records the names of all exported metrics
+type captureExporter struct {
+	mu    sync.Mutex
+	names map[string]bool
+}
+
+func (e *captureExporter) Temporality(k sdkmetric.InstrumentKind) metricdata.Temporality {
+	return sdkmetric.DefaultTemporalitySelector(k)
+}
+
+func (e *captureExporter) Aggregation(k sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	return sdkmetric.DefaultAggregationSelector(k)
+}
+
+func (e *captureExporter) Export(_ context.Context, rm *metricdata.ResourceMetrics) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			e.names[m.Name] = true
+		}
+	}
+	return nil
+}
+
+func (e *captureExporter) ForceFlush(context.Context) error { return nil }
+
+func (e *captureExporter) Shutdown(context.Context) error { return nil }
+
+func TestOTLPMeterProviderExportsCollectorMetrics(t *testing.T) {
+	logging.InitLogger("json", "error")
+	mc := testMetricsCollector()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(mc.RequestsSent, mc.ActiveTCPConnections)
+	mc.IncRequestsSent("tcp", "8080")
+
+	exporter := &captureExporter{names: map[string]bool{}}
+	mp := newOTLPMeterProvider("test-service", exporter, registry, time.Hour)
+
+	// Shutting down performs a final export
+	require.NoError(t, mp.Shutdown(context.Background()))
+	exporter.mu.Lock()
+	defer exporter.mu.Unlock()
+	assert.True(t, exporter.names["test_requests_sent_total"], "exported metrics: %v", exporter.names)
+	assert.True(t, exporter.names["test_active_tcp_connections"], "exported metrics: %v", exporter.names)
+}
+
+func TestInitOTLPExporter(t *testing.T) {
+	logging.InitLogger("json", "error")
+
+	// Exporting to an unreachable collector must not block or fail the shutdown path
+	shutdown := InitOTLPExporter("test-service", "localhost:4317", 0)
+	require.NotNil(t, shutdown)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.NotPanics(t, func() { _ = shutdown(ctx) })
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// RateWindow is the sliding window the rate gauges are computed over. As it slides one slot at a time, a
+	// rate covers the complete slots before the current one and the part of the current slot that has passed.
+	RateWindow = 10 * time.Second
+	// rateWindowBuckets is the number of equal slots the window is counted in, it slides one slot at a time
+	rateWindowBuckets = 10
+)
+
+// rateWindowHelp describes the span a rate of a rateWindow of RateWindow covers, for the help of the gauges
+func rateWindowHelp() string {
+	return fmt.Sprintf("over a sliding window of %v: the last %d complete slots of %v and the elapsed part of the current one",
+		RateWindow, rateWindowBuckets-1, RateWindow/rateWindowBuckets)
+}
+
+// rateBucket counts the events of one slot of a rate window
+type rateBucket struct {
+	// slot is the number of the slot counted, the time since the Unix epoch divided by the slot width
+	slot  atomic.Int64
+	count atomic.Uint64
+}
+
+// rateWindow computes the rate of events over a sliding window without locks, so it can be fed from the
+// hot path. An event racing with its bucket moving on to a new slot may be lost, which is fine for gauges.
+type rateWindow struct {
+	width   time.Duration
+	created time.Time
+	buckets [rateWindowBuckets]rateBucket
+}
+
+// newRateWindow creates a window of the given length starting at the given time
+func newRateWindow(length time.Duration, now time.Time) *rateWindow {
+	return &rateWindow{width: length / rateWindowBuckets, created: now}
+}
+
+// add counts n events at the given time
+func (w *rateWindow) add(now time.Time, n uint64) {
+	if w == nil {
+		return
+	}
+	slot := now.UnixNano() / int64(w.width)
+	b := &w.buckets[slot%rateWindowBuckets]
+	if old := b.slot.Load(); old != slot && b.slot.CompareAndSwap(old, slot) {
+		b.count.Store(0)
+	}
+	b.count.Add(n)
+}
+
+// rate returns the events per second within the window ending at the given time. While the window is not
+// filled yet, the rate is over the time since it was created.
+func (w *rateWindow) rate(now time.Time) float64 {
+	if w == nil {
+		return 0
+	}
+	current := now.UnixNano() / int64(w.width)
+	var total uint64
+	for i := range w.buckets {
+		b := &w.buckets[i]
+		if slot := b.slot.Load(); slot > current-rateWindowBuckets && slot <= current {
+			total += b.count.Load()
+		}
+	}
+	// The window ends with the part of the current slot that has passed
+	covered := time.Duration(rateWindowBuckets-1)*w.width + time.Duration(now.UnixNano()-current*int64(w.width))
+	covered = min(covered, now.Sub(w.created))
+	if covered <= 0 {
+		return 0
+	}
+	return float64(total) / covered.Seconds()
+}
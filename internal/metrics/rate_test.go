@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateWindow(t *testing.T) {
+	// Slots are aligned to the epoch, so start at a slot boundary
+	start := time.Unix(1_000_000, 0)
+	w := newRateWindow(10*time.Second, start)
+	assert.Equal(t, 0.0, w.rate(start))
+
+	// While the window fills up, the rate is over the time since it was created
+	w.add(start, 100)
+	w.add(start.Add(500*time.Millisecond), 100)
+	assert.InDelta(t, 200.0, w.rate(start.Add(time.Second)), 0.001)
+	w.add(start.Add(4*time.Second), 300)
+	assert.InDelta(t, 100.0, w.rate(start.Add(5*time.Second)), 0.001)
+
+	// Once it is filled, the rate is over the nine slots before the current one and the part of the current
+	// one that passed, here 3s-12.5s
+	w.add(start.Add(12*time.Second), 500)
+	assert.InDelta(t, 800/9.5, w.rate(start.Add(12500*time.Millisecond)), 0.001, "events older than the window are left out")
+
+	// Slots reused by a later pass of the window start counting from zero
+	w.add(start.Add(22*time.Second), 50)
+	assert.InDelta(t, 50/9.5, w.rate(start.Add(22500*time.Millisecond)), 0.001)
+
+	// Nothing within the window
+	assert.Equal(t, 0.0, w.rate(start.Add(time.Minute)))
+}
+
+func TestRateWindowConcurrent(t *testing.T) {
+	now := time.Unix(1_000_000, 0).Add(500 * time.Millisecond)
+	w := newRateWindow(10*time.Second, now.Add(-time.Minute))
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 1000 {
+				w.add(now, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.InDelta(t, 8000/9.5, w.rate(now), 0.001)
+}
+
+func TestRateWindowNil(t *testing.T) {
+	var w *rateWindow
+	w.add(time.Now(), 1)
+	assert.Equal(t, 0.0, w.rate(time.Now()))
+}
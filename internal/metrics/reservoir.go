@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Reservoir keeps a uniform sample of bounded size of the latencies observed, so that percentiles can be
+// computed over any number of them in constant memory. It is not safe for concurrent use.
+type Reservoir struct {
+	size     int
+	samples  []time.Duration
+	observed uint64
+	rng      *rand.Rand
+}
+
+// NewReservoir creates an empty reservoir keeping up to size latencies.
+func NewReservoir(size int) *Reservoir {
+	// #nosec G404 - math/rand is sufficient for reservoir sampling
+	return &Reservoir{size: size, rng: rand.New(rand.NewPCG(0, 0))}
+}
+
+// Observe records a single latency, replacing a random one of the sample once it is full.
+func (r *Reservoir) Observe(d time.Duration) {
+	r.observed++
+	if len(r.samples) < r.size {
+		r.samples = append(r.samples, d)
+	} else if i := r.rng.Uint64N(r.observed); i < uint64(r.size) {
+		r.samples[i] = d
+	}
+}
+
+// Samples returns the sampled latencies. The slice is owned by the reservoir and changes with the next
+// observation.
+func (r *Reservoir) Samples() []time.Duration {
+	return r.samples
+}
+
+// Observed returns the number of latencies observed, sampled or not.
+func (r *Reservoir) Observed() uint64 {
+	return r.observed
+}
+
+// Reset empties the reservoir, keeping its memory for the next observations.
+func (r *Reservoir) Reset() {
+	r.samples = r.samples[:0]
+	r.observed = 0
+}
@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReservoir(t *testing.T) {
+	r := NewReservoir(10)
+	for i := 1; i <= 5; i++ {
+		r.Observe(time.Duration(i) * time.Millisecond)
+	}
+	assert.Len(t, r.Samples(), 5)
+
+	for i := 0; i < 1000; i++ {
+		r.Observe(time.Second)
+	}
+	assert.Len(t, r.Samples(), 10)
+	assert.Equal(t, uint64(1005), r.Observed())
+	assert.Contains(t, r.Samples(), time.Second, "later latencies should replace sampled ones")
+
+	r.Reset()
+	assert.Empty(t, r.Samples())
+	assert.Zero(t, r.Observed())
+}
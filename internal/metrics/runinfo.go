@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const runInfoHelp = "Scenario and metadata of the current run as labels, always 1"
+
+// runInfoCollector exposes the run_info metric. It describes no metrics, which makes it an unchecked
+// collector whose label names may change between scrapes when the metadata is reloaded.
+type runInfoCollector struct {
+	mu     sync.Mutex
+	labels prometheus.Labels
+}
+
+// labelName matches the label names dashboards and the classic exposition format accept
+var labelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+var (
+	runInfo           = &runInfoCollector{}
+	runInfoRegistered sync.Once
+)
+
+// Describe sends no descriptors, see runInfoCollector
+func (c *runInfoCollector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect sends the run_info metric once labels are set
+func (c *runInfoCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	labels := c.labels
+	c.mu.Unlock()
+	if labels == nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(prometheus.NewDesc("run_info", runInfoHelp, nil, labels), prometheus.GaugeValue, 1)
+}
+
+// SetRunInfo exposes the run_info metric, a constant 1 carrying the scenario and metadata of the run as
+// labels so that dashboards and archived scrapes can be attributed to an experiment. The metadata keys
+// must be valid label names. Calling it again replaces the labels.
+func SetRunInfo(scenario string, metadata map[string]string) error {
+	labels := prometheus.Labels{"scenario": scenario}
+	for key, value := range metadata {
+		if !labelName.MatchString(key) || key == "scenario" {
+			return fmt.Errorf("invalid run_info label %q", key)
+		}
+		labels[key] = value
+	}
+
+	runInfoRegistered.Do(func() {
+		prometheus.MustRegister(runInfo)
+	})
+	runInfo.mu.Lock()
+	defer runInfo.mu.Unlock()
+	runInfo.labels = labels
+	return nil
+}
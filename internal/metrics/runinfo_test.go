@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runInfoLabels gathers the labels of the registered run_info metric
+func runInfoLabels(t *testing.T) map[string]string {
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "run_info" {
+			continue
+		}
+		require.Len(t, family.GetMetric(), 1)
+		assert.Equal(t, float64(1), family.GetMetric()[0].GetGauge().GetValue())
+		labels := make(map[string]string)
+		for _, pair := range family.GetMetric()[0].GetLabel() {
+			labels[pair.GetName()] = pair.GetValue()
+		}
+		return labels
+	}
+	return nil
+}
+
+func TestSetRunInfo(t *testing.T) {
+	require.NoError(t, SetRunInfo("soak", map[string]string{"owner": "netops", "ticket": "NET-1234"}))
+	assert.Equal(t, map[string]string{"scenario": "soak", "owner": "netops", "ticket": "NET-1234"}, runInfoLabels(t))
+
+	// A later call replaces the labels
+	require.NoError(t, SetRunInfo("smoke", nil))
+	assert.Equal(t, map[string]string{"scenario": "smoke"}, runInfoLabels(t))
+
+	assert.Error(t, SetRunInfo("smoke", map[string]string{"not-a-label": "x"}))
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"time"
+)
+
+// StatsdPrefix is prepended to the name of every metric sent to StatsD.
+const StatsdPrefix = "flow_generator."
+
+// StatsdSink sends per-flow counters and latency timings to a StatsD or DogStatsD server over UDP.
+// Protocol and port are attached as DogStatsD tags, which plain StatsD servers ignore.
+type StatsdSink struct {
+	conn       net.Conn
+	sampleRate float64
+	// sample decides whether a metric is sent, it is replaced in tests
+	sample func() bool
+}
+
+// NewStatsdSink creates a sink sending to the given host:port. Metrics are sent with probability
+// sampleRate and carry the rate so the server can scale them back up; a rate of 0 or above 1 sends all metrics.
+func NewStatsdSink(address string, sampleRate float64) (*StatsdSink, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to statsd at %s: %w", address, err)
+	}
+	if sampleRate <= 0 || sampleRate > 1 {
+		sampleRate = 1
+	}
+	s := &StatsdSink{conn: conn, sampleRate: sampleRate}
+	s.sample = func() bool {
+		// #nosec G404 - sampling does not need a cryptographically secure source
+		return s.sampleRate >= 1 || rand.Float64() < s.sampleRate
+	}
+	return s, nil
+}
+
+// Count sends a counter increment.
+func (s *StatsdSink) Count(name, protocol, port string, value int64) {
+	s.send(name, strconv.FormatInt(value, 10), "c", protocol, port)
+}
+
+// Timing sends a timing in milliseconds.
+func (s *StatsdSink) Timing(name, protocol, port string, d time.Duration) {
+	s.send(name, strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64), "ms", protocol, port)
+}
+
+// Close closes the connection to the StatsD server.
+func (s *StatsdSink) Close() error {
+	return s.conn.Close()
+}
+
+// send writes a single metric line if it is sampled. Delivery is best effort, so write errors are ignored
+// like in every StatsD client.
+func (s *StatsdSink) send(name, value, kind, protocol, port string) {
+	if !s.sample() {
+		return
+	}
+	_, _ = s.conn.Write([]byte(statsdLine(name, value, kind, s.sampleRate, protocol, port)))
+}
+
+// statsdLine formats a metric in the DogStatsD line format, e.g. "flow_generator.requests_sent:1|c|@0.5|#protocol:tcp,port:8080".
+func statsdLine(name, value, kind string, sampleRate float64, protocol, port string) string {
+	line := StatsdPrefix + name + ":" + value + "|" + kind
+	if sampleRate < 1 {
+		line += "|@" + strconv.FormatFloat(sampleRate, 'f', -1, 64)
+	}
+	return line + "|#protocol:" + protocol + ",port:" + port
+}
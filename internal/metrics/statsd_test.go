@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listenStatsd starts a UDP listener standing in for a StatsD server
+func listenStatsd(t *testing.T) *net.UDPConn {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// readStatsd returns the next metric line received, or an empty string on timeout
+func readStatsd(t *testing.T, conn *net.UDPConn) string {
+	buf := make([]byte, 512)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(200*time.Millisecond)))
+	n, err := conn.Read(buf)
+	if err != nil {
+		return ""
+	}
+	return string(buf[:n])
+}
+
+func TestStatsdLine(t *testing.T) {
+	tests := []struct {
+		name       string
+		value      string
+		kind       string
+		sampleRate float64
+		expected   string
+	}{
+		{"counter", "1", "c", 1, "flow_generator.requests_sent:1|c|#protocol:tcp,port:8080"},
+		{"sampled counter", "1", "c", 0.25, "flow_generator.requests_sent:1|c|@0.25|#protocol:tcp,port:8080"},
+		{"timing", "1.500", "ms", 1, "flow_generator.requests_sent:1.500|ms|#protocol:tcp,port:8080"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, statsdLine("requests_sent", tt.value, tt.kind, tt.sampleRate, "tcp", "8080"))
+		})
+	}
+}
+
+func TestStatsdSink(t *testing.T) {
+	server := listenStatsd(t)
+	sink, err := NewStatsdSink(server.LocalAddr().String(), 0)
+	require.NoError(t, err)
+	defer func() { _ = sink.Close() }()
+
+	sink.Count("bytes_sent", "udp", "53", 512)
+	assert.Equal(t, "flow_generator.bytes_sent:512|c|#protocol:udp,port:53", readStatsd(t, server))
+
+	sink.Timing("request_latency", "tcp", "8080", 1500*time.Microsecond)
+	assert.Equal(t, "flow_generator.request_latency:1.500|ms|#protocol:tcp,port:8080", readStatsd(t, server))
+
+	// Metrics that are not sampled are dropped
+	sink.sample = func() bool { return false }
+	sink.Count("bytes_sent", "udp", "53", 512)
+	assert.Empty(t, readStatsd(t, server))
+}
+
+func TestCollectorStatsdSink(t *testing.T) {
+	server := listenStatsd(t)
+	sink, err := NewStatsdSink(server.LocalAddr().String(), 1)
+	require.NoError(t, err)
+	defer func() { _ = sink.Close() }()
+
+	mc := testMetricsCollector()
+	mc.SetStatsdSink(sink)
+	mc.IncRequestsSent("tcp", "8080")
+	assert.Equal(t, "flow_generator.requests_sent:1|c|#protocol:tcp,port:8080", readStatsd(t, server))
+	mc.ObserveLatency("tcp", "8080", 2*time.Millisecond)
+	assert.Equal(t, "flow_generator.request_latency:2.000|ms|#protocol:tcp,port:8080", readStatsd(t, server))
+}
+
+func TestNewStatsdSinkInvalidAddress(t *testing.T) {
+	_, err := NewStatsdSink("not-an-address", 1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,60 @@
+package metrics
+
+// Header sizes used to estimate on-wire bytes, in bytes.
+const (
+	ipv4HeaderSize = 20
+	ipv6HeaderSize = 40
+	tcpHeaderSize  = 20
+	udpHeaderSize  = 8
+	// DefaultL2Overhead is the Ethernet header counted per frame by most interface counters.
+	DefaultL2Overhead = 14
+)
+
+// WireEstimator estimates the bytes a payload occupies on the wire, including the per-packet
+// IP, transport and link-layer headers. It ignores TCP handshakes, ACKs and options, so it is
+// a lower bound for the traffic interface counters see.
+type WireEstimator struct {
+	MTU        int
+	MSS        int
+	L2Overhead int
+}
+
+// TCPBytes estimates the on-wire size of a TCP payload split into MSS-sized segments.
+func (e WireEstimator) TCPBytes(payload int, ipv6 bool) int {
+	if payload <= 0 {
+		return 0
+	}
+	mss := e.MSS
+	if mss <= 0 {
+		mss = payload
+	}
+	segments := (payload + mss - 1) / mss
+	return payload + segments*(ipHeaderSize(ipv6)+tcpHeaderSize+e.L2Overhead)
+}
+
+// UDPBytes estimates the on-wire size of a UDP datagram, including IP fragmentation if it exceeds the MTU.
+// IPv6 datagrams are assumed to be fragmented by the sender like IPv4 ones, ignoring the fragment extension header.
+func (e WireEstimator) UDPBytes(payload int, ipv6 bool) int {
+	if payload < 0 {
+		return 0
+	}
+	ipHeader := ipHeaderSize(ipv6)
+	datagram := payload + udpHeaderSize
+	fragments := 1
+	if e.MTU > ipHeader {
+		// Fragment payloads must be multiples of 8 bytes, except for the last fragment
+		perFragment := (e.MTU - ipHeader) &^ 7
+		if perFragment > 0 {
+			fragments = (datagram + perFragment - 1) / perFragment
+		}
+	}
+	return datagram + fragments*(ipHeader+e.L2Overhead)
+}
+
+// ipHeaderSize returns the size of the IP header for the address family.
+func ipHeaderSize(ipv6 bool) int {
+	if ipv6 {
+		return ipv6HeaderSize
+	}
+	return ipv4HeaderSize
+}
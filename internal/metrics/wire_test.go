@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWireEstimatorTCP(t *testing.T) {
+	e := WireEstimator{MTU: 1500, MSS: 1460, L2Overhead: DefaultL2Overhead}
+
+	tests := []struct {
+		name     string
+		payload  int
+		ipv6     bool
+		expected int
+	}{
+		{"empty", 0, false, 0},
+		{"single segment", 100, false, 100 + 54},
+		{"full segment", 1460, false, 1460 + 54},
+		{"two segments", 1461, false, 1461 + 2*54},
+		{"ipv6", 100, true, 100 + 74},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, e.TCPBytes(tt.payload, tt.ipv6))
+		})
+	}
+}
+
+func TestWireEstimatorUDP(t *testing.T) {
+	e := WireEstimator{MTU: 1500, MSS: 1460, L2Overhead: DefaultL2Overhead}
+
+	tests := []struct {
+		name     string
+		payload  int
+		ipv6     bool
+		expected int
+	}{
+		{"empty datagram", 0, false, 8 + 34},
+		{"small datagram", 100, false, 108 + 34},
+		{"largest unfragmented", 1472, false, 1480 + 34},
+		{"two fragments", 1473, false, 1481 + 2*34},
+		{"ipv6", 100, true, 108 + 54},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, e.UDPBytes(tt.payload, tt.ipv6))
+		})
+	}
+}
+
+func TestWireEstimatorWithoutLimits(t *testing.T) {
+	e := WireEstimator{}
+	assert.Equal(t, 5000+40, e.TCPBytes(5000, false))
+	assert.Equal(t, 5008+20, e.UDPBytes(5000, false))
+}
@@ -0,0 +1,106 @@
+// Package netem detects Linux tc netem impairments on the interface used to reach a target, so that
+// delay or loss left over from a previous experiment shows up in run reports.
+package netem
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ErrUnsupported is returned when netem detection is not available on the current platform
+var ErrUnsupported = errors.New("netem detection not supported on this platform")
+
+// Status describes the netem configuration of an egress interface
+type Status struct {
+	Interface string `json:"interface,omitempty"`
+	Active    bool   `json:"active"`
+	Delay     string `json:"delay,omitempty"`
+	Loss      string `json:"loss,omitempty"`
+	// Qdisc is the netem qdisc as reported by tc
+	Qdisc string `json:"qdisc,omitempty"`
+	// Error is set if the status could not be determined
+	Error string `json:"error,omitempty"`
+}
+
+// Detect reports the netem configuration of the interface used to reach the target address (host:port)
+func Detect(target string) Status {
+	iface, err := egressInterface(target)
+	if err != nil {
+		return Status{Error: err.Error()}
+	}
+	output, err := showQdiscs(iface)
+	if err != nil {
+		return Status{Interface: iface, Error: err.Error()}
+	}
+	return Parse(iface, output)
+}
+
+// Parse extracts the netem configuration from the output of "tc qdisc show dev <iface>"
+func Parse(iface, output string) Status {
+	status := Status{Interface: iface}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "qdisc" || fields[1] != "netem" {
+			continue
+		}
+		status.Active = true
+		status.Qdisc = strings.Join(fields, " ")
+		for i := 2; i < len(fields)-1; i++ {
+			switch fields[i] {
+			case "delay":
+				status.Delay = fields[i+1]
+				// An optional jitter follows the delay
+				if i+2 < len(fields) && isDuration(fields[i+2]) {
+					status.Delay += " ± " + fields[i+2]
+				}
+			case "loss":
+				status.Loss = fields[i+1]
+				if status.Loss == "random" && i+2 < len(fields) {
+					status.Loss = fields[i+2]
+				}
+			}
+		}
+		break
+	}
+	return status
+}
+
+// isDuration reports whether a tc field is a time value such as 10ms or 1.5s
+func isDuration(field string) bool {
+	for _, unit := range []string{"us", "ms", "s"} {
+		if num, ok := strings.CutSuffix(field, unit); ok && num != "" && strings.Trim(num, "0123456789.") == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// egressInterface returns the name of the interface the OS routes traffic to the target through
+func egressInterface(target string) (string, error) {
+	// Connecting a UDP socket selects the route without sending any packet
+	conn, err := net.Dial("udp", target)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve route to %s: %w", target, err)
+	}
+	local := conn.LocalAddr().(*net.UDPAddr).IP
+	_ = conn.Close()
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "", fmt.Errorf("failed to list interfaces: %w", err)
+	}
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(local) {
+				return iface.Name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no interface with local address %s", local)
+}
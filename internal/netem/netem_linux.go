@@ -0,0 +1,23 @@
+//go:build linux
+
+package netem
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// showQdiscs returns the qdiscs configured on an interface as reported by tc
+func showQdiscs(iface string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// #nosec G204 - the interface name comes from net.Interfaces
+	output, err := exec.CommandContext(ctx, "tc", "qdisc", "show", "dev", iface).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run tc: %w", err)
+	}
+	return string(output), nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package netem
+
+// showQdiscs is not supported on this platform
+func showQdiscs(iface string) (string, error) {
+	return "", ErrUnsupported
+}
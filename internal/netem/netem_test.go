@@ -0,0 +1,83 @@
+package netem
+
+import (
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected Status
+	}{
+		{
+			name:     "no netem",
+			output:   "qdisc pfifo_fast 0: root refcnt 2 bands 3 priomap 1 2 2 2 1 2 0 0 1 1 1 1 1 1 1 1\n",
+			expected: Status{Interface: "eth0"},
+		},
+		{
+			name:   "delay with jitter and loss",
+			output: "qdisc netem 8001: root refcnt 2 limit 1000 delay 100ms  10ms loss 1%\n",
+			expected: Status{
+				Interface: "eth0",
+				Active:    true,
+				Delay:     "100ms ± 10ms",
+				Loss:      "1%",
+				Qdisc:     "qdisc netem 8001: root refcnt 2 limit 1000 delay 100ms 10ms loss 1%",
+			},
+		},
+		{
+			name:   "random loss only",
+			output: "qdisc noqueue 0: root\nqdisc netem 10: parent 1:1 limit 1000 loss random 5%\n",
+			expected: Status{
+				Interface: "eth0",
+				Active:    true,
+				Loss:      "5%",
+				Qdisc:     "qdisc netem 10: parent 1:1 limit 1000 loss random 5%",
+			},
+		},
+		{
+			name:   "delay without jitter",
+			output: "qdisc netem 8001: root refcnt 2 limit 1000 delay 1.5s\n",
+			expected: Status{
+				Interface: "eth0",
+				Active:    true,
+				Delay:     "1.5s",
+				Qdisc:     "qdisc netem 8001: root refcnt 2 limit 1000 delay 1.5s",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Parse("eth0", tt.output))
+		})
+	}
+}
+
+func TestIsDuration(t *testing.T) {
+	assert.True(t, isDuration("10ms"))
+	assert.True(t, isDuration("1.5s"))
+	assert.True(t, isDuration("250us"))
+	assert.False(t, isDuration("loss"))
+	assert.False(t, isDuration("ms"))
+	assert.False(t, isDuration("1%"))
+}
+
+func TestEgressInterface(t *testing.T) {
+	listener, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	iface, err := egressInterface("127.0.0.1:" + strconv.Itoa(listener.LocalAddr().(*net.UDPAddr).Port))
+	require.NoError(t, err)
+	assert.NotEmpty(t, iface)
+
+	_, err = egressInterface("invalid-target")
+	assert.Error(t, err)
+}
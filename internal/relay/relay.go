@@ -0,0 +1,104 @@
+// Package relay implements the flow header used to route TCP flows through a chain of relay servers.
+//
+// A client connects to the first relay and sends a header listing the remaining hops, ending
+// with the target address. Each relay pops the first hop, connects to it, forwards the remaining
+// hops if any, acknowledges with its local timestamp and then pipes data in both directions.
+// The client therefore receives one acknowledgement per relay, in chain order.
+package relay
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// MaxHops is the maximum number of addresses a header can carry
+	MaxHops = 16
+	// maxAddrLen is the maximum length of a single hop address
+	maxAddrLen = 255
+)
+
+var (
+	headerMagic = []byte("FGRH")
+	ackMagic    = []byte("FGRA")
+)
+
+// ErrInvalidHeader is returned when a flow does not start with a valid relay header
+var ErrInvalidHeader = errors.New("invalid relay header")
+
+// WriteHeader writes a relay header routing the flow through the given hops
+func WriteHeader(w io.Writer, hops []string) error {
+	if len(hops) == 0 || len(hops) > MaxHops {
+		return fmt.Errorf("relay header must contain between 1 and %d hops, got %d", MaxHops, len(hops))
+	}
+	var buf bytes.Buffer
+	buf.Write(headerMagic)
+	buf.WriteByte(byte(len(hops)))
+	for _, hop := range hops {
+		if hop == "" || len(hop) > maxAddrLen {
+			return fmt.Errorf("invalid relay hop address %q", hop)
+		}
+		buf.WriteByte(byte(len(hop)))
+		buf.WriteString(hop)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// ReadHeader reads a relay header and returns the hops it routes through
+func ReadHeader(r io.Reader) ([]string, error) {
+	prefix := make([]byte, len(headerMagic)+1)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(prefix[:len(headerMagic)], headerMagic) {
+		return nil, ErrInvalidHeader
+	}
+	count := int(prefix[len(headerMagic)])
+	if count == 0 || count > MaxHops {
+		return nil, fmt.Errorf("%w: hop count %d", ErrInvalidHeader, count)
+	}
+
+	hops := make([]string, 0, count)
+	length := make([]byte, 1)
+	for i := 0; i < count; i++ {
+		if _, err := io.ReadFull(r, length); err != nil {
+			return nil, err
+		}
+		if length[0] == 0 {
+			return nil, fmt.Errorf("%w: empty hop address", ErrInvalidHeader)
+		}
+		addr := make([]byte, length[0])
+		if _, err := io.ReadFull(r, addr); err != nil {
+			return nil, err
+		}
+		hops = append(hops, string(addr))
+	}
+	return hops, nil
+}
+
+// WriteAck acknowledges that the relay connected to its next hop at the given time
+func WriteAck(w io.Writer, t time.Time) error {
+	buf := make([]byte, len(ackMagic)+8)
+	copy(buf, ackMagic)
+	binary.BigEndian.PutUint64(buf[len(ackMagic):], uint64(t.UnixNano()))
+	_, err := w.Write(buf)
+	return err
+}
+
+// ReadAck reads a relay acknowledgement and returns the relay's timestamp
+func ReadAck(r io.Reader) (time.Time, error) {
+	buf := make([]byte, len(ackMagic)+8)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return time.Time{}, err
+	}
+	if !bytes.Equal(buf[:len(ackMagic)], ackMagic) {
+		return time.Time{}, fmt.Errorf("invalid relay acknowledgement")
+	}
+	// #nosec G115 - timestamps written by WriteAck always fit into int64
+	return time.Unix(0, int64(binary.BigEndian.Uint64(buf[len(ackMagic):]))), nil
+}
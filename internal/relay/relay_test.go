@@ -0,0 +1,79 @@
+package relay
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaderRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	hops := []string{"relay-b:9999", "10.0.0.5:8080", "[fd00::1]:8080"}
+	require.NoError(t, WriteHeader(&buf, hops))
+
+	// Payload following the header must be left untouched
+	buf.WriteString("payload")
+
+	got, err := ReadHeader(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, hops, got)
+	assert.Equal(t, "payload", buf.String())
+}
+
+func TestWriteHeaderInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		hops []string
+	}{
+		{"no hops", nil},
+		{"too many hops", make([]string, MaxHops+1)},
+		{"empty address", []string{""}},
+		{"address too long", []string{strings.Repeat("a", maxAddrLen+1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			assert.Error(t, WriteHeader(&buf, tt.hops))
+		})
+	}
+}
+
+func TestReadHeaderInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"wrong magic", []byte("HELLO")},
+		{"zero hops", append([]byte("FGRH"), 0)},
+		{"empty address", append([]byte("FGRH"), 1, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadHeader(bytes.NewReader(tt.input))
+			assert.True(t, errors.Is(err, ErrInvalidHeader))
+		})
+	}
+
+	_, err := ReadHeader(bytes.NewReader([]byte("FG")))
+	assert.Error(t, err)
+}
+
+func TestAckRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Now()
+	require.NoError(t, WriteAck(&buf, now))
+
+	got, err := ReadAck(&buf)
+	require.NoError(t, err)
+	assert.True(t, now.Equal(got))
+
+	_, err = ReadAck(bytes.NewReader([]byte("XXXX12345678")))
+	assert.Error(t, err)
+}
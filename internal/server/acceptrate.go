@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// AcceptLimiter paces the accept loop of a TCP server to a number of connections per second, which models a
+// backend admitting connections slowly. Connections arriving faster wait in the listen backlog. The accept
+// loops of all listeners of a port share it.
+type AcceptLimiter struct {
+	interval time.Duration
+	mu       sync.Mutex
+	// next is when the next connection may be accepted
+	next time.Time
+}
+
+// NewAcceptLimiter returns a limiter accepting rate connections per second, or nil if rate is 0
+func NewAcceptLimiter(rate float64) *AcceptLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &AcceptLimiter{interval: time.Duration(float64(time.Second) / rate)}
+}
+
+// wait waits until the next connection may be accepted, it reports false if ctx is done first. A nil limiter
+// never waits.
+func (l *AcceptLimiter) wait(ctx context.Context) bool {
+	if l == nil {
+		return true
+	}
+	// Every caller reserves its own slot, so concurrent accept loops are paced together
+	l.mu.Lock()
+	now := time.Now()
+	slot := now
+	if l.next.After(now) {
+		slot = l.next
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+	if slot.After(now) {
+		timer := time.NewTimer(slot.Sub(now))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		}
+	}
+	return true
+}
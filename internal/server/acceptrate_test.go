@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/handlers"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptLimiter(t *testing.T) {
+	assert.Nil(t, NewAcceptLimiter(0))
+	var unlimited *AcceptLimiter
+	assert.True(t, unlimited.wait(context.Background()))
+
+	l := NewAcceptLimiter(100)
+	assert.Equal(t, 10*time.Millisecond, l.interval)
+	start := time.Now()
+	for range 5 {
+		require.True(t, l.wait(context.Background()))
+	}
+	// The first connection is accepted right away, each further one an interval later
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, l.wait(ctx))
+}
+
+func TestAcceptLimiterConcurrent(t *testing.T) {
+	// The accept loops of several listen sockets share the rate of their port
+	l := NewAcceptLimiter(100)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 2 {
+				l.wait(context.Background())
+			}
+		}()
+	}
+	wg.Wait()
+	assert.GreaterOrEqual(t, time.Since(start), 70*time.Millisecond)
+}
+
+func TestTCPServerAcceptRate(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+
+	server := NewTCPServer(port, handlers.NewTCPHandler(metrics.NewMetricsCollector()))
+	server.SetAcceptLimiter(NewAcceptLimiter(20))
+	require.NoError(t, server.Start())
+	defer func() { _ = server.Stop() }()
+
+	// Connections dialed at once are accepted 50ms apart, the later ones wait in the listen backlog
+	start := time.Now()
+	for range 4 {
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+		require.True(t, echoes(conn, time.Second))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
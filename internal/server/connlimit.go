@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+)
+
+// ConnLimit caps the simultaneous TCP connections of a listener, or of all listeners sharing it
+type ConnLimit struct {
+	// name labels the metrics of the limit, the port of its listener or "global"
+	name  string
+	slots chan struct{}
+	mc    *metrics.MetricsCollector
+	// mu orders the saturation updates, so the gauge ends up with the latest one
+	mu sync.Mutex
+}
+
+// NewConnLimit returns a limit of max connections whose metrics are labeled with name, or nil if max is 0
+func NewConnLimit(mc *metrics.MetricsCollector, name string, max int) *ConnLimit {
+	if max <= 0 {
+		return nil
+	}
+	l := &ConnLimit{name: name, slots: make(chan struct{}, max), mc: mc}
+	l.observe()
+	return l
+}
+
+// tryAcquire takes a slot if one is free. A nil limit always has one.
+func (l *ConnLimit) tryAcquire() bool {
+	if l == nil {
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		l.observe()
+		return true
+	default:
+		return false
+	}
+}
+
+// acquire waits for a free slot until ctx is done and reports whether it took one. A nil limit always has one.
+func (l *ConnLimit) acquire(ctx context.Context) bool {
+	if l == nil {
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		l.observe()
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release frees a slot taken before
+func (l *ConnLimit) release() {
+	if l == nil {
+		return
+	}
+	<-l.slots
+	l.observe()
+}
+
+// observe reports the fraction of the slots in use
+func (l *ConnLimit) observe() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.mc.SetConnectionLimitSaturation(l.name, float64(len(l.slots))/float64(cap(l.slots)))
+}
+
+// ConnLimits caps the simultaneous connections of a TCP server
+type ConnLimits struct {
+	// Listener limits the connections of the server and Global those of all servers sharing it, nil does not
+	// limit them
+	Listener *ConnLimit
+	Global   *ConnLimit
+	// Queue stops accepting connections while a limit is reached, which leaves further connections in the
+	// listen backlog until a connection closes. Otherwise connections over a limit are closed right after
+	// accepting them.
+	Queue bool
+}
+
+// wait waits until both limits have a free slot and takes them, it reports false if ctx is done first
+func (c ConnLimits) wait(ctx context.Context) bool {
+	if !c.Listener.acquire(ctx) {
+		return false
+	}
+	if !c.Global.acquire(ctx) {
+		c.Listener.release()
+		return false
+	}
+	return true
+}
+
+// admit takes a slot of both limits for an accepted connection, or closes it with a RST if either is reached
+func (c ConnLimits) admit(conn net.Conn) bool {
+	limit := c.Listener
+	if limit.tryAcquire() {
+		if limit = c.Global; limit.tryAcquire() {
+			return true
+		}
+		c.Listener.release()
+	}
+	limit.mc.IncConnectionsRejected(limit.name)
+	logging.Logger.Debugf("Rejected TCP connection from %s, the %s connection limit of %d is reached", conn.RemoteAddr(), limit.name, cap(limit.slots))
+	if tc, ok := conn.(*net.TCPConn); ok {
+		// A RST tells the client right away and leaves no TIME_WAIT state behind
+		_ = tc.SetLinger(0)
+	}
+	_ = conn.Close()
+	return false
+}
+
+// release frees the slots of a connection
+func (c ConnLimits) release() {
+	c.Global.release()
+	c.Listener.release()
+}
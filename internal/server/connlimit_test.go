@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/handlers"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnLimit(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	assert.Nil(t, NewConnLimit(mc, "global", 0))
+
+	// A nil limit never runs out of slots
+	var unlimited *ConnLimit
+	assert.True(t, unlimited.tryAcquire())
+	unlimited.release()
+
+	l := NewConnLimit(mc, "8080", 2)
+	saturation := func() float64 { return testutil.ToFloat64(mc.ConnectionLimitSaturation.WithLabelValues("8080")) }
+	assert.Equal(t, float64(0), saturation())
+	assert.True(t, l.tryAcquire())
+	assert.Equal(t, 0.5, saturation())
+	assert.True(t, l.acquire(context.Background()))
+	assert.False(t, l.tryAcquire())
+	assert.Equal(t, float64(1), saturation())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.False(t, l.acquire(ctx))
+
+	l.release()
+	assert.Equal(t, 0.5, saturation())
+	assert.True(t, l.tryAcquire())
+}
+
+// startLimitedServer starts an echo server whose listener allows a single connection
+func startLimitedServer(t *testing.T, mc *metrics.MetricsCollector, queue bool) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+
+	server := NewTCPServer(port, handlers.NewTCPHandler(mc))
+	server.SetConnLimits(ConnLimits{Listener: NewConnLimit(mc, "listener", 1), Global: NewConnLimit(mc, "global", 10), Queue: queue})
+	require.NoError(t, server.Start())
+	t.Cleanup(func() { _ = server.Stop() })
+	return fmt.Sprintf("127.0.0.1:%d", port)
+}
+
+// echoes reports whether the server echoes a request on conn within the timeout
+func echoes(conn net.Conn, timeout time.Duration) bool {
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		return false
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	buf := make([]byte, 4)
+	n, err := conn.Read(buf)
+	return err == nil && string(buf[:n]) == "ping"
+}
+
+func TestTCPServerConnLimitReject(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	addr := startLimitedServer(t, mc, false)
+
+	first, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	require.True(t, echoes(first, time.Second))
+
+	// The connection over the limit is reset right after it was accepted
+	second, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer func() { _ = second.Close() }()
+	assert.False(t, echoes(second, time.Second))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.ConnectionsRejected.WithLabelValues("listener")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(mc.ConnectionsRejected.WithLabelValues("global")))
+
+	// Closing the first connection frees its slot
+	_ = first.Close()
+	assert.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return false
+		}
+		defer func() { _ = conn.Close() }()
+		return echoes(conn, time.Second)
+	}, 2*time.Second, 20*time.Millisecond)
+}
+
+func TestTCPServerConnLimitQueue(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	addr := startLimitedServer(t, mc, true)
+
+	first, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	require.True(t, echoes(first, time.Second))
+
+	// The connection over the limit waits in the listen backlog
+	second, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer func() { _ = second.Close() }()
+	assert.False(t, echoes(second, 200*time.Millisecond))
+
+	// and is served once the first connection closes, its request already waiting
+	_ = first.Close()
+	_ = second.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 4)
+	_, err = second.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+	assert.Equal(t, float64(0), testutil.ToFloat64(mc.ConnectionsRejected.WithLabelValues("listener")))
+}
@@ -41,6 +41,41 @@ func (m *Manager) AddServer(server Server) {
 	m.servers = append(m.servers, server)
 }
 
+// StartServer adds a server and starts it right away if the manager is already running
+func (m *Manager) StartServer(server Server) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.running {
+		if err := server.Start(); err != nil {
+			return fmt.Errorf("failed to start %s server on port %d: %w", server.Type(), server.Port(), err)
+		}
+	}
+	m.servers = append(m.servers, server)
+	return nil
+}
+
+// StopServer stops and removes the server of the given type listening on the given port
+func (m *Manager) StopServer(serverType string, port int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, server := range m.servers {
+		if server.Type() != serverType || server.Port() != port {
+			continue
+		}
+		m.servers = append(m.servers[:i], m.servers[i+1:]...)
+		if !m.running {
+			return nil
+		}
+		if err := server.Stop(); err != nil {
+			return fmt.Errorf("failed to stop %s server on port %d: %w", serverType, port, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("no %s server on port %d", serverType, port)
+}
+
 // Start starts all servers
 func (m *Manager) Start() error {
 	m.mu.Lock()
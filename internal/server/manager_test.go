@@ -202,6 +202,35 @@ func TestManagerStopWithErrors(t *testing.T) {
 	assert.True(t, server1.stopped)
 }
 
+func TestManagerStartStopServer(t *testing.T) {
+	manager := NewManager()
+
+	// Servers added before the manager runs are only started with it
+	server1 := &mockServer{port: 8080, typ: "TCP"}
+	require.NoError(t, manager.StartServer(server1))
+	assert.False(t, server1.started)
+
+	require.NoError(t, manager.Start())
+	defer func() { _ = manager.Stop() }()
+	assert.True(t, server1.started)
+
+	// Servers added while running are started right away
+	server2 := &mockServer{port: 9000, typ: "UDP"}
+	require.NoError(t, manager.StartServer(server2))
+	assert.True(t, server2.started)
+	assert.Equal(t, 2, manager.ServerCount())
+
+	failing := &mockServer{port: 9001, typ: "UDP", startErr: errors.New("address in use")}
+	assert.Error(t, manager.StartServer(failing))
+	assert.Equal(t, 2, manager.ServerCount())
+
+	require.NoError(t, manager.StopServer("TCP", 8080))
+	assert.True(t, server1.stopped)
+	assert.Equal(t, 1, manager.ServerCount())
+
+	assert.Error(t, manager.StopServer("TCP", 8080))
+}
+
 func TestManagerWait(t *testing.T) {
 	manager := NewManager()
 
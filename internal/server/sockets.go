@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net"
+	"syscall"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
+)
+
+// SocketOptions tunes the sockets of a listener
+type SocketOptions struct {
+	// NoDelay sets TCP_NODELAY on accepted TCP connections, which is the Go default
+	NoDelay bool
+	// SendBuffer and ReceiveBuffer are the SO_SNDBUF and SO_RCVBUF sizes in bytes, 0 keeps the kernel default
+	SendBuffer    int
+	ReceiveBuffer int
+	// KeepAlive configures the TCP keepalive probes of accepted TCP connections
+	KeepAlive sockopt.KeepAlive
+}
+
+// DefaultSocketOptions leaves the sockets as Go and the kernel set them up
+var DefaultSocketOptions = SocketOptions{NoDelay: true}
+
+// listenConfig returns the listen configuration applying the options
+func (o SocketOptions) listenConfig() net.ListenConfig {
+	lc := net.ListenConfig{Control: o.control}
+	lc.KeepAlive, lc.KeepAliveConfig = o.KeepAlive.Settings()
+	return lc
+}
+
+// control is the net.ListenConfig control function setting the buffer sizes on the listening socket,
+// which accepted TCP connections inherit
+func (o SocketOptions) control(network, address string, c syscall.RawConn) error {
+	if o.SendBuffer == 0 && o.ReceiveBuffer == 0 {
+		return nil
+	}
+	return sockopt.SetBuffers(c, o.SendBuffer, o.ReceiveBuffer)
+}
@@ -5,69 +5,140 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"syscall"
 
-	"github.com/PhilipSchmid/flow-generator-app/internal/handlers"
 	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/service"
 )
 
 // TCPServer represents a TCP server
 type TCPServer struct {
-	port     int
-	listener net.Listener
-	handler  *handlers.TCPHandler
-	wg       sync.WaitGroup
-	ctx      context.Context
-	cancel   context.CancelFunc
+	port      int
+	listeners []net.Listener
+	handler   service.ConnHandler
+	opts      SocketOptions
+	// sockets is the number of listen sockets bound to the port, each with its own accept loop
+	sockets int
+	limits  ConnLimits
+	accepts *AcceptLimiter
+	tls     *TLS
+	wg      sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
 }
 
 // NewTCPServer creates a new TCP server
-func NewTCPServer(port int, handler *handlers.TCPHandler) *TCPServer {
+func NewTCPServer(port int, handler service.ConnHandler) *TCPServer {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &TCPServer{
 		port:    port,
 		handler: handler,
+		opts:    DefaultSocketOptions,
 		ctx:     ctx,
 		cancel:  cancel,
 	}
 }
 
+// SetSocketOptions tunes the sockets of the server, it must be called before Start
+func (s *TCPServer) SetSocketOptions(opts SocketOptions) {
+	s.opts = opts
+}
+
+// SetConnLimits caps the simultaneous connections of the server, it must be called before Start
+func (s *TCPServer) SetConnLimits(limits ConnLimits) {
+	s.limits = limits
+}
+
+// SetAcceptLimiter paces the accepted connections of the server, it must be called before Start
+func (s *TCPServer) SetAcceptLimiter(l *AcceptLimiter) {
+	s.accepts = l
+}
+
+// SetListenSockets makes the server bind n listen sockets to its port with SO_REUSEPORT, each with its own
+// accept loop, so the kernel spreads new connections over n cores. It must be called before Start.
+func (s *TCPServer) SetListenSockets(n int) {
+	s.sockets = n
+}
+
+// SetTLS makes the server terminate TLS on its connections, it must be called before Start
+func (s *TCPServer) SetTLS(t *TLS) {
+	s.tls = t
+}
+
 // Start starts the TCP server
 func (s *TCPServer) Start() error {
 	addr := fmt.Sprintf(":%d", s.port)
-	listener, err := net.Listen("tcp", addr)
-	if err != nil {
-		return fmt.Errorf("failed to listen on TCP port %d: %w", s.port, err)
+	sockets := max(s.sockets, 1)
+	lc := s.opts.listenConfig()
+	if sockets > 1 {
+		control := lc.Control
+		lc.Control = func(network, address string, c syscall.RawConn) error {
+			if err := sockopt.ReusePort(network, address, c); err != nil {
+				return err
+			}
+			return control(network, address, c)
+		}
+	}
+	for range sockets {
+		listener, err := lc.Listen(s.ctx, "tcp", addr)
+		if err != nil {
+			s.closeListeners()
+			return fmt.Errorf("failed to listen on TCP port %d: %w", s.port, err)
+		}
+		s.listeners = append(s.listeners, listener)
+		// Further sockets bind the port the first one got, should the server listen on an ephemeral port
+		addr = listener.Addr().String()
 	}
-	s.listener = listener
 
-	logging.Logger.Infof("TCP server listening on port %d", s.port)
+	if sockets > 1 {
+		logging.Logger.Infof("%s server listening on port %d with %d listen sockets", s.Type(), s.port, sockets)
+	} else {
+		logging.Logger.Infof("%s server listening on port %d", s.Type(), s.port)
+	}
 
-	s.wg.Add(1)
-	go s.acceptConnections()
+	for _, listener := range s.listeners {
+		s.wg.Add(1)
+		go s.acceptConnections(listener)
+	}
 
 	return nil
 }
 
-// Stop stops the TCP server
-func (s *TCPServer) Stop() error {
-	s.cancel()
-	if s.listener != nil {
-		if err := s.listener.Close(); err != nil {
+// closeListeners closes the listeners of the server
+func (s *TCPServer) closeListeners() {
+	for _, listener := range s.listeners {
+		if err := listener.Close(); err != nil {
 			logging.Logger.Warnf("Error closing TCP listener on port %d: %v", s.port, err)
 		}
 	}
+}
+
+// Stop stops the TCP server
+func (s *TCPServer) Stop() error {
+	s.cancel()
+	s.closeListeners()
 	s.wg.Wait()
-	logging.Logger.Infof("TCP server on port %d stopped", s.port)
+	logging.Logger.Infof("%s server on port %d stopped", s.Type(), s.port)
 	return nil
 }
 
-// acceptConnections accepts incoming connections
-func (s *TCPServer) acceptConnections() {
+// acceptConnections accepts incoming connections of a listener
+func (s *TCPServer) acceptConnections(listener net.Listener) {
 	defer s.wg.Done()
 
 	for {
-		conn, err := s.listener.Accept()
+		if !s.accepts.wait(s.ctx) {
+			return
+		}
+		if s.limits.Queue && !s.limits.wait(s.ctx) {
+			return
+		}
+		conn, err := listener.Accept()
 		if err != nil {
+			if s.limits.Queue {
+				s.limits.release()
+			}
 			select {
 			case <-s.ctx.Done():
 				return
@@ -76,10 +147,27 @@ func (s *TCPServer) acceptConnections() {
 				continue
 			}
 		}
+		if !s.limits.Queue && !s.limits.admit(conn) {
+			continue
+		}
+
+		if tc, ok := conn.(*net.TCPConn); ok && !s.opts.NoDelay {
+			if err := tc.SetNoDelay(false); err != nil {
+				logging.Logger.Warnf("Failed to disable TCP_NODELAY: %v", err)
+			}
+		}
 
 		s.wg.Add(1)
 		go func() {
 			defer s.wg.Done()
+			defer s.limits.release()
+			if s.tls != nil {
+				tlsConn := s.tls.handshake(s.ctx, conn, s.port)
+				if tlsConn == nil {
+					return
+				}
+				conn = tlsConn
+			}
 			s.handler.Handle(conn)
 		}()
 	}
@@ -90,7 +178,10 @@ func (s *TCPServer) Port() int {
 	return s.port
 }
 
-// Type returns the server type
+// Type returns the server type, TLS if it terminates TLS
 func (s *TCPServer) Type() string {
+	if s.tls != nil {
+		return "TLS"
+	}
 	return "TCP"
 }
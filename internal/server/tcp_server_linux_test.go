@@ -0,0 +1,83 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// acceptedSockopts are the socket options of an accepted connection
+type acceptedSockopts struct {
+	noDelay, rcvbuf                         int
+	keepAlive, keepIdle, keepIntvl, keepCnt int
+}
+
+// sockoptHandler reports the socket options of every accepted connection
+type sockoptHandler chan acceptedSockopts
+
+func (h sockoptHandler) Handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	raw, err := conn.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		return
+	}
+	var o acceptedSockopts
+	_ = raw.Control(func(fd uintptr) {
+		o.noDelay, _ = unix.GetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_NODELAY)
+		o.rcvbuf, _ = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_RCVBUF)
+		o.keepAlive, _ = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_KEEPALIVE)
+		o.keepIdle, _ = unix.GetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPIDLE)
+		o.keepIntvl, _ = unix.GetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPINTVL)
+		o.keepCnt, _ = unix.GetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPCNT)
+	})
+	h <- o
+}
+
+// acceptWithOptions starts a TCP server with the socket options and returns the options of a connection it
+// accepted
+func acceptWithOptions(t *testing.T, opts SocketOptions) acceptedSockopts {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+
+	handler := make(sockoptHandler, 1)
+	server := NewTCPServer(port, handler)
+	server.SetSocketOptions(opts)
+	require.NoError(t, server.Start())
+	defer func() { _ = server.Stop() }()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	select {
+	case o := <-handler:
+		return o
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection not accepted")
+	}
+	return acceptedSockopts{}
+}
+
+func TestTCPServerSocketOptions(t *testing.T) {
+	o := acceptWithOptions(t, SocketOptions{NoDelay: false, ReceiveBuffer: 262144})
+	assert.Zero(t, o.noDelay, "TCP_NODELAY is disabled")
+	// Accepted connections inherit the buffer of the listener, which Linux reports doubled
+	assert.Equal(t, 2*262144, o.rcvbuf)
+	assert.Equal(t, 1, o.keepAlive, "keepalives are on by default")
+}
+
+func TestTCPServerKeepAlive(t *testing.T) {
+	o := acceptWithOptions(t, SocketOptions{NoDelay: true, KeepAlive: sockopt.KeepAlive{Idle: 42 * time.Second, Interval: 7 * time.Second, Count: 4}})
+	assert.Equal(t, acceptedSockopts{noDelay: 1, rcvbuf: o.rcvbuf, keepAlive: 1, keepIdle: 42, keepIntvl: 7, keepCnt: 4}, o)
+
+	o = acceptWithOptions(t, SocketOptions{NoDelay: true, KeepAlive: sockopt.KeepAlive{Disabled: true}})
+	assert.Zero(t, o.keepAlive)
+}
@@ -1,7 +1,9 @@
 package server
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"testing"
 	"time"
@@ -9,6 +11,7 @@ import (
 	"github.com/PhilipSchmid/flow-generator-app/internal/handlers"
 	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
 	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -30,6 +33,34 @@ func TestNewTCPServer(t *testing.T) {
 	assert.NotNil(t, server.cancel)
 }
 
+// greetHandler is a custom service answering every connection with a greeting
+type greetHandler struct{}
+
+func (greetHandler) Handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	_, _ = conn.Write([]byte("hello"))
+}
+
+func TestTCPServerCustomHandler(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+
+	server := NewTCPServer(port, greetHandler{})
+	require.NoError(t, server.Start())
+	defer func() { _ = server.Stop() }()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(buf))
+}
+
 func TestTCPServerStartStop(t *testing.T) {
 	mc := metrics.NewMetricsCollector()
 	handler := handlers.NewTCPHandler(mc)
@@ -225,3 +256,28 @@ func BenchmarkTCPServerConnection(b *testing.B) {
 		_ = conn.Close()
 	}
 }
+
+func TestTCPServerListenSockets(t *testing.T) {
+	server := NewTCPServer(0, handlers.NewTCPHandler(metrics.NewMetricsCollector()))
+	server.SetListenSockets(4)
+
+	err := server.Start()
+	if errors.Is(err, sockopt.ErrUnsupported) {
+		t.Skip("SO_REUSEPORT not supported on this platform")
+	}
+	require.NoError(t, err)
+	defer func() { _ = server.Stop() }()
+	require.Len(t, server.listeners, 4)
+	port := server.listeners[0].Addr().(*net.TCPAddr).Port
+	for _, listener := range server.listeners {
+		assert.Equal(t, port, listener.Addr().(*net.TCPAddr).Port)
+	}
+
+	// The kernel spreads the connections over the sockets, every one of them is accepted
+	for range 20 {
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		require.NoError(t, err)
+		assert.True(t, echoes(conn, time.Second))
+		_ = conn.Close()
+	}
+}
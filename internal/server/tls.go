@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+)
+
+const (
+	// tlsHandshakeTimeout is how long a client may take to complete the TLS handshake
+	tlsHandshakeTimeout = 10 * time.Second
+	// selfSignedName is the name of the self-signed certificate of clients sending no SNI
+	selfSignedName = "localhost"
+	// maxSelfSignedNames is the number of SNI names self-signed certificates are generated and kept for, clients
+	// sending further names get the certificate of selfSignedName
+	maxSelfSignedNames = 1024
+)
+
+// TLS terminates TLS on the connections of TCP servers. A client gets the configured certificate matching its
+// SNI, or a self-signed certificate generated for its SNI if none were configured.
+type TLS struct {
+	config *tls.Config
+	mc     *metrics.MetricsCollector
+
+	mu         sync.Mutex
+	selfSigned map[string]*tls.Certificate
+}
+
+// NewTLS creates the TLS termination of the given certificates, the first of which is served to clients whose
+// SNI matches none. Without any, certificates are self-signed for every SNI.
+func NewTLS(mc *metrics.MetricsCollector, certs []tls.Certificate) *TLS {
+	t := &TLS{mc: mc}
+	t.config = &tls.Config{MinVersion: tls.VersionTLS12, Certificates: certs}
+	if len(certs) == 0 {
+		t.selfSigned = make(map[string]*tls.Certificate)
+		t.config.GetCertificate = t.selfSignedCertificate
+	}
+	return t
+}
+
+// selfSignedCertificate returns the self-signed certificate of the SNI of a client, generating it on first use
+func (t *TLS) selfSignedCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	name := hello.ServerName
+	if name == "" || (len(t.selfSigned) >= maxSelfSignedNames && t.selfSigned[name] == nil) {
+		name = selfSignedName
+	}
+	if cert, ok := t.selfSigned[name]; ok {
+		return cert, nil
+	}
+	cert, err := selfSign(name)
+	if err != nil {
+		return nil, err
+	}
+	t.selfSigned[name] = cert
+	return cert, nil
+}
+
+// selfSign generates a self-signed certificate for a host name or IP address, valid for a year
+func selfSign(name string) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.AddDate(1, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ip := net.ParseIP(name); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{name}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// handshake completes the TLS handshake of a connection accepted on the given port and records the SNI and
+// cipher suite it negotiated. It closes the connection and returns nil if the handshake fails.
+func (t *TLS) handshake(ctx context.Context, conn net.Conn, port int) *tls.Conn {
+	portStr := strconv.Itoa(port)
+	tlsConn := tls.Server(conn, t.config)
+	ctx, cancel := context.WithTimeout(ctx, tlsHandshakeTimeout)
+	defer cancel()
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		logging.Logger.Debugf("TLS handshake with %s failed: %v", conn.RemoteAddr(), err)
+		t.mc.IncTLSHandshakeFailures(portStr)
+		_ = conn.Close()
+		return nil
+	}
+	state := tlsConn.ConnectionState()
+	t.mc.IncTLSHandshakes(portStr, SNILabel(state.ServerName), tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+	return tlsConn
+}
+
+// SNILabel returns the metrics label of an SNI, "none" if the client sent none
+func SNILabel(sni string) string {
+	if sni == "" {
+		return "none"
+	}
+	return sni
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/handlers"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTLSServer starts a TLS echo server with the given certificates and returns its port
+func startTLSServer(t *testing.T, mc *metrics.MetricsCollector, certs []tls.Certificate) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+
+	server := NewTCPServer(port, handlers.NewTCPHandler(mc))
+	server.SetTLS(NewTLS(mc, certs))
+	assert.Equal(t, "TLS", server.Type())
+	require.NoError(t, server.Start())
+	t.Cleanup(func() { _ = server.Stop() })
+	return port
+}
+
+// dialTLS completes a TLS handshake sending the given SNI and returns the common name of the certificate
+// the server presented
+func dialTLS(t *testing.T, port int, sni string) (*tls.Conn, string) {
+	// #nosec G402 - the test servers present self-signed certificates
+	conn, err := tls.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port), &tls.Config{InsecureSkipVerify: true, ServerName: sni})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn, conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+func TestTCPServerTLSSelfSigned(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	port := startTLSServer(t, mc, nil)
+	portStr := fmt.Sprint(port)
+
+	conn, name := dialTLS(t, port, "a.example.com")
+	assert.Equal(t, "a.example.com", name)
+	require.True(t, echoes(conn, time.Second))
+	state := conn.ConnectionState()
+	assert.Equal(t, 1.0, testutil.ToFloat64(mc.TLSHandshakes.WithLabelValues(portStr, "a.example.com",
+		tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))))
+
+	// Clients sending no SNI get the certificate of localhost
+	_, name = dialTLS(t, port, "")
+	assert.Equal(t, selfSignedName, name)
+
+	// Plaintext clients fail the handshake
+	plain, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.NoError(t, err)
+	defer func() { _ = plain.Close() }()
+	_, err = plain.Write([]byte("GET / HTTP/1.0\r\n\r\n"))
+	require.NoError(t, err)
+	_ = plain.SetReadDeadline(time.Now().Add(time.Second))
+	_, _ = plain.Read(make([]byte, 64))
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(mc.TLSHandshakeFailures.WithLabelValues(portStr)) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTCPServerTLSCertificates(t *testing.T) {
+	var certs []tls.Certificate
+	for _, name := range []string{"a.example.com", "b.example.com"} {
+		cert, err := selfSign(name)
+		require.NoError(t, err)
+		certs = append(certs, *cert)
+	}
+	port := startTLSServer(t, metrics.NewMetricsCollector(), certs)
+
+	tests := []struct {
+		sni  string
+		want string
+	}{
+		{"a.example.com", "a.example.com"},
+		{"b.example.com", "b.example.com"},
+		// The first certificate is served to clients whose SNI matches none
+		{"c.example.com", "a.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sni, func(t *testing.T) {
+			_, name := dialTLS(t, port, tt.sni)
+			assert.Equal(t, tt.want, name)
+		})
+	}
+}
+
+func TestSNILabel(t *testing.T) {
+	assert.Equal(t, "none", SNILabel(""))
+	assert.Equal(t, "a.example.com", SNILabel("a.example.com"))
+}
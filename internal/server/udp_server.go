@@ -5,32 +5,50 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"syscall"
 
-	"github.com/PhilipSchmid/flow-generator-app/internal/handlers"
 	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/service"
 )
 
 // UDPServer represents a UDP server
 type UDPServer struct {
 	port    int
-	conn    *net.UDPConn
-	handler *handlers.UDPHandler
+	conns   []*net.UDPConn
+	handler service.PacketHandler
+	opts    SocketOptions
+	// workers is the number of sockets bound to the port, each served by its own goroutine
+	workers int
 	wg      sync.WaitGroup
 	ctx     context.Context
 	cancel  context.CancelFunc
 }
 
 // NewUDPServer creates a new UDP server
-func NewUDPServer(port int, handler *handlers.UDPHandler) *UDPServer {
+func NewUDPServer(port int, handler service.PacketHandler) *UDPServer {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &UDPServer{
 		port:    port,
 		handler: handler,
+		opts:    DefaultSocketOptions,
 		ctx:     ctx,
 		cancel:  cancel,
 	}
 }
 
+// SetSocketOptions tunes the socket of the server, it must be called before Start. Only the buffer sizes
+// apply to UDP.
+func (s *UDPServer) SetSocketOptions(opts SocketOptions) {
+	s.opts = opts
+}
+
+// SetWorkers makes the server bind n sockets to its port with SO_REUSEPORT, each read by its own goroutine,
+// so the kernel spreads the peers of the port over n cores. It must be called before Start.
+func (s *UDPServer) SetWorkers(n int) {
+	s.workers = n
+}
+
 // Start starts the UDP server
 func (s *UDPServer) Start() error {
 	addr, err := net.ResolveUDPAddr("udp", fmt.Sprintf(":%d", s.port))
@@ -38,31 +56,62 @@ func (s *UDPServer) Start() error {
 		return fmt.Errorf("failed to resolve UDP address: %w", err)
 	}
 
-	conn, err := net.ListenUDP("udp", addr)
-	if err != nil {
-		return fmt.Errorf("failed to listen on UDP port %d: %w", s.port, err)
+	workers := max(s.workers, 1)
+	h, ok := s.handler.(interface{ ConnectedPeers() bool })
+	// Per-peer connected sockets and further workers bind the same port, so the listeners must allow reuse
+	reuse := workers > 1 || ok && h.ConnectedPeers()
+	control := s.opts.control
+	if reuse {
+		control = func(network, address string, c syscall.RawConn) error {
+			if err := sockopt.ReusePort(network, address, c); err != nil {
+				return err
+			}
+			return s.opts.control(network, address, c)
+		}
+	}
+	lc := net.ListenConfig{Control: control}
+	for range workers {
+		pc, err := lc.ListenPacket(s.ctx, "udp", addr.String())
+		if err != nil {
+			s.closeConns()
+			return fmt.Errorf("failed to listen on UDP port %d: %w", s.port, err)
+		}
+		conn := pc.(*net.UDPConn)
+		s.conns = append(s.conns, conn)
+		// Further workers bind the port the first one got, should the server listen on an ephemeral port
+		addr = conn.LocalAddr().(*net.UDPAddr)
 	}
-	s.conn = conn
 
-	logging.Logger.Infof("UDP server listening on port %d", s.port)
+	if workers > 1 {
+		logging.Logger.Infof("UDP server listening on port %d with %d workers", s.port, workers)
+	} else {
+		logging.Logger.Infof("UDP server listening on port %d", s.port)
+	}
 
-	s.wg.Add(1)
-	go func() {
-		defer s.wg.Done()
-		s.handler.Handle(s.conn)
-	}()
+	for _, conn := range s.conns {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handler.Handle(conn)
+		}()
+	}
 
 	return nil
 }
 
-// Stop stops the UDP server
-func (s *UDPServer) Stop() error {
-	s.cancel()
-	if s.conn != nil {
-		if err := s.conn.Close(); err != nil {
+// closeConns closes the sockets of the server
+func (s *UDPServer) closeConns() {
+	for _, conn := range s.conns {
+		if err := conn.Close(); err != nil {
 			logging.Logger.Warnf("Error closing UDP connection on port %d: %v", s.port, err)
 		}
 	}
+}
+
+// Stop stops the UDP server
+func (s *UDPServer) Stop() error {
+	s.cancel()
+	s.closeConns()
 	s.wg.Wait()
 	logging.Logger.Infof("UDP server on port %d stopped", s.port)
 	return nil
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"testing"
@@ -9,6 +10,7 @@ import (
 	"github.com/PhilipSchmid/flow-generator-app/internal/handlers"
 	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
 	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -229,3 +231,36 @@ func BenchmarkUDPServerPacket(b *testing.B) {
 		}
 	}
 }
+
+func TestUDPServerWorkers(t *testing.T) {
+	mc := metrics.NewMetricsCollector()
+	server := NewUDPServer(0, handlers.NewUDPHandler(mc))
+	server.SetWorkers(4)
+
+	err := server.Start()
+	if errors.Is(err, sockopt.ErrUnsupported) {
+		t.Skip("SO_REUSEPORT not supported on this platform")
+	}
+	require.NoError(t, err)
+	defer func() { _ = server.Stop() }()
+	require.Len(t, server.conns, 4)
+	port := server.conns[0].LocalAddr().(*net.UDPAddr).Port
+	for _, conn := range server.conns {
+		assert.Equal(t, port, conn.LocalAddr().(*net.UDPAddr).Port)
+	}
+
+	// The kernel spreads the peers over the workers, every one of them is echoed
+	for i := range 20 {
+		clientConn, err := net.Dial("udp", fmt.Sprintf("127.0.0.1:%d", port))
+		require.NoError(t, err)
+		testData := []byte(fmt.Sprintf("Packet %d", i))
+		_, err = clientConn.Write(testData)
+		require.NoError(t, err)
+		buf := make([]byte, 1024)
+		_ = clientConn.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := clientConn.Read(buf)
+		require.NoError(t, err)
+		assert.Equal(t, testData, buf[:n])
+		_ = clientConn.Close()
+	}
+}
@@ -0,0 +1,332 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// gcsMetadataAccount is the metadata server endpoint of the service account of a GCE instance or, with
+	// Workload Identity, a GKE pod
+	gcsMetadataAccount = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default"
+	// gcsSignBlob is the IAM Credentials endpoint signing URLs for service accounts without a key file
+	gcsSignBlob = "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/%s:signBlob"
+	// gcsScope is the OAuth scope of the access tokens exchanged for a service account key
+	gcsScope = "https://www.googleapis.com/auth/devstorage.read_write"
+	// gcsHost is the host signed URLs point to
+	gcsHost = "storage.googleapis.com"
+)
+
+// gcsSink uploads artifacts as objects to a Google Cloud Storage bucket. It authenticates with the access
+// token in GOOGLE_OAUTH_ACCESS_TOKEN, the service account key in GOOGLE_APPLICATION_CREDENTIALS or the
+// service account of the metadata server, and talks to the emulator in STORAGE_EMULATOR_HOST if set.
+type gcsSink struct {
+	bucket string
+	prefix string
+	client *http.Client
+	// now returns the signing time, metadataURL and signBlobURL are where the service account of the
+	// metadata server gets its tokens and URL signatures. They are replaced in tests.
+	now         func() time.Time
+	metadataURL string
+	signBlobURL string
+
+	mu sync.Mutex
+	// token is the cached access token and tokenExpires when it expires
+	token        string
+	tokenExpires time.Time
+}
+
+// serviceAccount is a service account key file as created by the Google Cloud console
+type serviceAccount struct {
+	Type        string `json:"type"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+	key         *rsa.PrivateKey
+}
+
+// newGCSSink returns the sink for gs://bucket/prefix
+func newGCSSink(bucket, prefix string, client *http.Client) *gcsSink {
+	return &gcsSink{bucket: bucket, prefix: prefix, client: client, now: time.Now, metadataURL: gcsMetadataAccount, signBlobURL: gcsSignBlob}
+}
+
+func (s *gcsSink) String() string {
+	return strings.TrimSuffix("gs://"+s.bucket+"/"+s.prefix, "/")
+}
+
+// emulator returns the endpoint of the emulator in STORAGE_EMULATOR_HOST, or "" if none is set
+func emulator() string {
+	endpoint := strings.TrimSuffix(os.Getenv("STORAGE_EMULATOR_HOST"), "/")
+	if endpoint != "" && !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	return endpoint
+}
+
+func (s *gcsSink) Write(ctx context.Context, name string, data []byte) error {
+	endpoint := "https://" + gcsHost
+	emulator := emulator()
+	if emulator != "" {
+		endpoint = emulator
+	}
+	u := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=media&name=%s", endpoint, url.PathEscape(s.bucket), url.QueryEscape(objectKey(s.prefix, name)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType(name))
+	// The emulator accepts unauthenticated uploads
+	if emulator == "" {
+		token, err := s.accessToken(ctx)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return do(s.client, req)
+}
+
+// URL returns a V4 signed URL the artifact can be downloaded from without credentials for up to 7 days. It
+// is signed with the service account key in GOOGLE_APPLICATION_CREDENTIALS, or else by the IAM Credentials
+// API for the service account of the metadata server. Artifacts in the emulator are returned by their
+// download URL.
+func (s *gcsSink) URL(ctx context.Context, name string, expiry time.Duration) (string, error) {
+	key := objectKey(s.prefix, name)
+	if emulator := emulator(); emulator != "" {
+		return fmt.Sprintf("%s/download/storage/v1/b/%s/o/%s?alt=media", emulator, url.PathEscape(s.bucket), url.PathEscape(key)), nil
+	}
+	sa, err := loadServiceAccount()
+	if err != nil {
+		return "", err
+	}
+	var email string
+	var sign func(ctx context.Context, data []byte) ([]byte, error)
+	if sa != nil {
+		email = sa.ClientEmail
+		sign = func(_ context.Context, data []byte) ([]byte, error) {
+			digest := sha256.Sum256(data)
+			return rsa.SignPKCS1v15(nil, sa.key, crypto.SHA256, digest[:])
+		}
+	} else {
+		body, err := s.metadataGet(ctx, "/email")
+		if err != nil {
+			return "", fmt.Errorf("signing a URL requires a service account key in GOOGLE_APPLICATION_CREDENTIALS or the metadata server: %w", err)
+		}
+		email = strings.TrimSpace(string(body))
+		sign = func(ctx context.Context, data []byte) ([]byte, error) {
+			return s.signBlob(ctx, email, data)
+		}
+	}
+
+	now := s.now().UTC()
+	datetime := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/auto/storage/goog4_request"
+	query := url.Values{}
+	query.Set("X-Goog-Algorithm", "GOOG4-RSA-SHA256")
+	query.Set("X-Goog-Credential", email+"/"+scope)
+	query.Set("X-Goog-Date", datetime)
+	query.Set("X-Goog-Expires", strconv.Itoa(int(expiry.Seconds())))
+	query.Set("X-Goog-SignedHeaders", "host")
+	canonicalQuery := strings.ReplaceAll(query.Encode(), "+", "%20")
+	path := uriEncodePath("/" + s.bucket + "/" + key)
+	canonicalRequest := strings.Join([]string{http.MethodGet, path, canonicalQuery, "host:" + gcsHost + "\n", "host", "UNSIGNED-PAYLOAD"}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	signature, err := sign(ctx, []byte("GOOG4-RSA-SHA256\n"+datetime+"\n"+scope+"\n"+hex.EncodeToString(requestHash[:])))
+	if err != nil {
+		return "", err
+	}
+	return "https://" + gcsHost + path + "?" + canonicalQuery + "&X-Goog-Signature=" + hex.EncodeToString(signature), nil
+}
+
+// accessToken returns the access token of GOOGLE_OAUTH_ACCESS_TOKEN, or else one exchanged for the service
+// account key or fetched from the metadata server, which is cached until shortly before it expires
+func (s *gcsSink) accessToken(ctx context.Context) (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Until(s.tokenExpires) > time.Minute {
+		return s.token, nil
+	}
+	sa, err := loadServiceAccount()
+	if err != nil {
+		return "", err
+	}
+	var token string
+	var expiresIn int
+	if sa != nil {
+		token, expiresIn, err = s.exchangeKey(ctx, sa)
+	} else {
+		token, expiresIn, err = s.metadataToken(ctx)
+	}
+	if err != nil {
+		return "", err
+	}
+	s.token, s.tokenExpires = token, time.Now().Add(time.Duration(expiresIn)*time.Second)
+	return token, nil
+}
+
+// metadataToken fetches an access token of the service account of the metadata server
+func (s *gcsSink) metadataToken(ctx context.Context) (string, int, error) {
+	body, err := s.metadataGet(ctx, "/token")
+	if err != nil {
+		return "", 0, fmt.Errorf("GOOGLE_OAUTH_ACCESS_TOKEN and GOOGLE_APPLICATION_CREDENTIALS are not set and the metadata server is unreachable: %w", err)
+	}
+	return decodeToken(body)
+}
+
+// metadataGet fetches a property of the service account from the metadata server
+func (s *gcsSink) metadataGet(ctx context.Context, property string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.metadataURL+property, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	return s.send(req)
+}
+
+// exchangeKey exchanges a JWT signed with the service account key for an access token
+func (s *gcsSink) exchangeKey(ctx context.Context, sa *serviceAccount) (string, int, error) {
+	now := s.now().Unix()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{"iss": sa.ClientEmail, "scope": gcsScope, "aud": sa.TokenURI, "iat": now, "exp": now + 3600})
+	if err != nil {
+		return "", 0, err
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(nil, sa.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", 0, err
+	}
+	form := url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"}, "assertion": {unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sa.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	body, err := s.send(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to exchange the service account key of %s for an access token: %w", sa.ClientEmail, err)
+	}
+	return decodeToken(body)
+}
+
+// signBlob signs data with the key of a service account through the IAM Credentials API, which requires
+// the account to hold the Service Account Token Creator role on itself
+func (s *gcsSink) signBlob(ctx context.Context, email string, data []byte) ([]byte, error) {
+	token, err := s.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(map[string]string{"payload": base64.StdEncoding.EncodeToString(data)})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(s.signBlobURL, url.PathEscape(email)), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	body, err := s.send(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign URL for %s: %w", email, err)
+	}
+	var signed struct {
+		SignedBlob string `json:"signedBlob"`
+	}
+	if err := json.Unmarshal(body, &signed); err != nil {
+		return nil, fmt.Errorf("failed to decode signed URL: %w", err)
+	}
+	return base64.StdEncoding.DecodeString(signed.SignedBlob)
+}
+
+// send sends an authentication request and returns the response body
+func (s *gcsSink) send(req *http.Request) ([]byte, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s: %s", req.URL.Redacted(), resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// decodeToken decodes an OAuth token response into the access token and its lifetime in seconds
+func decodeToken(body []byte) (string, int, error) {
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", 0, fmt.Errorf("failed to decode access token: %w", err)
+	}
+	return token.AccessToken, token.ExpiresIn, nil
+}
+
+// loadServiceAccount returns the service account key of GOOGLE_APPLICATION_CREDENTIALS, or nil if it is not
+// set or holds other credentials, such as those of a user
+func loadServiceAccount() (*serviceAccount, error) {
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		return nil, nil
+	}
+	// #nosec G304 - the credentials file is chosen by the operator
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GOOGLE_APPLICATION_CREDENTIALS: %w", err)
+	}
+	var sa serviceAccount
+	if err := json.Unmarshal(data, &sa); err != nil {
+		return nil, fmt.Errorf("failed to decode GOOGLE_APPLICATION_CREDENTIALS: %w", err)
+	}
+	if sa.Type != "service_account" {
+		return nil, nil
+	}
+	if sa.TokenURI == "" {
+		sa.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	block, _ := pem.Decode([]byte(sa.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("service account key of %s has no PEM private key", sa.ClientEmail)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("failed to parse service account key of %s: %w", sa.ClientEmail, err)
+		}
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("service account key of %s is not an RSA key", sa.ClientEmail)
+	}
+	sa.key = rsaKey
+	return &sa, nil
+}
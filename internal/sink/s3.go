@@ -0,0 +1,298 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// imdsEndpoint is the EC2 instance metadata service handing out the credentials of the instance role
+const imdsEndpoint = "http://169.254.169.254"
+
+// s3Sink uploads artifacts as objects to an S3 bucket, or a bucket of an S3-compatible store such as MinIO.
+// Requests are signed with AWS Signature Version 4 using the credentials of the standard AWS environment
+// variables, or those of the instance role from the instance metadata service.
+type s3Sink struct {
+	bucket string
+	prefix string
+	client *http.Client
+	// now returns the signing time, it is replaced in tests
+	now func() time.Time
+	// imdsURL is where instance role credentials are fetched, it is replaced in tests
+	imdsURL string
+
+	mu sync.Mutex
+	// roleCredentials are the cached instance role credentials
+	roleCredentials *awsCredentials
+}
+
+// awsCredentials are the credentials requests are signed with
+type awsCredentials struct {
+	accessKey string
+	secretKey string
+	token     string
+	// expires is when temporary credentials expire, zero for static ones
+	expires time.Time
+}
+
+// newS3Sink returns the sink for s3://bucket/prefix
+func newS3Sink(bucket, prefix string, client *http.Client) *s3Sink {
+	return &s3Sink{bucket: bucket, prefix: prefix, client: client, now: time.Now, imdsURL: imdsEndpoint}
+}
+
+func (s *s3Sink) String() string {
+	return strings.TrimSuffix("s3://"+s.bucket+"/"+s.prefix, "/")
+}
+
+// region returns the region of the bucket from AWS_REGION or AWS_DEFAULT_REGION, us-east-1 by default
+func (s *s3Sink) region() string {
+	for _, key := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if v := os.Getenv(key); v != "" {
+			return v
+		}
+	}
+	return "us-east-1"
+}
+
+// objectURL returns the URL of an object. A custom endpoint set with AWS_ENDPOINT_URL_S3 or
+// AWS_ENDPOINT_URL is addressed path-style, AWS itself virtual-hosted-style.
+func (s *s3Sink) objectURL(key string) (*url.URL, error) {
+	escaped := uriEncodePath("/" + key)
+	for _, env := range []string{"AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"} {
+		if endpoint := os.Getenv(env); endpoint != "" {
+			return url.Parse(strings.TrimSuffix(endpoint, "/") + "/" + s.bucket + escaped)
+		}
+	}
+	return url.Parse(fmt.Sprintf("https://%s.s3.%s.amazonaws.com%s", s.bucket, s.region(), escaped))
+}
+
+// uriEncodePath percent-encodes every byte of a path except the unreserved characters and slashes, as the
+// canonical request of a signature expects
+func uriEncodePath(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if c == '/' || c == '-' || c == '_' || c == '.' || c == '~' ||
+			('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func (s *s3Sink) Write(ctx context.Context, name string, data []byte) error {
+	creds, err := s.credentials(ctx)
+	if err != nil {
+		return err
+	}
+	u, err := s.objectURL(objectKey(s.prefix, name))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType(name))
+	if creds.token != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.token)
+	}
+	signV4(req, data, creds.accessKey, creds.secretKey, s.region(), s.now())
+	return do(s.client, req)
+}
+
+// URL returns a presigned URL the artifact can be downloaded from without credentials until it expires.
+// It is valid for at most 7 days, and no longer than temporary credentials it was signed with.
+func (s *s3Sink) URL(ctx context.Context, name string, expiry time.Duration) (string, error) {
+	creds, err := s.credentials(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := s.objectURL(objectKey(s.prefix, name))
+	if err != nil {
+		return "", err
+	}
+	return presignV4(u, creds, s.region(), s.now(), expiry), nil
+}
+
+// credentials returns the credentials of AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN, or
+// else those of the instance role, which are cached until shortly before they expire
+func (s *s3Sink) credentials(ctx context.Context) (awsCredentials, error) {
+	accessKey, secretKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey != "" && secretKey != "" {
+		return awsCredentials{accessKey: accessKey, secretKey: secretKey, token: os.Getenv("AWS_SESSION_TOKEN")}, nil
+	}
+	if os.Getenv("AWS_EC2_METADATA_DISABLED") == "true" {
+		return awsCredentials{}, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.roleCredentials != nil && time.Until(s.roleCredentials.expires) > 5*time.Minute {
+		return *s.roleCredentials, nil
+	}
+	creds, err := s.fetchRoleCredentials(ctx)
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are not set and the instance metadata service is unreachable: %w", err)
+	}
+	s.roleCredentials = &creds
+	return creds, nil
+}
+
+// fetchRoleCredentials fetches the credentials of the instance role from the instance metadata service with
+// an IMDSv2 session token
+func (s *s3Sink) fetchRoleCredentials(ctx context.Context) (awsCredentials, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.imdsURL+"/latest/api/token", nil)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	req.Header.Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", "300")
+	token, err := s.imdsGet(req)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	const path = "/latest/meta-data/iam/security-credentials/"
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, s.imdsURL+path, nil)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	req.Header.Set("X-Aws-Ec2-Metadata-Token", string(token))
+	roles, err := s.imdsGet(req)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	roleName, _, _ := strings.Cut(strings.TrimSpace(string(roles)), "\n")
+	if roleName == "" {
+		return awsCredentials{}, fmt.Errorf("the instance has no role")
+	}
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, s.imdsURL+path+url.PathEscape(roleName), nil)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	req.Header.Set("X-Aws-Ec2-Metadata-Token", string(token))
+	body, err := s.imdsGet(req)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	var role struct {
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string `json:"SecretAccessKey"`
+		Token           string `json:"Token"`
+		Expiration      time.Time
+	}
+	if err := json.Unmarshal(body, &role); err != nil {
+		return awsCredentials{}, fmt.Errorf("failed to decode instance role credentials: %w", err)
+	}
+	return awsCredentials{accessKey: role.AccessKeyID, secretKey: role.SecretAccessKey, token: role.Token, expires: role.Expiration}, nil
+}
+
+// imdsGet sends a request to the instance metadata service and returns the response body
+func (s *s3Sink) imdsGet(req *http.Request) ([]byte, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("instance metadata service returned %s for %s", resp.Status, req.URL.Path)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+}
+
+// signV4 signs a request to S3 with AWS Signature Version 4, covering the host, the content type, the date
+// and all x-amz-* headers
+func signV4(req *http.Request, payload []byte, accessKey, secretKey, region string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256.Sum256(payload)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	headers := map[string]string{"host": req.URL.Host}
+	for key, values := range req.Header {
+		lower := strings.ToLower(key)
+		if lower == "content-type" || lower == "date" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncodePath(req.URL.Path),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	signature := signatureV4(canonicalRequest, secretKey, amzDate, scope)
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKey, scope, signedHeaders, signature))
+}
+
+// presignV4 returns the URL of a GET request for u that is valid for expiry, signed with AWS Signature
+// Version 4 in the query string
+func presignV4(u *url.URL, creds awsCredentials, region string, now time.Time, expiry time.Duration) string {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/" + region + "/s3/aws4_request"
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", creds.accessKey+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expiry.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if creds.token != "" {
+		query.Set("X-Amz-Security-Token", creds.token)
+	}
+	// Signatures expect spaces encoded as %20 rather than +
+	canonicalQuery := strings.ReplaceAll(query.Encode(), "+", "%20")
+	path := uriEncodePath(u.Path)
+	canonicalRequest := strings.Join([]string{http.MethodGet, path, canonicalQuery, "host:" + u.Host + "\n", "host", "UNSIGNED-PAYLOAD"}, "\n")
+	signature := signatureV4(canonicalRequest, creds.secretKey, amzDate, scope)
+	return u.Scheme + "://" + u.Host + path + "?" + canonicalQuery + "&X-Amz-Signature=" + signature
+}
+
+// signatureV4 returns the AWS Signature Version 4 of a canonical request in the given credential scope
+func signatureV4(canonicalRequest, secretKey, amzDate, scope string) string {
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+	key := []byte("AWS4" + secretKey)
+	for _, part := range strings.Split(scope, "/") {
+		key = hmacSHA256(key, part)
+	}
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data under key
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
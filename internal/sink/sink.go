@@ -0,0 +1,255 @@
+// Package sink delivers run artifacts such as reports, flow logs and bundles to stdout, local directories,
+// HTTP endpoints and S3 or GCS object storage, so they outlive ephemeral runners like Kubernetes Jobs.
+package sink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+)
+
+const (
+	// requestTimeout bounds a single delivery attempt to a remote sink
+	requestTimeout = 30 * time.Second
+	// MaxURLExpiry is the longest time presigned S3 and signed GCS URLs can be valid for
+	MaxURLExpiry = 7 * 24 * time.Hour
+)
+
+// Sink is a destination for run artifacts
+type Sink interface {
+	// Write delivers the artifact with the given file name
+	Write(ctx context.Context, name string, data []byte) error
+	// String describes the destination for logs
+	String() string
+}
+
+// Locator is implemented by sinks whose artifacts can be downloaded by URL once delivered
+type Locator interface {
+	// URL returns a URL the artifact with the given name can be downloaded from for up to expiry
+	URL(ctx context.Context, name string, expiry time.Duration) (string, error)
+}
+
+// New returns the sink for a destination: "-" for stdout, a local directory path or file:// URL, an
+// http:// or https:// URL artifacts are POSTed to, s3://bucket/prefix or gs://bucket/prefix
+func New(destination string) (Sink, error) {
+	if destination == "-" {
+		return &writerSink{name: "stdout", w: os.Stdout}, nil
+	}
+	scheme, rest, found := strings.Cut(destination, "://")
+	if !found {
+		if destination == "" {
+			return nil, fmt.Errorf("empty sink destination")
+		}
+		return &dirSink{dir: destination}, nil
+	}
+	switch scheme {
+	case "file":
+		if rest == "" {
+			return nil, fmt.Errorf("sink %s has no directory", destination)
+		}
+		return &dirSink{dir: rest}, nil
+	case "http", "https":
+		u, err := url.Parse(destination)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid sink URL %q", destination)
+		}
+		return &httpSink{url: destination, client: &http.Client{Timeout: requestTimeout}}, nil
+	case "s3", "gs":
+		bucket, prefix, _ := strings.Cut(rest, "/")
+		if bucket == "" {
+			return nil, fmt.Errorf("sink %s has no bucket", destination)
+		}
+		prefix = strings.Trim(prefix, "/")
+		client := &http.Client{Timeout: requestTimeout}
+		if scheme == "s3" {
+			return newS3Sink(bucket, prefix, client), nil
+		}
+		return newGCSSink(bucket, prefix, client), nil
+	}
+	return nil, fmt.Errorf("unsupported sink scheme %q, must be file, http, https, s3 or gs", scheme)
+}
+
+// Fanout delivers every artifact to all of its sinks, retrying failed deliveries
+type Fanout struct {
+	sinks []Sink
+	// attempts is the number of deliveries tried per sink, backoff the wait before the first retry, which
+	// doubles with every further retry
+	attempts int
+	backoff  time.Duration
+}
+
+// NewFanout returns a fanout to the sinks of the given destinations, which retries a failed delivery up to
+// retries times
+func NewFanout(destinations []string, retries int, backoff time.Duration) (*Fanout, error) {
+	f := &Fanout{attempts: retries + 1, backoff: backoff}
+	for _, d := range destinations {
+		s, err := New(d)
+		if err != nil {
+			return nil, err
+		}
+		f.sinks = append(f.sinks, s)
+	}
+	return f, nil
+}
+
+// String lists the sinks of the fanout
+func (f *Fanout) String() string {
+	names := make([]string, len(f.sinks))
+	for i, s := range f.sinks {
+		names[i] = s.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// Write delivers the artifact to every sink. A sink that still fails after all attempts does not keep the
+// artifact from the others, the errors of all failed sinks are returned.
+func (f *Fanout) Write(ctx context.Context, name string, data []byte) error {
+	var errs []error
+	for _, s := range f.sinks {
+		if err := f.write(ctx, s, name, data); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// URLs returns the download URLs of a delivered artifact from every sink that is a Locator, valid for up
+// to expiry. A sink that fails to provide one is skipped and its error returned along with the others.
+func (f *Fanout) URLs(ctx context.Context, name string, expiry time.Duration) ([]string, error) {
+	var urls []string
+	var errs []error
+	for _, s := range f.sinks {
+		l, ok := s.(Locator)
+		if !ok {
+			continue
+		}
+		u, err := l.URL(ctx, name, expiry)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s, err))
+			continue
+		}
+		urls = append(urls, u)
+	}
+	return urls, errors.Join(errs...)
+}
+
+// write delivers the artifact to a single sink
+func (f *Fanout) write(ctx context.Context, s Sink, name string, data []byte) error {
+	backoff := f.backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = s.Write(ctx, name, data); err == nil {
+			return nil
+		}
+		if attempt >= f.attempts {
+			return err
+		}
+		logging.Logger.Debugf("Delivering %s to %s failed (attempt %d of %d), retrying in %v: %v", name, s, attempt, f.attempts, backoff, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// writerSink writes artifacts to a stream such as stdout
+type writerSink struct {
+	name string
+	w    io.Writer
+}
+
+func (s *writerSink) Write(_ context.Context, _ string, data []byte) error {
+	_, err := s.w.Write(data)
+	return err
+}
+
+func (s *writerSink) String() string {
+	return s.name
+}
+
+// dirSink writes artifacts as files into a local directory, such as a mounted volume
+type dirSink struct {
+	dir string
+}
+
+func (s *dirSink) Write(_ context.Context, name string, data []byte) error {
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.dir, filepath.Base(name)), data, 0o600)
+}
+
+func (s *dirSink) String() string {
+	return s.dir
+}
+
+// httpSink POSTs every artifact to a URL, naming it in the X-Artifact-Name header
+type httpSink struct {
+	url    string
+	client *http.Client
+}
+
+func (s *httpSink) Write(ctx context.Context, name string, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType(name))
+	req.Header.Set("X-Artifact-Name", name)
+	return do(s.client, req)
+}
+
+func (s *httpSink) String() string {
+	return s.url
+}
+
+// contentType returns the media type of an artifact from its file extension
+func contentType(name string) string {
+	switch path.Ext(name) {
+	case ".ndjson":
+		return "application/x-ndjson"
+	case ".tgz", ".gz":
+		return "application/gzip"
+	}
+	if t := mime.TypeByExtension(path.Ext(name)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// objectKey joins the prefix of an object storage sink and the name of an artifact
+func objectKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "/" + name
+}
+
+// do sends a request and fails on any status other than 2xx
+func do(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Redacted(), resp.Status, strings.TrimSpace(string(body)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
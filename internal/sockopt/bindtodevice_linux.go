@@ -0,0 +1,20 @@
+package sockopt
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// BindToDevice binds the socket behind c to the network interface with the given name (SO_BINDTODEVICE),
+// so its traffic leaves through that interface regardless of the routing table.
+func BindToDevice(c syscall.RawConn, iface string) error {
+	var opErr error
+	err := c.Control(func(fd uintptr) {
+		opErr = unix.SetsockoptString(int(fd), unix.SOL_SOCKET, unix.SO_BINDTODEVICE, iface)
+	})
+	if err != nil {
+		return err
+	}
+	return opErr
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package sockopt
+
+import "syscall"
+
+// BindToDevice is not supported on this platform
+func BindToDevice(c syscall.RawConn, iface string) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd
+
+package sockopt
+
+import "syscall"
+
+// SetBuffers is not supported on this platform
+func SetBuffers(c syscall.RawConn, sndbuf, rcvbuf int) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,30 @@
+//go:build linux || darwin || freebsd
+
+package sockopt
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// SetBuffers sets the send (SO_SNDBUF) and receive (SO_RCVBUF) buffer sizes in bytes of the socket behind
+// c, a size of 0 keeps the kernel default. Set on a listening socket, they are inherited by accepted
+// connections, and set before connecting they determine the TCP window scale.
+func SetBuffers(c syscall.RawConn, sndbuf, rcvbuf int) error {
+	var opErr error
+	err := c.Control(func(fd uintptr) {
+		if sndbuf > 0 {
+			if opErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_SNDBUF, sndbuf); opErr != nil {
+				return
+			}
+		}
+		if rcvbuf > 0 {
+			opErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_RCVBUF, rcvbuf)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return opErr
+}
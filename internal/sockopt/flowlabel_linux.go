@@ -0,0 +1,78 @@
+package sockopt
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/netip"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Flow label manager options of linux/in6.h, which x/sys/unix does not export
+const (
+	ipv6FlowLabelMgr = 32
+	ipv6FlowInfoSend = 33
+
+	ipv6FLActionGet  = 0
+	ipv6FLFlagCreate = 1
+	ipv6FLShareAny   = 255
+)
+
+// in6FlowLabelReq is struct in6_flowlabel_req, the request to lease a flow label
+type in6FlowLabelReq struct {
+	Dst     [16]byte
+	Label   [4]byte
+	Action  uint8
+	Share   uint8
+	Flags   uint16
+	Expires uint16
+	Linger  uint16
+	_       uint32
+}
+
+// ConnectFlowLabel connects the IPv6 socket behind c to dst and sends its packets with the given flow label.
+// Linux takes the label of a connected socket from the destination of the connect call, which the standard
+// library leaves empty, so the label is leased from the flow label manager and the socket connected here.
+// A non-blocking TCP socket is left connecting, the connect of the dialer then finds it in progress. A UDP
+// socket can be connected again after dialing.
+func ConnectFlowLabel(c syscall.RawConn, dst netip.AddrPort, label uint32) error {
+	if !dst.Addr().Is6() || dst.Addr().Is4In6() {
+		return errors.New("flow labels require an IPv6 destination")
+	}
+	sa := unix.RawSockaddrInet6{Family: unix.AF_INET6, Addr: dst.Addr().As16()}
+	binary.BigEndian.PutUint16((*[2]byte)(unsafe.Pointer(&sa.Port))[:], dst.Port())
+	binary.BigEndian.PutUint32((*[4]byte)(unsafe.Pointer(&sa.Flowinfo))[:], label)
+	if zone := dst.Addr().Zone(); zone != "" {
+		ifi, err := net.InterfaceByName(zone)
+		if err != nil {
+			return err
+		}
+		sa.Scope_id = uint32(ifi.Index)
+	}
+	req := in6FlowLabelReq{Dst: sa.Addr, Action: ipv6FLActionGet, Share: ipv6FLShareAny, Flags: ipv6FLFlagCreate}
+	binary.BigEndian.PutUint32(req.Label[:], label)
+
+	var opErr error
+	err := c.Control(func(fd uintptr) {
+		_, _, errno := unix.Syscall6(unix.SYS_SETSOCKOPT, fd, unix.IPPROTO_IPV6, ipv6FlowLabelMgr,
+			uintptr(unsafe.Pointer(&req)), unsafe.Sizeof(req), 0)
+		if errno != 0 {
+			opErr = errno
+			return
+		}
+		if opErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, ipv6FlowInfoSend, 1); opErr != nil {
+			return
+		}
+		_, _, errno = unix.Syscall(unix.SYS_CONNECT, fd, uintptr(unsafe.Pointer(&sa)), unsafe.Sizeof(sa))
+		if errno != 0 && errno != unix.EINPROGRESS {
+			opErr = errno
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return opErr
+}
@@ -0,0 +1,74 @@
+package sockopt
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// ipv6FlowInfo is IPV6_FLOWINFO, which makes a socket receive the flow information of incoming packets
+const ipv6FlowInfo = 11
+
+func TestConnectFlowLabelUDP(t *testing.T) {
+	ln, err := net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6loopback})
+	if err != nil {
+		t.Skipf("IPv6 not available: %v", err)
+	}
+	defer func() { _ = ln.Close() }()
+	raw, err := ln.SyscallConn()
+	require.NoError(t, err)
+	require.NoError(t, raw.Control(func(fd uintptr) {
+		require.NoError(t, unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, ipv6FlowInfo, 1))
+	}))
+
+	dst := ln.LocalAddr().(*net.UDPAddr).AddrPort()
+	conn, err := net.DialUDP("udp6", nil, net.UDPAddrFromAddrPort(dst))
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	raw, err = conn.SyscallConn()
+	require.NoError(t, err)
+	require.NoError(t, ConnectFlowLabel(raw, dst, 0x12345))
+
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	buf, oob := make([]byte, 16), make([]byte, 64)
+	_, oobn, _, _, err := ln.ReadMsgUDP(buf, oob)
+	require.NoError(t, err)
+	msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, []byte{0x00, 0x01, 0x23, 0x45}, msgs[0].Data)
+}
+
+func TestConnectFlowLabelTCP(t *testing.T) {
+	ln, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 not available: %v", err)
+	}
+	defer func() { _ = ln.Close() }()
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			_ = conn.Close()
+		}
+	}()
+
+	dst := netip.MustParseAddrPort(ln.Addr().String())
+	d := net.Dialer{Control: func(network, address string, c syscall.RawConn) error {
+		return ConnectFlowLabel(c, dst, 0xabcde)
+	}}
+	conn, err := d.DialContext(context.Background(), "tcp6", ln.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	// Only IPv6 destinations carry a flow label
+	raw, err := conn.(*net.TCPConn).SyscallConn()
+	require.NoError(t, err)
+	assert.Error(t, ConnectFlowLabel(raw, netip.MustParseAddrPort("127.0.0.1:9"), 1))
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package sockopt
+
+import (
+	"net/netip"
+	"syscall"
+)
+
+// ConnectFlowLabel is not supported on this platform
+func ConnectFlowLabel(c syscall.RawConn, dst netip.AddrPort, label uint32) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,29 @@
+package sockopt
+
+import (
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// FreeBind is a net.Dialer/net.ListenConfig control function that enables IP_FREEBIND (IPV6_FREEBIND
+// for IPv6 sockets), so a socket can bind a source address that is not configured on any local interface.
+// It also tries to enable IP_TRANSPARENT, which is only permitted with CAP_NET_ADMIN and is skipped otherwise.
+func FreeBind(network, address string, c syscall.RawConn) error {
+	level, freebind, transparent := unix.SOL_IP, unix.IP_FREEBIND, unix.IP_TRANSPARENT
+	if strings.HasSuffix(network, "6") {
+		level, freebind, transparent = unix.SOL_IPV6, unix.IPV6_FREEBIND, unix.IPV6_TRANSPARENT
+	}
+	var opErr error
+	err := c.Control(func(fd uintptr) {
+		if opErr = unix.SetsockoptInt(int(fd), level, freebind, 1); opErr != nil {
+			return
+		}
+		_ = unix.SetsockoptInt(int(fd), level, transparent, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return opErr
+}
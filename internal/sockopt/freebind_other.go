@@ -0,0 +1,10 @@
+//go:build !linux
+
+package sockopt
+
+import "syscall"
+
+// FreeBind is not supported on this platform
+func FreeBind(network, address string, c syscall.RawConn) error {
+	return ErrUnsupported
+}
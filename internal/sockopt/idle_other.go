@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd
+
+package sockopt
+
+import "syscall"
+
+// CheckIdle is not supported on this platform
+func CheckIdle(conn syscall.Conn) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,41 @@
+//go:build linux || darwin || freebsd
+
+package sockopt
+
+import (
+	"errors"
+	"io"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// CheckIdle checks without blocking that nothing happened to an idle connection. It returns io.EOF if the
+// peer closed the connection, the socket error if it was reset, and ErrDataPending if unexpected data
+// arrived on it.
+func CheckIdle(conn syscall.Conn) error {
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var n int
+	var peekErr error
+	buf := make([]byte, 1)
+	err = rc.Read(func(fd uintptr) bool {
+		// Peek, so the data stays queued if there is any, and never wait for the socket to become readable
+		n, _, peekErr = unix.Recvfrom(int(fd), buf, unix.MSG_PEEK|unix.MSG_DONTWAIT)
+		return true
+	})
+	switch {
+	case err != nil:
+		return err
+	case errors.Is(peekErr, unix.EAGAIN) || errors.Is(peekErr, unix.EWOULDBLOCK):
+		return nil
+	case peekErr != nil:
+		return peekErr
+	case n == 0:
+		return io.EOF
+	default:
+		return ErrDataPending
+	}
+}
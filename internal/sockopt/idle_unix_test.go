@@ -0,0 +1,41 @@
+//go:build linux || darwin || freebsd
+
+package sockopt
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckIdle(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	peer, err := listener.Accept()
+	require.NoError(t, err)
+	tcp := conn.(*net.TCPConn)
+
+	assert.NoError(t, CheckIdle(tcp))
+
+	// Pending data is only peeked at and stays readable
+	_, err = peer.Write([]byte("x"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return CheckIdle(tcp) == ErrDataPending }, time.Second, 10*time.Millisecond)
+	buf := make([]byte, 1)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "x", string(buf))
+	assert.NoError(t, CheckIdle(tcp))
+
+	require.NoError(t, peer.Close())
+	require.Eventually(t, func() bool { return CheckIdle(tcp) == io.EOF }, time.Second, 10*time.Millisecond)
+}
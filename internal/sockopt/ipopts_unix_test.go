@@ -0,0 +1,107 @@
+//go:build linux || darwin || freebsd
+
+package sockopt
+
+import (
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestSetTOS(t *testing.T) {
+	tests := []struct {
+		network string
+		addr    string
+		level   int
+		opt     int
+	}{
+		{"udp4", "127.0.0.1:9", unix.IPPROTO_IP, unix.IP_TOS},
+		{"udp6", "[::1]:9", unix.IPPROTO_IPV6, unix.IPV6_TCLASS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.network, func(t *testing.T) {
+			conn, err := net.Dial(tt.network, tt.addr)
+			if err != nil {
+				t.Skipf("%s not available: %v", tt.network, err)
+			}
+			defer func() { _ = conn.Close() }()
+
+			raw, err := conn.(syscall.Conn).SyscallConn()
+			require.NoError(t, err)
+			// EF (46) in the upper six bits
+			require.NoError(t, SetTOS(tt.network, raw, 46<<2))
+
+			var tos int
+			require.NoError(t, raw.Control(func(fd uintptr) {
+				tos, err = unix.GetsockoptInt(int(fd), tt.level, tt.opt)
+			}))
+			require.NoError(t, err)
+			assert.Equal(t, 46<<2, tos)
+		})
+	}
+}
+
+func TestSetTTL(t *testing.T) {
+	tests := []struct {
+		network string
+		addr    string
+		level   int
+		opt     int
+	}{
+		{"udp4", "127.0.0.1:9", unix.IPPROTO_IP, unix.IP_TTL},
+		{"udp6", "[::1]:9", unix.IPPROTO_IPV6, unix.IPV6_UNICAST_HOPS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.network, func(t *testing.T) {
+			conn, err := net.Dial(tt.network, tt.addr)
+			if err != nil {
+				t.Skipf("%s not available: %v", tt.network, err)
+			}
+			defer func() { _ = conn.Close() }()
+
+			raw, err := conn.(syscall.Conn).SyscallConn()
+			require.NoError(t, err)
+			require.NoError(t, SetTTL(tt.network, raw, 3))
+
+			var ttl int
+			require.NoError(t, raw.Control(func(fd uintptr) {
+				ttl, err = unix.GetsockoptInt(int(fd), tt.level, tt.opt)
+			}))
+			require.NoError(t, err)
+			assert.Equal(t, 3, ttl)
+		})
+	}
+}
+
+func TestSetBuffers(t *testing.T) {
+	conn, err := net.Dial("udp4", "127.0.0.1:9")
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	raw, err := conn.(syscall.Conn).SyscallConn()
+	require.NoError(t, err)
+
+	buffers := func() (snd, rcv int) {
+		require.NoError(t, raw.Control(func(fd uintptr) {
+			snd, err = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_SNDBUF)
+			require.NoError(t, err)
+			rcv, err = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_RCVBUF)
+			require.NoError(t, err)
+		}))
+		return snd, rcv
+	}
+	defaultSnd, _ := buffers()
+
+	// Linux reports twice the requested size to account for its bookkeeping overhead
+	require.NoError(t, SetBuffers(raw, 0, 65536))
+	snd, rcv := buffers()
+	assert.Equal(t, defaultSnd, snd, "a size of 0 keeps the default")
+	assert.Contains(t, []int{65536, 2 * 65536}, rcv)
+
+	require.NoError(t, SetBuffers(raw, 32768, 0))
+	snd, _ = buffers()
+	assert.Contains(t, []int{32768, 2 * 32768}, snd)
+}
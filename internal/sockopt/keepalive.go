@@ -0,0 +1,25 @@
+package sockopt
+
+import (
+	"net"
+	"time"
+)
+
+// KeepAlive configures the TCP keepalive probes of connections. The zero value enables them with the Go
+// defaults, a zero Idle, Interval or Count keeps the default of that setting (15s, 15s and 9 probes).
+type KeepAlive struct {
+	Disabled bool
+	// Idle is the time a connection is idle before the first probe, Interval the time between unanswered
+	// probes and Count the number of unanswered probes after which the connection is dropped
+	Idle     time.Duration
+	Interval time.Duration
+	Count    int
+}
+
+// Settings returns the KeepAlive and KeepAliveConfig fields of a net.Dialer or net.ListenConfig
+func (k KeepAlive) Settings() (time.Duration, net.KeepAliveConfig) {
+	if k.Disabled {
+		return -1, net.KeepAliveConfig{}
+	}
+	return 0, net.KeepAliveConfig{Enable: true, Idle: k.Idle, Interval: k.Interval, Count: k.Count}
+}
@@ -0,0 +1,33 @@
+package sockopt
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// InNetns runs fn on the current OS thread switched into the network namespace ns, so the sockets fn creates
+// on the calling goroutine belong to that namespace, and switches the thread back afterwards. Sockets created
+// on other goroutines stay in the namespace of the process. If the thread cannot be switched back, the
+// goroutine stays locked to it, so the runtime discards the thread once the goroutine exits.
+func InNetns(ns *os.File, fn func() error) error {
+	runtime.LockOSThread()
+	origin, err := os.Open("/proc/thread-self/ns/net")
+	if err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("failed to open the current network namespace: %w", err)
+	}
+	defer func() { _ = origin.Close() }()
+	if err := unix.Setns(int(ns.Fd()), unix.CLONE_NEWNET); err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("failed to enter network namespace %s: %w", ns.Name(), err)
+	}
+	defer func() {
+		if unix.Setns(int(origin.Fd()), unix.CLONE_NEWNET) == nil {
+			runtime.UnlockOSThread()
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package sockopt
+
+import "os"
+
+// InNetns is not supported on this platform
+func InNetns(ns *os.File, fn func() error) error {
+	return ErrUnsupported
+}
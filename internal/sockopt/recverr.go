@@ -0,0 +1,13 @@
+package sockopt
+
+import "net/netip"
+
+// ICMPError is an ICMP or ICMPv6 error the kernel queued on a socket for a packet it sent
+type ICMPError struct {
+	// IPv6 is set for ICMPv6 errors, whose types and codes differ from ICMP ones
+	IPv6 bool
+	Type uint8
+	Code uint8
+	// Offender is the address of the host that sent the error, the invalid address if it is unknown
+	Offender netip.Addr
+}
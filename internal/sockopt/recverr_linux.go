@@ -0,0 +1,110 @@
+package sockopt
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// sizeofSockExtendedErr is the size of struct sock_extended_err, which x/sys/unix does not export
+const sizeofSockExtendedErr = int(unsafe.Sizeof(unix.SockExtendedErr{}))
+
+// RecvErr is a net.Dialer/net.ListenConfig control function that enables IP_RECVERR (and IPV6_RECVERR
+// for IPv6 sockets), so the kernel reports all ICMP errors of the socket and queues their details on the
+// error queue, where ReadICMPError reads them
+func RecvErr(network, address string, c syscall.RawConn) error {
+	var opErr error
+	err := c.Control(func(fd uintptr) {
+		if strings.HasSuffix(network, "6") {
+			if opErr = unix.SetsockoptInt(int(fd), unix.SOL_IPV6, unix.IPV6_RECVERR, 1); opErr != nil {
+				return
+			}
+			// IPv4-mapped destinations of dual-stack sockets get ICMP errors, which IP_RECVERR enables
+			_ = unix.SetsockoptInt(int(fd), unix.SOL_IP, unix.IP_RECVERR, 1)
+			return
+		}
+		opErr = unix.SetsockoptInt(int(fd), unix.SOL_IP, unix.IP_RECVERR, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return opErr
+}
+
+// ReadICMPError drains the error queue of a socket with RecvErr enabled and returns the most recent ICMP
+// error on it. It reports false if no ICMP error was queued.
+func ReadICMPError(c syscall.RawConn) (ICMPError, bool, error) {
+	var latest ICMPError
+	var found bool
+	var opErr error
+	err := c.Control(func(fd uintptr) {
+		// The queued error carries the head of the datagram it refers to, which is not needed
+		buf, oob := make([]byte, 64), make([]byte, 512)
+		for {
+			_, oobn, _, _, err := unix.Recvmsg(int(fd), buf, oob, unix.MSG_ERRQUEUE|unix.MSG_DONTWAIT)
+			if err != nil {
+				if !errors.Is(err, unix.EAGAIN) {
+					opErr = err
+				}
+				return
+			}
+			if e, ok := parseICMPError(oob[:oobn]); ok {
+				latest, found = e, true
+			}
+		}
+	})
+	if err != nil {
+		return ICMPError{}, false, err
+	}
+	return latest, found, opErr
+}
+
+// parseICMPError extracts the ICMP error from the control messages of an error queue entry
+func parseICMPError(oob []byte) (ICMPError, bool) {
+	msgs, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return ICMPError{}, false
+	}
+	for _, m := range msgs {
+		isRecvErr := m.Header.Level == unix.SOL_IP && m.Header.Type == unix.IP_RECVERR ||
+			m.Header.Level == unix.SOL_IPV6 && m.Header.Type == unix.IPV6_RECVERR
+		if !isRecvErr || len(m.Data) < sizeofSockExtendedErr {
+			continue
+		}
+		ee := (*unix.SockExtendedErr)(unsafe.Pointer(&m.Data[0]))
+		if ee.Origin != unix.SO_EE_ORIGIN_ICMP && ee.Origin != unix.SO_EE_ORIGIN_ICMP6 {
+			continue
+		}
+		return ICMPError{
+			IPv6:     ee.Origin == unix.SO_EE_ORIGIN_ICMP6,
+			Type:     ee.Type,
+			Code:     ee.Code,
+			Offender: offenderAddr(m.Data[sizeofSockExtendedErr:]),
+		}, true
+	}
+	return ICMPError{}, false
+}
+
+// offenderAddr decodes the address of the host that sent an error, which follows the extended error as a
+// sockaddr_in or sockaddr_in6
+func offenderAddr(sa []byte) netip.Addr {
+	if len(sa) < 2 {
+		return netip.Addr{}
+	}
+	switch binary.NativeEndian.Uint16(sa) {
+	case unix.AF_INET:
+		if len(sa) >= unix.SizeofSockaddrInet4 {
+			return netip.AddrFrom4([4]byte(sa[4:8]))
+		}
+	case unix.AF_INET6:
+		if len(sa) >= unix.SizeofSockaddrInet6 {
+			return netip.AddrFrom16([16]byte(sa[8:24])).Unmap()
+		}
+	}
+	return netip.Addr{}
+}
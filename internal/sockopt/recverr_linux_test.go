@@ -0,0 +1,68 @@
+package sockopt
+
+import (
+	"net"
+	"net/netip"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// closedUDPPort returns a loopback UDP address nothing listens on
+func closedUDPPort(t *testing.T, network, ip string) *net.UDPAddr {
+	ln, err := net.ListenUDP(network, &net.UDPAddr{IP: net.ParseIP(ip)})
+	if err != nil {
+		t.Skipf("%s not available: %v", network, err)
+	}
+	addr := ln.LocalAddr().(*net.UDPAddr)
+	require.NoError(t, ln.Close())
+	return addr
+}
+
+func TestReadICMPErrorPortUnreachable(t *testing.T) {
+	tests := []struct {
+		network string
+		ip      string
+		want    ICMPError
+	}{
+		{"udp4", "127.0.0.1", ICMPError{Type: 3, Code: 3, Offender: netip.MustParseAddr("127.0.0.1")}},
+		{"udp6", "::1", ICMPError{IPv6: true, Type: 1, Code: 4, Offender: netip.MustParseAddr("::1")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.network, func(t *testing.T) {
+			dst := closedUDPPort(t, tt.network, tt.ip)
+			d := net.Dialer{Control: RecvErr}
+			conn, err := d.Dial(tt.network, dst.String())
+			require.NoError(t, err)
+			defer func() { _ = conn.Close() }()
+			raw, err := conn.(syscall.Conn).SyscallConn()
+			require.NoError(t, err)
+
+			_, found, err := ReadICMPError(raw)
+			require.NoError(t, err)
+			assert.False(t, found)
+
+			_, err = conn.Write([]byte("ping"))
+			require.NoError(t, err)
+			require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+			_, err = conn.Read(make([]byte, 16))
+			require.ErrorIs(t, err, syscall.ECONNREFUSED)
+
+			icmpErr, found, err := ReadICMPError(raw)
+			require.NoError(t, err)
+			require.True(t, found)
+			assert.Equal(t, tt.want, icmpErr)
+		})
+	}
+}
+
+func TestParseICMPErrorIgnoresOtherMessages(t *testing.T) {
+	_, ok := parseICMPError(nil)
+	assert.False(t, ok)
+	_, ok = parseICMPError([]byte{1, 2, 3})
+	assert.False(t, ok)
+	assert.False(t, offenderAddr([]byte{0}).IsValid())
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package sockopt
+
+import "syscall"
+
+// RecvErr is not supported on this platform
+func RecvErr(network, address string, c syscall.RawConn) error {
+	return ErrUnsupported
+}
+
+// ReadICMPError is not supported on this platform
+func ReadICMPError(c syscall.RawConn) (ICMPError, bool, error) {
+	return ICMPError{}, false, ErrUnsupported
+}
@@ -0,0 +1,10 @@
+// Package sockopt provides socket option helpers for listeners and dialers.
+package sockopt
+
+import "errors"
+
+// ErrUnsupported is returned when a socket option is not available on the current platform
+var ErrUnsupported = errors.New("socket option not supported on this platform")
+
+// ErrDataPending is returned by CheckIdle if data arrived on an idle connection
+var ErrDataPending = errors.New("unexpected data pending on idle connection")
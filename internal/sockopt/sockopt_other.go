@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd
+
+package sockopt
+
+import "syscall"
+
+// ReusePort is not supported on this platform
+func ReusePort(network, address string, c syscall.RawConn) error {
+	return ErrUnsupported
+}
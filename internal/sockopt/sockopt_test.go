@@ -0,0 +1,83 @@
+package sockopt
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReusePort(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" && runtime.GOOS != "freebsd" {
+		t.Skip("SO_REUSEPORT not supported on this platform")
+	}
+
+	lc := net.ListenConfig{Control: ReusePort}
+	first, err := lc.ListenPacket(context.Background(), "udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = first.Close() }()
+
+	// A second socket can bind the very same port
+	second, err := lc.ListenPacket(context.Background(), "udp", first.LocalAddr().String())
+	require.NoError(t, err)
+	defer func() { _ = second.Close() }()
+
+	assert.Equal(t, first.LocalAddr().String(), second.LocalAddr().String())
+}
+
+func TestFreeBind(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("IP_FREEBIND not supported on this platform")
+	}
+
+	// 192.0.2.1 (TEST-NET-1) is not configured locally, so binding it requires IP_FREEBIND
+	lc := net.ListenConfig{Control: FreeBind}
+	conn, err := lc.ListenPacket(context.Background(), "udp4", "192.0.2.1:0")
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	assert.Equal(t, "192.0.2.1", conn.LocalAddr().(*net.UDPAddr).IP.String())
+}
+
+// bindToDevice returns a dialer control function binding sockets to iface
+func bindToDevice(iface string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		return BindToDevice(c, iface)
+	}
+}
+
+func TestBindToDevice(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("SO_BINDTODEVICE not supported on this platform")
+	}
+
+	d := net.Dialer{Control: bindToDevice("lo")}
+	conn, err := d.Dial("udp4", "127.0.0.1:9")
+	if err != nil {
+		t.Skipf("binding to lo not permitted: %v", err)
+	}
+	_ = conn.Close()
+
+	d = net.Dialer{Control: bindToDevice("does-not-exist0")}
+	_, err = d.Dial("udp4", "127.0.0.1:9")
+	assert.Error(t, err)
+}
+
+func TestKeepAliveSettings(t *testing.T) {
+	period, cfg := KeepAlive{}.Settings()
+	assert.Equal(t, time.Duration(0), period)
+	assert.Equal(t, net.KeepAliveConfig{Enable: true}, cfg)
+
+	period, cfg = KeepAlive{Idle: time.Minute, Interval: 5 * time.Second, Count: 3}.Settings()
+	assert.Equal(t, time.Duration(0), period)
+	assert.Equal(t, net.KeepAliveConfig{Enable: true, Idle: time.Minute, Interval: 5 * time.Second, Count: 3}, cfg)
+
+	period, cfg = KeepAlive{Disabled: true, Idle: time.Minute}.Settings()
+	assert.Equal(t, time.Duration(-1), period)
+	assert.False(t, cfg.Enable)
+}
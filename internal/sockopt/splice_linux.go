@@ -0,0 +1,87 @@
+package sockopt
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// spliceMaxChunk is the most data moved through the pipe at once, the default capacity of a pipe
+const spliceMaxChunk = 64 << 10
+
+// Splicer echoes the data a TCP connection receives back to it through a pipe with splice(2), so the data
+// is never copied to user space
+type Splicer struct {
+	conn syscall.RawConn
+	// r and w are the read and write ends of the pipe
+	r, w int
+	// pending is the number of bytes received into the pipe and not sent yet
+	pending int
+}
+
+// NewSplicer creates a splicer of a connection. It is closed with Close.
+func NewSplicer(conn syscall.Conn) (*Splicer, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	var p [2]int
+	if err := unix.Pipe2(p[:], unix.O_CLOEXEC|unix.O_NONBLOCK); err != nil {
+		return nil, os.NewSyscallError("pipe2", err)
+	}
+	return &Splicer{conn: raw, r: p[0], w: p[1]}, nil
+}
+
+// Receive waits for data on the connection and moves up to max bytes of it into the pipe, the most the pipe
+// takes if max is larger. It returns the number of bytes received, and io.EOF once the peer closed the
+// connection. The data is sent back with Send before the next Receive.
+func (s *Splicer) Receive(max int) (int, error) {
+	max = min(max, spliceMaxChunk-s.pending)
+	var n int64
+	var opErr error
+	err := s.conn.Read(func(fd uintptr) bool {
+		n, opErr = unix.Splice(int(fd), nil, s.w, nil, max, unix.SPLICE_F_MOVE|unix.SPLICE_F_NONBLOCK)
+		return !errors.Is(opErr, unix.EAGAIN)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if opErr != nil {
+		return 0, os.NewSyscallError("splice", opErr)
+	}
+	if n == 0 {
+		return 0, io.EOF
+	}
+	s.pending += int(n)
+	return int(n), nil
+}
+
+// Send writes the data received into the pipe back to the connection and returns the number of bytes sent
+func (s *Splicer) Send() (int, error) {
+	var sent int
+	for s.pending > 0 {
+		var n int64
+		var opErr error
+		err := s.conn.Write(func(fd uintptr) bool {
+			n, opErr = unix.Splice(s.r, nil, int(fd), nil, s.pending, unix.SPLICE_F_MOVE|unix.SPLICE_F_NONBLOCK)
+			return !errors.Is(opErr, unix.EAGAIN)
+		})
+		if err != nil {
+			return sent, err
+		}
+		if opErr != nil {
+			return sent, os.NewSyscallError("splice", opErr)
+		}
+		sent += int(n)
+		s.pending -= int(n)
+	}
+	return sent, nil
+}
+
+// Close closes the pipe, the connection is left open
+func (s *Splicer) Close() error {
+	return errors.Join(unix.Close(s.r), unix.Close(s.w))
+}
@@ -0,0 +1,68 @@
+package sockopt
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplicerEcho(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+
+	type result struct {
+		echoed int
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		s, err := NewSplicer(conn.(syscall.Conn))
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer func() { _ = s.Close() }()
+		var total int
+		for {
+			if _, err := s.Receive(1024); err != nil {
+				done <- result{echoed: total, err: err}
+				return
+			}
+			n, err := s.Send()
+			total += n
+			if err != nil {
+				done <- result{echoed: total, err: err}
+				return
+			}
+		}
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+	payload := bytes.Repeat([]byte("0123456789"), 10000)
+	go func() { _, _ = conn.Write(payload) }()
+	got := make([]byte, len(payload))
+	_, err = io.ReadFull(conn, got)
+	require.NoError(t, err)
+	assert.Equal(t, payload, got)
+
+	require.NoError(t, conn.(*net.TCPConn).CloseWrite())
+	r := <-done
+	assert.ErrorIs(t, r.err, io.EOF)
+	assert.Equal(t, len(payload), r.echoed)
+	_ = conn.Close()
+}
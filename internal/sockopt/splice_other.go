@@ -0,0 +1,28 @@
+//go:build !linux
+
+package sockopt
+
+import "syscall"
+
+// Splicer is not supported on this platform
+type Splicer struct{}
+
+// NewSplicer is not supported on this platform
+func NewSplicer(conn syscall.Conn) (*Splicer, error) {
+	return nil, ErrUnsupported
+}
+
+// Receive is not supported on this platform
+func (s *Splicer) Receive(max int) (int, error) {
+	return 0, ErrUnsupported
+}
+
+// Send is not supported on this platform
+func (s *Splicer) Send() (int, error) {
+	return 0, ErrUnsupported
+}
+
+// Close is not supported on this platform
+func (s *Splicer) Close() error {
+	return ErrUnsupported
+}
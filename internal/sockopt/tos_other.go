@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd
+
+package sockopt
+
+import "syscall"
+
+// SetTOS is not supported on this platform
+func SetTOS(network string, c syscall.RawConn, tos int) error {
+	return ErrUnsupported
+}
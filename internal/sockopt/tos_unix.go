@@ -0,0 +1,27 @@
+//go:build linux || darwin || freebsd
+
+package sockopt
+
+import (
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// SetTOS sets the IPv4 TOS byte (the IPv6 traffic class for IPv6 sockets) of the socket behind c. The
+// DSCP occupies the upper six bits, so a DSCP value is passed as dscp<<2.
+func SetTOS(network string, c syscall.RawConn, tos int) error {
+	level, opt := unix.IPPROTO_IP, unix.IP_TOS
+	if strings.HasSuffix(network, "6") {
+		level, opt = unix.IPPROTO_IPV6, unix.IPV6_TCLASS
+	}
+	var opErr error
+	err := c.Control(func(fd uintptr) {
+		opErr = unix.SetsockoptInt(int(fd), level, opt, tos)
+	})
+	if err != nil {
+		return err
+	}
+	return opErr
+}
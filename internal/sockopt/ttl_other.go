@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd
+
+package sockopt
+
+import "syscall"
+
+// SetTTL is not supported on this platform
+func SetTTL(network string, c syscall.RawConn, ttl int) error {
+	return ErrUnsupported
+}
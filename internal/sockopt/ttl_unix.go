@@ -0,0 +1,26 @@
+//go:build linux || darwin || freebsd
+
+package sockopt
+
+import (
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// SetTTL sets the IPv4 TTL (the IPv6 unicast hop limit for IPv6 sockets) of the socket behind c
+func SetTTL(network string, c syscall.RawConn, ttl int) error {
+	level, opt := unix.IPPROTO_IP, unix.IP_TTL
+	if strings.HasSuffix(network, "6") {
+		level, opt = unix.IPPROTO_IPV6, unix.IPV6_UNICAST_HOPS
+	}
+	var opErr error
+	err := c.Control(func(fd uintptr) {
+		opErr = unix.SetsockoptInt(int(fd), level, opt, ttl)
+	})
+	if err != nil {
+		return err
+	}
+	return opErr
+}
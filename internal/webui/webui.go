@@ -0,0 +1,115 @@
+// Package webui assembles the web UI pages of the client and the server. The pages poll a stats endpoint
+// and chart its values without any external assets, so they also work without internet access.
+package webui
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+)
+
+// style is the CSS shared by all pages
+const style = `body { font-family: sans-serif; margin: 2em; color: #222; }
+#status span { margin-right: 2em; }
+.charts { display: grid; grid-template-columns: repeat(auto-fill, minmax(420px, 1fr)); gap: 1.5em; margin-top: 1.5em; }
+.chart h2 { font-size: 1em; margin: 0 0 .3em; }
+svg { width: 100%; height: 160px; border: 1px solid #ccc; background: #fafafa; }
+svg text { font-size: 11px; fill: #555; }
+.legend span { margin-right: 1em; font-size: .9em; }
+`
+
+// charts is the JavaScript shared by all pages. push adds a value to a series of a chart, draw redraws a
+// chart with the last maxPoints values of its series and total sums counts by protocol and port.
+const charts = `var maxPoints = 300;
+var colors = ["#1f77b4", "#ff7f0e", "#2ca02c", "#d62728"];
+var series = {};
+
+function total(m) {
+  var sum = 0;
+  for (var protocol in m || {}) {
+    for (var port in m[protocol]) {
+      sum += m[protocol][port];
+    }
+  }
+  return sum;
+}
+
+function push(chart, name, value) {
+  series[chart] = series[chart] || {};
+  var values = series[chart][name] = series[chart][name] || [];
+  values.push(value);
+  if (values.length > maxPoints) {
+    values.shift();
+  }
+}
+
+function draw(chart) {
+  var svg = document.getElementById(chart);
+  var width = svg.clientWidth || 420, height = svg.clientHeight || 160;
+  var max = 0;
+  for (var name in series[chart]) {
+    series[chart][name].forEach(function(v) { max = Math.max(max, v); });
+  }
+  max = max > 0 ? max * 1.1 : 1;
+  var html = "", legend = "", i = 0;
+  for (var name in series[chart]) {
+    var values = series[chart][name];
+    var points = values.map(function(v, j) {
+      var x = width - (values.length - 1 - j) * width / (maxPoints - 1);
+      return x.toFixed(1) + "," + (height - v / max * (height - 10)).toFixed(1);
+    }).join(" ");
+    html += '<polyline fill="none" stroke-width="1.5" stroke="' + colors[i % colors.length] + '" points="' + points + '"/>';
+    legend += '<span style="color:' + colors[i % colors.length] + '">&#9632; ' + name + ": " + values[values.length - 1].toFixed(2) + "</span>";
+    i++;
+  }
+  html += '<text x="4" y="12">' + max.toPrecision(3) + "</text>";
+  svg.innerHTML = html;
+  document.getElementById(chart + "Legend").innerHTML = legend;
+}
+`
+
+// Page returns a page with the given title. The body follows the title and a status line with the id
+// status, extraStyle adds CSS rules and script runs after the chart functions.
+func Page(title, extraStyle, body, script string) string {
+	title = html.EscapeString(title)
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+%s%s</style>
+</head>
+<body>
+<h1>%s</h1>
+<div id="status">connecting...</div>
+%s<script>
+%s
+%s</script>
+</body>
+</html>
+`, title, style, extraStyle, title, body, charts, script)
+}
+
+// Chart returns a chart with the given id and heading, drawn by draw(id)
+func Chart(id, heading string) string {
+	return fmt.Sprintf(`<div class="chart"><h2>%s</h2><svg id="%s"></svg><div class="legend" id="%sLegend"></div></div>
+`, html.EscapeString(heading), id, id)
+}
+
+// Charts returns the grid holding the given charts
+func Charts(charts ...string) string {
+	body := "<div class=\"charts\">\n"
+	for _, c := range charts {
+		body += c
+	}
+	return body + "</div>\n"
+}
+
+// Handler serves a page
+func Handler(page string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(page))
+	})
+}
@@ -0,0 +1,30 @@
+package webui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPage(t *testing.T) {
+	page := Page("Flows <test>", ".extra { color: red; }\n", Charts(Chart("rateChart", "Rate & more")), "poll();\n")
+	assert.True(t, strings.HasPrefix(page, "<!DOCTYPE html>"))
+	assert.Contains(t, page, "<title>Flows &lt;test&gt;</title>")
+	assert.Contains(t, page, "<h1>Flows &lt;test&gt;</h1>")
+	assert.Contains(t, page, ".extra { color: red; }\n</style>")
+	assert.Contains(t, page, `<div id="status">connecting...</div>`)
+	assert.Contains(t, page, `<div class="charts">`)
+	assert.Contains(t, page, `<h2>Rate &amp; more</h2><svg id="rateChart"></svg><div class="legend" id="rateChartLegend"></div>`)
+	// The page script runs after the chart functions it uses
+	assert.Less(t, strings.Index(page, "function draw(chart)"), strings.Index(page, "poll();"))
+}
+
+func TestHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler("<p>page</p>").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ui", nil))
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "<p>page</p>", rec.Body.String())
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/echoserver"
+	"github.com/PhilipSchmid/flow-generator-app/internal/health"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+)
+
+// roleAgent also serves the listeners of a server next to the flow generation, so a single process per
+// node can take part in mesh and all-to-all tests
+const roleAgent = "agent"
+
+// agentServer is the server side of the agent role. Its listeners record their traffic in the client's
+// metrics collector, and the health port serves the run status next to the health and readiness checks.
+type agentServer struct {
+	server      *echoserver.Server
+	health      *health.Checker
+	matrix      *peerMatrix
+	stopMonitor context.CancelFunc
+}
+
+// startAgentServer starts the listeners and the health server of the agent role
+func startAgentServer(clientCfg *config.ClientConfig, tracker *runTracker) (*agentServer, error) {
+	cfg, err := config.LoadAgentServerConfig(clientCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	monitorCtx, stopMonitor := context.WithCancel(context.Background())
+	a := &agentServer{stopMonitor: stopMonitor}
+	a.health = echoserver.NewHealthChecker(monitorCtx, cfg, mc)
+	a.health.Handle(runStatusPath, tracker)
+	if clientCfg.Peers != "" {
+		a.matrix = newPeerMatrix(clientCfg, cfg.HealthPort)
+		a.health.Handle(peerMatrixPath, a.matrix)
+	}
+	if err := a.health.Start(cfg.HealthPort); err != nil {
+		stopMonitor()
+		return nil, fmt.Errorf("failed to start health check server: %w", err)
+	}
+
+	a.server = echoserver.New(cfg, mc)
+	if err := a.server.Start(); err != nil {
+		a.stop()
+		return nil, fmt.Errorf("failed to start servers: %w", err)
+	}
+	a.health.SetReady(true)
+	if a.matrix != nil {
+		go a.matrix.run(monitorCtx)
+		logging.Logger.Infof("Probing peers %v every %v for the peer latency matrix as %s", a.matrix.entries, a.matrix.interval, a.matrix.name)
+	}
+	logging.Logger.Infof("Agent serving %d listeners, health checks and run status on port %s", len(a.server.Listeners()), cfg.HealthPort)
+	return a, nil
+}
+
+// reload applies the listener changes of a reloaded configuration
+func (a *agentServer) reload(clientCfg *config.ClientConfig) {
+	cfg, err := config.LoadAgentServerConfig(clientCfg)
+	if err != nil {
+		logging.Logger.Errorf("Keeping current listeners, reload failed: %v", err)
+		return
+	}
+	a.server.Reload(cfg)
+	logging.Logger.Infof("Agent listeners reloaded, serving %d listeners", len(a.server.Listeners()))
+}
+
+// stop closes the listeners and the health server
+func (a *agentServer) stop() {
+	a.health.SetReady(false)
+	if err := a.server.Stop(); err != nil {
+		logging.Logger.Errorf("Error stopping servers: %v", err)
+	}
+	if err := a.health.Stop(); err != nil {
+		logging.Logger.Errorf("Error stopping health check server: %v", err)
+	}
+	a.stopMonitor()
+}
+
+// serveUntilTerminated keeps serving peers after flow generation has ended until the process is terminated
+func (a *agentServer) serveUntilTerminated() {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	logging.Logger.Info("Flow generation finished, serving peers until terminated")
+	sig := <-sigChan
+	logging.Logger.Infof("Received signal: %v. Shutting down...", sig)
+	a.stop()
+}
+
+// writeAgentPlan describes the listeners and endpoints the agent role would serve
+func writeAgentPlan(w io.Writer, clientCfg *config.ClientConfig, cfg *config.ServerConfig) error {
+	var b strings.Builder
+	b.WriteString("Agent plan:\n")
+	listeners := echoserver.ListenerModes(cfg)
+	for _, key := range echoserver.SortedListenerKeys(listeners) {
+		fmt.Fprintf(&b, "  %s/%d: %s\n", strings.ToLower(key.ServerType), key.Port, listeners[key])
+	}
+	fmt.Fprintf(&b, "  :%s/health, /ready, %s\n", cfg.HealthPort, runStatusPath)
+	if clientCfg.Peers != "" {
+		fmt.Fprintf(&b, "  :%s%s: probing %s on udp/%d every %gs\n", cfg.HealthPort, peerMatrixPath, clientCfg.Peers, clientCfg.PeerProbePort, clientCfg.PeerProbeInterval)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
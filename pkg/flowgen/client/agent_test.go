@@ -0,0 +1,77 @@
+package client
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentServer(t *testing.T) {
+	logging.InitLogger("json", "error")
+	viper.Reset()
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	viper.Set("tcp_ports_server", "18195")
+	viper.Set("health_port", "18196")
+
+	oldMc := mc
+	mc = metrics.NewMetricsCollector()
+	defer func() { mc = oldMc }()
+
+	clientCfg := &config.ClientConfig{CommonConfig: config.CommonConfig{LogLevel: "error", LogFormat: "json"}}
+	flows := uint64(0)
+	a, err := startAgentServer(clientCfg, newRunTracker(clientCfg, time.Now(), &flows))
+	require.NoError(t, err)
+	defer a.stop()
+
+	// The listeners echo for peers
+	var conn net.Conn
+	require.Eventually(t, func() bool {
+		conn, err = net.Dial("tcp", "127.0.0.1:18195")
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+	_ = conn.Close()
+
+	// The health port serves readiness and the run status of the client side
+	for _, path := range []string{"/ready", runStatusPath} {
+		var resp *http.Response
+		require.Eventually(t, func() bool {
+			resp, err = http.Get("http://127.0.0.1:18196" + path)
+			return err == nil
+		}, 2*time.Second, 10*time.Millisecond)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, path)
+	}
+
+	// Listener changes are picked up on reload
+	viper.Set("tcp_ports_server", "18195,18197")
+	a.reload(clientCfg)
+	assert.Len(t, a.server.Listeners(), 2)
+}
+
+func TestAgentDryRun(t *testing.T) {
+	out, err := executeRootCmd(t, "--dry-run", "--role", "agent", "--tcp_ports", "8080", "--tcp_ports_server", "9000", "--udp_ports_server", "53")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Agent plan:\n  tcp/9000: echo\n  udp/53: echo\n")
+	assert.Contains(t, out, ":8082/health, /ready, /run")
+
+	out, err = executeRootCmd(t, "--dry-run", "--tcp_ports", "8080")
+	require.NoError(t, err)
+	assert.NotContains(t, out, "Agent plan:")
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/backpressure"
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+)
+
+// backpressureWatcher polls the server's backpressure endpoint and reduces the flow rate while the server is overloaded
+type backpressureWatcher struct {
+	url       string
+	interval  time.Duration
+	factor    float64
+	client    *http.Client
+	throttled bool
+}
+
+// newBackpressureWatcher creates a watcher from the client configuration, or nil if no backpressure URL is set
+func newBackpressureWatcher(c *config.ClientConfig) *backpressureWatcher {
+	if c.BackpressureURL == "" {
+		return nil
+	}
+	interval := time.Duration(c.BackpressurePollInterval * float64(time.Second))
+	return &backpressureWatcher{
+		url:      c.BackpressureURL,
+		interval: interval,
+		factor:   c.BackpressureFactor,
+		client:   &http.Client{Timeout: interval},
+	}
+}
+
+// run polls the server until the context is cancelled and sends the rate multiplier to apply whenever it changes
+func (w *backpressureWatcher) run(ctx context.Context, multipliers chan<- float64) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			status, err := w.poll(ctx)
+			if err != nil {
+				logging.Logger.Debugf("Failed to poll backpressure status: %v", err)
+				continue
+			}
+			if multiplier, changed := w.observe(status); changed {
+				select {
+				case multipliers <- multiplier:
+				case <-ctx.Done():
+					return
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// poll fetches the current backpressure status from the server
+func (w *backpressureWatcher) poll(ctx context.Context) (backpressure.Status, error) {
+	var status backpressure.Status
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.url, nil)
+	if err != nil {
+		return status, err
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return status, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return status, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return status, fmt.Errorf("failed to decode backpressure status: %w", err)
+	}
+	return status, nil
+}
+
+// observe records the server's status and returns the rate multiplier to apply, and whether it changed
+func (w *backpressureWatcher) observe(status backpressure.Status) (float64, bool) {
+	if status.Overloaded == w.throttled {
+		return w.multiplier(), false
+	}
+	w.throttled = status.Overloaded
+	mc.SetBackpressureActive(w.throttled)
+	if w.throttled {
+		mc.IncBackpressureSignals()
+		logging.Logger.Warnf("Server signaled backpressure (%s), reducing flow rate to %.0f%%", status.Reason, w.factor*100)
+	} else {
+		logging.Logger.Info("Server backpressure cleared, restoring flow rate")
+	}
+	return w.multiplier(), true
+}
+
+// multiplier returns the factor applied to the configured flow rate in the current state
+func (w *backpressureWatcher) multiplier() float64 {
+	if w.throttled {
+		return w.factor
+	}
+	return 1
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/backpressure"
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBackpressureWatcher(t *testing.T) {
+	assert.Nil(t, newBackpressureWatcher(&config.ClientConfig{}))
+
+	w := newBackpressureWatcher(&config.ClientConfig{
+		BackpressureURL:          "http://localhost:8082/backpressure",
+		BackpressurePollInterval: 0.5,
+		BackpressureFactor:       0.25,
+	})
+	require.NotNil(t, w)
+	assert.Equal(t, 500*time.Millisecond, w.interval)
+	assert.Equal(t, 0.25, w.factor)
+}
+
+func TestBackpressureWatcherRun(t *testing.T) {
+	logging.InitLogger("json", "error")
+	oldMc := mc
+	mc = metrics.NewMetricsCollector()
+	defer func() { mc = oldMc }()
+
+	var overloaded atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(backpressure.Status{Overloaded: overloaded.Load(), Reason: "test"})
+	}))
+	defer srv.Close()
+
+	w := newBackpressureWatcher(&config.ClientConfig{
+		BackpressureURL:          srv.URL,
+		BackpressurePollInterval: 0.01,
+		BackpressureFactor:       0.5,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	multipliers := make(chan float64)
+	go w.run(ctx, multipliers)
+
+	overloaded.Store(true)
+	select {
+	case m := <-multipliers:
+		assert.Equal(t, 0.5, m)
+	case <-time.After(1 * time.Second):
+		t.Fatal("Watcher did not react to backpressure signal")
+	}
+
+	overloaded.Store(false)
+	select {
+	case m := <-multipliers:
+		assert.Equal(t, 1.0, m)
+	case <-time.After(1 * time.Second):
+		t.Fatal("Watcher did not react to cleared backpressure signal")
+	}
+}
+
+func TestBackpressureWatcherPollError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	w := newBackpressureWatcher(&config.ClientConfig{
+		BackpressureURL:          srv.URL,
+		BackpressurePollInterval: 1,
+		BackpressureFactor:       0.5,
+	})
+	_, err := w.poll(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"io"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/bench"
+	"github.com/PhilipSchmid/flow-generator-app/internal/flowheader"
+
+	"github.com/spf13/cobra"
+)
+
+// newBenchCmd builds the bench subcommand, which checks the performance of the tool itself on the host
+// before its measurements are trusted
+func newBenchCmd() *cobra.Command {
+	var filter string
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Run micro-benchmarks of the metrics, handlers and payload generation on this host",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBench(cmd.OutOrStdout(), filter)
+		},
+	}
+	cmd.Flags().StringVar(&filter, "filter", "", "Only run the benchmarks whose name matches this regular expression")
+	return cmd
+}
+
+// runBench runs the standard benchmarks and the client ones matching filter and prints the results
+func runBench(w io.Writer, filter string) error {
+	benchmarks := append(bench.Standard(), bench.Benchmark{Name: "payload/generate", F: benchmarkPayload})
+	results, err := bench.Run(benchmarks, filter)
+	if err != nil {
+		return err
+	}
+	return bench.Print(w, results)
+}
+
+// benchPayloadSeed seeds the payload sizes of the payload benchmark, so that every run measures the same
+// sequence of sizes whatever the --seed of the client
+const benchPayloadSeed = 1
+
+// benchmarkPayload measures preparing the payload of a flow with a random size and a flow header, as
+// generateFlow does, and stamping the sequence number of a request
+func benchmarkPayload(b *testing.B) error {
+	// #nosec G404 - math/rand is sufficient for benchmark payload sizes
+	src := rand.New(rand.NewPCG(benchPayloadSeed, benchPayloadSeed))
+	h := &flowHeaders{runID: 1}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		payload := h.payload(payloadCache[:64+src.IntN(1400-64+1)], uint64(i))
+		flowheader.SetSeq(payload, 1)
+	}
+	return nil
+}
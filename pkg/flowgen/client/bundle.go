@@ -0,0 +1,228 @@
+package client
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/version"
+)
+
+// Names of the files in the artifact bundle
+const (
+	bundleManifest = "manifest.json"
+	bundleConfig   = "config.txt"
+	bundleReport   = "report.json"
+	bundleFlowLog  = "flows.ndjson"
+	bundleLogs     = "client.log"
+)
+
+// bundleManifestFile describes the run an artifact bundle belongs to
+type bundleManifestFile struct {
+	Created  string            `json:"created"`
+	Version  string            `json:"version"`
+	Scenario string            `json:"scenario"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Seed     uint64            `json:"seed"`
+	Files    []string          `json:"files"`
+}
+
+// bundleFile is a file of the artifact bundle, taken from data or, if set, copied from path
+type bundleFile struct {
+	name string
+	data []byte
+	path string
+}
+
+// artifactBundle captures the artifacts of a run while it runs and packs them into one archive when it
+// ends. Logs are captured in a temporary directory, which also holds the flow log if none was configured.
+type artifactBundle struct {
+	path    string
+	dir     string
+	logs    *os.File
+	flowLog string
+}
+
+// newArtifactBundle starts capturing the artifacts of the run for the archive configured with
+// artifact_bundle, or returns nil if no bundle is written
+func newArtifactBundle(c *config.ClientConfig) (*artifactBundle, error) {
+	if c.ArtifactBundle == "" {
+		return nil, nil
+	}
+	dir, err := os.MkdirTemp("", "flow-generator-bundle-")
+	if err != nil {
+		return nil, err
+	}
+	logs, err := os.Create(filepath.Join(dir, bundleLogs))
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	b := &artifactBundle{path: c.ArtifactBundle, dir: dir, logs: logs, flowLog: c.FlowLogFile}
+	// Flows streamed to stdout cannot be read back, so they are not bundled
+	switch c.FlowLogFile {
+	case "":
+		b.flowLog = filepath.Join(dir, bundleFlowLog)
+	case flowLogStdout:
+		b.flowLog = ""
+	}
+	logging.Tee(logs)
+	return b, nil
+}
+
+// flowLogPath returns where the flow log is written, the configured one or one captured for the bundle
+func (b *artifactBundle) flowLogPath(configured string) string {
+	if b == nil || configured != "" {
+		return configured
+	}
+	return b.flowLog
+}
+
+// write packs the artifacts of the finished run into the archive and removes the captured files. It must
+// be called after the flow log is closed.
+func (b *artifactBundle) write(results runResults, seed uint64, now time.Time) error {
+	defer func() { _ = os.RemoveAll(b.dir) }()
+	_ = b.logs.Sync()
+
+	var settings bytes.Buffer
+	if err := config.WriteEffectiveSettings(&settings); err != nil {
+		return err
+	}
+	report, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	files := []bundleFile{
+		{name: bundleConfig, data: settings.Bytes()},
+		{name: bundleReport, data: append(report, '\n')},
+	}
+	// Reports in other formats are bundled as written, next to the JSON one
+	if cfg.OutputFile != "" && cfg.OutputFormat != "json" && fileExists(cfg.OutputFile) {
+		files = append(files, bundleFile{name: filepath.Base(cfg.OutputFile), path: cfg.OutputFile})
+	}
+	if b.flowLog != "" {
+		files = append(files, bundleFile{name: bundleFlowLog, path: b.flowLog})
+	}
+	files = append(files, bundleFile{name: bundleLogs, path: b.logs.Name()})
+
+	manifest := bundleManifestFile{
+		Created:  now.UTC().Format(time.RFC3339Nano),
+		Version:  version.Short(),
+		Scenario: results.Run.Scenario,
+		Metadata: results.Run.Metadata,
+		Seed:     seed,
+		Files:    []string{bundleManifest},
+	}
+	for _, f := range files {
+		manifest.Files = append(manifest.Files, f.name)
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	files = append([]bundleFile{{name: bundleManifest, data: append(data, '\n')}}, files...)
+	return writeArchive(b.path, config.ArchiveFormat(b.path), files, now)
+}
+
+// fileExists reports whether a file was written at path
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// writeArchive writes the files to a gzipped tar or zip archive at path
+func writeArchive(path, format string, files []bundleFile, modTime time.Time) error {
+	// #nosec G304 - the bundle path is chosen by the operator
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if format == config.ArchiveZip {
+		err = writeZip(out, files, modTime)
+	} else {
+		err = writeTarGz(out, files, modTime)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeZip writes the files as a zip archive
+func writeZip(w io.Writer, files []bundleFile, modTime time.Time) error {
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: modTime})
+		if err != nil {
+			return err
+		}
+		if err := f.copyTo(fw, -1); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeTarGz writes the files as a gzipped tar archive
+func writeTarGz(w io.Writer, files []bundleFile, modTime time.Time) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	for _, f := range files {
+		size, err := f.size()
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0o644, Size: size, ModTime: modTime}); err != nil {
+			return err
+		}
+		if err := f.copyTo(tw, size); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// size returns the number of bytes the file adds to the archive
+func (f bundleFile) size() (int64, error) {
+	if f.path == "" {
+		return int64(len(f.data)), nil
+	}
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", f.name, err)
+	}
+	return info.Size(), nil
+}
+
+// copyTo writes the content of the file to w, at most limit bytes unless limit is negative. The logs may
+// still grow while they are bundled, while a tar entry must match the size in its header.
+func (f bundleFile) copyTo(w io.Writer, limit int64) error {
+	if f.path == "" {
+		_, err := w.Write(f.data)
+		return err
+	}
+	// #nosec G304 - bundled files are the run's own output files
+	in, err := os.Open(f.path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.name, err)
+	}
+	defer func() { _ = in.Close() }()
+	if limit >= 0 {
+		_, err = io.CopyN(w, in, limit)
+	} else {
+		_, err = io.Copy(w, in)
+	}
+	return err
+}
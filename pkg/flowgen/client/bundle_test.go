@@ -0,0 +1,118 @@
+package client
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readBundle returns the files of an artifact bundle by name
+func readBundle(t *testing.T, path string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	if config.ArchiveFormat(path) == config.ArchiveZip {
+		zr, err := zip.OpenReader(path)
+		require.NoError(t, err)
+		defer func() { _ = zr.Close() }()
+		for _, f := range zr.File {
+			r, err := f.Open()
+			require.NoError(t, err)
+			data, err := io.ReadAll(r)
+			require.NoError(t, err)
+			files[f.Name] = string(data)
+		}
+		return files
+	}
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+	gr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[hdr.Name] = string(data)
+	}
+}
+
+func TestNewArtifactBundleDisabled(t *testing.T) {
+	b, err := newArtifactBundle(&config.ClientConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, b)
+	assert.Equal(t, "flows.ndjson", b.flowLogPath("flows.ndjson"))
+	assert.Equal(t, "", b.flowLogPath(""))
+}
+
+func TestArtifactBundle(t *testing.T) {
+	for _, name := range []string{"run.tgz", "run.zip"} {
+		t.Run(name, func(t *testing.T) {
+			logging.InitLogger("json", "info")
+			defer logging.InitLogger("json", "error")
+			dir := t.TempDir()
+			oldCfg := cfg
+			defer func() { cfg = oldCfg }()
+			cfg = &config.ClientConfig{
+				ArtifactBundle: filepath.Join(dir, name),
+				OutputFile:     filepath.Join(dir, "report.csv"),
+				OutputFormat:   "csv",
+			}
+			require.NoError(t, writeResults(cfg.OutputFile, cfg.OutputFormat, testRunResults()))
+
+			b, err := newArtifactBundle(cfg)
+			require.NoError(t, err)
+			// Without a configured flow log, one is captured for the bundle
+			flowLogPath := b.flowLogPath("")
+			require.NotEmpty(t, flowLogPath)
+			require.NoError(t, os.WriteFile(flowLogPath, []byte(`{"flow_id":1}`+"\n"), 0o600))
+			logging.Logger.Info("captured for the bundle")
+
+			require.NoError(t, b.write(testRunResults(), 42, time.Now()))
+			files := readBundle(t, cfg.ArtifactBundle)
+
+			var manifest bundleManifestFile
+			require.NoError(t, json.Unmarshal([]byte(files[bundleManifest]), &manifest))
+			assert.Equal(t, uint64(42), manifest.Seed)
+			assert.Equal(t, "ci", manifest.Scenario)
+			assert.Equal(t, "netops", manifest.Metadata["owner"])
+			assert.Equal(t, []string{bundleManifest, bundleConfig, bundleReport, "report.csv", bundleFlowLog, bundleLogs}, manifest.Files)
+			assert.Len(t, files, len(manifest.Files))
+
+			var report runResults
+			require.NoError(t, json.Unmarshal([]byte(files[bundleReport]), &report))
+			assert.Equal(t, testRunResults(), report)
+			assert.Contains(t, files["report.csv"], "metric,protocol,port,value")
+			assert.Equal(t, `{"flow_id":1}`+"\n", files[bundleFlowLog])
+			assert.Contains(t, files[bundleLogs], "captured for the bundle")
+			assert.Contains(t, files[bundleConfig], "KEY")
+
+			// The captured files are removed once bundled
+			assert.NoDirExists(t, b.dir)
+		})
+	}
+}
+
+func TestArtifactBundleFlowLogToStdout(t *testing.T) {
+	logging.InitLogger("json", "error")
+	b, err := newArtifactBundle(&config.ClientConfig{ArtifactBundle: filepath.Join(t.TempDir(), "run.tgz"), FlowLogFile: flowLogStdout})
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(b.dir) }()
+	assert.Equal(t, flowLogStdout, b.flowLogPath(flowLogStdout))
+	assert.Empty(t, b.flowLog)
+}
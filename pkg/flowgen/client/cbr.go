@@ -0,0 +1,250 @@
+package client
+
+import (
+	"context"
+	"encoding/binary"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/bufpool"
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/flowheader"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+)
+
+// cbrHeaderSize is the length of the header of a constant bitrate datagram: the flow header carrying its
+// sequence number, followed by the time it was sent in nanoseconds since the flow started sending
+const cbrHeaderSize = flowheader.Size + 8
+
+// cbrPollInterval bounds how long the receiver of a constant bitrate flow waits for an echo before it checks
+// whether sending is over
+const cbrPollInterval = 100 * time.Millisecond
+
+// deadlineReceiver is implemented by datagram transports that can read up to a deadline of their own, without
+// affecting the response timeouts of their requests. Constant bitrate flows read echoes with it while
+// datagrams are still being sent, other transports are read with Recv and must allow Send and Recv to be
+// called concurrently.
+type deadlineReceiver interface {
+	RecvUntil(buf []byte, deadline time.Time) (int, error)
+}
+
+// constantBitrate paces the datagrams of UDP flows at the bitrate configured with udp_bitrate, like
+// iperf -u -b, instead of sending one per udp_interval
+type constantBitrate struct {
+	bitrate float64
+	// headers put the flow header carrying the sequence numbers into payloads without one, so echoes can be
+	// matched up even if flow_header is disabled
+	headers *flowHeaders
+}
+
+// newConstantBitrate returns the constant bitrate configured with udp_bitrate, or nil if datagrams are sent
+// per udp_interval
+func newConstantBitrate(c *config.ClientConfig) *constantBitrate {
+	if c.UDPBitrate == "" {
+		return nil
+	}
+	// The bitrate was checked when the configuration was validated
+	bitrate, _ := config.ParseBitrate(c.UDPBitrate)
+	return &constantBitrate{bitrate: bitrate, headers: &flowHeaders{runID: rand.Uint64()}}
+}
+
+// String describes the bitrate for the logs
+func (b *constantBitrate) String() string {
+	return config.FormatBitrate(b.bitrate)
+}
+
+// interval returns the time between two datagrams of the given size
+func (b *constantBitrate) interval(size int) time.Duration {
+	return time.Duration(float64(size*8) / b.bitrate * float64(time.Second))
+}
+
+// cbrStats are the loss, reordering and jitter statistics of the echoes of a constant bitrate flow
+type cbrStats struct {
+	received   uint64
+	outOfOrder uint64
+	// seen holds a bit per sequence number whose echo arrived, to tell duplicates apart
+	seen []uint64
+	// highest is the highest sequence number echoed, -1 before the first echo
+	highest int64
+	// jitter is the interarrival jitter as in RFC 3550, smoothed over the differences of the transit times
+	// of consecutive echoes
+	jitter      float64
+	lastTransit time.Duration
+}
+
+// newCBRStats returns the statistics of a flow before its first echo
+func newCBRStats() *cbrStats {
+	return &cbrStats{highest: -1}
+}
+
+// observe records the echo of the datagram with the given sequence number and transit time, the round trip
+// of the datagram. It reports false for duplicates, which are not counted.
+func (s *cbrStats) observe(seq uint32, transit time.Duration) bool {
+	word, bit := int(seq/64), uint64(1)<<(seq%64)
+	if word >= len(s.seen) {
+		s.seen = append(s.seen, make([]uint64, word-len(s.seen)+1)...)
+	}
+	if s.seen[word]&bit != 0 {
+		return false
+	}
+	s.seen[word] |= bit
+	if int64(seq) < s.highest {
+		s.outOfOrder++
+	} else {
+		s.highest = int64(seq)
+	}
+	if s.received > 0 {
+		d := (transit - s.lastTransit).Abs()
+		s.jitter += (float64(d) - s.jitter) / 16
+	}
+	s.lastTransit = transit
+	s.received++
+	return true
+}
+
+// lost returns the number of datagrams of the sent ones whose echo never arrived
+func (s *cbrStats) lost(sent uint64) uint64 {
+	if s.received >= sent {
+		return 0
+	}
+	return sent - s.received
+}
+
+// streamCBR sends fixed-size datagrams at the configured bitrate until ctx is done, while reading their
+// echoes concurrently. Echoes still in flight are awaited up to the response timeout of target, so
+// datagrams are only counted as lost once they could have been echoed.
+func (f *flowExchange) streamCBR(ctx context.Context, target string) {
+	name := protocolName(f.protocol)
+	payload := f.payload
+	if !f.header {
+		payload = cbr.headers.payload(payload, f.flowID)
+	}
+	datagram := make([]byte, max(len(payload), cbrHeaderSize))
+	copy(datagram, payload)
+	interval := cbr.interval(len(datagram))
+	logFlowDetail(f.flowID, f.protocol, f.sampled, "Sending %d byte datagrams at %s, one every %v", len(datagram), cbr, interval)
+
+	start := time.Now()
+	stats := newCBRStats()
+	var sent atomic.Uint64
+	var recvErr error
+	var rttSum time.Duration
+	var bytesReceived uint64
+	sending := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		bp := bufpool.Get(len(datagram))
+		defer bufpool.Put(bp)
+		buf := *bp
+		var drain time.Time
+		for {
+			deadline := time.Now().Add(cbrPollInterval)
+			select {
+			case <-sending:
+				if drain.IsZero() {
+					drain = time.Now().Add(responseTimeouts.timeout(target))
+				}
+				if stats.received >= sent.Load() || !time.Now().Before(drain) {
+					return
+				}
+				if drain.Before(deadline) {
+					deadline = drain
+				}
+			default:
+			}
+			n, err := f.recvUntil(buf, deadline)
+			if err != nil {
+				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+					continue
+				}
+				logFlowFailure(f.flowID, f.protocol, "Failed to read from %s connection: %v", name, err)
+				recordFlowError(f.protocol, f.port, err, flowOpRead)
+				recvErr = err
+				return
+			}
+			now := time.Now()
+			h, ok := flowheader.Parse(buf[:n])
+			if !ok || h.FlowID != f.flowID || n < cbrHeaderSize {
+				continue
+			}
+			sentAt := start.Add(time.Duration(binary.BigEndian.Uint64(buf[flowheader.Size:])))
+			rtt := now.Sub(sentAt)
+			if !stats.observe(h.Seq, rtt) {
+				continue
+			}
+			rttSum += rtt
+			bytesReceived += uint64(n)
+			mc.ObserveLatency(f.protocol, f.port, rtt)
+			mc.AddBytesReceived(f.protocol, f.port, n)
+			mc.AddWireBytesReceived(f.protocol, f.port, f.wireBytes(n))
+			recordLatency(now, rtt)
+			recordBytesReceived(now, n)
+		}
+	}()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for seq := uint32(0); ; seq++ {
+		// Datagrams are due on a fixed schedule, so a late send is made up for by the following ones
+		if wait := time.Until(start.Add(time.Duration(seq) * interval)); wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		flowheader.SetSeq(datagram, seq)
+		binary.BigEndian.PutUint64(datagram[flowheader.Size:], uint64(time.Since(start)))
+		nSent, err := f.transport.Send(datagram)
+		if err != nil {
+			logFlowFailure(f.flowID, f.protocol, "Failed to write to %s connection: %v", name, err)
+			recordFlowError(f.protocol, f.port, err, flowOpWrite)
+			f.fail(err)
+			break
+		}
+		f.requests++
+		f.bytesSent += uint64(nSent)
+		sent.Store(f.requests)
+		mc.IncRequestsSent(f.protocol, f.port)
+		mc.AddBytesSent(f.protocol, f.port, nSent)
+		mc.AddWireBytesSent(f.protocol, f.port, f.wireBytes(nSent))
+	}
+	elapsed := time.Since(start)
+	close(sending)
+	wg.Wait()
+
+	f.responses = stats.received
+	f.bytesReceived = bytesReceived
+	f.latency = rttSum
+	if recvErr != nil {
+		f.fail(recvErr)
+	}
+	lost := stats.lost(f.requests)
+	jitter := time.Duration(stats.jitter)
+	mc.ObserveCBRFlow(f.protocol, f.port, lost, stats.outOfOrder, jitter)
+	var lossPercent, bitrate float64
+	if f.requests > 0 {
+		lossPercent = float64(lost) / float64(f.requests) * 100
+	}
+	if elapsed > 0 {
+		bitrate = float64(f.bytesSent*8) / elapsed.Seconds()
+	}
+	logFlowSummary(f.flowID, f.protocol, f.sampled, "%s flow sent %d datagrams at %s: %d lost (%.2f%%), %d out of order, jitter %s",
+		name, f.requests, config.FormatBitrate(bitrate), lost, lossPercent, stats.outOfOrder, logging.CompactDuration(jitter))
+}
+
+// recvUntil reads the next echo up to the deadline if the transport supports it, or with Recv otherwise
+func (f *flowExchange) recvUntil(buf []byte, deadline time.Time) (int, error) {
+	if r, ok := f.transport.(deadlineReceiver); ok {
+		return r.RecvUntil(buf, deadline)
+	}
+	return f.transport.Recv(buf)
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/flowheader"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstantBitrate(t *testing.T) {
+	assert.Nil(t, newConstantBitrate(&config.ClientConfig{}))
+
+	b := newConstantBitrate(&config.ClientConfig{UDPBitrate: "10M"})
+	require.NotNil(t, b)
+	assert.Equal(t, "10 Mbit/s", b.String())
+	assert.Equal(t, 1000*time.Microsecond, b.interval(1250))
+}
+
+func TestCBRStats(t *testing.T) {
+	s := newCBRStats()
+	for _, echo := range []struct {
+		seq     uint32
+		transit time.Duration
+	}{{0, 10 * time.Millisecond}, {1, 12 * time.Millisecond}, {3, 10 * time.Millisecond}, {2, 14 * time.Millisecond}} {
+		assert.True(t, s.observe(echo.seq, echo.transit))
+	}
+	assert.False(t, s.observe(2, 10*time.Millisecond), "duplicates are not counted")
+	// A sequence number beyond the first word of the bitmap
+	assert.True(t, s.observe(100, 10*time.Millisecond))
+
+	assert.Equal(t, uint64(5), s.received)
+	assert.Equal(t, uint64(1), s.outOfOrder)
+	assert.Equal(t, uint64(96), s.lost(101))
+	assert.Zero(t, s.lost(3))
+	// Transit differences of 2, 2, 4 and 4 ms smoothed by 1/16 each
+	want := 0.0
+	for _, d := range []float64{2e6, 2e6, 4e6, 4e6} {
+		want += (d - want) / 16
+	}
+	assert.InDelta(t, want, s.jitter, 1)
+}
+
+func TestStreamCBR(t *testing.T) {
+	logging.InitLogger("json", "error")
+	oldCfg, oldMc, oldCBR, oldTimeouts := cfg, mc, cbr, responseTimeouts
+	defer func() { cfg, mc, cbr, responseTimeouts = oldCfg, oldMc, oldCBR, oldTimeouts }()
+	cfg = &config.ClientConfig{PayloadSize: 100, UDPBitrate: "80k", MTU: 1500}
+	mc = metrics.NewMetricsCollector()
+	cbr = newConstantBitrate(cfg)
+	responseTimeouts = newUDPTimeouts(&config.ClientConfig{UDPTimeoutMin: 0.05, UDPTimeoutMax: 0.2})
+
+	// The echo server drops the datagram with sequence number 5
+	echo, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer func() { _ = echo.Close() }()
+	go func() {
+		buf := make([]byte, 2048)
+		for {
+			n, addr, err := echo.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if h, ok := flowheader.Parse(buf[:n]); ok && h.Seq == 5 {
+				continue
+			}
+			_, _ = echo.WriteToUDP(buf[:n], addr)
+		}
+	}()
+
+	// 100 byte datagrams at 80 kbit/s are sent every 10ms
+	var wg sync.WaitGroup
+	wg.Add(1)
+	generateFlow(context.Background(), 1, "127.0.0.1", ProtocolPort{"udp", echo.LocalAddr().(*net.UDPAddr).Port}, 0.2, rand.New(rand.NewPCG(0, 0)), 1500, 1460, &wg)
+
+	port := strconv.Itoa(echo.LocalAddr().(*net.UDPAddr).Port)
+	assert.InDelta(t, 20, testutil.ToFloat64(mc.RequestsSent.WithLabelValues("udp", port)), 3)
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.CBRDatagramsLost.WithLabelValues("udp", port)))
+	assert.Equal(t, 1, testutil.CollectAndCount(mc.CBRJitter))
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"net"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/transport"
+)
+
+// init registers the connection churn transports
+func init() {
+	transport.Register("tcp_churn", transport.ChurnMode, newChurnTransport(false))
+	transport.Register("tcp_churn_rst", transport.ChurnMode, newChurnTransport(true))
+}
+
+// churnTransport opens a TCP connection and closes it right away without sending anything, to stress the
+// connection tracking and load balancer tables of the path with setup and teardown independent of
+// bandwidth. The connection is closed with a FIN, or with a RST if reset is set, which also spares the
+// client the TIME_WAIT state of the connection.
+type churnTransport struct {
+	flow  transport.FlowInfo
+	conn  net.Conn
+	reset bool
+}
+
+// newChurnTransport returns the factory of a churn transport
+func newChurnTransport(reset bool) transport.Factory {
+	return func(flow transport.FlowInfo) transport.Transport {
+		return &churnTransport{flow: flow, reset: reset}
+	}
+}
+
+// Dial completes the TCP handshake with addr. Churned connections are never pooled or relayed.
+func (t *churnTransport) Dial(ctx context.Context, addr string) error {
+	conn, err := dialing.dial(ctx, flowDialer("tcp", t.flow), "tcp", addr)
+	if err != nil {
+		return err
+	}
+	t.conn = conn
+	mc.TCPConnectionsOpenedPerSecond.Inc()
+	logFlowDetail(t.flow.ID, t.flow.Protocol, t.flow.Sampled, "Churned TCP connection %s -> %s established", t.conn.LocalAddr(), t.conn.RemoteAddr())
+	return nil
+}
+
+// Send sends nothing, generateFlow never calls it for churn mode transports
+func (t *churnTransport) Send([]byte) (int, error) {
+	return 0, nil
+}
+
+// Recv reads from the connection, generateFlow never calls it for churn mode transports
+func (t *churnTransport) Recv(buf []byte) (int, error) {
+	return t.conn.Read(buf)
+}
+
+// Close closes the connection, discarding any data still queued with a RST if reset is set
+func (t *churnTransport) Close() error {
+	if tcp, ok := t.conn.(*net.TCPConn); ok && t.reset {
+		if err := tcp.SetLinger(0); err != nil {
+			logging.Logger.Debugf("Failed to set linger on churned TCP connection: %v", err)
+		}
+	}
+	return t.conn.Close()
+}
+
+// RemoteAddr returns the address of the server
+func (t *churnTransport) RemoteAddr() net.Addr {
+	return t.conn.RemoteAddr()
+}
+
+// LocalAddr returns the local address of the connection
+func (t *churnTransport) LocalAddr() net.Addr {
+	return t.conn.LocalAddr()
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateFlowChurn(t *testing.T) {
+	logging.InitLogger("json", "error")
+
+	oldCfg, oldMc := cfg, mc
+	cfg = &config.ClientConfig{PayloadSize: 64}
+	mc = metrics.NewMetricsCollector()
+	defer func() { cfg, mc = oldCfg, oldMc }()
+
+	tests := []struct {
+		protocol string
+		// wantErr is how the server sees the connection end
+		wantErr error
+	}{
+		{"tcp_churn", io.EOF},
+		{"tcp_churn_rst", syscall.ECONNRESET},
+	}
+	for _, tt := range tests {
+		t.Run(tt.protocol, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			defer func() { _ = ln.Close() }()
+			closed := make(chan error, 1)
+			go func() {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer func() { _ = conn.Close() }()
+				_, err = conn.Read(make([]byte, 1))
+				closed <- err
+			}()
+			port := ln.Addr().(*net.TCPAddr).Port
+
+			// The flow ends with the handshake, long before its duration
+			start := time.Now()
+			var wg sync.WaitGroup
+			wg.Add(1)
+			generateFlow(context.Background(), 1, "127.0.0.1", ProtocolPort{tt.protocol, port}, 10, rand.New(rand.NewPCG(0, 0)), 1500, 1460, &wg)
+			assert.Less(t, time.Since(start), 5*time.Second)
+
+			select {
+			case err := <-closed:
+				assert.True(t, errors.Is(err, tt.wantErr), "server read error %v", err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("connection was not closed")
+			}
+			labelPort := strconv.Itoa(port)
+			assert.Equal(t, float64(1), testutil.ToFloat64(mc.ConnectionsChurned.WithLabelValues(tt.protocol, labelPort)))
+			assert.Equal(t, float64(0), testutil.ToFloat64(mc.RequestsSent.WithLabelValues(tt.protocol, labelPort)))
+			assert.Equal(t, uint64(1), mc.LatencySummaries()[tt.protocol].Count)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+// Package client is the flow generator client, which generates TCP and UDP flows towards an echo server.
+// NewCommand builds the command line of the flow-generator binary, so that programs can run the client with
+// their own transports and flow hooks registered with the transport and hooks packages.
+package client
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/version"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCommand builds the flow-generator command line, which the flow-generator binary executes.
+// Running it without a subcommand starts generating flows, like the run subcommand, and exits the process
+// with the exit code of the run once it is over. Programs linking their own transports and flow hooks into
+// the client execute it from their main function. The configuration flags are defined on the global flag
+// set, so it is built once per process.
+func NewCommand() *cobra.Command {
+	// Configuration flags live on the global flag set so that viper can bind them
+	defineFlags(pflag.CommandLine)
+
+	var showVersion, dryRunOnly bool
+	root := &cobra.Command{
+		Use:          "flow-generator",
+		Short:        "Generate TCP and UDP flows towards an echo server",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if showVersion {
+				printVersion(cmd.OutOrStdout())
+				return nil
+			}
+			if dryRunOnly {
+				return dryRun(cmd.OutOrStdout())
+			}
+			exitWith(run())
+			return nil
+		},
+	}
+	root.PersistentFlags().AddFlagSet(pflag.CommandLine)
+	root.PersistentFlags().BoolVar(&dryRunOnly, "dry-run", false, "Print the effective configuration and flow plan without generating flows")
+	root.Flags().BoolVar(&showVersion, "version", false, "Print version information and exit")
+
+	root.AddCommand(
+		&cobra.Command{
+			Use:   "run",
+			Short: "Start generating flows",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				if dryRunOnly {
+					return dryRun(cmd.OutOrStdout())
+				}
+				exitWith(run())
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:   "version",
+			Short: "Print version information",
+			Args:  cobra.NoArgs,
+			Run: func(cmd *cobra.Command, args []string) {
+				printVersion(cmd.OutOrStdout())
+			},
+		},
+		newConfigCmd(),
+		newBenchCmd(),
+	)
+	return root
+}
+
+// exitWith exits with the exit code of a run that did not succeed, once the run has cleaned up
+func exitWith(code int) {
+	if code != 0 {
+		os.Exit(code)
+	}
+}
+
+// newConfigCmd builds the config subcommand for inspecting the effective configuration
+func newConfigCmd() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the client configuration",
+	}
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "validate",
+		Short: "Validate the configuration from flags, environment and config file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := config.LoadClientConfig()
+			if err != nil {
+				return err
+			}
+			if err := checkTransportPorts(c); err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), "Configuration is valid")
+			return err
+		},
+	})
+	return configCmd
+}
+
+// printVersion writes the client version information
+func printVersion(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "Flow Generator Client")
+	_, _ = fmt.Fprintln(w, version.Info())
+}
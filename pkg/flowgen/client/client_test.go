@@ -0,0 +1,66 @@
+package client_test
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/client"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/hooks"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/transport"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCustomTransport runs the client as a program outside of the module would, with a transport it
+// registered itself
+func TestCustomTransport(t *testing.T) {
+	transport.Register("embedded_rpc", transport.DatagramMode, func(flow transport.FlowInfo) transport.Transport { return nil })
+	viper.Reset()
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+
+	var out bytes.Buffer
+	cmd := client.NewCommand()
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"--dry-run", "--tcp_ports", "", "--transport_ports", "9000=embedded_rpc"})
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), "embedded_rpc/9000")
+}
+
+// TestCustomHooks runs flows against a local echo server with a flow hook registered by the program
+func TestCustomHooks(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	var completed atomic.Int32
+	hooks.Register(hooks.Hooks{OnFlowCompleted: func(e hooks.Event) { completed.Add(1) }})
+	viper.Reset()
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+
+	port := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+	cmd := client.NewCommand()
+	cmd.SetArgs([]string{"--server", "127.0.0.1", "--protocol", "tcp", "--tcp_ports", port, "--flow_count", "3",
+		"--rate", "20", "--min_duration", "0.05", "--max_duration", "0.1", "--metrics_port", "", "--log_level", "error"})
+	require.NoError(t, cmd.Execute())
+	assert.Equal(t, int32(3), completed.Load())
+}
@@ -0,0 +1,210 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/hooks"
+)
+
+const (
+	// conntrackCheckInterval is how often the window is checked for the signature of a full conntrack table
+	conntrackCheckInterval = time.Second
+	// conntrackMinFlows is the number of flows that must have finished within the window before its failures
+	// count as the signature of a full conntrack table, so a few early failures do not
+	conntrackMinFlows = 10
+	// conntrackRecoveryFlows is the number of flows started while backed off that must complete in a row
+	// before the conntrack table counts as recovered
+	conntrackRecoveryFlows = 5
+)
+
+// conntrackEvent records a backoff from a full conntrack table, its end is missing while the client is
+// backed off
+type conntrackEvent struct {
+	StartSeconds float64 `json:"start_seconds"`
+	// FailureRate is the percentage of flows within the window that timed out or were refused
+	FailureRate float64  `json:"failure_rate"`
+	EndSeconds  *float64 `json:"end_seconds,omitempty"`
+	// RecoverySeconds is the time from the backoff to the first of the flows that completed in a row again
+	RecoverySeconds *float64 `json:"recovery_seconds,omitempty"`
+}
+
+// String describes the event for reports, e.g. "at 42.0s-57.0s: 85.0% of flows timed out or were refused,
+// recovered after 12.3s"
+func (e conntrackEvent) String() string {
+	if e.EndSeconds == nil {
+		return fmt.Sprintf("at %.1fs-: %.1f%% of flows timed out or were refused", e.StartSeconds, e.FailureRate)
+	}
+	return fmt.Sprintf("at %.1fs-%.1fs: %.1f%% of flows timed out or were refused, recovered after %.1fs",
+		e.StartSeconds, *e.EndSeconds, e.FailureRate, *e.RecoverySeconds)
+}
+
+// conntrackBackoff watches for the failure signature of a full conntrack table on the path: flows had been
+// completing, then most flows within the window time out or are refused, since new connections find no
+// free entry. The client then backs off to a fraction of the flow rate and keeps probing at that rate until
+// flows complete again, which measures how long the table took to recover, and restores the rate.
+type conntrackBackoff struct {
+	mu        sync.Mutex
+	threshold float64
+	window    time.Duration
+	factor    float64
+	start     time.Time
+	// results are the flows finished within the window, failed if they timed out or were refused
+	results []flowResult
+	// sustained is set once a window was checked in which the flows were completing
+	sustained bool
+	// since is when the client backed off, zero while it is not
+	since time.Time
+	// streak counts the flows started while backed off that completed in a row, the first of them at
+	// streakStart
+	streak      int
+	streakStart time.Time
+	events      []conntrackEvent
+}
+
+// newConntrackBackoff returns the conntrack backoff of a run starting at the given time, or nil if
+// conntrack_backoff is disabled
+func newConntrackBackoff(c *config.ClientConfig, start time.Time) *conntrackBackoff {
+	if !c.ConntrackBackoff {
+		return nil
+	}
+	return &conntrackBackoff{
+		threshold: c.ConntrackBackoffThreshold,
+		window:    seconds(c.ConntrackBackoffWindow),
+		factor:    c.ConntrackBackoffFactor,
+		start:     start,
+	}
+}
+
+// String describes the backoff for the startup log
+func (b *conntrackBackoff) String() string {
+	return fmt.Sprintf("to %g%% of the flow rate once %g%% of the flows within %v time out or are refused",
+		b.factor*100, b.threshold, b.window)
+}
+
+// conntrackFailure reports whether a flow failed the way flows to a full conntrack table do
+func conntrackFailure(err error) bool {
+	reason := flowErrorReason(err, "")
+	return reason == flowErrorTimeout || reason == flowErrorRefused
+}
+
+// hooks returns the flow hooks feeding the outcomes of flows to the backoff
+func (b *conntrackBackoff) hooks() hooks.Hooks {
+	return hooks.Hooks{
+		OnFlowCompleted: func(e hooks.Event) { b.observe(e.Time.Add(-e.Elapsed), e.Time, nil) },
+		OnFlowFailed:    func(e hooks.Event) { b.observe(e.Time.Add(-e.Elapsed), e.Time, e.Err) },
+	}
+}
+
+// observe records a flow started at the given time that finished at another, failed if err is set
+func (b *conntrackBackoff) observe(started, at time.Time, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.since.IsZero() {
+		b.results = append(b.results, flowResult{at: at, failed: err != nil && conntrackFailure(err)})
+		return
+	}
+	// Only the probing flows tell whether the table recovered, not those started before the backoff
+	if started.Before(b.since) {
+		return
+	}
+	if err != nil {
+		b.streak = 0
+		return
+	}
+	if b.streak == 0 {
+		b.streakStart = at
+	}
+	b.streak++
+}
+
+// run checks the window every second until ctx is done and sends the rate multiplier to apply whenever it
+// changes
+func (b *conntrackBackoff) run(ctx context.Context, multipliers chan<- float64) {
+	ticker := time.NewTicker(conntrackCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			if multiplier, changed := b.check(now); changed {
+				select {
+				case multipliers <- multiplier:
+				case <-ctx.Done():
+					return
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// check backs off if the flows within the window ending at the given time fail like those to a full
+// conntrack table, or restores the rate once the probing flows complete again. It returns the rate
+// multiplier to apply, and whether it changed.
+func (b *conntrackBackoff) check(now time.Time) (float64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	elapsed := now.Sub(b.start).Seconds()
+
+	if !b.since.IsZero() {
+		if b.streak < conntrackRecoveryFlows {
+			return b.factor, false
+		}
+		event := &b.events[len(b.events)-1]
+		recovery := b.streakStart.Sub(b.since).Seconds()
+		event.EndSeconds, event.RecoverySeconds = &elapsed, &recovery
+		b.since, b.streak = time.Time{}, 0
+		// The probing flows just completed, so a new burst of failures counts as the table filling up again
+		b.results, b.sustained = nil, true
+		mc.ConntrackBackoffEnded()
+		logging.Logger.Infof("Conntrack table recovered after %.3fs, restoring the flow rate", recovery)
+		return 1, true
+	}
+
+	i := 0
+	for i < len(b.results) && now.Sub(b.results[i].at) > b.window {
+		i++
+	}
+	b.results = b.results[i:]
+	if len(b.results) < conntrackMinFlows {
+		return 1, false
+	}
+	var failures int
+	for _, r := range b.results {
+		if r.failed {
+			failures++
+		}
+	}
+	rate := float64(failures) / float64(len(b.results)) * 100
+	if rate < b.threshold {
+		b.sustained = true
+		return 1, false
+	}
+	if !b.sustained {
+		// Flows failing from the start point to an unreachable server rather than a table that filled up
+		return 1, false
+	}
+
+	b.since, b.streak = now, 0
+	b.results, b.sustained = nil, false
+	b.events = append(b.events, conntrackEvent{StartSeconds: elapsed, FailureRate: rate})
+	mc.ConntrackBackoffStarted()
+	logging.Logger.Warnf("%.1f%% of the flows within %v timed out or were refused, the conntrack table on the path is likely full: backing off to %g%% of the flow rate",
+		rate, b.window, b.factor*100)
+	return b.factor, true
+}
+
+// status returns the backoffs of the run so far, nil if conntrack_backoff is disabled
+func (b *conntrackBackoff) status() []conntrackEvent {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]conntrackEvent(nil), b.events...)
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dialErr returns the error of a connect failing with errno
+func dialErr(errno syscall.Errno) error {
+	return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", errno)}
+}
+
+func TestConntrackFailure(t *testing.T) {
+	assert.True(t, conntrackFailure(dialErr(syscall.ETIMEDOUT)))
+	assert.True(t, conntrackFailure(dialErr(syscall.ECONNREFUSED)))
+	assert.False(t, conntrackFailure(dialErr(syscall.ECONNRESET)))
+	assert.False(t, conntrackFailure(errors.New("echo mismatch")))
+}
+
+func TestConntrackBackoff(t *testing.T) {
+	logging.InitLogger("json", "error")
+	oldMc := mc
+	mc = metrics.NewMetricsCollector()
+	defer func() { mc = oldMc }()
+
+	start := time.Unix(1000, 0)
+	assert.Nil(t, newConntrackBackoff(&config.ClientConfig{}, start))
+	b := newConntrackBackoff(&config.ClientConfig{ConntrackBackoff: true, ConntrackBackoffThreshold: 50, ConntrackBackoffWindow: 5, ConntrackBackoffFactor: 0.1}, start)
+	require.NotNil(t, b)
+	at := func(s float64) time.Time { return start.Add(seconds(s)) }
+	timeout := dialErr(syscall.ETIMEDOUT)
+
+	// Flows completing at a sustained rate, then most of them time out
+	for i := 0; i < 10; i++ {
+		b.observe(at(0.5), at(1+float64(i)/10), nil)
+	}
+	multiplier, changed := b.check(at(2))
+	assert.False(t, changed)
+	assert.Equal(t, 1.0, multiplier)
+	for i := 0; i < 20; i++ {
+		b.observe(at(2), at(3+float64(i)/20), timeout)
+	}
+	multiplier, changed = b.check(at(4))
+	assert.True(t, changed)
+	assert.Equal(t, 0.1, multiplier)
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.ConntrackBackoffActive))
+
+	events := b.status()
+	require.Len(t, events, 1)
+	assert.Equal(t, 4.0, events[0].StartSeconds)
+	assert.InDelta(t, 20.0/30*100, events[0].FailureRate, 0.01)
+	assert.Nil(t, events[0].EndSeconds)
+
+	// Flows started before the backoff do not count, a failed probe restarts the streak
+	b.observe(at(3), at(5), nil)
+	b.observe(at(5), at(6), nil)
+	b.observe(at(5), at(6.5), timeout)
+	for i := 0; i < conntrackRecoveryFlows-1; i++ {
+		b.observe(at(7), at(8+float64(i)), nil)
+	}
+	_, changed = b.check(at(12))
+	assert.False(t, changed, "one probe short of recovery")
+	b.observe(at(12), at(12.5), nil)
+	multiplier, changed = b.check(at(13))
+	assert.True(t, changed)
+	assert.Equal(t, 1.0, multiplier)
+	assert.Equal(t, float64(0), testutil.ToFloat64(mc.ConntrackBackoffActive))
+
+	events = b.status()
+	require.Len(t, events, 1)
+	require.NotNil(t, events[0].EndSeconds)
+	assert.Equal(t, 13.0, *events[0].EndSeconds)
+	require.NotNil(t, events[0].RecoverySeconds)
+	assert.Equal(t, 4.0, *events[0].RecoverySeconds)
+	assert.Equal(t, "at 4.0s-13.0s: 66.7% of flows timed out or were refused, recovered after 4.0s", events[0].String())
+}
+
+func TestConntrackBackoffNotSustained(t *testing.T) {
+	logging.InitLogger("json", "error")
+	start := time.Unix(1000, 0)
+	b := newConntrackBackoff(&config.ClientConfig{ConntrackBackoff: true, ConntrackBackoffThreshold: 50, ConntrackBackoffWindow: 5, ConntrackBackoffFactor: 0.1}, start)
+
+	// Flows refused from the start point to a server that is down, not to a table that filled up
+	for i := 0; i < 20; i++ {
+		b.observe(start, start.Add(time.Second), dialErr(syscall.ECONNREFUSED))
+	}
+	_, changed := b.check(start.Add(2 * time.Second))
+	assert.False(t, changed)
+	assert.Empty(t, b.status())
+}
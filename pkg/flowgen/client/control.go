@@ -0,0 +1,125 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+)
+
+// controlPath is the prefix of the control API endpoints on the status server
+const controlPath = "/control/"
+
+// Actions of the control API, the last element of the endpoint path
+const (
+	controlPause  = "pause"
+	controlResume = "resume"
+	controlRate   = "rate"
+)
+
+// controlTimeout bounds how long a request waits for the flow generation loop to take its command
+const controlTimeout = 5 * time.Second
+
+// errControlUnavailable is returned when the flow generation loop does not take commands, e.g. while draining
+var errControlUnavailable = errors.New("run is not accepting commands")
+
+// controlCommand is a request to change the run, applied by the flow generation loop. The outcome is sent
+// on done.
+type controlCommand struct {
+	action string
+	rate   float64
+	done   chan error
+}
+
+// runControl serves the control API. Commands are handed to the flow generation loop, which owns the
+// schedule, so they are applied between flow starts like a reload.
+type runControl struct {
+	commands chan controlCommand
+	tracker  *runTracker
+}
+
+// newRunControl creates the control API of a run, replying with the state of the run reported by tracker
+func newRunControl(tracker *runTracker) *runControl {
+	return &runControl{commands: make(chan controlCommand), tracker: tracker}
+}
+
+// ServeHTTP applies a POST to /control/pause, /control/resume or /control/rate and replies with the run
+// status. The rate is given as a rate query parameter or a JSON body like {"rate": 25}.
+func (c *runControl) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	cmd := controlCommand{action: strings.TrimPrefix(r.URL.Path, controlPath), done: make(chan error, 1)}
+	switch cmd.action {
+	case controlPause, controlResume:
+	case controlRate:
+		rate, err := requestedRate(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		cmd.rate = rate
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := c.send(cmd); err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, errControlUnavailable) {
+			status = http.StatusServiceUnavailable
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logging.Logger.Infof("Run control: %s applied", cmd.action)
+	c.tracker.ServeHTTP(w, r)
+}
+
+// send hands the command to the flow generation loop and waits for its outcome
+func (c *runControl) send(cmd controlCommand) error {
+	timer := time.NewTimer(controlTimeout)
+	defer timer.Stop()
+	select {
+	case c.commands <- cmd:
+	case <-timer.C:
+		return errControlUnavailable
+	}
+	return <-cmd.done
+}
+
+// requestedRate reads the new flow rate of a rate request
+func requestedRate(r *http.Request) (float64, error) {
+	var rate float64
+	if s := r.URL.Query().Get("rate"); s != "" {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid rate %q", s)
+		}
+		rate = v
+	} else {
+		var body struct {
+			Rate float64 `json:"rate"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return 0, fmt.Errorf("rate must be given as a query parameter or a JSON body: %w", err)
+		}
+		rate = body.Rate
+	}
+	// NaN passes any comparison and an infinite rate leaves no time between flows
+	if math.IsNaN(rate) || math.IsInf(rate, 0) {
+		return 0, fmt.Errorf("rate must be a finite number")
+	}
+	if rate <= 0 {
+		return 0, fmt.Errorf("rate must be positive")
+	}
+	return rate, nil
+}
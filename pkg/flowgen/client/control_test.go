@@ -0,0 +1,100 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveCommands answers the commands of c like the flow generation loop until the test ends, recording
+// them in applied
+func serveCommands(t *testing.T, c *runControl, result error) <-chan controlCommand {
+	applied := make(chan controlCommand, 10)
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+	go func() {
+		for {
+			select {
+			case cmd := <-c.commands:
+				applied <- cmd
+				cmd.done <- result
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return applied
+}
+
+func TestRunControlServeHTTP(t *testing.T) {
+	logging.InitLogger("json", "error")
+	flows := uint64(0)
+	tracker := newRunTracker(&config.ClientConfig{Scenario: "soak"}, time.Now(), &flows)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		wantAction string
+		wantRate   float64
+	}{
+		{name: "pause", method: http.MethodPost, target: "/control/pause", wantStatus: http.StatusOK, wantAction: controlPause},
+		{name: "resume", method: http.MethodPost, target: "/control/resume", wantStatus: http.StatusOK, wantAction: controlResume},
+		{name: "rate query", method: http.MethodPost, target: "/control/rate?rate=25", wantStatus: http.StatusOK, wantAction: controlRate, wantRate: 25},
+		{name: "rate body", method: http.MethodPost, target: "/control/rate", body: `{"rate": 2.5}`, wantStatus: http.StatusOK, wantAction: controlRate, wantRate: 2.5},
+		{name: "missing rate", method: http.MethodPost, target: "/control/rate", wantStatus: http.StatusBadRequest},
+		{name: "zero rate", method: http.MethodPost, target: "/control/rate?rate=0", wantStatus: http.StatusBadRequest},
+		{name: "invalid rate", method: http.MethodPost, target: "/control/rate?rate=fast", wantStatus: http.StatusBadRequest},
+		{name: "NaN rate", method: http.MethodPost, target: "/control/rate?rate=NaN", wantStatus: http.StatusBadRequest},
+		{name: "infinite rate", method: http.MethodPost, target: "/control/rate?rate=Inf", wantStatus: http.StatusBadRequest},
+		{name: "positive infinite rate", method: http.MethodPost, target: "/control/rate?rate=%2BInf", wantStatus: http.StatusBadRequest},
+		{name: "negative infinite rate", method: http.MethodPost, target: "/control/rate?rate=-Inf", wantStatus: http.StatusBadRequest},
+		{name: "unknown action", method: http.MethodPost, target: "/control/stop", wantStatus: http.StatusNotFound},
+		{name: "GET", method: http.MethodGet, target: "/control/pause", wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newRunControl(tracker)
+			applied := serveCommands(t, c, nil)
+
+			rec := httptest.NewRecorder()
+			c.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus != http.StatusOK {
+				assert.Empty(t, applied)
+				return
+			}
+			cmd := <-applied
+			assert.Equal(t, tt.wantAction, cmd.action)
+			assert.Equal(t, tt.wantRate, cmd.rate)
+
+			// The reply is the run status
+			var status runStatus
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+			assert.Equal(t, "soak", status.Scenario)
+		})
+	}
+}
+
+func TestRunControlRejected(t *testing.T) {
+	logging.InitLogger("json", "error")
+	flows := uint64(0)
+	c := newRunControl(newRunTracker(&config.ClientConfig{}, time.Now(), &flows))
+	serveCommands(t, c, errors.New("run is already paused"))
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/control/pause", nil))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "run is already paused")
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+)
+
+// dialPolicy controls how client connections are established: the address family of flows to servers with
+// both A and AAAA records, the timeout of every connection attempt and how failed attempts are retried
+type dialPolicy struct {
+	family string
+	// fallbackDelay is the time after which the other family is raced, 0 waits for the first one to fail
+	fallbackDelay time.Duration
+	// timeout bounds each attempt, 0 leaves it to the context of the flow
+	timeout time.Duration
+	retries int
+	// backoff is the wait before the first retry, doubled for every further retry
+	backoff time.Duration
+}
+
+// newDialPolicy returns the dial policy configured with address_family, happy_eyeballs_delay and the
+// connect_* settings
+func newDialPolicy(c *config.ClientConfig) *dialPolicy {
+	family := c.AddressFamily
+	if family == "" {
+		family = familyAny
+	}
+	fallbackDelay := seconds(c.HappyEyeballsDelay)
+	// Racing the families dials from other goroutines, whose sockets would not be created in the network
+	// namespace of the flow
+	if c.Netns != "" {
+		fallbackDelay = 0
+	}
+	return &dialPolicy{
+		family:        family,
+		fallbackDelay: fallbackDelay,
+		timeout:       seconds(c.ConnectTimeout),
+		retries:       c.ConnectRetries,
+		backoff:       seconds(c.ConnectBackoff),
+	}
+}
+
+// dial connects to addr over network ("tcp" or "udp") with d, following the policy. Attempts that fail or
+// time out are retried with exponential backoff until the retries are used up or ctx is done, and every
+// retry is counted in the metrics. A nil policy dials like the standard library.
+func (p *dialPolicy) dial(ctx context.Context, d *net.Dialer, network, addr string) (net.Conn, error) {
+	if p == nil {
+		return d.DialContext(ctx, network, addr)
+	}
+	backoff := p.backoff
+	for attempt := 0; ; attempt++ {
+		conn, err := p.attempt(ctx, d, network, addr)
+		if err == nil || attempt >= p.retries || ctx.Err() != nil {
+			return conn, err
+		}
+		logging.Logger.Debugf("Connection attempt %d to %s (%s) failed, retrying in %s: %v", attempt+1, addr, network, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, err
+		}
+		if mc != nil {
+			mc.IncConnectRetries(network)
+		}
+		backoff *= 2
+	}
+}
+
+// attempt makes a single connection attempt bounded by the timeout of the policy
+func (p *dialPolicy) attempt(ctx context.Context, d *net.Dialer, network, addr string) (net.Conn, error) {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+	return p.dialFamily(ctx, d, network, addr)
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingDialer returns a dialer whose first failures attempts fail, counting all attempts in attempts
+func failingDialer(failures int32, attempts *int32) *net.Dialer {
+	return &net.Dialer{Control: func(network, address string, c syscall.RawConn) error {
+		if atomic.AddInt32(attempts, 1) <= failures {
+			return errors.New("connection lost")
+		}
+		return nil
+	}}
+}
+
+func TestNewDialPolicy(t *testing.T) {
+	p := newDialPolicy(&config.ClientConfig{ConnectTimeout: 2, ConnectRetries: 3, ConnectBackoff: 0.25})
+	assert.Equal(t, familyAny, p.family)
+	assert.Equal(t, 2*time.Second, p.timeout)
+	assert.Equal(t, 3, p.retries)
+	assert.Equal(t, 250*time.Millisecond, p.backoff)
+}
+
+func TestDialPolicyRetries(t *testing.T) {
+	logging.InitLogger("json", "error")
+	oldMc := mc
+	mc = metrics.NewMetricsCollector()
+	defer func() { mc = oldMc }()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+	addr := ln.Addr().String()
+
+	tests := []struct {
+		name     string
+		retries  int
+		failures int32
+		wantErr  bool
+		attempts int32
+	}{
+		{name: "first attempt succeeds", retries: 2, failures: 0, attempts: 1},
+		{name: "succeeds after retries", retries: 2, failures: 2, attempts: 3},
+		{name: "retries used up", retries: 2, failures: 5, wantErr: true, attempts: 3},
+		{name: "no retries", retries: 0, failures: 1, wantErr: true, attempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := testutil.ToFloat64(mc.ConnectRetries.WithLabelValues("tcp"))
+			var attempts int32
+			p := &dialPolicy{family: familyAny, retries: tt.retries, backoff: time.Millisecond}
+			conn, err := p.dial(context.Background(), failingDialer(tt.failures, &attempts), "tcp", addr)
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "connection lost")
+			} else {
+				require.NoError(t, err)
+				_ = conn.Close()
+			}
+			assert.Equal(t, tt.attempts, attempts)
+			assert.Equal(t, float64(tt.attempts-1), testutil.ToFloat64(mc.ConnectRetries.WithLabelValues("tcp"))-before)
+		})
+	}
+}
+
+func TestDialPolicyTimeout(t *testing.T) {
+	logging.InitLogger("json", "error")
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+
+	// The first attempt hangs like a lost SYN until the attempt times out, the retry connects
+	var attempts int32
+	d := &net.Dialer{ControlContext: func(ctx context.Context, network, address string, c syscall.RawConn) error {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	}}
+	p := &dialPolicy{family: familyAny, timeout: 50 * time.Millisecond, retries: 1, backoff: time.Millisecond}
+	start := time.Now()
+	conn, err := p.dial(context.Background(), d, "tcp", ln.Addr().String())
+	require.NoError(t, err)
+	_ = conn.Close()
+	assert.Equal(t, int32(2), attempts)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestDialPolicyCanceled(t *testing.T) {
+	logging.InitLogger("json", "error")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// Retries stop once the flow is over, even with backoff left
+	var attempts int32
+	p := &dialPolicy{family: familyAny, retries: 10, backoff: time.Second}
+	start := time.Now()
+	_, err := p.dial(ctx, failingDialer(100, &attempts), "tcp", "127.0.0.1:1")
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), attempts)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
@@ -0,0 +1,189 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/transport"
+)
+
+// dryRun resolves and validates the configuration, then prints the effective settings and the flow plan
+// without generating any flows
+func dryRun(w io.Writer) error {
+	c, err := config.LoadClientConfig()
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(w, "Effective configuration:")
+	if err := config.WriteEffectiveSettings(w); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(w)
+	if err := writePlan(w, c); err != nil {
+		return err
+	}
+	if c.Role == roleAgent {
+		srv, err := config.LoadAgentServerConfig(c)
+		if err != nil {
+			return err
+		}
+		return writeAgentPlan(w, c, srv)
+	}
+	return nil
+}
+
+// writePlan describes the flows the client would generate with the given configuration
+func writePlan(w io.Writer, c *config.ClientConfig) error {
+	if err := checkTransportPorts(c); err != nil {
+		return err
+	}
+	ports := buildAvailablePorts(c)
+	if len(ports) == 0 {
+		return fmt.Errorf("no valid ports available for protocol %s", c.Protocol)
+	}
+	targets := make([]string, 0, len(ports))
+	for _, pp := range ports {
+		targets = append(targets, fmt.Sprintf("%s/%d", pp.Protocol, pp.Port))
+	}
+
+	var b strings.Builder
+	b.WriteString("Flow plan:\n")
+	line := func(label, format string, args ...any) {
+		fmt.Fprintf(&b, "  %-14s"+format+"\n", append([]any{label + ":"}, args...)...)
+	}
+	if c.TargetCIDR != "" {
+		sweep, err := newTargetSweep(c.TargetCIDR)
+		if err != nil {
+			return err
+		}
+		if c.TargetProbe {
+			line("Target", "responding addresses of %s", sweep)
+		} else {
+			line("Target", "%s in turn", sweep)
+		}
+	} else if c.BackupServer != "" {
+		line("Target", "%s, failing over to %s at %g%% failed flows within %gs", c.Server, c.BackupServer, c.FailoverThreshold, c.FailoverWindow)
+	} else {
+		line("Target", "%s", c.Server)
+	}
+	line("Ports", "%s", strings.Join(targets, ", "))
+	if ports := newSourcePortRange(c); ports != nil {
+		line("Source ports", "%s, one per flow in turn", ports)
+	}
+	if entries, err := config.ParseNetns(c.Netns); c.Netns != "" && err == nil {
+		line("Namespaces", "%s", describeNetns(entries))
+	}
+
+	ticksPerSecond, flowsPerTick := flowPacing(c)
+	interval := time.Duration(float64(time.Second) / ticksPerSecond)
+	steps := newLoadSteps(c, time.Time{})
+	switch {
+	case c.BurstSize > 0:
+		line("Pacing", "bursts of %d flows every %v", flowsPerTick, interval)
+	case steps != nil:
+		line("Pacing", "load steps %s", steps)
+	default:
+		line("Pacing", "%g flows/s (one flow every %v)", c.Rate, interval)
+	}
+	if c.PortStartOffsets {
+		line("Start offsets", "jittered per port within each tick")
+	}
+	line("Concurrency", "up to %d flows", c.MaxConcurrent)
+	if c.ConstantFlows {
+		line("Duration", "constant %gs per flow", float64(c.MaxConcurrent)/c.Rate)
+	} else {
+		line("Duration", "%gs to %gs per flow", c.MinDuration, c.MaxDuration)
+	}
+	if payloads, err := newPayloadDistribution(c); err == nil && payloads != nil {
+		line("Payloads", "%s bytes", payloads)
+	}
+	if mimicry := newPayloadMimicry(c); mimicry != nil {
+		var mimicked []string
+		for _, pp := range ports {
+			reg, ok := transport.Lookup(pp.Protocol)
+			if !ok || reg.Mode == transport.ChurnMode || reg.Mode == transport.ReceiveMode {
+				continue
+			}
+			if name := mimicry.protocol(pp.Port, reg.Mode == transport.DatagramMode); name != "" {
+				mimicked = append(mimicked, fmt.Sprintf("%s/%d as %s", pp.Protocol, pp.Port, name))
+			}
+		}
+		if len(mimicked) > 0 {
+			line("Mimicry", "%s", strings.Join(mimicked, ", "))
+		} else {
+			line("Mimicry", "none of the ports")
+		}
+	}
+	if c.ResponseSizes != "" {
+		line("Responses", "sized by the server on ports %s", c.ResponseSizes)
+	}
+	if b := newConstantBitrate(c); b != nil {
+		line("UDP bitrate", "constant %s per flow", b)
+	}
+	line("Stops after", "%s", stopCondition(c))
+	if windows := newPauseWindows(c); windows != nil {
+		line("Pauses", "no new flows daily during %s (local time)", windows)
+	}
+	if b := newConntrackBackoff(c, time.Time{}); b != nil {
+		line("Backoff", "%s", b)
+	}
+	if m := newPhaseMonitor(c, time.Time{}); m != nil {
+		line("Phases", "%s", m)
+	}
+	if l := newSoftLimits(c, time.Time{}); l != nil {
+		if c.WarnWebhook != "" {
+			// The URL of a chat webhook carries its secret, so it is not printed
+			line("Warnings", "%s, posted to the warn webhook", l)
+		} else {
+			line("Warnings", "%s", l)
+		}
+	}
+	switch fd, isFD := strings.CutPrefix(c.ProgressEvents, "fd:"); {
+	case c.ProgressEvents == "":
+	case c.ProgressEvents == "-":
+		line("Progress", "lifecycle events to stdout")
+	case isFD:
+		line("Progress", "lifecycle events to file descriptor %s", fd)
+	default:
+		line("Progress", "lifecycle events to %s", c.ProgressEvents)
+	}
+	if c.ExpectServices != "" {
+		line("Services", "%s, verified before the run", c.ExpectServices)
+	}
+	if chain := newRelayChain(c); chain != nil {
+		line("Relays", "%s", strings.Join(chain.relays, " -> "))
+	}
+	if c.MetricsPort != "" {
+		line("Metrics", "served on port %s", c.MetricsPort)
+	}
+	if c.BackpressureURL != "" {
+		line("Backpressure", "polling %s, rate reduced to %.0f%% while overloaded", c.BackpressureURL, c.BackpressureFactor*100)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// stopCondition describes when flow generation ends
+func stopCondition(c *config.ClientConfig) string {
+	var limits []string
+	if c.FlowCount > 0 {
+		limits = append(limits, fmt.Sprintf("%d flows", c.FlowCount))
+	}
+	if c.FlowTimeout > 0 {
+		limits = append(limits, fmt.Sprintf("%gs", c.FlowTimeout))
+	}
+	if steps := newLoadSteps(c, time.Time{}); steps != nil {
+		limits = append(limits, fmt.Sprintf("the last load step (%gs)", steps.duration().Seconds()))
+	}
+	if len(limits) == 0 {
+		return "never (until terminated)"
+	}
+	if c.StopCondition == stopAll {
+		return strings.Join(limits, " and ")
+	}
+	return strings.Join(limits, " or ")
+}
@@ -0,0 +1,155 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePlan(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.ClientConfig
+		contains []string
+	}{
+		{
+			name: "rate paced",
+			cfg: config.ClientConfig{Server: "echo", Rate: 4, MaxConcurrent: 10, Protocol: "both",
+				TCPPorts: "8080", UDPPorts: "53", MinDuration: 1, MaxDuration: 5, FlowCount: 100},
+			contains: []string{"echo", "tcp/8080, udp/53", "4 flows/s (one flow every 250ms)", "1s to 5s per flow", "100 flows"},
+		},
+		{
+			name: "burst with relays",
+			cfg: config.ClientConfig{Server: "echo", Rate: 1, MaxConcurrent: 10, Protocol: "tcp",
+				TCPPorts: "8080", BurstSize: 20, BurstInterval: 2, FlowTimeout: 60, RelayChain: "a:1,b:2",
+				CommonConfig: config.CommonConfig{MetricsPort: "9091"}},
+			contains: []string{"bursts of 20 flows every 2s", "60s", "a:1 -> b:2", "served on port 9091"},
+		},
+		{
+			name: "count and timeout both required",
+			cfg: config.ClientConfig{Server: "echo", Rate: 1, MaxConcurrent: 1, Protocol: "udp", UDPPorts: "53",
+				FlowCount: 100, FlowTimeout: 60, StopCondition: "all"},
+			contains: []string{"100 flows and 60s"},
+		},
+		{
+			name: "target sweep",
+			cfg: config.ClientConfig{Server: "echo", TargetCIDR: "10.2.0.0/24", TargetProbe: true, Rate: 1, MaxConcurrent: 1,
+				Protocol: "tcp", TCPPorts: "8080"},
+			contains: []string{"responding addresses of 10.2.0.0/24 (254 addresses)"},
+		},
+		{
+			name: "phases",
+			cfg: config.ClientConfig{Server: "localhost", Phases: "steady:60:max_error_rate=0.1;chaos:120",
+				Rate: 1, MaxConcurrent: 1, Protocol: "tcp", TCPPorts: "8080"},
+			contains: []string{"Phases:", "steady (60s), chaos (120s)"},
+		},
+		{
+			name: "expected services",
+			cfg: config.ClientConfig{Server: "localhost", ExpectServices: "8080=echo,8443=tls", Rate: 1, MaxConcurrent: 1,
+				Protocol: "tcp", TCPPorts: "8080"},
+			contains: []string{"Services:", "8080=echo,8443=tls, verified before the run"},
+		},
+		{
+			name: "source ports",
+			cfg: config.ClientConfig{Server: "localhost", SourcePortRange: "20000-29999", Rate: 1, MaxConcurrent: 1,
+				Protocol: "tcp", TCPPorts: "10000-10001"},
+			contains: []string{"tcp/10000, tcp/10001", "Source ports:", "20000-29999 (10000 ports), one per flow in turn"},
+		},
+		{
+			name: "failover",
+			cfg: config.ClientConfig{Server: "primary", BackupServer: "backup", FailoverThreshold: 50, FailoverWindow: 10,
+				Rate: 1, MaxConcurrent: 1, Protocol: "tcp", TCPPorts: "8080"},
+			contains: []string{"primary, failing over to backup at 50% failed flows within 10s"},
+		},
+		{
+			name: "load steps",
+			cfg: config.ClientConfig{Server: "localhost", Steps: "100:2m;500:60:peak", FlowTimeout: 300, Rate: 1,
+				MaxConcurrent: 1, Protocol: "tcp", TCPPorts: "8080"},
+			contains: []string{"load steps step1 (100/s for 120s), peak (500/s for 60s)", "300s or the last load step (180s)"},
+		},
+		{
+			name: "constant bitrate",
+			cfg: config.ClientConfig{Server: "localhost", UDPBitrate: "1.5M", Rate: 1, MaxConcurrent: 1, Protocol: "udp",
+				UDPPorts: "5001"},
+			contains: []string{"UDP bitrate:", "constant 1.5 Mbit/s per flow"},
+		},
+		{
+			name: "pause windows",
+			cfg: config.ClientConfig{Server: "localhost", PauseWindows: "02:00-02:15,23:50-00:10", Rate: 1, MaxConcurrent: 1,
+				Protocol: "tcp", TCPPorts: "8080"},
+			contains: []string{"Pauses:", "no new flows daily during 02:00-02:15, 23:50-00:10 (local time)"},
+		},
+		{
+			name: "protocol mimicry",
+			cfg: config.ClientConfig{Server: "localhost", MimicPayloads: true, MimicPorts: "5353=dns", Rate: 1, MaxConcurrent: 1,
+				Protocol: "both", TCPPorts: "443,8081", UDPPorts: "123,443,5353"},
+			contains: []string{"Mimicry:", "tcp/443 as tls, udp/123 as ntp, udp/5353 as dns"},
+		},
+		{
+			name: "soft limits",
+			cfg: config.ClientConfig{Server: "localhost", WarnThresholds: "max_error_rate=1,max_p99_latency=100", WarnWindow: 30,
+				WarnWebhook: "https://hooks.example.com/secret", Rate: 1, MaxConcurrent: 1, Protocol: "tcp", TCPPorts: "8080"},
+			contains: []string{"Warnings:", "error rate above 1%, p99 latency above 100ms within 30s, posted to the warn webhook"},
+		},
+		{
+			name: "network namespaces",
+			cfg: config.ClientConfig{Server: "localhost", Netns: "client-a=3,client-b", Rate: 1, MaxConcurrent: 1, Protocol: "tcp",
+				TCPPorts: "8080"},
+			contains: []string{"Namespaces:", "client-a (75%), client-b (25%)"},
+		},
+		{
+			name: "response sizes",
+			cfg: config.ClientConfig{Server: "localhost", Rate: 1, MaxConcurrent: 1, Protocol: "tcp", TCPPorts: "8080",
+				ResponseSizes: "8080=amplify:4"},
+			contains: []string{"Responses:", "sized by the server on ports 8080=amplify:4"},
+		},
+		{
+			name: "conntrack backoff",
+			cfg: config.ClientConfig{Server: "localhost", Rate: 1, MaxConcurrent: 1, Protocol: "tcp", TCPPorts: "8080",
+				ConntrackBackoff: true, ConntrackBackoffThreshold: 50, ConntrackBackoffWindow: 5, ConntrackBackoffFactor: 0.1},
+			contains: []string{"Backoff:", "to 10% of the flow rate once 50% of the flows within 5s time out or are refused"},
+		},
+		{
+			name: "progress events",
+			cfg: config.ClientConfig{Server: "localhost", ProgressEvents: "fd:3", Rate: 1, MaxConcurrent: 1, Protocol: "tcp",
+				TCPPorts: "8080"},
+			contains: []string{"Progress:", "lifecycle events to file descriptor 3"},
+		},
+		{
+			name:     "unlimited",
+			cfg:      config.ClientConfig{Server: "echo", Rate: 1, MaxConcurrent: 1, Protocol: "udp", UDPPorts: "53"},
+			contains: []string{"udp/53", "never (until terminated)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, writePlan(&buf, &tt.cfg))
+			for _, s := range tt.contains {
+				assert.Contains(t, buf.String(), s)
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	assert.Error(t, writePlan(&buf, &config.ClientConfig{Protocol: "udp", TCPPorts: "8080"}))
+}
+
+func TestDryRunCommand(t *testing.T) {
+	out, err := executeRootCmd(t, "--dry-run", "--rate", "2", "--protocol", "tcp")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Effective configuration:")
+	assert.Regexp(t, `rate\s+"2"\s+flag`, out)
+	assert.Contains(t, out, "tcp/8080")
+	assert.Regexp(t, `metrics_port\s+"9091"\s+default`, out)
+
+	_, err = executeRootCmd(t, "run", "--dry-run", "--rate", "-1")
+	assert.Error(t, err)
+
+	_, err = executeRootCmd(t, "--dry-run", "--transport_ports", "9000=missing,9001=tcp")
+	assert.ErrorContains(t, err, "no flow transport registered for transport_ports 9000=missing")
+}
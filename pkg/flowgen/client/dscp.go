@@ -0,0 +1,53 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
+)
+
+// dscpMarks holds the DSCP classes flows are marked with, globally and per port
+type dscpMarks struct {
+	global string
+	ports  map[int]string
+}
+
+// newDSCPMarks returns the DSCP classes configured with dscp and dscp_ports, or nil if flows stay unmarked
+func newDSCPMarks(c *config.ClientConfig) *dscpMarks {
+	if c.DSCP == "" && c.DSCPPorts == "" {
+		return nil
+	}
+	// The mapping was checked when the configuration was validated
+	ports, _ := config.ParsePortMap(c.DSCPPorts)
+	m := &dscpMarks{global: strings.ToLower(strings.TrimSpace(c.DSCP)), ports: make(map[int]string, len(ports))}
+	for port, class := range ports {
+		m.ports[port] = strings.ToLower(class)
+	}
+	return m
+}
+
+// class returns the DSCP class and value of flows to a port. An empty class means the flow stays unmarked.
+func (m *dscpMarks) class(port int) (string, int) {
+	class, ok := m.ports[port]
+	if !ok {
+		class = m.global
+	}
+	if class == "" {
+		return "", 0
+	}
+	value, _ := config.ParseDSCP(class)
+	return class, value
+}
+
+// dscpControl returns a dialer control function marking the socket with a DSCP value
+func dscpControl(dscp int) controlFunc {
+	return func(network, address string, c syscall.RawConn) error {
+		if err := sockopt.SetTOS(network, c, dscp<<2); err != nil {
+			return fmt.Errorf("failed to set DSCP %d: %w", dscp, err)
+		}
+		return nil
+	}
+}
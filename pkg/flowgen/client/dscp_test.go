@@ -0,0 +1,32 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDSCPMarks(t *testing.T) {
+	assert.Nil(t, newDSCPMarks(&config.ClientConfig{}))
+
+	tests := []struct {
+		name      string
+		cfg       config.ClientConfig
+		port      int
+		wantClass string
+		wantValue int
+	}{
+		{"global class", config.ClientConfig{DSCP: "AF41"}, 8080, "af41", 34},
+		{"port override", config.ClientConfig{DSCP: "af41", DSCPPorts: "53=ef"}, 53, "ef", 46},
+		{"numeric port class", config.ClientConfig{DSCPPorts: "53=10"}, 53, "10", 10},
+		{"unlisted port without global class", config.ClientConfig{DSCPPorts: "53=ef"}, 8080, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			class, value := newDSCPMarks(&tt.cfg).class(tt.port)
+			assert.Equal(t, tt.wantClass, class)
+			assert.Equal(t, tt.wantValue, value)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"syscall"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
+)
+
+// egressBinding pins all client connections to a local address and/or network interface, so the traffic
+// leaves a multi-homed host through a chosen NIC
+type egressBinding struct {
+	addr  netip.Addr
+	iface string
+}
+
+// newEgressBinding returns the binding configured with local_address and interface, or nil if connections
+// leave through the interface and address chosen by the routing table
+func newEgressBinding(c *config.ClientConfig) (*egressBinding, error) {
+	if c.LocalAddress == "" && c.Interface == "" {
+		return nil, nil
+	}
+	b := &egressBinding{iface: c.Interface}
+	if c.LocalAddress != "" {
+		// The address was checked when the configuration was validated
+		b.addr, _ = netip.ParseAddr(c.LocalAddress)
+	}
+	if b.iface != "" {
+		if _, err := net.InterfaceByName(b.iface); err != nil {
+			return nil, fmt.Errorf("unknown interface %q: %w", b.iface, err)
+		}
+	}
+	return b, nil
+}
+
+// dialer returns a dialer for network ("tcp" or "udp") bound to the local address and interface, or a plain
+// dialer if b is nil
+func (b *egressBinding) dialer(network string) *net.Dialer {
+	d := &net.Dialer{}
+	if b == nil {
+		return d
+	}
+	if b.addr.IsValid() {
+		d.LocalAddr = bindAddr(network, b.addr)
+	}
+	if b.iface != "" {
+		d.Control = bindToDeviceControl(b.iface)
+	}
+	return d
+}
+
+// String describes the binding for the logs
+func (b *egressBinding) String() string {
+	var parts []string
+	if b.addr.IsValid() {
+		parts = append(parts, "address "+b.addr.String())
+	}
+	if b.iface != "" {
+		parts = append(parts, "interface "+b.iface)
+	}
+	return strings.Join(parts, " on ")
+}
+
+// bindAddr returns addr as the local address of a dialer for network ("tcp" or "udp")
+func bindAddr(network string, addr netip.Addr) net.Addr {
+	if strings.HasPrefix(network, "udp") {
+		return &net.UDPAddr{IP: addr.AsSlice()}
+	}
+	return &net.TCPAddr{IP: addr.AsSlice()}
+}
+
+// bindToDeviceControl returns a dialer control function binding the socket to the interface
+func bindToDeviceControl(iface string) controlFunc {
+	return func(network, address string, c syscall.RawConn) error {
+		if err := sockopt.BindToDevice(c, iface); err != nil {
+			return fmt.Errorf("failed to bind to interface %s: %w", iface, err)
+		}
+		return nil
+	}
+}
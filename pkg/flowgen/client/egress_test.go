@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewEgressBinding(t *testing.T) {
+	b, err := newEgressBinding(&config.ClientConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, b)
+	assert.Equal(t, &net.Dialer{}, b.dialer("tcp"))
+
+	b, err = newEgressBinding(&config.ClientConfig{LocalAddress: "127.0.0.1"})
+	require.NoError(t, err)
+	assert.Equal(t, "address 127.0.0.1", b.String())
+	assert.Equal(t, &net.UDPAddr{IP: net.ParseIP("127.0.0.1").To4()}, b.dialer("udp").LocalAddr)
+	assert.Nil(t, b.dialer("tcp").Control)
+
+	_, err = newEgressBinding(&config.ClientConfig{Interface: "does-not-exist0"})
+	assert.ErrorContains(t, err, "unknown interface")
+}
+
+func TestEgressFlowDialer(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("SO_BINDTODEVICE not supported on this platform")
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	oldEgress := egress
+	defer func() { egress = oldEgress }()
+	egress, err = newEgressBinding(&config.ClientConfig{LocalAddress: "127.0.0.3", Interface: "lo"})
+	require.NoError(t, err)
+
+	conn, err := flowDialer("tcp", transport.FlowInfo{DSCP: 46}).DialContext(context.Background(), "tcp", ln.Addr().String())
+	if err != nil {
+		t.Skipf("binding to lo not permitted: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	assert.Equal(t, "127.0.0.3", conn.LocalAddr().(*net.TCPAddr).IP.String())
+
+	// Pooled connections are bound as well
+	pooled, err := newConnPool(1).dialTCP(ln.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = pooled.Close() }()
+	assert.Equal(t, "127.0.0.3", pooled.LocalAddr().(*net.TCPAddr).IP.String())
+}
@@ -0,0 +1,156 @@
+package client
+
+import (
+	"sync"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/hooks"
+)
+
+// failoverMinFlows is the number of flows to the primary that must have finished within the failover
+// window before its failure rate can trigger a switchover, so a single early failure does not
+const failoverMinFlows = 5
+
+// failoverReport describes the failover of a run for the run status and results
+type failoverReport struct {
+	Primary string `json:"primary"`
+	Backup  string `json:"backup"`
+	// Active is the target new flows are sent to
+	Active string `json:"active"`
+	// SwitchoverSeconds is when traffic was switched to the backup, since the start of the run
+	SwitchoverSeconds *float64 `json:"switchover_seconds,omitempty"`
+	// FailureRate is the percentage of failed flows to the primary within the window that triggered the switchover
+	FailureRate float64 `json:"failure_rate,omitempty"`
+	// DetectionSeconds is the time from the first failure within that window to the switchover
+	DetectionSeconds float64 `json:"detection_seconds,omitempty"`
+	// PrimaryFailedFlows is the number of flows to the primary that failed, including those still running
+	// at the switchover
+	PrimaryFailedFlows uint64 `json:"primary_failed_flows"`
+	// RecoverySeconds is the time from the switchover to the first flow completed by the backup
+	RecoverySeconds *float64 `json:"recovery_seconds,omitempty"`
+}
+
+// flowResult is the outcome of a flow to the primary within the failover window
+type flowResult struct {
+	at     time.Time
+	failed bool
+}
+
+// failoverMonitor sends flows to the primary target and switches them to the backup target for the rest
+// of the run once the failure rate of the primary within the failover window crosses the threshold
+type failoverMonitor struct {
+	mu        sync.Mutex
+	primary   string
+	backup    string
+	threshold float64
+	window    time.Duration
+	start     time.Time
+	results   []flowResult
+	// firstBackupFlow is the ID of the first flow sent to the backup, 0 until the switchover
+	firstBackupFlow uint64
+	switched        bool
+	report          failoverReport
+}
+
+// newFailoverMonitor returns the failover from server to backup_server, or nil if no backup is configured
+func newFailoverMonitor(c *config.ClientConfig, start time.Time) *failoverMonitor {
+	if c.BackupServer == "" {
+		return nil
+	}
+	return &failoverMonitor{
+		primary:   c.Server,
+		backup:    c.BackupServer,
+		threshold: c.FailoverThreshold,
+		window:    seconds(c.FailoverWindow),
+		start:     start,
+		report:    failoverReport{Primary: c.Server, Backup: c.BackupServer, Active: c.Server},
+	}
+}
+
+// target returns the target of a flow. Flows are launched in the order of their IDs, so the flows
+// before the first one sent to the backup went to the primary.
+func (m *failoverMonitor) target(flowID uint64) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.switched {
+		return m.primary
+	}
+	if m.firstBackupFlow == 0 {
+		m.firstBackupFlow = flowID
+	}
+	return m.backup
+}
+
+// hooks returns the flow hooks feeding the outcomes of flows to the monitor
+func (m *failoverMonitor) hooks() hooks.Hooks {
+	return hooks.Hooks{
+		OnFlowCompleted: func(e hooks.Event) { m.observe(e.FlowID, e.Time, false) },
+		OnFlowFailed:    func(e hooks.Event) { m.observe(e.FlowID, e.Time, true) },
+	}
+}
+
+// observe records the outcome of a flow finished at the given time and switches to the backup if the
+// primary is failing
+func (m *failoverMonitor) observe(flowID uint64, at time.Time, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.firstBackupFlow != 0 && flowID >= m.firstBackupFlow {
+		if !failed && m.report.RecoverySeconds == nil {
+			recovery := at.Sub(m.start).Seconds() - *m.report.SwitchoverSeconds
+			m.report.RecoverySeconds = &recovery
+			logging.Logger.Infof("Backup %s completed its first flow %.3fs after the switchover", m.backup, recovery)
+		}
+		return
+	}
+	if failed {
+		m.report.PrimaryFailedFlows++
+	}
+	if m.switched {
+		return
+	}
+
+	m.results = append(m.results, flowResult{at: at, failed: failed})
+	i := 0
+	for i < len(m.results) && at.Sub(m.results[i].at) > m.window {
+		i++
+	}
+	m.results = m.results[i:]
+	if len(m.results) < failoverMinFlows {
+		return
+	}
+	var failures int
+	var firstFailure time.Time
+	for _, r := range m.results {
+		if r.failed {
+			if failures == 0 {
+				firstFailure = r.at
+			}
+			failures++
+		}
+	}
+	rate := float64(failures) / float64(len(m.results)) * 100
+	if failures == 0 || rate < m.threshold {
+		return
+	}
+
+	m.switched = true
+	switchover := at.Sub(m.start).Seconds()
+	m.report.Active = m.backup
+	m.report.SwitchoverSeconds = &switchover
+	m.report.FailureRate = rate
+	m.report.DetectionSeconds = at.Sub(firstFailure).Seconds()
+	logging.Logger.Warnf("%.1f%% of the last %d flows to %s failed, switching traffic to backup %s", rate, len(m.results), m.primary, m.backup)
+}
+
+// status returns the report of the failover, nil if no backup is configured
+func (m *failoverMonitor) status() *failoverReport {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	report := m.report
+	return &report
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailoverMonitor(t *testing.T) {
+	logging.InitLogger("json", "error")
+	start := time.Unix(1000, 0)
+	assert.Nil(t, newFailoverMonitor(&config.ClientConfig{Server: "primary"}, start))
+
+	m := newFailoverMonitor(&config.ClientConfig{Server: "primary", BackupServer: "backup", FailoverThreshold: 50, FailoverWindow: 10}, start)
+	require.NotNil(t, m)
+	at := func(s float64) time.Time { return start.Add(seconds(s)) }
+
+	// Flows 1-4 succeed, the failures of flows 5-8 push the rate within the last 10s to 50%
+	for id := uint64(1); id <= 8; id++ {
+		assert.Equal(t, "primary", m.target(id))
+	}
+	for id := uint64(1); id <= 4; id++ {
+		m.observe(id, at(float64(id)), false)
+	}
+	m.observe(5, at(5), true)
+	m.observe(6, at(6), true)
+	m.observe(7, at(7), true)
+	assert.Equal(t, "primary", m.status().Active, "3 of 7 flows failed")
+	m.observe(8, at(8), true)
+
+	report := m.status()
+	assert.Equal(t, "backup", report.Active)
+	require.NotNil(t, report.SwitchoverSeconds)
+	assert.Equal(t, 8.0, *report.SwitchoverSeconds)
+	assert.Equal(t, 50.0, report.FailureRate)
+	assert.Equal(t, 3.0, report.DetectionSeconds)
+	assert.Nil(t, report.RecoverySeconds)
+
+	// Flows to the primary still running at the switchover are counted, later flows go to the backup
+	assert.Equal(t, "backup", m.target(9))
+	assert.Equal(t, "backup", m.target(10))
+	m.observe(9, at(9), true)
+	m.observe(10, at(9.5), false)
+	report = m.status()
+	assert.Equal(t, uint64(4), report.PrimaryFailedFlows)
+	require.NotNil(t, report.RecoverySeconds)
+	assert.Equal(t, 1.5, *report.RecoverySeconds)
+}
+
+func TestFailoverMonitorWindow(t *testing.T) {
+	logging.InitLogger("json", "error")
+	start := time.Unix(1000, 0)
+	m := newFailoverMonitor(&config.ClientConfig{Server: "primary", BackupServer: "backup", FailoverThreshold: 50, FailoverWindow: 10}, start)
+
+	// Failures that left the window no longer count: without them 4 of 8 flows would have failed
+	for id := uint64(1); id <= 4; id++ {
+		m.observe(id, start, true)
+	}
+	for id := uint64(5); id <= 9; id++ {
+		m.observe(id, start.Add(20*time.Second), false)
+	}
+	assert.Equal(t, "primary", m.status().Active)
+	assert.Equal(t, uint64(4), m.status().PrimaryFailedFlows)
+
+	// Too few flows within the window do not trigger a switchover either
+	m = newFailoverMonitor(&config.ClientConfig{Server: "primary", BackupServer: "backup", FailoverThreshold: 50, FailoverWindow: 10}, start)
+	for id := uint64(1); id < failoverMinFlows; id++ {
+		m.observe(id, start, true)
+	}
+	assert.Equal(t, "primary", m.status().Active)
+	m.observe(failoverMinFlows, start, true)
+	assert.Equal(t, "backup", m.status().Active)
+}
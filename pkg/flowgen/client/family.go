@@ -0,0 +1,166 @@
+package client
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"time"
+)
+
+// Address families flows to a dual-stack server are dialed with
+const (
+	familyAny        = "any"
+	familyIPv4       = "ipv4"
+	familyIPv6       = "ipv6"
+	familyPreferIPv4 = "prefer-ipv4"
+	familyPreferIPv6 = "prefer-ipv6"
+)
+
+// dialFamily connects to addr over network ("tcp" or "udp") with d in the configured address family
+func (p *dialPolicy) dialFamily(ctx context.Context, d *net.Dialer, network, addr string) (net.Conn, error) {
+	switch p.family {
+	case familyIPv4:
+		return d.DialContext(ctx, network+"4", addr)
+	case familyIPv6:
+		return d.DialContext(ctx, network+"6", addr)
+	case familyPreferIPv4, familyPreferIPv6:
+		return p.dialPreferred(ctx, d, network, addr)
+	}
+	// The standard library races the families of TCP flows itself, with the first resolved one as primary
+	if p.fallbackDelay > 0 {
+		d.FallbackDelay = p.fallbackDelay
+	} else {
+		d.FallbackDelay = -1
+	}
+	return d.DialContext(ctx, network, addr)
+}
+
+// dialPreferred resolves addr and dials the addresses of the preferred family first. The other family is
+// raced after the fallback delay, or once all preferred addresses failed.
+func (p *dialPolicy) dialPreferred(ctx context.Context, d *net.Dialer, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	var primary, fallback []netip.Addr
+	for _, ip := range ips {
+		if ip.Unmap().Is4() == (p.family == familyPreferIPv4) {
+			primary = append(primary, ip.Unmap())
+		} else {
+			fallback = append(fallback, ip.Unmap())
+		}
+	}
+	if len(primary) == 0 {
+		primary, fallback = fallback, nil
+	}
+	return raceDial(ctx, d, network, port, primary, fallback, p.fallbackDelay)
+}
+
+// dialResult is the outcome of dialing the addresses of one family
+type dialResult struct {
+	conn    net.Conn
+	err     error
+	primary bool
+}
+
+// raceDial dials the primary addresses one after another and starts on the fallback addresses after delay
+// or once the primary ones failed, whichever comes first. The first connection established wins, the other
+// dial is canceled.
+func raceDial(ctx context.Context, d *net.Dialer, network, port string, primary, fallback []netip.Addr, delay time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan dialResult, 2)
+	start := func(addrs []netip.Addr, isPrimary bool) {
+		go func() {
+			conn, err := dialSerial(ctx, d, network, port, addrs)
+			results <- dialResult{conn: conn, err: err, primary: isPrimary}
+		}()
+	}
+	start(primary, true)
+	pending := 1
+
+	var fallbackTimer <-chan time.Time
+	if len(fallback) > 0 && delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		fallbackTimer = timer.C
+	}
+	startFallback := func() {
+		if len(fallback) > 0 {
+			start(fallback, false)
+			fallback = nil
+			pending++
+		}
+		fallbackTimer = nil
+	}
+
+	var firstErr error
+	for {
+		select {
+		case <-fallbackTimer:
+			startFallback()
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				// Connections the losing dial still establishes are closed
+				go func(pending int) {
+					for range pending {
+						if r := <-results; r.conn != nil {
+							_ = r.conn.Close()
+						}
+					}
+				}(pending)
+				return r.conn, nil
+			}
+			if firstErr == nil || r.primary {
+				firstErr = r.err
+			}
+			if r.primary {
+				startFallback()
+			}
+			if pending == 0 {
+				return nil, firstErr
+			}
+		}
+	}
+}
+
+// dialSerial dials the addresses in order and returns the first connection established
+func dialSerial(ctx context.Context, d *net.Dialer, network, port string, addrs []netip.Addr) (net.Conn, error) {
+	var firstErr error
+	for _, ip := range addrs {
+		conn, err := d.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, firstErr
+}
+
+// addrFamily returns the address family of a socket address, or an empty string if it is unknown
+func addrFamily(addr net.Addr) string {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		if a.IP.To4() != nil {
+			return familyIPv4
+		}
+		return familyIPv6
+	case *net.UDPAddr:
+		if a.IP.To4() != nil {
+			return familyIPv4
+		}
+		return familyIPv6
+	}
+	return ""
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dualStackListeners listens on the same TCP port on 127.0.0.1 and ::1 and returns the port
+func dualStackListeners(t *testing.T) string {
+	ln4, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln4.Close() })
+	port := strconv.Itoa(ln4.Addr().(*net.TCPAddr).Port)
+	ln6, err := net.Listen("tcp6", net.JoinHostPort("::1", port))
+	if err != nil {
+		t.Skipf("IPv6 loopback not available: %v", err)
+	}
+	t.Cleanup(func() { _ = ln6.Close() })
+	for _, ln := range []net.Listener{ln4, ln6} {
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				_ = conn.Close()
+			}
+		}()
+	}
+	return port
+}
+
+func TestDialPolicyFamilies(t *testing.T) {
+	port := dualStackListeners(t)
+	tests := []struct {
+		family  string
+		addr    string
+		want    string
+		wantErr bool
+	}{
+		{familyAny, "127.0.0.1", familyIPv4, false},
+		{familyIPv4, "127.0.0.1", familyIPv4, false},
+		{familyIPv6, "::1", familyIPv6, false},
+		{familyIPv6, "127.0.0.1", "", true},
+		{familyPreferIPv6, "127.0.0.1", familyIPv4, false},
+		{familyPreferIPv4, "::1", familyIPv6, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.family+" "+tt.addr, func(t *testing.T) {
+			p := newDialPolicy(&config.ClientConfig{AddressFamily: tt.family, HappyEyeballsDelay: 0.3})
+			conn, err := p.dial(context.Background(), &net.Dialer{}, "tcp", net.JoinHostPort(tt.addr, port))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer func() { _ = conn.Close() }()
+			assert.Equal(t, tt.want, addrFamily(conn.RemoteAddr()))
+		})
+	}
+}
+
+func TestRaceDial(t *testing.T) {
+	port := dualStackListeners(t)
+	v4, v6 := netip.MustParseAddr("127.0.0.1"), netip.MustParseAddr("::1")
+
+	// slowIPv6 delays connecting IPv6 sockets, like a broken IPv6 path
+	slowIPv6 := &net.Dialer{Control: func(network, address string, c syscall.RawConn) error {
+		if strings.HasSuffix(network, "6") {
+			time.Sleep(300 * time.Millisecond)
+		}
+		return nil
+	}}
+
+	// The preferred family wins if it connects
+	conn, err := raceDial(context.Background(), &net.Dialer{}, "tcp", port, []netip.Addr{v6}, []netip.Addr{v4}, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, familyIPv6, addrFamily(conn.RemoteAddr()))
+	_ = conn.Close()
+
+	// A slow preferred family loses the race once the fallback delay passed
+	start := time.Now()
+	conn, err = raceDial(context.Background(), slowIPv6, "tcp", port, []netip.Addr{v6}, []netip.Addr{v4}, 20*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, familyIPv4, addrFamily(conn.RemoteAddr()))
+	assert.Less(t, time.Since(start), 250*time.Millisecond)
+	_ = conn.Close()
+
+	// Without a delay the fallback is only tried once the preferred family failed
+	closed := netip.MustParseAddr("127.0.0.2")
+	conn, err = raceDial(context.Background(), &net.Dialer{}, "tcp", port, []netip.Addr{closed}, []netip.Addr{v6}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, familyIPv6, addrFamily(conn.RemoteAddr()))
+	_ = conn.Close()
+
+	// The error of the preferred family is reported if all addresses fail
+	_, err = raceDial(context.Background(), &net.Dialer{}, "tcp", "1", []netip.Addr{v4}, []netip.Addr{v6}, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "127.0.0.1")
+}
+
+func TestAddrFamily(t *testing.T) {
+	assert.Equal(t, familyIPv4, addrFamily(&net.TCPAddr{IP: net.ParseIP("10.0.0.1")}))
+	assert.Equal(t, familyIPv4, addrFamily(&net.UDPAddr{IP: net.ParseIP("::ffff:10.0.0.1")}))
+	assert.Equal(t, familyIPv6, addrFamily(&net.UDPAddr{IP: net.ParseIP("fd00::1")}))
+	assert.Equal(t, "", addrFamily(nil))
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// Reasons a flow failed, used as the reason label of flow_errors_total
+const (
+	// flowErrorRefused is a connection or datagram refused by the server host (RST or ICMP port unreachable)
+	flowErrorRefused = "refused"
+	// flowErrorTimeout is a connect, write or read that timed out
+	flowErrorTimeout = "timeout"
+	// flowErrorReset is a connection reset by the server or a middlebox
+	flowErrorReset = "reset"
+	// flowErrorClosed is a connection the server closed before echoing everything sent
+	flowErrorClosed = "closed"
+	// flowErrorDNS is a server name that could not be resolved
+	flowErrorDNS = "dns"
+	// flowErrorUnreachable is a server host or network that cannot be reached
+	flowErrorUnreachable = "unreachable"
+	// flowErrorProhibited is a packet a firewall or policy rejected with an ICMP administratively prohibited
+	flowErrorProhibited = "prohibited"
+	// flowErrorMismatch is a response that did not echo the bytes sent
+	flowErrorMismatch = "mismatch"
+)
+
+// Operations of a flow, used as the reason of errors that match no other reason
+const (
+	flowOpDial  = "dial"
+	flowOpWrite = "write"
+	flowOpRead  = "read"
+)
+
+// flowErrorReason classifies an error of the given flow operation (dial, write or read). Errors of no
+// known kind are reported as the operation itself.
+func flowErrorReason(err error, op string) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var icmpErr *icmpError
+	switch {
+	case errors.As(err, &dnsErr) && !dnsErr.IsTimeout:
+		return flowErrorDNS
+	case errors.As(err, &icmpErr) && icmpErr.kind == icmpAdminProhibited:
+		return flowErrorProhibited
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return flowErrorRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE), errors.Is(err, syscall.ECONNABORTED):
+		return flowErrorReset
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return flowErrorUnreachable
+	case errors.Is(err, syscall.ETIMEDOUT), errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return flowErrorTimeout
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return flowErrorClosed
+	default:
+		return op
+	}
+}
+
+// recordFlowError counts an error of a flow operation by its reason, and by its type if an ICMP error
+// caused it. Flows canceled because the run ended or the flow was preempted did not fail and are not counted.
+func recordFlowError(protocol, port string, err error, op string) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	mc.IncFlowErrors(protocol, port, flowErrorReason(err, op))
+	var icmpErr *icmpError
+	if errors.As(err, &icmpErr) {
+		mc.IncICMPErrors(protocol, port, icmpErr.kind)
+	}
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlowErrorReason(t *testing.T) {
+	syscallErr := func(op string, errno syscall.Errno) error {
+		return &net.OpError{Op: op, Net: "tcp", Err: os.NewSyscallError(op, errno)}
+	}
+	tests := []struct {
+		name string
+		err  error
+		op   string
+		want string
+	}{
+		{"refused", syscallErr("connect", syscall.ECONNREFUSED), flowOpDial, flowErrorRefused},
+		{"ICMP port unreachable", syscallErr("read", syscall.ECONNREFUSED), flowOpRead, flowErrorRefused},
+		{"reset", syscallErr("read", syscall.ECONNRESET), flowOpRead, flowErrorReset},
+		{"broken pipe", syscallErr("write", syscall.EPIPE), flowOpWrite, flowErrorReset},
+		{"host unreachable", syscallErr("connect", syscall.EHOSTUNREACH), flowOpDial, flowErrorUnreachable},
+		{"network unreachable", syscallErr("connect", syscall.ENETUNREACH), flowOpDial, flowErrorUnreachable},
+		{"connect timeout", syscallErr("connect", syscall.ETIMEDOUT), flowOpDial, flowErrorTimeout},
+		{"deadline", &net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}, flowOpRead, flowErrorTimeout},
+		{"context deadline", fmt.Errorf("dial: %w", context.DeadlineExceeded), flowOpDial, flowErrorTimeout},
+		{"unknown host", &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "echo.invalid", IsNotFound: true}}, flowOpDial, flowErrorDNS},
+		{"resolver timeout", &net.DNSError{Err: "i/o timeout", Name: "echo.example.com", IsTimeout: true}, flowOpDial, flowErrorTimeout},
+		{"admin prohibited", &icmpError{kind: icmpAdminProhibited, err: syscallErr("read", syscall.EHOSTUNREACH)}, flowOpRead, flowErrorProhibited},
+		{"ICMP host unreachable", &icmpError{kind: icmpHostUnreachable, err: syscallErr("read", syscall.EHOSTUNREACH)}, flowOpRead, flowErrorUnreachable},
+		{"EOF", io.EOF, flowOpRead, flowErrorClosed},
+		{"other dial error", errors.New("no suitable address"), flowOpDial, flowOpDial},
+		{"other write error", errors.New("payload size 9000 exceeds MTU 1500"), flowOpWrite, flowOpWrite},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, flowErrorReason(tt.err, tt.op))
+		})
+	}
+}
+
+func TestRecordFlowError(t *testing.T) {
+	oldMc := mc
+	mc = metrics.NewMetricsCollector()
+	defer func() { mc = oldMc }()
+
+	// Dialing a closed port is refused
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+	_, err = net.Dial("tcp", addr)
+	require.Error(t, err)
+	recordFlowError("tcp", "8080", err, flowOpDial)
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.FlowErrors.WithLabelValues("tcp", "8080", flowErrorRefused)))
+
+	// Canceled flows did not fail
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	require.Error(t, err)
+	recordFlowError("tcp", "8080", err, flowOpDial)
+	assert.Equal(t, float64(0), testutil.ToFloat64(mc.FlowErrors.WithLabelValues("tcp", "8080", flowOpDial)))
+}
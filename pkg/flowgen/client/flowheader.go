@@ -0,0 +1,32 @@
+package client
+
+import (
+	"math/rand/v2"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/flowheader"
+)
+
+// flowHeaders stamps the flow header on the payloads of flows, so the server can detect duplicate flows
+// and replayed datagrams
+type flowHeaders struct {
+	// runID tells the flows of this client process apart from those of other clients and earlier runs
+	runID uint64
+}
+
+// newFlowHeaders returns the flow headers enabled with flow_header, or nil if payloads are sent as is
+func newFlowHeaders(c *config.ClientConfig) *flowHeaders {
+	if !c.FlowHeader {
+		return nil
+	}
+	return &flowHeaders{runID: rand.Uint64()}
+}
+
+// payload returns a copy of the payload of a flow starting with its flow header, grown to the header size
+// if it is smaller. The sequence number is updated per request with flowheader.SetSeq.
+func (h *flowHeaders) payload(payload []byte, flowID uint64) []byte {
+	b := make([]byte, max(len(payload), flowheader.Size))
+	copy(b, payload)
+	flowheader.Header{RunID: h.runID, FlowID: flowID}.Put(b)
+	return b
+}
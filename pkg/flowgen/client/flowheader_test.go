@@ -0,0 +1,44 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/flowheader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlowHeaders(t *testing.T) {
+	assert.Nil(t, newFlowHeaders(&config.ClientConfig{}))
+
+	h := newFlowHeaders(&config.ClientConfig{FlowHeader: true})
+	require.NotNil(t, h)
+	other := newFlowHeaders(&config.ClientConfig{FlowHeader: true})
+	assert.NotEqual(t, h.runID, other.runID)
+
+	tests := []struct {
+		name    string
+		payload []byte
+		wantLen int
+	}{
+		{"empty payload", nil, flowheader.Size},
+		{"small payload", []byte("hello"), flowheader.Size},
+		{"large payload", payloadCache[:1000], 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := h.payload(tt.payload, 42)
+			assert.Len(t, b, tt.wantLen)
+			got, ok := flowheader.Parse(b)
+			require.True(t, ok)
+			assert.Equal(t, flowheader.Header{RunID: h.runID, FlowID: 42}, got)
+			if tt.wantLen > flowheader.Size {
+				assert.Equal(t, tt.payload[flowheader.Size:], b[flowheader.Size:])
+			}
+		})
+	}
+	// The shared payload cache is left untouched
+	_, ok := flowheader.Parse(payloadCache)
+	assert.False(t, ok)
+}
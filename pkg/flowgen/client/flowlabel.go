@@ -0,0 +1,71 @@
+package client
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"strings"
+	"syscall"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
+)
+
+// flowLabels picks the IPv6 flow label of each flow, a fixed one or a random one per flow
+type flowLabels struct {
+	fixed  uint32
+	random bool
+}
+
+// newFlowLabels returns the flow labels configured with flow_label, or nil if the kernel chooses them
+func newFlowLabels(c *config.ClientConfig) *flowLabels {
+	if c.FlowLabel == "" {
+		return nil
+	}
+	// The label was checked when the configuration was validated
+	fixed, random, _ := config.ParseFlowLabel(c.FlowLabel)
+	return &flowLabels{fixed: fixed, random: random}
+}
+
+// label returns the flow label of the next flow
+func (l *flowLabels) label(src *rand.Rand) uint32 {
+	if l.random {
+		return src.Uint32N(0xfffff) + 1
+	}
+	return l.fixed
+}
+
+// flowLabelControl returns a dialer control function connecting IPv6 TCP sockets with the flow label, IPv4
+// sockets are left alone. It must run last, as the socket is connected when it returns.
+func flowLabelControl(label uint32) controlFunc {
+	return func(network, address string, c syscall.RawConn) error {
+		if !strings.HasSuffix(network, "6") {
+			return nil
+		}
+		dst, err := netip.ParseAddrPort(address)
+		if err != nil {
+			return err
+		}
+		if err := sockopt.ConnectFlowLabel(c, dst, label); err != nil {
+			return fmt.Errorf("failed to set flow label %#x: %w", label, err)
+		}
+		return nil
+	}
+}
+
+// applyFlowLabel reconnects a dialed IPv6 UDP socket with the flow label, IPv4 sockets are left alone
+func applyFlowLabel(conn *net.UDPConn, label uint32) error {
+	dst := conn.RemoteAddr().(*net.UDPAddr).AddrPort()
+	if !dst.Addr().Is6() || dst.Addr().Is4In6() {
+		return nil
+	}
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	if err := sockopt.ConnectFlowLabel(raw, dst, label); err != nil {
+		return fmt.Errorf("failed to set flow label %#x: %w", label, err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"math/rand/v2"
+	"net"
+	"runtime"
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlowLabels(t *testing.T) {
+	assert.Nil(t, newFlowLabels(&config.ClientConfig{}))
+
+	src := rand.New(rand.NewPCG(1, 2))
+	fixed := newFlowLabels(&config.ClientConfig{FlowLabel: "0x12345"})
+	assert.Equal(t, uint32(0x12345), fixed.label(src))
+	assert.Equal(t, uint32(0x12345), fixed.label(src))
+
+	random := newFlowLabels(&config.ClientConfig{FlowLabel: "random"})
+	seen := make(map[uint32]bool)
+	for range 100 {
+		label := random.label(src)
+		assert.True(t, label >= 1 && label <= 0xfffff, "label %#x out of range", label)
+		seen[label] = true
+	}
+	assert.Greater(t, len(seen), 90)
+}
+
+func TestFlowLabelDial(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("flow labels not supported on this platform")
+	}
+	ln, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 not available: %v", err)
+	}
+	defer func() { _ = ln.Close() }()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	flow := transport.FlowInfo{DSCP: 46, FlowLabel: 0xabcde}
+	conn, err := flowDialer("tcp", flow).DialContext(context.Background(), "tcp", ln.Addr().String())
+	require.NoError(t, err)
+	_ = conn.Close()
+
+	// IPv4 flows are sent without a label
+	ln4, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln4.Close() }()
+	conn, err = flowDialer("tcp", flow).DialContext(context.Background(), "tcp", ln4.Addr().String())
+	require.NoError(t, err)
+	_ = conn.Close()
+
+	udp := newUDPTransport(transport.FlowInfo{FlowLabel: 7})
+	require.NoError(t, udp.Dial(context.Background(), "[::1]:9"))
+	assert.NoError(t, udp.Close())
+}
@@ -0,0 +1,156 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/hooks"
+)
+
+// flowLogStdout is the flow log path that writes to stdout instead of a file
+const flowLogStdout = "-"
+
+// flowLogRecord is a single line of the flow log
+type flowLogRecord struct {
+	FlowID          uint64  `json:"flow_id"`
+	Start           string  `json:"start"`
+	End             string  `json:"end"`
+	Protocol        string  `json:"protocol"`
+	SrcIP           string  `json:"src_ip,omitempty"`
+	SrcPort         int     `json:"src_port,omitempty"`
+	DstIP           string  `json:"dst_ip,omitempty"`
+	DstPort         int     `json:"dst_port"`
+	FlowLabel       uint32  `json:"flow_label,omitempty"`
+	Requests        uint64  `json:"requests"`
+	BytesSent       uint64  `json:"bytes_sent"`
+	BytesReceived   uint64  `json:"bytes_received"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	LatencySeconds  float64 `json:"latency_seconds"`
+	Result          string  `json:"result"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// flowLogHeader is the first line of a flow log when experiment metadata is configured, telling it apart
+// from the flow records by its type
+type flowLogHeader struct {
+	Type     string            `json:"type"`
+	Time     string            `json:"time"`
+	Scenario string            `json:"scenario"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// flowLogWriter writes one JSON line per finished flow. It is fed by the flow hooks, so lines are
+// written sequentially off the flow hot path.
+type flowLogWriter struct {
+	w      *bufio.Writer
+	closer io.Closer
+	enc    *json.Encoder
+	failed bool
+}
+
+// newFlowLogWriter creates a flow log writing to the given file, or to stdout if the path is "-"
+func newFlowLogWriter(path string) (*flowLogWriter, error) {
+	var out io.Writer = os.Stdout
+	var closer io.Closer
+	if path != flowLogStdout {
+		// #nosec G304 - the flow log path is chosen by the operator
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		out, closer = f, f
+	}
+	w := bufio.NewWriter(out)
+	return &flowLogWriter{w: w, closer: closer, enc: json.NewEncoder(w)}, nil
+}
+
+// hooks returns the flow hooks that write finished flows to the log
+func (l *flowLogWriter) hooks() hooks.Hooks {
+	return hooks.Hooks{OnFlowCompleted: l.write, OnFlowFailed: l.write}
+}
+
+// writeHeader writes the header line describing the experiment the flows belong to
+func (l *flowLogWriter) writeHeader(scenario string, metadata map[string]string, now time.Time) {
+	header := flowLogHeader{Type: "header", Time: now.UTC().Format(time.RFC3339Nano), Scenario: scenario, Metadata: metadata}
+	if err := l.enc.Encode(header); err != nil && !l.failed {
+		l.failed = true
+		logging.Logger.Errorf("Failed to write flow log: %v", err)
+	}
+}
+
+// write appends the record of a finished flow, logging only the first write error
+func (l *flowLogWriter) write(e hooks.Event) {
+	if err := l.enc.Encode(newFlowLogRecord(e)); err != nil && !l.failed {
+		l.failed = true
+		logging.Logger.Errorf("Failed to write flow log: %v", err)
+	}
+}
+
+// close flushes the buffered records and closes the file
+func (l *flowLogWriter) close() error {
+	err := l.w.Flush()
+	if l.closer != nil {
+		if closeErr := l.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// newFlowLogRecord converts a finished flow event into a flow log record
+func newFlowLogRecord(e hooks.Event) flowLogRecord {
+	r := flowLogRecord{
+		FlowID:          e.FlowID,
+		Start:           e.Time.Add(-e.Elapsed).UTC().Format(time.RFC3339Nano),
+		End:             e.Time.UTC().Format(time.RFC3339Nano),
+		Protocol:        e.Protocol,
+		DstPort:         e.Port,
+		FlowLabel:       e.FlowLabel,
+		Requests:        e.Requests,
+		BytesSent:       e.BytesSent,
+		BytesReceived:   e.BytesReceived,
+		DurationSeconds: e.Elapsed.Seconds(),
+		LatencySeconds:  e.Latency.Seconds(),
+		Result:          "completed",
+	}
+	r.SrcIP, r.SrcPort = splitAddr(e.LocalAddr)
+	if ip, port := splitAddr(e.RemoteAddr); ip != "" {
+		r.DstIP, r.DstPort = ip, port
+	}
+	if e.Err != nil {
+		r.Result = "failed"
+		r.Error = e.Err.Error()
+	}
+	return r
+}
+
+// splitAddr returns the IP and port of an address, or zero values if it is unknown
+func splitAddr(addr net.Addr) (string, int) {
+	if addr == nil {
+		return "", 0
+	}
+	host, portStr, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return "", 0
+	}
+	port, _ := strconv.Atoi(portStr)
+	return host, port
+}
+
+// closeFlowLog flushes and closes the flow log if one is enabled. It must be called after the flow
+// hooks are stopped.
+func closeFlowLog() {
+	if flowLog == nil {
+		return
+	}
+	if err := flowLog.close(); err != nil {
+		logging.Logger.Errorf("Failed to close flow log: %v", err)
+	}
+	flowLog = nil
+}
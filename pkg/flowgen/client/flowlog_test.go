@@ -0,0 +1,121 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/hooks"
+)
+
+func TestNewFlowLogRecord(t *testing.T) {
+	end := time.Date(2024, 5, 1, 12, 0, 1, 0, time.UTC)
+	tests := []struct {
+		name  string
+		event hooks.Event
+		want  flowLogRecord
+	}{
+		{
+			name: "completed flow",
+			event: hooks.Event{
+				FlowID: 7, Protocol: "tcp", Port: 8080, Time: end, Elapsed: time.Second,
+				Requests: 1, BytesSent: 100, BytesReceived: 100, Latency: 2 * time.Millisecond,
+				LocalAddr:  &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 40000},
+				RemoteAddr: &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 8080},
+			},
+			want: flowLogRecord{
+				FlowID: 7, Start: "2024-05-01T12:00:00Z", End: "2024-05-01T12:00:01Z", Protocol: "tcp",
+				SrcIP: "10.0.0.1", SrcPort: 40000, DstIP: "10.0.0.2", DstPort: 8080,
+				Requests: 1, BytesSent: 100, BytesReceived: 100, DurationSeconds: 1, LatencySeconds: 0.002,
+				Result: "completed",
+			},
+		},
+		{
+			name: "failed flow without connection",
+			event: hooks.Event{
+				FlowID: 8, Protocol: "udp", Port: 53, Time: end, Elapsed: 500 * time.Millisecond,
+				Err: errors.New("connection refused"),
+			},
+			want: flowLogRecord{
+				FlowID: 8, Start: "2024-05-01T12:00:00.5Z", End: "2024-05-01T12:00:01Z", Protocol: "udp",
+				DstPort: 53, DurationSeconds: 0.5, Result: "failed", Error: "connection refused",
+			},
+		},
+		{
+			name: "IPv6 endpoints",
+			event: hooks.Event{
+				FlowID: 9, Protocol: "udp", Port: 53, Time: end,
+				LocalAddr:  &net.UDPAddr{IP: net.ParseIP("::1"), Port: 50000},
+				RemoteAddr: &net.UDPAddr{IP: net.ParseIP("::1"), Port: 53},
+				FlowLabel:  0x12345,
+			},
+			want: flowLogRecord{
+				FlowID: 9, Start: "2024-05-01T12:00:01Z", End: "2024-05-01T12:00:01Z", Protocol: "udp",
+				SrcIP: "::1", SrcPort: 50000, DstIP: "::1", DstPort: 53, FlowLabel: 0x12345, Result: "completed",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, newFlowLogRecord(tt.event))
+		})
+	}
+}
+
+func TestFlowLogWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flows.ndjson")
+	l, err := newFlowLogWriter(path)
+	require.NoError(t, err)
+
+	h := l.hooks()
+	assert.Nil(t, h.OnFlowScheduled)
+	h.OnFlowCompleted(hooks.Event{FlowID: 1, Protocol: "tcp", Port: 8080, Time: time.Now()})
+	h.OnFlowFailed(hooks.Event{FlowID: 2, Protocol: "udp", Port: 53, Time: time.Now(), Err: errors.New("timeout")})
+	require.NoError(t, l.close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	var records []flowLogRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r flowLogRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &r))
+		records = append(records, r)
+	}
+	require.Len(t, records, 2)
+	assert.Equal(t, "completed", records[0].Result)
+	assert.Equal(t, "failed", records[1].Result)
+	assert.Equal(t, "timeout", records[1].Error)
+}
+
+func TestFlowLogWriterHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flows.ndjson")
+	l, err := newFlowLogWriter(path)
+	require.NoError(t, err)
+	l.writeHeader("soak", map[string]string{"owner": "netops"}, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	l.write(hooks.Event{FlowID: 1, Protocol: "tcp", Port: 8080, Time: time.Now()})
+	require.NoError(t, l.close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, `{"type":"header","time":"2024-05-01T12:00:00Z","scenario":"soak","metadata":{"owner":"netops"}}`, lines[0])
+	assert.Contains(t, lines[1], `"flow_id":1`)
+}
+
+func TestNewFlowLogWriterInvalidPath(t *testing.T) {
+	_, err := newFlowLogWriter(filepath.Join(t.TempDir(), "missing", "flows.ndjson"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/transport"
+)
+
+// init registers the half-open TCP transports
+func init() {
+	transport.Register("tcp_handshake", transport.HoldMode, newHalfOpenTransport(false))
+	transport.Register("tcp_noread", transport.HoldMode, newHalfOpenTransport(true))
+}
+
+// halfOpenTransport holds a TCP connection open for the whole flow without ever reading from it, to
+// stress the half-open and idle connection handling of middleboxes. The handshake-only variant sends
+// nothing after the handshake, the no-read variant writes the payload once and leaves the echo unread,
+// so the receive window of the connection closes once the echo fills the receive buffer.
+type halfOpenTransport struct {
+	flow transport.FlowInfo
+	conn net.Conn
+	// ctx is the context of the flow, stop releases the hook unblocking a pending write when it ends
+	ctx  context.Context
+	stop func() bool
+	// write is set if the payload is written, otherwise nothing is sent after the handshake
+	write bool
+}
+
+// newHalfOpenTransport returns the factory of a half-open transport
+func newHalfOpenTransport(write bool) transport.Factory {
+	return func(flow transport.FlowInfo) transport.Transport {
+		return &halfOpenTransport{flow: flow, write: write}
+	}
+}
+
+// Dial completes the TCP handshake with addr. Half-open connections are never pooled or relayed.
+func (t *halfOpenTransport) Dial(ctx context.Context, addr string) error {
+	conn, err := dialing.dial(ctx, flowDialer("tcp", t.flow), "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if err := tuning.conn(conn); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	t.conn, t.ctx = conn, ctx
+	// A write stalled on the closed receive window of the server would outlive the flow otherwise
+	t.stop = context.AfterFunc(ctx, func() { _ = conn.SetWriteDeadline(time.Now()) })
+	sockets.add(t.conn)
+	mc.TCPConnectionsOpenedPerSecond.Inc()
+	logFlowDetail(t.flow.ID, t.flow.Protocol, t.flow.Sampled, "Half-open TCP connection %s -> %s established", t.conn.LocalAddr(), t.conn.RemoteAddr())
+	return nil
+}
+
+// Send writes the payload if the transport writes one, otherwise it sends nothing. A write still
+// pending when the flow ends is not an error, the server stopped reading because its echo went unread.
+func (t *halfOpenTransport) Send(payload []byte) (int, error) {
+	if !t.write {
+		return 0, nil
+	}
+	n, err := t.conn.Write(payload)
+	if err != nil && t.ctx.Err() != nil {
+		return n, nil
+	}
+	return n, err
+}
+
+// Recv reads from the connection, generateFlow never calls it for hold mode transports
+func (t *halfOpenTransport) Recv(buf []byte) (int, error) {
+	return t.conn.Read(buf)
+}
+
+// Close closes the connection, discarding whatever the server echoed
+func (t *halfOpenTransport) Close() error {
+	t.stop()
+	sockets.remove(t.conn)
+	return t.conn.Close()
+}
+
+// RemoteAddr returns the address of the server
+func (t *halfOpenTransport) RemoteAddr() net.Addr {
+	return t.conn.RemoteAddr()
+}
+
+// LocalAddr returns the local address of the connection
+func (t *halfOpenTransport) LocalAddr() net.Addr {
+	return t.conn.LocalAddr()
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/transport"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoListener echoes every connection and reports the bytes it received from each
+func echoListener(t *testing.T) (int, <-chan int) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+	received := make(chan int, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		n, _ := io.Copy(conn, conn)
+		received <- int(n)
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, received
+}
+
+func TestGenerateFlowHalfOpen(t *testing.T) {
+	logging.InitLogger("json", "error")
+
+	oldCfg, oldMc := cfg, mc
+	cfg = &config.ClientConfig{PayloadSize: 64}
+	mc = metrics.NewMetricsCollector()
+	defer func() { cfg, mc = oldCfg, oldMc }()
+
+	tests := []struct {
+		protocol string
+		wantSent int
+	}{
+		{"tcp_handshake", 0},
+		{"tcp_noread", 64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.protocol, func(t *testing.T) {
+			port, received := echoListener(t)
+			labelPort := strconv.Itoa(port)
+
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go generateFlow(context.Background(), 1, "127.0.0.1", ProtocolPort{tt.protocol, port}, 0.2, rand.New(rand.NewPCG(0, 0)), 1500, 1460, &wg)
+			assert.Eventually(t, func() bool {
+				return testutil.ToFloat64(mc.HalfOpenActiveFlows.WithLabelValues(tt.protocol)) == 1
+			}, time.Second, 5*time.Millisecond)
+			wg.Wait()
+
+			assert.Equal(t, tt.wantSent, <-received)
+			assert.Equal(t, float64(1), testutil.ToFloat64(mc.HalfOpenFlows.WithLabelValues(tt.protocol, labelPort)))
+			assert.Equal(t, float64(0), testutil.ToFloat64(mc.HalfOpenActiveFlows.WithLabelValues(tt.protocol)))
+			assert.Equal(t, float64(tt.wantSent/64), testutil.ToFloat64(mc.RequestsSent.WithLabelValues(tt.protocol, labelPort)))
+			assert.Equal(t, float64(0), testutil.ToFloat64(mc.BytesReceived.WithLabelValues(tt.protocol, labelPort)))
+		})
+	}
+}
+
+func TestHalfOpenTransportStalledWrite(t *testing.T) {
+	logging.InitLogger("json", "error")
+	oldMc := mc
+	mc = metrics.NewMetricsCollector()
+	defer func() { mc = oldMc }()
+
+	// The server never reads, so a payload larger than both socket buffers stalls the write
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			time.Sleep(time.Second)
+			_ = conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	tr := newHalfOpenTransport(true)(transport.FlowInfo{MSS: 1460})
+	require.NoError(t, tr.Dial(ctx, ln.Addr().String()))
+	defer func() { _ = tr.Close() }()
+
+	start := time.Now()
+	n, err := tr.Send(make([]byte, 64<<20))
+	assert.NoError(t, err)
+	assert.Less(t, n, 64<<20)
+	assert.Less(t, time.Since(start), 900*time.Millisecond)
+}
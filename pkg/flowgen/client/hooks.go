@@ -0,0 +1,171 @@
+package client
+
+import (
+	"runtime/debug"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/hooks"
+)
+
+const (
+	// hookQueueSize is the number of flow events buffered for hooks before new events are dropped
+	hookQueueSize = 4096
+	// hookDrainTimeout bounds how long the end of a run waits for hooks to process queued events
+	hookDrainTimeout = 5 * time.Second
+)
+
+// hookKind identifies the callback an event is dispatched to
+type hookKind int
+
+const (
+	hookScheduled hookKind = iota
+	hookCompleted
+	hookFailed
+)
+
+// hookCall is a queued event for the hooks
+type hookCall struct {
+	kind  hookKind
+	event hooks.Event
+}
+
+// hookDispatcher queues flow events and runs the hooks on a single goroutine: those of the client's own
+// features added with add and those embedders registered with hooks.Register
+type hookDispatcher struct {
+	mu      sync.RWMutex
+	hooks   []hooks.Hooks
+	queue   chan hookCall
+	done    chan struct{}
+	dropped uint64
+}
+
+// flowHooks dispatches the flow events of the run
+var flowHooks = &hookDispatcher{}
+
+// add adds hooks of a feature of the client. It must be called before the dispatcher starts.
+func (d *hookDispatcher) add(h hooks.Hooks) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.hooks = append(d.hooks, h)
+}
+
+// start begins dispatching to the added hooks and the given registered ones, if there are any
+func (d *hookDispatcher) start(queueSize int, registered []hooks.Hooks) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	all := append(slices.Clone(d.hooks), registered...)
+	if len(all) == 0 || d.queue != nil {
+		return
+	}
+	d.queue = make(chan hookCall, queueSize)
+	d.done = make(chan struct{})
+	go d.run(d.queue, d.done, all)
+}
+
+// run invokes the hooks for each queued event until the queue is closed
+func (d *hookDispatcher) run(queue <-chan hookCall, done chan<- struct{}, all []hooks.Hooks) {
+	defer close(done)
+	for call := range queue {
+		for _, h := range all {
+			invokeHook(h, call)
+		}
+	}
+}
+
+// invokeHook calls the hook matching the event, recovering from panics so a faulty hook cannot abort the run
+func invokeHook(h hooks.Hooks, call hookCall) {
+	var fn func(hooks.Event)
+	switch call.kind {
+	case hookScheduled:
+		fn = h.OnFlowScheduled
+	case hookCompleted:
+		fn = h.OnFlowCompleted
+	case hookFailed:
+		fn = h.OnFlowFailed
+	}
+	if fn == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Logger.Errorf("Flow hook panicked for flow %d: %v\n%s", call.event.FlowID, r, debug.Stack())
+		}
+	}()
+	fn(call.event)
+}
+
+// emit queues an event without blocking, dropping it if the hooks have fallen behind
+func (d *hookDispatcher) emit(kind hookKind, event hooks.Event) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.queue == nil {
+		return
+	}
+	select {
+	case d.queue <- hookCall{kind: kind, event: event}:
+	default:
+		atomic.AddUint64(&d.dropped, 1)
+	}
+}
+
+// stop stops accepting events and waits up to timeout for the hooks to process the queued ones
+func (d *hookDispatcher) stop(timeout time.Duration) {
+	d.mu.Lock()
+	if d.queue == nil {
+		d.mu.Unlock()
+		return
+	}
+	close(d.queue)
+	d.queue = nil
+	done := d.done
+	d.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		logging.Logger.Warnf("Flow hooks did not finish within %v, remaining events are discarded", timeout)
+	}
+	if dropped := atomic.LoadUint64(&d.dropped); dropped > 0 {
+		logging.Logger.Warnf("Dropped %d flow events because hooks could not keep up", dropped)
+	}
+}
+
+// emitFlowResult reports the end of a flow to the hooks as completed or failed. f is nil if the flow
+// never got to exchange requests.
+func emitFlowResult(flowID uint64, pp ProtocolPort, duration float64, startedAt time.Time, f *flowExchange, err error) {
+	now := time.Now()
+	event := hooks.Event{
+		FlowID:   flowID,
+		Protocol: pp.Protocol,
+		Port:     pp.Port,
+		Duration: seconds(duration),
+		Time:     now,
+		Elapsed:  now.Sub(startedAt),
+		Err:      err,
+	}
+	if f != nil {
+		event.Requests = f.requests
+		event.BytesSent = f.bytesSent
+		event.BytesReceived = f.bytesReceived
+		if f.responses > 0 {
+			event.Latency = f.latency / time.Duration(f.responses)
+		}
+		event.LocalAddr = localAddr(f.transport)
+		event.RemoteAddr = remoteAddr(f.transport)
+		event.FlowLabel = f.flowLabel
+	}
+	if err != nil {
+		flowHooks.emit(hookFailed, event)
+		return
+	}
+	flowHooks.emit(hookCompleted, event)
+}
+
+// seconds converts a duration in seconds to a time.Duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
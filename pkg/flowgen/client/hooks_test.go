@@ -0,0 +1,186 @@
+package client
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/hooks"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingHooks returns hooks that record the kind and flow of every event
+func recordingHooks(mu *sync.Mutex, events *[]string) hooks.Hooks {
+	record := func(kind string) func(hooks.Event) {
+		return func(e hooks.Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			*events = append(*events, kind+":"+e.Protocol)
+		}
+	}
+	return hooks.Hooks{OnFlowScheduled: record("scheduled"), OnFlowCompleted: record("completed"), OnFlowFailed: record("failed")}
+}
+
+func TestHookDispatcherOrder(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+	d := &hookDispatcher{}
+	d.hooks = []hooks.Hooks{recordingHooks(&mu, &events), {}}
+
+	d.emit(hookScheduled, hooks.Event{Protocol: "ignored"})
+	d.start(16, nil)
+	d.emit(hookScheduled, hooks.Event{Protocol: "tcp"})
+	d.emit(hookCompleted, hooks.Event{Protocol: "tcp"})
+	d.emit(hookFailed, hooks.Event{Protocol: "udp"})
+	d.stop(time.Second)
+	d.emit(hookScheduled, hooks.Event{Protocol: "ignored"})
+
+	assert.Equal(t, []string{"scheduled:tcp", "completed:tcp", "failed:udp"}, events)
+}
+
+func TestHookDispatcherRegisteredHooks(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+	d := &hookDispatcher{}
+	d.add(recordingHooks(&mu, &events))
+
+	// Hooks registered by embedders run after those of the client's own features
+	d.start(16, []hooks.Hooks{{OnFlowFailed: func(e hooks.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, "registered:"+e.Protocol)
+	}}})
+	d.emit(hookFailed, hooks.Event{Protocol: "tcp"})
+	d.stop(time.Second)
+
+	assert.Equal(t, []string{"failed:tcp", "registered:tcp"}, events)
+}
+
+func TestHookDispatcherNoHooks(t *testing.T) {
+	d := &hookDispatcher{}
+	d.start(16, nil)
+	assert.Nil(t, d.queue)
+	assert.NotPanics(t, func() {
+		d.emit(hookScheduled, hooks.Event{})
+		d.stop(time.Second)
+	})
+}
+
+func TestHookDispatcherRecoversPanics(t *testing.T) {
+	logging.InitLogger("json", "fatal")
+
+	var mu sync.Mutex
+	var events []string
+	d := &hookDispatcher{}
+	d.hooks = []hooks.Hooks{{OnFlowScheduled: func(hooks.Event) { panic("faulty hook") }}, recordingHooks(&mu, &events)}
+	d.start(16, nil)
+	d.emit(hookScheduled, hooks.Event{Protocol: "tcp"})
+	d.emit(hookScheduled, hooks.Event{Protocol: "udp"})
+	d.stop(time.Second)
+
+	assert.Equal(t, []string{"scheduled:tcp", "scheduled:udp"}, events)
+}
+
+func TestHookDispatcherDropsWhenBehind(t *testing.T) {
+	logging.InitLogger("json", "fatal")
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	d := &hookDispatcher{}
+	d.hooks = []hooks.Hooks{{OnFlowScheduled: func(hooks.Event) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+	}}}
+	d.start(1, nil)
+
+	d.emit(hookScheduled, hooks.Event{FlowID: 1})
+	<-started // The hook is now blocked on the first event
+	d.emit(hookScheduled, hooks.Event{FlowID: 2})
+	done := make(chan struct{})
+	go func() {
+		d.emit(hookScheduled, hooks.Event{FlowID: 3})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("emit blocked on a slow hook")
+	}
+	close(release)
+	d.stop(time.Second)
+	assert.Equal(t, uint64(1), d.dropped)
+}
+
+func TestGenerateFlowHooks(t *testing.T) {
+	logging.InitLogger("json", "fatal")
+	transport.Register("loopback-hooks", transport.StreamMode, func(transport.FlowInfo) transport.Transport { return &loopbackTransport{} })
+
+	oldCfg, oldMc, oldHooks := cfg, mc, flowHooks
+	cfg = &config.ClientConfig{PayloadSize: 64}
+	mc = metrics.NewMetricsCollector()
+	var mu sync.Mutex
+	var results []hooks.Event
+	record := func(e hooks.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		results = append(results, e)
+	}
+	flowHooks = &hookDispatcher{hooks: []hooks.Hooks{{OnFlowCompleted: record, OnFlowFailed: record}}}
+	defer func() { cfg, mc, flowHooks = oldCfg, oldMc, oldHooks }()
+	flowHooks.start(16, nil)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	generateFlow(context.Background(), 1, "127.0.0.1", ProtocolPort{"loopback-hooks", 9000}, 0.01, rand.New(rand.NewPCG(0, 0)), 1500, 1460, &wg)
+	generateFlow(context.Background(), 2, "127.0.0.1", ProtocolPort{"missing", 9000}, 0.01, rand.New(rand.NewPCG(0, 0)), 1500, 1460, &wg)
+	wg.Wait()
+	flowHooks.stop(time.Second)
+
+	require.Len(t, results, 2)
+	completed, failed := results[0], results[1]
+	assert.Equal(t, uint64(1), completed.FlowID)
+	assert.NoError(t, completed.Err)
+	assert.Equal(t, uint64(1), completed.Requests)
+	assert.Equal(t, uint64(64), completed.BytesSent)
+	assert.Equal(t, uint64(64), completed.BytesReceived)
+	assert.Equal(t, 10*time.Millisecond, completed.Duration)
+	assert.GreaterOrEqual(t, completed.Elapsed, 10*time.Millisecond)
+
+	assert.Equal(t, uint64(2), failed.FlowID)
+	assert.Error(t, failed.Err)
+	assert.Zero(t, failed.Requests)
+}
+
+func TestFlowExchangeResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		flow    flowExchange
+		wantErr bool
+	}{
+		{"stream without error", flowExchange{mode: transport.StreamMode, requests: 1}, false},
+		{"datagram with responses", flowExchange{mode: transport.DatagramMode, requests: 3, responses: 1}, false},
+		{"datagram without responses", flowExchange{mode: transport.DatagramMode, requests: 3}, true},
+		{"datagram without requests", flowExchange{mode: transport.DatagramMode}, false},
+		{"recorded error", flowExchange{mode: transport.StreamMode, requests: 1, err: assert.AnError}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr {
+				assert.Error(t, tt.flow.result())
+			} else {
+				assert.NoError(t, tt.flow.result())
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
+)
+
+// Types of ICMP errors received on client sockets, used as the type label of icmp_errors_total
+const (
+	icmpPortUnreachable = "port_unreachable"
+	// icmpAdminProhibited is a packet rejected by a firewall or policy, which answered instead of dropping it
+	icmpAdminProhibited = "admin_prohibited"
+	icmpHostUnreachable = "host_unreachable"
+	icmpNetUnreachable  = "net_unreachable"
+	// icmpFragNeeded is a packet exceeding the MTU of the path (fragmentation needed or packet too big)
+	icmpFragNeeded  = "frag_needed"
+	icmpTTLExceeded = "ttl_exceeded"
+	icmpOther       = "other"
+)
+
+// ICMP and ICMPv6 message types of the errors the kernel reports
+const (
+	icmpv4DestUnreachable = 3
+	icmpv4TimeExceeded    = 11
+	icmpv6DestUnreachable = 1
+	icmpv6PacketTooBig    = 2
+	icmpv6TimeExceeded    = 3
+)
+
+// icmpError is a socket error caused by an ICMP error, annotated with the type of the ICMP error
+type icmpError struct {
+	kind string
+	// offender is the host that sent the ICMP error, the invalid address if it is unknown
+	offender netip.Addr
+	err      error
+}
+
+func (e *icmpError) Error() string {
+	if e.offender.IsValid() {
+		return fmt.Sprintf("ICMP %s from %s: %v", e.kind, e.offender, e.err)
+	}
+	return fmt.Sprintf("ICMP %s: %v", e.kind, e.err)
+}
+
+func (e *icmpError) Unwrap() error {
+	return e.err
+}
+
+// icmpErrorKind classifies an ICMP error read from the error queue of a socket
+func icmpErrorKind(e sockopt.ICMPError) string {
+	if e.IPv6 {
+		switch {
+		case e.Type == icmpv6DestUnreachable && e.Code == 4:
+			return icmpPortUnreachable
+		// Administratively prohibited, source address failed policy and reject route
+		case e.Type == icmpv6DestUnreachable && (e.Code == 1 || e.Code == 5 || e.Code == 6):
+			return icmpAdminProhibited
+		case e.Type == icmpv6DestUnreachable && e.Code == 3:
+			return icmpHostUnreachable
+		case e.Type == icmpv6DestUnreachable && e.Code == 0:
+			return icmpNetUnreachable
+		case e.Type == icmpv6PacketTooBig:
+			return icmpFragNeeded
+		case e.Type == icmpv6TimeExceeded:
+			return icmpTTLExceeded
+		}
+		return icmpOther
+	}
+	switch {
+	case e.Type == icmpv4DestUnreachable:
+		switch e.Code {
+		case 3:
+			return icmpPortUnreachable
+		// Network and host administratively prohibited, communication administratively prohibited
+		case 9, 10, 13:
+			return icmpAdminProhibited
+		case 1, 5, 7, 12:
+			return icmpHostUnreachable
+		case 0, 6, 11:
+			return icmpNetUnreachable
+		case 4:
+			return icmpFragNeeded
+		}
+	case e.Type == icmpv4TimeExceeded:
+		return icmpTTLExceeded
+	}
+	return icmpOther
+}
+
+// icmpErrnoKind classifies a socket error by the errno the kernel mapped the ICMP error to, for platforms
+// without an error queue. Administratively prohibited errors cannot be told apart from unreachable hosts
+// this way. It returns an empty string for errors not caused by ICMP.
+func icmpErrnoKind(err error) string {
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return icmpPortUnreachable
+	case errors.Is(err, syscall.EHOSTUNREACH):
+		return icmpHostUnreachable
+	case errors.Is(err, syscall.ENETUNREACH):
+		return icmpNetUnreachable
+	case errors.Is(err, syscall.EMSGSIZE):
+		return icmpFragNeeded
+	}
+	return ""
+}
+
+// icmpErrorControl enables the error queue of a socket where the platform has one, so ICMP errors can be
+// read with their type and code
+func icmpErrorControl(network, address string, c syscall.RawConn) error {
+	if err := sockopt.RecvErr(network, address, c); err != nil && !errors.Is(err, sockopt.ErrUnsupported) {
+		return err
+	}
+	return nil
+}
+
+// annotateICMPError wraps an error of a socket with the ICMP error that caused it. Errors not caused by an
+// ICMP error, such as timeouts, are returned unchanged.
+func annotateICMPError(conn syscall.Conn, err error) error {
+	var netErr net.Error
+	if err == nil || errors.As(err, &netErr) && netErr.Timeout() {
+		return err
+	}
+	if raw, rawErr := conn.SyscallConn(); rawErr == nil {
+		if e, found, _ := sockopt.ReadICMPError(raw); found {
+			return &icmpError{kind: icmpErrorKind(e), offender: e.Offender, err: err}
+		}
+	}
+	if kind := icmpErrnoKind(err); kind != "" {
+		return &icmpError{kind: kind, err: err}
+	}
+	return err
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/transport"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestICMPErrorKind(t *testing.T) {
+	tests := []struct {
+		name string
+		err  sockopt.ICMPError
+		want string
+	}{
+		{"port unreachable", sockopt.ICMPError{Type: 3, Code: 3}, icmpPortUnreachable},
+		{"communication prohibited", sockopt.ICMPError{Type: 3, Code: 13}, icmpAdminProhibited},
+		{"host prohibited", sockopt.ICMPError{Type: 3, Code: 10}, icmpAdminProhibited},
+		{"host unreachable", sockopt.ICMPError{Type: 3, Code: 1}, icmpHostUnreachable},
+		{"net unreachable", sockopt.ICMPError{Type: 3, Code: 0}, icmpNetUnreachable},
+		{"fragmentation needed", sockopt.ICMPError{Type: 3, Code: 4}, icmpFragNeeded},
+		{"ttl exceeded", sockopt.ICMPError{Type: 11}, icmpTTLExceeded},
+		{"protocol unreachable", sockopt.ICMPError{Type: 3, Code: 2}, icmpOther},
+		{"parameter problem", sockopt.ICMPError{Type: 12}, icmpOther},
+		{"v6 port unreachable", sockopt.ICMPError{IPv6: true, Type: 1, Code: 4}, icmpPortUnreachable},
+		{"v6 admin prohibited", sockopt.ICMPError{IPv6: true, Type: 1, Code: 1}, icmpAdminProhibited},
+		{"v6 reject route", sockopt.ICMPError{IPv6: true, Type: 1, Code: 6}, icmpAdminProhibited},
+		{"v6 address unreachable", sockopt.ICMPError{IPv6: true, Type: 1, Code: 3}, icmpHostUnreachable},
+		{"v6 no route", sockopt.ICMPError{IPv6: true, Type: 1, Code: 0}, icmpNetUnreachable},
+		{"v6 packet too big", sockopt.ICMPError{IPv6: true, Type: 2}, icmpFragNeeded},
+		{"v6 hop limit exceeded", sockopt.ICMPError{IPv6: true, Type: 3}, icmpTTLExceeded},
+		{"v6 parameter problem", sockopt.ICMPError{IPv6: true, Type: 4}, icmpOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, icmpErrorKind(tt.err))
+		})
+	}
+}
+
+func TestICMPErrnoKind(t *testing.T) {
+	readErr := func(errno syscall.Errno) error {
+		return &net.OpError{Op: "read", Net: "udp", Err: os.NewSyscallError("recvfrom", errno)}
+	}
+	assert.Equal(t, icmpPortUnreachable, icmpErrnoKind(readErr(syscall.ECONNREFUSED)))
+	assert.Equal(t, icmpHostUnreachable, icmpErrnoKind(readErr(syscall.EHOSTUNREACH)))
+	assert.Equal(t, icmpNetUnreachable, icmpErrnoKind(readErr(syscall.ENETUNREACH)))
+	assert.Equal(t, icmpFragNeeded, icmpErrnoKind(readErr(syscall.EMSGSIZE)))
+	assert.Equal(t, "", icmpErrnoKind(readErr(syscall.EBADF)))
+	assert.Equal(t, "", icmpErrnoKind(errors.New("closed")))
+}
+
+func TestICMPErrorMessage(t *testing.T) {
+	err := &icmpError{kind: icmpAdminProhibited, offender: netip.MustParseAddr("10.0.0.1"), err: syscall.EHOSTUNREACH}
+	assert.Equal(t, "ICMP admin_prohibited from 10.0.0.1: no route to host", err.Error())
+	assert.ErrorIs(t, err, syscall.EHOSTUNREACH)
+	assert.Equal(t, "ICMP port_unreachable: connection refused", (&icmpError{kind: icmpPortUnreachable, err: syscall.ECONNREFUSED}).Error())
+}
+
+func TestUDPTransportICMPPortUnreachable(t *testing.T) {
+	oldMc := mc
+	mc = metrics.NewMetricsCollector()
+	defer func() { mc = oldMc }()
+
+	ln, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	addr := ln.LocalAddr().String()
+	require.NoError(t, ln.Close())
+
+	tr := newUDPTransport(transport.FlowInfo{MTU: 1500})
+	require.NoError(t, tr.Dial(context.Background(), addr))
+	defer func() { _ = tr.Close() }()
+	_, err = tr.Send([]byte("ping"))
+	require.NoError(t, err)
+	_, err = tr.Recv(make([]byte, 16))
+	require.Error(t, err)
+
+	var icmpErr *icmpError
+	require.ErrorAs(t, err, &icmpErr)
+	assert.Equal(t, icmpPortUnreachable, icmpErr.kind)
+	if runtime.GOOS == "linux" {
+		// Read from the error queue rather than guessed from the errno
+		assert.Equal(t, netip.MustParseAddr("127.0.0.1"), icmpErr.offender)
+	}
+	recordFlowError("udp", "9999", err, flowOpRead)
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.ICMPErrors.WithLabelValues("udp", "9999", icmpPortUnreachable)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.FlowErrors.WithLabelValues("udp", "9999", flowErrorRefused)))
+
+	// Timeouts are not ICMP errors
+	timeout := &net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}
+	assert.Same(t, error(timeout), annotateICMPError(tr.(*udpTransport).conn, timeout))
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+)
+
+// intervalMinSeconds is the shortest final interval worth reporting when the run ends, shorter ones only
+// add noise after the last full interval
+const intervalMinSeconds = 0.1
+
+// intervalCounts are the running totals of the run that interval reports are computed from
+type intervalCounts struct {
+	started   uint64
+	completed uint64
+	failed    uint64
+	sent      uint64
+	received  uint64
+}
+
+// sub returns the counts since the previous totals. Totals only go down if the metrics were reset, which
+// counts as nothing happening.
+func (c intervalCounts) sub(prev intervalCounts) intervalCounts {
+	delta := func(current, previous uint64) uint64 {
+		if current < previous {
+			return 0
+		}
+		return current - previous
+	}
+	return intervalCounts{
+		started:   delta(c.started, prev.started),
+		completed: delta(c.completed, prev.completed),
+		failed:    delta(c.failed, prev.failed),
+		sent:      delta(c.sent, prev.sent),
+		received:  delta(c.received, prev.received),
+	}
+}
+
+// intervalReporter logs iperf-style reports of the flows, traffic and errors of every interval of the run,
+// so a run gives feedback between its start and its final report
+type intervalReporter struct {
+	tracker *runTracker
+	slots   *flowSlots
+	// last are the totals at the end of the previous interval, which ended lastSeconds into the run
+	last        intervalCounts
+	lastSeconds float64
+	stopCh      chan struct{}
+	done        chan struct{}
+	once        sync.Once
+}
+
+// startIntervalReports logs a report every interval until stopped
+func startIntervalReports(t *runTracker, slots *flowSlots, interval time.Duration) *intervalReporter {
+	r := &intervalReporter{tracker: t, slots: slots, stopCh: make(chan struct{}), done: make(chan struct{})}
+	r.last, r.lastSeconds = r.counts(time.Now())
+	go r.run(interval)
+	return r
+}
+
+// run reports every interval until the reporter is stopped
+func (r *intervalReporter) run(interval time.Duration) {
+	defer close(r.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			logging.Logger.Info(r.report(now))
+		case <-r.stopCh:
+			return
+		}
+	}
+}
+
+// stop ends the reports with the interval since the last one, unless it was too short to tell anything
+func (r *intervalReporter) stop() {
+	if r == nil {
+		return
+	}
+	r.once.Do(func() {
+		close(r.stopCh)
+		<-r.done
+		now := time.Now()
+		if r.tracker.status(now).ElapsedSeconds-r.lastSeconds >= intervalMinSeconds {
+			logging.Logger.Info(r.report(now))
+		}
+	})
+}
+
+// counts takes the totals of the run and the seconds since it started
+func (r *intervalReporter) counts(now time.Time) (intervalCounts, float64) {
+	status := r.tracker.status(now)
+	summary := mc.Summary()
+	flows := outcomes.counts()
+	return intervalCounts{
+		started:   status.FlowsStarted,
+		completed: flows.Completed,
+		failed:    flows.Failed,
+		sent:      sumCounts(summary.BytesSent),
+		received:  sumCounts(summary.BytesReceived),
+	}, status.ElapsedSeconds
+}
+
+// report describes the interval ending now and starts the next one
+func (r *intervalReporter) report(now time.Time) string {
+	totals, elapsed := r.counts(now)
+	line := formatInterval(r.lastSeconds, elapsed, totals.sub(r.last), r.slots.inUse())
+	r.last, r.lastSeconds = totals, elapsed
+	return line
+}
+
+// formatInterval describes the flows, traffic and errors of the interval from one number of seconds into the
+// run to another and the flows active at its end, e.g. "[ 10.0- 20.0 s] started 200 flows (20.00/s),
+// completed 198, failed 2, active 12, sent 1.2 MB (0.96 Mbit/s), received 1.2 MB (0.96 Mbit/s)"
+func formatInterval(from, to float64, c intervalCounts, active int) string {
+	length := to - from
+	perSecond := func(n uint64) float64 {
+		if length <= 0 {
+			return 0
+		}
+		return float64(n) / length
+	}
+	return fmt.Sprintf("[%5.1f-%5.1f s] started %d flows (%.2f/s), completed %d, failed %d, active %d, sent %s (%.2f Mbit/s), received %s (%.2f Mbit/s)",
+		from, to, c.started, perSecond(c.started), c.completed, c.failed, active,
+		formatBytes(float64(c.sent)), perSecond(c.sent)*8/1e6, formatBytes(float64(c.received)), perSecond(c.received)*8/1e6)
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/hooks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntervalCountsSub(t *testing.T) {
+	prev := intervalCounts{started: 10, completed: 8, failed: 1, sent: 1000, received: 900}
+	c := intervalCounts{started: 25, completed: 20, failed: 3, sent: 2500, received: 2000}
+	assert.Equal(t, intervalCounts{started: 15, completed: 12, failed: 2, sent: 1500, received: 1100}, c.sub(prev))
+
+	// Reset metrics count as nothing happening
+	reset := intervalCounts{started: 30, completed: 24, failed: 3, sent: 100, received: 50}
+	assert.Equal(t, intervalCounts{started: 5, completed: 4}, reset.sub(c))
+}
+
+func TestFormatInterval(t *testing.T) {
+	line := formatInterval(10, 20, intervalCounts{started: 200, completed: 198, failed: 2, sent: 1200000, received: 600000}, 12)
+	assert.Equal(t, "[ 10.0- 20.0 s] started 200 flows (20.00/s), completed 198, failed 2, active 12, sent 1.2 MB (0.96 Mbit/s), received 600.0 kB (0.48 Mbit/s)", line)
+
+	// An empty interval has no rates
+	assert.Contains(t, formatInterval(5, 5, intervalCounts{started: 1}, 0), "started 1 flows (0.00/s)")
+}
+
+func TestIntervalReporter(t *testing.T) {
+	oldMc, oldOutcomes := mc, outcomes
+	mc = metrics.NewMetricsCollector()
+	outcomes = &flowOutcomes{}
+	logging.InitLogger("json", "info")
+	defer func() {
+		mc, outcomes = oldMc, oldOutcomes
+		logging.InitLogger("human", "info")
+	}()
+	var logs bytes.Buffer
+	logging.Tee(&logs)
+
+	var flows uint64
+	tracker := newRunTracker(&config.ClientConfig{}, time.Now(), &flows)
+	slots := newFlowSlots(4)
+	r := startIntervalReports(tracker, slots, time.Hour)
+
+	flows = 3
+	_, _ = slots.acquire(t.Context(), priorityHigh)
+	h := outcomes.hooks()
+	h.OnFlowCompleted(hooks.Event{FlowID: 1})
+	h.OnFlowFailed(hooks.Event{FlowID: 2, Err: errors.New("timeout")})
+	mc.AddBytesSent("tcp", "8080", 300)
+	mc.AddBytesReceived("tcp", "8080", 200)
+
+	// The first interval reports what happened since the start
+	line := r.report(time.Now())
+	assert.Contains(t, line, "started 3 flows")
+	assert.Contains(t, line, "completed 1, failed 1, active 1, sent 300 B")
+	assert.Contains(t, line, "received 200 B")
+
+	// The next one only what happened since then
+	flows = 5
+	mc.AddBytesSent("tcp", "8080", 100)
+	line = r.report(time.Now())
+	assert.Contains(t, line, "started 2 flows")
+	assert.Contains(t, line, "completed 0, failed 0, active 1, sent 100 B")
+
+	// Stopping reports the final interval once it lasted long enough, and only once
+	time.Sleep(seconds(intervalMinSeconds))
+	r.stop()
+	r.stop()
+	require.Equal(t, 1, strings.Count(logs.String(), "started 0 flows"))
+
+	var none *intervalReporter
+	none.stop()
+}
+
+func TestIntervalReporterTicks(t *testing.T) {
+	oldMc := mc
+	mc = metrics.NewMetricsCollector()
+	logging.InitLogger("json", "info")
+	defer func() {
+		mc = oldMc
+		logging.InitLogger("human", "info")
+	}()
+	var logs bytes.Buffer
+	logging.Tee(&logs)
+
+	var flows uint64
+	tracker := newRunTracker(&config.ClientConfig{}, time.Now(), &flows)
+	r := startIntervalReports(tracker, newFlowSlots(1), 20*time.Millisecond)
+	time.Sleep(70 * time.Millisecond)
+	r.stop()
+	assert.GreaterOrEqual(t, strings.Count(logs.String(), "started 0 flows"), 2)
+}
@@ -0,0 +1,1396 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/bufpool"
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/echoserver"
+	"github.com/PhilipSchmid/flow-generator-app/internal/flowheader"
+	"github.com/PhilipSchmid/flow-generator-app/internal/health"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/internal/tracing"
+	"github.com/PhilipSchmid/flow-generator-app/internal/webui"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/hooks"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/transport"
+
+	"github.com/spf13/pflag"
+)
+
+// ProtocolPort combines a protocol and its associated port
+type ProtocolPort struct {
+	Protocol string
+	Port     int
+}
+
+// flowSeed seeds the random source picking the ports, durations and payload sizes of flows, resolved from
+// the seed setting at startup
+var flowSeed uint64
+
+var payloadCache []byte
+var cfg *config.ClientConfig
+var mc *metrics.MetricsCollector
+var sampler *flowSampler
+var pool *connPool
+var relays *relayChain
+var sockets = newSocketRegistry()
+var wire metrics.WireEstimator
+var shutdownOTLPMetrics func(context.Context) error
+var flowLog *flowLogWriter
+var stream *resultStream
+var sources *sourcePool
+var targets *targetSweep
+var marks *dscpMarks
+var ttls *ttlLimits
+var egress *egressBinding
+var labels *flowLabels
+var dialing *dialPolicy
+var tuning *socketTuning
+var responseTimeouts *udpTimeouts
+var headers *flowHeaders
+var templates *payloadTemplate
+var payloadSizes *payloadDistribution
+var bundle *artifactBundle
+var outcomes *flowOutcomes
+var artifactSinks *outputSinks
+var phases *phaseMonitor
+var warnings *softLimits
+var sourcePorts *sourcePortRange
+var tuples *tupleTracker
+var cbr *constantBitrate
+var mimicry *payloadMimicry
+
+// responseSizes are the sizes the server gives the responses of ports instead of echoing requests as is
+var responseSizes map[int]config.ResponseSize
+var progress *progressEvents
+var namespaces *netnsGroups
+
+// init initializes the payload cache with random bytes
+func init() {
+	payloadCache = newPayloadCache(1 << 20) // 1MB
+}
+
+// resolveSeed returns the configured seed, or a random one if none is configured. A random seed is never 0,
+// so it can be passed back to reproduce the run.
+func resolveSeed(configured uint64) uint64 {
+	seed := configured
+	for seed == 0 {
+		seed = rand.Uint64()
+	}
+	return seed
+}
+
+// flowRand returns the random source of a single flow, derived from the seed and the flow ID. Each flow
+// draws its start offset, duration and payload sizes from its own source, so the values of a flow do not
+// depend on how the goroutines of concurrent flows interleave.
+func flowRand(seed, flowID uint64) *rand.Rand {
+	// #nosec G404 - math/rand is sufficient for flow randomization
+	return rand.New(rand.NewPCG(seed, flowID))
+}
+
+// constructAddress formats the server address with port
+func constructAddress(server string, port int) string {
+	if ip := net.ParseIP(server); ip != nil {
+		if ip.To4() == nil { // IPv6 address
+			return fmt.Sprintf("[%s]:%d", server, port)
+		}
+	}
+	return fmt.Sprintf("%s:%d", server, port)
+}
+
+// isIPv6 reports whether a socket address is an IPv6 address
+func isIPv6(addr net.Addr) bool {
+	var ip net.IP
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		ip = a.IP
+	case *net.UDPAddr:
+		ip = a.IP
+	}
+	return ip != nil && ip.To4() == nil
+}
+
+// getPayloadSize determines the size of the payload to send
+func getPayloadSize(src *rand.Rand) int {
+	if payloadSizes != nil {
+		return payloadSizes.pick(src)
+	}
+	if size := cfg.PayloadSize; size > 0 {
+		return size // Fixed size
+	}
+	minSize := cfg.MinPayloadSize
+	maxSize := cfg.MaxPayloadSize
+	if minSize > 0 && maxSize > minSize {
+		return minSize + src.IntN(maxSize-minSize+1)
+	}
+	return 5 // Default to 5 bytes
+}
+
+// getUDPSendInterval determines the pause between two UDP sends, applying random jitter if configured
+func getUDPSendInterval(src *rand.Rand) time.Duration {
+	interval := cfg.UDPInterval
+	if jitter := cfg.UDPJitter; jitter > 0 {
+		interval += (src.Float64()*2 - 1) * jitter
+	}
+	if interval < 0 {
+		interval = 0
+	}
+	return time.Duration(interval * float64(time.Second))
+}
+
+// generateFlow generates network traffic to the server over the transport registered for the flow's
+// protocol and reads the echoed response
+func generateFlow(mainCtx context.Context, flowID uint64, server string, pp ProtocolPort, duration float64, src *rand.Rand, mtu int, mss int, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	// The outcome of the flow is reported to the flow hooks once it ends
+	startedAt := time.Now()
+	var f *flowExchange
+	var flowErr error
+	defer func() {
+		if flowErr == nil && errors.Is(context.Cause(mainCtx), errFlowPreempted) {
+			flowErr = errFlowPreempted
+		}
+		emitFlowResult(flowID, pp, duration, startedAt, f, flowErr)
+	}()
+
+	reg, ok := transport.Lookup(pp.Protocol)
+	if !ok {
+		flowErr = fmt.Errorf("no flow transport registered for protocol %q", pp.Protocol)
+		logFlowFailure(flowID, pp.Protocol, "%v", flowErr)
+		return
+	}
+
+	payloadSize := getPayloadSize(src)
+	if payloadSize > len(payloadCache) {
+		payloadSize = len(payloadCache)
+	}
+	payload := flowPayload(payloadSize)
+	if headers != nil {
+		payload = headers.payload(payload, flowID)
+		payloadSize = len(payload)
+	}
+	if mimicry != nil && reg.Mode != transport.ChurnMode && reg.Mode != transport.ReceiveMode {
+		if mimicked, protocol := mimicry.payload(pp.Port, reg.Mode == transport.DatagramMode, payloadSize); mimicked != nil {
+			payload, payloadSize = mimicked, len(mimicked)
+			mc.IncFlowsMimicked(pp.Protocol, strconv.Itoa(pp.Port), protocol)
+		}
+	}
+
+	sampled := sampler.sampled(flowID) || flowVerbosity() >= verbosityDetail
+	logFlowSummary(flowID, pp.Protocol, sampled, "Starting %s flow for %s to %s on port %d with payload size %d bytes", pp.Protocol, logging.CompactDuration(seconds(duration)), server, pp.Port, payloadSize)
+
+	// Create a context for this flow with its own timeout
+	flowCtx, flowCancel := context.WithTimeout(mainCtx, time.Duration(duration*float64(time.Second)))
+	defer flowCancel()
+
+	name := protocolName(pp.Protocol)
+	flow := transport.FlowInfo{ID: flowID, Protocol: pp.Protocol, Sampled: sampled, MTU: mtu, MSS: mss}
+	if sources != nil {
+		source := sources.pick(constructAddress(server, pp.Port), src)
+		flow.Source, flow.SourceInterface = source.addr, source.iface
+		mc.SourceFlowStarted(source.String(), pp.Protocol)
+		defer mc.SourceFlowEnded(source.String())
+	}
+	var group *netnsGroup
+	if namespaces != nil {
+		group = namespaces.pick()
+		flow.Netns = group.name
+		mc.NetnsFlowStarted(group.name)
+		defer func() {
+			// Runs before the deferred flow result, which counts preempted flows as failed as well
+			mc.NetnsFlowEnded(group.name, pp.Protocol, flowErr != nil || errors.Is(context.Cause(mainCtx), errFlowPreempted))
+		}()
+	}
+	if marks != nil {
+		var class string
+		if class, flow.DSCP = marks.class(pp.Port); class != "" {
+			mc.IncFlowsMarked(pp.Protocol, strconv.Itoa(pp.Port), class)
+		}
+	}
+	if ttls != nil {
+		flow.TTL = ttls.ttl(pp.Port)
+	}
+	if labels != nil {
+		flow.FlowLabel = labels.label(src)
+	}
+	if sourcePorts != nil {
+		flow.SourcePort = sourcePorts.port()
+	}
+	t := reg.Factory(flow)
+	dialedAt := time.Now()
+	if err := group.run(func() error { return t.Dial(flowCtx, constructAddress(server, pp.Port)) }); err != nil {
+		logFlowFailure(flowID, pp.Protocol, "Failed to connect to %s:%d (%s): %v", server, pp.Port, name, err)
+		recordFlowError(pp.Protocol, strconv.Itoa(pp.Port), err, flowOpDial)
+		flowErr = err
+		return
+	}
+	handshake := time.Since(dialedAt)
+	defer func() { _ = t.Close() }()
+	if tuples != nil && tuples.observe(pp.Protocol, localAddr(t), remoteAddr(t)) {
+		mc.IncUniqueTuples(pp.Protocol)
+	}
+
+	f = &flowExchange{
+		flowID:    flowID,
+		sampled:   sampled,
+		protocol:  pp.Protocol,
+		port:      strconv.Itoa(pp.Port),
+		payload:   payload,
+		header:    headers != nil,
+		transport: t,
+		ipv6:      isIPv6(remoteAddr(t)),
+		mode:      reg.Mode,
+		size:      responseSizes[pp.Port],
+		readSize:  cfg.TCPReadBuffer,
+	}
+	if f.ipv6 {
+		f.flowLabel = flow.FlowLabel
+	}
+	if templates != nil {
+		f.base = payload
+	}
+	if family := addrFamily(remoteAddr(t)); family != "" {
+		mc.IncFlowsByFamily(pp.Protocol, family)
+	}
+
+	switch reg.Mode {
+	case transport.StreamMode:
+		f.exchange()
+		// Wait for the flow's context to be done (timeout or mainCtx cancellation)
+		<-flowCtx.Done()
+	case transport.HoldMode:
+		mc.HalfOpenFlowStarted(pp.Protocol, f.port)
+		f.exchange()
+		<-flowCtx.Done()
+		mc.HalfOpenFlowEnded(pp.Protocol)
+	case transport.ChurnMode:
+		f.responses, f.latency = 1, handshake
+		mc.IncConnectionsChurned(pp.Protocol, f.port)
+		mc.ObserveLatency(pp.Protocol, f.port, handshake)
+		recordLatency(time.Now(), handshake)
+	case transport.ReceiveMode:
+		f.receive(flowCtx)
+	default:
+		if cbr != nil {
+			f.streamCBR(flowCtx, constructAddress(server, pp.Port))
+			if errors.Is(flowCtx.Err(), context.Canceled) {
+				logFlowSummary(flowID, pp.Protocol, sampled, "%s flow to %s:%d canceled", name, server, pp.Port)
+				flowErr = f.result()
+				return
+			}
+			break
+		}
+		startTime := time.Now()
+		for time.Since(startTime) < time.Duration(duration*float64(time.Second)) {
+			if !f.exchange() {
+				continue
+			}
+			select {
+			case <-time.After(getUDPSendInterval(src)):
+			case <-flowCtx.Done():
+				logFlowSummary(flowID, pp.Protocol, sampled, "%s flow to %s:%d canceled", name, server, pp.Port)
+				flowErr = f.result()
+				return
+			}
+		}
+	}
+	flowErr = f.result()
+	logFlowSummary(flowID, pp.Protocol, sampled, "%s flow to %s:%d ended after %s", name, server, pp.Port, logging.CompactDuration(seconds(duration)))
+}
+
+// flowExchange sends requests of a single flow over its transport and records the metrics
+type flowExchange struct {
+	flowID   uint64
+	sampled  bool
+	protocol string
+	port     string
+	payload  []byte
+	// header is set if the payload starts with a flow header, whose sequence number counts the requests
+	header bool
+	// base is the payload the payload template is rendered into per request, nil without a template
+	base      []byte
+	transport transport.Transport
+	ipv6      bool
+	mode      transport.Mode
+	// flowLabel is the IPv6 flow label the flow is sent with, 0 if none was set
+	flowLabel uint32
+	// size is how the server sizes the responses of the port, the zero size echoes requests as is
+	size config.ResponseSize
+	// readSize is the size of the reads of stream responses
+	readSize int
+
+	// Totals of the flow and the first error of its exchanges
+	requests      uint64
+	responses     uint64
+	bytesSent     uint64
+	bytesReceived uint64
+	latency       time.Duration
+	err           error
+}
+
+// fail records the first error of the flow
+func (f *flowExchange) fail(err error) {
+	if f.err == nil {
+		f.err = err
+	}
+}
+
+// result returns why the flow failed, or nil if all of its exchanges succeeded
+func (f *flowExchange) result() error {
+	if f.err == nil && f.mode == transport.DatagramMode && f.requests > 0 && f.responses == 0 {
+		return fmt.Errorf("no response to %d requests", f.requests)
+	}
+	return f.err
+}
+
+// wireBytes estimates the on-wire bytes of n payload bytes for the transport's framing
+func (f *flowExchange) wireBytes(n int) int {
+	if f.mode != transport.DatagramMode {
+		return wire.TCPBytes(n, f.ipv6)
+	}
+	return wire.UDPBytes(n, f.ipv6)
+}
+
+// streamReadSize returns the size of the reads of a stream response of the expected size, the size configured
+// with tcp_read_buffer or, without one, the response size from 1 KiB up to the largest pooled buffer, so large
+// responses take few reads
+func streamReadSize(n, expected int) int {
+	if n > 0 {
+		return n
+	}
+	return min(max(expected, 1024), bufpool.MaxSize)
+}
+
+// exchange sends one request and reads its response. A stream response is read until the whole
+// payload has been echoed, a datagram response is a single read. It reports whether the request was
+// sent, a failed send must not be followed by the send interval.
+func (f *flowExchange) exchange() bool {
+	name := protocolName(f.protocol)
+	if f.base != nil {
+		offset := 0
+		if f.header {
+			offset = flowheader.Size
+		}
+		payload, err := templates.render(f.base, offset, payloadVars{FlowID: f.flowID, Counter: f.requests + 1, Protocol: f.protocol, Port: f.port})
+		if err != nil {
+			logFlowFailure(f.flowID, f.protocol, "Failed to render payload template: %v", err)
+			f.fail(err)
+			return false
+		}
+		f.payload = payload
+	}
+	if f.header {
+		flowheader.SetSeq(f.payload, uint32(f.requests))
+	}
+	sentAt := time.Now()
+	nSent, err := f.transport.Send(f.payload)
+	if err != nil {
+		logFlowFailure(f.flowID, f.protocol, "Failed to write to %s connection: %v", name, err)
+		recordFlowError(f.protocol, f.port, err, flowOpWrite)
+		f.fail(err)
+		return false
+	}
+	// A handshake-only hold mode transport sends nothing
+	if nSent == 0 && f.mode == transport.HoldMode {
+		return true
+	}
+	f.requests++
+	f.bytesSent += uint64(nSent)
+	mc.IncRequestsSent(f.protocol, f.port)
+	mc.AddBytesSent(f.protocol, f.port, nSent)
+	mc.AddWireBytesSent(f.protocol, f.port, f.wireBytes(nSent))
+	if f.sampled {
+		sampler.logPayload(f.flowID, f.protocol, "sent", f.payload[:nSent])
+	}
+
+	// The response of a hold mode request is never read
+	if f.mode == transport.HoldMode {
+		return true
+	}
+	expected := f.size.Of(len(f.payload))
+	if f.mode == transport.DatagramMode {
+		// A datagram larger than expected is read whole, so it counts as a mismatch instead of being truncated
+		bp := bufpool.Get(max(expected, config.MaxUDPReadBuffer))
+		defer bufpool.Put(bp)
+		buf := *bp
+		nReceived, err := f.transport.Recv(buf)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				logFlowDetail(f.flowID, f.protocol, f.sampled, "Timeout waiting for %s response on port %s", name, f.port)
+			} else {
+				logFlowFailure(f.flowID, f.protocol, "Failed to read from %s connection: %v", name, err)
+				recordFlowError(f.protocol, f.port, err, flowOpRead)
+				f.fail(err)
+			}
+			return true
+		}
+		rtt := time.Since(sentAt)
+		f.responses++
+		f.bytesReceived += uint64(nReceived)
+		f.latency += rtt
+		mc.ObserveLatency(f.protocol, f.port, rtt)
+		mc.AddBytesReceived(f.protocol, f.port, nReceived)
+		recordLatency(sentAt.Add(rtt), rtt)
+		recordBytesReceived(sentAt.Add(rtt), nReceived)
+		mc.AddWireBytesReceived(f.protocol, f.port, f.wireBytes(nReceived))
+		if f.sampled {
+			sampler.logPayload(f.flowID, f.protocol, "received", buf[:nReceived])
+		}
+		if nReceived != expected {
+			logFlowFailure(f.flowID, f.protocol, "%s byte mismatch: sent %d bytes, received %d of %d bytes expected", name, len(f.payload), nReceived, expected)
+			mc.IncFlowErrors(f.protocol, f.port, flowErrorMismatch)
+			f.fail(fmt.Errorf("received %d of %d bytes expected", nReceived, expected))
+		}
+		return true
+	}
+
+	totalReceived := 0
+	bp := bufpool.Get(streamReadSize(f.readSize, expected))
+	defer bufpool.Put(bp)
+	buf := *bp
+	var readErr error
+	var firstRead, lastRead time.Time
+	for totalReceived < expected {
+		n, err := f.transport.Recv(buf)
+		if err != nil {
+			logFlowFailure(f.flowID, f.protocol, "Failed to read full %s response: %v", name, err)
+			readErr = err
+			break
+		}
+		// The throughput is measured from the first read, the round trip to the server is not part of it
+		if lastRead = time.Now(); totalReceived == 0 {
+			firstRead = lastRead
+		}
+		totalReceived += n
+		f.bytesReceived += uint64(n)
+		mc.AddBytesReceived(f.protocol, f.port, n)
+		recordBytesReceived(time.Now(), n)
+		if f.sampled {
+			sampler.logPayload(f.flowID, f.protocol, "received", buf[:n])
+		}
+	}
+	mc.AddWireBytesReceived(f.protocol, f.port, f.wireBytes(totalReceived))
+	if totalReceived != expected {
+		logFlowFailure(f.flowID, f.protocol, "%s byte mismatch: sent %d bytes, received %d of %d bytes expected", name, len(f.payload), totalReceived, expected)
+		// A read error is what cut the echo short, so it is counted instead of the mismatch
+		if readErr != nil {
+			recordFlowError(f.protocol, f.port, readErr, flowOpRead)
+		} else {
+			mc.IncFlowErrors(f.protocol, f.port, flowErrorMismatch)
+		}
+		f.fail(fmt.Errorf("received %d of %d bytes expected", totalReceived, expected))
+	} else {
+		rtt := time.Since(sentAt)
+		mc.ObserveResponseRead(f.protocol, f.port, totalReceived, lastRead.Sub(firstRead))
+		f.responses++
+		f.latency += rtt
+		mc.ObserveLatency(f.protocol, f.port, rtt)
+		recordLatency(sentAt.Add(rtt), rtt)
+	}
+	return true
+}
+
+// buildAvailablePorts returns the protocol/port combinations flows are generated for
+func buildAvailablePorts(c *config.ClientConfig) []ProtocolPort {
+	var availablePorts []ProtocolPort
+	if c.Protocol == "tcp" || c.Protocol == "both" {
+		for _, p := range echoserver.ParsePorts(c.TCPPorts) {
+			availablePorts = append(availablePorts, ProtocolPort{"tcp", p})
+		}
+	}
+	if c.Protocol == "udp" || c.Protocol == "both" {
+		for _, p := range echoserver.ParsePorts(c.UDPPorts) {
+			availablePorts = append(availablePorts, ProtocolPort{"udp", p})
+		}
+	}
+	// Ports of custom transports are used regardless of the protocol setting
+	transportPorts, _ := config.ParsePortMap(c.TransportPorts)
+	ports := make([]int, 0, len(transportPorts))
+	for p := range transportPorts {
+		ports = append(ports, p)
+	}
+	sort.Ints(ports)
+	for _, p := range ports {
+		protocol := transportPorts[p]
+		if _, ok := transport.Lookup(protocol); !ok {
+			logging.Logger.Warnf("Port %d ignored, no flow transport registered for %q (available: %v)", p, protocol, transport.Registered())
+			continue
+		}
+		availablePorts = append(availablePorts, ProtocolPort{protocol, p})
+	}
+	return availablePorts
+}
+
+// flowPacing returns the number of scheduler ticks per second and the number of flows launched per tick.
+// In burst mode, each tick launches a whole burst of flows back-to-back.
+func flowPacing(c *config.ClientConfig) (float64, int) {
+	if c.BurstSize > 0 {
+		return 1 / c.BurstInterval, c.BurstSize
+	}
+	return c.Rate, 1
+}
+
+// defineFlags registers the client configuration flags on the given flag set
+func defineFlags(fs *pflag.FlagSet) {
+	fs.String("log_level", "", "Log level: debug, info, warn, error")
+	fs.String("log_format", "", "Log format: human or json")
+	fs.String("log_theme", "", "Look of the human log format: auto (colored on terminals), color, plain or classic")
+	fs.String("metrics_port", "", "Port for the Prometheus metrics server (empty to disable)")
+	fs.Bool("tracing_enabled", false, "Enable tracing")
+	fs.String("jaeger_endpoint", "", "Jaeger endpoint")
+	fs.Bool("otlp_metrics_enabled", false, "Push metrics to the OTLP endpoint configured by jaeger_endpoint")
+	fs.Float64("otlp_metrics_interval", 0, "Interval in seconds between OTLP metric pushes")
+	fs.String("statsd_address", "", "StatsD/DogStatsD address (host:port) to send per-flow counters and latency timings to")
+	fs.Float64("statsd_sample_rate", 0, "Fraction of StatsD metrics to send (0-1]")
+	fs.String("profile_dir", "", "Directory of named YAML configuration profiles")
+	fs.String("profile", "", "Comma-separated profiles from profile_dir to apply, later ones override earlier ones")
+	fs.Bool("tcp_nodelay", true, "Send TCP segments without waiting to coalesce small writes (TCP_NODELAY)")
+	fs.Int("socket_sndbuf", 0, "Send buffer size (SO_SNDBUF) of the sockets in bytes (0 for the kernel default)")
+	fs.Int("socket_rcvbuf", 0, "Receive buffer size (SO_RCVBUF) of the sockets in bytes (0 for the kernel default)")
+	fs.Bool("tcp_keepalive", true, "Send TCP keepalive probes on idle TCP connections")
+	fs.Float64("tcp_keepalive_idle", 0, "Idle time in seconds before the first TCP keepalive probe (0 for the Go default of 15s)")
+	fs.Float64("tcp_keepalive_interval", 0, "Time in seconds between unanswered TCP keepalive probes (0 for the Go default of 15s)")
+	fs.Int("tcp_keepalive_count", 0, "Unanswered TCP keepalive probes after which a connection is dropped (0 for the Go default of 9)")
+	fs.String("server", "", "Server address or hostname")
+	fs.String("target_cidr", "", "Spread flows across the addresses of this prefix instead of server (e.g. 10.2.0.0/24)")
+	fs.Bool("target_probe", false, "Only send flows to the addresses of target_cidr answering a probe before the run")
+	fs.String("backup_server", "", "Backup server address that flows switch to once the failure rate of server crosses failover_threshold")
+	fs.Float64("failover_threshold", 0, "Percentage of failed flows to server within failover_window that switches traffic to backup_server")
+	fs.Float64("failover_window", 0, "Window in seconds over which the failure rate of server is measured for failover")
+	fs.Float64("rate", 0, "Flow generation rate in flows per second")
+	fs.Int("max_concurrent", 0, "Maximum number of concurrent flows")
+	fs.String("protocol", "", "Protocol to use (tcp, udp, both)")
+	fs.Float64("min_duration", 0, "Minimum flow duration in seconds")
+	fs.Float64("max_duration", 0, "Maximum flow duration in seconds")
+	fs.Bool("constant_flows", false, "Enable constant flow mode")
+	fs.String("tcp_ports", "", "Comma-separated list of TCP ports and port ranges (e.g. 8080,20000-29999)")
+	fs.String("udp_ports", "", "Comma-separated list of UDP ports and port ranges")
+	fs.String("transport_ports", "", "Comma-separated port=transport pairs for flows over registered custom transports")
+	fs.String("expect_service", "", "Comma-separated port=service pairs declaring what answers on ports of the server (echo, http, tls or none), verified before the run")
+	fs.String("response_sizes", "", "Comma-separated port=mode:value pairs the server sizes responses with (truncate, amplify, fixed), so responses are checked against the size expected, e.g. 8081=amplify:4")
+	fs.String("tls_server_names", "", "Comma-separated SNI names the flows of tls transport ports send in turn (the server address if unset)")
+	fs.Int("tcp_read_buffer", 0, "Size in bytes of the reads of stream responses (0 sizes them to the response, from 1 KiB to 64 KiB)")
+	fs.Int("payload_size", 0, "Fixed payload size in bytes")
+	fs.Int("min_payload_size", 0, "Minimum payload size in bytes")
+	fs.Int("max_payload_size", 0, "Maximum payload size in bytes")
+	fs.String("payload_pattern", "cached", "Content of payloads: cached (the same random bytes in every flow), random (fresh random bytes per flow), zeros, ascii-text or compressible")
+	fs.String("payload_template", "", "Go template rendered at the start of every message, with .FlowID, .Counter, .Timestamp, .UnixNano, .Hostname, .Protocol and .Port")
+	fs.String("payload_distribution", "uniform", "Distribution of payload sizes: uniform (between min and max_payload_size), bimodal (either of them) or empirical (from payload_sizes)")
+	fs.Float64("payload_large_fraction", 0.5, "Fraction of max_payload_size payloads of the bimodal distribution")
+	fs.String("payload_sizes", "", "Table of payload sizes and their weights for the empirical distribution, e.g. 64:7,576:4,1500:1, or imix")
+	fs.Bool("mimic_payloads", false, "Replace the payloads of flows to well-known ports (22, 25, 53, 80, 123, 443, 3306, 5432, ...) with messages of their protocols, such as DNS queries and TLS ClientHellos")
+	fs.String("mimic_ports", "", "Ports whose payloads mimic a protocol, in addition to or instead of the well-known ones, e.g. 8053=dns,9443=tls")
+	fs.Bool("flow_header", false, "Prefix payloads with a flow header so the server can detect duplicate flows and replayed datagrams")
+	fs.Int("mtu", 0, "Maximum Transmission Unit in bytes")
+	fs.Int("mss", 0, "Maximum Segment Size in bytes")
+	fs.Int("wire_l2_overhead", 0, "Link-layer header bytes per packet added to on-wire byte estimates")
+	fs.Float64("flow_timeout", 0.0, "Timeout in seconds for flow generation (0 for no timeout)")
+	fs.Uint64("seed", 0, "Seed for the random choice of ports, durations and payload sizes, to reproduce a run (0 for a random seed)")
+	fs.Float64("warmup", 0, "Seconds at the start of the run whose traffic is not counted in the metrics, summary and SLA assertions")
+	fs.Int("flow_count", 0, "Maximum number of flows to generate (0 for no limit)")
+	fs.String("stop_condition", "any", "How flow_count and flow_timeout combine: any (stop at whichever is reached first) or all (generate flows until both are reached)")
+	fs.Float64("shutdown_timeout", 0, "Seconds active flows may take to end once flow generation stopped or the client was terminated, before the run is reported without them (default 10, 0 waits for all)")
+	fs.StringSlice("pause_windows", nil, "Daily quiet windows in local time as HH:MM-HH:MM during which no new flows are started while active ones finish (repeatable or comma-separated, e.g. 02:00-02:15)")
+	fs.Int("debug_sample_flows", 0, "Log the first N flows in full detail (0 to disable)")
+	fs.Int("debug_sample_interval", 0, "After the first N flows, log every Nth flow in full detail (0 to disable)")
+	fs.Bool("debug_hex_dump", false, "Include hex dumps of payloads in sampled flow logs")
+	fs.Int("flow_verbosity", 1, "What to log about individual flows regardless of log_level: 0 nothing, 1 failures, 2 start and end of every flow, 3 every flow in full")
+	fs.Bool("connection_reuse", false, "Reuse pooled TCP connections across flows instead of dialing per flow")
+	fs.Int("pool_size", 0, "Maximum idle TCP connections kept per target port in connection reuse mode")
+	fs.Int("burst_size", 0, "Number of flows launched back-to-back per burst (0 disables burst mode)")
+	fs.Float64("burst_interval", 0, "Pause in seconds between bursts in burst mode")
+	fs.Bool("port_start_offsets", false, "Spread flow starts over each tick with a jittered phase offset per port instead of starting them together")
+	fs.String("source_cidr", "", "CIDR range to rotate the source addresses of flows through (empty to use the default source address)")
+	fs.String("source_addresses", "", "Comma-separated source addresses or network interfaces to spread flows over, instead of source_cidr")
+	fs.String("source_strategy", "", "Strategy selecting the source of each flow: round-robin, random or hash (by target)")
+	fs.String("netns", "", "Comma-separated network namespaces to send flows from as name[=share] pairs, names under /run/netns or paths (e.g. client-a=2,client-b)")
+	fs.String("source_port_range", "", "Range of source ports flows are bound to in turn, one per flow until the range wraps (e.g. 20000-59999)")
+	fs.Bool("track_tuples", false, "Count the flows whose 5-tuple was not used by a recent flow in unique_tuples_total")
+	fs.Bool("conntrack_backoff", false, "Back off the flow rate while timeouts and refusals suggest a full conntrack table on the path")
+	fs.Float64("conntrack_backoff_threshold", 0, "Percentage of flows within conntrack_backoff_window timing out or refused that triggers a backoff")
+	fs.Float64("conntrack_backoff_window", 0, "Window in seconds over which flows are checked for a full conntrack table")
+	fs.Float64("conntrack_backoff_factor", 0, "Fraction of the flow rate kept to probe for recovery while backed off")
+	fs.String("local_address", "", "Local address to bind all client connections to (empty to let the routing table choose)")
+	fs.String("interface", "", "Network interface to bind all client connections to with SO_BINDTODEVICE, Linux only (empty for any)")
+	fs.String("address_family", "", "Address family of flows to dual-stack servers: any, ipv4, ipv6, prefer-ipv4 or prefer-ipv6")
+	fs.Float64("happy_eyeballs_delay", 0, "Seconds after which the other address family is raced (0 to wait for the first one to fail)")
+	fs.Float64("udp_timeout_min", 0, "Lower bound in seconds of the UDP response timeout derived from the measured round trips")
+	fs.Float64("udp_timeout_max", 0, "Upper bound in seconds of the UDP response timeout derived from the measured round trips (0 for a fixed 1s timeout)")
+	fs.Float64("connect_timeout", 0, "Timeout in seconds of each connection attempt (0 for no limit below the flow duration)")
+	fs.Int("connect_retries", 0, "Number of times a failed connection attempt is retried")
+	fs.Float64("connect_backoff", 0, "Seconds to wait before the first connection retry, doubled for every further retry")
+	fs.String("dscp", "", "DSCP class (e.g. ef, af41, cs1) or value (0-63) to mark the packets of all flows with (empty to leave unmarked)")
+	fs.String("dscp_ports", "", "Comma-separated port=class list of DSCP classes overriding --dscp per port")
+	fs.Int("ttl", 0, "IPv4 TTL and IPv6 hop limit of the packets of all flows (0 for the system default)")
+	fs.String("ttl_ports", "", "Comma-separated port=ttl list overriding --ttl per port")
+	fs.String("flow_label", "", "IPv6 flow label of flows: a value between 1 and 0xfffff, or random for a random label per flow (Linux only, empty to leave it to the kernel)")
+	fs.String("role", "", "Process role: client to only generate flows, agent to also serve the listeners of a server")
+	fs.String("peers", "", "Comma-separated peer hosts to probe for the peer latency matrix in the agent role, host names expand to all their addresses")
+	fs.Int("peer_probe_port", 0, "UDP echo port of the peers the latency probes are sent to")
+	fs.Float64("peer_probe_interval", 0, "Interval in seconds between peer probe rounds")
+	fs.Int("peer_probe_count", 0, "Number of probes sent to each peer per round")
+	fs.String("peer_name", "", "Name of this agent in the peer latency matrix (defaults to the host name)")
+	fs.String("tcp_ports_server", "", "Comma-separated list of TCP ports served in the agent role")
+	fs.String("udp_ports_server", "", "Comma-separated list of UDP ports served in the agent role")
+	fs.String("health_port", "", "Port for the health check and run status server in the agent role")
+	fs.String("priority_ports", "", "Comma-separated port=class list of flow priority classes (high or low), unlisted ports are low priority")
+	fs.Float64("rate_transition", 0, "Time in seconds over which rate changes at runtime are ramped in (0 to change at once)")
+	fs.StringSlice("step", nil, "Stepped load profile replacing rate as rate:duration[:name] steps, each run in turn before flow generation stops (repeatable or comma-separated, e.g. --step 100:2m,500:2m,1000:2m)")
+	fs.Float64("udp_interval", 0, "Interval in seconds between UDP packets within a flow")
+	fs.String("udp_bitrate", "", "Send the datagrams of UDP flows at a constant bitrate in bits per second with an optional k, M or G suffix (e.g. 10M), replacing udp_interval and udp_jitter")
+	fs.Float64("udp_jitter", 0, "Maximum random deviation in seconds applied to each UDP send interval")
+	fs.String("backpressure_url", "", "Server backpressure endpoint to poll, e.g. http://server:8082/backpressure (empty to disable)")
+	fs.Float64("backpressure_poll_interval", 0, "Interval in seconds between backpressure status polls")
+	fs.Float64("backpressure_factor", 0, "Fraction of the flow rate kept while the server signals backpressure")
+	fs.String("relay_chain", "", "Comma-separated relay server addresses (host:port) that TCP flows traverse in order")
+	fs.String("status_port", "", "Port for the HTTP server exposing the run status endpoint and web UI (empty to disable)")
+	fs.Bool("control_api", false, "Allow pausing, resuming and changing the rate of the run through the status server")
+	fs.String("scenario", "", "Scenario name reported by the run status endpoint")
+	fs.StringSlice("meta", nil, "Experiment metadata as key=value (repeatable, e.g. --meta owner=netops --meta ticket=NET-1234), added to reports, the run_info metric and the flow log")
+	fs.String("output_file", "", "File to write the final run results to (empty to disable)")
+	fs.String("output", "", "What to write to stdout: text for the final metric tables, ndjson to stream stats and finished flows as JSON lines")
+	fs.Float64("stats_interval", 0, "Interval in seconds between stats lines when output is ndjson")
+	fs.Float64("report_interval", 0, "Interval in seconds between reports of the flows, traffic and errors of the last interval written to the log (0 to disable)")
+	fs.Bool("tui", false, "Show a live dashboard of active flows, rates, errors and latency on the terminal while the run goes on")
+	fs.String("progress_events", "", "Where to write run lifecycle events as JSON lines: '-' for stdout, fd:N for an inherited file descriptor or a file path (empty to disable)")
+	fs.Float64("max_error_rate", 0, "Fail the run if more than this percentage of flows failed (100 to disable)")
+	fs.Float64("max_p99_latency", 0, "Fail the run if the p99 round-trip latency of a protocol exceeds this many milliseconds (0 to disable)")
+	fs.Float64("min_throughput", 0, "Fail the run if the echoed throughput stays below this many Mbit/s (0 to disable)")
+	fs.String("warn_thresholds", "", "Soft limits checked during the run as assertion=value pairs like those of phases (e.g. max_error_rate=1,max_p99_latency=100), crossing one warns without stopping the run")
+	fs.Float64("warn_window", 30, "Seconds of the sliding window the warn_thresholds are checked over")
+	fs.String("warn_webhook", "", "URL a JSON notification is posted to whenever a soft limit is crossed or recovered")
+	fs.StringArray("phase", nil, "Time-boxed phase of the run with its own SLA assertions as name:seconds[:assertion=value,...] (repeatable, e.g. --phase steady:60:max_error_rate=0.1 --phase chaos:120:max_error_rate=5)")
+	fs.String("flow_log_file", "", "File to write one JSON line per finished flow to, '-' for stdout (empty to disable)")
+	fs.String("output_format", "", "Format of the run results file: json, csv, junit or html")
+	fs.Float64("latency_heatmap_slice", 0, "Length in seconds of the time slices of the latency heatmap in the JSON results (0 to disable)")
+	fs.String("artifact_bundle", "", "Archive (.tgz, .tar.gz or .zip) to pack the resolved config, seed, report, flow log and logs of the run into (empty to disable)")
+	fs.StringSlice("output_sink", nil, "Destination the results, flow log and artifact bundle are shipped to when the run ends: '-' for stdout, a directory, an http(s):// URL, s3://bucket/prefix or gs://bucket/prefix (repeatable)")
+	fs.Int("output_sink_retries", 0, "Number of times a failed delivery to an output sink is retried")
+	fs.Float64("output_sink_url_expiry", 0, "Seconds the presigned URLs logged for artifacts uploaded to S3 or GCS are valid (0 logs none, at most 7 days)")
+}
+
+// run loads the configuration and generates flows until the limits are reached or the process is terminated.
+// It returns the exit code of the run.
+func run() int {
+	// Load configuration
+	var err error
+	cfg, err = config.LoadClientConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	logging.InitLoggerWithTheme(cfg.LogFormat, cfg.LogLevel, cfg.LogTheme)
+	defer func() {
+		if err := logging.SyncLogger(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to sync logger: %v\n", err)
+		}
+	}()
+
+	// Logs are captured for the artifact bundle from the start of the run
+	if bundle, err = newArtifactBundle(cfg); err != nil {
+		logging.Logger.Errorf("Failed to set up artifact bundle: %v", err)
+		os.Exit(1)
+	}
+	if artifactSinks, err = newOutputSinks(cfg); err != nil {
+		logging.Logger.Errorf("Failed to set up output sinks: %v", err)
+		os.Exit(1)
+	}
+	if artifactSinks != nil {
+		logging.Logger.Infof("Shipping run artifacts to %s", artifactSinks)
+	}
+
+	mc = metrics.NewMetricsCollector()
+	// The metadata was checked when the configuration was validated
+	metadata, _ := config.ParseMetadata(cfg.Meta)
+	if err := metrics.SetRunInfo(cfg.Scenario, metadata); err != nil {
+		logging.Logger.Warnf("Failed to expose run info: %v", err)
+	}
+	if cfg.StatsdAddress != "" {
+		sink, err := metrics.NewStatsdSink(cfg.StatsdAddress, cfg.StatsdSampleRate)
+		if err != nil {
+			logging.Logger.Warnf("StatsD export disabled: %v", err)
+		} else {
+			mc.SetStatsdSink(sink)
+			logging.Logger.Infof("Sending metrics to StatsD at %s (sample rate %g)", cfg.StatsdAddress, cfg.StatsdSampleRate)
+		}
+	}
+	sampler = newFlowSampler(cfg)
+	if cfg.ConnectionReuse {
+		pool = newConnPool(cfg.PoolSize)
+	}
+	relays = newRelayChain(cfg)
+	if sources, err = newFlowSources(cfg); err != nil {
+		logging.Logger.Errorf("Failed to set up flow sources: %v", err)
+		os.Exit(1)
+	}
+	if sources != nil {
+		logging.Logger.Infof("Selecting flow sources %s by %s", sources, sources.strategy)
+	}
+	if namespaces, err = newNetnsGroups(cfg); err != nil {
+		logging.Logger.Errorf("Failed to set up network namespaces: %v", err)
+		os.Exit(1)
+	}
+	if namespaces != nil {
+		logging.Logger.Infof("Sending flows from network namespaces %s", namespaces)
+	}
+	if cfg.TargetCIDR != "" {
+		if targets, err = newTargetSweep(cfg.TargetCIDR); err != nil {
+			logging.Logger.Errorf("Failed to set up flow targets: %v", err)
+			os.Exit(1)
+		}
+		logging.Logger.Infof("Sweeping flows across %s instead of %s", targets, cfg.Server)
+	}
+	if egress, err = newEgressBinding(cfg); err != nil {
+		logging.Logger.Errorf("Failed to bind client traffic: %v", err)
+		os.Exit(1)
+	}
+	if egress != nil {
+		logging.Logger.Infof("Binding client traffic to %s", egress)
+	}
+	if sourcePorts = newSourcePortRange(cfg); sourcePorts != nil {
+		logging.Logger.Infof("Binding flows to source ports %s in turn", sourcePorts)
+	}
+	tuples = newTupleTracker(cfg)
+	marks = newDSCPMarks(cfg)
+	ttls = newTTLLimits(cfg)
+	labels = newFlowLabels(cfg)
+	dialing = newDialPolicy(cfg)
+	tuning = newSocketTuning(cfg)
+	responseTimeouts = newUDPTimeouts(cfg)
+	headers = newFlowHeaders(cfg)
+	if cbr = newConstantBitrate(cfg); cbr != nil {
+		logging.Logger.Infof("Sending the datagrams of UDP flows at a constant %s", cbr)
+	}
+	// Payloads of many megabytes slice a larger cache, the first megabyte stays the same
+	if size := cfg.LargestPayloadSize(); size > len(payloadCache) {
+		payloadCache = newPayloadCache(size)
+	}
+	if cfg.PayloadPattern != payloadCached && cfg.PayloadPattern != payloadRandom {
+		// #nosec G404 - math/rand is sufficient for payload content
+		fillPayload(payloadCache, cfg.PayloadPattern, rand.New(rand.NewPCG(0, 0)))
+	}
+	if templates, err = newPayloadTemplate(cfg); err != nil {
+		logging.Logger.Errorf("Failed to set up the payload template: %v", err)
+		os.Exit(1)
+	}
+	if payloadSizes, err = newPayloadDistribution(cfg); err != nil {
+		logging.Logger.Errorf("Failed to set up the payload size distribution: %v", err)
+		os.Exit(1)
+	}
+	if payloadSizes != nil {
+		logging.Logger.Infof("Picking payload sizes %s", payloadSizes)
+	}
+	if mimicry = newPayloadMimicry(cfg); mimicry != nil {
+		logging.Logger.Infof("Mimicking application protocols in the payloads of flows to ports %s", mimicry)
+	}
+	// The sizes were checked when the configuration was validated
+	if responseSizes, _ = config.ParseResponseSizes(cfg.ResponseSizes); len(responseSizes) > 0 {
+		logging.Logger.Infof("Expecting responses sized by the server on ports %s", cfg.ResponseSizes)
+	}
+	if tlsServerNames = splitServerNames(cfg.TLSServerNames); len(tlsServerNames) > 0 {
+		logging.Logger.Infof("Sending the SNI names %s in turn on TLS flows", strings.Join(tlsServerNames, ", "))
+	}
+	flowSeed = resolveSeed(cfg.Seed)
+	logging.Logger.Infof("Using seed %d, pass --seed %d to reproduce the sequence of flows", flowSeed, flowSeed)
+	wire = metrics.WireEstimator{MTU: cfg.MTU, MSS: cfg.MSS, L2Overhead: cfg.WireL2Overhead}
+	if path := bundle.flowLogPath(cfg.FlowLogFile); path != "" {
+		if flowLog, err = newFlowLogWriter(path); err != nil {
+			logging.Logger.Errorf("Failed to open flow log: %v", err)
+			os.Exit(1)
+		}
+		if len(metadata) > 0 {
+			flowLog.writeHeader(cfg.Scenario, metadata, time.Now())
+		}
+		flowHooks.add(flowLog.hooks())
+	}
+	if cfg.Output == outputNDJSON {
+		// Keep stdout for the stream, the zap logs already go to stderr
+		stream = newResultStream(os.Stdout)
+		flowHooks.add(stream.hooks())
+		mc.SetTableOutput(os.Stderr)
+	}
+	if cfg.StatusPort != "" || cfg.TUI || cfg.ReportInterval > 0 || assertionsEnabled(cfg) {
+		outcomes = &flowOutcomes{}
+		flowHooks.add(outcomes.hooks())
+	}
+
+	if cfg.TracingEnabled {
+		tracing.InitTracer("flow-generator", cfg.JaegerEndpoint)
+	}
+	if cfg.OTLPMetricsEnabled {
+		shutdownOTLPMetrics = metrics.InitOTLPExporter("flow-generator", cfg.JaegerEndpoint, time.Duration(cfg.OTLPMetricsInterval*float64(time.Second)))
+	}
+
+	// Serve the metrics for live scraping while flows are generated
+	if cfg.MetricsPort != "" {
+		if err := metrics.StartMetricsServer(cfg.MetricsPort); err != nil {
+			logging.Logger.Warnf("Metrics server error: %v", err)
+		} else {
+			logging.Logger.Infof("Serving Prometheus metrics on port %s", cfg.MetricsPort)
+		}
+	}
+
+	server := cfg.Server
+	rate := cfg.Rate
+	maxConcurrent := cfg.MaxConcurrent
+	minDuration := cfg.MinDuration
+	maxDuration := cfg.MaxDuration
+	constantFlows := cfg.ConstantFlows
+	mtu := cfg.MTU
+	mss := cfg.MSS
+	flowTimeout := cfg.FlowTimeout
+	flowCount := cfg.FlowCount
+
+	// Build list of available ports
+	availablePorts := buildAvailablePorts(cfg)
+	if len(availablePorts) == 0 {
+		logging.Logger.Error("No valid ports available for the selected protocol")
+		os.Exit(1)
+	}
+
+	if targets != nil && cfg.TargetProbe {
+		timeout := defaultProbeTimeout
+		if cfg.ConnectTimeout > 0 {
+			timeout = seconds(cfg.ConnectTimeout)
+		}
+		pp := availablePorts[0]
+		logging.Logger.Infof("Probing %s on %s port %d", targets, pp.Protocol, pp.Port)
+		if targets.probe(context.Background(), pp, timeout) == 0 {
+			logging.Logger.Errorf("No address of %s responded to the probe", cfg.TargetCIDR)
+			os.Exit(1)
+		}
+		logging.Logger.Infof("Sending flows to the %d responding addresses", len(targets.addrs))
+	}
+	// The path toward the first target stands for the sweep when detecting netem
+	if targets != nil {
+		server = targets.pick(1)
+	}
+	services := verifyServices(cfg, server)
+	logServices(services)
+
+	var flowCounter uint64
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	if cfg.LatencyHeatmapSlice > 0 {
+		mc.EnableLatencyHeatmap(start, seconds(cfg.LatencyHeatmapSlice))
+	}
+	tracker := newRunTracker(cfg, start, &flowCounter)
+	tracker.setNetem(detectNetem(constructAddress(server, availablePorts[0].Port)))
+	tracker.setServices(services)
+	failover := newFailoverMonitor(cfg, start)
+	if failover != nil {
+		flowHooks.add(failover.hooks())
+		tracker.setFailover(failover)
+		logging.Logger.Infof("Failing over from %s to %s once %g%% of the flows within %gs fail", cfg.Server, cfg.BackupServer, cfg.FailoverThreshold, cfg.FailoverWindow)
+	}
+	conntrack := newConntrackBackoff(cfg, start)
+	if conntrack != nil {
+		flowHooks.add(conntrack.hooks())
+		tracker.setConntrackBackoff(conntrack)
+		logging.Logger.Infof("Backing off %s", conntrack)
+	}
+	// Phases follow the warmup, whose flows are left out of the statistics as well
+	windows = nil
+	phases = newPhaseMonitor(cfg, start.Add(seconds(cfg.Warmup)))
+	if phases != nil {
+		flowHooks.add(phases.hooks())
+		windows = append(windows, &phases.windowedStats)
+		tracker.setPhases(phases)
+		logging.Logger.Infof("Checking the assertions of phases %s", phases)
+	}
+	warnings = newSoftLimits(cfg, start.Add(seconds(cfg.Warmup)))
+	if warnings != nil {
+		flowHooks.add(warnings.hooks())
+		windows = append(windows, &warnings.windowedStats)
+		tracker.setSoftLimits(warnings)
+		logging.Logger.Infof("Warning about soft limits %s", warnings)
+	}
+	if progress, err = newProgressEvents(cfg, start); err != nil {
+		logging.Logger.Errorf("Failed to open progress events: %v", err)
+		os.Exit(1)
+	}
+	if progress != nil {
+		if cfg.ProgressEvents == "-" {
+			// Keep stdout for the events, like for the result stream
+			mc.SetTableOutput(os.Stderr)
+		}
+		logging.Logger.Infof("Writing progress events to %s", cfg.ProgressEvents)
+	}
+	// In the agent role the listeners are up before the first flow, so peers can reach this node right away
+	var agent *agentServer
+	if cfg.Role == roleAgent {
+		if agent, err = startAgentServer(cfg, tracker); err != nil {
+			logging.Logger.Errorf("Failed to start agent listeners: %v", err)
+			os.Exit(1)
+		}
+	}
+	sup := newSupervisor(sockets, tracker)
+	defer sup.guard()
+	if cfg.Warmup > 0 {
+		mc.SetWarmup(true)
+		logging.Logger.Infof("Warming up for %v, statistics are collected afterwards", seconds(cfg.Warmup))
+		warmupTimer := time.AfterFunc(seconds(cfg.Warmup), func() {
+			mc.SetWarmup(false)
+			logging.Logger.Info("Warmup completed, collecting statistics")
+		})
+		defer warmupTimer.Stop()
+	}
+
+	// Termination signals are handled once the run can be stopped, until then they wait in the channel
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	slots := newFlowSlots(maxConcurrent)
+	var control *runControl
+	if cfg.StatusPort != "" {
+		statusServer := health.NewChecker()
+		statusServer.Handle(runStatusPath, tracker)
+		statusServer.Handle(statsPath, &statsHandler{tracker: tracker, outcomes: outcomes, slots: slots, control: cfg.ControlAPI})
+		statusServer.Handle(webUIPath, webui.Handler(webUIPage))
+		if cfg.ControlAPI {
+			control = newRunControl(tracker)
+			statusServer.Handle(controlPath, control)
+		}
+		if err := statusServer.Start(cfg.StatusPort); err != nil {
+			logging.Logger.Errorf("Failed to start status server: %v", err)
+		}
+		statusServer.SetReady(true)
+		defer func() { _ = statusServer.Stop() }()
+	}
+
+	var dash *dashboard
+	if cfg.TUI {
+		dash = newDashboard(tracker, slots)
+		defer dash.close()
+	}
+	var intervals *intervalReporter
+	if cfg.ReportInterval > 0 {
+		intervals = startIntervalReports(tracker, slots, seconds(cfg.ReportInterval))
+		defer intervals.stop()
+	}
+
+	if stream != nil {
+		statsCtx, stopStats := context.WithCancel(context.Background())
+		defer stopStats()
+		go func() {
+			defer sup.guard()
+			stream.runStats(statsCtx, tracker, seconds(cfg.StatsInterval))
+		}()
+	}
+
+	mainCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Flow count and timeout stop generation by cancelling the main context, alone or together
+	stop := newRunStop(cfg, cancel, tracker)
+	if flowTimeout > 0 {
+		timeoutTimer := time.AfterFunc(seconds(flowTimeout), func() { stop.reached(stopFlowTimeout) })
+		defer timeoutTimer.Stop()
+	}
+
+	// A termination signal stops flow generation like a stop condition, so the active flows end and are
+	// reported. A second one exits right away. Whichever reports the run first sets reported.
+	var reported atomic.Bool
+	go func() {
+		stop.terminate(<-sigChan)
+		sig := <-sigChan
+		if !reported.CompareAndSwap(false, true) {
+			return
+		}
+		logging.Logger.Warnf("Received signal: %v again, exiting without waiting for active flows", sig)
+		tracker.setPhase(phaseTerminated)
+		dash.close()
+		mc.LogMetrics(cfg.LogFormat)
+		code := reportRun(tracker)
+		if agent != nil {
+			agent.stop()
+		}
+		os.Exit(code)
+	}()
+
+	priorities := flowPriorities(cfg)
+	// The launch source only picks ports, the flows draw everything else from their own source
+	// #nosec G404 - math/rand is sufficient for flow scheduling randomization
+	src := rand.New(rand.NewPCG(flowSeed, flowSeed))
+
+	var startGaps startGapTracker
+
+	// launchFlow starts a single flow if limits allow it and reports whether generation may continue.
+	// The tick interval is used to spread flow starts when port start offsets are enabled.
+	launchFlow := func(tickInterval time.Duration) bool {
+		if flowCount > 0 && atomic.LoadUint64(&flowCounter) >= uint64(flowCount) && stop.reached(stopFlowCount) {
+			return false
+		}
+		// Ports and rate may change on reload, so pick them before handing off the flow
+		portIndex := src.IntN(len(availablePorts))
+		pp := availablePorts[portIndex]
+		priority := portPriority(priorities, pp.Port)
+		slot, preempted := slots.acquire(mainCtx, priority)
+		if slot == nil {
+			mc.IncFlowsSkipped(priority)
+			logging.Logger.Debugf("Max concurrent flows (%d) reached, skipping %s priority flow generation", maxConcurrent, priority)
+			return true
+		}
+		if preempted {
+			mc.IncFlowsPreempted()
+			logging.Logger.Debugf("Max concurrent flows (%d) reached, preempting a low priority flow", maxConcurrent)
+		}
+
+		// Increment flow counter atomically
+		flowID := atomic.AddUint64(&flowCounter, 1)
+		mc.ObserveFlowStarted()
+		flowSrc := flowRand(flowSeed, flowID)
+		var offset time.Duration
+		if cfg.PortStartOffsets {
+			offset = portStartOffset(flowSrc, portIndex, len(availablePorts), tickInterval)
+		}
+		var duration float64
+		if constantFlows {
+			duration = float64(maxConcurrent) / rate
+			if duration < minDuration {
+				logging.Logger.Warnf("Duration %f less than min_duration %f; adjusting max_concurrent may be required", duration, minDuration)
+			}
+		} else {
+			duration = minDuration + flowSrc.Float64()*(maxDuration-minDuration)
+		}
+		target := server
+		switch {
+		case targets != nil:
+			target = targets.pick(flowID)
+		case failover != nil:
+			target = failover.target(flowID)
+		}
+		flowHooks.emit(hookScheduled, hooks.Event{FlowID: flowID, Protocol: pp.Protocol, Port: pp.Port, Duration: seconds(duration), Time: time.Now()})
+		wg.Add(1) // Track this flow
+		go func() {
+			defer sup.guard()
+			defer slots.release(slot)
+			if offset > 0 {
+				select {
+				case <-time.After(offset):
+				case <-slot.ctx.Done():
+					emitFlowResult(flowID, pp, duration, time.Now(), nil, context.Cause(slot.ctx))
+					wg.Done()
+					return
+				}
+			}
+			if ratio, burstiness, ok := startGaps.observe(); ok {
+				mc.ObserveFlowStartGap(ratio, burstiness)
+			}
+			generateFlow(slot.ctx, flowID, target, pp, duration, flowSrc, mtu, mss, &wg)
+		}()
+		return true
+	}
+
+	ticksPerSecond, flowsPerTick := flowPacing(cfg)
+	if cfg.BurstSize > 0 {
+		logging.Logger.Infof("Burst mode enabled: %d flows every %gs", flowsPerTick, cfg.BurstInterval)
+	}
+	// A stepped load profile replaces the rate, moving from step to step until the last one is over
+	steps := newLoadSteps(cfg, start)
+	var stepChanges chan int
+	if steps != nil {
+		steps.enter(0)
+		rate = steps.rate()
+		ticksPerSecond = rate
+		tracker.setSteps(steps)
+		flowHooks.add(steps.hooks())
+		stepChanges = make(chan int)
+		go func() {
+			defer sup.guard()
+			steps.run(mainCtx, stepChanges)
+		}()
+		logging.Logger.Infof("Following load steps %s", steps)
+	}
+	// The hooks are handed to the dispatcher when it starts, so all of them must be registered by now
+	flowHooks.start(hookQueueSize, hooks.Registered())
+	schedule := newFlowScheduler(time.Now(), ticksPerSecond)
+	timer := time.NewTimer(time.Until(schedule.next()))
+	configuredRate := ticksPerSecond * float64(flowsPerTick)
+	mc.SetEffectiveFlowRate(configuredRate)
+	tracker.setRates(configuredRate, configuredRate)
+	startGaps.setRate(configuredRate)
+
+	// The server may ask for a lower rate while it is overloaded
+	var rateMultipliers chan float64
+	if watcher := newBackpressureWatcher(cfg); watcher != nil {
+		rateMultipliers = make(chan float64)
+		go func() {
+			defer sup.guard()
+			watcher.run(mainCtx, rateMultipliers)
+		}()
+	}
+	rateMultiplier := 1.0
+	// A full conntrack table on the path is probed at a lower rate until it recovered
+	var backoffMultipliers chan float64
+	if conntrack != nil {
+		backoffMultipliers = make(chan float64)
+		go func() {
+			defer sup.guard()
+			conntrack.run(mainCtx, backoffMultipliers)
+		}()
+	}
+	backoffMultiplier := 1.0
+	paused := false
+	// pauseWindow is the pause window the run is in, which suspends flow generation like a pause
+	pauseWindow := ""
+	var pauseChanges chan string
+	if windows := newPauseWindows(cfg); windows != nil {
+		pauseChanges = make(chan string)
+		go func() {
+			defer sup.guard()
+			windows.run(mainCtx, pauseChanges)
+		}()
+		logging.Logger.Infof("Starting no new flows during the daily pause windows %s", windows)
+	}
+	if warnings != nil {
+		go func() {
+			defer sup.guard()
+			warnings.run(mainCtx)
+		}()
+	}
+	if progress != nil {
+		progress.runStarted(time.Now(), flowSeed)
+		go func() {
+			defer sup.guard()
+			progress.run(mainCtx, phases)
+		}()
+	}
+	transition := seconds(cfg.RateTransition)
+	effectiveRate := configuredRate
+	var ramp *rateRamp
+
+	// setTickRate paces the schedule at the given tick rate and publishes the resulting flow rate
+	setTickRate := func(tickRate float64) {
+		schedule.setRate(tickRate)
+		effectiveRate = tickRate * float64(flowsPerTick)
+		mc.SetEffectiveFlowRate(effectiveRate)
+		tracker.setRates(ticksPerSecond*float64(flowsPerTick), effectiveRate)
+		startGaps.setRate(effectiveRate)
+	}
+
+	// applyPacing moves to the rate given by the pacing and the backpressure and conntrack backoff multipliers
+	// after any of them changed. With a rate transition configured the rate is ramped in instead of changed at
+	// once, and every change is recorded in the run timeline.
+	applyPacing := func(reason string) {
+		now := time.Now()
+		fromRate := effectiveRate
+		target := ticksPerSecond * rateMultiplier * backoffMultiplier
+		targetRate := target * float64(flowsPerTick)
+		tracker.addRateChange(now, fromRate, targetRate, transition, reason)
+		if paused || pauseWindow != "" {
+			// The new rate is picked up on resume
+			ramp = nil
+			setTickRate(target)
+			return
+		}
+		if transition > 0 && schedule.rate != target {
+			ramp = &rateRamp{from: schedule.rate, to: target, start: now, period: transition}
+			setTickRate(schedule.rate)
+			logging.Logger.Infof("Flow rate changing from %.2f to %.2f flows per second over %v", fromRate, targetRate, transition)
+		} else {
+			ramp = nil
+			setTickRate(target)
+			logging.Logger.Infof("Flow rate adjusted to %.2f flows per second", targetRate)
+		}
+		timer.Reset(time.Until(schedule.next()))
+	}
+
+	// nextWake returns when the next tick is due, or the next rate update if a transition is in progress
+	nextWake := func() time.Time {
+		next := schedule.next()
+		if ramp != nil {
+			if step := time.Now().Add(rateRampStep); step.Before(next) {
+				return step
+			}
+		}
+		return next
+	}
+
+	// applyControl applies a command of the control API. Pausing stops starting flows, the active ones
+	// continue, and resuming restarts the schedule from now on instead of catching up.
+	applyControl := func(cmd controlCommand) error {
+		switch cmd.action {
+		case controlPause:
+			if paused {
+				return fmt.Errorf("run is already paused")
+			}
+			paused = true
+			timer.Stop()
+			tracker.setPhase(phasePaused)
+		case controlResume:
+			if !paused {
+				return fmt.Errorf("run is not paused")
+			}
+			paused = false
+			if pauseWindow != "" {
+				logging.Logger.Infof("Resuming flow generation once pause window %s ends", pauseWindow)
+				return nil
+			}
+			schedule = newFlowScheduler(time.Now(), schedule.rate)
+			tracker.setPhase(phaseRunning)
+			timer.Reset(time.Until(nextWake()))
+		case controlRate:
+			if cfg.BurstSize > 0 {
+				return fmt.Errorf("the rate cannot be changed in burst mode")
+			}
+			if steps != nil {
+				return fmt.Errorf("the rate cannot be changed while following load steps")
+			}
+			rate = cmd.rate
+			ticksPerSecond = cmd.rate
+			applyPacing("control")
+		}
+		return nil
+	}
+	var controlCommands chan controlCommand
+	if control != nil {
+		controlCommands = control.commands
+	}
+
+	// Reload rate, ports and log level on SIGHUP
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+
+	for {
+		select {
+		case <-reloadChan:
+			logging.Logger.Info("Received SIGHUP, reloading configuration")
+			newCfg, err := config.LoadClientConfig()
+			if err != nil {
+				logging.Logger.Errorf("Keeping current configuration, reload failed: %v", err)
+				continue
+			}
+			ports := buildAvailablePorts(newCfg)
+			if len(ports) == 0 {
+				logging.Logger.Error("Keeping current configuration, no valid ports available for the selected protocol")
+				continue
+			}
+			logging.SetLevel(newCfg.LogLevel)
+			availablePorts = ports
+			rate = newCfg.Rate
+			ticksPerSecond, flowsPerTick = flowPacing(newCfg)
+			if steps != nil {
+				// The load steps keep pacing the run, they only take effect after a restart
+				rate = steps.rate()
+				ticksPerSecond = rate
+			}
+			transition = seconds(newCfg.RateTransition)
+			applyPacing("reload")
+			if agent != nil {
+				agent.reload(newCfg)
+			}
+			if restartRequired(cfg, newCfg) {
+				logging.Logger.Warn("Some changed settings only take effect after a restart")
+			}
+			logging.Logger.Infof("Configuration reloaded, generating flows for %d ports", len(availablePorts))
+		case rateMultiplier = <-rateMultipliers:
+			applyPacing("backpressure")
+		case backoffMultiplier = <-backoffMultipliers:
+			applyPacing("conntrack_backoff")
+		case i := <-stepChanges:
+			steps.enter(i)
+			if i == len(steps.steps) {
+				stop.reached(stopSteps)
+				continue
+			}
+			rate = steps.rate()
+			ticksPerSecond = rate
+			applyPacing("step " + steps.steps[i].Name)
+		case cmd := <-controlCommands:
+			cmd.done <- applyControl(cmd)
+		case window := <-pauseChanges:
+			now := time.Now()
+			tracker.setPauseWindow(now, window)
+			switch {
+			case window != "":
+				if pauseWindow == "" && !paused {
+					timer.Stop()
+					tracker.setPhase(phasePaused)
+				}
+				logging.Logger.Infof("Pause window %s started, no new flows are started until it ends", window)
+			case !paused:
+				// Like a resume, the schedule restarts without catching up on the flows missed in the window
+				schedule = newFlowScheduler(now, schedule.rate)
+				tracker.setPhase(phaseRunning)
+				timer.Reset(time.Until(nextWake()))
+				logging.Logger.Infof("Pause window %s ended, starting flows again", pauseWindow)
+			default:
+				logging.Logger.Infof("Pause window %s ended, flow generation stays paused", pauseWindow)
+			}
+			pauseWindow = window
+		case now := <-timer.C:
+			if ramp != nil {
+				tickRate, done := ramp.at(now)
+				setTickRate(tickRate)
+				if done {
+					ramp = nil
+					logging.Logger.Infof("Flow rate reached %.2f flows per second", effectiveRate)
+				}
+			}
+			if now.Before(schedule.next()) {
+				// Woken up only to update the rate of a transition
+				timer.Reset(time.Until(nextWake()))
+				continue
+			}
+			schedule.fire(now)
+			timer.Reset(time.Until(nextWake()))
+			launchTick(flowsPerTick, time.Duration(float64(time.Second)/schedule.rate), launchFlow)
+		case <-mainCtx.Done():
+			timer.Stop()
+			tracker.setPhase(phaseDraining)
+			logging.Logger.Info("Flow generation stopped, waiting for active flows to complete")
+			shutdownTimeout := seconds(cfg.ShutdownTimeout)
+			drained := drainFlows(&wg, shutdownTimeout)
+			if !reported.CompareAndSwap(false, true) {
+				// A second termination signal is reporting the run and exiting
+				select {}
+			}
+			terminated := stop.wasTerminated()
+			if terminated {
+				tracker.setPhase(phaseTerminated)
+			} else {
+				tracker.setPhase(phaseCompleted)
+			}
+			if pool != nil {
+				pool.closeAll()
+			}
+			if drained {
+				logging.Logger.Info("All flows completed")
+			} else {
+				logging.Logger.Warnf("Active flows did not end within the shutdown timeout of %v, reporting the run without them", shutdownTimeout)
+			}
+			intervals.stop()
+			// The final frame stays on screen above the metric tables
+			dash.close()
+			mc.LogMetrics(cfg.LogFormat) // Log metrics after flows complete
+			code := reportRun(tracker)
+			if agent != nil {
+				// The run is reported, so termination only has to stop the listeners from now on
+				signal.Stop(sigChan)
+				if terminated {
+					agent.stop()
+				} else {
+					agent.serveUntilTerminated()
+				}
+			}
+			return code
+		}
+	}
+}
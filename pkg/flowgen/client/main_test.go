@@ -1,15 +1,20 @@
-package main
+package client
 
 import (
 	"context"
+	"io"
 	"math/rand/v2"
 	"net"
+	"strconv"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/PhilipSchmid/flow-generator-app/internal/bufpool"
 	"github.com/PhilipSchmid/flow-generator-app/internal/config"
 	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
 	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -35,6 +40,62 @@ func TestConstructAddress(t *testing.T) {
 	}
 }
 
+func TestResolveSeed(t *testing.T) {
+	assert.Equal(t, uint64(42), resolveSeed(42))
+	assert.NotZero(t, resolveSeed(0))
+	assert.NotEqual(t, resolveSeed(0), resolveSeed(0), "random seeds should differ between runs")
+}
+
+func TestFlowRandReproducible(t *testing.T) {
+	oldCfg := cfg
+	cfg = &config.ClientConfig{MinPayloadSize: 100, MaxPayloadSize: 1400}
+	defer func() { cfg = oldCfg }()
+
+	type flowValues struct {
+		port     int
+		duration float64
+		payloads [3]int
+	}
+	ports := []ProtocolPort{{"tcp", 8080}, {"tcp", 8081}, {"udp", 9000}, {"udp", 9001}}
+	// run launches flows as the client does: the port from the launch source, the rest from a per-flow
+	// source in concurrently running goroutines
+	run := func(seed uint64) []flowValues {
+		launch := rand.New(rand.NewPCG(seed, seed))
+		values := make([]flowValues, 50)
+		var wg sync.WaitGroup
+		for i := range values {
+			flowID := uint64(i + 1)
+			port := ports[launch.IntN(len(ports))].Port
+			src := flowRand(seed, flowID)
+			duration := 1 + src.Float64()*9
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v := flowValues{port: port, duration: duration}
+				for j := range v.payloads {
+					v.payloads[j] = getPayloadSize(src)
+				}
+				values[i] = v
+			}()
+		}
+		wg.Wait()
+		return values
+	}
+
+	first := run(42)
+	assert.Equal(t, first, run(42), "the same seed should reproduce the values of every flow")
+	assert.NotEqual(t, first, run(43))
+	assert.NotEqual(t, first[0], first[1], "flows should not share their random values")
+}
+
+func TestIsIPv6(t *testing.T) {
+	assert.False(t, isIPv6(&net.TCPAddr{IP: net.IPv4(10, 0, 0, 1)}))
+	assert.True(t, isIPv6(&net.TCPAddr{IP: net.ParseIP("2001:db8::1")}))
+	assert.True(t, isIPv6(&net.UDPAddr{IP: net.IPv6loopback}))
+	assert.False(t, isIPv6(&net.UDPAddr{IP: net.ParseIP("::ffff:10.0.0.1")}))
+	assert.False(t, isIPv6(&net.UnixAddr{Name: "/tmp/socket"}))
+}
+
 func TestGetPayloadSize(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -82,6 +143,51 @@ func TestGetPayloadSize(t *testing.T) {
 	}
 }
 
+func TestGetUDPSendInterval(t *testing.T) {
+	oldCfg := cfg
+	defer func() { cfg = oldCfg }()
+	src := rand.New(rand.NewPCG(0, 0))
+
+	cfg = &config.ClientConfig{UDPInterval: 0.1}
+	assert.Equal(t, 100*time.Millisecond, getUDPSendInterval(src))
+
+	cfg = &config.ClientConfig{UDPInterval: 0.1, UDPJitter: 0.05}
+	for i := 0; i < 100; i++ {
+		interval := getUDPSendInterval(src)
+		assert.GreaterOrEqual(t, interval, 50*time.Millisecond)
+		assert.LessOrEqual(t, interval, 150*time.Millisecond)
+	}
+
+	// Jitter larger than the interval never yields a negative pause
+	cfg = &config.ClientConfig{UDPInterval: 0.01, UDPJitter: 1}
+	for i := 0; i < 100; i++ {
+		assert.GreaterOrEqual(t, getUDPSendInterval(src), time.Duration(0))
+	}
+}
+
+func TestBuildAvailablePorts(t *testing.T) {
+	logging.InitLogger("json", "error")
+
+	c := &config.ClientConfig{Protocol: "both", TCPPorts: "8080,8443", UDPPorts: "9000"}
+	assert.Equal(t, []ProtocolPort{{"tcp", 8080}, {"tcp", 8443}, {"udp", 9000}}, buildAvailablePorts(c))
+
+	c.Protocol = "udp"
+	assert.Equal(t, []ProtocolPort{{"udp", 9000}}, buildAvailablePorts(c))
+
+	c.UDPPorts = ""
+	assert.Empty(t, buildAvailablePorts(c))
+}
+
+func TestFlowPacing(t *testing.T) {
+	ticksPerSecond, flows := flowPacing(&config.ClientConfig{Rate: 0.2})
+	assert.Equal(t, 0.2, ticksPerSecond)
+	assert.Equal(t, 1, flows)
+
+	ticksPerSecond, flows = flowPacing(&config.ClientConfig{Rate: 4, BurstSize: 20, BurstInterval: 2})
+	assert.Equal(t, 0.5, ticksPerSecond)
+	assert.Equal(t, 20, flows)
+}
+
 func TestClientConfiguration(t *testing.T) {
 	testCfg := &config.ClientConfig{
 		CommonConfig: config.CommonConfig{
@@ -130,31 +236,6 @@ func TestProtocolPort(t *testing.T) {
 	assert.Equal(t, 8080, pp.Port)
 }
 
-func TestParsePorts(t *testing.T) {
-	logging.InitLogger("json", "error")
-
-	tests := []struct {
-		name     string
-		input    string
-		expected []int
-	}{
-		{"single port", "8080", []int{8080}},
-		{"multiple ports", "8080,8081,8082", []int{8080, 8081, 8082}},
-		{"with spaces", " 8080 , 8081 , 8082 ", []int{8080, 8081, 8082}},
-		{"empty string", "", []int{}},
-		{"invalid port", "8080,invalid,8081", []int{8080, 8081}},
-		{"out of range port", "8080,70000,8081", []int{8080, 8081}},
-		{"negative port", "8080,-1,8081", []int{8080, 8081}},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := parsePorts(tt.input)
-			assert.Equal(t, tt.expected, result)
-		})
-	}
-}
-
 func TestGenerateFlow(t *testing.T) {
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	require.NoError(t, err)
@@ -199,12 +280,64 @@ func TestGenerateFlow(t *testing.T) {
 	var wg sync.WaitGroup
 
 	wg.Add(1)
-	generateFlow(ctx, "127.0.0.1", pp, 0.1, src, 1500, 1460, &wg)
+	generateFlow(ctx, 1, "127.0.0.1", pp, 0.1, src, 1500, 1460, &wg)
 	wg.Wait()
 
 	assert.True(t, true)
 }
 
+func TestGenerateFlowLargePayload(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		_, _ = io.Copy(conn, conn)
+	}()
+
+	size := 4 << 20
+	oldCfg, oldCache, oldMc := cfg, payloadCache, mc
+	defer func() { cfg, payloadCache, mc = oldCfg, oldCache, oldMc }()
+	cfg = &config.ClientConfig{PayloadSize: size}
+	payloadCache = newPayloadCache(size)
+	mc = metrics.NewMetricsCollector()
+
+	pp := ProtocolPort{Protocol: "tcp", Port: listener.Addr().(*net.TCPAddr).Port}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	generateFlow(context.Background(), 1, "127.0.0.1", pp, 0.5, rand.New(rand.NewPCG(0, 0)), 1500, 1460, &wg)
+	wg.Wait()
+
+	port := strconv.Itoa(pp.Port)
+	assert.Equal(t, float64(size), testutil.ToFloat64(mc.BytesReceived.WithLabelValues("tcp", port)))
+	// The response takes many reads, so its read throughput is recorded
+	assert.Equal(t, 1, testutil.CollectAndCount(mc.ResponseReadThroughput))
+}
+
+func TestStreamReadSize(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured int
+		expected   int
+		want       int
+	}{
+		{"small response", 0, 100, 1024},
+		{"medium response", 0, 20000, 20000},
+		{"large response", 0, 4 << 20, bufpool.MaxSize},
+		{"configured", 4096, 4 << 20, 4096},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, streamReadSize(tt.configured, tt.expected))
+		})
+	}
+}
+
 func TestMetricsCollectorInterface(t *testing.T) {
 	mc := metrics.NewMetricsCollector()
 
@@ -321,7 +454,7 @@ func BenchmarkGenerateFlow(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		var wg sync.WaitGroup
 		wg.Add(1)
-		generateFlow(ctx, "127.0.0.1", pp, 0.01, src, 1500, 1460, &wg)
+		generateFlow(ctx, 1, "127.0.0.1", pp, 0.01, src, 1500, 1460, &wg)
 		wg.Wait()
 	}
 }
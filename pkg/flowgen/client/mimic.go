@@ -0,0 +1,373 @@
+package client
+
+import (
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+)
+
+// wellKnownMimicPorts are the ports whose payloads mimic their protocol with mimic_payloads
+var wellKnownMimicPorts = map[int]string{
+	22:   "ssh",
+	25:   "smtp",
+	53:   "dns",
+	80:   "http",
+	123:  "ntp",
+	443:  "tls",
+	465:  "tls",
+	587:  "smtp",
+	853:  "tls",
+	993:  "tls",
+	995:  "tls",
+	3306: "mysql",
+	5432: "postgres",
+	8080: "http",
+	8443: "tls",
+}
+
+// mimicProtocol builds the first message a client of an application protocol sends
+type mimicProtocol struct {
+	// datagram and stream tell whether the protocol runs over UDP and over TCP
+	datagram, stream bool
+	// build returns a message padded toward size where the protocol has room for padding. Messages that
+	// cannot be padded keep their own size, and no message is shorter than the protocol needs.
+	build func(size int, stream bool) []byte
+}
+
+// mimicProtocols are the protocols payloads can mimic, by the names in config.ValidMimicProtocols
+var mimicProtocols = map[string]mimicProtocol{
+	"dns":      {datagram: true, stream: true, build: mimicDNS},
+	"http":     {stream: true, build: mimicHTTP},
+	"mysql":    {stream: true, build: mimicMySQL},
+	"ntp":      {datagram: true, build: mimicNTP},
+	"postgres": {stream: true, build: mimicPostgres},
+	"smtp":     {stream: true, build: mimicSMTP},
+	"ssh":      {stream: true, build: mimicSSH},
+	"tls":      {stream: true, build: mimicTLS},
+}
+
+// mimicHosts are the names DNS queries, HTTP requests and TLS server names are made for
+var mimicHosts = []string{"www.example.com", "api.example.net", "cdn.example.org", "mail.example.com", "login.example.net"}
+
+// payloadMimicry replaces the payloads of flows to the ports configured with mimic_payloads and mimic_ports
+// with messages of the ports' protocols, so DPI-based policy engines classify the flows
+type payloadMimicry struct {
+	ports map[int]string
+}
+
+// newPayloadMimicry returns the mimicry of the well-known ports if mimic_payloads is enabled and of the ports
+// of mimic_ports, or nil if payloads follow the payload pattern
+func newPayloadMimicry(c *config.ClientConfig) *payloadMimicry {
+	if !c.MimicPayloads && c.MimicPorts == "" {
+		return nil
+	}
+	m := &payloadMimicry{ports: make(map[int]string)}
+	if c.MimicPayloads {
+		for port, protocol := range wellKnownMimicPorts {
+			m.ports[port] = protocol
+		}
+	}
+	// The ports were checked when the configuration was validated
+	ports, _ := config.ParsePortMap(c.MimicPorts)
+	for port, protocol := range ports {
+		m.ports[port] = protocol
+	}
+	return m
+}
+
+// String describes the mimicked ports for the logs
+func (m *payloadMimicry) String() string {
+	ports := slices.Sorted(maps.Keys(m.ports))
+	parts := make([]string, len(ports))
+	for i, port := range ports {
+		parts[i] = fmt.Sprintf("%d=%s", port, m.ports[port])
+	}
+	return strings.Join(parts, ",")
+}
+
+// protocol returns the name of the protocol mimicked on port, or empty if the port is not mimicked or its
+// protocol does not run over the flow's transport
+func (m *payloadMimicry) protocol(port int, datagram bool) string {
+	name, ok := m.ports[port]
+	if !ok {
+		return ""
+	}
+	if p := mimicProtocols[name]; (datagram && !p.datagram) || (!datagram && !p.stream) {
+		return ""
+	}
+	return name
+}
+
+// payload returns a message of the protocol mimicked on port of about size bytes and the protocol's name, or
+// nil if the port's payloads are not mimicked
+func (m *payloadMimicry) payload(port int, datagram bool, size int) ([]byte, string) {
+	name := m.protocol(port, datagram)
+	if name == "" {
+		return nil, ""
+	}
+	return mimicProtocols[name].build(size, !datagram), name
+}
+
+// mimicDNS builds a recursive A query with an EDNS(0) OPT record, padded with the EDNS padding option
+// (RFC 7830). Over TCP the query is prefixed with its length.
+func mimicDNS(size int, stream bool) []byte {
+	var b []byte
+	if stream {
+		b = append(b, 0, 0)
+	}
+	b = binary.BigEndian.AppendUint16(b, uint16(rand.Uint32()))
+	// Flags with recursion desired, one question and one additional record
+	b = append(b, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 1)
+	for _, label := range strings.Split(mimicHosts[rand.IntN(len(mimicHosts))], ".") {
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	// Root label, QTYPE A and QCLASS IN
+	b = append(b, 0, 0, 1, 0, 1)
+	// OPT record of the root domain advertising a 1232 byte UDP payload
+	b = append(b, 0, 0, 41, 0x04, 0xd0, 0, 0, 0, 0)
+	padding := min(max(size-len(b)-6, 0), 0xffff-4)
+	b = binary.BigEndian.AppendUint16(b, uint16(4+padding))
+	b = append(b, 0, 12)
+	b = binary.BigEndian.AppendUint16(b, uint16(padding))
+	b = append(b, make([]byte, padding)...)
+	if stream {
+		binary.BigEndian.PutUint16(b, uint16(len(b)-2))
+	}
+	return b
+}
+
+// ntpEpochOffset is the number of seconds from the NTP epoch in 1900 to the Unix epoch
+const ntpEpochOffset = 2208988800
+
+// mimicNTP builds an NTPv4 client request carrying the current time as its transmit timestamp. NTP requests
+// have a fixed size of 48 bytes.
+func mimicNTP(int, bool) []byte {
+	b := make([]byte, 48)
+	// Leap indicator 0, version 4, client mode, poll interval 2^6 seconds and a precision of 2^-20 seconds
+	b[0], b[2], b[3] = 0x23, 6, 0xec
+	now := time.Now()
+	binary.BigEndian.PutUint32(b[40:], uint32(now.Unix()+ntpEpochOffset))
+	binary.BigEndian.PutUint32(b[44:], uint32((uint64(now.Nanosecond())<<32)/uint64(time.Second)))
+	return b
+}
+
+// mimicHTTP builds an HTTP/1.1 GET request, padded with an X-Padding header
+func mimicHTTP(size int, _ bool) []byte {
+	request := fmt.Sprintf("GET /index.html HTTP/1.1\r\nHost: %s\r\nUser-Agent: flow-generator\r\nAccept: */*\r\n", mimicHosts[rand.IntN(len(mimicHosts))])
+	if padding := size - len(request) - len("X-Padding: \r\n\r\n"); padding > 0 {
+		request += "X-Padding: " + strings.Repeat("x", padding) + "\r\n"
+	}
+	return []byte(request + "\r\n")
+}
+
+// tlsMaxRecord is the largest plaintext TLS record
+const tlsMaxRecord = 1 << 14
+
+// mimicTLS builds a TLS 1.3 ClientHello record with the server name, groups, signature algorithms, key share
+// and ALPN extensions browsers send, padded with the padding extension (RFC 7685)
+func mimicTLS(size int, _ bool) []byte {
+	random := func(n int) []byte {
+		b := make([]byte, n)
+		fillRandom(b, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+		return b
+	}
+	host := mimicHosts[rand.IntN(len(mimicHosts))]
+	var ext []byte
+	addExtension := func(typ uint16, data []byte) {
+		ext = binary.BigEndian.AppendUint16(ext, typ)
+		ext = binary.BigEndian.AppendUint16(ext, uint16(len(data)))
+		ext = append(ext, data...)
+	}
+	// server_name with a single host name
+	sni := binary.BigEndian.AppendUint16(nil, uint16(len(host)+3))
+	sni = append(sni, 0)
+	sni = binary.BigEndian.AppendUint16(sni, uint16(len(host)))
+	addExtension(0, append(sni, host...))
+	// supported_groups x25519 and secp256r1, signature_algorithms and supported_versions TLS 1.3 and 1.2
+	addExtension(10, []byte{0, 4, 0x00, 0x1d, 0x00, 0x17})
+	addExtension(13, []byte{0, 8, 0x04, 0x03, 0x08, 0x04, 0x04, 0x01, 0x05, 0x03})
+	addExtension(43, []byte{4, 0x03, 0x04, 0x03, 0x03})
+	// key_share with an x25519 key
+	addExtension(51, append([]byte{0, 36, 0x00, 0x1d, 0, 32}, random(32)...))
+	// application_layer_protocol_negotiation h2 and http/1.1
+	addExtension(16, []byte{0, 12, 2, 'h', '2', 8, 'h', 't', 't', 'p', '/', '1', '.', '1'})
+
+	hello := []byte{0x03, 0x03}
+	hello = append(hello, random(32)...)
+	hello = append(hello, 32)
+	hello = append(hello, random(32)...)
+	// TLS 1.3 suites followed by ECDHE suites of TLS 1.2, and the null compression method
+	hello = append(hello, 0, 18, 0x13, 0x01, 0x13, 0x02, 0x13, 0x03, 0xc0, 0x2b, 0xc0, 0x2f, 0xc0, 0x2c, 0xc0, 0x30, 0xcc, 0xa9, 0xcc, 0xa8, 1, 0)
+
+	// Record header, handshake header, hello, extensions length and the padding extension header
+	unpadded := 5 + 4 + len(hello) + 2 + len(ext)
+	if padding := min(size, tlsMaxRecord+5) - unpadded - 4; padding >= 0 {
+		addExtension(21, make([]byte, padding))
+	}
+	hello = binary.BigEndian.AppendUint16(hello, uint16(len(ext)))
+	hello = append(hello, ext...)
+
+	b := []byte{0x16, 0x03, 0x01}
+	b = binary.BigEndian.AppendUint16(b, uint16(4+len(hello)))
+	b = append(b, 1, byte(len(hello)>>16), byte(len(hello)>>8), byte(len(hello)))
+	return append(b, hello...)
+}
+
+// sshPacketMaxPadding is the most random padding an SSH packet can carry
+const sshPacketMaxPadding = 255
+
+// sshMsgIgnore and sshMsgKexinit are the SSH message numbers of the messages the client sends
+const (
+	sshMsgIgnore  = 2
+	sshMsgKexinit = 20
+)
+
+// mimicSSH builds the SSH version banner followed by the KEXINIT packet, padded with random packet padding
+// or with an SSH_MSG_IGNORE packet carrying a string, which servers discard
+func mimicSSH(size int, _ bool) []byte {
+	b := []byte("SSH-2.0-OpenSSH_9.6\r\n")
+	payload := []byte{sshMsgKexinit}
+	cookie := make([]byte, 16)
+	fillRandom(cookie, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+	payload = append(payload, cookie...)
+	for _, list := range []string{
+		"curve25519-sha256,ecdh-sha2-nistp256,diffie-hellman-group14-sha256", "ssh-ed25519,rsa-sha2-512,rsa-sha2-256",
+		"chacha20-poly1305@openssh.com,aes128-gcm@openssh.com,aes256-ctr", "chacha20-poly1305@openssh.com,aes128-gcm@openssh.com,aes256-ctr",
+		"hmac-sha2-256-etm@openssh.com,hmac-sha2-256", "hmac-sha2-256-etm@openssh.com,hmac-sha2-256",
+		"none,zlib@openssh.com", "none,zlib@openssh.com", "", "",
+	} {
+		payload = binary.BigEndian.AppendUint32(payload, uint32(len(list)))
+		payload = append(payload, list...)
+	}
+	// No guessed key exchange packet follows, and the reserved field
+	payload = append(payload, 0, 0, 0, 0, 0)
+	// Packets take at least 4 bytes of padding. Beyond the most the KEXINIT packet can take, an
+	// SSH_MSG_IGNORE packet takes the rest.
+	padding := size - len(b) - 5 - len(payload)
+	if padding > sshPacketMaxPadding {
+		padding = 4
+	}
+	b = appendSSHPacket(b, payload, max(padding, 4))
+	// The ignored packet takes its length, padding length, message number, string length and 4 bytes of
+	// padding besides its string
+	if data := size - len(b) - 14; data >= 0 {
+		ignore := binary.BigEndian.AppendUint32([]byte{sshMsgIgnore}, uint32(data))
+		b = appendSSHPacket(b, append(ignore, make([]byte, data)...), 4)
+	}
+	return b
+}
+
+// appendSSHPacket appends an unencrypted SSH packet carrying payload with the given length of random padding
+func appendSSHPacket(b, payload []byte, padding int) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(1+len(payload)+padding))
+	b = append(b, byte(padding))
+	b = append(b, payload...)
+	padded := make([]byte, padding)
+	fillRandom(padded, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+	return append(b, padded...)
+}
+
+// mimicSMTP builds the EHLO command of an SMTP client, padded with a NOOP command carrying a string
+func mimicSMTP(size int, _ bool) []byte {
+	command := "EHLO client.example.com\r\n"
+	if padding := size - len(command) - len("NOOP \r\n"); padding > 0 {
+		command += "NOOP " + strings.Repeat("x", padding) + "\r\n"
+	}
+	return []byte(command)
+}
+
+// MySQL client capabilities of the handshake response
+const (
+	mysqlLongPassword     = 1 << 0
+	mysqlLongFlag         = 1 << 2
+	mysqlProtocol41       = 1 << 9
+	mysqlTransactions     = 1 << 13
+	mysqlSecureConnection = 1 << 15
+	mysqlPluginAuth       = 1 << 19
+	mysqlConnectAttrs     = 1 << 20
+)
+
+// mimicMySQL builds the handshake response a MySQL client answers the server greeting with, padded with a
+// connection attribute
+func mimicMySQL(size int, _ bool) []byte {
+	body := binary.LittleEndian.AppendUint32(nil, mysqlLongPassword|mysqlLongFlag|mysqlProtocol41|mysqlTransactions|mysqlSecureConnection|mysqlPluginAuth|mysqlConnectAttrs)
+	// Maximum packet size, utf8mb4 character set and the reserved filler
+	body = binary.LittleEndian.AppendUint32(body, 1<<24)
+	body = append(body, 45)
+	body = append(body, make([]byte, 23)...)
+	body = append(body, "flowgen\x00"...)
+	auth := make([]byte, 20)
+	fillRandom(auth, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+	body = append(body, byte(len(auth)))
+	body = append(body, auth...)
+	body = append(body, "mysql_native_password\x00"...)
+
+	attrs := mysqlLengthEncoded(nil, "_client_name")
+	attrs = mysqlLengthEncoded(attrs, "flow-generator")
+	// The padding attribute takes what is left after its key and the length prefixes of its value and of the
+	// attributes, whose sizes depend on the lengths they encode
+	rest := size - 4 - len(body) - len(attrs) - 1 - len("_padding")
+	padding := rest - 2
+	for padding > 0 && mysqlLengthSize(padding)+padding+mysqlLengthSize(len(attrs)+1+len("_padding")+mysqlLengthSize(padding)+padding) > rest {
+		padding--
+	}
+	if padding > 0 {
+		attrs = mysqlLengthEncoded(attrs, "_padding")
+		attrs = mysqlLengthEncoded(attrs, strings.Repeat("x", padding))
+	}
+	body = mysqlLengthEncoded(body, string(attrs))
+
+	// Packet header with the 3 byte length and sequence number 1, as the response follows the greeting
+	b := []byte{byte(len(body)), byte(len(body) >> 8), byte(len(body) >> 16), 1}
+	return append(b, body...)
+}
+
+// mysqlLengthSize returns the size of the length prefix of a MySQL length-encoded string of n bytes
+func mysqlLengthSize(n int) int {
+	switch {
+	case n < 251:
+		return 1
+	case n < 1<<16:
+		return 3
+	}
+	return 4
+}
+
+// mysqlLengthEncoded appends s to b as a MySQL length-encoded string
+func mysqlLengthEncoded(b []byte, s string) []byte {
+	switch mysqlLengthSize(len(s)) {
+	case 1:
+		b = append(b, byte(len(s)))
+	case 3:
+		b = append(b, 0xfc)
+		b = binary.LittleEndian.AppendUint16(b, uint16(len(s)))
+	default:
+		b = append(b, 0xfd, byte(len(s)), byte(len(s)>>8), byte(len(s)>>16))
+	}
+	return append(b, s...)
+}
+
+// mimicPostgres builds the startup message of a PostgreSQL client for protocol 3.0, padded with the
+// application name
+func mimicPostgres(size int, _ bool) []byte {
+	params := "user\x00flowgen\x00database\x00postgres\x00application_name\x00"
+	// Length, protocol version, parameters, the application name and the terminators
+	name := "flow-generator"
+	if padding := size - 8 - len(params) - 2; padding > len(name) {
+		name += strings.Repeat("x", padding-len(name))
+	}
+	b := binary.BigEndian.AppendUint32(nil, 0)
+	b = binary.BigEndian.AppendUint32(b, 3<<16)
+	b = append(b, params...)
+	b = append(b, name...)
+	b = append(b, 0, 0)
+	binary.BigEndian.PutUint32(b, uint32(len(b)))
+	return b
+}
@@ -0,0 +1,141 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPayloadMimicry(t *testing.T) {
+	assert.Nil(t, newPayloadMimicry(&config.ClientConfig{}))
+
+	m := newPayloadMimicry(&config.ClientConfig{MimicPorts: "8053=dns,443=http"})
+	require.NotNil(t, m)
+	assert.Equal(t, "443=http,8053=dns", m.String())
+
+	m = newPayloadMimicry(&config.ClientConfig{MimicPayloads: true, MimicPorts: "443=http"})
+	assert.Equal(t, "http", m.protocol(443, false), "mimic_ports override the well-known ports")
+	assert.Equal(t, "ntp", m.protocol(123, true))
+	assert.Empty(t, m.protocol(123, false), "NTP only runs over UDP")
+	assert.Empty(t, m.protocol(9999, false))
+
+	payload, name := m.payload(53, true, 200)
+	assert.Equal(t, "dns", name)
+	assert.Len(t, payload, 200)
+	payload, name = m.payload(9999, false, 200)
+	assert.Nil(t, payload)
+	assert.Empty(t, name)
+}
+
+func TestMimicSizes(t *testing.T) {
+	tests := []struct {
+		protocol string
+		size     int
+		stream   bool
+		want     int
+	}{
+		{"dns", 512, false, 512},
+		{"dns", 512, true, 512},
+		{"http", 1000, true, 1000},
+		{"mysql", 1000, true, 1000},
+		{"ntp", 1000, false, 48},
+		{"postgres", 1000, true, 1000},
+		{"smtp", 1000, true, 1000},
+		{"ssh", 500, true, 500},
+		{"ssh", 1000, true, 1000},
+		{"tls", 1000, true, 1000},
+		{"tls", 1 << 20, true, tlsMaxRecord + 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.protocol, func(t *testing.T) {
+			assert.Len(t, mimicProtocols[tt.protocol].build(tt.size, tt.stream), tt.want)
+		})
+	}
+
+	// Messages keep their own size when they are larger than requested
+	assert.Greater(t, len(mimicDNS(10, false)), 10)
+	assert.Greater(t, len(mimicTLS(10, true)), 10)
+}
+
+func TestMimicDNS(t *testing.T) {
+	b := mimicDNS(300, true)
+	require.Len(t, b, 300)
+	assert.Equal(t, uint16(298), binary.BigEndian.Uint16(b), "TCP queries are prefixed with their length")
+
+	msg := b[2:]
+	assert.Equal(t, []byte{0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 1}, msg[2:12])
+	// The question name is followed by the type and class, then the OPT record whose data fills the rest
+	i := 12
+	for msg[i] != 0 {
+		i += int(msg[i]) + 1
+	}
+	assert.Equal(t, []byte{0, 0, 1, 0, 1}, msg[i:i+5])
+	opt := msg[i+5:]
+	assert.Equal(t, uint16(41), binary.BigEndian.Uint16(opt[1:]))
+	assert.Equal(t, len(opt)-11, int(binary.BigEndian.Uint16(opt[9:])))
+	assert.Equal(t, uint16(12), binary.BigEndian.Uint16(opt[11:]), "padding option")
+}
+
+func TestMimicTLS(t *testing.T) {
+	client, server := net.Pipe()
+	defer func() { _ = client.Close() }()
+	hellos := make(chan *tls.ClientHelloInfo, 1)
+	go func() {
+		defer func() { _ = server.Close() }()
+		conn := tls.Server(server, &tls.Config{GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			hellos <- hello
+			return nil, context.Canceled
+		}})
+		_ = conn.Handshake()
+	}()
+
+	_ = client.SetDeadline(time.Now().Add(5 * time.Second))
+	_, err := client.Write(mimicTLS(600, true))
+	require.NoError(t, err)
+	select {
+	case hello := <-hellos:
+		assert.Contains(t, mimicHosts, hello.ServerName)
+		assert.Equal(t, []string{"h2", "http/1.1"}, hello.SupportedProtos)
+		assert.Contains(t, hello.SupportedVersions, uint16(tls.VersionTLS13))
+	case <-time.After(5 * time.Second):
+		t.Fatal("the ClientHello was not parsed")
+	}
+}
+
+func TestMimicHTTP(t *testing.T) {
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(mimicHTTP(400, true))))
+	require.NoError(t, err)
+	assert.Equal(t, "GET", req.Method)
+	assert.Contains(t, mimicHosts, req.Host)
+	assert.NotEmpty(t, req.Header.Get("X-Padding"))
+}
+
+func TestMimicLengthPrefixes(t *testing.T) {
+	ssh := mimicSSH(1000, true)
+	banner := bytes.IndexByte(ssh, '\n') + 1
+	assert.Equal(t, "SSH-2.0-OpenSSH_9.6\r\n", string(ssh[:banner]))
+	kexinit := banner + 4 + int(binary.BigEndian.Uint32(ssh[banner:]))
+	assert.Equal(t, byte(sshMsgKexinit), ssh[banner+5])
+	assert.Equal(t, len(ssh)-kexinit-4, int(binary.BigEndian.Uint32(ssh[kexinit:])))
+	assert.Equal(t, byte(sshMsgIgnore), ssh[kexinit+5])
+
+	mysql := mimicMySQL(600, true)
+	assert.Equal(t, len(mysql)-4, int(mysql[0])|int(mysql[1])<<8|int(mysql[2])<<16)
+
+	postgres := mimicPostgres(600, true)
+	assert.Equal(t, len(postgres), int(binary.BigEndian.Uint32(postgres)))
+	assert.Equal(t, uint32(3<<16), binary.BigEndian.Uint32(postgres[4:]))
+
+	assert.Equal(t, []byte{0, 0}, mimicPostgres(10, true)[len(mimicPostgres(10, true))-2:])
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
+)
+
+// netnsGroup is the sender group of a network namespace, the flows dialed from within it
+type netnsGroup struct {
+	name  string
+	file  *os.File
+	share float64
+	// current is the running weight of smooth weighted round-robin
+	current float64
+}
+
+// run runs fn, which dials the connection of a flow, in the network namespace of the group. A nil group
+// runs it in the namespace of the client.
+func (g *netnsGroup) run(fn func() error) error {
+	if g == nil {
+		return fn()
+	}
+	return sockopt.InNetns(g.file, fn)
+}
+
+// netnsGroups spreads flows over the sender groups of network namespaces by their shares, so a single
+// client emulates as many isolated clients as there are namespaces
+type netnsGroups struct {
+	mu      sync.Mutex
+	entries []config.NetnsGroup
+	groups  []*netnsGroup
+	total   float64
+}
+
+// newNetnsGroups opens the network namespaces configured with netns and checks they can be entered, or
+// returns nil if flows are sent from the namespace of the client
+func newNetnsGroups(c *config.ClientConfig) (*netnsGroups, error) {
+	if c.Netns == "" {
+		return nil, nil
+	}
+	// The namespaces were checked when the configuration was validated
+	entries, _ := config.ParseNetns(c.Netns)
+	n := &netnsGroups{entries: entries}
+	for _, e := range entries {
+		// #nosec G304 - the namespace paths are chosen by the operator
+		f, err := os.Open(e.Path)
+		if err != nil {
+			n.close()
+			return nil, fmt.Errorf("failed to open network namespace %s: %w", e.Name, err)
+		}
+		g := &netnsGroup{name: e.Name, file: f, share: e.Share}
+		n.groups = append(n.groups, g)
+		n.total += e.Share
+		if err := g.run(func() error { return nil }); err != nil {
+			n.close()
+			if errors.Is(err, sockopt.ErrUnsupported) {
+				return nil, fmt.Errorf("network namespaces are only supported on Linux")
+			}
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// pick selects the group of the next flow by smooth weighted round-robin, which interleaves the groups and
+// gives each exactly its share of every full round
+func (n *netnsGroups) pick() *netnsGroup {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var best *netnsGroup
+	for _, g := range n.groups {
+		g.current += g.share
+		if best == nil || g.current > best.current {
+			best = g
+		}
+	}
+	best.current -= n.total
+	return best
+}
+
+// close closes the namespace files opened so far
+func (n *netnsGroups) close() {
+	for _, g := range n.groups {
+		_ = g.file.Close()
+	}
+}
+
+// String describes the groups and their share of the flows for the logs
+func (n *netnsGroups) String() string {
+	return describeNetns(n.entries)
+}
+
+// describeNetns describes network namespaces and their share of the flows
+func describeNetns(entries []config.NetnsGroup) string {
+	var total float64
+	for _, e := range entries {
+		total += e.Share
+	}
+	parts := make([]string, len(entries))
+	for i, e := range entries {
+		parts[i] = fmt.Sprintf("%s (%.4g%%)", e.Name, e.Share/total*100)
+	}
+	return strings.Join(parts, ", ")
+}
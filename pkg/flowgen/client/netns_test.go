@@ -0,0 +1,61 @@
+package client
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetnsGroupsPick(t *testing.T) {
+	n := &netnsGroups{
+		groups: []*netnsGroup{{name: "a", share: 2}, {name: "b", share: 1}},
+		total:  3,
+	}
+	var picked []string
+	for range 6 {
+		picked = append(picked, n.pick().name)
+	}
+	assert.Equal(t, []string{"a", "b", "a", "a", "b", "a"}, picked)
+}
+
+func TestDescribeNetns(t *testing.T) {
+	entries, err := config.ParseNetns("client-a=2,client-b")
+	require.NoError(t, err)
+	assert.Equal(t, "client-a (66.67%), client-b (33.33%)", describeNetns(entries))
+}
+
+func TestNetnsGroupRun(t *testing.T) {
+	// Without a group the dial runs as is
+	errDial := errors.New("dial failed")
+	assert.Equal(t, errDial, (*netnsGroup)(nil).run(func() error { return errDial }))
+}
+
+func TestNewNetnsGroups(t *testing.T) {
+	n, err := newNetnsGroups(&config.ClientConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, n)
+
+	_, err = newNetnsGroups(&config.ClientConfig{Netns: filepath.Join(t.TempDir(), "missing")})
+	assert.ErrorContains(t, err, "failed to open network namespace missing")
+
+	// Entering the namespace of the test itself needs CAP_SYS_ADMIN
+	n, err = newNetnsGroups(&config.ClientConfig{Netns: "/proc/self/ns/net=3"})
+	if err != nil {
+		t.Skipf("cannot enter network namespaces: %v", err)
+	}
+	defer n.close()
+	assert.Equal(t, "self (100%)", n.String())
+	g := n.pick()
+	var ran bool
+	require.NoError(t, g.run(func() error {
+		ran = true
+		_, err := os.Stat("/proc/thread-self/ns/net")
+		return err
+	}))
+	assert.True(t, ran)
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// burstinessSmoothing is the weight of the latest gap in the exponentially weighted burstiness average
+const burstinessSmoothing = 0.1
+
+// portStartOffset returns how long to delay a flow to the port at portIndex within the current tick.
+// Each of the numPorts ports owns an equal slot of the tick interval and flows start at a random point
+// within their port's slot, so flows launched in the same tick do not leave in one synchronized burst.
+func portStartOffset(src *rand.Rand, portIndex, numPorts int, tickInterval time.Duration) time.Duration {
+	if numPorts <= 0 || tickInterval <= 0 {
+		return 0
+	}
+	slot := tickInterval / time.Duration(numPorts)
+	offset := time.Duration(portIndex) * slot
+	if slot > 0 {
+		offset += time.Duration(src.Int64N(int64(slot)))
+	}
+	return offset
+}
+
+// startGapTracker compares the observed gaps between flow starts to the intended gap at the configured rate
+type startGapTracker struct {
+	mu         sync.Mutex
+	intended   time.Duration
+	last       time.Time
+	burstiness float64
+}
+
+// setRate updates the intended gap between flow starts from the effective flow rate
+func (g *startGapTracker) setRate(flowsPerSecond float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.intended = time.Duration(float64(time.Second) / flowsPerSecond)
+}
+
+// observe records that a flow started now. It returns the observed gap relative to the intended gap,
+// and the burstiness: the smoothed deviation of that ratio from 1, where 0 means evenly spaced starts
+// and values near 1 mean flows start back-to-back.
+func (g *startGapTracker) observe() (ratio, burstiness float64, ok bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Read the clock under the lock so that gaps are never negative
+	now := time.Now()
+	last := g.last
+	g.last = now
+	if last.IsZero() || g.intended <= 0 {
+		return 0, 0, false
+	}
+
+	ratio = float64(now.Sub(last)) / float64(g.intended)
+	deviation := math.Min(math.Abs(ratio-1), 1)
+	g.burstiness += burstinessSmoothing * (deviation - g.burstiness)
+	return ratio, g.burstiness, true
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPortStartOffset(t *testing.T) {
+	src := rand.New(rand.NewPCG(1, 2))
+	tick := time.Second
+
+	for i := 0; i < 100; i++ {
+		for port := 0; port < 4; port++ {
+			offset := portStartOffset(src, port, 4, tick)
+			assert.GreaterOrEqual(t, offset, time.Duration(port)*250*time.Millisecond)
+			assert.Less(t, offset, time.Duration(port+1)*250*time.Millisecond)
+		}
+	}
+
+	assert.Equal(t, time.Duration(0), portStartOffset(src, 0, 0, tick))
+	assert.Equal(t, time.Duration(0), portStartOffset(src, 1, 2, 0))
+}
+
+func TestStartGapTracker(t *testing.T) {
+	var g startGapTracker
+
+	// Without an intended rate or a previous start, there is nothing to compare
+	_, _, ok := g.observe()
+	assert.False(t, ok)
+
+	g.setRate(1000)
+	time.Sleep(2 * time.Millisecond)
+	ratio, burstiness, ok := g.observe()
+	assert.True(t, ok)
+	assert.Greater(t, ratio, 1.0)
+	assert.Greater(t, burstiness, 0.0)
+
+	// Back-to-back starts drive the burstiness towards 1
+	for i := 0; i < 100; i++ {
+		_, burstiness, _ = g.observe()
+	}
+	assert.Greater(t, burstiness, 0.9)
+	assert.LessOrEqual(t, burstiness, 1.0)
+}
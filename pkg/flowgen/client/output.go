@@ -0,0 +1,210 @@
+package client
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+)
+
+// runResults are the final results of a run written to the output file
+type runResults struct {
+	Run     runStatus       `json:"run"`
+	Metrics metrics.Summary `json:"metrics"`
+	// LatencyHeatmap is the latency heatmap per protocol, only part of the JSON results
+	LatencyHeatmap map[string]metrics.LatencyHeatmap `json:"latency_heatmap,omitempty"`
+}
+
+// reportRun delivers the remaining flow events to the hooks and the flow log, logs the final run report,
+// ends the result stream, checks the SLA assertions of the run and its phases as well as the expected
+// services, writes the run results to the output file, packs the artifact bundle and ships them to the output
+// sinks if they are configured, and finally reports the run completed in the progress events. It returns the
+// exit code of the run.
+func reportRun(t *runTracker) int {
+	flowHooks.stop(hookDrainTimeout)
+	closeFlowLog()
+	phases.finish(time.Now())
+	warnings.finish()
+	logRunReport(t)
+	flushOTLPMetrics()
+	if stream != nil {
+		stream.finish(t, time.Now())
+	}
+	results := runResults{Run: t.status(time.Now()), Metrics: mc.Summary(), LatencyHeatmap: mc.LatencyHeatmaps()}
+	code := 0
+	var violations []string
+	if assertionsEnabled(cfg) {
+		violations = checkAssertions(cfg, results, outcomes.counts())
+		code = reportAssertions(violations)
+	}
+	if len(results.Run.Phases) > 0 {
+		code = max(code, reportPhases(results.Run.Phases))
+	}
+	code = max(code, servicesExitCode(results.Run.Services))
+	if cfg.OutputFile != "" {
+		if err := writeResults(cfg.OutputFile, cfg.OutputFormat, results); err != nil {
+			logging.Logger.Errorf("Failed to write run results: %v", err)
+		} else {
+			logging.Logger.Infof("Run results written to %s", cfg.OutputFile)
+		}
+	}
+	if bundle != nil {
+		if err := bundle.write(results, flowSeed, time.Now()); err != nil {
+			logging.Logger.Errorf("Failed to write artifact bundle: %v", err)
+		} else {
+			logging.Logger.Infof("Run artifacts bundled into %s", bundle.path)
+		}
+	}
+	if artifactSinks != nil {
+		artifactSinks.ship(results)
+	}
+	// The run is only reported completed once its results are written, so orchestrators can pick them up
+	progress.finish(time.Now(), results.Run.Phases, violations, code)
+	return code
+}
+
+// flushOTLPMetrics pushes the final metric values to the OTLP collector if the exporter is enabled
+func flushOTLPMetrics() {
+	if shutdownOTLPMetrics == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownOTLPMetrics(ctx); err != nil {
+		logging.Logger.Warnf("Failed to flush OTLP metrics: %v", err)
+	}
+}
+
+// writeResults writes the run results to the given file in JSON, CSV, JUnit XML or HTML format
+func writeResults(path, format string, results runResults) error {
+	// #nosec G304 - the output path is chosen by the operator
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = encodeResults(f, format, results)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// encodeResults writes the run results to w in JSON, CSV, JUnit XML or HTML format
+func encodeResults(w io.Writer, format string, results runResults) error {
+	switch format {
+	case "csv":
+		return writeResultsCSV(w, results)
+	case "junit":
+		return writeResultsJUnit(w, results)
+	case "html":
+		return writeResultsHTML(w, results)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+// writeResultsCSV writes the run results as metric,protocol,port,value rows
+func writeResultsCSV(w io.Writer, r runResults) error {
+	rows := [][]string{{"metric", "protocol", "port", "value"}}
+	add := func(metric, protocol, port, value string) {
+		rows = append(rows, []string{metric, protocol, port, value})
+	}
+	formatFloat := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	formatUint := func(v uint64) string {
+		return strconv.FormatUint(v, 10)
+	}
+
+	add("scenario", "", "", r.Run.Scenario)
+	for _, key := range slices.Sorted(maps.Keys(r.Run.Metadata)) {
+		add("meta_"+key, "", "", r.Run.Metadata[key])
+	}
+	add("phase", "", "", r.Run.Phase)
+	if r.Run.StopReason != "" {
+		add("stop_reason", "", "", r.Run.StopReason)
+	}
+	add("elapsed_seconds", "", "", formatFloat(r.Run.ElapsedSeconds))
+	if r.Run.RemainingSeconds != nil {
+		add("remaining_seconds", "", "", formatFloat(*r.Run.RemainingSeconds))
+	}
+	add("flows_started", "", "", formatUint(r.Run.FlowsStarted))
+	add("configured_rate", "", "", formatFloat(r.Run.ConfiguredRate))
+	add("effective_rate", "", "", formatFloat(r.Run.EffectiveRate))
+	add("achieved_rate", "", "", formatFloat(r.Run.AchievedRate))
+	if n := r.Run.Netem; n != nil {
+		add("netem_active", "", "", strconv.FormatBool(n.Active))
+		add("netem_interface", "", "", n.Interface)
+		add("netem_delay", "", "", n.Delay)
+		add("netem_loss", "", "", n.Loss)
+	}
+
+	m := r.Metrics
+	add("total_requests_received", "", "", formatUint(m.TotalRequestsReceived))
+	add("total_requests_sent", "", "", formatUint(m.TotalRequestsSent))
+	add("total_tcp_received", "tcp", "", formatUint(m.TotalTCPReceived))
+	add("total_tcp_sent", "tcp", "", formatUint(m.TotalTCPSent))
+	add("total_udp_received", "udp", "", formatUint(m.TotalUDPReceived))
+	add("total_udp_sent", "udp", "", formatUint(m.TotalUDPSent))
+
+	for _, table := range []struct {
+		metric string
+		data   map[string]map[string]uint64
+	}{
+		{"requests_received", m.RequestsReceived},
+		{"requests_sent", m.RequestsSent},
+		{"bytes_received", m.BytesReceived},
+		{"bytes_sent", m.BytesSent},
+		{"wire_bytes_received", m.WireBytesReceived},
+		{"wire_bytes_sent", m.WireBytesSent},
+	} {
+		for _, protocol := range sortedKeys(table.data) {
+			for _, port := range sortedKeys(table.data[protocol]) {
+				add(table.metric, protocol, port, formatUint(table.data[protocol][port]))
+			}
+		}
+	}
+
+	for _, protocol := range sortedKeys(m.Latency) {
+		l := m.Latency[protocol]
+		add("latency_count", protocol, "", formatUint(l.Count))
+		add("latency_min_ms", protocol, "", formatFloat(l.MinMs))
+		add("latency_mean_ms", protocol, "", formatFloat(l.MeanMs))
+		add("latency_p50_ms", protocol, "", formatFloat(l.P50Ms))
+		add("latency_p90_ms", protocol, "", formatFloat(l.P90Ms))
+		add("latency_p99_ms", protocol, "", formatFloat(l.P99Ms))
+		add("latency_max_ms", protocol, "", formatFloat(l.MaxMs))
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write CSV results: %w", err)
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of a map ordered by length, then lexically, so that numeric keys such as ports sort by value
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) < len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
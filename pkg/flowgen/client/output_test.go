@@ -0,0 +1,77 @@
+package client
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/internal/netem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRunResults returns results of a short run with TCP and UDP traffic
+func testRunResults() runResults {
+	remaining := 5.0
+	return runResults{
+		Run: runStatus{Scenario: "ci", Metadata: map[string]string{"owner": "netops", "ticket": "NET-1234"}, Phase: phaseCompleted, ElapsedSeconds: 10, RemainingSeconds: &remaining, FlowsStarted: 20,
+			Netem: &netem.Status{Interface: "eth0", Active: true, Delay: "100ms", Loss: "1%", Qdisc: "netem delay 100ms loss 1%"}},
+		Metrics: metrics.Summary{
+			TotalRequestsSent: 30,
+			TotalTCPSent:      30,
+			RequestsSent:      map[string]map[string]uint64{"tcp": {"8080": 10, "443": 20}},
+			WireBytesSent:     map[string]map[string]uint64{"tcp": {"8080": 540}},
+			Latency:           map[string]metrics.LatencySummary{"tcp": {Count: 30, P99Ms: 1.5}},
+		},
+	}
+}
+
+func TestWriteResultsJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.json")
+	require.NoError(t, writeResults(path, "json", testRunResults()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var parsed runResults
+	require.NoError(t, json.Unmarshal(data, &parsed))
+	assert.Equal(t, testRunResults(), parsed)
+}
+
+func TestWriteResultsCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.csv")
+	require.NoError(t, writeResults(path, "csv", testRunResults()))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+	rows, err := csv.NewReader(f).ReadAll()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"metric", "protocol", "port", "value"}, rows[0])
+	assert.Contains(t, rows, []string{"scenario", "", "", "ci"})
+	assert.Equal(t, []string{"meta_owner", "", "", "netops"}, rows[2])
+	assert.Equal(t, []string{"meta_ticket", "", "", "NET-1234"}, rows[3])
+	assert.Contains(t, rows, []string{"remaining_seconds", "", "", "5"})
+	assert.Contains(t, rows, []string{"netem_active", "", "", "true"})
+	assert.Contains(t, rows, []string{"netem_delay", "", "", "100ms"})
+	assert.Contains(t, rows, []string{"total_tcp_sent", "tcp", "", "30"})
+	assert.Contains(t, rows, []string{"latency_p99_ms", "tcp", "", "1.5"})
+	assert.Contains(t, rows, []string{"wire_bytes_sent", "tcp", "8080", "540"})
+
+	// Ports are ordered numerically
+	var ports []string
+	for _, row := range rows {
+		if row[0] == "requests_sent" {
+			ports = append(ports, row[2])
+		}
+	}
+	assert.Equal(t, []string{"443", "8080"}, ports)
+}
+
+func TestWriteResultsInvalidPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "results.json")
+	assert.Error(t, writeResults(path, "json", testRunResults()))
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+)
+
+// pauseWindows are the daily quiet windows configured with pause_windows, during which no new flows are
+// started while the active ones finish
+type pauseWindows struct {
+	windows []config.PauseWindow
+}
+
+// newPauseWindows returns the pause windows configured with pause_windows, or nil if flows are started
+// around the clock
+func newPauseWindows(c *config.ClientConfig) *pauseWindows {
+	if c.PauseWindows == "" {
+		return nil
+	}
+	// The windows were checked when the configuration was validated
+	windows, _ := config.ParsePauseWindows(c.PauseWindows)
+	return &pauseWindows{windows: windows}
+}
+
+// String describes the windows for the logs
+func (p *pauseWindows) String() string {
+	names := make([]string, len(p.windows))
+	for i, w := range p.windows {
+		names[i] = w.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// at returns the window the given time falls into, empty if none, and when the next window starts or ends
+// after it. Windows are placed on the days of the time's location, so they follow its time zone.
+func (p *pauseWindows) at(t time.Time) (string, time.Time) {
+	var window string
+	var next time.Time
+	y, m, d := t.Date()
+	for _, w := range p.windows {
+		// A window spanning midnight may have started the day before
+		for day := -1; day <= 1; day++ {
+			start := time.Date(y, m, d+day, 0, 0, 0, 0, t.Location()).Add(w.Start)
+			end := time.Date(y, m, d+day, 0, 0, 0, 0, t.Location()).Add(w.End)
+			if !end.After(start) {
+				end = end.AddDate(0, 0, 1)
+			}
+			if window == "" && !t.Before(start) && t.Before(end) {
+				window = w.String()
+			}
+			for _, boundary := range []time.Time{start, end} {
+				if boundary.After(t) && (next.IsZero() || boundary.Before(next)) {
+					next = boundary
+				}
+			}
+		}
+	}
+	return window, next
+}
+
+// run sends the window the run entered on changes whenever it enters or leaves a pause window, an empty one
+// once it left all windows, starting with the window the run starts in if any, until ctx is done
+func (p *pauseWindows) run(ctx context.Context, changes chan<- string) {
+	current := ""
+	for {
+		window, next := p.at(time.Now())
+		if window != current {
+			select {
+			case changes <- window:
+			case <-ctx.Done():
+				return
+			}
+			current = window
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
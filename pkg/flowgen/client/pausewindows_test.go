@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPauseWindowsAt(t *testing.T) {
+	assert.Nil(t, newPauseWindows(&config.ClientConfig{}))
+
+	windows := newPauseWindows(&config.ClientConfig{PauseWindows: "02:00-02:15,23:50-00:10"})
+	require.NotNil(t, windows)
+	assert.Equal(t, "02:00-02:15, 23:50-00:10", windows.String())
+
+	day := func(d, h, m int) time.Time { return time.Date(2024, 5, d, h, m, 0, 0, time.UTC) }
+	tests := []struct {
+		name   string
+		at     time.Time
+		window string
+		next   time.Time
+	}{
+		{"before a window", day(1, 1, 0), "", day(1, 2, 0)},
+		{"start of a window", day(1, 2, 0), "02:00-02:15", day(1, 2, 15)},
+		{"end of a window", day(1, 2, 15), "", day(1, 23, 50)},
+		{"before midnight", day(1, 23, 55), "23:50-00:10", day(2, 0, 10)},
+		{"after midnight", day(2, 0, 5), "23:50-00:10", day(2, 0, 10)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, next := windows.at(tt.at)
+			assert.Equal(t, tt.window, window)
+			assert.Equal(t, tt.next, next)
+		})
+	}
+}
+
+func TestPauseWindowsRun(t *testing.T) {
+	// A window around the current time is entered right away
+	clock := func(t time.Time) time.Duration {
+		return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	now := time.Now()
+	w := config.PauseWindow{Start: clock(now.Add(-time.Minute)), End: clock(now.Add(2 * time.Minute))}
+	windows := &pauseWindows{windows: []config.PauseWindow{w}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan string)
+	done := make(chan struct{})
+	go func() {
+		windows.run(ctx, changes)
+		close(done)
+	}()
+	select {
+	case window := <-changes:
+		assert.Equal(t, w.String(), window)
+	case <-time.After(time.Second):
+		t.Fatal("the current pause window was not entered")
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("run did not return after cancellation")
+	}
+}
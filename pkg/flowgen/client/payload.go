@@ -0,0 +1,162 @@
+package client
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+)
+
+// Payload size distributions selected with payload_distribution besides the default, uniform
+const (
+	payloadBimodal   = "bimodal"
+	payloadEmpirical = "empirical"
+)
+
+// payloadDistribution picks payload sizes from a weighted set of sizes
+type payloadDistribution struct {
+	sizes []int
+	// cumulative holds the sum of the weights up to and including each size
+	cumulative []float64
+}
+
+// newPayloadDistribution returns the bimodal or empirical payload size distribution configured with
+// payload_distribution, or nil if sizes are fixed or uniform
+func newPayloadDistribution(c *config.ClientConfig) (*payloadDistribution, error) {
+	d := &payloadDistribution{}
+	switch c.PayloadDistribution {
+	case payloadBimodal:
+		d.add(c.MinPayloadSize, 1-c.PayloadLargeFraction)
+		d.add(c.MaxPayloadSize, c.PayloadLargeFraction)
+	case payloadEmpirical:
+		sizes, err := config.ParsePayloadSizes(c.PayloadSizes)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range sizes {
+			d.add(s.Size, float64(s.Weight))
+		}
+	default:
+		return nil, nil
+	}
+	return d, nil
+}
+
+// add adds a size with the given weight to the distribution
+func (d *payloadDistribution) add(size int, weight float64) {
+	total := 0.0
+	if n := len(d.cumulative); n > 0 {
+		total = d.cumulative[n-1]
+	}
+	d.sizes = append(d.sizes, size)
+	d.cumulative = append(d.cumulative, total+weight)
+}
+
+// pick returns a random payload size
+func (d *payloadDistribution) pick(src *rand.Rand) int {
+	r := src.Float64() * d.cumulative[len(d.cumulative)-1]
+	i := sort.Search(len(d.cumulative), func(i int) bool { return d.cumulative[i] > r })
+	return d.sizes[min(i, len(d.sizes)-1)]
+}
+
+// String describes the distribution as size:share pairs, e.g. "64 (58%), 576 (33%), 1500 (8%)"
+func (d *payloadDistribution) String() string {
+	total := d.cumulative[len(d.cumulative)-1]
+	parts := make([]string, len(d.sizes))
+	prev := 0.0
+	for i, size := range d.sizes {
+		parts[i] = fmt.Sprintf("%d (%.0f%%)", size, (d.cumulative[i]-prev)/total*100)
+		prev = d.cumulative[i]
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Payload content patterns selected with payload_pattern. The default, cached, sends slices of a cache of
+// random bytes filled at startup, so every flow carries the same bytes.
+const (
+	payloadCached       = "cached"
+	payloadZeros        = "zeros"
+	payloadRandom       = "random"
+	payloadText         = "ascii-text"
+	payloadCompressible = "compressible"
+)
+
+// compressibleRun is the number of times the compressible pattern repeats each random byte
+const compressibleRun = 8
+
+// textWords are the words the ascii-text pattern is made of
+var textWords = strings.Fields(`the of and to in is was that for on are with as be at by this from have or an
+	they which one you were all we when there can been has more if will would who so out up what about into
+	than them only some could other time these two may then first any like now its over such our made after
+	network packet flow server client traffic latency route switch firewall connection stream window buffer`)
+
+// fillPayload fills b with the content of a payload pattern, picking random content from src
+func fillPayload(b []byte, pattern string, src *rand.Rand) {
+	switch pattern {
+	case payloadZeros:
+		clear(b)
+	case payloadText:
+		fillText(b, src)
+	case payloadCompressible:
+		for i := 0; i < len(b); i += compressibleRun {
+			v := byte(src.Uint32())
+			for j := i; j < min(i+compressibleRun, len(b)); j++ {
+				b[j] = v
+			}
+		}
+	default:
+		fillRandom(b, src)
+	}
+}
+
+// fillRandom fills b with random bytes
+func fillRandom(b []byte, src *rand.Rand) {
+	var word [8]byte
+	for i := 0; i < len(b); i += len(word) {
+		binary.LittleEndian.PutUint64(word[:], src.Uint64())
+		copy(b[i:], word[:])
+	}
+}
+
+// fillText fills b with random words separated by spaces and broken into sentences and lines
+func fillText(b []byte, src *rand.Rand) {
+	i := 0
+	for words := 0; i < len(b); words++ {
+		word := textWords[src.IntN(len(textWords))]
+		switch {
+		case words > 0 && words%64 == 0:
+			i += copy(b[i:], ".\n")
+		case words > 0 && words%12 == 0:
+			i += copy(b[i:], ". ")
+		case words > 0:
+			i += copy(b[i:], " ")
+		}
+		i += copy(b[i:], word)
+	}
+}
+
+// newPayloadCache returns a payload cache of the given size filled with random bytes, the same in every run
+func newPayloadCache(size int) []byte {
+	// #nosec G404 - math/rand is sufficient for test data generation
+	src := rand.New(rand.NewPCG(0, 0))
+	b := make([]byte, size)
+	for i := range b {
+		b[i] = byte(src.Uint32() & 0xFF) // Random bytes (0-255)
+	}
+	return b
+}
+
+// flowPayload returns the payload of a flow of the given size. The random pattern fills a fresh payload per
+// flow, the others slice the payload cache.
+func flowPayload(size int) []byte {
+	if cfg.PayloadPattern != payloadRandom {
+		return payloadCache[:size]
+	}
+	b := make([]byte, size)
+	// #nosec G404 - math/rand is sufficient for payload content
+	fillRandom(b, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+	return b
+}
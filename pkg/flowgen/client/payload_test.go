@@ -0,0 +1,144 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadDistribution(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.ClientConfig
+		// shares are the expected fractions of the picked sizes
+		shares map[int]float64
+		desc   string
+	}{
+		{
+			name:   "bimodal",
+			cfg:    config.ClientConfig{PayloadDistribution: "bimodal", MinPayloadSize: 64, MaxPayloadSize: 1400, PayloadLargeFraction: 0.2},
+			shares: map[int]float64{64: 0.8, 1400: 0.2},
+			desc:   "64 (80%), 1400 (20%)",
+		},
+		{
+			name:   "bimodal only large",
+			cfg:    config.ClientConfig{PayloadDistribution: "bimodal", MinPayloadSize: 64, MaxPayloadSize: 1400, PayloadLargeFraction: 1},
+			shares: map[int]float64{1400: 1},
+			desc:   "64 (0%), 1400 (100%)",
+		},
+		{
+			name:   "empirical",
+			cfg:    config.ClientConfig{PayloadDistribution: "empirical", PayloadSizes: "100:1,200:3"},
+			shares: map[int]float64{100: 0.25, 200: 0.75},
+			desc:   "100 (25%), 200 (75%)",
+		},
+		{
+			name:   "imix",
+			cfg:    config.ClientConfig{PayloadDistribution: "empirical", PayloadSizes: "imix"},
+			shares: map[int]float64{18: 7.0 / 12, 548: 4.0 / 12, 1472: 1.0 / 12},
+			desc:   "18 (58%), 548 (33%), 1472 (8%)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := newPayloadDistribution(&tt.cfg)
+			require.NoError(t, err)
+			require.NotNil(t, d)
+			assert.Equal(t, tt.desc, d.String())
+
+			const picks = 20000
+			src := rand.New(rand.NewPCG(1, 2))
+			counts := make(map[int]int)
+			for i := 0; i < picks; i++ {
+				counts[d.pick(src)]++
+			}
+			for size, count := range counts {
+				assert.Contains(t, tt.shares, size)
+				assert.InDelta(t, tt.shares[size], float64(count)/picks, 0.02, "share of size %d", size)
+			}
+		})
+	}
+}
+
+func TestPayloadDistributionUniform(t *testing.T) {
+	d, err := newPayloadDistribution(&config.ClientConfig{PayloadDistribution: "uniform", MinPayloadSize: 10, MaxPayloadSize: 20})
+	require.NoError(t, err)
+	assert.Nil(t, d)
+
+	_, err = newPayloadDistribution(&config.ClientConfig{PayloadDistribution: "empirical", PayloadSizes: "64"})
+	assert.Error(t, err)
+}
+
+func TestGetPayloadSizeFromDistribution(t *testing.T) {
+	oldCfg, oldSizes := cfg, payloadSizes
+	defer func() { cfg, payloadSizes = oldCfg, oldSizes }()
+	cfg = &config.ClientConfig{PayloadDistribution: "empirical", PayloadSizes: "512:1"}
+	var err error
+	payloadSizes, err = newPayloadDistribution(cfg)
+	require.NoError(t, err)
+
+	assert.Equal(t, 512, getPayloadSize(rand.New(rand.NewPCG(0, 0))))
+}
+
+func TestFillPayload(t *testing.T) {
+	// compressed is the size of b compressed with gzip relative to its size
+	compressed := func(b []byte) float64 {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, _ = w.Write(b)
+		require.NoError(t, w.Close())
+		return float64(buf.Len()) / float64(len(b))
+	}
+
+	tests := []struct {
+		pattern        string
+		minCompression float64
+		maxCompression float64
+	}{
+		{payloadZeros, 0, 0.01},
+		{payloadCompressible, 0.15, 0.5},
+		{payloadText, 0.2, 0.5},
+		{payloadRandom, 0.99, 1.01},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			b := bytes.Repeat([]byte{0xff}, 64*1024+3)
+			fillPayload(b, tt.pattern, rand.New(rand.NewPCG(1, 2)))
+			ratio := compressed(b)
+			assert.GreaterOrEqual(t, ratio, tt.minCompression)
+			assert.LessOrEqual(t, ratio, tt.maxCompression)
+			if tt.pattern == payloadText {
+				for _, c := range b {
+					assert.True(t, c == '\n' || c >= ' ' && c <= '~', "non-printable byte %#x", c)
+				}
+			}
+		})
+	}
+}
+
+func TestFlowPayload(t *testing.T) {
+	oldCfg := cfg
+	defer func() { cfg = oldCfg }()
+
+	cfg = &config.ClientConfig{PayloadPattern: payloadCached}
+	assert.Equal(t, flowPayload(100), flowPayload(100))
+	assert.Equal(t, payloadCache[:100], flowPayload(100))
+
+	cfg = &config.ClientConfig{PayloadPattern: payloadRandom}
+	a, b := flowPayload(100), flowPayload(100)
+	assert.Len(t, a, 100)
+	assert.NotEqual(t, a, b, "random payloads differ between flows")
+}
+
+func TestNewPayloadCache(t *testing.T) {
+	large := newPayloadCache(3 << 20)
+	assert.Len(t, large, 3<<20)
+	// A larger cache keeps the payloads of the default one
+	assert.Equal(t, newPayloadCache(1<<20), large[:1<<20])
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+)
+
+// payloadVars are the variables of the payload template, rendered for every message
+type payloadVars struct {
+	FlowID uint64
+	// Counter is the number of the message within its flow, starting at 1
+	Counter   uint64
+	Timestamp string
+	UnixNano  int64
+	Hostname  string
+	Protocol  string
+	Port      string
+}
+
+// payloadTemplate renders the payload_template at the start of every message, so captured traffic tells
+// which flow and message it belongs to
+type payloadTemplate struct {
+	tmpl     *template.Template
+	hostname string
+}
+
+// newPayloadTemplate parses the template configured with payload_template, or returns nil if payloads are
+// sent as is. The template is rendered once with sample values, so references to unknown variables fail
+// at startup rather than with the first flow.
+func newPayloadTemplate(c *config.ClientConfig) (*payloadTemplate, error) {
+	if c.PayloadTemplate == "" {
+		return nil, nil
+	}
+	tmpl, err := config.ParsePayloadTemplate(c.PayloadTemplate)
+	if err != nil {
+		return nil, err
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	t := &payloadTemplate{tmpl: tmpl, hostname: hostname}
+	if _, err := t.text(payloadVars{FlowID: 1, Counter: 1, Protocol: "tcp", Port: "8080"}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// text renders the template with the given variables, the hostname and the current time
+func (t *payloadTemplate) text(vars payloadVars) ([]byte, error) {
+	now := time.Now()
+	vars.Timestamp = now.UTC().Format(time.RFC3339Nano)
+	vars.UnixNano = now.UnixNano()
+	vars.Hostname = t.hostname
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, vars); err != nil {
+		return nil, fmt.Errorf("invalid payload_template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// render returns a copy of payload with the rendered template written at offset, past the flow header if
+// there is one. The rest of the payload keeps its content, and the payload grows if the text does not fit.
+func (t *payloadTemplate) render(payload []byte, offset int, vars payloadVars) ([]byte, error) {
+	text, err := t.text(vars)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, max(len(payload), offset+len(text)))
+	copy(b, payload)
+	copy(b[offset:], text)
+	return b, nil
+}
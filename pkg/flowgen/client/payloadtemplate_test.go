@@ -0,0 +1,49 @@
+package client
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPayloadTemplate(t *testing.T) {
+	tmpl, err := newPayloadTemplate(&config.ClientConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, tmpl)
+
+	_, err = newPayloadTemplate(&config.ClientConfig{PayloadTemplate: "flow={{.Flow}}"})
+	assert.Error(t, err, "unknown variables are rejected at startup")
+	_, err = newPayloadTemplate(&config.ClientConfig{PayloadTemplate: "flow={{.FlowID"})
+	assert.Error(t, err)
+}
+
+func TestPayloadTemplateRender(t *testing.T) {
+	tmpl, err := newPayloadTemplate(&config.ClientConfig{PayloadTemplate: "{{.Protocol}}/{{.Port}} flow={{.FlowID}} msg={{.Counter}} host={{.Hostname}}|"})
+	require.NoError(t, err)
+	hostname, _ := os.Hostname()
+	want := "udp/53 flow=42 msg=7 host=" + hostname + "|"
+
+	// The text replaces the start of the payload, which keeps its size and the rest of its content
+	base := []byte(strings.Repeat(".", 100))
+	b, err := tmpl.render(base, 0, payloadVars{FlowID: 42, Counter: 7, Protocol: "udp", Port: "53"})
+	require.NoError(t, err)
+	assert.Equal(t, want+strings.Repeat(".", 100-len(want)), string(b))
+	assert.Equal(t, strings.Repeat(".", 100), string(base), "the base payload is not changed")
+
+	// The text follows the flow header and grows payloads too small for it
+	b, err = tmpl.render([]byte("HEADER"), 6, payloadVars{FlowID: 42, Counter: 7, Protocol: "udp", Port: "53"})
+	require.NoError(t, err)
+	assert.Equal(t, "HEADER"+want, string(b))
+}
+
+func TestPayloadTemplateTimestamp(t *testing.T) {
+	tmpl, err := newPayloadTemplate(&config.ClientConfig{PayloadTemplate: "{{.Timestamp}} {{.UnixNano}}"})
+	require.NoError(t, err)
+	b, err := tmpl.render(nil, 0, payloadVars{})
+	require.NoError(t, err)
+	assert.Regexp(t, `^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(\.\d+)?Z \d{19}$`, string(b))
+}
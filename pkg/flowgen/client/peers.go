@@ -0,0 +1,294 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+)
+
+// peerMatrixPath is the agent endpoint exposing the peer latency matrix
+const peerMatrixPath = "/matrix"
+
+// peerProbeMagic prefixes every probe, followed by the round and sequence number of the probe
+var peerProbeMagic = []byte("flow-generator-peer-probe")
+
+// peerProbeTimeout bounds the wait for the response to a single probe
+const peerProbeTimeout = time.Second
+
+// peerRowTimeout bounds the time to fetch the row of a peer when assembling the full matrix
+const peerRowTimeout = 2 * time.Second
+
+// peerResult is the outcome of the last probe round to a peer
+type peerResult struct {
+	RTTSeconds float64 `json:"rtt_seconds,omitempty"`
+	LossRatio  float64 `json:"loss_ratio"`
+	Sent       int     `json:"sent"`
+	Received   int     `json:"received"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// peerRow is the latency and loss from one agent to all of its peers
+type peerRow struct {
+	Src     string                `json:"src"`
+	Updated string                `json:"updated,omitempty"`
+	Peers   map[string]peerResult `json:"peers"`
+}
+
+// peerMatrixView is the full matrix assembled from the rows of all peers, keyed by peer address
+type peerMatrixView struct {
+	Rows   map[string]peerRow `json:"rows"`
+	Errors map[string]string  `json:"errors,omitempty"`
+}
+
+// peerMatrix probes the peers of an agent with UDP echo requests and keeps the results of the last round.
+// Every agent only measures its own row, the full matrix is assembled on request from the rows of all peers.
+type peerMatrix struct {
+	name       string
+	entries    []string
+	port       int
+	count      int
+	interval   time.Duration
+	healthPort string
+
+	mu      sync.Mutex
+	row     peerRow
+	round   uint32
+	current []string
+}
+
+// newPeerMatrix creates the peer matrix of an agent whose peers serve their health endpoints on healthPort
+func newPeerMatrix(c *config.ClientConfig, healthPort string) *peerMatrix {
+	name := c.PeerName
+	if name == "" {
+		name, _ = os.Hostname()
+	}
+	var entries []string
+	for _, entry := range strings.Split(c.Peers, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return &peerMatrix{
+		name:       name,
+		entries:    entries,
+		port:       c.PeerProbePort,
+		count:      c.PeerProbeCount,
+		interval:   seconds(c.PeerProbeInterval),
+		healthPort: healthPort,
+		row:        peerRow{Src: name, Peers: map[string]peerResult{}},
+	}
+}
+
+// run probes all peers every interval until ctx is done
+func (m *peerMatrix) run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		m.probeRound(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// probeRound resolves the peers and probes all of them concurrently. Peers that are gone are dropped from
+// the row and the metrics.
+func (m *peerMatrix) probeRound(ctx context.Context) {
+	peers := resolvePeers(ctx, m.entries)
+	m.mu.Lock()
+	m.round++
+	round := m.round
+	m.mu.Unlock()
+
+	results := make(map[string]peerResult, len(peers))
+	var resultsMu sync.Mutex
+	var wg sync.WaitGroup
+	for _, peer := range peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, rtt := probePeer(ctx, net.JoinHostPort(peer, strconv.Itoa(m.port)), round, m.count)
+			mc.SetPeerProbe(m.name, peer, rtt, result.LossRatio, result.Received > 0)
+			resultsMu.Lock()
+			results[peer] = result
+			resultsMu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for peer := range m.row.Peers {
+		if _, ok := results[peer]; !ok {
+			mc.DeletePeer(m.name, peer)
+		}
+	}
+	m.row.Peers = results
+	m.row.Updated = time.Now().UTC().Format(time.RFC3339Nano)
+	m.current = peers
+}
+
+// resolvePeers expands the peer entries to addresses. Host names expand to all of their addresses, so a
+// headless service name covers every agent behind it. Entries that fail to resolve are skipped.
+func resolvePeers(ctx context.Context, entries []string) []string {
+	seen := make(map[string]bool)
+	var peers []string
+	for _, entry := range entries {
+		addrs, err := net.DefaultResolver.LookupHost(ctx, entry)
+		if err != nil {
+			logging.Logger.Warnf("Failed to resolve peer %s: %v", entry, err)
+			continue
+		}
+		for _, addr := range addrs {
+			if !seen[addr] {
+				seen[addr] = true
+				peers = append(peers, addr)
+			}
+		}
+	}
+	sort.Strings(peers)
+	return peers
+}
+
+// probePeer sends count UDP echo probes to addr one after another and returns the result and the mean
+// round-trip time of the answered probes
+func probePeer(ctx context.Context, addr string, round uint32, count int) (peerResult, time.Duration) {
+	result := peerResult{Sent: count, LossRatio: 1}
+	conn, err := egress.dialer("udp").DialContext(ctx, "udp", addr)
+	if err != nil {
+		result.Error = err.Error()
+		return result, 0
+	}
+	defer func() { _ = conn.Close() }()
+
+	probe := make([]byte, len(peerProbeMagic)+8)
+	copy(probe, peerProbeMagic)
+	buf := make([]byte, len(probe)+64)
+	var total time.Duration
+	var lastErr error
+	for seq := 0; seq < count; seq++ {
+		binary.BigEndian.PutUint32(probe[len(peerProbeMagic):], round)
+		binary.BigEndian.PutUint32(probe[len(peerProbeMagic)+4:], uint32(seq))
+		start := time.Now()
+		if _, err := conn.Write(probe); err != nil {
+			lastErr = err
+			continue
+		}
+		_ = conn.SetReadDeadline(start.Add(peerProbeTimeout))
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				lastErr = err
+				break
+			}
+			// Late responses to earlier probes are skipped
+			if bytes.Equal(buf[:n], probe) {
+				total += time.Since(start)
+				result.Received++
+				break
+			}
+		}
+	}
+
+	result.LossRatio = float64(count-result.Received) / float64(count)
+	if result.Received == 0 {
+		if lastErr != nil {
+			result.Error = lastErr.Error()
+		}
+		return result, 0
+	}
+	rtt := total / time.Duration(result.Received)
+	result.RTTSeconds = rtt.Seconds()
+	return result, rtt
+}
+
+// localRow returns a copy of the row of this agent
+func (m *peerMatrix) localRow() peerRow {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	row := m.row
+	row.Peers = make(map[string]peerResult, len(m.row.Peers))
+	for peer, result := range m.row.Peers {
+		row.Peers[peer] = result
+	}
+	return row
+}
+
+// fullMatrix fetches the rows of all peers of the last round from their health endpoints
+func (m *peerMatrix) fullMatrix(ctx context.Context) peerMatrixView {
+	m.mu.Lock()
+	peers := append([]string(nil), m.current...)
+	m.mu.Unlock()
+
+	view := peerMatrixView{Rows: make(map[string]peerRow, len(peers))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	client := &http.Client{Timeout: peerRowTimeout}
+	for _, peer := range peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			row, err := fetchPeerRow(ctx, client, fmt.Sprintf("http://%s%s?scope=local", net.JoinHostPort(peer, m.healthPort), peerMatrixPath))
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if view.Errors == nil {
+					view.Errors = make(map[string]string)
+				}
+				view.Errors[peer] = err.Error()
+				return
+			}
+			view.Rows[peer] = row
+		}()
+	}
+	wg.Wait()
+	return view
+}
+
+// fetchPeerRow fetches the row of a single peer
+func fetchPeerRow(ctx context.Context, client *http.Client, url string) (peerRow, error) {
+	var row peerRow
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return row, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return row, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return row, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&row)
+	return row, err
+}
+
+// ServeHTTP reports the full matrix as JSON, or only the row of this agent with ?scope=local
+func (m *peerMatrix) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var v any
+	if r.URL.Query().Get("scope") == "local" {
+		v = m.localRow()
+	} else {
+		v = m.fullMatrix(r.Context())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logging.Logger.Debugf("Failed to write peer matrix: %v", err)
+	}
+}
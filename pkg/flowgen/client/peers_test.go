@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/health"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startUDPEcho starts a UDP echo listener on a random loopback port and returns the port
+func startUDPEcho(t *testing.T) int {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, _ = conn.WriteTo(buf[:n], addr)
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func TestProbePeer(t *testing.T) {
+	port := startUDPEcho(t)
+	result, rtt := probePeer(context.Background(), net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), 1, 3)
+	assert.Equal(t, 3, result.Sent)
+	assert.Equal(t, 3, result.Received)
+	assert.Zero(t, result.LossRatio)
+	assert.Empty(t, result.Error)
+	assert.Positive(t, rtt)
+	assert.Equal(t, rtt.Seconds(), result.RTTSeconds)
+
+	// Nothing listens on the port of a closed socket
+	closed, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := closed.LocalAddr().String()
+	_ = closed.Close()
+	result, rtt = probePeer(context.Background(), addr, 1, 2)
+	assert.Equal(t, 0, result.Received)
+	assert.Equal(t, float64(1), result.LossRatio)
+	assert.NotEmpty(t, result.Error)
+	assert.Zero(t, rtt)
+}
+
+func TestResolvePeers(t *testing.T) {
+	logging.InitLogger("json", "error")
+	peers := resolvePeers(context.Background(), []string{"127.0.0.2", "127.0.0.1", "127.0.0.1", "invalid.invalid"})
+	assert.Equal(t, []string{"127.0.0.1", "127.0.0.2"}, peers)
+}
+
+func TestPeerMatrix(t *testing.T) {
+	logging.InitLogger("json", "error")
+	oldMc := mc
+	mc = metrics.NewMetricsCollector()
+	defer func() { mc = oldMc }()
+
+	m := newPeerMatrix(&config.ClientConfig{
+		Peers:             "127.0.0.1",
+		PeerProbePort:     startUDPEcho(t),
+		PeerProbeInterval: 1,
+		PeerProbeCount:    2,
+		PeerName:          "node-a",
+	}, "18199")
+	checker := health.NewChecker()
+	checker.Handle(peerMatrixPath, m)
+	require.NoError(t, checker.Start("18199"))
+	defer func() { _ = checker.Stop() }()
+
+	m.probeRound(context.Background())
+	row := m.localRow()
+	assert.Equal(t, "node-a", row.Src)
+	require.Contains(t, row.Peers, "127.0.0.1")
+	assert.Equal(t, 2, row.Peers["127.0.0.1"].Received)
+	assert.Equal(t, float64(0), testutil.ToFloat64(mc.PeerLoss.WithLabelValues("node-a", "127.0.0.1")))
+	assert.Positive(t, testutil.ToFloat64(mc.PeerRTT.WithLabelValues("node-a", "127.0.0.1")))
+
+	// The full matrix is assembled from the rows the peers serve themselves
+	var view peerMatrixView
+	require.Eventually(t, func() bool {
+		resp, err := http.Get("http://127.0.0.1:18199" + peerMatrixPath)
+		if err != nil {
+			return false
+		}
+		defer func() { _ = resp.Body.Close() }()
+		return json.NewDecoder(resp.Body).Decode(&view) == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Empty(t, view.Errors)
+	require.Contains(t, view.Rows, "127.0.0.1")
+	assert.Equal(t, "node-a", view.Rows["127.0.0.1"].Src)
+	assert.Equal(t, 2, view.Rows["127.0.0.1"].Peers["127.0.0.1"].Sent)
+
+	// Peers that are gone are dropped from the row and the metrics
+	m.entries = nil
+	m.probeRound(context.Background())
+	assert.Empty(t, m.localRow().Peers)
+	assert.Equal(t, 0, testutil.CollectAndCount(mc.PeerLoss))
+}
@@ -0,0 +1,216 @@
+package client
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+)
+
+// phaseLatencySamples bounds the round trips kept per phase for its p99 latency, like the latency
+// percentiles of the whole run
+const phaseLatencySamples = 10000
+
+// Outcomes of a phase reported by the run status
+const (
+	phaseStatusPending = "pending"
+	phaseStatusRunning = "running"
+	phaseStatusPassed  = "passed"
+	phaseStatusFailed  = "failed"
+	// phaseStatusSkipped is a phase the run ended before
+	phaseStatusSkipped = "skipped"
+)
+
+// phaseReport describes a phase of the run and the outcome of its assertions
+type phaseReport struct {
+	Name         string  `json:"name"`
+	StartSeconds float64 `json:"start_seconds"`
+	EndSeconds   float64 `json:"end_seconds"`
+	Status       string  `json:"status"`
+	// The measurements the assertions of the phase are checked against, they are only reported once the
+	// phase is over
+	FlowsCompleted uint64   `json:"flows_completed"`
+	FlowsFailed    uint64   `json:"flows_failed"`
+	ErrorRate      *float64 `json:"error_rate,omitempty"`
+	P99LatencyMs   *float64 `json:"p99_latency_ms,omitempty"`
+	ThroughputMbps *float64 `json:"throughput_mbps,omitempty"`
+	// Violations describes every assertion of the phase that failed
+	Violations []string `json:"violations,omitempty"`
+}
+
+// phaseMonitor splits the run into the configured time-boxed phases and checks the assertions of every
+// phase against the flows finished, the round trips measured and the bytes echoed within it
+type phaseMonitor struct {
+	windowedStats
+	start  time.Time
+	phases []config.Phase
+	// ends holds the end of every phase since the start of the first one
+	ends  []time.Duration
+	stats []flowStats
+	// finished is when the run ended, phases still running then are checked up to it
+	finished time.Time
+}
+
+// newPhaseMonitor returns the phases configured with phase starting at the given time, or nil if the run
+// has no phases
+func newPhaseMonitor(c *config.ClientConfig, start time.Time) *phaseMonitor {
+	if c.Phases == "" {
+		return nil
+	}
+	// The phases were checked when the configuration was validated
+	phases, _ := config.ParsePhases(c.Phases)
+	m := &phaseMonitor{
+		start:  start,
+		phases: phases,
+		ends:   make([]time.Duration, len(phases)),
+		stats:  make([]flowStats, len(phases)),
+	}
+	m.statsAt = m.at
+	var end time.Duration
+	for i, p := range phases {
+		end += seconds(p.Duration)
+		m.ends[i] = end
+		m.stats[i] = newFlowStats(phaseLatencySamples)
+	}
+	return m
+}
+
+// String describes the phases for the startup log
+func (m *phaseMonitor) String() string {
+	names := make([]string, len(m.phases))
+	for i, p := range m.phases {
+		names[i] = fmt.Sprintf("%s (%gs)", p.Name, p.Duration)
+	}
+	return strings.Join(names, ", ")
+}
+
+// at returns the statistics of the phase running at the given time, nil before the first and after the
+// last phase. The caller holds the lock.
+func (m *phaseMonitor) at(t time.Time) *flowStats {
+	elapsed := t.Sub(m.start)
+	if elapsed < 0 {
+		return nil
+	}
+	i, _ := slices.BinarySearch(m.ends, elapsed)
+	// A time on the end of a phase belongs to the next one
+	if i < len(m.ends) && m.ends[i] == elapsed {
+		i++
+	}
+	if i == len(m.ends) {
+		return nil
+	}
+	return &m.stats[i]
+}
+
+// finish records the end of the run, after which phases still running are checked up to it and phases
+// not reached are skipped
+func (m *phaseMonitor) finish(at time.Time) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.finished = at
+}
+
+// status returns the report of every phase at the given time, nil if the run has no phases
+func (m *phaseMonitor) status(now time.Time) []phaseReport {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.finished.IsZero() {
+		now = m.finished
+	}
+	elapsed := now.Sub(m.start)
+
+	reports := make([]phaseReport, len(m.phases))
+	var start time.Duration
+	for i, p := range m.phases {
+		end := m.ends[i]
+		r := phaseReport{
+			Name:           p.Name,
+			StartSeconds:   start.Seconds(),
+			EndSeconds:     end.Seconds(),
+			FlowsCompleted: m.stats[i].completed,
+			FlowsFailed:    m.stats[i].failed,
+		}
+		switch {
+		case elapsed < start && m.finished.IsZero():
+			r.Status = phaseStatusPending
+		case elapsed <= start && !m.finished.IsZero():
+			r.Status = phaseStatusSkipped
+		case elapsed < end && m.finished.IsZero():
+			r.Status = phaseStatusRunning
+		default:
+			r.check(p, &m.stats[i], min(elapsed, end)-start)
+		}
+		reports[i] = r
+		start = end
+	}
+	return reports
+}
+
+// check evaluates the assertions of the phase against what was measured during the given time of it
+func (r *phaseReport) check(p config.Phase, s *flowStats, measured time.Duration) {
+	if finished := s.completed + s.failed; finished > 0 {
+		rate := float64(s.failed) / float64(finished) * 100
+		r.ErrorRate = &rate
+	}
+	if latency, ok := p99Latency(s); ok {
+		p99 := float64(latency) / float64(time.Millisecond)
+		r.P99LatencyMs = &p99
+	}
+	var mbps float64
+	if measured > 0 {
+		mbps = float64(s.received) * 8 / measured.Seconds() / 1e6
+	}
+	r.ThroughputMbps = &mbps
+
+	if p.MaxErrorRate < 100 {
+		switch {
+		case r.ErrorRate == nil:
+			r.Violations = append(r.Violations, "error rate: no flows finished")
+		case *r.ErrorRate > p.MaxErrorRate:
+			r.Violations = append(r.Violations, fmt.Sprintf("error rate %.2f%% (%d of %d flows failed) exceeds max_error_rate %g%%", *r.ErrorRate, s.failed, s.completed+s.failed, p.MaxErrorRate))
+		}
+	}
+	if p.MaxP99Latency > 0 {
+		switch {
+		case r.P99LatencyMs == nil:
+			r.Violations = append(r.Violations, "p99 latency: no responses received")
+		case *r.P99LatencyMs > p.MaxP99Latency:
+			r.Violations = append(r.Violations, fmt.Sprintf("p99 latency %.2fms exceeds max_p99_latency %gms", *r.P99LatencyMs, p.MaxP99Latency))
+		}
+	}
+	if p.MinThroughput > 0 && mbps < p.MinThroughput {
+		r.Violations = append(r.Violations, fmt.Sprintf("throughput %.3f Mbit/s is below min_throughput %g Mbit/s", mbps, p.MinThroughput))
+	}
+	r.Status = phaseStatusPassed
+	if len(r.Violations) > 0 {
+		r.Status = phaseStatusFailed
+	}
+}
+
+// reportPhases logs the outcome of every phase and returns the exit code of the run
+func reportPhases(reports []phaseReport) int {
+	code := 0
+	for _, r := range reports {
+		switch r.Status {
+		case phaseStatusPassed:
+			logging.Logger.Infof("Phase %s passed", r.Name)
+		case phaseStatusFailed:
+			for _, v := range r.Violations {
+				logging.Logger.Errorf("Phase %s assertion failed: %s", r.Name, v)
+			}
+			code = exitAssertionsFailed
+		default:
+			logging.Logger.Warnf("Phase %s was not reached before the run ended", r.Name)
+		}
+	}
+	return code
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhaseMonitor(t *testing.T) {
+	start := time.Unix(1000, 0)
+	assert.Nil(t, newPhaseMonitor(&config.ClientConfig{}, start))
+	assert.Nil(t, (*phaseMonitor)(nil).status(start))
+
+	m := newPhaseMonitor(&config.ClientConfig{Phases: "steady:10:max_error_rate=1,max_p99_latency=50;chaos:10:max_error_rate=20,min_throughput=1;recovery:10"}, start)
+	require.NotNil(t, m)
+	assert.Equal(t, "steady (10s), chaos (10s), recovery (10s)", m.String())
+	at := func(s float64) time.Time { return start.Add(seconds(s)) }
+
+	// Steady: 100 flows, none failed, all round trips fast
+	for i := range 100 {
+		m.observeFlow(at(float64(i)/10), false)
+		m.observeLatency(at(float64(i)/10), 5*time.Millisecond)
+	}
+	// Chaos: 10 of 50 flows failed, 1.25 MB echoed in 10s make 1 Mbit/s. A flow on the end of steady is
+	// counted in chaos.
+	m.observeFlow(at(10), true)
+	for i := range 49 {
+		m.observeFlow(at(11+float64(i)/10), i < 9)
+	}
+	m.addBytesReceived(at(15), 1250000)
+	// Flows before the first and after the last phase are not counted
+	m.observeFlow(at(-1), true)
+	m.observeFlow(at(35), true)
+
+	reports := m.status(at(15))
+	assert.Equal(t, phaseStatusPassed, reports[0].Status)
+	assert.Equal(t, phaseStatusRunning, reports[1].Status)
+	assert.Nil(t, reports[1].ErrorRate, "measurements are only reported once the phase is over")
+	assert.Equal(t, phaseStatusPending, reports[2].Status)
+
+	reports = m.status(at(25))
+	require.Len(t, reports, 3)
+	assert.Equal(t, phaseReport{Name: "steady", StartSeconds: 0, EndSeconds: 10, Status: phaseStatusPassed,
+		FlowsCompleted: 100, ErrorRate: ptr(0.0), P99LatencyMs: ptr(5.0), ThroughputMbps: ptr(0.0)}, reports[0])
+	assert.Equal(t, phaseStatusPassed, reports[1].Status, reports[1].Violations)
+	assert.Equal(t, uint64(10), reports[1].FlowsFailed)
+	assert.Equal(t, 20.0, *reports[1].ErrorRate)
+	assert.Equal(t, 1.0, *reports[1].ThroughputMbps)
+	assert.Equal(t, phaseStatusRunning, reports[2].Status)
+
+	// The run ending within recovery checks it up to then, it has no assertions to fail
+	m.finish(at(25))
+	reports = m.status(at(100))
+	assert.Equal(t, phaseStatusPassed, reports[2].Status)
+	assert.Equal(t, uint64(0), reports[2].FlowsFailed)
+}
+
+func TestPhaseMonitorViolations(t *testing.T) {
+	start := time.Unix(1000, 0)
+	m := newPhaseMonitor(&config.ClientConfig{Phases: "chaos:10:max_error_rate=5,max_p99_latency=50,min_throughput=1;recovery:10:max_error_rate=0"}, start)
+	at := func(s float64) time.Time { return start.Add(seconds(s)) }
+	for i := range 10 {
+		m.observeFlow(at(float64(i)), i == 0)
+		m.observeLatency(at(float64(i)), time.Duration(10*(i+1))*time.Millisecond)
+	}
+
+	// The run ends before recovery, which is skipped
+	m.finish(at(10))
+	reports := m.status(at(10))
+	assert.Equal(t, phaseStatusFailed, reports[0].Status)
+	assert.Equal(t, []string{
+		"error rate 10.00% (1 of 10 flows failed) exceeds max_error_rate 5%",
+		"p99 latency 100.00ms exceeds max_p99_latency 50ms",
+		"throughput 0.000 Mbit/s is below min_throughput 1 Mbit/s",
+	}, reports[0].Violations)
+	assert.Equal(t, phaseStatusSkipped, reports[1].Status)
+
+	logging.InitLogger("json", "error")
+	assert.Equal(t, exitAssertionsFailed, reportPhases(reports))
+	assert.Equal(t, 0, reportPhases(reports[1:]))
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"syscall"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
+)
+
+// connPool keeps idle TCP connections per target address so flows can reuse them
+type connPool struct {
+	mu      sync.Mutex
+	size    int
+	idle    map[string][]net.Conn
+	closed  bool
+	dialTCP func(addr string) (net.Conn, error)
+}
+
+// newConnPool creates a connection pool holding at most size idle connections per address
+func newConnPool(size int) *connPool {
+	return &connPool{
+		size: size,
+		idle: make(map[string][]net.Conn),
+		dialTCP: func(addr string) (net.Conn, error) {
+			return dialing.dial(context.Background(), tuning.dialer(egress.dialer("tcp")), "tcp", addr)
+		},
+	}
+}
+
+// get returns an idle connection for addr, dialing a new one if none is available. Idle connections the
+// server or a middlebox closed in the meantime are discarded rather than failing the flow.
+// The reused return value reports whether the connection came from the pool.
+func (p *connPool) get(addr string) (conn net.Conn, reused bool, err error) {
+	for {
+		p.mu.Lock()
+		conns := p.idle[addr]
+		if len(conns) == 0 {
+			p.mu.Unlock()
+			break
+		}
+		conn = conns[len(conns)-1]
+		p.idle[addr] = conns[:len(conns)-1]
+		p.mu.Unlock()
+		if stillOpen(conn) {
+			return conn, true, nil
+		}
+		_ = conn.Close()
+	}
+
+	conn, err = p.dialTCP(addr)
+	return conn, false, err
+}
+
+// put returns a healthy connection to the pool, closing it if the pool is full or closed
+func (p *connPool) put(addr string, conn net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed || len(p.idle[addr]) >= p.size {
+		_ = conn.Close()
+		return
+	}
+	p.idle[addr] = append(p.idle[addr], conn)
+}
+
+// stillOpen reports whether an idle connection can carry another flow, i.e. it was neither closed nor
+// reset by the peer and no unexpected data arrived on it. Connections that cannot be checked are assumed open.
+func stillOpen(conn net.Conn) bool {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return true
+	}
+	err := sockopt.CheckIdle(sc)
+	return err == nil || errors.Is(err, sockopt.ErrUnsupported)
+}
+
+// idleCount returns the number of idle connections held for addr
+func (p *connPool) idleCount(addr string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.idle[addr])
+}
+
+// closeAll closes all idle connections and stops the pool from accepting new ones
+func (p *connPool) closeAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for addr, conns := range p.idle {
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+		delete(p.idle, addr)
+	}
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnPoolReuse(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	go func() {
+		var accepted []net.Conn
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				for _, c := range accepted {
+					_ = c.Close()
+				}
+				return
+			}
+			accepted = append(accepted, conn)
+		}
+	}()
+
+	addr := listener.Addr().String()
+	p := newConnPool(1)
+
+	conn1, reused, err := p.get(addr)
+	require.NoError(t, err)
+	assert.False(t, reused)
+
+	conn2, reused, err := p.get(addr)
+	require.NoError(t, err)
+	assert.False(t, reused)
+
+	// Only one idle connection is kept, the second one is closed
+	p.put(addr, conn1)
+	p.put(addr, conn2)
+	assert.Equal(t, 1, p.idleCount(addr))
+
+	conn3, reused, err := p.get(addr)
+	require.NoError(t, err)
+	assert.True(t, reused)
+	assert.Same(t, conn1, conn3)
+	assert.Equal(t, 0, p.idleCount(addr))
+
+	p.closeAll()
+	p.put(addr, conn3)
+	assert.Equal(t, 0, p.idleCount(addr))
+}
+
+func TestConnPoolDiscardsClosedConnections(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	addr := listener.Addr().String()
+	p := newConnPool(1)
+	conn1, _, err := p.get(addr)
+	require.NoError(t, err)
+	p.put(addr, conn1)
+
+	// The server times out the idle connection
+	server1 := <-accepted
+	require.NoError(t, server1.Close())
+	require.Eventually(t, func() bool { return !stillOpen(conn1) }, time.Second, 10*time.Millisecond)
+
+	conn2, reused, err := p.get(addr)
+	require.NoError(t, err)
+	defer func() { _ = conn2.Close() }()
+	assert.False(t, reused)
+	assert.NotSame(t, conn1, conn2)
+	assert.Equal(t, 0, p.idleCount(addr))
+
+	// The redialed connection carries the flow
+	server2 := <-accepted
+	defer func() { _ = server2.Close() }()
+	_, err = conn2.Write([]byte("ping"))
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(server2, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+
+	// An open idle connection is reused
+	p.put(addr, conn2)
+	conn3, reused, err := p.get(addr)
+	require.NoError(t, err)
+	assert.True(t, reused)
+	assert.Same(t, conn2, conn3)
+}
+
+func TestConnPoolDialError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	p := newConnPool(1)
+	_, reused, err := p.get(addr)
+	assert.Error(t, err)
+	assert.False(t, reused)
+}
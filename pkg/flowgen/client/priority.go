@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+)
+
+// Flow priority classes
+const (
+	priorityHigh = "high"
+	priorityLow  = "low"
+)
+
+// errFlowPreempted is the reason a low-priority flow ended early to make room for a high-priority flow
+var errFlowPreempted = errors.New("preempted by a high-priority flow")
+
+// flowSlot is the concurrency slot held by a running flow
+type flowSlot struct {
+	priority string
+	// ctx is the context the flow runs with, it is canceled if the flow is preempted
+	ctx       context.Context
+	cancel    context.CancelCauseFunc
+	preempted bool
+}
+
+// flowSlots limits the number of concurrent flows. While all slots are taken, low-priority flows are
+// skipped, and a high-priority flow takes over the slot of the oldest running low-priority flow, which
+// is canceled.
+type flowSlots struct {
+	mu     sync.Mutex
+	limit  int
+	active int
+	// low holds the running low-priority flows in start order
+	low []*flowSlot
+}
+
+// newFlowSlots creates a limiter for up to limit concurrent flows
+func newFlowSlots(limit int) *flowSlots {
+	return &flowSlots{limit: limit}
+}
+
+// acquire takes a slot for a flow of the given priority running under parent. It returns nil if no slot
+// is available, and whether a low-priority flow was preempted to free the slot.
+func (s *flowSlots) acquire(parent context.Context, priority string) (*flowSlot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	preempted := false
+	if s.active >= s.limit {
+		if priority != priorityHigh || len(s.low) == 0 {
+			return nil, false
+		}
+		// The preempted flow hands its slot over, so the active count stays the same
+		victim := s.low[0]
+		s.low = s.low[1:]
+		victim.preempted = true
+		victim.cancel(errFlowPreempted)
+		preempted = true
+	} else {
+		s.active++
+	}
+
+	slot := &flowSlot{priority: priority}
+	slot.ctx, slot.cancel = context.WithCancelCause(parent)
+	if priority != priorityHigh {
+		s.low = append(s.low, slot)
+	}
+	return slot, preempted
+}
+
+// inUse returns the number of flows holding a slot
+func (s *flowSlots) inUse() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active
+}
+
+// release frees the slot of a flow that ended
+func (s *flowSlots) release(slot *flowSlot) {
+	slot.cancel(nil)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if slot.preempted {
+		return
+	}
+	s.active--
+	for i, l := range s.low {
+		if l == slot {
+			s.low = append(s.low[:i], s.low[i+1:]...)
+			break
+		}
+	}
+}
+
+// flowPriorities returns the priority class of every port listed in priority_ports
+func flowPriorities(c *config.ClientConfig) map[int]string {
+	// The mapping was checked when the configuration was validated
+	priorities, _ := config.ParsePortMap(c.PriorityPorts)
+	return priorities
+}
+
+// portPriority returns the priority class of flows to a port, ports that are not listed are low priority
+func portPriority(priorities map[int]string, port int) string {
+	if p, ok := priorities[port]; ok {
+		return p
+	}
+	return priorityLow
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlowSlotsLimit(t *testing.T) {
+	s := newFlowSlots(2)
+	a, _ := s.acquire(context.Background(), priorityLow)
+	b, _ := s.acquire(context.Background(), priorityHigh)
+	require.NotNil(t, a)
+	require.NotNil(t, b)
+
+	// Low-priority flows are skipped while all slots are taken
+	slot, preempted := s.acquire(context.Background(), priorityLow)
+	assert.Nil(t, slot)
+	assert.False(t, preempted)
+
+	s.release(a)
+	c, _ := s.acquire(context.Background(), priorityHigh)
+	require.NotNil(t, c)
+	// High-priority flows are skipped as well if no low-priority flow is left to preempt
+	slot, preempted = s.acquire(context.Background(), priorityHigh)
+	assert.Nil(t, slot)
+	assert.False(t, preempted)
+
+	s.release(b)
+	s.release(c)
+	assert.Equal(t, 0, s.active)
+}
+
+func TestFlowSlotsPreemption(t *testing.T) {
+	s := newFlowSlots(2)
+	first, _ := s.acquire(context.Background(), priorityLow)
+	second, _ := s.acquire(context.Background(), priorityLow)
+
+	high, preempted := s.acquire(context.Background(), priorityHigh)
+	require.NotNil(t, high)
+	assert.True(t, preempted)
+
+	// The oldest low-priority flow is canceled with the preemption as the cause
+	assert.ErrorIs(t, context.Cause(first.ctx), errFlowPreempted)
+	assert.NoError(t, second.ctx.Err())
+	assert.Equal(t, 2, s.active)
+
+	// The preempted flow handed its slot over, so releasing it does not free another one
+	s.release(first)
+	slot, _ := s.acquire(context.Background(), priorityLow)
+	assert.Nil(t, slot)
+
+	s.release(second)
+	s.release(high)
+	assert.Equal(t, 0, s.active)
+	assert.Empty(t, s.low)
+}
+
+func TestFlowSlotsParentCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := newFlowSlots(1)
+	slot, _ := s.acquire(ctx, priorityLow)
+	cancel()
+
+	assert.ErrorIs(t, context.Cause(slot.ctx), context.Canceled)
+	assert.NotErrorIs(t, context.Cause(slot.ctx), errFlowPreempted)
+}
+
+func TestPortPriority(t *testing.T) {
+	priorities := flowPriorities(&config.ClientConfig{PriorityPorts: "53=high, 8080=low"})
+
+	tests := []struct {
+		port int
+		want string
+	}{
+		{53, priorityHigh},
+		{8080, priorityLow},
+		{9000, priorityLow},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, portPriority(priorities, tt.port), "port %d", tt.port)
+	}
+}
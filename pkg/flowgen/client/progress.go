@@ -0,0 +1,202 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+)
+
+// Lifecycle events of the run written to the progress events destination
+const (
+	eventRunStarted      = "run_started"
+	eventPhaseStarted    = "phase_started"
+	eventPhaseCompleted  = "phase_completed"
+	eventAssertionFailed = "assertion_failed"
+	eventRunCompleted    = "run_completed"
+)
+
+// Outcomes of the run in its run_completed event
+const (
+	runStatusPassed = "passed"
+	runStatusFailed = "failed"
+)
+
+// progressEvent is a lifecycle event of the run. Time and elapsed seconds are set for every event, the other
+// fields depend on the event.
+type progressEvent struct {
+	Event          string  `json:"event"`
+	Time           string  `json:"time"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	Scenario       string  `json:"scenario,omitempty"`
+	// Seed is set on run_started
+	Seed *uint64 `json:"seed,omitempty"`
+	// Phase names the phase of phase events and of assertions of a phase, assertions of the whole run have none
+	Phase  string       `json:"phase,omitempty"`
+	Report *phaseReport `json:"report,omitempty"`
+	// Assertion describes the failed assertion of assertion_failed
+	Assertion string `json:"assertion,omitempty"`
+	// Status is the outcome of phase_completed and run_completed
+	Status   string `json:"status,omitempty"`
+	ExitCode *int   `json:"exit_code,omitempty"`
+}
+
+// progressEvents writes the lifecycle events of the run as JSON lines, so orchestrators like Argo Workflows
+// or CI jobs can react to phases and failed assertions while the run goes on instead of waiting for it to
+// exit. Every event is written with a single write, so readers of a pipe never see partial lines.
+type progressEvents struct {
+	mu       sync.Mutex
+	enc      *json.Encoder
+	closer   io.Closer
+	start    time.Time
+	scenario string
+	failed   bool
+	// started and completed count the phases whose phase_started and phase_completed were written
+	started   int
+	completed int
+	// finished is set once run_completed was written, later events are dropped
+	finished bool
+}
+
+// newProgressEvents opens the progress events destination of the configuration, or returns nil if progress
+// events are disabled
+func newProgressEvents(c *config.ClientConfig, start time.Time) (*progressEvents, error) {
+	var w io.Writer
+	var closer io.Closer
+	switch {
+	case c.ProgressEvents == "":
+		return nil, nil
+	case c.ProgressEvents == "-":
+		w = os.Stdout
+	case strings.HasPrefix(c.ProgressEvents, "fd:"):
+		// The descriptor was checked when the configuration was validated
+		fd, _ := strconv.Atoi(strings.TrimPrefix(c.ProgressEvents, "fd:"))
+		f := os.NewFile(uintptr(fd), c.ProgressEvents)
+		if _, err := f.Stat(); err != nil {
+			return nil, fmt.Errorf("file descriptor %d is not open: %w", fd, err)
+		}
+		w, closer = f, f
+	default:
+		// #nosec G304 - the progress events path is chosen by the operator
+		f, err := os.OpenFile(c.ProgressEvents, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		w, closer = f, f
+	}
+	p := newProgressWriter(w, start, c.Scenario)
+	p.closer = closer
+	return p, nil
+}
+
+// newProgressWriter creates progress events written to w, with elapsed seconds counted from start
+func newProgressWriter(w io.Writer, start time.Time, scenario string) *progressEvents {
+	return &progressEvents{enc: json.NewEncoder(w), start: start, scenario: scenario}
+}
+
+// runStarted writes the run_started event
+func (p *progressEvents) runStarted(now time.Time, seed uint64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.emit(now, progressEvent{Event: eventRunStarted, Scenario: p.scenario, Seed: &seed})
+}
+
+// run writes the events of the phases as their boundaries pass until ctx is done
+func (p *progressEvents) run(ctx context.Context, m *phaseMonitor) {
+	if p == nil || m == nil {
+		return
+	}
+	boundaries := make([]time.Time, 0, len(m.ends)+1)
+	boundaries = append(boundaries, m.start)
+	for _, end := range m.ends {
+		boundaries = append(boundaries, m.start.Add(end))
+	}
+	for _, b := range boundaries {
+		timer := time.NewTimer(time.Until(b))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		now := time.Now()
+		p.mu.Lock()
+		if !p.finished {
+			p.emitPhases(now, m.status(now))
+		}
+		p.mu.Unlock()
+	}
+}
+
+// finish writes the events of the phases not reported yet, the failed assertions of the whole run and the
+// run_completed event with the exit code of the run, then closes the destination
+func (p *progressEvents) finish(now time.Time, phases []phaseReport, violations []string, code int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.finished {
+		return
+	}
+	p.emitPhases(now, phases)
+	for _, v := range violations {
+		p.emit(now, progressEvent{Event: eventAssertionFailed, Assertion: v})
+	}
+	status := runStatusPassed
+	if code != 0 {
+		status = runStatusFailed
+	}
+	p.emit(now, progressEvent{Event: eventRunCompleted, Scenario: p.scenario, Status: status, ExitCode: &code})
+	p.finished = true
+	if p.closer != nil {
+		if err := p.closer.Close(); err != nil {
+			logging.Logger.Warnf("Failed to close progress events: %v", err)
+		}
+	}
+}
+
+// emitPhases writes the phase_started, phase_completed and assertion_failed events of the phases that
+// started or completed according to the reports since the last call. The caller holds the lock.
+func (p *progressEvents) emitPhases(now time.Time, reports []phaseReport) {
+	for i := p.completed; i < len(reports); i++ {
+		r := reports[i]
+		if r.Status == phaseStatusPending {
+			return
+		}
+		// A phase the run ended before completes as skipped without having started
+		if i >= p.started && r.Status != phaseStatusSkipped {
+			p.emit(now, progressEvent{Event: eventPhaseStarted, Phase: r.Name})
+			p.started = i + 1
+		}
+		if r.Status == phaseStatusRunning {
+			return
+		}
+		p.emit(now, progressEvent{Event: eventPhaseCompleted, Phase: r.Name, Status: r.Status, Report: &r})
+		for _, v := range r.Violations {
+			p.emit(now, progressEvent{Event: eventAssertionFailed, Phase: r.Name, Assertion: v})
+		}
+		p.completed = i + 1
+	}
+}
+
+// emit writes a single event, logging only the first write error. The caller holds the lock.
+func (p *progressEvents) emit(now time.Time, e progressEvent) {
+	e.Time = now.UTC().Format(time.RFC3339Nano)
+	e.ElapsedSeconds = max(now.Sub(p.start).Seconds(), 0)
+	if err := p.enc.Encode(e); err != nil && !p.failed {
+		p.failed = true
+		logging.Logger.Errorf("Failed to write progress events: %v", err)
+	}
+}
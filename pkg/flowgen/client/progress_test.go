@@ -0,0 +1,127 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readProgressEvents decodes the JSON lines of progress events
+func readProgressEvents(t *testing.T, b []byte) []progressEvent {
+	var events []progressEvent
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		var e progressEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestProgressEvents(t *testing.T) {
+	logging.InitLogger("json", "error")
+	start := time.Unix(1000, 0)
+	at := func(s float64) time.Time { return start.Add(seconds(s)) }
+	m := newPhaseMonitor(&config.ClientConfig{Phases: "steady:10:max_error_rate=0;chaos:10;recovery:10"}, start)
+
+	var buf bytes.Buffer
+	p := newProgressWriter(&buf, start, "chaos-test")
+	p.runStarted(start, 42)
+	m.observeFlow(at(5), true)
+
+	p.emitPhases(at(5), m.status(at(5)))
+	p.emitPhases(at(12), m.status(at(12)))
+	// Reports of a phase already written are not written again
+	p.emitPhases(at(13), m.status(at(13)))
+
+	// The run ends in chaos, so recovery is skipped
+	m.finish(at(15))
+	p.finish(at(16), m.status(at(16)), []string{"tcp p99 latency 80.00ms exceeds max_p99_latency 50ms"}, exitAssertionsFailed)
+	p.finish(at(17), nil, nil, 0)
+
+	events := readProgressEvents(t, buf.Bytes())
+	var kinds []string
+	for _, e := range events {
+		kinds = append(kinds, e.Event+"/"+e.Phase)
+	}
+	assert.Equal(t, []string{
+		"run_started/",
+		"phase_started/steady",
+		"phase_completed/steady", "assertion_failed/steady", "phase_started/chaos",
+		"phase_completed/chaos", "phase_completed/recovery", "assertion_failed/",
+		"run_completed/",
+	}, kinds)
+
+	assert.Equal(t, "chaos-test", events[0].Scenario)
+	require.NotNil(t, events[0].Seed)
+	assert.Equal(t, uint64(42), *events[0].Seed)
+	assert.Equal(t, "1970-01-01T00:16:45Z", events[1].Time)
+	assert.Equal(t, 5.0, events[1].ElapsedSeconds)
+
+	assert.Equal(t, phaseStatusFailed, events[2].Status)
+	require.NotNil(t, events[2].Report)
+	assert.Equal(t, uint64(1), events[2].Report.FlowsFailed)
+	assert.Equal(t, "error rate 100.00% (1 of 1 flows failed) exceeds max_error_rate 0%", events[3].Assertion)
+	assert.Equal(t, phaseStatusPassed, events[5].Status)
+	assert.Equal(t, phaseStatusSkipped, events[6].Status)
+	assert.Equal(t, "tcp p99 latency 80.00ms exceeds max_p99_latency 50ms", events[7].Assertion)
+
+	assert.Equal(t, runStatusFailed, events[8].Status)
+	require.NotNil(t, events[8].ExitCode)
+	assert.Equal(t, exitAssertionsFailed, *events[8].ExitCode)
+}
+
+func TestProgressEventsRun(t *testing.T) {
+	logging.InitLogger("json", "error")
+	start := time.Now()
+	m := newPhaseMonitor(&config.ClientConfig{Phases: "first:0.05;second:0.05"}, start)
+
+	var buf bytes.Buffer
+	p := newProgressWriter(&buf, start, "")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	p.run(ctx, m)
+	p.finish(time.Now(), m.status(time.Now()), nil, 0)
+
+	var kinds []string
+	for _, e := range readProgressEvents(t, buf.Bytes()) {
+		kinds = append(kinds, e.Event+"/"+e.Phase+"/"+e.Status)
+	}
+	assert.Equal(t, []string{
+		"phase_started/first/", "phase_completed/first/passed", "phase_started/second/",
+		"phase_completed/second/passed", "run_completed//passed",
+	}, kinds)
+}
+
+func TestNewProgressEvents(t *testing.T) {
+	logging.InitLogger("json", "error")
+	p, err := newProgressEvents(&config.ClientConfig{}, time.Now())
+	assert.NoError(t, err)
+	assert.Nil(t, p)
+
+	// A nil destination ignores all events
+	p.runStarted(time.Now(), 1)
+	p.finish(time.Now(), nil, nil, 0)
+
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	p, err = newProgressEvents(&config.ClientConfig{ProgressEvents: path}, time.Now())
+	require.NoError(t, err)
+	p.runStarted(time.Now(), 7)
+	p.finish(time.Now(), nil, nil, 0)
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Len(t, readProgressEvents(t, b), 2)
+
+	_, err = newProgressEvents(&config.ClientConfig{ProgressEvents: "fd:987"}, time.Now())
+	assert.ErrorContains(t, err, "file descriptor 987 is not open")
+}
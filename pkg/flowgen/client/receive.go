@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/bufpool"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/transport"
+)
+
+// receiveBufferSize is the size of the reads of receive-only flows
+const receiveBufferSize = 64 * 1024
+
+// init registers the receive-only TCP transport
+func init() {
+	transport.Register("tcp_recv", transport.ReceiveMode, newReceiveTransport)
+}
+
+// receiveTransport connects to a server that streams by itself, such as a generator or chargen port, and only
+// reads what it sends for the whole flow, for server-to-client throughput tests
+type receiveTransport struct {
+	flow transport.FlowInfo
+	conn net.Conn
+	// stop releases the hook unblocking a pending read when the flow ends
+	stop func() bool
+}
+
+// newReceiveTransport creates a receive-only transport for a flow
+func newReceiveTransport(flow transport.FlowInfo) transport.Transport {
+	return &receiveTransport{flow: flow}
+}
+
+// Dial connects to addr. Receive-only connections are never pooled or relayed.
+func (t *receiveTransport) Dial(ctx context.Context, addr string) error {
+	conn, err := dialing.dial(ctx, flowDialer("tcp", t.flow), "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if err := tuning.conn(conn); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	t.conn = conn
+	// The read waiting for the stream would outlive the flow otherwise
+	t.stop = context.AfterFunc(ctx, func() { _ = conn.SetReadDeadline(time.Now()) })
+	sockets.add(t.conn)
+	mc.TCPConnectionsOpenedPerSecond.Inc()
+	logFlowDetail(t.flow.ID, t.flow.Protocol, t.flow.Sampled, "Receive-only TCP connection %s -> %s established", t.conn.LocalAddr(), t.conn.RemoteAddr())
+	return nil
+}
+
+// Send sends nothing, generateFlow never calls it for receive mode transports
+func (t *receiveTransport) Send([]byte) (int, error) {
+	return 0, nil
+}
+
+// Recv reads the next part of the stream
+func (t *receiveTransport) Recv(buf []byte) (int, error) {
+	return t.conn.Read(buf)
+}
+
+// Close closes the connection
+func (t *receiveTransport) Close() error {
+	t.stop()
+	sockets.remove(t.conn)
+	return t.conn.Close()
+}
+
+// RemoteAddr returns the address of the server
+func (t *receiveTransport) RemoteAddr() net.Addr {
+	return t.conn.RemoteAddr()
+}
+
+// LocalAddr returns the local address of the connection
+func (t *receiveTransport) LocalAddr() net.Addr {
+	return t.conn.LocalAddr()
+}
+
+// receive reads the stream of the server until the flow ends and records the bytes received. The flow fails
+// if the server ends the stream early, or sent nothing by the end of the flow.
+func (f *flowExchange) receive(ctx context.Context) {
+	bp := bufpool.Get(receiveBufferSize)
+	defer bufpool.Put(bp)
+	buf := *bp
+	for {
+		n, err := f.transport.Recv(buf)
+		if n > 0 {
+			now := time.Now()
+			if f.sampled && f.bytesReceived == 0 {
+				sampler.logPayload(f.flowID, f.protocol, "received", buf[:n])
+			}
+			f.bytesReceived += uint64(n)
+			mc.AddBytesReceived(f.protocol, f.port, n)
+			mc.AddWireBytesReceived(f.protocol, f.port, f.wireBytes(n))
+			recordBytesReceived(now, n)
+		}
+		if err != nil {
+			// The read was unblocked because the flow ended
+			if ctx.Err() != nil {
+				if f.bytesReceived == 0 {
+					f.fail(fmt.Errorf("no data received"))
+				}
+				return
+			}
+			logFlowFailure(f.flowID, f.protocol, "%s stream ended after %d bytes: %v", protocolName(f.protocol), f.bytesReceived, err)
+			recordFlowError(f.protocol, f.port, err, flowOpRead)
+			f.fail(err)
+			return
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/transport"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamListener writes n bytes to the first connection, then closes it if hangup is set, or otherwise holds
+// it open until the client closes it
+func streamListener(t *testing.T, n int, hangup bool) int {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		_, _ = conn.Write(make([]byte, n))
+		if !hangup {
+			_, _ = io.Copy(io.Discard, conn)
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestFlowExchangeReceive(t *testing.T) {
+	logging.InitLogger("json", "error")
+	oldMc := mc
+	mc = metrics.NewMetricsCollector()
+	defer func() { mc = oldMc }()
+
+	tests := []struct {
+		name    string
+		stream  int
+		hangup  bool
+		wantErr string
+	}{
+		{"stream until the flow ends", 200000, false, ""},
+		{"stream ended early", 1000, true, "EOF"},
+		{"nothing streamed", 0, false, "no data received"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := streamListener(t, tt.stream, tt.hangup)
+			labelPort := strconv.Itoa(port)
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+
+			tr := newReceiveTransport(transport.FlowInfo{ID: 1, Protocol: "tcp_recv"})
+			require.NoError(t, tr.Dial(ctx, net.JoinHostPort("127.0.0.1", labelPort)))
+			defer func() { _ = tr.Close() }()
+			f := &flowExchange{flowID: 1, protocol: "tcp_recv", port: labelPort, transport: tr, mode: transport.ReceiveMode}
+			f.receive(ctx)
+
+			if tt.wantErr != "" {
+				require.Error(t, f.err)
+				assert.Contains(t, f.err.Error(), tt.wantErr)
+			} else {
+				assert.NoError(t, f.err)
+			}
+			assert.Equal(t, uint64(tt.stream), f.bytesReceived)
+			assert.Equal(t, float64(tt.stream), testutil.ToFloat64(mc.BytesReceived.WithLabelValues("tcp_recv", labelPort)))
+			assert.Zero(t, f.requests)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/relay"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/transport"
+)
+
+// relayHop describes how a single relay in the chain set up its next hop
+type relayHop struct {
+	addr string
+	// setup is the time between the previous acknowledgement and this relay's, as seen by the client
+	setup time.Duration
+	// relayTime is the timestamp reported by the relay itself
+	relayTime time.Time
+}
+
+// relayChain routes TCP flows through a chain of relay servers
+type relayChain struct {
+	relays []string
+}
+
+// newRelayChain creates a relay chain from the client configuration, or nil if relaying is disabled
+func newRelayChain(c *config.ClientConfig) *relayChain {
+	var relays []string
+	for _, r := range strings.Split(c.RelayChain, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			relays = append(relays, r)
+		}
+	}
+	if len(relays) == 0 {
+		return nil
+	}
+	return &relayChain{relays: relays}
+}
+
+// observeRelayHops records the per-hop setup times of a relayed flow
+func observeRelayHops(flow transport.FlowInfo, hops []relayHop) {
+	for i, hop := range hops {
+		mc.ObserveRelayHopSetup(i+1, hop.setup)
+		logFlowDetail(flow.ID, flow.Protocol, flow.Sampled, "Relay hop %d via %s connected in %v (relay timestamp %s)", i+1, hop.addr, hop.setup, hop.relayTime.Format(time.RFC3339Nano))
+	}
+}
+
+// dial connects to the target through all relays and returns the connection with per-hop timings
+func (c *relayChain) dial(target string) (net.Conn, []relayHop, error) {
+	conn, err := tuning.dialer(egress.dialer("tcp")).Dial("tcp", c.relays[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	last := time.Now()
+
+	hops := append(append([]string{}, c.relays[1:]...), target)
+	if err := relay.WriteHeader(conn, hops); err != nil {
+		_ = conn.Close()
+		return nil, nil, fmt.Errorf("failed to send relay header: %w", err)
+	}
+
+	timings := make([]relayHop, 0, len(c.relays))
+	for _, addr := range c.relays {
+		relayTime, err := relay.ReadAck(conn)
+		if err != nil {
+			_ = conn.Close()
+			return nil, nil, fmt.Errorf("relay %s did not reach its next hop: %w", addr, err)
+		}
+		now := time.Now()
+		timings = append(timings, relayHop{addr: addr, setup: now.Sub(last), relayTime: relayTime})
+		last = now
+	}
+	return conn, timings, nil
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/relay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startFakeRelay accepts a single relay flow, checks its header and echoes the payload after acknowledging.
+// Without acknowledgements it closes the connection, like a relay failing to reach its next hop.
+func startFakeRelay(t *testing.T, wantHops []string, acks int) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		hops, err := relay.ReadHeader(conn)
+		if err != nil {
+			return
+		}
+		assert.Equal(t, wantHops, hops)
+		if acks == 0 {
+			return
+		}
+		for i := 0; i < acks; i++ {
+			_ = relay.WriteAck(conn, time.Now())
+		}
+		_, _ = io.Copy(conn, conn)
+	}()
+	return listener.Addr().String()
+}
+
+func TestNewRelayChain(t *testing.T) {
+	assert.Nil(t, newRelayChain(&config.ClientConfig{}))
+
+	chain := newRelayChain(&config.ClientConfig{RelayChain: "relay-a:9999, relay-b:9999"})
+	require.NotNil(t, chain)
+	assert.Equal(t, []string{"relay-a:9999", "relay-b:9999"}, chain.relays)
+}
+
+func TestRelayChainDial(t *testing.T) {
+	// A single fake relay stands in for a two-relay chain by acknowledging twice
+	first := startFakeRelay(t, []string{"relay-b:9999", "target:8080"}, 2)
+	chain := &relayChain{relays: []string{first, "relay-b:9999"}}
+
+	conn, hops, err := chain.dial("target:8080")
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	require.Len(t, hops, 2)
+	assert.Equal(t, first, hops[0].addr)
+	assert.Equal(t, "relay-b:9999", hops[1].addr)
+	assert.False(t, hops[0].relayTime.IsZero())
+
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(buf))
+}
+
+func TestRelayChainDialMissingAck(t *testing.T) {
+	first := startFakeRelay(t, []string{"relay-b:9999", "target:8080"}, 0)
+	chain := &relayChain{relays: []string{first, "relay-b:9999"}}
+
+	_, _, err := chain.dial("target:8080")
+	assert.Error(t, err)
+}
@@ -0,0 +1,20 @@
+package client
+
+import "github.com/PhilipSchmid/flow-generator-app/internal/config"
+
+// restartRequired reports whether settings changed that are only applied on restart.
+// The rate, burst pacing, rate transition, ports and log level are applied on reload.
+func restartRequired(oldCfg, newCfg *config.ClientConfig) bool {
+	a, b := *oldCfg, *newCfg
+	for _, c := range []*config.ClientConfig{&a, &b} {
+		c.LogLevel = ""
+		c.Rate = 0
+		c.BurstSize = 0
+		c.BurstInterval = 0
+		c.RateTransition = 0
+		c.Protocol = ""
+		c.TCPPorts = ""
+		c.UDPPorts = ""
+	}
+	return a != b
+}
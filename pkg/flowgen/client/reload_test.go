@@ -0,0 +1,24 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestartRequired(t *testing.T) {
+	base := config.ClientConfig{Server: "localhost", Rate: 10, Protocol: "tcp", TCPPorts: "8080", MaxConcurrent: 100}
+
+	reloadable := base
+	reloadable.Rate = 50
+	reloadable.Protocol = "both"
+	reloadable.UDPPorts = "9000"
+	reloadable.LogLevel = "debug"
+	reloadable.RateTransition = 30
+	assert.False(t, restartRequired(&base, &reloadable))
+
+	restart := base
+	restart.MaxConcurrent = 200
+	assert.True(t, restartRequired(&base, &restart))
+}
@@ -0,0 +1,308 @@
+package client
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/version"
+)
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite groups the test cases of a single run
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+// junitProperty is a name/value pair attached to a test suite
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// junitTestCase is a single check of the run
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// junitFailure describes why a test case failed
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+}
+
+// portResult holds the traffic exchanged with a single protocol/port
+type portResult struct {
+	Protocol      string
+	Port          string
+	RequestsSent  uint64
+	BytesSent     uint64
+	BytesReceived uint64
+	// Sized is set if the server sizes the responses of the port, which then echo a different number of bytes
+	Sized bool
+	// Failure is why the traffic on the port counts as failed, empty if it passed
+	Failure string
+}
+
+// failure returns why the traffic on the port counts as failed, or an empty string if it passed.
+// TCP must echo every byte unless the server sizes its responses, while UDP only fails if nothing came back
+// at all.
+func (p portResult) failure() string {
+	switch {
+	case p.Protocol == "tcp" && !p.Sized && p.BytesReceived < p.BytesSent:
+		return fmt.Sprintf("received %d of %d bytes sent", p.BytesReceived, p.BytesSent)
+	case p.BytesSent > 0 && p.BytesReceived == 0:
+		return fmt.Sprintf("no response to %d bytes sent", p.BytesSent)
+	}
+	return ""
+}
+
+// portResults returns the per-protocol/port traffic of the run ordered by protocol and port
+func portResults(r runResults) []portResult {
+	var results []portResult
+	m := r.Metrics
+	for _, protocol := range sortedKeys(m.RequestsSent) {
+		for _, port := range sortedKeys(m.RequestsSent[protocol]) {
+			result := portResult{
+				Protocol:      protocol,
+				Port:          port,
+				RequestsSent:  m.RequestsSent[protocol][port],
+				BytesSent:     m.BytesSent[protocol][port],
+				BytesReceived: m.BytesReceived[protocol][port],
+			}
+			if n, err := strconv.Atoi(port); err == nil {
+				_, result.Sized = responseSizes[n]
+			}
+			result.Failure = result.failure()
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// writeResultsJUnit writes the run results as a JUnit XML report with one test case for the run itself
+// and one per target protocol/port
+func writeResultsJUnit(w io.Writer, r runResults) error {
+	const className = "flow-generator"
+	suite := junitTestSuite{
+		Name: r.Run.Scenario,
+		Time: strconv.FormatFloat(r.Run.ElapsedSeconds, 'f', 3, 64),
+		Properties: []junitProperty{
+			{Name: "phase", Value: r.Run.Phase},
+			{Name: "flows_started", Value: strconv.FormatUint(r.Run.FlowsStarted, 10)},
+			{Name: "configured_rate", Value: strconv.FormatFloat(r.Run.ConfiguredRate, 'f', -1, 64)},
+			{Name: "achieved_rate", Value: strconv.FormatFloat(r.Run.AchievedRate, 'f', -1, 64)},
+			{Name: "version", Value: version.Version},
+		},
+	}
+
+	if r.Run.StopReason != "" {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "stop_reason", Value: r.Run.StopReason})
+	}
+	for _, key := range slices.Sorted(maps.Keys(r.Run.Metadata)) {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "meta." + key, Value: r.Run.Metadata[key]})
+	}
+	if n := r.Run.Netem; n != nil && n.Active {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "netem", Value: n.Interface + ": " + n.Qdisc})
+	}
+	for _, p := range r.Run.PauseWindows {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "pause_window", Value: p.String()})
+	}
+	for _, w := range r.Run.Warnings {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "warning", Value: w.String()})
+	}
+	for _, e := range r.Run.ConntrackBackoffs {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "conntrack_backoff", Value: e.String()})
+	}
+
+	run := junitTestCase{Name: "run", ClassName: className}
+	if r.Run.Phase != phaseCompleted {
+		run.Failure = &junitFailure{Message: "run ended in phase " + r.Run.Phase, Type: "RunIncomplete"}
+	}
+	suite.Cases = append(suite.Cases, run)
+
+	for _, p := range r.Run.Phases {
+		tc := junitTestCase{Name: "phase/" + p.Name, ClassName: className, SystemOut: fmt.Sprintf("status: %s, flows completed: %d, flows failed: %d", p.Status, p.FlowsCompleted, p.FlowsFailed)}
+		if p.Status == phaseStatusFailed {
+			tc.Failure = &junitFailure{Message: strings.Join(p.Violations, "; "), Type: "PhaseAssertion"}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	for _, c := range r.Run.Services {
+		tc := junitTestCase{Name: "service/" + strconv.Itoa(c.Port), ClassName: className, SystemOut: fmt.Sprintf("expected: %s, observed: %s", c.Expected, c.Observed)}
+		if !c.Match {
+			tc.Failure = &junitFailure{Message: fmt.Sprintf("expected %s but port answered as %s", c.Expected, c.Observed), Type: "ServiceMismatch"}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	for _, p := range portResults(r) {
+		tc := junitTestCase{
+			Name:      p.Protocol + "/" + p.Port,
+			ClassName: className,
+			SystemOut: fmt.Sprintf("requests sent: %d, bytes sent: %d, bytes received: %d", p.RequestsSent, p.BytesSent, p.BytesReceived),
+		}
+		if p.Failure != "" {
+			tc.Failure = &junitFailure{Message: p.Failure, Type: "EchoMismatch"}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	suite.Tests = len(suite.Cases)
+	for _, tc := range suite.Cases {
+		if tc.Failure != nil {
+			suite.Failures++
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return fmt.Errorf("failed to write JUnit results: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// chartBar is a single bar of an HTML report chart
+type chartBar struct {
+	Label string
+	Value string
+	// Width is the bar length in pixels, relative to the largest bar
+	Width int
+	Y     int
+}
+
+// chart is a horizontal bar chart rendered as inline SVG
+type chart struct {
+	Title  string
+	Bars   []chartBar
+	Height int
+}
+
+// newChart builds a bar chart scaling all bars relative to the largest value
+func newChart(title string, labels []string, values []float64, format func(float64) string) chart {
+	const barHeight, maxWidth = 24, 480
+	largest := 0.0
+	for _, v := range values {
+		largest = max(largest, v)
+	}
+	c := chart{Title: title, Height: len(values) * barHeight}
+	for i, v := range values {
+		bar := chartBar{Label: labels[i], Value: format(v), Y: i * barHeight}
+		if largest > 0 {
+			bar.Width = int(v / largest * maxWidth)
+		}
+		c.Bars = append(c.Bars, bar)
+	}
+	return c
+}
+
+// htmlReport is the data rendered into the HTML report
+type htmlReport struct {
+	Results runResults
+	Version string
+	Ports   []portResult
+	Charts  []chart
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"upper": strings.ToUpper,
+	"add":   func(a, b int) int { return a + b },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Flow Generator Report - {{.Results.Run.Scenario}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 12px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+.failed { color: #b00020; }
+svg text { font-size: 12px; }
+</style>
+</head>
+<body>
+<h1>Flow Generator Report</h1>
+<table>
+<tr><td>Scenario</td><td>{{.Results.Run.Scenario}}</td></tr>
+{{range $key, $value := .Results.Run.Metadata}}<tr><td>{{$key}}</td><td>{{$value}}</td></tr>
+{{end}}<tr><td>Phase</td><td{{if ne .Results.Run.Phase "completed"}} class="failed"{{end}}>{{.Results.Run.Phase}}</td></tr>
+{{with .Results.Run.StopReason}}<tr><td>Stopped by</td><td>{{.}}</td></tr>
+{{end}}<tr><td>Elapsed</td><td>{{printf "%.1f" .Results.Run.ElapsedSeconds}}s</td></tr>
+<tr><td>Flows started</td><td>{{.Results.Run.FlowsStarted}}</td></tr>
+<tr><td>Configured rate</td><td>{{printf "%.2f" .Results.Run.ConfiguredRate}} flows/s</td></tr>
+<tr><td>Achieved rate</td><td>{{printf "%.2f" .Results.Run.AchievedRate}} flows/s</td></tr>
+{{with .Results.Run.Netem}}{{if .Active}}<tr><td>Netem ({{.Interface}})</td><td class="failed">{{.Qdisc}}</td></tr>
+{{end}}{{end}}<tr><td>Requests sent</td><td>{{.Results.Metrics.TotalRequestsSent}}</td></tr>
+<tr><td>Version</td><td>{{.Version}}</td></tr>
+</table>
+{{if .Ports}}<h2>Targets</h2>
+<table>
+<tr><th>Target</th><th>Requests sent</th><th>Bytes sent</th><th>Bytes received</th><th>Result</th></tr>
+{{range .Ports}}<tr><td>{{upper .Protocol}}/{{.Port}}</td><td>{{.RequestsSent}}</td><td>{{.BytesSent}}</td><td>{{.BytesReceived}}</td>{{with .Failure}}<td class="failed">{{.}}</td>{{else}}<td>ok</td>{{end}}</tr>
+{{end}}</table>
+{{end}}{{range .Charts}}<h2>{{.Title}}</h2>
+<svg width="720" height="{{.Height}}" role="img" aria-label="{{.Title}}">
+{{range .Bars}}<text x="0" y="{{.Y}}" dy="16">{{.Label}}</text>
+<rect x="120" y="{{.Y}}" width="{{.Width}}" height="18" fill="#4a7bd0"></rect>
+<text x="{{add .Width 126}}" y="{{.Y}}" dy="16">{{.Value}}</text>
+{{end}}</svg>
+{{end}}</body>
+</html>
+`))
+
+// writeResultsHTML writes the run results as a standalone HTML report with charts
+func writeResultsHTML(w io.Writer, r runResults) error {
+	report := htmlReport{Results: r, Version: version.Version, Ports: portResults(r)}
+
+	if len(report.Ports) > 0 {
+		labels := make([]string, 0, len(report.Ports))
+		values := make([]float64, 0, len(report.Ports))
+		for _, p := range report.Ports {
+			labels = append(labels, strings.ToUpper(p.Protocol)+"/"+p.Port)
+			values = append(values, float64(p.RequestsSent))
+		}
+		report.Charts = append(report.Charts, newChart("Requests sent per target", labels, values, func(v float64) string {
+			return strconv.FormatFloat(v, 'f', 0, 64)
+		}))
+	}
+
+	for _, protocol := range sortedKeys(r.Metrics.Latency) {
+		l := r.Metrics.Latency[protocol]
+		report.Charts = append(report.Charts, newChart(
+			strings.ToUpper(protocol)+" round-trip latency",
+			[]string{"min", "mean", "p50", "p90", "p99", "max"},
+			[]float64{l.MinMs, l.MeanMs, l.P50Ms, l.P90Ms, l.P99Ms, l.MaxMs},
+			func(v float64) string { return fmt.Sprintf("%.3f ms", v) },
+		))
+	}
+
+	if err := htmlReportTemplate.Execute(w, report); err != nil {
+		return fmt.Errorf("failed to write HTML results: %w", err)
+	}
+	return nil
+}
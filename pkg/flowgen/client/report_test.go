@@ -0,0 +1,180 @@
+package client
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/internal/netem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testReportResults returns results with a healthy TCP port, a TCP port with missing echoes and a healthy UDP port
+func testReportResults(phase string) runResults {
+	return runResults{
+		Run: runStatus{Scenario: "nightly", Metadata: map[string]string{"owner": "netops"}, Phase: phase, ElapsedSeconds: 12.5, FlowsStarted: 30},
+		Metrics: metrics.Summary{
+			TotalRequestsSent: 30,
+			RequestsSent:      map[string]map[string]uint64{"tcp": {"8080": 10, "8081": 10}, "udp": {"53": 10}},
+			BytesSent:         map[string]map[string]uint64{"tcp": {"8080": 100, "8081": 100}, "udp": {"53": 100}},
+			BytesReceived:     map[string]map[string]uint64{"tcp": {"8080": 100, "8081": 40}, "udp": {"53": 90}},
+			Latency:           map[string]metrics.LatencySummary{"tcp": {Count: 20, MinMs: 0.5, MeanMs: 1, P50Ms: 1, P90Ms: 2, P99Ms: 3, MaxMs: 4}},
+		},
+	}
+}
+
+func TestPortResultFailure(t *testing.T) {
+	assert.Empty(t, portResult{Protocol: "tcp", BytesSent: 10, BytesReceived: 10}.failure())
+	assert.Equal(t, "received 5 of 10 bytes sent", portResult{Protocol: "tcp", BytesSent: 10, BytesReceived: 5}.failure())
+	assert.Empty(t, portResult{Protocol: "tcp", BytesSent: 10, BytesReceived: 5, Sized: true}.failure(), "truncated responses")
+	assert.Empty(t, portResult{Protocol: "udp", BytesSent: 10, BytesReceived: 5}.failure())
+	assert.Equal(t, "no response to 10 bytes sent", portResult{Protocol: "udp", BytesSent: 10}.failure())
+}
+
+func TestWriteResultsJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeResultsJUnit(&buf, testReportResults(phaseCompleted)))
+
+	var report junitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &report))
+	require.Len(t, report.Suites, 1)
+
+	suite := report.Suites[0]
+	assert.Equal(t, "nightly", suite.Name)
+	assert.Equal(t, "12.500", suite.Time)
+	assert.Equal(t, 4, suite.Tests)
+	assert.Equal(t, 1, suite.Failures)
+	assert.Contains(t, suite.Properties, junitProperty{Name: "meta.owner", Value: "netops"})
+
+	names := make([]string, 0, len(suite.Cases))
+	for _, tc := range suite.Cases {
+		names = append(names, tc.Name)
+	}
+	assert.Equal(t, []string{"run", "tcp/8080", "tcp/8081", "udp/53"}, names)
+	assert.Nil(t, suite.Cases[0].Failure)
+	require.NotNil(t, suite.Cases[2].Failure)
+	assert.Equal(t, "received 40 of 100 bytes sent", suite.Cases[2].Failure.Message)
+}
+
+func TestWriteResultsJUnitPhases(t *testing.T) {
+	results := testReportResults(phaseCompleted)
+	results.Run.Phases = []phaseReport{
+		{Name: "steady", Status: phaseStatusPassed},
+		{Name: "chaos", Status: phaseStatusFailed, Violations: []string{"error rate 8.00% (8 of 100 flows failed) exceeds max_error_rate 5%"}},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, writeResultsJUnit(&buf, results))
+
+	var report junitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &report))
+	suite := report.Suites[0]
+	assert.Equal(t, 6, suite.Tests)
+	assert.Equal(t, 2, suite.Failures)
+	assert.Equal(t, "phase/steady", suite.Cases[1].Name)
+	assert.Nil(t, suite.Cases[1].Failure)
+	require.NotNil(t, suite.Cases[2].Failure)
+	assert.Equal(t, "PhaseAssertion", suite.Cases[2].Failure.Type)
+	assert.Contains(t, suite.Cases[2].Failure.Message, "exceeds max_error_rate 5%")
+}
+
+func TestWriteResultsJUnitPauseWindows(t *testing.T) {
+	results := testReportResults(phaseCompleted)
+	end := 4500.0
+	results.Run.PauseWindows = []pauseMarker{{Window: "02:00-02:15", StartSeconds: 3600, EndSeconds: &end}}
+	var buf bytes.Buffer
+	require.NoError(t, writeResultsJUnit(&buf, results))
+
+	var report junitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &report))
+	assert.Contains(t, report.Suites[0].Properties, junitProperty{Name: "pause_window", Value: "02:00-02:15 at 3600.0s-4500.0s"})
+}
+
+func TestWriteResultsJUnitWarnings(t *testing.T) {
+	results := testReportResults(phaseCompleted)
+	end := 57.0
+	results.Run.Warnings = []warningMarker{{Limit: "max_error_rate", Value: 3.1, Threshold: 1, Message: "error rate 3.10% (1 of 32 flows failed) over the last 30s exceeds 1%", StartSeconds: 42, EndSeconds: &end}}
+	var buf bytes.Buffer
+	require.NoError(t, writeResultsJUnit(&buf, results))
+
+	var report junitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &report))
+	assert.Contains(t, report.Suites[0].Properties, junitProperty{Name: "warning", Value: "max_error_rate at 42.0s-57.0s: error rate 3.10% (1 of 32 flows failed) over the last 30s exceeds 1%"})
+}
+
+func TestWriteResultsJUnitConntrackBackoffs(t *testing.T) {
+	results := testReportResults(phaseCompleted)
+	end, recovery := 57.0, 12.5
+	results.Run.ConntrackBackoffs = []conntrackEvent{{StartSeconds: 42, FailureRate: 85, EndSeconds: &end, RecoverySeconds: &recovery}}
+	var buf bytes.Buffer
+	require.NoError(t, writeResultsJUnit(&buf, results))
+
+	var report junitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &report))
+	assert.Contains(t, report.Suites[0].Properties, junitProperty{Name: "conntrack_backoff", Value: "at 42.0s-57.0s: 85.0% of flows timed out or were refused, recovered after 12.5s"})
+}
+
+func TestWriteResultsJUnitServices(t *testing.T) {
+	results := testReportResults(phaseCompleted)
+	results.Run.Services = []serviceCheck{
+		{Port: 8080, Expected: serviceEcho, Observed: serviceEcho, Match: true},
+		{Port: 8443, Expected: serviceEcho, Observed: serviceTLS},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, writeResultsJUnit(&buf, results))
+
+	var report junitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &report))
+	suite := report.Suites[0]
+	assert.Equal(t, "service/8080", suite.Cases[1].Name)
+	assert.Nil(t, suite.Cases[1].Failure)
+	require.NotNil(t, suite.Cases[2].Failure)
+	assert.Equal(t, "ServiceMismatch", suite.Cases[2].Failure.Type)
+	assert.Equal(t, "expected echo but port answered as tls", suite.Cases[2].Failure.Message)
+}
+
+func TestWriteResultsJUnitAborted(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeResultsJUnit(&buf, testReportResults(phaseAborted)))
+
+	var report junitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &report))
+	require.NotNil(t, report.Suites[0].Cases[0].Failure)
+	assert.Equal(t, "run ended in phase aborted", report.Suites[0].Cases[0].Failure.Message)
+	assert.Equal(t, 2, report.Suites[0].Failures)
+}
+
+func TestWriteResultsHTML(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeResultsHTML(&buf, testReportResults(phaseCompleted)))
+
+	out := buf.String()
+	assert.Contains(t, out, "<title>Flow Generator Report - nightly</title>")
+	assert.Contains(t, out, "<tr><td>owner</td><td>netops</td></tr>")
+	assert.Contains(t, out, "<td>TCP/8081</td>")
+	assert.Contains(t, out, `<td class="failed">received 40 of 100 bytes sent</td>`)
+	assert.Contains(t, out, "<h2>Requests sent per target</h2>")
+	assert.Contains(t, out, "<h2>TCP round-trip latency</h2>")
+	assert.Contains(t, out, "4.000 ms")
+	assert.NotContains(t, out, "Netem")
+
+	results := testReportResults(phaseCompleted)
+	results.Run.Netem = &netem.Status{Interface: "eth0", Active: true, Qdisc: "netem delay 100ms"}
+	buf.Reset()
+	require.NoError(t, writeResultsHTML(&buf, results))
+	assert.Contains(t, buf.String(), `<tr><td>Netem (eth0)</td><td class="failed">netem delay 100ms</td></tr>`)
+}
+
+func TestNewChart(t *testing.T) {
+	c := newChart("test", []string{"a", "b", "c"}, []float64{1, 4, 0}, func(v float64) string { return "" })
+	assert.Equal(t, 72, c.Height)
+	assert.Equal(t, 120, c.Bars[0].Width)
+	assert.Equal(t, 480, c.Bars[1].Width)
+	assert.Equal(t, 0, c.Bars[2].Width)
+	assert.Equal(t, 48, c.Bars[2].Y)
+
+	// All-zero charts must not divide by zero
+	c = newChart("empty", []string{"a"}, []float64{0}, func(v float64) string { return "" })
+	assert.Equal(t, 0, c.Bars[0].Width)
+}
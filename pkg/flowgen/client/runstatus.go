@@ -0,0 +1,294 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/netem"
+)
+
+// runStatusPath is the client endpoint exposing the state of the current run
+const runStatusPath = "/run"
+
+// Run phases reported by the run endpoint
+const (
+	phaseRunning    = "running"
+	phasePaused     = "paused"
+	phaseDraining   = "draining"
+	phaseCompleted  = "completed"
+	phaseAborted    = "aborted"
+	phaseTerminated = "terminated"
+)
+
+// runStatus describes where the current run is
+type runStatus struct {
+	Scenario string `json:"scenario"`
+	// Metadata describes the experiment the run belongs to, such as its owner or ticket
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Phase    string            `json:"phase"`
+	// StopReason is the stop condition that ended flow generation, flow_count, flow_timeout or steps
+	StopReason string `json:"stop_reason,omitempty"`
+	// Stage is the load step running if the run follows a stepped load profile
+	Stage          string  `json:"stage,omitempty"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	// WarmupSeconds is the start of the run left out of the statistics
+	WarmupSeconds    float64  `json:"warmup_seconds,omitempty"`
+	RemainingSeconds *float64 `json:"remaining_seconds"`
+	FlowsStarted     uint64   `json:"flows_started"`
+	ConfiguredRate   float64  `json:"configured_rate"`
+	EffectiveRate    float64  `json:"effective_rate"`
+	AchievedRate     float64  `json:"achieved_rate"`
+	// Netem is the netem impairment detected on the egress interface at run start
+	Netem *netem.Status `json:"netem,omitempty"`
+	// RateChanges is the timeline of flow rate changes made while the run was in progress
+	RateChanges []rateChange `json:"rate_changes,omitempty"`
+	// PauseWindows marks the pause windows the run went through, in which no new flows were started
+	PauseWindows []pauseMarker `json:"pause_windows,omitempty"`
+	// Warnings marks the soft limits crossed while the run was in progress
+	Warnings []warningMarker `json:"warnings,omitempty"`
+	// ConntrackBackoffs marks the backoffs from a full conntrack table on the path and how long it took to recover
+	ConntrackBackoffs []conntrackEvent `json:"conntrack_backoffs,omitempty"`
+	// Failover describes the switchover from the primary to the backup target, if a backup is configured
+	Failover *failoverReport `json:"failover,omitempty"`
+	// Phases reports the time-boxed phases of the run and the outcome of their assertions
+	Phases []phaseReport `json:"phases,omitempty"`
+	// Services are the checks of the services expected on ports of the server, made before the run
+	Services []serviceCheck `json:"services,omitempty"`
+}
+
+// rateChange records a change of the effective flow rate during a run
+type rateChange struct {
+	ElapsedSeconds    float64 `json:"elapsed_seconds"`
+	FromRate          float64 `json:"from_rate"`
+	ToRate            float64 `json:"to_rate"`
+	TransitionSeconds float64 `json:"transition_seconds"`
+	Reason            string  `json:"reason"`
+}
+
+// pauseMarker records a pause window of a run, its end is missing while the window lasts
+type pauseMarker struct {
+	Window       string   `json:"window"`
+	StartSeconds float64  `json:"start_seconds"`
+	EndSeconds   *float64 `json:"end_seconds,omitempty"`
+}
+
+// String describes the marker for reports, e.g. "02:00-02:15 at 3600.0s-4500.0s"
+func (m pauseMarker) String() string {
+	if m.EndSeconds == nil {
+		return fmt.Sprintf("%s at %.1fs-", m.Window, m.StartSeconds)
+	}
+	return fmt.Sprintf("%s at %.1fs-%.1fs", m.Window, m.StartSeconds, *m.EndSeconds)
+}
+
+// runTracker keeps track of the current run for the run endpoint
+type runTracker struct {
+	mu             sync.Mutex
+	scenario       string
+	metadata       map[string]string
+	phase          string
+	stopReason     string
+	start          time.Time
+	timeout        time.Duration
+	warmup         time.Duration
+	configuredRate float64
+	effectiveRate  float64
+	flows          *uint64
+	netem          *netem.Status
+	rateChanges    []rateChange
+	pauses         []pauseMarker
+	failover       *failoverMonitor
+	phases         *phaseMonitor
+	warnings       *softLimits
+	conntrack      *conntrackBackoff
+	steps          *loadSteps
+	services       []serviceCheck
+}
+
+// newRunTracker creates a tracker for a run starting at the given time, reading the number of started flows from flows
+func newRunTracker(c *config.ClientConfig, start time.Time, flows *uint64) *runTracker {
+	// The metadata was checked when the configuration was validated
+	metadata, _ := config.ParseMetadata(c.Meta)
+	if len(metadata) == 0 {
+		metadata = nil
+	}
+	return &runTracker{
+		scenario: c.Scenario,
+		metadata: metadata,
+		phase:    phaseRunning,
+		start:    start,
+		timeout:  time.Duration(c.FlowTimeout * float64(time.Second)),
+		warmup:   seconds(c.Warmup),
+		flows:    flows,
+	}
+}
+
+// setPhase records the phase the run has entered
+func (t *runTracker) setPhase(phase string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.phase = phase
+}
+
+// setStopReason records the stop condition that ended flow generation
+func (t *runTracker) setStopReason(reason string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopReason = reason
+}
+
+// setNetem records the netem impairment detected at run start
+func (t *runTracker) setNetem(status netem.Status) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.netem = &status
+}
+
+// setFailover records the failover whose switchover is reported
+func (t *runTracker) setFailover(m *failoverMonitor) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.failover = m
+}
+
+// setPhases records the phases whose assertions are reported
+func (t *runTracker) setPhases(m *phaseMonitor) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.phases = m
+}
+
+// setSoftLimits records the soft limits whose crossings are reported
+func (t *runTracker) setSoftLimits(l *softLimits) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.warnings = l
+}
+
+// setConntrackBackoff records the conntrack backoff whose backoffs are reported
+func (t *runTracker) setConntrackBackoff(b *conntrackBackoff) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.conntrack = b
+}
+
+// setSteps records the load steps whose current stage is reported
+func (t *runTracker) setSteps(s *loadSteps) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.steps = s
+}
+
+// setServices records the service checks made before the run
+func (t *runTracker) setServices(checks []serviceCheck) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.services = checks
+}
+
+// setRates records the configured flow rate and the rate currently applied after backpressure
+func (t *runTracker) setRates(configured, effective float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.configuredRate = configured
+	t.effectiveRate = effective
+}
+
+// addRateChange records a change of the effective flow rate from one rate to another starting at the
+// given time and ramped in over transition
+func (t *runTracker) addRateChange(at time.Time, from, to float64, transition time.Duration, reason string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rateChanges = append(t.rateChanges, rateChange{
+		ElapsedSeconds:    at.Sub(t.start).Seconds(),
+		FromRate:          from,
+		ToRate:            to,
+		TransitionSeconds: transition.Seconds(),
+		Reason:            reason,
+	})
+}
+
+// setPauseWindow records that the run entered the given pause window at the given time, ending the window
+// it was in before. An empty window ends the current one.
+func (t *runTracker) setPauseWindow(at time.Time, window string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	elapsed := at.Sub(t.start).Seconds()
+	if n := len(t.pauses); n > 0 && t.pauses[n-1].EndSeconds == nil {
+		t.pauses[n-1].EndSeconds = &elapsed
+	}
+	if window != "" {
+		t.pauses = append(t.pauses, pauseMarker{Window: window, StartSeconds: elapsed})
+	}
+}
+
+// status returns the state of the run at the given time
+func (t *runTracker) status(now time.Time) runStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	elapsed := now.Sub(t.start)
+	status := runStatus{
+		Scenario:          t.scenario,
+		Metadata:          t.metadata,
+		Phase:             t.phase,
+		StopReason:        t.stopReason,
+		Stage:             t.steps.stage(),
+		ElapsedSeconds:    elapsed.Seconds(),
+		WarmupSeconds:     t.warmup.Seconds(),
+		FlowsStarted:      atomic.LoadUint64(t.flows),
+		ConfiguredRate:    t.configuredRate,
+		EffectiveRate:     t.effectiveRate,
+		Netem:             t.netem,
+		RateChanges:       append([]rateChange(nil), t.rateChanges...),
+		PauseWindows:      append([]pauseMarker(nil), t.pauses...),
+		Warnings:          t.warnings.status(),
+		ConntrackBackoffs: t.conntrack.status(),
+		Failover:          t.failover.status(),
+		Phases:            t.phases.status(now),
+		Services:          t.services,
+	}
+	if elapsed > 0 {
+		status.AchievedRate = float64(status.FlowsStarted) / elapsed.Seconds()
+	}
+	if t.timeout > 0 {
+		remaining := max(t.timeout-elapsed, 0).Seconds()
+		status.RemainingSeconds = &remaining
+	}
+	return status
+}
+
+// ServeHTTP reports the state of the run as JSON
+func (t *runTracker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(t.status(time.Now())); err != nil {
+		logging.Logger.Debugf("Failed to write run status: %v", err)
+	}
+}
+
+// logRunReport emits the final state of the run as JSON
+func logRunReport(t *runTracker) {
+	report, err := json.Marshal(t.status(time.Now()))
+	if err != nil {
+		logging.Logger.Errorf("Failed to encode run report: %v", err)
+		return
+	}
+	logging.Logger.Infof("Run report: %s", report)
+}
+
+// detectNetem checks the egress interface towards the target for netem impairments and warns if one is configured
+func detectNetem(target string) netem.Status {
+	status := netem.Detect(target)
+	switch {
+	case status.Error != "":
+		logging.Logger.Debugf("Could not detect netem configuration: %s", status.Error)
+	case status.Active:
+		logging.Logger.Warnf("netem impairment configured on egress interface %s (%s), results will include it", status.Interface, status.Qdisc)
+	}
+	return status
+}
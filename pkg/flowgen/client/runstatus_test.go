@@ -0,0 +1,118 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/netem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunTrackerStatus(t *testing.T) {
+	start := time.Now()
+	flows := uint64(30)
+	tracker := newRunTracker(&config.ClientConfig{Scenario: "soak", FlowTimeout: 60}, start, &flows)
+	tracker.setRates(20, 10)
+
+	status := tracker.status(start.Add(10 * time.Second))
+	assert.Equal(t, "soak", status.Scenario)
+	assert.Equal(t, phaseRunning, status.Phase)
+	assert.InDelta(t, 10, status.ElapsedSeconds, 0.001)
+	require.NotNil(t, status.RemainingSeconds)
+	assert.InDelta(t, 50, *status.RemainingSeconds, 0.001)
+	assert.Equal(t, uint64(30), status.FlowsStarted)
+	assert.Equal(t, float64(20), status.ConfiguredRate)
+	assert.Equal(t, float64(10), status.EffectiveRate)
+	assert.InDelta(t, 3, status.AchievedRate, 0.001)
+	assert.Nil(t, status.Metadata)
+
+	// Remaining time never drops below zero while flows drain
+	tracker.setPhase(phaseDraining)
+	status = tracker.status(start.Add(90 * time.Second))
+	assert.Equal(t, phaseDraining, status.Phase)
+	assert.Equal(t, float64(0), *status.RemainingSeconds)
+}
+
+func TestRunTrackerMetadata(t *testing.T) {
+	flows := uint64(0)
+	tracker := newRunTracker(&config.ClientConfig{Scenario: "soak", Meta: "owner=netops,ticket=NET-1234"}, time.Now(), &flows)
+	assert.Equal(t, map[string]string{"owner": "netops", "ticket": "NET-1234"}, tracker.status(time.Now()).Metadata)
+}
+
+func TestRunTrackerRateChanges(t *testing.T) {
+	start := time.Now()
+	flows := uint64(0)
+	tracker := newRunTracker(&config.ClientConfig{}, start, &flows)
+	assert.Empty(t, tracker.status(start).RateChanges)
+
+	tracker.addRateChange(start.Add(30*time.Second), 100, 500, 10*time.Second, "reload")
+	tracker.addRateChange(start.Add(60*time.Second), 500, 250, 0, "backpressure")
+
+	status := tracker.status(start.Add(90 * time.Second))
+	require.Len(t, status.RateChanges, 2)
+	assert.Equal(t, rateChange{ElapsedSeconds: 30, FromRate: 100, ToRate: 500, TransitionSeconds: 10, Reason: "reload"}, status.RateChanges[0])
+	assert.Equal(t, "backpressure", status.RateChanges[1].Reason)
+
+	// The timeline of a returned status is not affected by later changes
+	tracker.addRateChange(start.Add(120*time.Second), 250, 500, 0, "backpressure")
+	assert.Len(t, status.RateChanges, 2)
+}
+
+func TestRunTrackerPauseWindows(t *testing.T) {
+	start := time.Now()
+	flows := uint64(0)
+	tracker := newRunTracker(&config.ClientConfig{}, start, &flows)
+
+	tracker.setPauseWindow(start.Add(10*time.Second), "02:00-02:15")
+	status := tracker.status(start.Add(20 * time.Second))
+	require.Len(t, status.PauseWindows, 1)
+	assert.Nil(t, status.PauseWindows[0].EndSeconds)
+	assert.Equal(t, "02:00-02:15 at 10.0s-", status.PauseWindows[0].String())
+
+	// Entering an adjacent window ends the one before
+	tracker.setPauseWindow(start.Add(30*time.Second), "02:15-02:30")
+	tracker.setPauseWindow(start.Add(45*time.Second), "")
+	assert.Nil(t, status.PauseWindows[0].EndSeconds, "a returned status is not affected by later changes")
+	status = tracker.status(start.Add(60 * time.Second))
+	require.Len(t, status.PauseWindows, 2)
+	assert.Equal(t, "02:00-02:15 at 10.0s-30.0s", status.PauseWindows[0].String())
+	assert.Equal(t, "02:15-02:30 at 30.0s-45.0s", status.PauseWindows[1].String())
+}
+
+func TestRunTrackerUnlimited(t *testing.T) {
+	var flows uint64
+	start := time.Now()
+	tracker := newRunTracker(&config.ClientConfig{Scenario: "default"}, start, &flows)
+
+	status := tracker.status(start)
+	assert.Nil(t, status.RemainingSeconds)
+	assert.Equal(t, float64(0), status.AchievedRate)
+	assert.Nil(t, status.Netem)
+
+	tracker.setNetem(netem.Status{Interface: "eth0", Active: true, Delay: "50ms"})
+	status = tracker.status(start)
+	require.NotNil(t, status.Netem)
+	assert.Equal(t, "50ms", status.Netem.Delay)
+}
+
+func TestRunTrackerServeHTTP(t *testing.T) {
+	var flows uint64
+	tracker := newRunTracker(&config.ClientConfig{Scenario: "default"}, time.Now(), &flows)
+
+	rec := httptest.NewRecorder()
+	tracker.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, runStatusPath, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "default", body["scenario"])
+	assert.Equal(t, phaseRunning, body["phase"])
+	assert.Contains(t, body, "remaining_seconds")
+	assert.Nil(t, body["remaining_seconds"])
+}
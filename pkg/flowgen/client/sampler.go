@@ -0,0 +1,48 @@
+package client
+
+import (
+	"encoding/hex"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+)
+
+// flowSampler decides which flows are logged in full for wire-level debugging
+type flowSampler struct {
+	firstN   uint64
+	interval uint64
+	hexDump  bool
+}
+
+// newFlowSampler creates a sampler from the client configuration, or nil if sampling is disabled
+func newFlowSampler(c *config.ClientConfig) *flowSampler {
+	if c.DebugSampleFlows <= 0 && c.DebugSampleInterval <= 0 {
+		return nil
+	}
+	return &flowSampler{
+		firstN:   uint64(c.DebugSampleFlows),
+		interval: uint64(c.DebugSampleInterval),
+		hexDump:  c.DebugHexDump,
+	}
+}
+
+// sampled reports whether the flow with the given 1-based ID should be logged in full.
+// The first N flows are always sampled, afterwards every interval-th flow is.
+func (s *flowSampler) sampled(flowID uint64) bool {
+	if s == nil || flowID == 0 {
+		return false
+	}
+	if flowID <= s.firstN {
+		return true
+	}
+	return s.interval > 0 && (flowID-s.firstN)%s.interval == 0
+}
+
+// logPayload logs a sent or received payload of a sampled flow. Flows logged in full with flow_verbosity
+// are sampled without a sampler, their payloads are logged without hex dump.
+func (s *flowSampler) logPayload(flowID uint64, protocol, direction string, data []byte) {
+	if s != nil && s.hexDump {
+		logFlowDetail(flowID, protocol, true, "%s %d bytes:\n%s", direction, len(data), hex.Dump(data))
+		return
+	}
+	logFlowDetail(flowID, protocol, true, "%s %d bytes", direction, len(data))
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFlowSampler(t *testing.T) {
+	assert.Nil(t, newFlowSampler(&config.ClientConfig{}))
+
+	s := newFlowSampler(&config.ClientConfig{DebugSampleFlows: 3, DebugHexDump: true})
+	assert.NotNil(t, s)
+	assert.Equal(t, uint64(3), s.firstN)
+	assert.True(t, s.hexDump)
+}
+
+func TestFlowSamplerSampled(t *testing.T) {
+	tests := []struct {
+		name     string
+		sampler  *flowSampler
+		expected []uint64
+	}{
+		{"disabled", nil, nil},
+		{"first N only", &flowSampler{firstN: 3}, []uint64{1, 2, 3}},
+		{"interval only", &flowSampler{interval: 4}, []uint64{4, 8}},
+		{"first N then interval", &flowSampler{firstN: 2, interval: 3}, []uint64{1, 2, 5, 8}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []uint64
+			for id := uint64(0); id <= 10; id++ {
+				if tt.sampler.sampled(id) {
+					got = append(got, id)
+				}
+			}
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestFlowSamplerLogPayload(t *testing.T) {
+	logging.InitLogger("json", "error")
+
+	assert.NotPanics(t, func() {
+		(&flowSampler{hexDump: true}).logPayload(1, "tcp", "sent", []byte("hello"))
+		(&flowSampler{}).logPayload(1, "tcp", "received", []byte("hello"))
+	})
+}
@@ -0,0 +1,76 @@
+package client
+
+import "time"
+
+// flowScheduler computes exact fire times for paced flow generation.
+// The n-th tick is due at anchor + n/rate seconds, computed from the tick count instead of by adding up
+// rounded intervals, so very low or fractional rates stay on schedule over multi-day runs.
+type flowScheduler struct {
+	anchor time.Time
+	rate   float64 // ticks per second
+	ticks  int64   // ticks fired since anchor
+}
+
+// newFlowScheduler creates a scheduler ticking rate times per second starting at start
+func newFlowScheduler(start time.Time, rate float64) *flowScheduler {
+	return &flowScheduler{anchor: start, rate: rate}
+}
+
+// next returns when the next tick is due
+func (s *flowScheduler) next() time.Time {
+	return s.at(s.ticks + 1)
+}
+
+// at returns when the n-th tick after the anchor is due
+func (s *flowScheduler) at(n int64) time.Time {
+	return s.anchor.Add(time.Duration(float64(n) * float64(time.Second) / s.rate))
+}
+
+// fire records that the next tick fired at now. Ticks missed because generation fell behind by more than
+// a whole interval are skipped rather than fired in a burst, like time.Ticker does.
+func (s *flowScheduler) fire(now time.Time) {
+	s.ticks++
+	if due := int64(now.Sub(s.anchor).Seconds() * s.rate); due > s.ticks {
+		s.ticks = due
+	}
+}
+
+// setRate changes the tick rate, anchoring the new schedule at the last tick
+func (s *flowScheduler) setRate(rate float64) {
+	s.anchor = s.at(s.ticks)
+	s.ticks = 0
+	s.rate = rate
+}
+
+// launchTick launches the flows of a tick back-to-back, a whole burst in burst mode, and returns how many
+// were launched. It stops at the first flow launch refuses, e.g. once the flow limit is reached.
+func launchTick(flowsPerTick int, tickInterval time.Duration, launch func(tickInterval time.Duration) bool) int {
+	for i := 0; i < flowsPerTick; i++ {
+		if !launch(tickInterval) {
+			return i
+		}
+	}
+	return flowsPerTick
+}
+
+// rateRampStep is how often the tick rate is updated during a rate transition when ticks are further apart
+const rateRampStep = 100 * time.Millisecond
+
+// rateRamp moves the tick rate linearly from one rate to another over a transition period, so a rate
+// change does not hit the target as a step
+type rateRamp struct {
+	from   float64
+	to     float64
+	start  time.Time
+	period time.Duration
+}
+
+// at returns the tick rate at now and whether the transition has finished
+func (r *rateRamp) at(now time.Time) (float64, bool) {
+	progress := float64(now.Sub(r.start)) / float64(r.period)
+	if progress >= 1 {
+		return r.to, true
+	}
+	progress = max(progress, 0)
+	return r.from + (r.to-r.from)*progress, false
+}
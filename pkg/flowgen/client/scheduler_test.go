@@ -0,0 +1,126 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlowSchedulerFractionalRate(t *testing.T) {
+	start := time.Now()
+	s := newFlowScheduler(start, 0.2)
+	assert.Equal(t, start.Add(5*time.Second), s.next())
+
+	s.fire(s.next())
+	assert.Equal(t, start.Add(10*time.Second), s.next())
+}
+
+func TestFlowSchedulerLongHorizon(t *testing.T) {
+	start := time.Now()
+
+	// One tick every 1/3 s cannot be represented exactly in nanoseconds, yet three days of ticks
+	// must still end exactly on schedule
+	s := newFlowScheduler(start, 3)
+	ticks := int64(3 * 3 * 24 * 3600)
+	for i := int64(0); i < ticks; i++ {
+		s.fire(s.next())
+	}
+	assert.Equal(t, start.Add(72*time.Hour), s.at(s.ticks))
+
+	s = newFlowScheduler(start, 0.3)
+	assert.Equal(t, start.Add(10*time.Second), s.at(3))
+	assert.Equal(t, start.Add(100*time.Hour), s.at(108000))
+}
+
+func TestFlowSchedulerSkipsMissedTicks(t *testing.T) {
+	start := time.Now()
+	s := newFlowScheduler(start, 10)
+
+	// The loop was blocked for 1.05 s, so the next tick is the one due at 1.1 s
+	s.fire(start.Add(1050 * time.Millisecond))
+	assert.Equal(t, start.Add(1100*time.Millisecond), s.next())
+}
+
+func TestFlowSchedulerSetRate(t *testing.T) {
+	start := time.Now()
+	s := newFlowScheduler(start, 1)
+	s.fire(s.next())
+	s.fire(s.next())
+
+	// The new schedule continues from the last tick
+	s.setRate(0.5)
+	assert.Equal(t, start.Add(4*time.Second), s.next())
+}
+
+func TestBurstTicks(t *testing.T) {
+	ticksPerSecond, flowsPerTick := flowPacing(&config.ClientConfig{Rate: 4, BurstSize: 20, BurstInterval: 2})
+	start := time.Now()
+	s := newFlowScheduler(start, ticksPerSecond)
+
+	// Every tick is due a burst interval after the previous one and launches a whole burst
+	var launched []time.Time
+	var intervals []time.Duration
+	launch := func(tickInterval time.Duration) bool {
+		launched = append(launched, s.at(s.ticks))
+		intervals = append(intervals, tickInterval)
+		return true
+	}
+	for tick := 1; tick <= 3; tick++ {
+		due := s.next()
+		assert.Equal(t, start.Add(time.Duration(tick)*2*time.Second), due)
+		s.fire(due)
+		assert.Equal(t, 20, launchTick(flowsPerTick, time.Duration(float64(time.Second)/s.rate), launch))
+	}
+	require.Len(t, launched, 60)
+	for i, at := range launched {
+		assert.Equal(t, start.Add(time.Duration(i/20+1)*2*time.Second), at, "flow %d", i)
+		assert.Equal(t, 2*time.Second, intervals[i])
+	}
+
+	// A burst stops at the first flow that cannot be launched
+	remaining := 5
+	n := launchTick(flowsPerTick, 2*time.Second, func(time.Duration) bool {
+		remaining--
+		return remaining >= 0
+	})
+	assert.Equal(t, 5, n)
+
+	// Without burst mode, each tick launches a single flow
+	ticksPerSecond, flowsPerTick = flowPacing(&config.ClientConfig{Rate: 4})
+	s = newFlowScheduler(start, ticksPerSecond)
+	assert.Equal(t, start.Add(250*time.Millisecond), s.next())
+	assert.Equal(t, 1, launchTick(flowsPerTick, 250*time.Millisecond, func(time.Duration) bool { return true }))
+}
+
+func TestRateRamp(t *testing.T) {
+	start := time.Now()
+	r := &rateRamp{from: 10, to: 30, start: start, period: 10 * time.Second}
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		wantRate float64
+		wantDone bool
+	}{
+		{"at start", start, 10, false},
+		{"before start", start.Add(-time.Second), 10, false},
+		{"halfway", start.Add(5 * time.Second), 20, false},
+		{"at end", start.Add(10 * time.Second), 30, true},
+		{"after end", start.Add(time.Minute), 30, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, done := r.at(tt.at)
+			assert.InDelta(t, tt.wantRate, rate, 0.001)
+			assert.Equal(t, tt.wantDone, done)
+		})
+	}
+
+	// Ramping down works the same way
+	down := &rateRamp{from: 30, to: 10, start: start, period: 10 * time.Second}
+	rate, _ := down.at(start.Add(2500 * time.Millisecond))
+	assert.InDelta(t, 25, rate, 0.001)
+}
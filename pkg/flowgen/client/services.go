@@ -0,0 +1,140 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/transport"
+)
+
+// Services a port can be fingerprinted as. serviceUnknown accepts connections but answers neither as an
+// echo, HTTP nor TLS service.
+const (
+	serviceEcho    = "echo"
+	serviceHTTP    = "http"
+	serviceTLS     = "tls"
+	serviceNone    = "none"
+	serviceUnknown = "unknown"
+)
+
+// serviceCheck is the outcome of verifying the service expected on a port
+type serviceCheck struct {
+	Port     int    `json:"port"`
+	Expected string `json:"expected"`
+	Observed string `json:"observed"`
+	Match    bool   `json:"match"`
+	// Detail describes what was observed, such as the connect error or the first bytes of the answer
+	Detail string `json:"detail,omitempty"`
+}
+
+// verifyServices fingerprints every port of server the configuration declares a service for, in parallel,
+// and returns the checks sorted by port. It returns nil if no services are declared.
+func verifyServices(c *config.ClientConfig, server string) []serviceCheck {
+	// The ports were checked when the configuration was validated
+	expected, _ := config.ParsePortMap(c.ExpectServices)
+	if len(expected) == 0 {
+		return nil
+	}
+	timeout := defaultProbeTimeout
+	if c.ConnectTimeout > 0 {
+		timeout = seconds(c.ConnectTimeout)
+	}
+	checks := make([]serviceCheck, 0, len(expected))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for port, service := range expected {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			observed, detail := fingerprintService(context.Background(), server, port, timeout)
+			mu.Lock()
+			defer mu.Unlock()
+			checks = append(checks, serviceCheck{Port: port, Expected: service, Observed: observed, Match: observed == service, Detail: detail})
+		}()
+	}
+	wg.Wait()
+	sort.Slice(checks, func(i, j int) bool { return checks[i].Port < checks[j].Port })
+	return checks
+}
+
+// fingerprintService determines what answers on a TCP port. A completed TLS handshake identifies TLS, which
+// is tried first as TLS servers answer plain HTTP requests too. Otherwise an HTTP request is sent, which an
+// echo service returns unchanged and an HTTP server answers with a status line. A port refusing or timing
+// out connections runs no service.
+func fingerprintService(ctx context.Context, host string, port int, timeout time.Duration) (string, string) {
+	addr := constructAddress(host, port)
+	dialer := flowDialer("tcp", transport.FlowInfo{})
+	dialer.Timeout = timeout
+
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return serviceNone, err.Error()
+	}
+	// #nosec G402 - the handshake only identifies the service, the certificate does not matter
+	tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true, ServerName: host, MinVersion: tls.VersionTLS12})
+	_ = tlsConn.SetDeadline(time.Now().Add(timeout))
+	err = tlsConn.HandshakeContext(ctx)
+	_ = conn.Close()
+	if err == nil {
+		state := tlsConn.ConnectionState()
+		return serviceTLS, fmt.Sprintf("%s, ALPN %q", tls.VersionName(state.Version), state.NegotiatedProtocol)
+	}
+
+	conn, err = dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return serviceNone, err.Error()
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	probe := []byte("GET / HTTP/1.0\r\nHost: " + addr + "\r\nUser-Agent: flow-generator\r\n\r\n")
+	if _, err := conn.Write(probe); err != nil {
+		return serviceUnknown, err.Error()
+	}
+	answer := make([]byte, len(probe))
+	n, err := io.ReadFull(conn, answer)
+	answer = answer[:n]
+	switch {
+	case bytes.Equal(answer, probe):
+		return serviceEcho, ""
+	case bytes.HasPrefix(answer, []byte("HTTP/")):
+		status, _, _ := bytes.Cut(answer, []byte("\r\n"))
+		return serviceHTTP, string(status)
+	case n == 0:
+		return serviceUnknown, fmt.Sprintf("no answer: %v", err)
+	}
+	return serviceUnknown, "answered " + strconv.Quote(string(answer[:min(n, 32)]))
+}
+
+// logServices logs the outcome of the service checks
+func logServices(checks []serviceCheck) {
+	for _, c := range checks {
+		if c.Match {
+			logging.Logger.Infof("Port %d answers as %s as expected", c.Port, c.Expected)
+			continue
+		}
+		if c.Detail != "" {
+			logging.Logger.Errorf("Port %d was expected to answer as %s but answered as %s (%s)", c.Port, c.Expected, c.Observed, c.Detail)
+		} else {
+			logging.Logger.Errorf("Port %d was expected to answer as %s but answered as %s", c.Port, c.Expected, c.Observed)
+		}
+	}
+}
+
+// servicesExitCode returns the exit code of a run whose service checks mismatched
+func servicesExitCode(checks []serviceCheck) int {
+	for _, c := range checks {
+		if !c.Match {
+			return exitAssertionsFailed
+		}
+	}
+	return 0
+}
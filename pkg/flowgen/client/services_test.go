@@ -0,0 +1,83 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveTCP accepts connections on a local port and hands them to handle
+func serveTCP(t *testing.T, handle func(net.Conn)) int {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				handle(conn)
+			}()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// serverPort returns the port of a test server
+func serverPort(t *testing.T, srv *httptest.Server) int {
+	addr, err := net.ResolveTCPAddr("tcp", srv.Listener.Addr().String())
+	require.NoError(t, err)
+	return addr.Port
+}
+
+func TestVerifyServices(t *testing.T) {
+	echo := serveTCP(t, func(conn net.Conn) { _, _ = io.Copy(conn, conn) })
+	silent := serveTCP(t, func(conn net.Conn) {})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	secure := httptest.NewTLSServer(handler)
+	defer secure.Close()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closed := ln.Addr().(*net.TCPAddr).Port
+	require.NoError(t, ln.Close())
+
+	c := &config.ClientConfig{ExpectServices: fmt.Sprintf("%d=echo,%d=http,%d=echo,%d=none,%d=echo",
+		echo, serverPort(t, plain), serverPort(t, secure), closed, silent)}
+	checks := verifyServices(c, "127.0.0.1")
+	observed := make(map[int]serviceCheck)
+	for _, check := range checks {
+		observed[check.Port] = check
+	}
+	require.Len(t, observed, 5)
+
+	assert.Equal(t, serviceCheck{Port: echo, Expected: serviceEcho, Observed: serviceEcho, Match: true}, observed[echo])
+	assert.Equal(t, serviceHTTP, observed[serverPort(t, plain)].Observed)
+	assert.Equal(t, "HTTP/1.0 200 OK", observed[serverPort(t, plain)].Detail)
+	assert.True(t, observed[serverPort(t, plain)].Match)
+	assert.Equal(t, serviceNone, observed[closed].Observed)
+	assert.True(t, observed[closed].Match)
+	assert.Equal(t, serviceUnknown, observed[silent].Observed)
+	assert.False(t, observed[silent].Match)
+
+	// A TLS server where plain echo was expected
+	tls := observed[serverPort(t, secure)]
+	assert.Equal(t, serviceTLS, tls.Observed)
+	assert.False(t, tls.Match)
+	assert.Contains(t, tls.Detail, "TLS 1.3")
+	assert.Equal(t, exitAssertionsFailed, servicesExitCode(checks))
+
+	assert.Nil(t, verifyServices(&config.ClientConfig{}, "127.0.0.1"))
+	assert.Equal(t, 0, servicesExitCode(nil))
+}
@@ -0,0 +1,115 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/sink"
+)
+
+const (
+	// sinkRetryBackoff is the wait before the first retry of a failed delivery, it doubles with every retry
+	sinkRetryBackoff = time.Second
+	// sinkShipTimeout bounds shipping all artifacts of a run, including the retries
+	sinkShipTimeout = 2 * time.Minute
+)
+
+// resultsExtensions are the file extensions of the run results per output format
+var resultsExtensions = map[string]string{"json": ".json", "csv": ".csv", "junit": ".xml", "html": ".html"}
+
+// outputSinks ships the run results, the flow log and the artifact bundle to the output sinks when the
+// run ends
+type outputSinks struct {
+	fanout *sink.Fanout
+	format string
+	// resultsName is the name the results are shipped as, that of the output file if one is written
+	resultsName string
+	flowLog     string
+	bundle      string
+	// urlExpiry is how long the logged download URLs of the artifacts are valid, 0 logs none
+	urlExpiry time.Duration
+}
+
+// newOutputSinks returns the sinks configured with output_sink, or nil if artifacts are not shipped
+func newOutputSinks(c *config.ClientConfig) (*outputSinks, error) {
+	var destinations []string
+	for _, d := range strings.Split(c.OutputSinks, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			destinations = append(destinations, d)
+		}
+	}
+	if len(destinations) == 0 {
+		return nil, nil
+	}
+	fanout, err := sink.NewFanout(destinations, c.OutputSinkRetries, sinkRetryBackoff)
+	if err != nil {
+		return nil, err
+	}
+	s := &outputSinks{fanout: fanout, format: c.OutputFormat, resultsName: "results" + resultsExtensions[c.OutputFormat], bundle: c.ArtifactBundle,
+		urlExpiry: time.Duration(c.OutputSinkURLExpiry * float64(time.Second))}
+	if c.OutputFile != "" {
+		s.resultsName = filepath.Base(c.OutputFile)
+	}
+	// Flows streamed to stdout cannot be read back, so they are not shipped
+	if c.FlowLogFile != flowLogStdout {
+		s.flowLog = c.FlowLogFile
+	}
+	return s, nil
+}
+
+// String lists the sinks for the startup log
+func (s *outputSinks) String() string {
+	return s.fanout.String()
+}
+
+// ship delivers the artifacts of the finished run to every sink. It must be called after the flow log is
+// closed and the bundle is written.
+func (s *outputSinks) ship(results runResults) {
+	ctx, cancel := context.WithTimeout(context.Background(), sinkShipTimeout)
+	defer cancel()
+
+	var buf bytes.Buffer
+	if err := encodeResults(&buf, s.format, results); err != nil {
+		logging.Logger.Errorf("Failed to encode run results for the output sinks: %v", err)
+	} else {
+		s.deliver(ctx, s.resultsName, buf.Bytes())
+	}
+	for _, path := range []string{s.flowLog, s.bundle} {
+		if path == "" || !fileExists(path) {
+			continue
+		}
+		// #nosec G304 - the artifact paths are chosen by the operator
+		data, err := os.ReadFile(path)
+		if err != nil {
+			logging.Logger.Errorf("Failed to read %s for the output sinks: %v", path, err)
+			continue
+		}
+		s.deliver(ctx, filepath.Base(path), data)
+	}
+}
+
+// deliver writes a single artifact to the sinks and logs the outcome along with the URLs the artifact can
+// be downloaded from
+func (s *outputSinks) deliver(ctx context.Context, name string, data []byte) {
+	if err := s.fanout.Write(ctx, name, data); err != nil {
+		logging.Logger.Errorf("Failed to ship %s: %v", name, err)
+		return
+	}
+	logging.Logger.Infof("Shipped %s to %s", name, s.fanout)
+	if s.urlExpiry == 0 {
+		return
+	}
+	urls, err := s.fanout.URLs(ctx, name, s.urlExpiry)
+	if err != nil {
+		logging.Logger.Warnf("Failed to sign the download URL of %s: %v", name, err)
+	}
+	for _, u := range urls {
+		logging.Logger.Infof("Download %s until %s: %s", name, time.Now().Add(s.urlExpiry).Format(time.RFC3339), u)
+	}
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOutputSinks(t *testing.T) {
+	s, err := newOutputSinks(&config.ClientConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, s)
+
+	s, err = newOutputSinks(&config.ClientConfig{OutputSinks: "/tmp/a, s3://bucket/runs", OutputFormat: "junit", FlowLogFile: flowLogStdout, OutputSinkURLExpiry: 3600})
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/a, s3://bucket/runs", s.String())
+	assert.Equal(t, "results.xml", s.resultsName)
+	assert.Empty(t, s.flowLog, "flows streamed to stdout are not shipped")
+	assert.Equal(t, time.Hour, s.urlExpiry)
+
+	s, err = newOutputSinks(&config.ClientConfig{OutputSinks: "/tmp/a", OutputFile: "/var/out/nightly.json", OutputFormat: "json"})
+	require.NoError(t, err)
+	assert.Equal(t, "nightly.json", s.resultsName)
+
+	_, err = newOutputSinks(&config.ClientConfig{OutputSinks: "ftp://host"})
+	assert.Error(t, err)
+}
+
+func TestOutputSinksShip(t *testing.T) {
+	logging.InitLogger("json", "error")
+	dir, out := t.TempDir(), t.TempDir()
+	flowLog := filepath.Join(dir, "flows.ndjson")
+	require.NoError(t, os.WriteFile(flowLog, []byte(`{"flow_id":1}`+"\n"), 0o600))
+
+	s, err := newOutputSinks(&config.ClientConfig{OutputSinks: out, OutputFormat: "json", FlowLogFile: flowLog,
+		ArtifactBundle: filepath.Join(dir, "missing.tgz")})
+	require.NoError(t, err)
+	s.ship(testReportResults(phaseCompleted))
+
+	data, err := os.ReadFile(filepath.Join(out, "results.json"))
+	require.NoError(t, err)
+	var results runResults
+	require.NoError(t, json.Unmarshal(data, &results))
+	assert.Equal(t, "nightly", results.Run.Scenario)
+
+	data, err = os.ReadFile(filepath.Join(out, "flows.ndjson"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"flow_id":1}`+"\n", string(data))
+	assert.NoFileExists(t, filepath.Join(out, "missing.tgz"), "artifacts that were not written are skipped")
+}
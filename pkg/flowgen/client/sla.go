@@ -0,0 +1,71 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+)
+
+// exitAssertionsFailed is the exit code of a run that violated an SLA assertion, so CI jobs can tell it
+// apart from a run that could not start (1)
+const exitAssertionsFailed = 2
+
+// assertionsEnabled reports whether any SLA assertion is configured
+func assertionsEnabled(c *config.ClientConfig) bool {
+	return c.MaxErrorRate < 100 || c.MaxP99Latency > 0 || c.MinThroughput > 0
+}
+
+// checkAssertions evaluates the SLA assertions against the results of the run and the finished flows, and
+// returns a description of every violated one
+func checkAssertions(c *config.ClientConfig, r runResults, flows flowOutcomeCounts) []string {
+	var violations []string
+	if c.MaxErrorRate < 100 {
+		finished := flows.Completed + flows.Failed
+		if finished == 0 {
+			violations = append(violations, "error rate: no flows finished")
+		} else if rate := float64(flows.Failed) / float64(finished) * 100; rate > c.MaxErrorRate {
+			violations = append(violations, fmt.Sprintf("error rate %.2f%% (%d of %d flows failed) exceeds max_error_rate %g%%", rate, flows.Failed, finished, c.MaxErrorRate))
+		}
+	}
+	if c.MaxP99Latency > 0 {
+		if len(r.Metrics.Latency) == 0 {
+			violations = append(violations, "p99 latency: no responses received")
+		}
+		for _, protocol := range sortedKeys(r.Metrics.Latency) {
+			if p99 := r.Metrics.Latency[protocol].P99Ms; p99 > c.MaxP99Latency {
+				violations = append(violations, fmt.Sprintf("%s p99 latency %.2fms exceeds max_p99_latency %gms", protocol, p99, c.MaxP99Latency))
+			}
+		}
+	}
+	if c.MinThroughput > 0 {
+		var received uint64
+		for _, ports := range r.Metrics.BytesReceived {
+			for _, n := range ports {
+				received += n
+			}
+		}
+		// Bytes echoed during the warmup are not counted, so neither is its time
+		var mbps float64
+		if measured := r.Run.ElapsedSeconds - r.Run.WarmupSeconds; measured > 0 {
+			mbps = float64(received) * 8 / measured / 1e6
+		}
+		if mbps < c.MinThroughput {
+			violations = append(violations, fmt.Sprintf("throughput %.3f Mbit/s is below min_throughput %g Mbit/s", mbps, c.MinThroughput))
+		}
+	}
+	return violations
+}
+
+// reportAssertions logs the outcome of the SLA assertions and returns the exit code of the run
+func reportAssertions(violations []string) int {
+	if len(violations) == 0 {
+		logging.Logger.Info("All SLA assertions passed")
+		return 0
+	}
+	for _, v := range violations {
+		logging.Logger.Errorf("SLA assertion failed: %s", v)
+	}
+	logging.Logger.Errorf("%d SLA assertion(s) failed", len(violations))
+	return exitAssertionsFailed
+}
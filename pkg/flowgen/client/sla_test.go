@@ -0,0 +1,89 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssertionsEnabled(t *testing.T) {
+	assert.False(t, assertionsEnabled(&config.ClientConfig{MaxErrorRate: 100}))
+	assert.True(t, assertionsEnabled(&config.ClientConfig{MaxErrorRate: 0}))
+	assert.True(t, assertionsEnabled(&config.ClientConfig{MaxErrorRate: 100, MaxP99Latency: 10}))
+	assert.True(t, assertionsEnabled(&config.ClientConfig{MaxErrorRate: 100, MinThroughput: 1}))
+}
+
+func TestCheckAssertions(t *testing.T) {
+	// 10 seconds with 2.5 MB echoed back are 2 Mbit/s
+	results := runResults{
+		Run: runStatus{ElapsedSeconds: 10},
+		Metrics: metrics.Summary{
+			BytesReceived: map[string]map[string]uint64{"tcp": {"8080": 2_000_000}, "udp": {"53": 500_000}},
+			Latency:       map[string]metrics.LatencySummary{"tcp": {P99Ms: 12}, "udp": {P99Ms: 3}},
+		},
+	}
+	flows := flowOutcomeCounts{Completed: 95, Failed: 5}
+
+	tests := []struct {
+		name    string
+		cfg     config.ClientConfig
+		results runResults
+		flows   flowOutcomeCounts
+		want    []string
+	}{
+		{
+			name: "disabled",
+			cfg:  config.ClientConfig{MaxErrorRate: 100},
+		},
+		{
+			name:    "all met",
+			cfg:     config.ClientConfig{MaxErrorRate: 5, MaxP99Latency: 15, MinThroughput: 2},
+			results: results,
+			flows:   flows,
+		},
+		{
+			name: "warmup excluded from throughput",
+			cfg:  config.ClientConfig{MaxErrorRate: 100, MinThroughput: 4},
+			results: runResults{
+				Run:     runStatus{ElapsedSeconds: 10, WarmupSeconds: 5},
+				Metrics: results.Metrics,
+			},
+		},
+		{
+			name:    "all violated",
+			cfg:     config.ClientConfig{MaxErrorRate: 1, MaxP99Latency: 2.5, MinThroughput: 10},
+			results: results,
+			flows:   flows,
+			want: []string{
+				"error rate 5.00% (5 of 100 flows failed) exceeds max_error_rate 1%",
+				"tcp p99 latency 12.00ms exceeds max_p99_latency 2.5ms",
+				"udp p99 latency 3.00ms exceeds max_p99_latency 2.5ms",
+				"throughput 2.000 Mbit/s is below min_throughput 10 Mbit/s",
+			},
+		},
+		{
+			name:    "no flows finished",
+			cfg:     config.ClientConfig{MaxErrorRate: 0, MaxP99Latency: 10, MinThroughput: 1},
+			results: runResults{},
+			want: []string{
+				"error rate: no flows finished",
+				"p99 latency: no responses received",
+				"throughput 0.000 Mbit/s is below min_throughput 1 Mbit/s",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, checkAssertions(&tt.cfg, tt.results, tt.flows))
+		})
+	}
+}
+
+func TestReportAssertions(t *testing.T) {
+	logging.InitLogger("json", "fatal")
+	assert.Equal(t, 0, reportAssertions(nil))
+	assert.Equal(t, exitAssertionsFailed, reportAssertions([]string{"error rate 5.00% exceeds max_error_rate 1%"}))
+}
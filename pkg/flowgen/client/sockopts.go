@@ -0,0 +1,99 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/transport"
+)
+
+// controlFunc is a net.Dialer control function
+type controlFunc func(network, address string, c syscall.RawConn) error
+
+// flowControls returns the control functions applying the DSCP marking and TTL of a flow to its socket
+func flowControls(flow transport.FlowInfo) []controlFunc {
+	var controls []controlFunc
+	if flow.DSCP != 0 {
+		controls = append(controls, dscpControl(flow.DSCP))
+	}
+	if flow.TTL != 0 {
+		controls = append(controls, ttlControl(flow.TTL))
+	}
+	return controls
+}
+
+// flowDialer returns the dialer for a flow, bound to the local address and interface of the client or the
+// source of the flow, and applying its DSCP marking, TTL and flow label and the socket buffer sizes
+func flowDialer(network string, flow transport.FlowInfo) *net.Dialer {
+	d := egress.dialer(network)
+	var controls []controlFunc
+	if d.Control != nil {
+		controls = append(controls, d.Control)
+	}
+	if flow.Source.IsValid() {
+		source := sourceDialer(network, flow.Source)
+		d.LocalAddr = source.LocalAddr
+		controls = append(controls, source.Control)
+	}
+	if flow.SourcePort != 0 {
+		d.LocalAddr = withSourcePort(network, d.LocalAddr, flow.SourcePort)
+		controls = append(controls, reuseSourcePortControl)
+	}
+	if flow.SourceInterface != "" {
+		controls = append(controls, bindToDeviceControl(flow.SourceInterface))
+	}
+	controls = append(controls, flowControls(flow)...)
+	// ICMP errors of UDP flows are read from the error queue, TCP reports them as connect errors
+	if strings.HasPrefix(network, "udp") {
+		controls = append(controls, icmpErrorControl)
+	}
+	// UDP sockets get the flow label once dialed, see applyFlowLabel
+	if flow.FlowLabel != 0 && strings.HasPrefix(network, "tcp") {
+		controls = append(controls, flowLabelControl(flow.FlowLabel))
+	}
+	switch len(controls) {
+	case 0:
+		d.Control = nil
+	case 1:
+		d.Control = controls[0]
+	default:
+		d.Control = func(network, address string, c syscall.RawConn) error {
+			for _, control := range controls {
+				if err := control(network, address, c); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return tuning.dialer(d)
+}
+
+// applyFlowOptions applies the DSCP marking and TTL of a flow to an established connection, for connections
+// that were not dialed for the flow itself such as pooled and relayed connections
+func applyFlowOptions(conn net.Conn, flow transport.FlowInfo) error {
+	controls := flowControls(flow)
+	if len(controls) == 0 {
+		return nil
+	}
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return fmt.Errorf("failed to apply socket options: connection does not expose its socket")
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+	network := "tcp4"
+	if isIPv6(conn.LocalAddr()) {
+		network = "tcp6"
+	}
+	for _, control := range controls {
+		if err := control(network, conn.RemoteAddr().String(), raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/transport"
+)
+
+func TestFlowDialer(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" && runtime.GOOS != "freebsd" {
+		t.Skip("IP_TOS and IP_TTL not supported on this platform")
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	flow := transport.FlowInfo{DSCP: 46, TTL: 3}
+	if runtime.GOOS == "linux" {
+		flow.Source = netip.MustParseAddr("127.0.0.2")
+	}
+	conn, err := flowDialer("tcp", flow).DialContext(context.Background(), "tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	if flow.Source.IsValid() {
+		assert.Equal(t, "127.0.0.2", conn.LocalAddr().(*net.TCPAddr).IP.String())
+	}
+
+	// Established connections can be marked as well
+	assert.NoError(t, applyFlowOptions(conn, transport.FlowInfo{DSCP: 10, TTL: 64}))
+
+	udp, err := flowDialer("udp", transport.FlowInfo{DSCP: 46}).DialContext(context.Background(), "udp", "127.0.0.1:9")
+	require.NoError(t, err)
+	_ = udp.Close()
+
+	assert.NoError(t, applyFlowOptions(conn, transport.FlowInfo{}))
+	assert.Nil(t, flowDialer("tcp", transport.FlowInfo{}).Control)
+}
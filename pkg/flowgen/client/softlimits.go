@@ -0,0 +1,298 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+)
+
+const (
+	// softLimitCheckInterval is how often the soft limits are checked against the window before it
+	softLimitCheckInterval = time.Second
+	// softLimitMinFlows is the number of flows that must have finished within the window before its error
+	// rate is checked, so a single early failure does not cross the limit
+	softLimitMinFlows = 5
+	// softLimitLatencySamples bounds the round trips kept per second of the window for its p99 latency
+	softLimitLatencySamples = 1000
+	// softLimitWebhookTimeout bounds a notification posted to the warn webhook
+	softLimitWebhookTimeout = 5 * time.Second
+)
+
+// Soft limits, named like the assertions they are given with
+const (
+	limitErrorRate  = "max_error_rate"
+	limitP99Latency = "max_p99_latency"
+	limitThroughput = "min_throughput"
+)
+
+// warningMarker records a soft limit crossed during a run, its end is missing while the limit is crossed
+type warningMarker struct {
+	Limit     string  `json:"limit"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	// Message describes the crossing, as it was logged
+	Message      string   `json:"message"`
+	StartSeconds float64  `json:"start_seconds"`
+	EndSeconds   *float64 `json:"end_seconds,omitempty"`
+}
+
+// String describes the marker for reports, e.g. "max_error_rate at 42.0s-57.0s: error rate 3.10% ..."
+func (m warningMarker) String() string {
+	if m.EndSeconds == nil {
+		return fmt.Sprintf("%s at %.1fs-: %s", m.Limit, m.StartSeconds, m.Message)
+	}
+	return fmt.Sprintf("%s at %.1fs-%.1fs: %s", m.Limit, m.StartSeconds, *m.EndSeconds, m.Message)
+}
+
+// softLimitSecond holds what was measured within one second of the window
+type softLimitSecond struct {
+	second int64
+	flowStats
+}
+
+// softLimitNotification is the JSON body posted to the warn webhook. Text carries the message for chat
+// webhooks, such as those of Slack, which only show that field.
+type softLimitNotification struct {
+	Text           string  `json:"text"`
+	Event          string  `json:"event"`
+	Scenario       string  `json:"scenario"`
+	Limit          string  `json:"limit"`
+	Value          float64 `json:"value"`
+	Threshold      float64 `json:"threshold"`
+	WindowSeconds  float64 `json:"window_seconds"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+}
+
+// softLimits checks the thresholds configured with warn_thresholds against the flows finished, the round
+// trips measured and the bytes echoed within a sliding window while the run is in progress. Crossing a
+// limit and getting back within it are logged, posted to the warn webhook and marked in the report, the
+// run goes on either way.
+type softLimits struct {
+	windowedStats
+	thresholds config.WarnThresholds
+	window     time.Duration
+	start      time.Time
+	scenario   string
+	// seconds is a ring of the seconds of the window, indexed by the Unix second modulo its length
+	seconds []softLimitSecond
+	// crossed holds the index of the open marker of every limit currently crossed
+	crossed map[string]int
+	markers []warningMarker
+
+	webhook  string
+	client   *http.Client
+	inflight sync.WaitGroup
+	// posted is closed once the last notification was posted, the next one waits for it to keep their order
+	posted chan struct{}
+}
+
+// newSoftLimits returns the soft limits configured with warn_thresholds checked from the given time, or nil
+// if none are configured
+func newSoftLimits(c *config.ClientConfig, start time.Time) *softLimits {
+	if c.WarnThresholds == "" {
+		return nil
+	}
+	// The thresholds were checked when the configuration was validated
+	thresholds, _ := config.ParseWarnThresholds(c.WarnThresholds)
+	window := seconds(c.WarnWindow)
+	l := &softLimits{
+		thresholds: thresholds,
+		window:     window,
+		start:      start,
+		scenario:   c.Scenario,
+		seconds:    make([]softLimitSecond, int(math.Ceil(window.Seconds()))+1),
+		crossed:    make(map[string]int),
+		webhook:    c.WarnWebhook,
+		client:     &http.Client{Timeout: softLimitWebhookTimeout},
+	}
+	l.statsAt = l.at
+	for i := range l.seconds {
+		l.seconds[i].flowStats = newFlowStats(softLimitLatencySamples)
+	}
+	return l
+}
+
+// String describes the thresholds for the startup log
+func (l *softLimits) String() string {
+	var limits []string
+	if l.thresholds.MaxErrorRate < 100 {
+		limits = append(limits, fmt.Sprintf("error rate above %g%%", l.thresholds.MaxErrorRate))
+	}
+	if l.thresholds.MaxP99Latency > 0 {
+		limits = append(limits, fmt.Sprintf("p99 latency above %gms", l.thresholds.MaxP99Latency))
+	}
+	if l.thresholds.MinThroughput > 0 {
+		limits = append(limits, fmt.Sprintf("throughput below %g Mbit/s", l.thresholds.MinThroughput))
+	}
+	return fmt.Sprintf("%s within %v", strings.Join(limits, ", "), l.window)
+}
+
+// at returns the second of the window the given time falls into, cleared if it was last used for an
+// earlier second, or nil if the time is before the start or the window moved past it. The caller holds
+// the lock.
+func (l *softLimits) at(t time.Time) *flowStats {
+	if t.Before(l.start) {
+		return nil
+	}
+	second := t.Unix()
+	s := &l.seconds[second%int64(len(l.seconds))]
+	if s.second > second {
+		// Events reported after the window moved past them are left out
+		return nil
+	}
+	if s.second != second {
+		s.second = second
+		s.reset()
+	}
+	return &s.flowStats
+}
+
+// run checks the limits every second until ctx is done
+func (l *softLimits) run(ctx context.Context) {
+	ticker := time.NewTicker(softLimitCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			l.check(now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// check evaluates the limits against the window ending at the given time, and warns about every limit
+// crossed or gotten back within since the last check
+func (l *softLimits) check(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// The window covers the whole seconds before the current one, which is still being measured
+	var completed, failed, received uint64
+	var measured []*flowStats
+	last := now.Unix() - 1
+	first := last - int64(len(l.seconds)) + 2
+	for i := range l.seconds {
+		s := &l.seconds[i]
+		if s.second < first || s.second > last {
+			continue
+		}
+		completed += s.completed
+		failed += s.failed
+		received += s.received
+		measured = append(measured, &s.flowStats)
+	}
+	elapsed := now.Sub(l.start)
+	window := fmt.Sprintf("over the last %v", l.window)
+
+	if l.thresholds.MaxErrorRate < 100 {
+		if finished := completed + failed; finished >= softLimitMinFlows {
+			rate := float64(failed) / float64(finished) * 100
+			l.update(now, limitErrorRate, rate > l.thresholds.MaxErrorRate, rate, l.thresholds.MaxErrorRate,
+				fmt.Sprintf("error rate %.2f%% (%d of %d flows failed) %s exceeds %g%%", rate, failed, finished, window, l.thresholds.MaxErrorRate))
+		}
+	}
+	if latency, ok := p99Latency(measured...); l.thresholds.MaxP99Latency > 0 && ok {
+		p99 := float64(latency) / float64(time.Millisecond)
+		l.update(now, limitP99Latency, p99 > l.thresholds.MaxP99Latency, p99, l.thresholds.MaxP99Latency,
+			fmt.Sprintf("p99 latency %.2fms %s exceeds %gms", p99, window, l.thresholds.MaxP99Latency))
+	}
+	// Throughput is only checked once a whole window was measured, so the ramp-up does not cross it
+	if l.thresholds.MinThroughput > 0 && elapsed >= l.window+softLimitCheckInterval {
+		mbps := float64(received) * 8 / float64(len(l.seconds)-1) / 1e6
+		l.update(now, limitThroughput, mbps < l.thresholds.MinThroughput, mbps, l.thresholds.MinThroughput,
+			fmt.Sprintf("throughput %.3f Mbit/s %s is below %g Mbit/s", mbps, window, l.thresholds.MinThroughput))
+	}
+}
+
+// update records whether a limit is crossed at the given time, warning when it was just crossed and when
+// the run got back within it. The caller holds the lock.
+func (l *softLimits) update(now time.Time, limit string, crossed bool, value, threshold float64, message string) {
+	elapsed := now.Sub(l.start).Seconds()
+	i, wasCrossed := l.crossed[limit]
+	switch {
+	case crossed && !wasCrossed:
+		l.crossed[limit] = len(l.markers)
+		l.markers = append(l.markers, warningMarker{Limit: limit, Value: value, Threshold: threshold, Message: message, StartSeconds: elapsed})
+		mc.IncSoftLimitWarnings(limit)
+		logging.Logger.Warnf("SOFT LIMIT CROSSED: %s (the run continues)", message)
+		l.notify("crossed", limit, value, threshold, elapsed, "Soft limit crossed: "+message)
+	case crossed:
+		// The marker keeps the worst value seen while the limit is crossed
+		if m := &l.markers[i]; (limit == limitThroughput) == (value < m.Value) {
+			m.Value, m.Message = value, message
+		}
+	case wasCrossed:
+		delete(l.crossed, limit)
+		l.markers[i].EndSeconds = &elapsed
+		logging.Logger.Infof("Soft limit %s recovered: back within %g", limit, threshold)
+		l.notify("recovered", limit, value, threshold, elapsed, fmt.Sprintf("Soft limit %s recovered: back within %g", limit, threshold))
+	}
+}
+
+// notify posts an event to the warn webhook in the background after the events before it, if a webhook is
+// configured. The caller holds the lock.
+func (l *softLimits) notify(event, limit string, value, threshold, elapsed float64, text string) {
+	if l.webhook == "" {
+		return
+	}
+	body, err := json.Marshal(softLimitNotification{
+		Text:           text,
+		Event:          event,
+		Scenario:       l.scenario,
+		Limit:          limit,
+		Value:          value,
+		Threshold:      threshold,
+		WindowSeconds:  l.window.Seconds(),
+		ElapsedSeconds: elapsed,
+	})
+	if err != nil {
+		logging.Logger.Warnf("Failed to encode soft limit notification: %v", err)
+		return
+	}
+	previous, posted := l.posted, make(chan struct{})
+	l.posted = posted
+	l.inflight.Add(1)
+	go func() {
+		defer l.inflight.Done()
+		defer close(posted)
+		if previous != nil {
+			<-previous
+		}
+		resp, err := l.client.Post(l.webhook, "application/json", bytes.NewReader(body))
+		if err != nil {
+			logging.Logger.Warnf("Failed to post soft limit notification to %s: %v", l.webhook, err)
+			return
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			logging.Logger.Warnf("Soft limit notification to %s returned %s", l.webhook, resp.Status)
+		}
+	}()
+}
+
+// finish waits for the notifications still being posted, each of which is bounded by the webhook timeout
+func (l *softLimits) finish() {
+	if l == nil {
+		return
+	}
+	l.inflight.Wait()
+}
+
+// status returns the markers of the limits crossed so far, nil if no soft limits are configured
+func (l *softLimits) status() []warningMarker {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]warningMarker(nil), l.markers...)
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoftLimits(t *testing.T) {
+	logging.InitLogger("json", "error")
+	oldMc := mc
+	mc = metrics.NewMetricsCollector()
+	defer func() { mc = oldMc }()
+
+	assert.Nil(t, newSoftLimits(&config.ClientConfig{}, time.Now()))
+
+	start := time.Unix(1700000000, 0)
+	l := newSoftLimits(&config.ClientConfig{WarnThresholds: "max_error_rate=10,max_p99_latency=100,min_throughput=0.001", WarnWindow: 5}, start)
+	require.NotNil(t, l)
+	assert.Equal(t, "error rate above 10%, p99 latency above 100ms, throughput below 0.001 Mbit/s within 5s", l.String())
+	clock := func(s float64) time.Time { return start.Add(seconds(s)) }
+
+	// 2 of 10 flows fail within the first seconds and round trips are slow
+	for i := 0; i < 10; i++ {
+		l.observeFlow(clock(0.5+float64(i)/10), i < 2)
+		l.observeLatency(clock(0.5), 150*time.Millisecond)
+		l.addBytesReceived(clock(0.5), 1000)
+	}
+	l.check(clock(2))
+	status := l.status()
+	require.Len(t, status, 2)
+	assert.Equal(t, limitErrorRate, status[0].Limit)
+	assert.Equal(t, float64(20), status[0].Value)
+	assert.Equal(t, 2.0, status[0].StartSeconds)
+	assert.Nil(t, status[0].EndSeconds)
+	assert.Equal(t, limitP99Latency, status[1].Limit)
+	assert.Contains(t, status[1].Message, "p99 latency 150.00ms over the last 5s exceeds 100ms")
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.SoftLimitWarnings.WithLabelValues(limitErrorRate)))
+
+	// Staying across the limit does not warn again
+	l.check(clock(3))
+	assert.Len(t, l.status(), 2)
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.SoftLimitWarnings.WithLabelValues(limitErrorRate)))
+
+	// Once the bad second left the window, fast and successful flows bring the run back within the limits,
+	// while the throughput, checked once a whole window was measured, is too low
+	for i := 0; i < 10; i++ {
+		l.observeFlow(clock(6.5), false)
+		l.observeLatency(clock(6.5), 10*time.Millisecond)
+	}
+	l.check(clock(7))
+	status = l.status()
+	require.Len(t, status, 3)
+	require.NotNil(t, status[0].EndSeconds)
+	assert.Equal(t, 7.0, *status[0].EndSeconds)
+	require.NotNil(t, status[1].EndSeconds)
+	assert.Equal(t, limitThroughput, status[2].Limit)
+	assert.Equal(t, float64(0), status[2].Value)
+
+	// Events reported after the window moved past their second are left out
+	l.observeFlow(clock(0.5), true)
+	assert.Zero(t, l.seconds[start.Unix()%int64(len(l.seconds))].failed)
+}
+
+func TestSoftLimitsWebhook(t *testing.T) {
+	logging.InitLogger("json", "error")
+	oldMc := mc
+	mc = metrics.NewMetricsCollector()
+	defer func() { mc = oldMc }()
+
+	notifications := make(chan softLimitNotification, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n softLimitNotification
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&n))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		notifications <- n
+	}))
+	defer server.Close()
+
+	start := time.Unix(1700000000, 0)
+	l := newSoftLimits(&config.ClientConfig{Scenario: "soak", WarnThresholds: "max_error_rate=10", WarnWindow: 2, WarnWebhook: server.URL}, start)
+	for i := 0; i < 5; i++ {
+		l.observeFlow(start.Add(500*time.Millisecond), true)
+	}
+	l.check(start.Add(time.Second))
+	for i := 0; i < 5; i++ {
+		l.observeFlow(start.Add(3500*time.Millisecond), false)
+	}
+	l.check(start.Add(4 * time.Second))
+	l.finish()
+
+	require.Len(t, notifications, 2)
+	crossed := <-notifications
+	assert.Equal(t, "crossed", crossed.Event)
+	assert.Equal(t, "soak", crossed.Scenario)
+	assert.Equal(t, limitErrorRate, crossed.Limit)
+	assert.Equal(t, float64(100), crossed.Value)
+	assert.Equal(t, float64(10), crossed.Threshold)
+	assert.Equal(t, float64(2), crossed.WindowSeconds)
+	assert.Contains(t, crossed.Text, "Soft limit crossed: error rate 100.00% (5 of 5 flows failed) over the last 2s exceeds 10%")
+	recovered := <-notifications
+	assert.Equal(t, "recovered", recovered.Event)
+	assert.Equal(t, float64(4), recovered.ElapsedSeconds)
+}
@@ -0,0 +1,179 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
+)
+
+// Strategies selecting the source of each flow from the source pool
+const (
+	// sourceRoundRobin takes the sources in turn
+	sourceRoundRobin = "round-robin"
+	// sourceRandom takes a random source for every flow
+	sourceRandom = "random"
+	// sourceHash hashes the target of a flow, so all flows to a target come from the same source
+	sourceHash = "hash"
+)
+
+// flowSource is where a flow is sent from, a source address or a network interface the flow is bound to
+type flowSource struct {
+	addr  netip.Addr
+	iface string
+}
+
+// String returns the address or interface name of the source
+func (s flowSource) String() string {
+	if s.iface != "" {
+		return s.iface
+	}
+	return s.addr.String()
+}
+
+// sourcePool hands out the sources of flows, either the addresses of a CIDR range or a list of addresses
+// and interfaces, to emulate the address pool of a source NAT
+type sourcePool struct {
+	mu       sync.Mutex
+	strategy string
+	prefix   netip.Prefix
+	first    netip.Addr
+	list     []flowSource
+	size     uint64
+	next     uint64
+}
+
+// newFlowSources creates the source pool configured with source_cidr or source_addresses, or returns nil
+// if flows are sent from the default source address
+func newFlowSources(c *config.ClientConfig) (*sourcePool, error) {
+	var p *sourcePool
+	switch {
+	case c.SourceCIDR != "":
+		pool, err := newSourcePool(c.SourceCIDR)
+		if err != nil {
+			return nil, err
+		}
+		p = pool
+	case c.SourceAddresses != "":
+		sources, err := config.ParseSources(c.SourceAddresses)
+		if err != nil {
+			return nil, err
+		}
+		p = &sourcePool{strategy: sourceRoundRobin, size: uint64(len(sources))}
+		for _, s := range sources {
+			if s.Interface != "" {
+				if _, err := net.InterfaceByName(s.Interface); err != nil {
+					return nil, fmt.Errorf("unknown interface %q: %w", s.Interface, err)
+				}
+			}
+			p.list = append(p.list, flowSource{addr: s.Addr, iface: s.Interface})
+		}
+	default:
+		return nil, nil
+	}
+	if c.SourceStrategy != "" {
+		p.strategy = c.SourceStrategy
+	}
+	return p, nil
+}
+
+// newSourcePool creates a pool rotating over the addresses of cidr. The network and broadcast addresses
+// of IPv4 ranges and the subnet-router anycast address of IPv6 ranges are skipped if the range has room.
+func newSourcePool(cidr string) (*sourcePool, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source CIDR %q: %w", cidr, err)
+	}
+	prefix = prefix.Masked()
+
+	// Ranges beyond 2^63 addresses are only partially used, which is still far more than can be rotated through
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	size := uint64(1) << min(hostBits, 63)
+	first := prefix.Addr()
+	switch {
+	case prefix.Addr().Is4() && size > 2:
+		first, size = first.Next(), size-2
+	case prefix.Addr().Is6() && size > 2:
+		first, size = first.Next(), size-1
+	}
+	return &sourcePool{strategy: sourceRoundRobin, prefix: prefix, first: first, size: size}, nil
+}
+
+// pick selects the source of a flow to target with the strategy of the pool. src is the random source
+// of the flow's goroutine.
+func (p *sourcePool) pick(target string, src *rand.Rand) flowSource {
+	var i uint64
+	switch p.strategy {
+	case sourceRandom:
+		i = src.Uint64N(p.size)
+	case sourceHash:
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(target))
+		i = h.Sum64() % p.size
+	default:
+		p.mu.Lock()
+		i = p.next
+		p.next = (p.next + 1) % p.size
+		p.mu.Unlock()
+	}
+	if p.list != nil {
+		return p.list[i]
+	}
+	return flowSource{addr: addOffset(p.first, i)}
+}
+
+// String describes the sources of the pool for the logs
+func (p *sourcePool) String() string {
+	if p.list == nil {
+		return fmt.Sprintf("%s (%d addresses)", p.prefix, p.size)
+	}
+	names := make([]string, 0, len(p.list))
+	for _, s := range p.list {
+		names = append(names, s.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// addOffset returns the address offset addresses after addr
+func addOffset(addr netip.Addr, offset uint64) netip.Addr {
+	b := addr.As16()
+	for i := 15; i >= 0 && offset > 0; i-- {
+		sum := uint64(b[i]) + offset&0xff
+		b[i] = byte(sum)
+		offset = offset>>8 + sum>>8
+	}
+	result := netip.AddrFrom16(b)
+	if addr.Is4() {
+		return result.Unmap()
+	}
+	return result
+}
+
+// sourceDialer returns a dialer for network ("tcp" or "udp") bound to the given source address, or a plain
+// dialer if source is the zero address. Binding uses IP_FREEBIND where available, so the addresses need
+// not be configured locally.
+func sourceDialer(network string, source netip.Addr) *net.Dialer {
+	d := &net.Dialer{}
+	if !source.IsValid() {
+		return d
+	}
+	d.LocalAddr = bindAddr(network, source)
+	d.Control = freeBindControl
+	return d
+}
+
+// freeBindControl enables free binding where the platform supports it
+func freeBindControl(network, address string, c syscall.RawConn) error {
+	if err := sockopt.FreeBind(network, address, c); err != nil && !errors.Is(err, sockopt.ErrUnsupported) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"runtime"
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourcePoolRotation(t *testing.T) {
+	tests := []struct {
+		cidr string
+		want []string
+	}{
+		{"10.0.0.0/30", []string{"10.0.0.1", "10.0.0.2", "10.0.0.1"}},
+		{"10.0.0.5/24", []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+		{"10.0.0.0/31", []string{"10.0.0.0", "10.0.0.1", "10.0.0.0"}},
+		{"10.0.0.7/32", []string{"10.0.0.7", "10.0.0.7"}},
+		{"10.0.0.0/8", []string{"10.0.0.1", "10.0.0.2"}},
+		{"fd00::/126", []string{"fd00::1", "fd00::2", "fd00::3", "fd00::1"}},
+		{"fd00::/64", []string{"fd00::1", "fd00::2"}},
+		{"fd00::1/128", []string{"fd00::1", "fd00::1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cidr, func(t *testing.T) {
+			p, err := newSourcePool(tt.cidr)
+			require.NoError(t, err)
+			var got []string
+			for range tt.want {
+				got = append(got, p.pick("", nil).addr.String())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := newSourcePool("10.0.0.0/33")
+	assert.Error(t, err)
+}
+
+func TestSourcePoolStrategies(t *testing.T) {
+	src := rand.New(rand.NewPCG(1, 2))
+
+	p, err := newFlowSources(&config.ClientConfig{SourceAddresses: "10.0.0.1,10.0.0.2,lo", SourceStrategy: sourceRoundRobin})
+	require.NoError(t, err)
+	var got []string
+	for range 4 {
+		got = append(got, p.pick("server:8080", src).String())
+	}
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2", "lo", "10.0.0.1"}, got)
+	assert.Equal(t, "10.0.0.1, 10.0.0.2, lo", p.String())
+
+	// Hashing sends all flows to a target from the same source
+	p, err = newFlowSources(&config.ClientConfig{SourceCIDR: "10.0.0.0/24", SourceStrategy: sourceHash})
+	require.NoError(t, err)
+	first := p.pick("server:8080", src)
+	for range 10 {
+		assert.Equal(t, first, p.pick("server:8080", src))
+	}
+	targets := make(map[flowSource]bool)
+	for port := range 50 {
+		targets[p.pick(fmt.Sprintf("server:%d", 8000+port), src)] = true
+	}
+	assert.Greater(t, len(targets), 30)
+
+	// Random selection covers the whole pool
+	p, err = newFlowSources(&config.ClientConfig{SourceCIDR: "10.0.0.0/29", SourceStrategy: sourceRandom})
+	require.NoError(t, err)
+	seen := make(map[flowSource]bool)
+	for range 200 {
+		s := p.pick("server:8080", src)
+		assert.True(t, p.prefix.Contains(s.addr), s.addr)
+		seen[s] = true
+	}
+	assert.Len(t, seen, 6)
+}
+
+func TestNewFlowSources(t *testing.T) {
+	p, err := newFlowSources(&config.ClientConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, p)
+
+	p, err = newFlowSources(&config.ClientConfig{SourceCIDR: "10.1.0.0/16"})
+	require.NoError(t, err)
+	assert.Equal(t, sourceRoundRobin, p.strategy)
+	assert.Equal(t, "10.1.0.0/16 (65534 addresses)", p.String())
+
+	_, err = newFlowSources(&config.ClientConfig{SourceAddresses: "10.0.0.1,does-not-exist0"})
+	assert.ErrorContains(t, err, "unknown interface")
+}
+
+func TestAddOffset(t *testing.T) {
+	assert.Equal(t, "10.0.1.0", addOffset(netip.MustParseAddr("10.0.0.255"), 1).String())
+	assert.Equal(t, "10.1.0.0", addOffset(netip.MustParseAddr("10.0.0.0"), 65536).String())
+	assert.Equal(t, "fd00::1:0", addOffset(netip.MustParseAddr("fd00::ffff"), 1).String())
+}
+
+func TestSourceDialer(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("binding 127.0.0.2 requires Linux loopback routing")
+	}
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	// Linux routes all of 127.0.0.0/8 to the loopback interface
+	source := netip.MustParseAddr("127.0.0.2")
+	conn, err := sourceDialer("tcp", source).DialContext(context.Background(), "tcp", ln.Addr().String())
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.2", conn.LocalAddr().(*net.TCPAddr).IP.String())
+	_ = conn.Close()
+
+	udp, err := sourceDialer("udp", source).DialContext(context.Background(), "udp", "127.0.0.1:9")
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.2", udp.LocalAddr().(*net.UDPAddr).IP.String())
+	_ = udp.Close()
+
+	assert.Nil(t, sourceDialer("tcp", netip.Addr{}).LocalAddr)
+}
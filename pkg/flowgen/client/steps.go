@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/hooks"
+)
+
+// loadSteps runs the stepped load profile configured with step, moving the flow rate from step to step and
+// labelling the flows finished within each step with its name as the stage
+type loadSteps struct {
+	mu    sync.Mutex
+	start time.Time
+	steps []config.Step
+	// ends holds the end of every step since the start of the run
+	ends []time.Duration
+	// current is the index of the step running, len(steps) once the last one is over
+	current int
+}
+
+// newLoadSteps returns the load steps configured with step starting at the given time, or nil if the run
+// generates flows at a fixed rate
+func newLoadSteps(c *config.ClientConfig, start time.Time) *loadSteps {
+	if c.Steps == "" {
+		return nil
+	}
+	// The steps were checked when the configuration was validated
+	steps, _ := config.ParseSteps(c.Steps)
+	s := &loadSteps{start: start, steps: steps, ends: make([]time.Duration, len(steps))}
+	var end time.Duration
+	for i, st := range steps {
+		end += seconds(st.Duration)
+		s.ends[i] = end
+	}
+	return s
+}
+
+// String describes the steps for the logs
+func (s *loadSteps) String() string {
+	names := make([]string, len(s.steps))
+	for i, st := range s.steps {
+		names[i] = fmt.Sprintf("%s (%g/s for %gs)", st.Name, st.Rate, st.Duration)
+	}
+	return strings.Join(names, ", ")
+}
+
+// at returns the index of the step running at the given time, len(steps) after the last one
+func (s *loadSteps) at(t time.Time) int {
+	elapsed := max(t.Sub(s.start), 0)
+	i, _ := slices.BinarySearch(s.ends, elapsed)
+	// A time on the end of a step belongs to the next one
+	if i < len(s.ends) && s.ends[i] == elapsed {
+		i++
+	}
+	return i
+}
+
+// enter makes step i the current one, publishing its rate as the rate of its stage while the rate of the
+// step before drops to 0. Entering len(steps) ends the last step.
+func (s *loadSteps) enter(i int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current < len(s.steps) {
+		mc.SetLoadStageRate(s.steps[s.current].Name, 0)
+	}
+	s.current = i
+	if i < len(s.steps) {
+		mc.SetLoadStageRate(s.steps[i].Name, s.steps[i].Rate)
+	}
+}
+
+// stage returns the name of the step running, empty once the last one is over
+func (s *loadSteps) stage() string {
+	if s == nil {
+		return ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == len(s.steps) {
+		return ""
+	}
+	return s.steps[s.current].Name
+}
+
+// rate returns the flow rate of the step running, the rate of the last step once it is over
+func (s *loadSteps) rate() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.steps[min(s.current, len(s.steps)-1)].Rate
+}
+
+// duration returns the time from the start of the first step to the end of the last one
+func (s *loadSteps) duration() time.Duration {
+	return s.ends[len(s.ends)-1]
+}
+
+// run sends the index of every following step on changes when it is due, and len(steps) once the last
+// step is over, until ctx is done
+func (s *loadSteps) run(ctx context.Context, changes chan<- int) {
+	for i := 1; i <= len(s.steps); i++ {
+		timer := time.NewTimer(time.Until(s.start.Add(s.ends[i-1])))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		select {
+		case changes <- i:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// hooks returns the flow hooks counting the flows finished within each step
+func (s *loadSteps) hooks() hooks.Hooks {
+	return hooks.Hooks{
+		OnFlowCompleted: func(e hooks.Event) { s.observeFlow(e, "completed") },
+		OnFlowFailed:    func(e hooks.Event) { s.observeFlow(e, "failed") },
+	}
+}
+
+// observeFlow attributes a finished flow to the step running when it ended. Flows draining after the last
+// step are attributed to the last one.
+func (s *loadSteps) observeFlow(e hooks.Event, result string) {
+	i := min(s.at(e.Time), len(s.steps)-1)
+	latency := e.Latency
+	if result == "failed" {
+		latency = 0
+	}
+	mc.ObserveStageFlow(s.steps[i].Name, result, latency)
+}
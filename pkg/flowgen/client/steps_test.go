@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/hooks"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSteps(t *testing.T) {
+	oldMc := mc
+	mc = metrics.NewMetricsCollector()
+	defer func() { mc = oldMc }()
+
+	assert.Nil(t, newLoadSteps(&config.ClientConfig{}, time.Now()))
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	steps := newLoadSteps(&config.ClientConfig{Steps: "100:60;500:30:peak"}, start)
+	require.NotNil(t, steps)
+	assert.Equal(t, "step1 (100/s for 60s), peak (500/s for 30s)", steps.String())
+	assert.Equal(t, 90*time.Second, steps.duration())
+
+	assert.Equal(t, 0, steps.at(start.Add(-time.Second)))
+	assert.Equal(t, 0, steps.at(start.Add(59*time.Second)))
+	assert.Equal(t, 1, steps.at(start.Add(60*time.Second)))
+	assert.Equal(t, 2, steps.at(start.Add(90*time.Second)))
+
+	steps.enter(0)
+	assert.Equal(t, "step1", steps.stage())
+	assert.Equal(t, float64(100), steps.rate())
+	steps.enter(1)
+	assert.Equal(t, "peak", steps.stage())
+	assert.Equal(t, float64(500), steps.rate())
+	assert.Equal(t, float64(0), testutil.ToFloat64(mc.LoadStageRate.WithLabelValues("step1")))
+	assert.Equal(t, float64(500), testutil.ToFloat64(mc.LoadStageRate.WithLabelValues("peak")))
+	steps.enter(2)
+	assert.Empty(t, steps.stage())
+	assert.Equal(t, float64(500), steps.rate(), "the last rate is kept once the steps are over")
+	assert.Equal(t, float64(0), testutil.ToFloat64(mc.LoadStageRate.WithLabelValues("peak")))
+
+	// Flows are attributed to the step they ended in, draining flows to the last one
+	steps.observeFlow(hooks.Event{Time: start.Add(10 * time.Second), Latency: time.Millisecond}, "completed")
+	steps.observeFlow(hooks.Event{Time: start.Add(70 * time.Second)}, "failed")
+	steps.observeFlow(hooks.Event{Time: start.Add(100 * time.Second), Latency: time.Millisecond}, "completed")
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.StageFlows.WithLabelValues("step1", "completed")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.StageFlows.WithLabelValues("peak", "failed")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.StageFlows.WithLabelValues("peak", "completed")))
+}
+
+func TestLoadStepsRun(t *testing.T) {
+	steps := newLoadSteps(&config.ClientConfig{Steps: "10:0.02;20:0.02;30:0.02"}, time.Now())
+	changes := make(chan int)
+	go steps.run(context.Background(), changes)
+
+	for want := 1; want <= 3; want++ {
+		select {
+		case got := <-changes:
+			assert.Equal(t, want, got)
+		case <-time.After(time.Second):
+			t.Fatalf("step %d was not entered", want)
+		}
+	}
+
+	// Cancelling the run stops waiting for the next step
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		newLoadSteps(&config.ClientConfig{Steps: "10:60"}, time.Now()).run(ctx, changes)
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("run did not return after cancellation")
+	}
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+)
+
+// stopAll is the stop_condition generating flows until flow_count and flow_timeout are both reached. The
+// default, "any", stops at whichever is reached first.
+const stopAll = "all"
+
+// Conditions ending flow generation, reported as the stop reason of the run
+const (
+	stopFlowCount   = "flow_count"
+	stopFlowTimeout = "flow_timeout"
+	stopSteps       = "steps"
+	stopSignal      = "signal"
+)
+
+// stopConditionNames are the names of the stop conditions used in the logs
+var stopConditionNames = map[string]string{
+	stopFlowCount:   "Flow count limit",
+	stopFlowTimeout: "Flow timeout",
+	stopSteps:       "End of the last load step",
+	stopSignal:      "Termination signal",
+}
+
+// runStop ends flow generation once the configured stop conditions are reached, and records which one did
+type runStop struct {
+	mu      sync.Mutex
+	all     bool
+	pending map[string]bool
+	reason  string
+	cancel  context.CancelFunc
+	tracker *runTracker
+	// terminated is set once the client was asked to terminate by a signal
+	terminated bool
+}
+
+// newRunStop creates the stop conditions of a run configured with flow_count, flow_timeout, step and
+// stop_condition. Generation is stopped with cancel.
+func newRunStop(c *config.ClientConfig, cancel context.CancelFunc, tracker *runTracker) *runStop {
+	s := &runStop{all: c.StopCondition == stopAll, pending: make(map[string]bool), cancel: cancel, tracker: tracker}
+	if c.FlowCount > 0 {
+		s.pending[stopFlowCount] = true
+	}
+	if c.FlowTimeout > 0 {
+		s.pending[stopFlowTimeout] = true
+	}
+	if c.Steps != "" {
+		s.pending[stopSteps] = true
+	}
+	return s
+}
+
+// terminate stops flow generation because the client received a termination signal, regardless of the stop
+// conditions. The flows already started are still waited for and reported.
+func (s *runStop) terminate(sig os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.terminated = true
+	if s.reason != "" {
+		logging.Logger.Infof("Received signal: %v, waiting for active flows to end", sig)
+		return
+	}
+	s.reason = stopSignal
+	s.tracker.setStopReason(stopSignal)
+	logging.Logger.Infof("Received signal: %v, stopping flow generation and waiting for active flows to end", sig)
+	s.cancel()
+}
+
+// wasTerminated reports whether the client was asked to terminate by a signal
+func (s *runStop) wasTerminated() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.terminated
+}
+
+// reached records that a stop condition is met and reports whether flow generation stops. With
+// stop_condition all, generation goes on until the other conditions are reached too.
+func (s *runStop) reached(condition string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reason != "" {
+		return true
+	}
+	if !s.pending[condition] {
+		return false
+	}
+	delete(s.pending, condition)
+	if s.all && len(s.pending) > 0 {
+		for other := range s.pending {
+			logging.Logger.Infof("%s reached, generating flows until %s is reached as well", stopConditionNames[condition], other)
+		}
+		return false
+	}
+	s.reason = condition
+	s.tracker.setStopReason(condition)
+	logging.Logger.Infof("%s reached, stopping flow generation", stopConditionNames[condition])
+	s.cancel()
+	return true
+}
+
+// drainFlows waits for the active flows of wg to end, at most timeout unless it is 0, and reports whether all
+// of them ended
+func drainFlows(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	if timeout <= 0 {
+		<-done
+		return true
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	select {
+	case <-done:
+		return true
+	case <-deadline.C:
+		return false
+	}
+}
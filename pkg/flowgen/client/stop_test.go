@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunStop(t *testing.T) {
+	logging.InitLogger("json", "error")
+
+	tests := []struct {
+		name       string
+		cfg        config.ClientConfig
+		conditions []string
+		// stops holds whether generation stops after each condition
+		stops  []bool
+		reason string
+	}{
+		{
+			name:       "any stops at the first condition",
+			cfg:        config.ClientConfig{FlowCount: 10, FlowTimeout: 60, StopCondition: "any"},
+			conditions: []string{stopFlowCount, stopFlowTimeout},
+			stops:      []bool{true, true},
+			reason:     stopFlowCount,
+		},
+		{
+			name:       "default is any",
+			cfg:        config.ClientConfig{FlowCount: 10, FlowTimeout: 60},
+			conditions: []string{stopFlowTimeout},
+			stops:      []bool{true},
+			reason:     stopFlowTimeout,
+		},
+		{
+			name:       "all waits for both conditions",
+			cfg:        config.ClientConfig{FlowCount: 10, FlowTimeout: 60, StopCondition: stopAll},
+			conditions: []string{stopFlowCount, stopFlowCount, stopFlowTimeout},
+			stops:      []bool{false, false, true},
+			reason:     stopFlowTimeout,
+		},
+		{
+			name:       "all with a single condition",
+			cfg:        config.ClientConfig{FlowTimeout: 60, StopCondition: stopAll},
+			conditions: []string{stopFlowTimeout},
+			stops:      []bool{true},
+			reason:     stopFlowTimeout,
+		},
+		{
+			name:       "all waits for the last load step",
+			cfg:        config.ClientConfig{FlowTimeout: 60, Steps: "100:2m", StopCondition: stopAll},
+			conditions: []string{stopFlowTimeout, stopSteps},
+			stops:      []bool{false, true},
+			reason:     stopSteps,
+		},
+		{
+			name:       "unconfigured condition is ignored",
+			cfg:        config.ClientConfig{FlowTimeout: 60},
+			conditions: []string{stopFlowCount},
+			stops:      []bool{false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var flows uint64
+			tracker := newRunTracker(&tt.cfg, time.Now(), &flows)
+			stop := newRunStop(&tt.cfg, cancel, tracker)
+
+			for i, condition := range tt.conditions {
+				assert.Equal(t, tt.stops[i], stop.reached(condition), "condition %d: %s", i, condition)
+			}
+			assert.Equal(t, tt.reason, tracker.status(time.Now()).StopReason)
+			assert.Equal(t, tt.reason != "", ctx.Err() != nil, "main context cancelled")
+		})
+	}
+}
+
+func TestRunStopTerminate(t *testing.T) {
+	logging.InitLogger("json", "error")
+
+	// A signal stops generation even if stop_condition all still waits for other conditions
+	cfg := config.ClientConfig{FlowCount: 10, FlowTimeout: 60, StopCondition: stopAll}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var flows uint64
+	tracker := newRunTracker(&cfg, time.Now(), &flows)
+	stop := newRunStop(&cfg, cancel, tracker)
+
+	assert.False(t, stop.reached(stopFlowCount))
+	assert.False(t, stop.wasTerminated())
+	stop.terminate(syscall.SIGTERM)
+	assert.True(t, stop.wasTerminated())
+	assert.Equal(t, stopSignal, tracker.status(time.Now()).StopReason)
+	assert.Error(t, ctx.Err())
+
+	// A signal while draining keeps the stop condition that was reached
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	tracker = newRunTracker(&cfg, time.Now(), &flows)
+	stop = newRunStop(&config.ClientConfig{FlowCount: 10}, cancel, tracker)
+	assert.True(t, stop.reached(stopFlowCount))
+	stop.terminate(os.Interrupt)
+	assert.True(t, stop.wasTerminated())
+	assert.Equal(t, stopFlowCount, tracker.status(time.Now()).StopReason)
+}
+
+func TestDrainFlows(t *testing.T) {
+	var wg sync.WaitGroup
+	assert.True(t, drainFlows(&wg, time.Second), "no active flows")
+
+	wg.Add(1)
+	time.AfterFunc(20*time.Millisecond, wg.Done)
+	assert.True(t, drainFlows(&wg, time.Second), "flow ending within the timeout")
+
+	wg.Add(1)
+	start := time.Now()
+	assert.False(t, drainFlows(&wg, 50*time.Millisecond), "flow outliving the timeout")
+	assert.Less(t, time.Since(start), time.Second)
+
+	time.AfterFunc(20*time.Millisecond, wg.Done)
+	assert.True(t, drainFlows(&wg, 0), "no timeout waits for all flows")
+}
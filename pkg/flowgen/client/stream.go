@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/hooks"
+)
+
+// outputNDJSON streams run statistics and finished flows to stdout as newline-delimited JSON
+const outputNDJSON = "ndjson"
+
+// streamFlow is a finished flow in the result stream
+type streamFlow struct {
+	Type string `json:"type"`
+	flowLogRecord
+}
+
+// streamStats is a snapshot of the run in the result stream. Periodic snapshots have type "stats",
+// the final one written when the run ends has type "result".
+type streamStats struct {
+	Type    string          `json:"type"`
+	Time    string          `json:"time"`
+	Run     runStatus       `json:"run"`
+	Metrics metrics.Summary `json:"metrics"`
+}
+
+// resultStream writes one JSON object per line, so the client can be piped into tools like jq or Vector.
+// Every line is written with a single write, so consumers never see partial lines.
+type resultStream struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	failed bool
+	// finished is set once the final result was written, later lines are dropped
+	finished bool
+}
+
+// newResultStream creates a result stream writing to w
+func newResultStream(w io.Writer) *resultStream {
+	return &resultStream{enc: json.NewEncoder(w)}
+}
+
+// hooks returns the flow hooks that write finished flows to the stream
+func (s *resultStream) hooks() hooks.Hooks {
+	write := func(e hooks.Event) {
+		s.write(streamFlow{Type: "flow", flowLogRecord: newFlowLogRecord(e)})
+	}
+	return hooks.Hooks{OnFlowCompleted: write, OnFlowFailed: write}
+}
+
+// writeStats writes a periodic snapshot of the run
+func (s *resultStream) writeStats(t *runTracker, now time.Time) {
+	s.write(newStreamStats("stats", t, now))
+}
+
+// finish writes the final result of the run as the last line of the stream
+func (s *resultStream) finish(t *runTracker, now time.Time) {
+	result := newStreamStats("result", t, now)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.finished {
+		s.encode(result)
+		s.finished = true
+	}
+}
+
+// newStreamStats takes a snapshot of the run with the given type
+func newStreamStats(kind string, t *runTracker, now time.Time) streamStats {
+	return streamStats{
+		Type:    kind,
+		Time:    now.UTC().Format(time.RFC3339Nano),
+		Run:     t.status(now),
+		Metrics: mc.Summary(),
+	}
+}
+
+// runStats writes a stats snapshot every interval until ctx is done
+func (s *resultStream) runStats(ctx context.Context, t *runTracker, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.writeStats(t, now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// write writes a single line unless the stream has finished
+func (s *resultStream) write(v any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.finished {
+		s.encode(v)
+	}
+}
+
+// encode encodes a single line, logging only the first write error. The caller must hold mu.
+func (s *resultStream) encode(v any) {
+	if err := s.enc.Encode(v); err != nil && !s.failed {
+		s.failed = true
+		logging.Logger.Errorf("Failed to write result stream: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/hooks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamLines decodes every line written to a result stream
+func streamLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var v map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &v), line)
+		lines = append(lines, v)
+	}
+	return lines
+}
+
+func TestResultStream(t *testing.T) {
+	oldMc := mc
+	mc = metrics.NewMetricsCollector()
+	defer func() { mc = oldMc }()
+
+	start := time.Now()
+	flows := uint64(2)
+	tracker := newRunTracker(&config.ClientConfig{Scenario: "pipeline"}, start, &flows)
+
+	var buf bytes.Buffer
+	s := newResultStream(&buf)
+	h := s.hooks()
+	h.OnFlowCompleted(hooks.Event{FlowID: 1, Protocol: "tcp", Port: 8080, Time: start})
+	h.OnFlowFailed(hooks.Event{FlowID: 2, Protocol: "udp", Port: 53, Time: start, Err: errors.New("timeout")})
+	s.writeStats(tracker, start.Add(time.Second))
+	s.finish(tracker, start.Add(2*time.Second))
+
+	// Nothing is written after the final result
+	s.writeStats(tracker, start.Add(3*time.Second))
+	s.finish(tracker, start.Add(3*time.Second))
+
+	lines := streamLines(t, &buf)
+	require.Len(t, lines, 4)
+	assert.Equal(t, "flow", lines[0]["type"])
+	assert.Equal(t, float64(1), lines[0]["flow_id"])
+	assert.Equal(t, "completed", lines[0]["result"])
+	assert.Equal(t, "failed", lines[1]["result"])
+	assert.Equal(t, "timeout", lines[1]["error"])
+	assert.Equal(t, "stats", lines[2]["type"])
+	assert.Equal(t, "pipeline", lines[2]["run"].(map[string]any)["scenario"])
+	assert.Contains(t, lines[2], "metrics")
+	assert.Equal(t, "result", lines[3]["type"])
+}
+
+func TestResultStreamPeriodicStats(t *testing.T) {
+	oldMc := mc
+	mc = metrics.NewMetricsCollector()
+	defer func() { mc = oldMc }()
+
+	flows := uint64(0)
+	tracker := newRunTracker(&config.ClientConfig{}, time.Now(), &flows)
+
+	var buf bytes.Buffer
+	s := newResultStream(&buf)
+	ctx, cancel := context.WithTimeout(context.Background(), 55*time.Millisecond)
+	defer cancel()
+	s.runStats(ctx, tracker, 10*time.Millisecond)
+	s.finish(tracker, time.Now())
+
+	lines := streamLines(t, &buf)
+	require.GreaterOrEqual(t, len(lines), 3)
+	for _, line := range lines[:len(lines)-1] {
+		assert.Equal(t, "stats", line["type"])
+	}
+	assert.Equal(t, "result", lines[len(lines)-1]["type"])
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"net"
+	"os"
+	"runtime/debug"
+	"sync"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+)
+
+// socketRegistry tracks the sockets held by active flows so they can be closed if the run is aborted
+type socketRegistry struct {
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+// newSocketRegistry creates an empty socket registry
+func newSocketRegistry() *socketRegistry {
+	return &socketRegistry{conns: make(map[net.Conn]struct{})}
+}
+
+// add registers a socket opened by a flow
+func (r *socketRegistry) add(conn net.Conn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.conns[conn] = struct{}{}
+}
+
+// remove unregisters a socket once its flow no longer holds it
+func (r *socketRegistry) remove(conn net.Conn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.conns, conn)
+}
+
+// count returns the number of registered sockets
+func (r *socketRegistry) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.conns)
+}
+
+// closeAll closes and unregisters all sockets and returns how many were closed
+func (r *socketRegistry) closeAll() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := len(r.conns)
+	for conn := range r.conns {
+		_ = conn.Close()
+		delete(r.conns, conn)
+	}
+	return n
+}
+
+// supervisor cleans up the run when flow generation exits abnormally, instead of leaving the
+// remaining sockets for the OS to reap
+type supervisor struct {
+	once    sync.Once
+	sockets *socketRegistry
+	tracker *runTracker
+	exit    func(code int)
+}
+
+// newSupervisor creates a supervisor closing the given sockets and reporting through the given tracker
+func newSupervisor(sockets *socketRegistry, tracker *runTracker) *supervisor {
+	return &supervisor{
+		sockets: sockets,
+		tracker: tracker,
+		exit:    os.Exit,
+	}
+}
+
+// guard aborts the run if the calling goroutine panics. It must be deferred directly.
+func (s *supervisor) guard() {
+	if r := recover(); r != nil {
+		s.abort(r, debug.Stack())
+	}
+}
+
+// abort closes all remaining sockets, flushes the metrics, emits a final report marked aborted and exits.
+// Only the first abnormal exit is handled, later ones wait for the process to exit.
+func (s *supervisor) abort(reason any, stack []byte) {
+	s.once.Do(func() {
+		logging.Logger.Errorf("Flow generation exited abnormally: %v\n%s", reason, stack)
+		s.tracker.setPhase(phaseAborted)
+
+		closed := s.sockets.closeAll()
+		if pool != nil {
+			pool.closeAll()
+		}
+		logging.Logger.Warnf("Closed %d remaining sockets", closed)
+
+		mc.LogMetrics(cfg.LogFormat)
+		reportRun(s.tracker)
+		s.exit(1)
+	})
+}
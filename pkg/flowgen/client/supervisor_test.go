@@ -0,0 +1,81 @@
+package client
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSocketRegistry(t *testing.T) {
+	r := newSocketRegistry()
+	a, b := net.Pipe()
+	defer func() { _ = b.Close() }()
+	c, d := net.Pipe()
+	defer func() { _ = d.Close() }()
+
+	r.add(a)
+	r.add(c)
+	r.remove(c)
+	assert.Equal(t, 1, r.count())
+
+	assert.Equal(t, 1, r.closeAll())
+	assert.Equal(t, 0, r.count())
+
+	// Closed sockets fail immediately
+	_, err := a.Write([]byte("x"))
+	assert.Error(t, err)
+}
+
+func TestSupervisorAbortsOnPanic(t *testing.T) {
+	logging.InitLogger("json", "error")
+	oldMc, oldCfg := mc, cfg
+	mc = metrics.NewMetricsCollector()
+	cfg = &config.ClientConfig{CommonConfig: config.CommonConfig{LogFormat: "json"}}
+	defer func() { mc, cfg = oldMc, oldCfg }()
+
+	var flows uint64
+	sockets := newSocketRegistry()
+	tracker := newRunTracker(cfg, time.Now(), &flows)
+	sup := newSupervisor(sockets, tracker)
+
+	exitCodes := make(chan int, 2)
+	sup.exit = func(code int) { exitCodes <- code }
+
+	conn, peer := net.Pipe()
+	defer func() { _ = peer.Close() }()
+	sockets.add(conn)
+
+	// Concurrent panics only abort the run once
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer sup.guard()
+			panic("generation loop failed")
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, exitCodes, 1)
+	assert.Equal(t, 1, <-exitCodes)
+	assert.Equal(t, 0, sockets.count())
+	assert.Equal(t, phaseAborted, tracker.status(time.Now()).Phase)
+}
+
+func TestSupervisorGuardWithoutPanic(t *testing.T) {
+	var flows uint64
+	sup := newSupervisor(newSocketRegistry(), newRunTracker(&config.ClientConfig{}, time.Now(), &flows))
+	sup.exit = func(code int) { t.Fatalf("unexpected exit with code %d", code) }
+
+	func() {
+		defer sup.guard()
+	}()
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+)
+
+// probeWorkers is the number of addresses of target_cidr probed concurrently
+const probeWorkers = 256
+
+// defaultProbeTimeout bounds a probe if connect_timeout is not set
+const defaultProbeTimeout = time.Second
+
+// targetSweep spreads flows across the addresses of target_cidr, taking them in turn by flow ID
+type targetSweep struct {
+	prefix netip.Prefix
+	addrs  []netip.Addr
+}
+
+// newTargetSweep creates the sweep over the addresses of cidr. As with source_cidr, the network and
+// broadcast addresses of IPv4 ranges and the subnet-router anycast address of IPv6 ranges are skipped if
+// the range has room.
+func newTargetSweep(cidr string) (*targetSweep, error) {
+	prefix, err := config.ParseTargetCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	size := uint64(1) << (prefix.Addr().BitLen() - prefix.Bits())
+	first := prefix.Addr()
+	switch {
+	case prefix.Addr().Is4() && size > 2:
+		first, size = first.Next(), size-2
+	case prefix.Addr().Is6() && size > 2:
+		first, size = first.Next(), size-1
+	}
+	s := &targetSweep{prefix: prefix, addrs: make([]netip.Addr, 0, size)}
+	for addr, i := first, uint64(0); i < size; addr, i = addr.Next(), i+1 {
+		s.addrs = append(s.addrs, addr)
+	}
+	return s, nil
+}
+
+// pick returns the target of a flow. Flow IDs start at 1, so the first flow goes to the first address.
+func (s *targetSweep) pick(flowID uint64) string {
+	return s.addrs[(flowID-1)%uint64(len(s.addrs))].String()
+}
+
+// String describes the addresses of the sweep for the logs
+func (s *targetSweep) String() string {
+	return fmt.Sprintf("%s (%d addresses)", s.prefix, len(s.addrs))
+}
+
+// probe keeps the addresses answering on the given port, and returns how many of them did. The probes
+// run concurrently, each bounded by timeout.
+func (s *targetSweep) probe(ctx context.Context, pp ProtocolPort, timeout time.Duration) int {
+	responding := make([]bool, len(s.addrs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(probeWorkers, len(s.addrs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				responding[i] = probeTarget(ctx, constructAddress(s.addrs[i].String(), pp.Port), pp.Protocol, timeout)
+			}
+		}()
+	}
+	for i := range s.addrs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	kept := s.addrs[:0]
+	for i, addr := range s.addrs {
+		if responding[i] {
+			kept = append(kept, addr)
+		}
+	}
+	s.addrs = kept
+	return len(kept)
+}
+
+// probeTarget reports whether a host answers at addr. A refused TCP connection or an ICMP port
+// unreachable for a UDP probe counts as an answer too, as the host is up, while a silent address does not.
+func probeTarget(ctx context.Context, addr, protocol string, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, protocol, addr)
+	if err != nil {
+		return errors.Is(err, syscall.ECONNREFUSED)
+	}
+	defer func() { _ = conn.Close() }()
+	if protocol != "udp" {
+		return true
+	}
+
+	// A UDP host answers with the echo of the probe or, on a connected socket, with a refused read
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return false
+	}
+	if _, err := conn.Write([]byte("probe")); err != nil {
+		return errors.Is(err, syscall.ECONNREFUSED)
+	}
+	_, err = conn.Read(make([]byte, 64))
+	return err == nil || errors.Is(err, syscall.ECONNREFUSED)
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTargetSweep(t *testing.T) {
+	tests := []struct {
+		cidr  string
+		first string
+		last  string
+		size  int
+	}{
+		{"10.2.0.0/24", "10.2.0.1", "10.2.0.254", 254},
+		{"10.2.0.9/30", "10.2.0.9", "10.2.0.10", 2},
+		{"10.2.0.8/31", "10.2.0.8", "10.2.0.9", 2},
+		{"10.2.0.8/32", "10.2.0.8", "10.2.0.8", 1},
+		{"fd00::/120", "fd00::1", "fd00::ff", 255},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cidr, func(t *testing.T) {
+			s, err := newTargetSweep(tt.cidr)
+			require.NoError(t, err)
+			require.Len(t, s.addrs, tt.size)
+			assert.Equal(t, tt.first, s.addrs[0].String())
+			assert.Equal(t, tt.last, s.addrs[len(s.addrs)-1].String())
+		})
+	}
+
+	_, err := newTargetSweep("10.0.0.0/8")
+	assert.Error(t, err)
+}
+
+func TestTargetSweepPick(t *testing.T) {
+	s, err := newTargetSweep("10.2.0.0/30")
+	require.NoError(t, err)
+	assert.Equal(t, "10.2.0.0/30 (2 addresses)", s.String())
+
+	var picked []string
+	for flowID := uint64(1); flowID <= 5; flowID++ {
+		picked = append(picked, s.pick(flowID))
+	}
+	assert.Equal(t, []string{"10.2.0.1", "10.2.0.2", "10.2.0.1", "10.2.0.2", "10.2.0.1"}, picked)
+}
+
+func TestTargetSweepProbe(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	// 127.0.0.1 accepts and 127.0.0.2 refuses the connection, both count as responding
+	s := &targetSweep{addrs: []netip.Addr{netip.MustParseAddr("127.0.0.1"), netip.MustParseAddr("127.0.0.2")}}
+	n := s.probe(context.Background(), ProtocolPort{Protocol: "tcp", Port: port}, time.Second)
+	assert.Equal(t, 2, n)
+
+	// A probe that cannot complete in time does not
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Zero(t, s.probe(ctx, ProtocolPort{Protocol: "tcp", Port: port}, time.Second))
+	assert.Empty(t, s.addrs)
+}
+
+func TestProbeTargetUDP(t *testing.T) {
+	echo, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer func() { _ = echo.Close() }()
+	go func() {
+		buf := make([]byte, 64)
+		n, addr, err := echo.ReadFromUDP(buf)
+		if err == nil {
+			_, _ = echo.WriteToUDP(buf[:n], addr)
+		}
+	}()
+	assert.True(t, probeTarget(context.Background(), echo.LocalAddr().String(), "udp", time.Second))
+
+	// A closed port answers with an ICMP port unreachable
+	closed, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	addr := closed.LocalAddr().String()
+	require.NoError(t, closed.Close())
+	assert.True(t, probeTarget(context.Background(), addr, "udp", time.Second))
+}
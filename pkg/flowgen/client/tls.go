@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strings"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/server"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/transport"
+)
+
+// tlsServerNames are the SNI names the flows of the tls transport send in turn, none sends the server address
+var tlsServerNames []string
+
+// init registers the TLS transport
+func init() {
+	transport.Register("tls", transport.StreamMode, newTLSTransport)
+}
+
+// splitServerNames splits the comma-separated SNI names of tls_server_names
+func splitServerNames(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// serverName returns the SNI name of a flow to addr, the flows take turns over the configured names
+func serverName(flowID uint64, addr string) string {
+	if len(tlsServerNames) > 0 {
+		return tlsServerNames[flowID%uint64(len(tlsServerNames))]
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	return host
+}
+
+// tlsTransport echoes the payload over TLS, to test SNI-based routing and policy on the path. The certificate
+// of the server is not verified, flows test the path and not the identity of the server.
+type tlsTransport struct {
+	flow transport.FlowInfo
+	conn *tls.Conn
+}
+
+// newTLSTransport creates a TLS transport for a flow
+func newTLSTransport(flow transport.FlowInfo) transport.Transport {
+	return &tlsTransport{flow: flow}
+}
+
+// Dial connects to addr and completes the TLS handshake, recording the SNI and cipher suite it negotiated. TLS
+// connections are never pooled or relayed.
+func (t *tlsTransport) Dial(ctx context.Context, addr string) error {
+	conn, err := dialing.dial(ctx, flowDialer("tcp", t.flow), "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if err := tuning.conn(conn); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	_, port, _ := net.SplitHostPort(addr)
+	// #nosec G402 - flows test the path, not the identity of the server
+	tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true, ServerName: serverName(t.flow.ID, addr), MinVersion: tls.VersionTLS12})
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		mc.IncTLSHandshakeFailures(port)
+		_ = conn.Close()
+		return err
+	}
+	state := tlsConn.ConnectionState()
+	mc.IncTLSHandshakes(port, server.SNILabel(state.ServerName), tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+	t.conn = tlsConn
+	sockets.add(conn)
+	mc.TCPConnectionsOpenedPerSecond.Inc()
+	logFlowDetail(t.flow.ID, t.flow.Protocol, t.flow.Sampled, "TLS connection %s -> %s established (SNI %q, %s, %s)", conn.LocalAddr(), conn.RemoteAddr(),
+		state.ServerName, tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+	return nil
+}
+
+// Send writes the payload to the connection
+func (t *tlsTransport) Send(payload []byte) (int, error) {
+	return t.conn.Write(payload)
+}
+
+// Recv reads from the connection
+func (t *tlsTransport) Recv(buf []byte) (int, error) {
+	return t.conn.Read(buf)
+}
+
+// Close closes the connection
+func (t *tlsTransport) Close() error {
+	sockets.remove(t.conn.NetConn())
+	return t.conn.Close()
+}
+
+// RemoteAddr returns the address of the server
+func (t *tlsTransport) RemoteAddr() net.Addr {
+	return t.conn.RemoteAddr()
+}
+
+// LocalAddr returns the local address of the connection
+func (t *tlsTransport) LocalAddr() net.Addr {
+	return t.conn.LocalAddr()
+}
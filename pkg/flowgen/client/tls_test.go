@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/handlers"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/internal/server"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/transport"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitServerNames(t *testing.T) {
+	assert.Nil(t, splitServerNames(""))
+	assert.Equal(t, []string{"a.example.com", "b.example.com"}, splitServerNames(" a.example.com, ,b.example.com "))
+}
+
+func TestServerName(t *testing.T) {
+	oldNames := tlsServerNames
+	defer func() { tlsServerNames = oldNames }()
+
+	tlsServerNames = nil
+	assert.Equal(t, "echo.example.com", serverName(3, "echo.example.com:8443"))
+
+	tlsServerNames = []string{"a.example.com", "b.example.com"}
+	assert.Equal(t, "a.example.com", serverName(0, "127.0.0.1:8443"))
+	assert.Equal(t, "b.example.com", serverName(1, "127.0.0.1:8443"))
+	assert.Equal(t, "a.example.com", serverName(2, "127.0.0.1:8443"))
+}
+
+func TestTLSTransport(t *testing.T) {
+	logging.InitLogger("json", "error")
+	oldMc, oldNames := mc, tlsServerNames
+	mc = metrics.NewMetricsCollector()
+	tlsServerNames = []string{"a.example.com"}
+	defer func() { mc, tlsServerNames = oldMc, oldNames }()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	portNum := listener.Addr().(*net.TCPAddr).Port
+	port := strconv.Itoa(portNum)
+	_ = listener.Close()
+	serverMc := metrics.NewMetricsCollector()
+	srv := server.NewTCPServer(portNum, handlers.NewTCPHandler(serverMc))
+	srv.SetTLS(server.NewTLS(serverMc, nil))
+	require.NoError(t, srv.Start())
+	defer func() { _ = srv.Stop() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	tr := newTLSTransport(transport.FlowInfo{ID: 1, Protocol: "tls"})
+	require.NoError(t, tr.Dial(ctx, net.JoinHostPort("127.0.0.1", port)))
+	defer func() { _ = tr.Close() }()
+
+	_, err = tr.Send([]byte("hello"))
+	require.NoError(t, err)
+	buf := make([]byte, 5)
+	n, err := tr.Recv(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(buf[:n]))
+
+	state := tr.(*tlsTransport).conn.ConnectionState()
+	assert.Equal(t, "a.example.com", state.PeerCertificates[0].Subject.CommonName)
+	assert.Equal(t, 1.0, testutil.ToFloat64(mc.TLSHandshakes.WithLabelValues(port, "a.example.com",
+		tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))))
+}
+
+func TestTLSTransportHandshakeFailure(t *testing.T) {
+	logging.InitLogger("json", "error")
+	oldMc := mc
+	mc = metrics.NewMetricsCollector()
+	defer func() { mc = oldMc }()
+
+	// A plaintext server hangs up on the client hello
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			_ = conn.Close()
+		}
+	}()
+	port := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	tr := newTLSTransport(transport.FlowInfo{ID: 1, Protocol: "tls"})
+	assert.Error(t, tr.Dial(ctx, net.JoinHostPort("127.0.0.1", port)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(mc.TLSHandshakeFailures.WithLabelValues(port)))
+}
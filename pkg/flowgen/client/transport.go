@@ -0,0 +1,235 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/transport"
+)
+
+// init registers the built-in TCP and UDP transports
+func init() {
+	transport.Register("tcp", transport.StreamMode, newTCPTransport)
+	transport.Register("udp", transport.DatagramMode, newUDPTransport)
+}
+
+// checkTransportPorts reports the ports mapped with transport_ports to a transport that is not registered.
+// The configuration cannot check them when it is loaded, as transports are registered by the program.
+func checkTransportPorts(c *config.ClientConfig) error {
+	transportPorts, _ := config.ParsePortMap(c.TransportPorts)
+	var unknown []string
+	for p, name := range transportPorts {
+		if _, ok := transport.Lookup(name); !ok {
+			unknown = append(unknown, fmt.Sprintf("%d=%s", p, name))
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("no flow transport registered for transport_ports %s (available: %v)", strings.Join(unknown, ","), transport.Registered())
+	}
+	return nil
+}
+
+// remoteAddr returns the remote address of a transport if it exposes one
+func remoteAddr(t transport.Transport) net.Addr {
+	if r, ok := t.(interface{ RemoteAddr() net.Addr }); ok {
+		return r.RemoteAddr()
+	}
+	return nil
+}
+
+// localAddr returns the local address of a transport if it exposes one
+func localAddr(t transport.Transport) net.Addr {
+	if l, ok := t.(interface{ LocalAddr() net.Addr }); ok {
+		return l.LocalAddr()
+	}
+	return nil
+}
+
+// tcpTransport is the built-in TCP transport. It dials through the connection pool or the relay
+// chain if enabled, and returns connections that completed a clean echo to the pool.
+type tcpTransport struct {
+	flow     transport.FlowInfo
+	addr     string
+	conn     net.Conn
+	sent     int
+	received int
+}
+
+// newTCPTransport creates a TCP transport for a flow
+func newTCPTransport(flow transport.FlowInfo) transport.Transport {
+	return &tcpTransport{flow: flow}
+}
+
+// Dial connects to addr, reusing a pooled connection if available
+func (t *tcpTransport) Dial(ctx context.Context, addr string) error {
+	var reused bool
+	var err error
+	if pool != nil {
+		t.conn, reused, err = pool.get(addr)
+	} else if relays != nil {
+		var hops []relayHop
+		t.conn, hops, err = relays.dial(addr)
+		if err == nil {
+			observeRelayHops(t.flow, hops)
+		}
+	} else {
+		t.conn, err = dialing.dial(ctx, flowDialer("tcp", t.flow), "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	if err := tuning.conn(t.conn); err != nil {
+		_ = t.conn.Close()
+		return err
+	}
+	// Pooled and relayed connections were not dialed for this flow and get its socket options once established
+	if pool != nil || relays != nil {
+		if err := applyFlowOptions(t.conn, t.flow); err != nil {
+			_ = t.conn.Close()
+			return err
+		}
+	}
+	t.addr = addr
+	sockets.add(t.conn)
+
+	if reused {
+		mc.IncTCPConnectionsReused()
+	} else {
+		mc.TCPConnectionsOpenedPerSecond.Inc()
+	}
+	logFlowDetail(t.flow.ID, t.flow.Protocol, t.flow.Sampled, "TCP connection %s -> %s established (reused: %t)", t.conn.LocalAddr(), t.conn.RemoteAddr(), reused)
+	return nil
+}
+
+// Send writes the payload to the connection
+func (t *tcpTransport) Send(payload []byte) (int, error) {
+	if len(payload) > t.flow.MSS {
+		logging.Logger.Debugf("TCP payload size %d exceeds MSS %d, will be segmented", len(payload), t.flow.MSS)
+	}
+	n, err := t.conn.Write(payload)
+	t.sent += n
+	return n, err
+}
+
+// Recv reads from the connection
+func (t *tcpTransport) Recv(buf []byte) (int, error) {
+	n, err := t.conn.Read(buf)
+	t.received += n
+	return n, err
+}
+
+// Close returns the connection to the pool if everything sent was echoed back, otherwise closes it
+func (t *tcpTransport) Close() error {
+	sockets.remove(t.conn)
+	if pool != nil && t.sent > 0 && t.received == t.sent {
+		pool.put(t.addr, t.conn)
+		return nil
+	}
+	return t.conn.Close()
+}
+
+// RemoteAddr returns the address of the server
+func (t *tcpTransport) RemoteAddr() net.Addr {
+	return t.conn.RemoteAddr()
+}
+
+// LocalAddr returns the local address of the connection
+func (t *tcpTransport) LocalAddr() net.Addr {
+	return t.conn.LocalAddr()
+}
+
+// udpTransport is the built-in UDP transport using a connected socket per flow
+type udpTransport struct {
+	flow transport.FlowInfo
+	conn *net.UDPConn
+	addr string
+	// sentAt is when the last request was sent, to measure the round trip of its response
+	sentAt time.Time
+}
+
+// newUDPTransport creates a UDP transport for a flow
+func newUDPTransport(flow transport.FlowInfo) transport.Transport {
+	return &udpTransport{flow: flow}
+}
+
+// Dial opens a UDP socket connected to addr
+func (t *udpTransport) Dial(ctx context.Context, addr string) error {
+	conn, err := dialing.dial(ctx, flowDialer("udp", t.flow), "udp", addr)
+	if err != nil {
+		return err
+	}
+	t.conn = conn.(*net.UDPConn)
+	t.addr = addr
+	if t.flow.FlowLabel != 0 {
+		if err := applyFlowLabel(t.conn, t.flow.FlowLabel); err != nil {
+			_ = t.conn.Close()
+			return err
+		}
+	}
+	sockets.add(t.conn)
+	return nil
+}
+
+// Send sends the payload as a single datagram, refusing payloads that would exceed the MTU
+func (t *udpTransport) Send(payload []byte) (int, error) {
+	if len(payload) > t.flow.MTU {
+		return 0, fmt.Errorf("payload size %d exceeds MTU %d", len(payload), t.flow.MTU)
+	}
+	t.sentAt = time.Now()
+	n, err := t.conn.Write(payload)
+	return n, annotateICMPError(t.conn, err)
+}
+
+// Recv waits for the response datagram up to the timeout derived from the round trips to the target.
+// Errors caused by ICMP errors, like a port unreachable or a firewall rejecting the datagram, are
+// annotated with their ICMP type.
+func (t *udpTransport) Recv(buf []byte) (int, error) {
+	if err := t.conn.SetReadDeadline(time.Now().Add(responseTimeouts.timeout(t.addr))); err != nil {
+		logging.Logger.Warnf("Failed to set read deadline for UDP connection: %v", err)
+	}
+	n, _, err := t.conn.ReadFromUDP(buf)
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		responseTimeouts.expired(t.addr)
+	} else if err == nil {
+		responseTimeouts.observe(t.addr, time.Since(t.sentAt))
+	}
+	return n, annotateICMPError(t.conn, err)
+}
+
+// RecvUntil reads the next datagram up to the deadline, leaving the response timeouts of the target alone.
+// It does not touch sentAt, so it may be called while datagrams are sent.
+func (t *udpTransport) RecvUntil(buf []byte, deadline time.Time) (int, error) {
+	if err := t.conn.SetReadDeadline(deadline); err != nil {
+		logging.Logger.Warnf("Failed to set read deadline for UDP connection: %v", err)
+	}
+	n, _, err := t.conn.ReadFromUDP(buf)
+	return n, annotateICMPError(t.conn, err)
+}
+
+// Close closes the socket
+func (t *udpTransport) Close() error {
+	sockets.remove(t.conn)
+	return t.conn.Close()
+}
+
+// RemoteAddr returns the address of the server
+func (t *udpTransport) RemoteAddr() net.Addr {
+	return t.conn.RemoteAddr()
+}
+
+// LocalAddr returns the local address of the socket
+func (t *udpTransport) LocalAddr() net.Addr {
+	return t.conn.LocalAddr()
+}
+
+// protocolName returns the protocol name used in log messages
+func protocolName(protocol string) string {
+	return strings.ToUpper(protocol)
+}
@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/transport"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loopbackTransport is a custom transport that answers every request itself
+type loopbackTransport struct {
+	mu      sync.Mutex
+	dialed  string
+	pending []byte
+	closed  bool
+}
+
+func (l *loopbackTransport) Dial(_ context.Context, addr string) error {
+	l.dialed = addr
+	return nil
+}
+
+func (l *loopbackTransport) Send(payload []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pending = append([]byte(nil), payload...)
+	return len(payload), nil
+}
+
+func (l *loopbackTransport) Recv(buf []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.pending == nil {
+		return 0, errors.New("no pending request")
+	}
+	n := copy(buf, l.pending)
+	l.pending = nil
+	return n, nil
+}
+
+func (l *loopbackTransport) Close() error {
+	l.closed = true
+	return nil
+}
+
+func TestBuiltinTransports(t *testing.T) {
+	modes := map[string]transport.Mode{
+		"tcp":           transport.StreamMode,
+		"udp":           transport.DatagramMode,
+		"tls":           transport.StreamMode,
+		"tcp_handshake": transport.HoldMode,
+		"tcp_noread":    transport.HoldMode,
+		"tcp_churn":     transport.ChurnMode,
+		"tcp_churn_rst": transport.ChurnMode,
+		"tcp_recv":      transport.ReceiveMode,
+	}
+	for protocol, mode := range modes {
+		reg, ok := transport.Lookup(protocol)
+		if assert.True(t, ok, protocol) {
+			assert.Equal(t, mode, reg.Mode, protocol)
+		}
+	}
+	assert.Panics(t, func() { transport.Register("tcp", transport.StreamMode, newTCPTransport) })
+}
+
+func TestGenerateFlowCustomTransport(t *testing.T) {
+	logging.InitLogger("json", "error")
+
+	tr := &loopbackTransport{}
+	transport.Register("loopback-stream", transport.StreamMode, func(transport.FlowInfo) transport.Transport { return tr })
+
+	oldCfg, oldMc := cfg, mc
+	cfg = &config.ClientConfig{PayloadSize: 64}
+	mc = metrics.NewMetricsCollector()
+	defer func() { cfg, mc = oldCfg, oldMc }()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	generateFlow(context.Background(), 1, "127.0.0.1", ProtocolPort{"loopback-stream", 9000}, 0.01, rand.New(rand.NewPCG(0, 0)), 1500, 1460, &wg)
+	wg.Wait()
+
+	assert.Equal(t, "127.0.0.1:9000", tr.dialed)
+	assert.True(t, tr.closed)
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.RequestsSent.WithLabelValues("loopback-stream", "9000")))
+	assert.Equal(t, float64(64), testutil.ToFloat64(mc.BytesReceived.WithLabelValues("loopback-stream", "9000")))
+	assert.Equal(t, uint64(1), mc.LatencySummaries()["loopback-stream"].Count)
+}
+
+func TestGenerateFlowUnknownTransport(t *testing.T) {
+	logging.InitLogger("json", "error")
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	assert.NotPanics(t, func() {
+		generateFlow(context.Background(), 1, "127.0.0.1", ProtocolPort{"missing", 9000}, 0.01, rand.New(rand.NewPCG(0, 0)), 1500, 1460, &wg)
+	})
+	wg.Wait()
+}
+
+func TestBuildAvailablePortsTransports(t *testing.T) {
+	logging.InitLogger("json", "error")
+	transport.Register("loopback-datagram", transport.DatagramMode, func(transport.FlowInfo) transport.Transport { return &loopbackTransport{} })
+
+	c := &config.ClientConfig{Protocol: "tcp", TCPPorts: "8080", TransportPorts: "9001=loopback-datagram,9000=loopback-datagram,9002=unknown"}
+	require.Equal(t, []ProtocolPort{{"tcp", 8080}, {"loopback-datagram", 9000}, {"loopback-datagram", 9001}}, buildAvailablePorts(c))
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"syscall"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
+)
+
+// ttlLimits holds the TTL (hop limit for IPv6) flows are sent with, globally and per port
+type ttlLimits struct {
+	global int
+	ports  map[int]int
+}
+
+// newTTLLimits returns the TTLs configured with ttl and ttl_ports, or nil if flows use the system default
+func newTTLLimits(c *config.ClientConfig) *ttlLimits {
+	if c.TTL == 0 && c.TTLPorts == "" {
+		return nil
+	}
+	// The mapping was checked when the configuration was validated
+	ports, _ := config.ParsePortMap(c.TTLPorts)
+	l := &ttlLimits{global: c.TTL, ports: make(map[int]int, len(ports))}
+	for port, value := range ports {
+		l.ports[port], _ = strconv.Atoi(value)
+	}
+	return l
+}
+
+// ttl returns the TTL of flows to a port, 0 leaves the system default
+func (l *ttlLimits) ttl(port int) int {
+	if ttl, ok := l.ports[port]; ok {
+		return ttl
+	}
+	return l.global
+}
+
+// ttlControl returns a dialer control function setting the TTL of the socket
+func ttlControl(ttl int) controlFunc {
+	return func(network, address string, c syscall.RawConn) error {
+		if err := sockopt.SetTTL(network, c, ttl); err != nil {
+			return fmt.Errorf("failed to set TTL %d: %w", ttl, err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTTLLimits(t *testing.T) {
+	assert.Nil(t, newTTLLimits(&config.ClientConfig{}))
+
+	tests := []struct {
+		name string
+		cfg  config.ClientConfig
+		port int
+		want int
+	}{
+		{"global TTL", config.ClientConfig{TTL: 64}, 8080, 64},
+		{"port override", config.ClientConfig{TTL: 64, TTLPorts: "53=2"}, 53, 2},
+		{"unlisted port without global TTL", config.ClientConfig{TTLPorts: "53=2"}, 8080, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, newTTLLimits(&tt.cfg).ttl(tt.port))
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+)
+
+const (
+	// tuiRefreshInterval is how often the dashboard is redrawn
+	tuiRefreshInterval = time.Second
+	// tuiLogLines is the number of latest log entries shown below the statistics
+	tuiLogLines = 8
+)
+
+// ANSI escape sequences drawing the dashboard in place. Line wrapping is turned off while it is shown, so
+// long log entries are cut at the edge of the terminal instead of pushing the dashboard off screen.
+const (
+	ansiHome           = "\x1b[H"
+	ansiClearScreen    = "\x1b[2J"
+	ansiClearLine      = "\x1b[K"
+	ansiClearBelow     = "\x1b[J"
+	ansiEnterDashboard = "\x1b[?25l\x1b[?7l"
+	ansiLeaveDashboard = "\x1b[?25h\x1b[?7h"
+)
+
+// dashboardSnapshot is the state of the run shown in a frame of the dashboard
+type dashboardSnapshot struct {
+	at      time.Time
+	run     runStatus
+	metrics metrics.Summary
+	flows   flowOutcomeCounts
+	active  int
+}
+
+// logTail keeps the latest log entries written to it
+type logTail struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+// Write records the lines of a log entry, dropping the oldest beyond tuiLogLines
+func (l *logTail) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		l.lines = append(l.lines, line)
+	}
+	if len(l.lines) > tuiLogLines {
+		l.lines = slices.Clone(l.lines[len(l.lines)-tuiLogLines:])
+	}
+	return len(p), nil
+}
+
+// latest returns the recorded log lines, oldest first
+func (l *logTail) latest() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.lines)
+}
+
+// dashboard redraws the state of the run on the terminal every second, with the log entries diverted into
+// it, until it is closed
+type dashboard struct {
+	out     io.Writer
+	tracker *runTracker
+	slots   *flowSlots
+	logs    *logTail
+	restore func()
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	// last is the snapshot of the previous frame, the rates since then are computed from
+	last *dashboardSnapshot
+}
+
+// newDashboard shows the dashboard on stdout, or returns nil if stdout is not a terminal
+func newDashboard(t *runTracker, slots *flowSlots) *dashboard {
+	if info, err := os.Stdout.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		logging.Logger.Warn("Stdout is not a terminal, the dashboard is disabled")
+		return nil
+	}
+	return startDashboard(os.Stdout, t, slots)
+}
+
+// startDashboard starts drawing the dashboard to out, diverting the log entries into it
+func startDashboard(out io.Writer, t *runTracker, slots *flowSlots) *dashboard {
+	d := &dashboard{
+		out:     out,
+		tracker: t,
+		slots:   slots,
+		logs:    &logTail{},
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	d.restore = logging.Divert(d.logs)
+	_, _ = io.WriteString(out, ansiEnterDashboard+ansiClearScreen)
+	d.draw(time.Now())
+	go d.run()
+	return d
+}
+
+// run redraws the dashboard until it is closed
+func (d *dashboard) run() {
+	defer close(d.done)
+	ticker := time.NewTicker(tuiRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			d.draw(now)
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// close draws the final frame, which stays on the terminal, and hands the terminal back to the logs
+func (d *dashboard) close() {
+	if d == nil {
+		return
+	}
+	d.once.Do(func() {
+		close(d.stop)
+		<-d.done
+		// The final frame rates over the whole run rather than its last moments
+		d.last = nil
+		d.draw(time.Now())
+		_, _ = io.WriteString(d.out, ansiLeaveDashboard)
+		d.restore()
+	})
+}
+
+// draw takes a snapshot of the run and redraws the dashboard with it
+func (d *dashboard) draw(now time.Time) {
+	s := &dashboardSnapshot{
+		at:      now,
+		run:     d.tracker.status(now),
+		metrics: mc.Summary(),
+		flows:   outcomes.counts(),
+		active:  d.slots.inUse(),
+	}
+	var buf bytes.Buffer
+	buf.WriteString(ansiHome)
+	for _, line := range strings.Split(renderDashboard(s, d.last, d.logs.latest()), "\n") {
+		buf.WriteString(line + ansiClearLine + "\n")
+	}
+	buf.WriteString(ansiClearBelow)
+	// A single write, so the terminal never shows half a frame
+	_, _ = d.out.Write(buf.Bytes())
+	d.last = s
+}
+
+// renderDashboard renders a frame of the dashboard. The rates of finished flows and of the traffic are
+// those since the previous snapshot, or since the start of the run without one.
+func renderDashboard(s, prev *dashboardSnapshot, logs []string) string {
+	var b strings.Builder
+	title := "Flow Generator"
+	if s.run.Scenario != "" {
+		title += " - " + s.run.Scenario
+	}
+	fmt.Fprintf(&b, "%s    phase %s    elapsed %v", title, s.run.Phase, seconds(s.run.ElapsedSeconds).Round(time.Second))
+	if s.run.RemainingSeconds != nil {
+		fmt.Fprintf(&b, "    remaining %v", seconds(*s.run.RemainingSeconds).Round(time.Second))
+	}
+	b.WriteString("\n\n")
+
+	interval := s.run.ElapsedSeconds
+	var last flowOutcomeCounts
+	var lastSent, lastReceived uint64
+	if prev != nil {
+		interval = s.at.Sub(prev.at).Seconds()
+		last = prev.flows
+		lastSent, lastReceived = sumCounts(prev.metrics.BytesSent), sumCounts(prev.metrics.BytesReceived)
+	}
+	perSecond := func(current, previous uint64) float64 {
+		// Counts only go down if the metrics were reset
+		if interval <= 0 || current < previous {
+			return 0
+		}
+		return float64(current-previous) / interval
+	}
+
+	finished := s.flows.Completed + s.flows.Failed
+	var failedPct float64
+	if finished > 0 {
+		failedPct = float64(s.flows.Failed) / float64(finished) * 100
+	}
+	fmt.Fprintf(&b, "Flows     active %-8d started %-10d completed %-10d failed %d (%.1f%%)\n",
+		s.active, s.run.FlowsStarted, s.flows.Completed, s.flows.Failed, failedPct)
+	fmt.Fprintf(&b, "Rate      configured %.2f/s    effective %.2f/s    achieved %.2f/s\n",
+		s.run.ConfiguredRate, s.run.EffectiveRate, s.run.AchievedRate)
+	fmt.Fprintf(&b, "Finished  completed %.2f/s    failed %.2f/s\n",
+		perSecond(s.flows.Completed, last.Completed), perSecond(s.flows.Failed, last.Failed))
+	sent, received := sumCounts(s.metrics.BytesSent), sumCounts(s.metrics.BytesReceived)
+	fmt.Fprintf(&b, "Traffic   sent %s (%s/s)    received %s (%s/s)\n\n",
+		formatBytes(float64(sent)), formatBytes(perSecond(sent, lastSent)),
+		formatBytes(float64(received)), formatBytes(perSecond(received, lastReceived)))
+
+	fmt.Fprintf(&b, "%-10s %10s %10s %10s %10s %10s %10s %10s\n", "Latency", "count", "min ms", "mean ms", "p50 ms", "p90 ms", "p99 ms", "max ms")
+	protocols := slices.Sorted(maps.Keys(s.metrics.Latency))
+	if len(protocols) == 0 {
+		b.WriteString("no responses yet\n")
+	}
+	for _, protocol := range protocols {
+		l := s.metrics.Latency[protocol]
+		fmt.Fprintf(&b, "%-10s %10d %10.2f %10.2f %10.2f %10.2f %10.2f %10.2f\n",
+			protocol, l.Count, l.MinMs, l.MeanMs, l.P50Ms, l.P90Ms, l.P99Ms, l.MaxMs)
+	}
+
+	if len(logs) > 0 {
+		b.WriteString("\nLog\n")
+		for _, line := range logs {
+			b.WriteString(line + "\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// sumCounts adds up counts by protocol and port
+func sumCounts(m map[string]map[string]uint64) uint64 {
+	var total uint64
+	for _, ports := range m {
+		for _, n := range ports {
+			total += n
+		}
+	}
+	return total
+}
+
+// formatBytes formats a number of bytes with a decimal unit, e.g. "1.5 MB"
+func formatBytes(n float64) string {
+	units := []string{"B", "kB", "MB", "GB", "TB"}
+	i := 0
+	for n >= 1000 && i < len(units)-1 {
+		n /= 1000
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", n, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", n, units[i])
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/hooks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderDashboard(t *testing.T) {
+	remaining := 30.0
+	start := time.Now()
+	prev := &dashboardSnapshot{
+		at:      start,
+		flows:   flowOutcomeCounts{Completed: 10, Failed: 2},
+		metrics: metrics.Summary{BytesSent: map[string]map[string]uint64{"tcp": {"8080": 1000}}},
+	}
+	s := &dashboardSnapshot{
+		at: start.Add(2 * time.Second),
+		run: runStatus{
+			Scenario: "soak", Phase: phaseRunning, ElapsedSeconds: 30, RemainingSeconds: &remaining,
+			FlowsStarted: 40, ConfiguredRate: 10, EffectiveRate: 10, AchievedRate: 9.5,
+		},
+		metrics: metrics.Summary{
+			BytesSent:     map[string]map[string]uint64{"tcp": {"8080": 2000}, "udp": {"53": 2000}},
+			BytesReceived: map[string]map[string]uint64{"tcp": {"8080": 1500000}},
+			Latency: map[string]metrics.LatencySummary{
+				"udp": {Count: 5, MinMs: 0.5, MeanMs: 1, P50Ms: 1, P90Ms: 1.5, P99Ms: 2, MaxMs: 2.5},
+				"tcp": {Count: 30, MinMs: 1, MeanMs: 2, P50Ms: 2, P90Ms: 3, P99Ms: 4.25, MaxMs: 5},
+			},
+		},
+		flows:  flowOutcomeCounts{Completed: 30, Failed: 6},
+		active: 4,
+	}
+
+	frame := renderDashboard(s, prev, []string{"first entry", "second entry"})
+	assert.Contains(t, frame, "Flow Generator - soak    phase running    elapsed 30s    remaining 30s")
+	assert.Contains(t, frame, "active 4 ")
+	assert.Contains(t, frame, "started 40 ")
+	assert.Contains(t, frame, "failed 6 (16.7%)")
+	assert.Contains(t, frame, "achieved 9.50/s")
+	// Finished flows and traffic are rated over the 2s since the previous frame
+	assert.Contains(t, frame, "completed 10.00/s    failed 2.00/s")
+	assert.Contains(t, frame, "sent 4.0 kB (1.5 kB/s)")
+	assert.Contains(t, frame, "received 1.5 MB (750.0 kB/s)")
+	assert.Contains(t, frame, "4.25")
+	assert.Less(t, strings.Index(frame, "\ntcp "), strings.Index(frame, "\nudp "), "protocols are sorted")
+	assert.True(t, strings.HasSuffix(frame, "Log\nfirst entry\nsecond entry"))
+
+	// The first frame rates over the whole run and waits for responses
+	frame = renderDashboard(&dashboardSnapshot{run: runStatus{Phase: phaseRunning, ElapsedSeconds: 2}, flows: flowOutcomeCounts{Completed: 4}}, nil, nil)
+	assert.Contains(t, frame, "Flow Generator    phase running    elapsed 2s\n")
+	assert.Contains(t, frame, "completed 2.00/s")
+	assert.Contains(t, frame, "no responses yet")
+	assert.NotContains(t, frame, "Log")
+	assert.NotContains(t, frame, "remaining")
+}
+
+func TestLogTail(t *testing.T) {
+	var l logTail
+	for i := range tuiLogLines + 2 {
+		_, err := fmt.Fprintf(&l, "entry %d\n", i)
+		require.NoError(t, err)
+	}
+	lines := l.latest()
+	require.Len(t, lines, tuiLogLines)
+	assert.Equal(t, "entry 2", lines[0])
+	assert.Equal(t, fmt.Sprintf("entry %d", tuiLogLines+1), lines[tuiLogLines-1])
+
+	// Entries with a stack trace keep all their lines
+	_, _ = l.Write([]byte("error\nstack\n"))
+	lines = l.latest()
+	assert.Equal(t, []string{"error", "stack"}, lines[tuiLogLines-2:])
+}
+
+// lockedBuffer is a buffer the dashboard can draw to while the test reads it
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestDashboard(t *testing.T) {
+	oldMc, oldOutcomes := mc, outcomes
+	mc = metrics.NewMetricsCollector()
+	outcomes = &flowOutcomes{}
+	logging.InitLoggerWithTheme("human", "info", logging.ThemeClassic)
+	defer func() {
+		mc, outcomes = oldMc, oldOutcomes
+		logging.InitLogger("human", "info")
+	}()
+
+	flows := uint64(1)
+	tracker := newRunTracker(&config.ClientConfig{Scenario: "dashboard"}, time.Now(), &flows)
+	slots := newFlowSlots(2)
+	_, _ = slots.acquire(t.Context(), priorityHigh)
+	h := outcomes.hooks()
+	h.OnFlowFailed(hooks.Event{FlowID: 1, Err: errors.New("timeout")})
+
+	var out lockedBuffer
+	d := startDashboard(&out, tracker, slots)
+	logging.Logger.Info("shown on the dashboard")
+	d.close()
+	// Closing again is a no-op
+	d.close()
+	logging.Logger.Info("back on stderr")
+
+	frames := out.String()
+	assert.True(t, strings.HasPrefix(frames, ansiEnterDashboard+ansiClearScreen+ansiHome))
+	assert.True(t, strings.HasSuffix(frames, ansiClearBelow+ansiLeaveDashboard))
+	assert.Equal(t, 1, strings.Count(frames, ansiLeaveDashboard))
+	assert.Contains(t, frames, "Flow Generator - dashboard")
+	assert.Contains(t, frames, "active 1 ")
+	assert.Contains(t, frames, "failed 1 (100.0%)")
+	assert.Contains(t, frames, "shown on the dashboard")
+	assert.NotContains(t, frames, "back on stderr")
+
+	// A nil dashboard, when stdout is not a terminal, closes like an open one
+	var none *dashboard
+	none.close()
+}
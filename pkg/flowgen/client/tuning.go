@@ -0,0 +1,61 @@
+package client
+
+import (
+	"net"
+	"syscall"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
+)
+
+// socketTuning applies the TCP_NODELAY setting, the socket buffer sizes and the TCP keepalive settings to
+// the client sockets
+type socketTuning struct {
+	noDelay   bool
+	sndbuf    int
+	rcvbuf    int
+	keepAlive sockopt.KeepAlive
+}
+
+// newSocketTuning returns the socket tuning configured with tcp_nodelay, socket_sndbuf, socket_rcvbuf and
+// tcp_keepalive, or nil if sockets keep the Go and kernel defaults
+func newSocketTuning(c *config.ClientConfig) *socketTuning {
+	keepAlive := c.KeepAlive()
+	if c.TCPNoDelay && c.SocketSendBuffer == 0 && c.SocketReceiveBuffer == 0 && keepAlive == (sockopt.KeepAlive{}) {
+		return nil
+	}
+	return &socketTuning{noDelay: c.TCPNoDelay, sndbuf: c.SocketSendBuffer, rcvbuf: c.SocketReceiveBuffer, keepAlive: keepAlive}
+}
+
+// dialer adds the keepalive settings to d and the buffer sizes to its control function. The buffers are set
+// before connecting, so the TCP window scale is negotiated for the receive buffer.
+func (t *socketTuning) dialer(d *net.Dialer) *net.Dialer {
+	if t == nil {
+		return d
+	}
+	d.KeepAlive, d.KeepAliveConfig = t.keepAlive.Settings()
+	if t.sndbuf == 0 && t.rcvbuf == 0 {
+		return d
+	}
+	control := d.Control
+	d.Control = func(network, address string, c syscall.RawConn) error {
+		if control != nil {
+			if err := control(network, address, c); err != nil {
+				return err
+			}
+		}
+		return sockopt.SetBuffers(c, t.sndbuf, t.rcvbuf)
+	}
+	return d
+}
+
+// conn applies TCP_NODELAY to an established TCP connection, Go enables it on every new connection
+func (t *socketTuning) conn(conn net.Conn) error {
+	if t == nil {
+		return nil
+	}
+	if tc, ok := conn.(interface{ SetNoDelay(bool) error }); ok {
+		return tc.SetNoDelay(t.noDelay)
+	}
+	return nil
+}
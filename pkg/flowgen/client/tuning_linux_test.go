@@ -0,0 +1,48 @@
+package client
+
+import (
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestSocketTuning(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+
+	var controlled bool
+	tuning := &socketTuning{noDelay: false, sndbuf: 65536, rcvbuf: 131072, keepAlive: sockopt.KeepAlive{Idle: 300 * time.Second, Interval: 10 * time.Second, Count: 5}}
+	d := tuning.dialer(&net.Dialer{Control: func(network, address string, c syscall.RawConn) error {
+		controlled = true
+		return nil
+	}})
+	conn, err := d.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	assert.True(t, controlled, "the control function of the dialer still runs")
+	require.NoError(t, tuning.conn(conn))
+
+	raw, err := conn.(*net.TCPConn).SyscallConn()
+	require.NoError(t, err)
+	var snd, rcv, noDelay, keepIdle, keepIntvl, keepCnt int
+	require.NoError(t, raw.Control(func(fd uintptr) {
+		snd, _ = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_SNDBUF)
+		rcv, _ = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_RCVBUF)
+		noDelay, _ = unix.GetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_NODELAY)
+		keepIdle, _ = unix.GetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPIDLE)
+		keepIntvl, _ = unix.GetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPINTVL)
+		keepCnt, _ = unix.GetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPCNT)
+	}))
+	// Linux reports twice the requested size
+	assert.Equal(t, 2*65536, snd)
+	assert.Equal(t, 2*131072, rcv)
+	assert.Zero(t, noDelay)
+	assert.Equal(t, []int{300, 10, 5}, []int{keepIdle, keepIntvl, keepCnt})
+}
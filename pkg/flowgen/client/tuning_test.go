@@ -0,0 +1,29 @@
+package client
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSocketTuning(t *testing.T) {
+	assert.Nil(t, newSocketTuning(&config.ClientConfig{CommonConfig: config.CommonConfig{TCPNoDelay: true, TCPKeepAlive: true}}))
+	assert.Equal(t, &socketTuning{}, newSocketTuning(&config.ClientConfig{CommonConfig: config.CommonConfig{TCPKeepAlive: true}}))
+	assert.Equal(t, &socketTuning{noDelay: true, rcvbuf: 1 << 20},
+		newSocketTuning(&config.ClientConfig{CommonConfig: config.CommonConfig{TCPNoDelay: true, TCPKeepAlive: true, SocketReceiveBuffer: 1 << 20}}))
+	assert.Equal(t, &socketTuning{noDelay: true, keepAlive: sockopt.KeepAlive{Idle: 90 * time.Second, Count: 3}},
+		newSocketTuning(&config.ClientConfig{CommonConfig: config.CommonConfig{TCPNoDelay: true, TCPKeepAlive: true, TCPKeepAliveIdle: 90, TCPKeepAliveCount: 3}}))
+	assert.Equal(t, &socketTuning{noDelay: true, keepAlive: sockopt.KeepAlive{Disabled: true}},
+		newSocketTuning(&config.ClientConfig{CommonConfig: config.CommonConfig{TCPNoDelay: true}}))
+
+	// A nil tuning leaves dialers and connections as they are
+	var none *socketTuning
+	d := &net.Dialer{}
+	assert.Same(t, d, none.dialer(d))
+	assert.Nil(t, d.Control)
+	assert.NoError(t, none.conn(nil))
+}
@@ -0,0 +1,138 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/sockopt"
+)
+
+// sourcePortRange hands out the source ports of a range to flows in turn, so every flow gets its own source
+// port and thereby its own 5-tuple until the range wraps
+type sourcePortRange struct {
+	first int
+	size  uint64
+	next  atomic.Uint64
+}
+
+// newSourcePortRange returns the source ports configured with source_port_range, or nil if the kernel picks
+// the source ports of flows
+func newSourcePortRange(c *config.ClientConfig) *sourcePortRange {
+	if c.SourcePortRange == "" {
+		return nil
+	}
+	// The range was checked when the configuration was validated
+	first, last, _ := config.ParsePortRange(c.SourcePortRange)
+	return &sourcePortRange{first: first, size: uint64(last - first + 1)}
+}
+
+// port returns the source port of the next flow
+func (r *sourcePortRange) port() int {
+	return r.first + int((r.next.Add(1)-1)%r.size)
+}
+
+// String describes the range for the logs
+func (r *sourcePortRange) String() string {
+	return fmt.Sprintf("%d-%d (%d ports)", r.first, r.first+int(r.size)-1, r.size)
+}
+
+// withSourcePort returns the local address of a dialer with its port set to port, keeping the source address
+// the dialer is bound to if any
+func withSourcePort(network string, local net.Addr, port int) net.Addr {
+	if strings.HasPrefix(network, "udp") {
+		addr := &net.UDPAddr{Port: port}
+		if l, ok := local.(*net.UDPAddr); ok && l != nil {
+			addr.IP, addr.Zone = l.IP, l.Zone
+		}
+		return addr
+	}
+	addr := &net.TCPAddr{Port: port}
+	if l, ok := local.(*net.TCPAddr); ok && l != nil {
+		addr.IP, addr.Zone = l.IP, l.Zone
+	}
+	return addr
+}
+
+// reuseSourcePortControl lets a flow bind a source port still held in TIME_WAIT by an earlier flow, which the
+// range wraps around to sooner than the kernel releases the port
+func reuseSourcePortControl(network, address string, c syscall.RawConn) error {
+	if err := sockopt.ReusePort(network, address, c); err != nil && !errors.Is(err, sockopt.ErrUnsupported) {
+		return fmt.Errorf("failed to reuse source port: %w", err)
+	}
+	return nil
+}
+
+// maxTrackedTuples bounds a generation of the tuple tracker, two generations of 5-tuples take around 200 MB
+const maxTrackedTuples = 1 << 20
+
+// tuple is the 5-tuple of a flow, with the flow's protocol name standing in for the transport protocol
+type tuple struct {
+	protocol      string
+	local, remote netip.AddrPort
+}
+
+// tupleTracker counts the flows whose 5-tuple was not used by a recent flow. It remembers the tuples of
+// the current and the previous generation, and starts a new generation when the current one is full, so
+// memory stays bounded in long runs while tuples are still recognized across the generation boundary.
+type tupleTracker struct {
+	mu       sync.Mutex
+	current  map[tuple]struct{}
+	previous map[tuple]struct{}
+	limit    int
+}
+
+// newTupleTracker returns the tuple tracker if track_tuples is enabled, or nil
+func newTupleTracker(c *config.ClientConfig) *tupleTracker {
+	if !c.TrackTuples {
+		return nil
+	}
+	return &tupleTracker{current: make(map[tuple]struct{}), limit: maxTrackedTuples}
+}
+
+// observe records the tuple of a flow connected from local to remote, and reports whether the tuple was
+// not used by a recent flow. Flows without known addresses are never unique.
+func (t *tupleTracker) observe(protocol string, local, remote net.Addr) bool {
+	l, lok := addrPort(local)
+	r, rok := addrPort(remote)
+	if !lok || !rok {
+		return false
+	}
+	key := tuple{protocol: protocol, local: l, remote: r}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.current[key]; ok {
+		return false
+	}
+	if _, ok := t.previous[key]; ok {
+		t.current[key] = struct{}{}
+		return false
+	}
+	if len(t.current) >= t.limit {
+		t.previous, t.current = t.current, make(map[tuple]struct{})
+	}
+	t.current[key] = struct{}{}
+	return true
+}
+
+// addrPort returns the address and port of a TCP or UDP address
+func addrPort(addr net.Addr) (netip.AddrPort, bool) {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		if a != nil {
+			return a.AddrPort(), true
+		}
+	case *net.UDPAddr:
+		if a != nil {
+			return a.AddrPort(), true
+		}
+	}
+	return netip.AddrPort{}, false
+}
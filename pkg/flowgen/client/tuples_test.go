@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourcePortRange(t *testing.T) {
+	assert.Nil(t, newSourcePortRange(&config.ClientConfig{}))
+
+	r := newSourcePortRange(&config.ClientConfig{SourcePortRange: "40000-40002"})
+	require.NotNil(t, r)
+	var ports []int
+	for range 5 {
+		ports = append(ports, r.port())
+	}
+	assert.Equal(t, []int{40000, 40001, 40002, 40000, 40001}, ports)
+	assert.Equal(t, "40000-40002 (3 ports)", r.String())
+}
+
+func TestWithSourcePort(t *testing.T) {
+	assert.Equal(t, &net.TCPAddr{Port: 40000}, withSourcePort("tcp", nil, 40000))
+	assert.Equal(t, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 5), Port: 40000},
+		withSourcePort("udp4", &net.UDPAddr{IP: net.IPv4(10, 0, 0, 5)}, 40000))
+	assert.Equal(t, &net.TCPAddr{IP: net.IPv6loopback, Port: 40001},
+		withSourcePort("tcp6", &net.TCPAddr{IP: net.IPv6loopback}, 40001))
+}
+
+func TestTupleTracker(t *testing.T) {
+	assert.Nil(t, newTupleTracker(&config.ClientConfig{}))
+
+	tr := newTupleTracker(&config.ClientConfig{TrackTuples: true})
+	tr.limit = 2
+	server := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080}
+	local := func(port int) net.Addr { return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port} }
+
+	assert.True(t, tr.observe("tcp", local(40000), server))
+	assert.False(t, tr.observe("tcp", local(40000), server))
+	// The same addresses with another protocol are another tuple
+	assert.True(t, tr.observe("tcp_churn", local(40000), server))
+	// A full generation is kept as the previous one, whose tuples are still recognized
+	assert.True(t, tr.observe("tcp", local(40001), server))
+	assert.False(t, tr.observe("tcp", local(40000), server))
+	assert.True(t, tr.observe("tcp", local(40002), server))
+	// Tuples older than the previous generation are forgotten
+	assert.True(t, tr.observe("tcp_churn", local(40000), server))
+
+	assert.False(t, tr.observe("tcp", nil, server))
+}
+
+func TestGenerateFlowSourcePorts(t *testing.T) {
+	logging.InitLogger("json", "error")
+
+	oldCfg, oldMc, oldPorts, oldTuples := cfg, mc, sourcePorts, tuples
+	cfg = &config.ClientConfig{PayloadSize: 64, SourcePortRange: "41000-41001", TrackTuples: true}
+	mc = metrics.NewMetricsCollector()
+	sourcePorts = newSourcePortRange(cfg)
+	tuples = newTupleTracker(cfg)
+	defer func() { cfg, mc, sourcePorts, tuples = oldCfg, oldMc, oldPorts, oldTuples }()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+	peers := make(chan int, 3)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			peers <- conn.RemoteAddr().(*net.TCPAddr).Port
+			go func() {
+				defer func() { _ = conn.Close() }()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	// The third flow wraps around to the source port of the first one, whose tuple is not unique
+	for i := range 3 {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		generateFlow(context.Background(), uint64(i+1), "127.0.0.1", ProtocolPort{"tcp_churn_rst", port}, 10, rand.New(rand.NewPCG(0, 0)), 1500, 1460, &wg)
+	}
+	assert.Equal(t, 41000, <-peers)
+	assert.Equal(t, 41001, <-peers)
+	assert.Equal(t, 41000, <-peers)
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.UniqueTuples.WithLabelValues("tcp_churn_rst")))
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"sync"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+)
+
+// defaultUDPTimeout is the response timeout of UDP requests before the first round trip to a target was
+// measured, and of all requests if no timeouts are configured
+const defaultUDPTimeout = time.Second
+
+// rttEstimate is the smoothed round trip time of a target and its variation, as in RFC 6298
+type rttEstimate struct {
+	srtt   time.Duration
+	rttvar time.Duration
+	// timeout is the current response timeout, derived from the estimate or backed off after a timeout
+	timeout time.Duration
+}
+
+// udpTimeouts derives the response timeout of UDP requests from the round trips measured per target,
+// clamped to the udp_timeout_min and udp_timeout_max settings, so requests to fast targets do not wait
+// out the worst case and slow links are given the time they need
+type udpTimeouts struct {
+	mu      sync.Mutex
+	min     time.Duration
+	max     time.Duration
+	targets map[string]*rttEstimate
+}
+
+// newUDPTimeouts returns the response timeouts configured with udp_timeout_min and udp_timeout_max, or nil
+// if udp_timeout_max is 0 and every request waits one second
+func newUDPTimeouts(c *config.ClientConfig) *udpTimeouts {
+	if c.UDPTimeoutMax == 0 {
+		return nil
+	}
+	return &udpTimeouts{min: seconds(c.UDPTimeoutMin), max: seconds(c.UDPTimeoutMax), targets: make(map[string]*rttEstimate)}
+}
+
+// timeout returns the response timeout of the next request to target. A nil policy always waits one second.
+func (u *udpTimeouts) timeout(target string) time.Duration {
+	if u == nil {
+		return defaultUDPTimeout
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if e, ok := u.targets[target]; ok {
+		return e.timeout
+	}
+	return u.clamp(defaultUDPTimeout)
+}
+
+// observe updates the estimate of target with a measured round trip
+func (u *udpTimeouts) observe(target string, rtt time.Duration) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	e, ok := u.targets[target]
+	if !ok {
+		e = &rttEstimate{srtt: rtt, rttvar: rtt / 2}
+		u.targets[target] = e
+	} else {
+		e.rttvar = (3*e.rttvar + (e.srtt - rtt).Abs()) / 4
+		e.srtt = (7*e.srtt + rtt) / 8
+	}
+	e.timeout = u.clamp(e.srtt + 4*e.rttvar)
+}
+
+// expired doubles the timeout of target after a request timed out, until the next round trip is measured
+func (u *udpTimeouts) expired(target string) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if e, ok := u.targets[target]; ok {
+		e.timeout = u.clamp(2 * e.timeout)
+	}
+}
+
+// clamp limits a timeout to the configured bounds
+func (u *udpTimeouts) clamp(d time.Duration) time.Duration {
+	return min(max(d, u.min), u.max)
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUDPTimeouts(t *testing.T) {
+	u := newUDPTimeouts(&config.ClientConfig{UDPTimeoutMin: 0.05, UDPTimeoutMax: 3})
+	require.NotNil(t, u)
+	assert.Equal(t, time.Second, u.timeout("10.0.0.1:53"), "targets without round trips wait the default")
+
+	// The first sample sets the variation to half the round trip: 10ms + 4*5ms, raised to the minimum
+	u.observe("10.0.0.1:53", 10*time.Millisecond)
+	assert.Equal(t, 50*time.Millisecond, u.timeout("10.0.0.1:53"))
+
+	// Slow targets get a longer timeout, other targets keep theirs
+	u.observe("10.0.0.2:53", 400*time.Millisecond)
+	assert.Equal(t, 1200*time.Millisecond, u.timeout("10.0.0.2:53"))
+	assert.Equal(t, 50*time.Millisecond, u.timeout("10.0.0.1:53"))
+
+	// A steady round trip shrinks the variation
+	for range 50 {
+		u.observe("10.0.0.2:53", 400*time.Millisecond)
+	}
+	assert.InDelta(t, 400*time.Millisecond, u.timeout("10.0.0.2:53"), float64(5*time.Millisecond))
+
+	// Timeouts back off up to the maximum, the next round trip restores the estimate
+	u.expired("10.0.0.2:53")
+	assert.InDelta(t, 800*time.Millisecond, u.timeout("10.0.0.2:53"), float64(10*time.Millisecond))
+	for range 5 {
+		u.expired("10.0.0.2:53")
+	}
+	assert.Equal(t, 3*time.Second, u.timeout("10.0.0.2:53"))
+	u.observe("10.0.0.2:53", 400*time.Millisecond)
+	assert.Less(t, u.timeout("10.0.0.2:53"), time.Second)
+
+	// Unknown targets are not backed off
+	u.expired("10.0.0.3:53")
+	assert.Equal(t, time.Second, u.timeout("10.0.0.3:53"))
+}
+
+func TestUDPTimeoutsDisabled(t *testing.T) {
+	u := newUDPTimeouts(&config.ClientConfig{})
+	assert.Nil(t, u)
+	u.observe("10.0.0.1:53", time.Millisecond)
+	u.expired("10.0.0.1:53")
+	assert.Equal(t, time.Second, u.timeout("10.0.0.1:53"))
+}
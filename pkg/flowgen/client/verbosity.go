@@ -0,0 +1,58 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Flow verbosities selecting what is logged about individual flows with flow_verbosity. They are independent
+// of the log level, flow entries are written to logging.Flows which ignores it.
+const (
+	// verbosityNone logs nothing about individual flows, apart from sampled flows
+	verbosityNone = iota
+	// verbosityFailures logs why flows failed
+	verbosityFailures
+	// verbositySummaries logs the start and end of every flow as well
+	verbositySummaries
+	// verbosityDetail logs every flow in full, as if it was sampled with debug_sample_flows
+	verbosityDetail
+)
+
+// flowVerbosity returns the configured flow verbosity
+func flowVerbosity() int {
+	if cfg == nil {
+		return verbosityNone
+	}
+	return cfg.FlowVerbosity
+}
+
+// logFlowFailure logs why a flow failed if failures are logged
+func logFlowFailure(flowID uint64, protocol, template string, args ...any) {
+	if flowVerbosity() >= verbosityFailures {
+		logFlow(zapcore.WarnLevel, flowID, protocol, template, args)
+	}
+}
+
+// logFlowSummary logs the start or end of a flow if summaries are logged or the flow is sampled
+func logFlowSummary(flowID uint64, protocol string, sampled bool, template string, args ...any) {
+	if sampled || flowVerbosity() >= verbositySummaries {
+		logFlow(zapcore.InfoLevel, flowID, protocol, template, args)
+	}
+}
+
+// logFlowDetail logs a detail of a sampled flow
+func logFlowDetail(flowID uint64, protocol string, sampled bool, template string, args ...any) {
+	if sampled {
+		logFlow(zapcore.InfoLevel, flowID, protocol, template, args)
+	}
+}
+
+// logFlow writes an entry about a flow to the flow logger, identifying the flow and its protocol by fields
+// and the caller by the call site of the log helper
+func logFlow(level zapcore.Level, flowID uint64, protocol, template string, args []any) {
+	logging.Flows.Desugar().WithOptions(zap.AddCallerSkip(2)).Log(level, fmt.Sprintf(template, args...),
+		zap.Uint64(logging.FlowKey, flowID), zap.String(logging.ProtocolKey, protocol))
+}
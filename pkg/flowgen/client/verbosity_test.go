@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestFlowVerbosity(t *testing.T) {
+	// The log level does not affect the flow entries
+	logging.InitLogger("json", "error")
+	oldCfg, oldMc, oldFlows := cfg, mc, logging.Flows
+	mc = metrics.NewMetricsCollector()
+	defer func() { cfg, mc, logging.Flows = oldCfg, oldMc, oldFlows }()
+
+	echo := serveTCP(t, func(conn net.Conn) { _, _ = io.Copy(conn, conn) })
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closed := ln.Addr().(*net.TCPAddr).Port
+	require.NoError(t, ln.Close())
+
+	tests := []struct {
+		verbosity int
+		// want are the messages logged for a successful and a failed flow
+		want []string
+	}{
+		{verbosityNone, nil},
+		{verbosityFailures, []string{"Failed to connect"}},
+		{verbositySummaries, []string{"Starting", "ended", "Starting", "Failed to connect"}},
+		{verbosityDetail, []string{"Starting", "TCP connection", "sent", "received", "ended", "Starting", "Failed to connect"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("verbosity %d", tt.verbosity), func(t *testing.T) {
+			core, recorded := observer.New(zapcore.DebugLevel)
+			logging.Flows = zap.New(core).Sugar()
+			cfg = &config.ClientConfig{PayloadSize: 64, FlowVerbosity: tt.verbosity}
+
+			for i, port := range []int{echo, closed} {
+				var wg sync.WaitGroup
+				wg.Add(1)
+				generateFlow(context.Background(), uint64(i+1), "127.0.0.1", ProtocolPort{"tcp", port}, 0.05, rand.New(rand.NewPCG(0, 0)), 1500, 1460, &wg)
+			}
+
+			var got []string
+			for _, entry := range recorded.All() {
+				for _, kind := range []string{"Starting", "TCP connection", "sent", "received", "ended", "Failed to connect"} {
+					if strings.Contains(entry.Message, kind) {
+						got = append(got, kind)
+						break
+					}
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
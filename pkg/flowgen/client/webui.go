@@ -0,0 +1,165 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/webui"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/hooks"
+)
+
+// Paths of the live stats endpoint and the web UI on the status server
+const (
+	statsPath = "/stats"
+	webUIPath = "/ui"
+)
+
+// flowOutcomes counts finished flows by result, fed by the flow hooks
+type flowOutcomes struct {
+	completed atomic.Uint64
+	failed    atomic.Uint64
+}
+
+// hooks returns the flow hooks that count finished flows. Flows finishing during the warmup are not counted.
+func (o *flowOutcomes) hooks() hooks.Hooks {
+	return hooks.Hooks{
+		OnFlowCompleted: func(hooks.Event) {
+			if !warmingUp() {
+				o.completed.Add(1)
+			}
+		},
+		OnFlowFailed: func(hooks.Event) {
+			if !warmingUp() {
+				o.failed.Add(1)
+			}
+		},
+	}
+}
+
+// warmingUp reports whether the run is still in its warmup
+func warmingUp() bool {
+	return mc != nil && mc.WarmingUp()
+}
+
+// counts returns the number of flows finished so far, zero if outcomes are not counted
+func (o *flowOutcomes) counts() flowOutcomeCounts {
+	if o == nil {
+		return flowOutcomeCounts{}
+	}
+	return flowOutcomeCounts{Completed: o.completed.Load(), Failed: o.failed.Load()}
+}
+
+// flowOutcomeCounts is the number of finished flows by result
+type flowOutcomeCounts struct {
+	Completed uint64 `json:"completed"`
+	Failed    uint64 `json:"failed"`
+}
+
+// liveStats is the snapshot of the run served on the stats endpoint. Control tells the web UI whether the
+// control API is enabled.
+type liveStats struct {
+	streamStats
+	Flows flowOutcomeCounts `json:"flows"`
+	// ActiveFlows is the number of flows running at the time of the snapshot
+	ActiveFlows int  `json:"active_flows"`
+	Control     bool `json:"control"`
+}
+
+// statsHandler serves snapshots of the run, polled by the web UI
+type statsHandler struct {
+	tracker  *runTracker
+	outcomes *flowOutcomes
+	slots    *flowSlots
+	control  bool
+}
+
+// ServeHTTP reports the run status, metrics and finished flows as JSON
+func (h *statsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	stats := liveStats{
+		streamStats: newStreamStats("stats", h.tracker, time.Now()),
+		Flows:       h.outcomes.counts(),
+		ActiveFlows: h.slots.inUse(),
+		Control:     h.control,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		logging.Logger.Debugf("Failed to write stats: %v", err)
+	}
+}
+
+// webUIPage polls the stats endpoint every second and charts the last minutes of the run
+var webUIPage = webui.Page("Flow Generator", `#controls { margin-top: 1em; }
+#controls input { width: 6em; }
+#message { margin-left: 1em; color: #b00020; }
+`, `<div id="controls" hidden>
+<button id="pause">Pause</button>
+<button id="resume">Resume</button>
+<input id="rate" type="number" min="0" step="any" placeholder="flows/s">
+<button id="setRate">Set rate</button>
+<span id="message"></span>
+</div>
+`+webui.Charts(
+	webui.Chart("rateChart", "Flow rate (flows/s)"),
+	webui.Chart("throughputChart", "Throughput (Mbit/s)"),
+	webui.Chart("errorChart", "Flows per second by result"),
+	webui.Chart("activeChart", "Active flows"),
+	webui.Chart("latencyChart", "Round-trip latency (ms)"),
+), `var last = null;
+
+function update(stats) {
+  var run = stats.run, m = stats.metrics;
+  var now = {
+    time: Date.parse(stats.time) / 1000,
+    started: run.flows_started,
+    completed: stats.flows.completed,
+    failed: stats.flows.failed,
+    bytes: total(m.bytes_sent) + total(m.bytes_received)
+  };
+  if (last && now.time > last.time) {
+    var dt = now.time - last.time;
+    push("rateChart", "started", (now.started - last.started) / dt);
+    push("rateChart", "effective", run.effective_rate);
+    push("throughputChart", "sent + received", (now.bytes - last.bytes) * 8 / dt / 1e6);
+    push("errorChart", "completed", (now.completed - last.completed) / dt);
+    push("errorChart", "failed", (now.failed - last.failed) / dt);
+    for (var protocol in m.latency || {}) {
+      push("latencyChart", protocol + " p50", m.latency[protocol].p50_ms);
+      push("latencyChart", protocol + " p99", m.latency[protocol].p99_ms);
+    }
+    push("activeChart", "active", stats.active_flows);
+    ["rateChart", "throughputChart", "errorChart", "activeChart", "latencyChart"].forEach(draw);
+  }
+  last = now;
+
+  var status = "<span>Scenario: <b>" + run.scenario + "</b></span><span>Phase: <b>" + run.phase + "</b></span>" +
+    "<span>Elapsed: " + run.elapsed_seconds.toFixed(0) + "s</span><span>Flows started: " + run.flows_started + "</span>" +
+    "<span>Failed: " + stats.flows.failed + "</span><span>Configured rate: " + run.configured_rate.toFixed(2) + " flows/s</span>";
+  document.getElementById("status").innerHTML = status;
+  document.getElementById("controls").hidden = !stats.control;
+}
+
+function poll() {
+  fetch("stats").then(function(r) { return r.json(); }).then(update).catch(function() {
+    document.getElementById("status").textContent = "disconnected, the run may have ended";
+  });
+}
+
+function control(action, query) {
+  fetch("control/" + action + (query || ""), {method: "POST"}).then(function(r) {
+    return r.text().then(function(text) {
+      document.getElementById("message").textContent = r.ok ? "" : text;
+    });
+  });
+}
+
+document.getElementById("pause").onclick = function() { control("pause"); };
+document.getElementById("resume").onclick = function() { control("resume"); };
+document.getElementById("setRate").onclick = function() {
+  control("rate", "?rate=" + encodeURIComponent(document.getElementById("rate").value));
+};
+poll();
+setInterval(poll, 1000);
+`)
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/internal/webui"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/hooks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsHandler(t *testing.T) {
+	oldMc := mc
+	mc = metrics.NewMetricsCollector()
+	defer func() { mc = oldMc }()
+
+	flows := uint64(3)
+	tracker := newRunTracker(&config.ClientConfig{Scenario: "soak"}, time.Now(), &flows)
+	outcomes := &flowOutcomes{}
+	h := outcomes.hooks()
+	h.OnFlowCompleted(hooks.Event{FlowID: 1})
+	h.OnFlowCompleted(hooks.Event{FlowID: 2})
+	h.OnFlowFailed(hooks.Event{FlowID: 3, Err: errors.New("timeout")})
+
+	slots := newFlowSlots(4)
+	_, _ = slots.acquire(t.Context(), priorityHigh)
+	_, _ = slots.acquire(t.Context(), priorityHigh)
+
+	rec := httptest.NewRecorder()
+	(&statsHandler{tracker: tracker, outcomes: outcomes, slots: slots, control: true}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, statsPath, nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var stats liveStats
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	assert.Equal(t, "stats", stats.Type)
+	assert.Equal(t, "soak", stats.Run.Scenario)
+	assert.Equal(t, uint64(3), stats.Run.FlowsStarted)
+	assert.Equal(t, flowOutcomeCounts{Completed: 2, Failed: 1}, stats.Flows)
+	assert.Equal(t, 2, stats.ActiveFlows)
+	assert.True(t, stats.Control)
+}
+
+func TestServeWebUI(t *testing.T) {
+	rec := httptest.NewRecorder()
+	webui.Handler(webUIPage).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, webUIPath, nil))
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), `<svg id="activeChart">`)
+	assert.Contains(t, rec.Body.String(), `stats.active_flows`)
+	assert.Contains(t, rec.Body.String(), `fetch("stats")`)
+	assert.Contains(t, rec.Body.String(), `"control/" + action`)
+}
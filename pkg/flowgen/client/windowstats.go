@@ -0,0 +1,112 @@
+package client
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/hooks"
+)
+
+// windows holds the windowed statistics of the phases and the soft limits configured for the run, which
+// the flows record their round trips and echoed bytes in
+var windows []*windowedStats
+
+// recordLatency records the round trip of a request answered at the given time in every windowed statistic
+func recordLatency(at time.Time, rtt time.Duration) {
+	for _, w := range windows {
+		w.observeLatency(at, rtt)
+	}
+}
+
+// recordBytesReceived counts bytes echoed at the given time in every windowed statistic
+func recordBytesReceived(at time.Time, n int) {
+	for _, w := range windows {
+		w.addBytesReceived(at, n)
+	}
+}
+
+// flowStats holds what was measured within a window of the run
+type flowStats struct {
+	completed uint64
+	failed    uint64
+	received  uint64
+	// latencies is a uniform sample of the round trips
+	latencies *metrics.Reservoir
+}
+
+// newFlowStats returns empty statistics keeping up to samples round trips
+func newFlowStats(samples int) flowStats {
+	return flowStats{latencies: metrics.NewReservoir(samples)}
+}
+
+// reset empties the statistics, keeping the memory of the round trip sample
+func (s *flowStats) reset() {
+	*s = flowStats{latencies: s.latencies}
+	s.latencies.Reset()
+}
+
+// p99Latency returns the nearest-rank p99 of the round trips sampled in the given statistics, as for the
+// latency of the whole run, and false if none were sampled
+func p99Latency(stats ...*flowStats) (time.Duration, bool) {
+	var sorted []time.Duration
+	for _, s := range stats {
+		sorted = append(sorted, s.latencies.Samples()...)
+	}
+	if len(sorted) == 0 {
+		return 0, false
+	}
+	slices.Sort(sorted)
+	return metrics.Percentile(sorted, 0.99), true
+}
+
+// windowedStats records the flows finished, the round trips measured and the bytes echoed into the
+// statistics of the window of the run they happened in. The phases and the soft limits split the run into
+// windows differently and record alike.
+type windowedStats struct {
+	mu sync.Mutex
+	// statsAt returns the statistics of the window the given time falls into, nil if it falls into none.
+	// It is called with mu held.
+	statsAt func(t time.Time) *flowStats
+}
+
+// hooks returns the flow hooks counting the flows finished within each window
+func (w *windowedStats) hooks() hooks.Hooks {
+	return hooks.Hooks{
+		OnFlowCompleted: func(e hooks.Event) { w.observeFlow(e.Time, false) },
+		OnFlowFailed:    func(e hooks.Event) { w.observeFlow(e.Time, true) },
+	}
+}
+
+// observeFlow counts a flow finished at the given time
+func (w *windowedStats) observeFlow(at time.Time, failed bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := w.statsAt(at)
+	switch {
+	case s == nil:
+	case failed:
+		s.failed++
+	default:
+		s.completed++
+	}
+}
+
+// observeLatency records the round trip of a request answered at the given time
+func (w *windowedStats) observeLatency(at time.Time, rtt time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if s := w.statsAt(at); s != nil {
+		s.latencies.Observe(rtt)
+	}
+}
+
+// addBytesReceived counts bytes echoed at the given time
+func (w *windowedStats) addBytesReceived(at time.Time, n int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if s := w.statsAt(at); s != nil {
+		s.received += uint64(n)
+	}
+}
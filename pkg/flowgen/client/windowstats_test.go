@@ -0,0 +1,62 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/hooks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindowedStats(t *testing.T) {
+	start := time.Unix(1000, 0)
+	// Two windows of a second each from the start
+	stats := []flowStats{newFlowStats(10), newFlowStats(10)}
+	w := &windowedStats{statsAt: func(at time.Time) *flowStats {
+		i := int(at.Sub(start) / time.Second)
+		if i < 0 || i >= len(stats) {
+			return nil
+		}
+		return &stats[i]
+	}}
+
+	h := w.hooks()
+	h.OnFlowCompleted(hooks.Event{Time: start.Add(100 * time.Millisecond)})
+	h.OnFlowFailed(hooks.Event{Time: start.Add(1500 * time.Millisecond)})
+	h.OnFlowFailed(hooks.Event{Time: start.Add(5 * time.Second)})
+	w.observeLatency(start, 5*time.Millisecond)
+	w.observeLatency(start.Add(-time.Second), time.Second)
+	w.addBytesReceived(start.Add(1200*time.Millisecond), 100)
+
+	assert.Equal(t, uint64(1), stats[0].completed)
+	assert.Zero(t, stats[0].failed)
+	assert.Equal(t, uint64(1), stats[1].failed)
+	assert.Equal(t, uint64(100), stats[1].received)
+	p99, ok := p99Latency(&stats[0])
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Millisecond, p99)
+	_, ok = p99Latency(&stats[1])
+	assert.False(t, ok)
+
+	stats[0].reset()
+	assert.Zero(t, stats[0].completed)
+	assert.Zero(t, stats[0].latencies.Observed())
+}
+
+func TestRecordLatency(t *testing.T) {
+	old := windows
+	defer func() { windows = old }()
+
+	a, b := newFlowStats(10), newFlowStats(10)
+	windows = []*windowedStats{
+		{statsAt: func(time.Time) *flowStats { return &a }},
+		{statsAt: func(time.Time) *flowStats { return &b }},
+	}
+	recordLatency(time.Now(), time.Millisecond)
+	recordBytesReceived(time.Now(), 42)
+
+	for _, s := range []flowStats{a, b} {
+		assert.Equal(t, uint64(1), s.latencies.Observed())
+		assert.Equal(t, uint64(42), s.received)
+	}
+}
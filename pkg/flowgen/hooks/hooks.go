@@ -0,0 +1,68 @@
+// Package hooks lets programs embedding the flow generator observe the individual flows of the client,
+// for example to feed flow outcomes into an external test harness. Hooks are registered with Register,
+// typically from an init function, and are called for the flows of every run of the client executed with
+// client.NewCommand.
+package hooks
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// Event describes a flow at one point of its lifecycle
+type Event struct {
+	FlowID   uint64
+	Protocol string
+	Port     int
+	// Duration is the planned duration of the flow
+	Duration time.Duration
+	// Time is when the event happened
+	Time time.Time
+	// Elapsed is the time since the flow started, it is zero for scheduled flows
+	Elapsed       time.Duration
+	Requests      uint64
+	BytesSent     uint64
+	BytesReceived uint64
+	// Latency is the mean round-trip time of the answered requests
+	Latency time.Duration
+	// LocalAddr and RemoteAddr are the endpoints of the flow if its transport exposes them
+	LocalAddr  net.Addr
+	RemoteAddr net.Addr
+	// FlowLabel is the IPv6 flow label the flow was sent with, 0 if none was set
+	FlowLabel uint32
+	// Err is the reason a flow failed, it is nil for other events
+	Err error
+}
+
+// Hooks are callbacks invoked for flow lifecycle events, unset callbacks are skipped.
+// Hooks run sequentially in event order on a dedicated goroutine of the client, off the flow hot path: a
+// slow hook delays later hooks but never flow generation. If hooks fall behind too far, further events are
+// dropped and counted. A panicking hook is logged and does not abort the run.
+type Hooks struct {
+	// OnFlowScheduled is called when a flow is handed off to its goroutine
+	OnFlowScheduled func(Event)
+	// OnFlowCompleted is called when a flow ended after a clean exchange
+	OnFlowCompleted func(Event)
+	// OnFlowFailed is called when a flow could not connect or its exchange failed
+	OnFlowFailed func(Event)
+}
+
+var (
+	mu         sync.RWMutex
+	registered []Hooks
+)
+
+// Register adds hooks invoked for the flows of every run. It must be called before the run starts.
+func Register(h Hooks) {
+	mu.Lock()
+	defer mu.Unlock()
+	registered = append(registered, h)
+}
+
+// Registered returns the registered hooks in the order they were registered
+func Registered() []Hooks {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]Hooks(nil), registered...)
+}
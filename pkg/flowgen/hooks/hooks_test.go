@@ -0,0 +1,24 @@
+package hooks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	var failed []uint64
+	Register(Hooks{OnFlowFailed: func(e Event) { failed = append(failed, e.FlowID) }})
+	Register(Hooks{})
+
+	registered := Registered()
+	require.Len(t, registered, 2)
+	assert.Nil(t, registered[0].OnFlowScheduled)
+	registered[0].OnFlowFailed(Event{FlowID: 7})
+	assert.Equal(t, []uint64{7}, failed)
+
+	// The returned slice is a copy
+	registered[0] = Hooks{}
+	assert.NotNil(t, Registered()[0].OnFlowFailed)
+}
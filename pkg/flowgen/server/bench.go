@@ -0,0 +1,34 @@
+package server
+
+import (
+	"io"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/bench"
+
+	"github.com/spf13/cobra"
+)
+
+// newBenchCmd builds the bench subcommand, which checks the performance of the server on the host before
+// its measurements are trusted
+func newBenchCmd() *cobra.Command {
+	var filter string
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Run micro-benchmarks of the metrics and handlers on this host",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBench(cmd.OutOrStdout(), filter)
+		},
+	}
+	cmd.Flags().StringVar(&filter, "filter", "", "Only run the benchmarks whose name matches this regular expression")
+	return cmd
+}
+
+// runBench runs the standard benchmarks matching filter and prints the results
+func runBench(w io.Writer, filter string) error {
+	results, err := bench.Run(bench.Standard(), filter)
+	if err != nil {
+		return err
+	}
+	return bench.Print(w, results)
+}
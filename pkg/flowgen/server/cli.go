@@ -0,0 +1,104 @@
+// Package server is the echo server, which answers the flows of flow generator clients. NewCommand builds
+// the command line of the echo-server binary, so that programs can run the server with their own services
+// registered with the service package.
+package server
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/echoserver"
+	"github.com/PhilipSchmid/flow-generator-app/internal/version"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCommand builds the echo-server command line, which the echo-server binary executes.
+// Running it without a subcommand starts the server, like the run subcommand, until it is terminated.
+// Programs linking their own services into the server execute it from their main function. The
+// configuration flags are defined on the global flag set, so it is built once per process.
+func NewCommand() *cobra.Command {
+	// Configuration flags live on the global flag set so that viper can bind them
+	defineFlags(pflag.CommandLine)
+
+	var showVersion, dryRunOnly bool
+	root := &cobra.Command{
+		Use:          "echo-server",
+		Short:        "Echo TCP and UDP traffic back to flow generator clients",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if showVersion {
+				printVersion(cmd.OutOrStdout())
+				return nil
+			}
+			if dryRunOnly {
+				return dryRun(cmd.OutOrStdout())
+			}
+			run()
+			return nil
+		},
+	}
+	root.PersistentFlags().AddFlagSet(pflag.CommandLine)
+	root.PersistentFlags().BoolVar(&dryRunOnly, "dry-run", false, "Print the effective configuration and listener plan without serving")
+	root.Flags().BoolVar(&showVersion, "version", false, "Print version information and exit")
+
+	root.AddCommand(
+		&cobra.Command{
+			Use:   "run",
+			Short: "Start serving the configured listeners",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				if dryRunOnly {
+					return dryRun(cmd.OutOrStdout())
+				}
+				run()
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:   "version",
+			Short: "Print version information",
+			Args:  cobra.NoArgs,
+			Run: func(cmd *cobra.Command, args []string) {
+				printVersion(cmd.OutOrStdout())
+			},
+		},
+		newConfigCmd(),
+		newBenchCmd(),
+	)
+	return root
+}
+
+// newConfigCmd builds the config subcommand for inspecting the effective configuration
+func newConfigCmd() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the server configuration",
+	}
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "validate",
+		Short: "Validate the configuration from flags, environment and config file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := config.LoadServerConfig()
+			if err != nil {
+				return err
+			}
+			if err := echoserver.CheckHandlerPorts(c); err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), "Configuration is valid")
+			return err
+		},
+	})
+	return configCmd
+}
+
+// printVersion writes the server version information
+func printVersion(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "Echo Server")
+	_, _ = fmt.Fprintln(w, version.Info())
+}
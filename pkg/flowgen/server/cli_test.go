@@ -0,0 +1,65 @@
+package server
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// executeRootCmd runs the server command line with the given arguments on a clean flag set
+func executeRootCmd(t *testing.T, args ...string) (string, error) {
+	viper.Reset()
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+
+	var out bytes.Buffer
+	cmd := NewCommand()
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestVersionCommand(t *testing.T) {
+	out, err := executeRootCmd(t, "version")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Echo Server")
+
+	out, err = executeRootCmd(t, "--version")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Echo Server")
+}
+
+func TestConfigValidateCommand(t *testing.T) {
+	out, err := executeRootCmd(t, "config", "validate", "--tcp_ports_server", "8080")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Configuration is valid")
+
+	out, err = executeRootCmd(t, "config", "validate", "--tcp_ports_server", "", "--udp_ports_server", "")
+	assert.Error(t, err)
+	assert.Contains(t, out, "at least one port")
+
+	out, err = executeRootCmd(t, "config", "validate", "--handler_ports", "9092=missing")
+	assert.Error(t, err)
+	assert.Contains(t, out, "no service registered for handler_ports 9092=missing")
+}
+
+func TestRunRejectsArguments(t *testing.T) {
+	_, err := executeRootCmd(t, "run", "unexpected")
+	assert.Error(t, err)
+}
+
+func TestBenchCommand(t *testing.T) {
+	_, err := executeRootCmd(t, "bench", "--filter", "(")
+	assert.ErrorContains(t, err, "invalid filter")
+
+	out, err := executeRootCmd(t, "bench", "--filter", "^metrics/request$")
+	require.NoError(t, err)
+	assert.Contains(t, out, "metrics/request")
+	assert.NotContains(t, out, "handler/")
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/backpressure"
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/echoserver"
+)
+
+// dryRun resolves and validates the configuration, then prints the effective settings and the listener plan
+// without opening any listener
+func dryRun(w io.Writer) error {
+	c, err := config.LoadServerConfig()
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(w, "Effective configuration:")
+	if err := config.WriteEffectiveSettings(w); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(w)
+	return writePlan(w, c)
+}
+
+// writePlan describes the listeners and endpoints the server would serve with the given configuration
+func writePlan(w io.Writer, c *config.ServerConfig) error {
+	if err := echoserver.CheckHandlerPorts(c); err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString("Listener plan:\n")
+	listeners := echoserver.ListenerModes(c)
+	for _, key := range echoserver.SortedListenerKeys(listeners) {
+		fmt.Fprintf(&b, "  %s/%d: %s\n", strings.ToLower(key.ServerType), key.Port, listeners[key])
+	}
+	delays, err := config.ParseResponseDelays(c.ResponseDelays)
+	if err != nil {
+		return err
+	}
+	ports := slices.Sorted(maps.Keys(delays))
+	for _, port := range ports {
+		fmt.Fprintf(&b, "  Echo responses of port %d delayed by %s\n", port, delays[port])
+	}
+	drops, err := config.ParseResponseDrops(c.ResponseDrops)
+	if err != nil {
+		return err
+	}
+	for _, port := range slices.Sorted(maps.Keys(drops)) {
+		fmt.Fprintf(&b, "  Echo responses of port %d dropped with %g%% probability\n", port, drops[port])
+	}
+	sizes, err := config.ParseResponseSizes(c.ResponseSizes)
+	if err != nil {
+		return err
+	}
+	for _, port := range slices.Sorted(maps.Keys(sizes)) {
+		fmt.Fprintf(&b, "  Echo responses of port %d sized as %s\n", port, sizes[port])
+	}
+	rates, err := config.ParseGeneratorRates(c.GeneratorRates)
+	if err != nil {
+		return err
+	}
+	for _, port := range slices.Sorted(maps.Keys(rates)) {
+		fmt.Fprintf(&b, "  Generator stream of port %d paced at %s\n", port, config.FormatBitrate(rates[port]))
+	}
+	if c.TLSPortsServer != "" {
+		fmt.Fprintf(&b, "  TLS ports serve %s\n", echoserver.DescribeTLS(c))
+	}
+	if c.MaxConnections > 0 || c.MaxConnectionsPerListener > 0 {
+		fmt.Fprintf(&b, "  TCP connections limited to %s\n", echoserver.DescribeConnLimits(c))
+	}
+	if c.AcceptRate > 0 {
+		fmt.Fprintf(&b, "  TCP listeners accept at most %g connections per second each\n", c.AcceptRate)
+	}
+	if c.TCPListenSockets > 1 {
+		fmt.Fprintf(&b, "  TCP ports accept on %d listen sockets each\n", c.TCPListenSockets)
+	}
+	if c.UDPWorkers > 1 {
+		fmt.Fprintf(&b, "  UDP ports read by %d workers each\n", c.UDPWorkers)
+	}
+	if c.TCPReadBuffer > 0 || c.UDPReadBuffer > 0 {
+		fmt.Fprintf(&b, "  Reads of up to %d bytes on TCP connections and %d bytes on UDP sockets\n",
+			cmp.Or(c.TCPReadBuffer, 1024), cmp.Or(c.UDPReadBuffer, config.MaxUDPReadBuffer))
+	}
+	if c.ZeroCopyEcho {
+		b.WriteString("  TCP echo responses sent back unchanged are spliced in the kernel (Linux only)\n")
+	}
+	if c.UDPConnectedPeers {
+		fmt.Fprintf(&b, "  UDP peers are served over connected sockets, closed after %gs idle\n", c.UDPPeerIdleTimeout)
+	}
+	if c.UpstreamServers != "" {
+		fmt.Fprintf(&b, "  %.0f%% of echo requests relayed to %s (depth %d)\n", c.UpstreamFraction*100, c.UpstreamServers, c.UpstreamDepth)
+	}
+
+	b.WriteString("Endpoints:\n")
+	fmt.Fprintf(&b, "  :%s/metrics\n", c.MetricsPort)
+	fmt.Fprintf(&b, "  :%s/health, /ready, %s, %s\n", c.HealthPort, echoserver.StatsPath, echoserver.WebUIPath)
+	if c.BackpressureMaxConnections > 0 || c.BackpressureMaxPPS > 0 {
+		fmt.Fprintf(&b, "  :%s%s\n", c.HealthPort, backpressure.Path)
+	}
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePlan(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writePlan(&buf, &config.ServerConfig{
+		CommonConfig:        config.CommonConfig{MetricsPort: "9090"},
+		TCPPortsServer:      "8080,8090",
+		UDPPortsServer:      "53",
+		RelayPortsServer:    "9999",
+		TLSPortsServer:      "8443",
+		ServiceModes:        "53=discard,8090=generator",
+		ResponseDelays:      "8080=50ms±10ms",
+		ResponseDrops:       "8081=2.5",
+		ResponseSizes:       "8080=amplify:4",
+		GeneratorRates:      "8090=100M",
+		MaxConnections:      1000,
+		ConnectionLimitMode: "queue",
+		AcceptRate:          50,
+		UDPWorkers:          4,
+		TCPListenSockets:    2,
+		TCPReadBuffer:       16384,
+		ZeroCopyEcho:        true,
+		HealthPort:          "8082",
+		BackpressureMaxPPS:  100,
+	}))
+
+	out := buf.String()
+	assert.Contains(t, out, "tcp/8080: echo\n  tcp/8090: generator\n  tcp/9999: relay\n  tls/8443: echo\n  udp/53: discard\n")
+	assert.Contains(t, out, "TLS ports serve self-signed certificates generated for the SNI of every client\n")
+	assert.Contains(t, out, "Echo responses of port 8080 delayed by 50ms±10ms\n")
+	assert.Contains(t, out, "Echo responses of port 8081 dropped with 2.5% probability\n")
+	assert.Contains(t, out, "Echo responses of port 8080 sized as amplify:4\n")
+	assert.Contains(t, out, "Generator stream of port 8090 paced at 100 Mbit/s\n")
+	assert.Contains(t, out, "TCP listeners accept at most 50 connections per second each\n")
+	assert.Contains(t, out, "TCP ports accept on 2 listen sockets each\n")
+	assert.Contains(t, out, "UDP ports read by 4 workers each\n")
+	assert.Contains(t, out, "TCP echo responses sent back unchanged are spliced in the kernel (Linux only)\n")
+	assert.Contains(t, out, "Reads of up to 16384 bytes on TCP connections and 65536 bytes on UDP sockets\n")
+	assert.Contains(t, out, "TCP connections limited to 1000 in total, queueing connections over a limit in the listen backlog\n")
+	assert.Contains(t, out, ":9090/metrics")
+	assert.Contains(t, out, ":8082/health, /ready, /stats, /ui\n")
+	assert.Contains(t, out, ":8082/backpressure")
+}
+
+func TestDryRunCommand(t *testing.T) {
+	out, err := executeRootCmd(t, "--dry-run", "--tcp_ports_server", "7")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Effective configuration:")
+	assert.Regexp(t, `tcp_ports_server\s+"7"\s+flag`, out)
+	assert.Contains(t, out, "tcp/7: echo")
+
+	_, err = executeRootCmd(t, "run", "--dry-run", "--log_level", "invalid")
+	assert.Error(t, err)
+
+	_, err = executeRootCmd(t, "--dry-run", "--handler_ports", "9092=missing")
+	assert.ErrorContains(t, err, "no service registered for handler_ports 9092=missing")
+}
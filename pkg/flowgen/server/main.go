@@ -0,0 +1,185 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/echoserver"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
+	"github.com/PhilipSchmid/flow-generator-app/internal/tracing"
+
+	"github.com/spf13/pflag"
+)
+
+// defineFlags registers the server configuration flags on the given flag set
+func defineFlags(fs *pflag.FlagSet) {
+	fs.String("log_level", "", "Log level: debug, info, warn, error")
+	fs.String("log_format", "", "Log format: human or json")
+	fs.String("log_theme", "", "Look of the human log format: auto (colored on terminals), color, plain or classic")
+	fs.String("metrics_port", "", "Port for the metrics server")
+	fs.String("health_port", "", "Port for the health check server")
+	fs.Bool("tracing_enabled", false, "Enable tracing")
+	fs.String("jaeger_endpoint", "", "Jaeger endpoint")
+	fs.Bool("otlp_metrics_enabled", false, "Push metrics to the OTLP endpoint configured by jaeger_endpoint")
+	fs.Float64("otlp_metrics_interval", 0, "Interval in seconds between OTLP metric pushes")
+	fs.String("statsd_address", "", "StatsD/DogStatsD address (host:port) to send per-flow counters and latency timings to")
+	fs.Float64("statsd_sample_rate", 0, "Fraction of StatsD metrics to send (0-1]")
+	fs.String("profile_dir", "", "Directory of named YAML configuration profiles")
+	fs.String("profile", "", "Comma-separated profiles from profile_dir to apply, later ones override earlier ones")
+	fs.Bool("tcp_nodelay", true, "Send TCP segments without waiting to coalesce small writes (TCP_NODELAY)")
+	fs.Int("socket_sndbuf", 0, "Send buffer size (SO_SNDBUF) of the sockets in bytes (0 for the kernel default)")
+	fs.Int("socket_rcvbuf", 0, "Receive buffer size (SO_RCVBUF) of the sockets in bytes (0 for the kernel default)")
+	fs.Bool("tcp_keepalive", true, "Send TCP keepalive probes on idle TCP connections")
+	fs.Float64("tcp_keepalive_idle", 0, "Idle time in seconds before the first TCP keepalive probe (0 for the Go default of 15s)")
+	fs.Float64("tcp_keepalive_interval", 0, "Time in seconds between unanswered TCP keepalive probes (0 for the Go default of 15s)")
+	fs.Int("tcp_keepalive_count", 0, "Unanswered TCP keepalive probes after which a connection is dropped (0 for the Go default of 9)")
+	fs.String("tcp_ports_server", "", "Comma-separated list of TCP ports or port ranges (e.g. 8080,9000-9099)")
+	fs.String("udp_ports_server", "", "Comma-separated list of UDP ports or port ranges (e.g. 8080,9000-9099)")
+	fs.Bool("udp_connected_peers", false, "Serve each UDP peer over a dedicated connected socket")
+	fs.Float64("udp_peer_idle_timeout", 0, "Idle timeout in seconds after which a connected UDP peer socket is closed")
+	fs.Int("udp_workers", 0, "Sockets bound to every UDP port with SO_REUSEPORT, each read by its own goroutine (default 1)")
+	fs.Int("tcp_read_buffer", 0, "Size in bytes of the reads of TCP connections, which sizes echo responses (default 1024)")
+	fs.Int("udp_read_buffer", 0, "Size in bytes of the reads of UDP sockets, larger datagrams are truncated (default 65536)")
+	fs.Bool("zero_copy_echo", false, "Echo TCP connections with splice on Linux, so echoed data is not copied to user space")
+	fs.Float64("duplicate_window", 0, "Seconds flow headers are remembered to detect duplicate flows and replayed datagrams (0 to disable)")
+	fs.String("service_modes", "", "Comma-separated port=mode pairs (echo, discard, sink, chargen, generator, http), e.g. 7=echo,9=discard,19=chargen")
+	fs.String("response_delays", "", "Comma-separated port=delay[±jitter] pairs delaying echo responses, e.g. 8081=50ms±10ms")
+	fs.String("response_drops", "", "Comma-separated port=percent pairs dropping a share of echo responses, e.g. 8081=5")
+	fs.String("response_sizes", "", "Comma-separated port=mode:value pairs sizing echo responses (truncate, amplify, fixed), e.g. 8081=amplify:4")
+	fs.String("http_echo_headers", "", "Comma-separated request headers ports in http mode reflect as X-Echo-<name> response headers (default User-Agent,X-Request-Id,X-Forwarded-For)")
+	fs.String("generator_rates", "", "Comma-separated port=bitrate pairs pacing the streams of generator ports, e.g. 8090=100M")
+	fs.String("handler_ports", "", "Comma-separated port=service pairs for listeners served by registered custom services")
+	fs.Int("backpressure_max_connections", 0, "Active TCP connections at which backpressure is signaled to clients (0 to disable)")
+	fs.Float64("backpressure_max_pps", 0, "UDP packets per second at which backpressure is signaled to clients (0 to disable)")
+	fs.Float64("backpressure_interval", 0, "Interval in seconds between server load samples for backpressure")
+	fs.Int("max_connections", 0, "Simultaneous TCP connections of all listeners (0 for no limit)")
+	fs.Int("max_connections_per_listener", 0, "Simultaneous TCP connections of each listener (0 for no limit)")
+	fs.Float64("accept_rate", 0, "TCP connections each listener accepts per second at most (0 for no limit)")
+	fs.Int("tcp_listen_sockets", 0, "Sockets bound to every TCP port with SO_REUSEPORT, each with its own accept loop (default 1)")
+	fs.String("connection_limit_mode", "", "What happens to connections over a limit: reject closes them right after accepting them, queue leaves them in the listen backlog")
+	fs.String("relay_ports_server", "", "Comma-separated list of TCP ports on which flows are relayed to the next hop of their relay header")
+	fs.String("tls_ports_server", "", "Comma-separated list of TCP ports or port ranges served over TLS (e.g. 8443,9443-9449)")
+	fs.String("tls_certificates", "", "Comma-separated cert:key pairs of PEM files for TLS ports, chosen by the SNI of the client (self-signed certificates if unset)")
+	fs.String("upstream_servers", "", "Comma-separated upstream echo server hosts that echo requests are relayed to")
+	fs.Float64("upstream_fraction", 0, "Fraction of echo requests relayed to upstream servers (0 to 1)")
+	fs.Int("upstream_depth", 0, "Number of sequential upstream calls per relayed request")
+	fs.Float64("upstream_timeout", 0, "Timeout in seconds for each upstream call")
+}
+
+// run loads the configuration and serves the configured listeners until the process is terminated
+func run() {
+	// Load configuration
+	cfg, err := config.LoadServerConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Initialize logger
+	logging.InitLoggerWithTheme(cfg.LogFormat, cfg.LogLevel, cfg.LogTheme)
+	defer func() {
+		if err := logging.SyncLogger(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to sync logger: %v\n", err)
+		}
+	}()
+
+	// Initialize MetricsCollector
+	mc := metrics.NewMetricsCollector()
+	if cfg.StatsdAddress != "" {
+		sink, err := metrics.NewStatsdSink(cfg.StatsdAddress, cfg.StatsdSampleRate)
+		if err != nil {
+			logging.Logger.Warnf("StatsD export disabled: %v", err)
+		} else {
+			mc.SetStatsdSink(sink)
+			defer func() { _ = sink.Close() }()
+			logging.Logger.Infof("Sending metrics to StatsD at %s (sample rate %g)", cfg.StatsdAddress, cfg.StatsdSampleRate)
+		}
+	}
+
+	// Initialize tracing if enabled
+	if cfg.TracingEnabled {
+		tracing.InitTracer("echo-server", cfg.JaegerEndpoint)
+		logging.Logger.Info("Tracing enabled")
+	}
+	if cfg.OTLPMetricsEnabled {
+		shutdownOTLPMetrics := metrics.InitOTLPExporter("echo-server", cfg.JaegerEndpoint, time.Duration(cfg.OTLPMetricsInterval*float64(time.Second)))
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdownOTLPMetrics(ctx); err != nil {
+				logging.Logger.Warnf("Failed to flush OTLP metrics: %v", err)
+			}
+		}()
+		logging.Logger.Info("OTLP metrics export enabled")
+	}
+
+	// Start metrics server
+	go func() {
+		if err := metrics.StartMetricsServer(cfg.MetricsPort); err != nil && err != http.ErrServerClosed {
+			logging.Logger.Warnf("Metrics server error: %v", err)
+		}
+	}()
+
+	// Start health check server, which also serves the backpressure status to clients
+	monitorCtx, stopMonitor := context.WithCancel(context.Background())
+	defer stopMonitor()
+	healthChecker := echoserver.NewHealthChecker(monitorCtx, cfg, mc)
+	if err := healthChecker.Start(cfg.HealthPort); err != nil {
+		logging.Logger.Fatalf("Failed to start health check server: %v", err)
+	}
+
+	// Create and start all listeners
+	srv := echoserver.New(cfg, mc)
+	if err := srv.Start(); err != nil {
+		logging.Logger.Fatalf("Failed to start servers: %v", err)
+	}
+
+	// Mark service as ready after all servers are started
+	healthChecker.SetReady(true)
+	logging.Logger.Info("Echo server is ready")
+
+	// Handle termination signals
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	// Reload the configuration on SIGHUP
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+
+	// Wait for termination signal
+	var sig os.Signal
+	for sig == nil {
+		select {
+		case <-reloadChan:
+			logging.Logger.Info("Received SIGHUP, reloading configuration")
+			cfg = reloadConfig(cfg, srv)
+		case sig = <-sigChan:
+		}
+	}
+	logging.Logger.Infof("Received signal: %v. Shutting down...", sig)
+
+	// Mark service as not ready during shutdown
+	healthChecker.SetReady(false)
+
+	// Stop all servers
+	if err := srv.Stop(); err != nil {
+		logging.Logger.Errorf("Error stopping servers: %v", err)
+	}
+
+	// Stop health check server
+	if err := healthChecker.Stop(); err != nil {
+		logging.Logger.Errorf("Error stopping health check server: %v", err)
+	}
+
+	// Flush metrics
+	mc.FlushMetrics()
+
+	logging.Logger.Info("Echo server shutdown complete")
+}
@@ -1,4 +1,4 @@
-package main
+package server
 
 import (
 	"fmt"
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/echoserver"
 	"github.com/PhilipSchmid/flow-generator-app/internal/handlers"
 	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
 	"github.com/PhilipSchmid/flow-generator-app/internal/metrics"
@@ -15,35 +16,6 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func TestParsePorts(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    string
-		expected []int
-	}{
-		{"single port", "8080", []int{8080}},
-		{"multiple ports", "8080,8081,8082", []int{8080, 8081, 8082}},
-		{"with spaces", " 8080 , 8081 , 8082 ", []int{8080, 8081, 8082}},
-		{"empty string", "", []int{}},
-		{"invalid port", "8080,invalid,8081", []int{8080, 8081}},
-		{"out of range port", "8080,70000,8081", []int{8080, 8081}},
-		{"negative port", "8080,-1,8081", []int{8080, 8081}},
-		{"duplicate ports", "8080,8080,8081", []int{8080, 8080, 8081}},
-	}
-
-	// Capture log output
-	oldLogger := logging.Logger
-	logging.InitLogger("json", "error")
-	defer func() { logging.Logger = oldLogger }()
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := parsePorts(tt.input)
-			assert.Equal(t, tt.expected, result)
-		})
-	}
-}
-
 func TestServerConfiguration(t *testing.T) {
 	// Test that we can create a valid server configuration
 	cfg := &config.ServerConfig{
@@ -62,10 +34,10 @@ func TestServerConfiguration(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Test parsing ports from config
-	tcpPorts := parsePorts(cfg.TCPPortsServer)
+	tcpPorts := echoserver.ParsePorts(cfg.TCPPortsServer)
 	assert.Equal(t, []int{8080, 8081}, tcpPorts)
 
-	udpPorts := parsePorts(cfg.UDPPortsServer)
+	udpPorts := echoserver.ParsePorts(cfg.UDPPortsServer)
 	assert.Equal(t, []int{9000}, udpPorts)
 }
 
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/PhilipSchmid/flow-generator-app/internal/echoserver"
+	"github.com/PhilipSchmid/flow-generator-app/internal/logging"
+)
+
+// applyReloadable returns a copy of cfg with the settings that can be changed on reload taken from newCfg:
+// the log level and the listener ports and their service modes
+func applyReloadable(cfg, newCfg *config.ServerConfig) *config.ServerConfig {
+	applied := *cfg
+	applied.LogLevel = newCfg.LogLevel
+	applied.TCPPortsServer = newCfg.TCPPortsServer
+	applied.UDPPortsServer = newCfg.UDPPortsServer
+	applied.RelayPortsServer = newCfg.RelayPortsServer
+	applied.TLSPortsServer = newCfg.TLSPortsServer
+	applied.ServiceModes = newCfg.ServiceModes
+	applied.HandlerPorts = newCfg.HandlerPorts
+	return &applied
+}
+
+// restartRequired reports whether settings changed that are only applied on restart
+func restartRequired(oldCfg, newCfg *config.ServerConfig) bool {
+	return *applyReloadable(oldCfg, newCfg) != *newCfg
+}
+
+// reloadConfig re-reads the configuration and applies the log level and listener changes.
+// It returns the configuration in effect afterwards, which keeps the previous value of every setting
+// that only takes effect after a restart.
+func reloadConfig(cfg *config.ServerConfig, srv *echoserver.Server) *config.ServerConfig {
+	newCfg, err := config.LoadServerConfig()
+	if err != nil {
+		logging.Logger.Errorf("Keeping current configuration, reload failed: %v", err)
+		return cfg
+	}
+
+	applied := applyReloadable(cfg, newCfg)
+	logging.SetLevel(applied.LogLevel)
+	srv.Reload(applied)
+	if restartRequired(cfg, newCfg) {
+		logging.Logger.Warn("Some changed settings only take effect after a restart")
+	}
+	logging.Logger.Infof("Configuration reloaded, serving %d listeners", len(srv.Listeners()))
+	return applied
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestartRequired(t *testing.T) {
+	base := config.ServerConfig{TCPPortsServer: "8080", HealthPort: "8082"}
+
+	reloadable := base
+	reloadable.LogLevel = "debug"
+	reloadable.TCPPortsServer = "8080,8081"
+	reloadable.ServiceModes = "8081=discard"
+	assert.False(t, restartRequired(&base, &reloadable))
+
+	restart := base
+	restart.HealthPort = "8083"
+	assert.True(t, restartRequired(&base, &restart))
+
+	// Custom service ports can be added and removed on reload
+	assert.False(t, restartRequired(&config.ServerConfig{}, &config.ServerConfig{HandlerPorts: "9092=mock-kafka"}))
+}
+
+func TestApplyReloadable(t *testing.T) {
+	cfg := config.ServerConfig{TCPPortsServer: "8080", HealthPort: "8082"}
+	cfg.LogLevel = "info"
+	newCfg := config.ServerConfig{
+		TCPPortsServer: "8080,8081",
+		UDPPortsServer: "9000",
+		ServiceModes:   "8081=discard",
+		HandlerPorts:   "9092=mock-kafka",
+		HealthPort:     "8083",
+	}
+	newCfg.LogLevel = "debug"
+
+	applied := applyReloadable(&cfg, &newCfg)
+	assert.Equal(t, "debug", applied.LogLevel)
+	assert.Equal(t, "8080,8081", applied.TCPPortsServer)
+	assert.Equal(t, "9000", applied.UDPPortsServer)
+	assert.Equal(t, "8081=discard", applied.ServiceModes)
+	assert.Equal(t, "9092=mock-kafka", applied.HandlerPorts)
+	// Settings only applied on restart keep the value in effect
+	assert.Equal(t, "8082", applied.HealthPort)
+	assert.Equal(t, "8080", cfg.TCPPortsServer, "the current configuration should not be modified")
+}
@@ -0,0 +1,38 @@
+package server_test
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/server"
+	"github.com/PhilipSchmid/flow-generator-app/pkg/flowgen/service"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// closeHandler is a custom TCP service that closes every connection
+type closeHandler struct{}
+
+func (closeHandler) Handle(conn net.Conn) {
+	_ = conn.Close()
+}
+
+// TestCustomService runs the server as a program outside of the module would, with a service it registered
+// itself
+func TestCustomService(t *testing.T) {
+	service.Register("embedded-close", service.Service{TCP: func(service.Metrics) service.ConnHandler { return closeHandler{} }})
+	viper.Reset()
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+
+	var out bytes.Buffer
+	cmd := server.NewCommand()
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"--dry-run", "--handler_ports", "9092=embedded-close"})
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), "tcp/9092: embedded-close")
+}
@@ -0,0 +1,100 @@
+// Package service is the registry of the custom services of the echo server. Programs embedding the
+// flow generator add echo-like services that speak another wire protocol, such as a mock Kafka responder,
+// by registering them with Register, typically from an init function, run the server with
+// server.NewCommand from their main function and map ports to them with --handler_ports. A registered service reuses the listener manager, metrics, health checks, configuration
+// reload and graceful shutdown of the server.
+package service
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// Service modes built into the server, which custom services cannot be registered under
+const (
+	ModeEcho      = "echo"
+	ModeDiscard   = "discard"
+	ModeSink      = "sink"
+	ModeChargen   = "chargen"
+	ModeGenerator = "generator"
+	ModeHTTP      = "http"
+	ModeRelay     = "relay"
+)
+
+// builtinModes lists the service modes built into the server
+var builtinModes = []string{ModeEcho, ModeDiscard, ModeSink, ModeChargen, ModeGenerator, ModeHTTP, ModeRelay}
+
+// Builtin reports whether name is a service mode built into the server
+func Builtin(name string) bool {
+	return slices.Contains(builtinModes, name)
+}
+
+// ConnHandler serves the connections accepted by a TCP listener. Handle is called in its own
+// goroutine for every connection and owns the connection.
+type ConnHandler interface {
+	Handle(conn net.Conn)
+}
+
+// PacketHandler serves the datagrams of a UDP listener. Handle is called once with the listening
+// socket and must return when the socket is closed.
+type PacketHandler interface {
+	Handle(conn *net.UDPConn)
+}
+
+// Metrics records the traffic of a service in the metrics of the server. The port is the listening
+// port, the protocol is "tcp" or "udp".
+type Metrics interface {
+	IncRequestsReceived(protocol, port string)
+	AddBytesReceived(protocol, port string, n int)
+	AddBytesSent(protocol, port string, n int)
+}
+
+// Service creates the handlers of a custom service. A service sets the constructor of every
+// protocol it supports and leaves the others nil.
+type Service struct {
+	TCP func(m Metrics) ConnHandler
+	UDP func(m Metrics) PacketHandler
+}
+
+var (
+	mu       sync.RWMutex
+	services = make(map[string]Service)
+)
+
+// Register makes a custom service available under the given name, so ports can be mapped to it. It
+// panics if the name is taken by a built-in service mode or another service, or if the service
+// supports no protocol.
+func Register(name string, s Service) {
+	mu.Lock()
+	defer mu.Unlock()
+	if name == "" || (s.TCP == nil && s.UDP == nil) {
+		panic("service registration requires a name and at least one protocol")
+	}
+	if _, exists := services[name]; exists || Builtin(name) {
+		panic(fmt.Sprintf("service %q registered twice", name))
+	}
+	services[name] = s
+}
+
+// Lookup returns the custom service registered under the given name
+func Lookup(name string) (Service, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	s, ok := services[name]
+	return s, ok
+}
+
+// Registered returns the names of all custom services in sorted order
+func Registered() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
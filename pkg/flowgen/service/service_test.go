@@ -0,0 +1,46 @@
+package service
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// greetHandler is a custom TCP service that writes a fixed greeting
+type greetHandler struct{}
+
+func (greetHandler) Handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	_, _ = conn.Write([]byte("hello"))
+}
+
+func TestRegister(t *testing.T) {
+	Register("greet", Service{TCP: func(Metrics) ConnHandler { return greetHandler{} }})
+
+	service, ok := Lookup("greet")
+	assert.True(t, ok)
+	assert.NotNil(t, service.TCP)
+	assert.Nil(t, service.UDP)
+	assert.Contains(t, Registered(), "greet")
+
+	_, ok = Lookup("missing")
+	assert.False(t, ok)
+}
+
+func TestRegisterInvalid(t *testing.T) {
+	tcp := func(Metrics) ConnHandler { return greetHandler{} }
+
+	assert.Panics(t, func() { Register("", Service{TCP: tcp}) })
+	assert.Panics(t, func() { Register("no-protocol", Service{}) })
+	assert.Panics(t, func() { Register(ModeEcho, Service{TCP: tcp}) })
+
+	Register("twice", Service{TCP: tcp})
+	assert.Panics(t, func() { Register("twice", Service{TCP: tcp}) })
+}
+
+func TestBuiltin(t *testing.T) {
+	assert.True(t, Builtin(ModeEcho))
+	assert.True(t, Builtin(ModeRelay))
+	assert.False(t, Builtin("mock-kafka"))
+}
@@ -0,0 +1,123 @@
+// Package transport is the registry of the flow transports of the client. Programs embedding the flow
+// generator add custom protocols by implementing Transport and registering it with Register, typically from
+// an init function, run the client with client.NewCommand from their main function and map ports to it
+// with --transport_ports.
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+	"sync"
+)
+
+// Transport carries the requests of a single flow. The client owns the scheduling, metrics and logging of
+// the flow and only uses the transport to move bytes.
+type Transport interface {
+	// Dial connects to the target address
+	Dial(ctx context.Context, addr string) error
+	// Send sends one request and returns the number of payload bytes written
+	Send(payload []byte) (int, error)
+	// Recv reads the next part of the response into buf and returns the number of bytes read
+	Recv(buf []byte) (int, error)
+	// Close releases the connection, it is called once after a successful Dial
+	Close() error
+}
+
+// Mode selects how the client drives a transport
+type Mode int
+
+const (
+	// StreamMode sends a single request, reads until the whole payload has been echoed and then holds
+	// the connection open until the flow ends. Wire bytes are estimated with TCP framing.
+	StreamMode Mode = iota
+	// DatagramMode sends a request every send interval until the flow ends and expects one response
+	// per request. Wire bytes are estimated with UDP framing.
+	DatagramMode
+	// HoldMode sends a single request, which may be empty, never reads a response and holds the connection
+	// open until the flow ends. The flows are counted as half-open, wire bytes are estimated with TCP
+	// framing.
+	HoldMode
+	// ChurnMode only connects and closes the connection right away, so the flow ends with the handshake
+	// regardless of its duration. The handshake time is recorded as the request latency.
+	ChurnMode
+	// ReceiveMode sends nothing and reads what the server streams until the flow ends. Wire bytes are
+	// estimated with TCP framing.
+	ReceiveMode
+)
+
+// FlowInfo describes the flow a transport is created for
+type FlowInfo struct {
+	ID uint64
+	// Protocol is the protocol name the transport was registered under
+	Protocol string
+	Sampled  bool
+	MTU      int
+	MSS      int
+	// Source is the source address the flow should be sent from and SourceInterface the network interface
+	// it should be bound to, both are unset unless a source pool is configured
+	Source          netip.Addr
+	SourceInterface string
+	// Netns is the name of the network namespace the flow is sent from, whose sockets are those created on
+	// the goroutine calling Dial. It is unset unless netns is configured.
+	Netns string
+	// DSCP is the DSCP value the packets of the flow should be marked with, 0 leaves them unmarked
+	DSCP int
+	// TTL is the IPv4 TTL or IPv6 hop limit of the packets of the flow, 0 leaves the system default
+	TTL int
+	// FlowLabel is the IPv6 flow label of the packets of the flow, 0 leaves it to the kernel. It only applies
+	// to IPv6 flows.
+	FlowLabel uint32
+	// SourcePort is the source port the flow should be sent from, 0 leaves it to the kernel
+	SourcePort int
+}
+
+// Factory creates the transport of a single flow
+type Factory func(flow FlowInfo) Transport
+
+// Registration is a registered transport and the mode it is driven in
+type Registration struct {
+	Mode    Mode
+	Factory Factory
+}
+
+var (
+	mu         sync.RWMutex
+	transports = make(map[string]Registration)
+)
+
+// Register makes a flow transport available under the given protocol name. The name is used as the
+// protocol label of all metrics of the transport's flows. It panics if the name is already taken, the
+// client registers tcp, udp and its other built-in transports itself.
+func Register(protocol string, mode Mode, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if protocol == "" || factory == nil {
+		panic("flow transport registration requires a protocol name and a factory")
+	}
+	if _, exists := transports[protocol]; exists {
+		panic(fmt.Sprintf("flow transport %q registered twice", protocol))
+	}
+	transports[protocol] = Registration{Mode: mode, Factory: factory}
+}
+
+// Lookup returns the transport registered for a protocol
+func Lookup(protocol string) (Registration, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	reg, ok := transports[protocol]
+	return reg, ok
+}
+
+// Registered returns the names of all registered transports in sorted order
+func Registered() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(transports))
+	for name := range transports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,40 @@
+package transport
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// nopTransport is a transport that does nothing
+type nopTransport struct{}
+
+func (nopTransport) Dial(context.Context, string) error { return nil }
+func (nopTransport) Send(payload []byte) (int, error)   { return len(payload), nil }
+func (nopTransport) Recv(buf []byte) (int, error)       { return 0, nil }
+func (nopTransport) Close() error                       { return nil }
+
+func TestRegister(t *testing.T) {
+	factory := func(flow FlowInfo) Transport { return nopTransport{} }
+	Register("test-rpc", DatagramMode, factory)
+	Register("test-stream", StreamMode, factory)
+
+	reg, ok := Lookup("test-rpc")
+	assert.True(t, ok)
+	assert.Equal(t, DatagramMode, reg.Mode)
+	assert.Equal(t, nopTransport{}, reg.Factory(FlowInfo{ID: 1}))
+	_, ok = Lookup("missing")
+	assert.False(t, ok)
+	assert.Subset(t, Registered(), []string{"test-rpc", "test-stream"})
+	assert.IsNonDecreasing(t, Registered())
+}
+
+func TestRegisterInvalid(t *testing.T) {
+	factory := func(flow FlowInfo) Transport { return nopTransport{} }
+	assert.Panics(t, func() { Register("", StreamMode, factory) })
+	assert.Panics(t, func() { Register("nil-factory", StreamMode, nil) })
+
+	Register("twice", StreamMode, factory)
+	assert.Panics(t, func() { Register("twice", DatagramMode, factory) })
+}